@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/getzep/zep/pkg/browse"
+	"github.com/getzep/zep/pkg/client"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// The client-mode commands call the API of a running server, printing the response as
+// JSON. The server's URL and API key are read from ZEP_URL and ZEP_API_KEY, unless set by
+// flags.
+var (
+	clientURL     string
+	clientAPIKey  string
+	clientTimeout time.Duration
+
+	listLimit  int
+	listCursor int64
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Manages the sessions of a running Zep server",
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "Lists sessions",
+	Example: "zep sessions list --limit 20",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessions, err := newClient().ListSessions(cmd.Context(), listCursor, listLimit)
+		if err != nil {
+			return err
+		}
+		return printJSON(sessions)
+	},
+}
+
+var memoryCmd = &cobra.Command{
+	Use:   "memory",
+	Short: "Manages the memory of a running Zep server's sessions",
+}
+
+var memorySearchCmd = &cobra.Command{
+	Use:     "search <session-id> <query>",
+	Short:   "Searches a session's memory",
+	Example: `zep memory search session1 "What did the user order?" --limit 5`,
+	Args:    cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		payload := &models.MemorySearchPayload{Text: strings.Join(args[1:], " ")}
+		results, err := newClient().SearchMemory(cmd.Context(), args[0], payload, listLimit)
+		if err != nil {
+			return err
+		}
+		return printJSON(results)
+	},
+}
+
+var usersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Manages the users of a running Zep server",
+}
+
+var usersGetCmd = &cobra.Command{
+	Use:     "get <user-id>",
+	Short:   "Gets a user",
+	Example: "zep users get alice",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		user, err := newClient().GetUser(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		return printJSON(user)
+	},
+}
+
+var collectionsCmd = &cobra.Command{
+	Use:   "collections",
+	Short: "Manages the document collections of a running Zep server",
+}
+
+// collectionStats summarizes a collection's documents and index.
+type collectionStats struct {
+	Name                  string `json:"name"`
+	DocumentCount         int    `json:"document_count"`
+	DocumentEmbeddedCount int    `json:"document_embedded_count"`
+	EmbeddingDimensions   int    `json:"embedding_dimensions"`
+	IsAutoEmbedded        bool   `json:"is_auto_embedded"`
+	IsIndexed             bool   `json:"is_indexed"`
+}
+
+var collectionsStatsCmd = &cobra.Command{
+	Use:     "stats <collection>",
+	Short:   "Gets a collection's document counts and index status",
+	Example: "zep collections stats docs",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, err := newClient().GetCollection(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		stats := collectionStats{
+			Name:                collection.Name,
+			EmbeddingDimensions: collection.EmbeddingDimensions,
+			IsAutoEmbedded:      collection.IsAutoEmbedded,
+			IsIndexed:           collection.IsIndexed,
+		}
+		if collection.DocumentCollectionCounts != nil {
+			stats.DocumentCount = collection.DocumentCount
+			stats.DocumentEmbeddedCount = collection.DocumentEmbeddedCount
+		}
+		return printJSON(stats)
+	},
+}
+
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Browses the sessions of a running Zep server in a terminal UI",
+	Long: "Browses sessions and their messages, summaries, and metadata, and searches their " +
+		"memory, to debug what an agent remembers.",
+	Example: "ZEP_URL=http://localhost:8000 zep browse",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return browse.Run(cmd.Context(), newClient())
+	},
+}
+
+func newClient() *client.Client {
+	url := clientURL
+	if url == "" {
+		url = os.Getenv("ZEP_URL")
+	}
+	if url == "" {
+		url = client.DefaultBaseURL
+	}
+	apiKey := clientAPIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("ZEP_API_KEY")
+	}
+	return client.New(url, apiKey, clientTimeout)
+}
+
+func printJSON(v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func init() {
+	sessionsCmd.AddCommand(sessionsListCmd)
+	memoryCmd.AddCommand(memorySearchCmd)
+	usersCmd.AddCommand(usersGetCmd)
+	collectionsCmd.AddCommand(collectionsStatsCmd)
+
+	for _, c := range []*cobra.Command{
+		sessionsCmd,
+		memoryCmd,
+		usersCmd,
+		collectionsCmd,
+		browseCmd,
+	} {
+		// arguments are validated before this, so a usage error still prints the usage
+		c.PersistentPreRun = func(cmd *cobra.Command, args []string) { cmd.SilenceUsage = true }
+		f := c.PersistentFlags()
+		f.StringVar(&clientURL, "url", "", "Base URL of the Zep server (default $ZEP_URL or "+
+			client.DefaultBaseURL+")")
+		f.StringVar(&clientAPIKey, "api-key", "", "API key (default $ZEP_API_KEY)")
+		f.DurationVar(&clientTimeout, "timeout", client.DefaultTimeout, "Request timeout")
+		cmd.AddCommand(c)
+	}
+
+	sessionsListCmd.Flags().IntVar(&listLimit, "limit", 0, "Maximum number of sessions")
+	sessionsListCmd.Flags().Int64Var(&listCursor, "cursor", 0, "Cursor for pagination")
+	memorySearchCmd.Flags().IntVar(&listLimit, "limit", 0, "Maximum number of results")
+}
@@ -2,13 +2,21 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 
 	"github.com/getzep/zep/config"
 	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/bench"
+	"github.com/getzep/zep/pkg/egress"
+	"github.com/getzep/zep/pkg/export"
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/metrics"
 	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/provision"
 	"github.com/getzep/zep/pkg/store/postgres"
+	"github.com/getzep/zep/pkg/tasks"
 	"github.com/sirupsen/logrus"
 
 	"github.com/spf13/cobra"
@@ -17,11 +25,19 @@ import (
 var (
 	log *logrus.Logger
 
-	cfgFile     string
-	showVersion bool
-	dumpConfig  bool
-	generateKey bool
-	fixturePath string
+	cfgFile          string
+	showVersion      bool
+	dumpConfig       bool
+	generateKey      bool
+	generateAdminKey bool
+	collectionKey    string
+	fixturePath      string
+
+	exportPath        string
+	exportUserIDs     []string
+	exportCollections []string
+
+	resourcesFile string
 )
 
 var cmd = &cobra.Command{
@@ -83,22 +99,297 @@ var dumpJsonSchemaCmd = &cobra.Command{
 	},
 }
 
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports users, sessions, and document collections to an archive",
+	Long: "Exports users, sessions, and document collections to an archive that can be imported " +
+		"into another deployment. By default, everything is exported. Use --user and --collection " +
+		"to export a single tenant's data.",
+	Example: "zep export --output tenant.jsonl --user alice --user bob --collection docs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appState := newStoreAppState()
+
+		f, err := os.Create(exportPath)
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		defer f.Close()
+
+		opts := export.Options{}
+		if cmd.Flags().Changed("user") {
+			opts.UserIDs = exportUserIDs
+		}
+		if cmd.Flags().Changed("collection") {
+			opts.Collections = exportCollections
+		} else if opts.UserIDs != nil {
+			// a tenant's collections must be named explicitly
+			opts.Collections = []string{}
+		}
+
+		stats, err := export.Export(cmd.Context(), appState, f, opts)
+		if err != nil {
+			return err
+		}
+		return printJSON(stats)
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Imports users, sessions, and document collections from an archive",
+	Long: "Imports an archive created by zep export. Existing records are never modified: " +
+		"the import stops if a user, session, or collection in the archive already exists.",
+	Example: "zep import --input tenant.jsonl",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appState := newStoreAppState()
+
+		f, err := os.Open(exportPath)
+		if err != nil {
+			return fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer f.Close()
+
+		stats, err := export.Import(cmd.Context(), appState, f)
+		if stats != nil {
+			if printErr := printJSON(stats); printErr != nil {
+				return printErr
+			}
+		}
+		return err
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Creates or updates the resources declared in a file",
+	Long: "Creates the document collections declared in a resources file, and updates the " +
+		"description and metadata of those that exist. The file has the same layout as the " +
+		"resources section of the config file, which is applied each time the server starts.",
+	Example: "zep apply -f resources.yaml",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resources, err := config.LoadResources(resourcesFile)
+		if err != nil {
+			return fmt.Errorf("failed to load resources: %w", err)
+		}
+
+		appState := newStoreAppState()
+		result, err := provision.Apply(cmd.Context(), appState.DocumentStore, resources)
+		if result != nil {
+			b, marshalErr := json.MarshalIndent(result, "", "  ")
+			if marshalErr != nil {
+				return marshalErr
+			}
+			fmt.Println(string(b))
+		}
+		return err
+	},
+}
+
+// newStoreAppState returns an AppState with the stores and a task publisher, without
+// starting the server or task router.
+func newStoreAppState() *models.AppState {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		log.Fatalf("Error configuring Zep: %s", err)
+	}
+	config.SetLogLevel(cfg)
+
+	appState := &models.AppState{Config: cfg}
+	initializeStores(context.Background(), appState)
+
+	queueDB, err := postgres.NewPostgresConnForQueue(appState)
+	if err != nil {
+		log.Fatalf("failed to create postgres queue connection %v", err)
+	}
+	appState.TaskPublisher = tasks.NewTaskPublisher(queueDB)
+
+	return appState
+}
+
+var dualWriteCmd = &cobra.Command{
+	Use:   "dual-write",
+	Short: "Blue/green schema migration utilities",
+}
+
+var verifyDualWriteCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Compares the tables written by dual writes with their copies in the target schema",
+	Long: "Compares each table mirrored by store.postgres.dual_write with the table of the same " +
+		"name in the target schema, by primary key and the values of their common columns. " +
+		"Exits with an error if any rows are missing, extra, or mismatched.",
+	Example: "zep dual-write verify --config config.yaml",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf("error configuring Zep: %w", err)
+		}
+		appState := &models.AppState{Config: cfg}
+		db, err := postgres.NewPostgresConn(appState)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer db.Close()
+
+		reports, err := postgres.VerifyDualWrite(cmd.Context(), appState, db)
+		if err != nil {
+			return err
+		}
+		if err := printJSON(reports); err != nil {
+			return err
+		}
+		for i := range reports {
+			if !reports[i].Consistent() {
+				return fmt.Errorf("table %s differs from its copy", reports[i].Table)
+			}
+		}
+		return nil
+	},
+}
+
+var migrateDataCmd = &cobra.Command{
+	Use:   "migrate-data",
+	Short: "Runs the data migrations of an upgrade",
+	Long: "Runs the data migrations of an upgrade, such as backfills of new columns, in " +
+		"batches, and prints the status of each. Migrations interrupted, here or on a server, " +
+		"resume where they stopped. Progress is reported by the task API as a task of type " +
+		"data_migration. Set store.postgres.data_migrations.enabled to false to run them only " +
+		"with this command.",
+	Example: "zep migrate-data --config config.yaml",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf("error configuring Zep: %w", err)
+		}
+		config.SetLogLevel(cfg)
+		ctx := cmd.Context()
+		if err := egress.Configure(&cfg.Egress); err != nil {
+			return err
+		}
+
+		// the LLM client counts tokens
+		llmClient, err := llms.NewLLMClient(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		appState := &models.AppState{Config: cfg, LLMClient: llmClient}
+		db := initializeStores(ctx, appState)
+		defer db.Close()
+
+		task, err := tasks.RunAsyncTask(
+			ctx,
+			appState.AsyncTaskStore,
+			postgres.DataMigrationTaskType,
+			nil,
+			dataMigrationTask(appState, db),
+		)
+		if err != nil {
+			return err
+		}
+		if err := printJSON(task); err != nil {
+			return err
+		}
+		if task.Status != models.AsyncTaskSucceeded {
+			return fmt.Errorf("data migrations %s", task.Status)
+		}
+		return nil
+	},
+}
+
+var sloRulesCmd = &cobra.Command{
+	Use:   "slo-rules",
+	Short: "Generates Prometheus alerting rules for the SLOs in the config file",
+	Long: "Generates Prometheus recording rules for the SLO error ratios and multi-window " +
+		"burn-rate alerts on them, from the metrics.slo section of the config file. The " +
+		"rules use the metrics served at /metrics when metrics.enabled is true.",
+	Example: "zep slo-rules --config config.yaml > zep-slo-rules.yaml",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf("error configuring Zep: %w", err)
+		}
+		rules, err := metrics.MarshalAlertRules(metrics.NewSLOs(cfg.Metrics.SLO))
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(rules)
+		return err
+	},
+}
+
+var loadTestConfig bench.Config
+
+var loadTestCmd = &cobra.Command{
+	Use:     "loadtest",
+	Short:   "Measures ingest throughput and search latency against a Zep deployment",
+	Example: "zep loadtest --url http://localhost:8000 --sessions 50 --concurrency 16",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if loadTestConfig.APIKey == "" {
+			loadTestConfig.APIKey = os.Getenv("ZEP_API_KEY")
+		}
+		report, err := bench.Run(cmd.Context(), loadTestConfig)
+		if err != nil {
+			return err
+		}
+		return bench.WriteSummaries(os.Stdout, report.Summaries)
+	},
+}
+
 func init() {
 	testCmd.AddCommand(createFixturesCmd)
 	testCmd.AddCommand(loadFixturesCmd)
 	cmd.AddCommand(testCmd)
 	cmd.AddCommand(dumpJsonSchemaCmd)
+	cmd.AddCommand(loadTestCmd)
+	cmd.AddCommand(exportCmd)
+	cmd.AddCommand(importCmd)
+	cmd.AddCommand(applyCmd)
+	cmd.AddCommand(sloRulesCmd)
+	dualWriteCmd.AddCommand(verifyDualWriteCmd)
+	cmd.AddCommand(dualWriteCmd)
+	cmd.AddCommand(migrateDataCmd)
 
 	cmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default config.yaml)")
 	cmd.PersistentFlags().BoolVarP(&showVersion, "version", "v", false, "print version number")
 	cmd.PersistentFlags().BoolVarP(&dumpConfig, "dump-config", "d", false, "dump config")
 	cmd.PersistentFlags().
 		BoolVarP(&generateKey, "generate-token", "g", false, "generate a new JWT token")
+	cmd.PersistentFlags().
+		BoolVar(&generateAdminKey, "generate-admin-token", false, "generate a new JWT token with the admin scope")
+	cmd.PersistentFlags().
+		StringVar(&collectionKey, "generate-collection-token", "", "generate a new JWT token that may only read and search the named collection")
 
 	createFixturesCmd.Flags().Int("count", 100, "Number of fixtures to generate per model")
 	createFixturesCmd.Flags().String("outputDir", "./test_data", "Path to output fixtures")
 	loadFixturesCmd.Flags().
 		StringVarP(&fixturePath, "fixturePath", "f", "./test_data", "Path containing fixtures to load")
+
+	exportCmd.Flags().StringVarP(&exportPath, "output", "o", "zep-export.jsonl", "Path of the archive to write")
+	exportCmd.Flags().StringSliceVar(&exportUserIDs, "user", nil, "Export only this user and its sessions (repeatable)")
+	exportCmd.Flags().
+		StringSliceVar(&exportCollections, "collection", nil, "Export only this collection (repeatable)")
+	importCmd.Flags().StringVarP(&exportPath, "input", "i", "zep-export.jsonl", "Path of the archive to import")
+
+	applyCmd.Flags().StringVarP(&resourcesFile, "file", "f", "", "Path of the resources file")
+	_ = applyCmd.MarkFlagRequired("file")
+
+	f := loadTestCmd.Flags()
+	c := &loadTestConfig
+	f.StringVar(&c.BaseURL, "url", "http://localhost:8000", "Base URL of the Zep deployment")
+	f.StringVar(&c.APIKey, "api-key", "", "API key (default $ZEP_API_KEY)")
+	f.IntVar(&c.Sessions, "sessions", bench.DefaultSessions, "Number of sessions to create")
+	f.IntVar(&c.MessagesPerSession, "messages", bench.DefaultMessagesPerSession, "Messages per session")
+	f.IntVar(&c.MessageBatchSize, "message-batch", bench.DefaultMessageBatchSize, "Messages per memory request")
+	f.IntVar(&c.Collections, "collections", bench.DefaultCollections, "Number of collections to create")
+	f.IntVar(&c.DocumentsPerCollection, "documents", bench.DefaultDocumentsPerCollection, "Documents per collection")
+	f.IntVar(&c.DocumentBatchSize, "document-batch", bench.DefaultDocumentBatchSize, "Documents per request")
+	f.IntVar(&c.EmbeddingDimensions, "dimensions", 384, "Document embedding dimensions")
+	f.BoolVar(&c.AutoEmbed, "auto-embed", false, "Create auto-embedded collections, embedded by the server")
+	f.IntVar(&c.Searches, "searches", bench.DefaultSearches, "Number of memory and document searches")
+	f.IntVar(&c.SearchLimit, "search-limit", bench.DefaultSearchLimit, "Search result limit")
+	f.IntVar(&c.Concurrency, "concurrency", bench.DefaultConcurrency, "Concurrent requests")
+	f.DurationVar(&c.Timeout, "timeout", bench.DefaultTimeout, "Request timeout")
+	f.Int64Var(&c.Seed, "seed", 0, "Seed for generated data")
+	f.BoolVar(&c.Cleanup, "cleanup", true, "Delete generated sessions and collections when done")
 }
 
 // Execute executes the root cobra command.
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
+	"github.com/getzep/zep/pkg/store/opensearch"
 	"github.com/getzep/zep/pkg/store/postgres"
+	"github.com/getzep/zep/pkg/store/qdrant"
 	"github.com/getzep/zep/pkg/tasks"
 
 	"github.com/getzep/zep/pkg/auth"
@@ -19,9 +22,22 @@ import (
 	"github.com/uptrace/bun"
 
 	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/egress"
+	"github.com/getzep/zep/pkg/experiment"
+	"github.com/getzep/zep/pkg/faultinject"
 	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/maintenance"
 	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/provision"
+	"github.com/getzep/zep/pkg/roles"
+	"github.com/getzep/zep/pkg/rules"
+	"github.com/getzep/zep/pkg/scheduler"
+	"github.com/getzep/zep/pkg/searchcache"
+	"github.com/getzep/zep/pkg/secrets"
 	"github.com/getzep/zep/pkg/server"
+	"github.com/getzep/zep/pkg/webhooks"
+	"github.com/getzep/zep/pkg/writebuffer"
+	"github.com/getzep/zep/pkg/writehooks"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
@@ -35,6 +51,8 @@ const (
 	ErrPostgresDSNNotSet           = "store.postgres.dsn must be set"
 	ErrOtelEnabledButExporterEmpty = "OpenTelemtry is enabled but OTEL_EXPORTER_OTLP_ENDPOINT is not set"
 	StoreTypePostgres              = "postgres"
+	VectorStoreTypeQdrant          = "qdrant"
+	MemoryStoreTypeOpenSearch      = "opensearch"
 )
 
 // run is the entrypoint for the zep server
@@ -78,6 +96,32 @@ func run() {
 func NewAppState(cfg *config.Config) *models.AppState {
 	ctx := context.Background()
 
+	if err := experiment.ValidateRetrieval(cfg.Experiments.Retrieval); err != nil {
+		log.Fatal(err)
+	}
+	if err := server.ValidateDeprecation(cfg.Server.V1Deprecation); err != nil {
+		log.Fatal(err)
+	}
+	for _, embeddings := range []config.EmbeddingsConfig{
+		cfg.Extractors.Messages.Embeddings,
+		cfg.Extractors.Messages.Summarizer.Embeddings,
+		cfg.Extractors.Documents.Embeddings,
+	} {
+		if err := llms.ValidateNormalization(embeddings.Normalize); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if cfg.Extractors.Messages.Embeddings.TurnPairs && cfg.Extractors.Messages.Embeddings.Window > 1 {
+		log.Fatal("extractors.messages.embeddings.window can't be combined with turn_pairs")
+	}
+
+	if err := egress.Configure(&cfg.Egress); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := egress.LoadTLSConfig(cfg.NLP.TLS); err != nil {
+		log.Fatalf("invalid nlp.tls: %s", err)
+	}
+
 	// Create a new LLM client
 	llmClient, err := llms.NewLLMClient(ctx, cfg)
 	if err != nil {
@@ -85,17 +129,50 @@ func NewAppState(cfg *config.Config) *models.AppState {
 	}
 
 	appState := &models.AppState{
-		LLMClient: llmClient,
-		Config:    cfg,
+		LLMClient:   llmClient,
+		Config:      cfg,
+		Maintenance: maintenance.New(),
 	}
 
-	initializeStores(ctx, appState)
+	db := initializeStores(ctx, appState)
+
+	failAbandonedTasks(ctx, appState)
+
+	if cfg.Store.Postgres.DataMigrations.Enabled {
+		startDataMigrations(ctx, appState, db)
+	}
+
+	applyResources(ctx, appState)
+
+	// the search cache is wrapped by the write buffer, so that buffered writes invalidate
+	// cached results once inserted rather than once buffered
+	searchcache.Wrap(appState)
+	if err := writebuffer.Wrap(appState); err != nil {
+		log.Fatal(err)
+	}
+	// hooks run before writes are buffered, so that rejections reach the client
+	if err := writehooks.Wrap(appState); err != nil {
+		log.Fatal(err)
+	}
+	if err := rules.Wrap(appState); err != nil {
+		log.Fatal(err)
+	}
+	// roles are mapped first, so that rules and hooks see registered roles
+	if err := roles.Wrap(appState); err != nil {
+		log.Fatal(err)
+	}
+	if err := llms.WrapTenantProviders(appState); err != nil {
+		log.Fatal(err)
+	}
+
+	// In development, optionally wrap the stores and LLM client to inject faults
+	faultinject.Wrap(appState)
 
 	setupTaskRouter(ctx, appState)
 
 	setupSignalHandler(ctx, appState)
 
-	setupPurgeProcessor(ctx, appState)
+	setupScheduler(ctx, appState)
 
 	return appState
 }
@@ -112,27 +189,113 @@ func handleCLIOptions(cfg *config.Config) {
 	case generateKey:
 		fmt.Println(auth.GenerateJWT(cfg))
 		os.Exit(0)
+	case generateAdminKey:
+		fmt.Println(auth.GenerateScopedJWT(cfg, auth.ScopeAdmin))
+		os.Exit(0)
+	case collectionKey != "":
+		fmt.Println(auth.GenerateCollectionJWT(cfg, collectionKey))
+		os.Exit(0)
+	}
+}
+
+// failAbandonedTasks fails the async tasks left unfinished by a previous run of the server
+func failAbandonedTasks(ctx context.Context, appState *models.AppState) {
+	before := time.Now().Add(-tasks.AsyncTaskAbandonedAfter)
+	n, err := appState.AsyncTaskStore.FailAbandoned(ctx, before)
+	if err != nil {
+		log.Errorf("failed to fail abandoned tasks: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Warnf("failed %d abandoned tasks", n)
+	}
+}
+
+// applyResources creates or updates the resources declared in the config file
+func applyResources(ctx context.Context, appState *models.AppState) {
+	if len(appState.Config.Resources.Collections) == 0 {
+		return
+	}
+	result, err := provision.Apply(ctx, appState.DocumentStore, &appState.Config.Resources)
+	if err != nil {
+		log.Fatalf("failed to apply resources: %v", err)
+	}
+	log.Infof(
+		"Applied resources: %d created, %d updated, %d unchanged",
+		len(result.Created), len(result.Updated), len(result.Unchanged),
+	)
+}
+
+// initializeVectorStore sets the vector store of document embeddings, unless they're
+// stored in Postgres.
+func initializeVectorStore(appState *models.AppState) {
+	cfg := appState.Config.Store.VectorStore
+	switch cfg.Type {
+	case "", StoreTypePostgres:
+	case VectorStoreTypeQdrant:
+		vectorStore, err := qdrant.NewVectorStore(&cfg.Qdrant)
+		if err != nil {
+			log.Fatalf("unable to create vector store: %v", err)
+		}
+		appState.VectorStore = vectorStore
+		log.Infof("document embeddings are stored in qdrant at %s", cfg.Qdrant.URL)
+	default:
+		log.Fatalf("unknown store.vector_store.type %s", cfg.Type)
+	}
+}
+
+// initializeMemoryStore replaces the Postgres memory store with OpenSearch's, if
+// configured. Users remain in Postgres, so the user store is wrapped to delete and
+// anonymize their sessions in OpenSearch.
+func initializeMemoryStore(ctx context.Context, appState *models.AppState) {
+	cfg := appState.Config.Store.MemoryStore
+	switch cfg.Type {
+	case "", StoreTypePostgres:
+	case MemoryStoreTypeOpenSearch:
+		client, err := opensearch.NewClient(&cfg.OpenSearch)
+		if err != nil {
+			log.Fatalf("unable to create opensearch client: %v", err)
+		}
+		memoryStore, err := opensearch.NewMemoryStore(appState, client)
+		if err != nil {
+			log.Fatalf("unable to create memoryStore: %v", err)
+		}
+		if err := memoryStore.CreateIndexes(ctx); err != nil {
+			log.Fatalf("unable to create opensearch indexes: %v", err)
+		}
+		appState.MemoryStore = memoryStore
+		appState.UserStore = opensearch.NewUserStore(appState.UserStore, memoryStore)
+		appState.MemorySnapshotStore = opensearch.NewMemorySnapshotStore(
+			appState.MemorySnapshotStore,
+		)
+		log.Infof("sessions and messages are stored in opensearch at %s", cfg.OpenSearch.URL)
+	default:
+		log.Fatalf("unknown store.memory_store.type %s", cfg.Type)
 	}
 }
 
-// initializeStores initializes the memory and document stores based on the config file / ENV
-func initializeStores(ctx context.Context, appState *models.AppState) {
+// initializeStores initializes the memory and document stores based on the config file / ENV,
+// returning the database connection they share
+func initializeStores(ctx context.Context, appState *models.AppState) *bun.DB {
 	if appState.Config.Store.Type == "" {
 		log.Fatal(ErrStoreTypeNotSet)
 	}
 
+	var db *bun.DB
 	switch appState.Config.Store.Type {
 	case StoreTypePostgres:
 		if appState.Config.Store.Postgres.DSN == "" {
 			log.Fatal(ErrPostgresDSNNotSet)
 		}
-		db, err := postgres.NewPostgresConn(appState)
+		var err error
+		db, err = postgres.NewPostgresConn(appState)
 		if err != nil {
 			log.Fatalf("Failed to connect to database: %v\n", err)
 		}
 		if appState.Config.Log.Level == "debug" {
 			pgDebugLogging(db)
 		}
+		initializeVectorStore(appState)
 		memoryStore, err := postgres.NewPostgresMemoryStore(appState, db)
 		if err != nil {
 			log.Fatalf("unable to create memoryStore %v", err)
@@ -149,12 +312,55 @@ func initializeStores(ctx context.Context, appState *models.AppState) {
 		}
 		log.Debug("documentStore created")
 
+		if appState.Config.Store.Postgres.Warmup.Enabled {
+			warmup(ctx, appState, db)
+		}
+
 		userStore := postgres.NewUserStoreDAO(db)
 		log.Debug("userStore created")
 
+		asyncTaskStore := postgres.NewAsyncTaskStoreDAO(db)
+		log.Debug("asyncTaskStore created")
+
+		memorySnapshotStore := postgres.NewMemorySnapshotStoreDAO(db)
+		log.Debug("memorySnapshotStore created")
+
+		provenanceStore := postgres.NewProvenanceStoreDAO(db)
+		log.Debug("provenanceStore created")
+
+		queryLogStore := postgres.NewQueryLogStoreDAO(db)
+		log.Debug("queryLogStore created")
+
+		evalSetStore := postgres.NewEvalSetStoreDAO(db)
+		log.Debug("evalSetStore created")
+
+		legalHoldStore := postgres.NewLegalHoldStoreDAO(db)
+		log.Debug("legalHoldStore created")
+
+		if appState.Config.LLM.Tenants.Enabled {
+			box, err := secrets.NewBoxFromBase64(appState.Config.LLM.Tenants.EncryptionKey)
+			if err != nil {
+				log.Fatalf(
+					"invalid llm.tenants.encryption_key. "+
+						"set ZEP_LLM_TENANTS_ENCRYPTION_KEY to a base64-encoded %d-byte key: %v",
+					secrets.KeySize,
+					err,
+				)
+			}
+			appState.ProviderOverrideStore = postgres.NewProviderOverrideStoreDAO(db, box)
+			log.Debug("providerOverrideStore created")
+		}
+
 		appState.MemoryStore = memoryStore
 		appState.DocumentStore = documentStore
 		appState.UserStore = userStore
+		appState.AsyncTaskStore = asyncTaskStore
+		appState.MemorySnapshotStore = memorySnapshotStore
+		appState.ProvenanceStore = provenanceStore
+		appState.QueryLogStore = queryLogStore
+		appState.EvalSetStore = evalSetStore
+		appState.LegalHoldStore = legalHoldStore
+		initializeMemoryStore(ctx, appState)
 	default:
 		log.Fatal(
 			fmt.Sprintf(
@@ -165,6 +371,48 @@ func initializeStores(ctx context.Context, appState *models.AppState) {
 	}
 
 	log.Info("Using memory store: ", appState.Config.Store.Type)
+
+	return db
+}
+
+// startDataMigrations runs the unfinished data migrations in an async task, whose progress
+// is reported by the task API.
+func startDataMigrations(ctx context.Context, appState *models.AppState, db *bun.DB) {
+	task, err := tasks.StartAsyncTask(
+		ctx,
+		appState.AsyncTaskStore,
+		postgres.DataMigrationTaskType,
+		nil,
+		dataMigrationTask(appState, db),
+	)
+	if err != nil {
+		log.Errorf("failed to start data migrations: %v", err)
+		return
+	}
+	log.Infof("Running data migrations in task %s", task.UUID)
+}
+
+// dataMigrationTask runs the data migrations, reporting the rows migrated as the task's
+// progress and the status of each migration as its result.
+func dataMigrationTask(appState *models.AppState, db *bun.DB) tasks.AsyncTaskFunc {
+	return func(ctx context.Context, p *tasks.AsyncTaskProgress) (map[string]interface{}, error) {
+		statuses, err := postgres.RunDataMigrations(ctx, appState, db, p.Set)
+		return map[string]interface{}{"migrations": statuses}, err
+	}
+}
+
+// warmup pre-warms the vector indexes and recent sessions before the server starts. A
+// failed or timed out warm-up is logged, but doesn't prevent startup.
+func warmup(ctx context.Context, appState *models.AppState, db *bun.DB) {
+	started := time.Now()
+	result, err := postgres.Warmup(ctx, appState, db)
+	if err != nil {
+		log.Warnf("warm-up incomplete after %s: %v", time.Since(started), err)
+	}
+	log.Infof(
+		"Warmed up %d vector indexes and %d sessions in %s",
+		result.Indexes, result.Sessions, time.Since(started),
+	)
 }
 
 func pgDebugLogging(db *bun.DB) {
@@ -207,32 +455,132 @@ func setupTaskRouter(ctx context.Context, appState *models.AppState) {
 	tasks.RunTaskRouter(ctx, appState, db)
 }
 
-// setupPurgeProcessor sets up a go routine to purge deleted records from the MemoryStore
-// at a regular interval. It's cancellable via the passed context.
-// If Config.DataConfig.PurgeEvery is 0, this function does nothing.
-func setupPurgeProcessor(ctx context.Context, appState *models.AppState) {
-	interval := time.Duration(appState.Config.DataConfig.PurgeEvery) * time.Minute
-	if interval == 0 {
-		log.Debug("purge delete processor disabled")
-		return
+// setupScheduler schedules the background jobs and starts them. Jobs are cancellable via
+// the passed context. A job's schedule is taken from Config.Scheduler.Jobs, or, for jobs
+// predating the scheduler, from its interval in Config.DataConfig.
+func setupScheduler(ctx context.Context, appState *models.AppState) {
+	cfg := appState.Config
+	dataConfig := cfg.DataConfig
+	sender := webhooks.NewSender(&cfg.Webhooks)
+	warnBefore := time.Duration(dataConfig.SessionExpiry.WarnBefore) * time.Minute
+
+	// each deduplication run only considers sessions with messages embedded since the
+	// previous successful run
+	var dedupeSince time.Time
+
+	jobs := []jobDefinition{
+		{"purge", dataConfig.PurgeEvery, appState.MemoryStore.PurgeDeleted},
+		{"session_expiry", dataConfig.SessionExpiry.CheckEvery, func(ctx context.Context) error {
+			return tasks.ExpireSessions(ctx, appState, sender, warnBefore)
+		}},
+		{"deduplication", dataConfig.Deduplication.RunEvery, func(ctx context.Context) error {
+			runStarted := time.Now()
+			_, err := appState.MemoryStore.DeduplicateMessageEmbeddings(
+				ctx,
+				dedupeSince,
+				dataConfig.Deduplication.SimilarityThreshold,
+				models.DeduplicationScope(dataConfig.Deduplication.Scope),
+			)
+			if err != nil {
+				return err
+			}
+			dedupeSince = runStarted
+			return nil
+		}},
+		{"topics", dataConfig.Topics.RunEvery, func(ctx context.Context) error {
+			return tasks.ModelUserTopics(ctx, appState, dataConfig.Topics)
+		}},
+		{"reflection", dataConfig.Reflection.RunEvery, func(ctx context.Context) error {
+			return tasks.ReflectUsers(ctx, appState, dataConfig.Reflection)
+		}},
+		{"embedding_drift", dataConfig.EmbeddingDrift.RunEvery, func(ctx context.Context) error {
+			_, err := tasks.CheckEmbeddingDrift(ctx, appState, sender, dataConfig.EmbeddingDrift)
+			return err
+		}},
+		{"index_maintenance", 0, func(ctx context.Context) error {
+			return tasks.MaintainCollectionIndexes(ctx, appState)
+		}},
+		{"provenance_retention", provenanceRetentionEvery(cfg), func(ctx context.Context) error {
+			return tasks.DeleteExpiredProvenance(ctx, appState)
+		}},
+		{"query_log_retention", queryLogRetentionEvery(cfg), func(ctx context.Context) error {
+			return tasks.DeleteExpiredSearches(ctx, appState)
+		}},
+		// content hashes are only verified when scheduled, as every message and document is read
+		{"content_integrity", 0, func(ctx context.Context) error {
+			_, err := tasks.VerifyContentHashes(ctx, appState, nil)
+			return err
+		}},
 	}
 
-	log.Infof("Starting purge delete processor. Purging every %v", interval)
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				log.Info("Stopping purge delete processor")
-				return
-			default:
-				err := appState.MemoryStore.PurgeDeleted(ctx)
-				if err != nil {
-					log.Errorf("error purging deleted records: %v", err)
-				}
+	s := scheduler.New()
+	for name := range cfg.Scheduler.Jobs {
+		if !slices.ContainsFunc(jobs, func(j jobDefinition) bool { return j.name == name }) {
+			log.Warnf("scheduler.jobs.%s is not a known job", name)
+		}
+	}
+	for _, job := range jobs {
+		err := s.Add(job.name, jobSchedule(cfg, job.name, job.interval), job.fn)
+		if err != nil {
+			log.Fatalf("failed to schedule job: %v", err)
+		}
+	}
+	s.Start(ctx)
+	appState.Scheduler = s
+
+	if dataConfig.EmbeddingDrift.CheckOnStartup {
+		// the check is logged, and alerts on drift, but doesn't delay startup
+		go func() {
+			_, err := tasks.CheckEmbeddingDrift(ctx, appState, sender, dataConfig.EmbeddingDrift)
+			if err != nil {
+				log.Errorf("startup embedding drift check failed: %v", err)
 			}
-			time.Sleep(interval)
+		}()
+	}
+}
+
+// provenanceRetentionEvery returns the interval of the provenance retention job, in
+// minutes. The job runs hourly while provenance is recorded with a retention.
+func provenanceRetentionEvery(cfg *config.Config) int {
+	if !cfg.Provenance.Enabled || cfg.Provenance.Retention <= 0 {
+		return 0
+	}
+	return 60
+}
+
+// queryLogRetentionEvery returns the interval of the query log retention job, in minutes.
+// Like the provenance retention job, it runs hourly while searches are logged with a
+// retention.
+func queryLogRetentionEvery(cfg *config.Config) int {
+	if !cfg.QueryLog.Enabled || cfg.QueryLog.Retention <= 0 {
+		return 0
+	}
+	return 60
+}
+
+// jobDefinition is a scheduled job, with its interval in minutes from Config.DataConfig.
+type jobDefinition struct {
+	name     string
+	interval int
+	fn       scheduler.JobFunc
+}
+
+// jobSchedule returns the configured schedule of a job, or every interval minutes if the
+// job isn't configured. An empty schedule disables the job.
+func jobSchedule(cfg *config.Config, name string, interval int) string {
+	if jobConfig, ok := cfg.Scheduler.Jobs[name]; ok {
+		if !jobConfig.Enabled {
+			return ""
 		}
-	}()
+		if jobConfig.Schedule == "" {
+			log.Warnf("scheduler.jobs.%s is enabled but has no schedule", name)
+		}
+		return jobConfig.Schedule
+	}
+	if interval > 0 {
+		return fmt.Sprintf("@every %dm", interval)
+	}
+	return ""
 }
 
 func dumpConfigToJSON(cfg *config.Config) string {
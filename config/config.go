@@ -16,10 +16,14 @@ var log = logrus.New()
 
 // EnvVars is a set of secrets that should be stored in the environment, not config file
 var EnvVars = map[string]string{
-	"llm.anthropic_api_key": "ZEP_ANTHROPIC_API_KEY",
-	"llm.openai_api_key":    "ZEP_OPENAI_API_KEY",
-	"auth.secret":           "ZEP_AUTH_SECRET",
-	"development":           "ZEP_DEVELOPMENT",
+	"llm.anthropic_api_key":                  "ZEP_ANTHROPIC_API_KEY",
+	"llm.openai_api_key":                     "ZEP_OPENAI_API_KEY",
+	"auth.secret":                            "ZEP_AUTH_SECRET",
+	"development":                            "ZEP_DEVELOPMENT",
+	"webhooks.secret":                        "ZEP_WEBHOOKS_SECRET",
+	"llm.tenants.encryption_key":             "ZEP_LLM_TENANTS_ENCRYPTION_KEY",
+	"store.vector_store.qdrant.api_key":      "ZEP_STORE_VECTOR_STORE_QDRANT_API_KEY",
+	"store.memory_store.opensearch.password": "ZEP_STORE_MEMORY_STORE_OPENSEARCH_PASSWORD",
 }
 
 // LoadConfig loads the config file and ENV variables into a Config struct
@@ -61,6 +65,24 @@ func LoadConfig(configFile string) (*Config, error) {
 	return &cfg, nil
 }
 
+// LoadResources loads a resources file, as used by the apply command. The file has the
+// same layout as the resources section of the config file.
+func LoadResources(resourcesFile string) (*ResourcesConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(resourcesFile)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	var resources ResourcesConfig
+	if err := v.Unmarshal(&resources); err != nil {
+		return nil, err
+	}
+
+	return &resources, nil
+}
+
 // loadDotEnv loads environment variables from .env file
 func loadDotEnv() {
 	err := godotenv.Load()
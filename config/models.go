@@ -12,14 +12,173 @@ type Config struct {
 	Log           LogConfig           `mapstructure:"log"`
 	Auth          AuthConfig          `mapstructure:"auth"`
 	OpenTelemetry OpenTelemetryConfig `mapstructure:"opentelemetry"`
+	Metrics       MetricsConfig       `mapstructure:"metrics"`
 	DataConfig    DataConfig          `mapstructure:"data"`
 	Development   bool                `mapstructure:"development"`
 	CustomPrompts CustomPromptsConfig `mapstructure:"custom_prompts"`
+	Webhooks      WebhooksConfig      `mapstructure:"webhooks"`
+	// Egress applies to the outbound requests of LLM, embedding and webhook clients.
+	Egress EgressConfig `mapstructure:"egress"`
+	// FaultInjection is only applied in development mode.
+	FaultInjection FaultInjectionConfig `mapstructure:"fault_injection"`
+	// Resources are created or updated at startup. See also the apply command.
+	Resources ResourcesConfig `mapstructure:"resources"`
+	Scheduler SchedulerConfig `mapstructure:"scheduler"`
+	// SearchCache caches the results of repeated identical memory and document searches.
+	SearchCache SearchCacheConfig `mapstructure:"search_cache"`
+	Provenance  ProvenanceConfig  `mapstructure:"provenance"`
+	QueryLog    QueryLogConfig    `mapstructure:"query_log"`
+	Experiments ExperimentsConfig `mapstructure:"experiments"`
+	// Rules are evaluated on each message as it's written.
+	Rules RulesConfig `mapstructure:"rules"`
+}
+
+// RulesConfig holds the rules evaluated on each message as it's written, in order, and
+// the extractors that only process the messages rules route to them.
+type RulesConfig struct {
+	Messages []MessageRuleConfig `mapstructure:"messages"`
+	// RoutedExtractors are the task topics, such as message_intent, of the extractors
+	// that only process messages routed to them by a rule.
+	RoutedExtractors []string `mapstructure:"routed_extractors"`
+}
+
+// MessageRuleConfig is a rule applied to the messages matching its When CEL expression.
+type MessageRuleConfig struct {
+	Name string `mapstructure:"name"`
+	// When is a CEL expression over message, with role, content, metadata and
+	// token_count fields, and session_id. It must evaluate to a bool.
+	When string `mapstructure:"when"`
+	// SetMetadata sets top-level metadata fields of the message.
+	SetMetadata map[string]interface{} `mapstructure:"set_metadata"`
+	// Extractors routes the message to these routed extractors.
+	Extractors []string `mapstructure:"extractors"`
+	// SkipSummarization leaves the message out of session summaries.
+	SkipSummarization bool `mapstructure:"skip_summarization"`
+}
+
+type ExperimentsConfig struct {
+	Retrieval RetrievalExperimentConfig `mapstructure:"retrieval"`
+}
+
+// RetrievalExperimentConfig configures an A/B experiment on search settings. Each session,
+// for memory searches, and each collection, for document searches, is assigned variant a
+// or b at random, and searched with its variant's preset. Searches setting a search type
+// aren't part of the experiment.
+type RetrievalExperimentConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Name identifies the experiment. Renaming it reassigns sessions and collections.
+	Name string `mapstructure:"name"`
+	// Split is the fraction of sessions and collections assigned variant b. Defaults to 0.5.
+	Split float64         `mapstructure:"split"`
+	A     RetrievalPreset `mapstructure:"a"`
+	B     RetrievalPreset `mapstructure:"b"`
+}
+
+// RetrievalPreset holds search settings. An empty search type uses similarity search.
+type RetrievalPreset struct {
+	SearchType string  `mapstructure:"search_type"`
+	MMRLambda  float32 `mapstructure:"mmr_lambda"`
+}
+
+// ProvenanceConfig configures the recording of the messages, summaries, and documents
+// returned by memory reads and searches. Each recorded response has an ID, returned in the
+// X-Zep-Response-Id header, by which its provenance can be looked up.
+type ProvenanceConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Retention is how long records are kept, in days. If set to 0, records are kept
+	// forever.
+	Retention int `mapstructure:"retention"`
+}
+
+// QueryLogConfig configures the logging of memory and document searches, with their
+// latency and result count. Logged searches are aggregated by the search analytics
+// endpoint.
+type QueryLogConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// SampleRate is the fraction of searches logged, from 0 to 1. Defaults to 1.
+	SampleRate float64 `mapstructure:"sample_rate"`
+	// Retention is how long searches are kept, in days. If set to 0, searches are kept
+	// forever.
+	Retention int `mapstructure:"retention"`
+	// Noise configures the noise added to search analytics on request.
+	Noise AnalyticsNoiseConfig `mapstructure:"noise"`
+}
+
+// AnalyticsNoiseConfig configures the Laplace noise added to the counts of search analytics,
+// so that they show usage patterns without exposing the searches of a few users.
+type AnalyticsNoiseConfig struct {
+	// Required adds noise to all analytics, rather than only those requested with noise.
+	Required bool `mapstructure:"required"`
+	// Epsilon is the privacy budget of any one search, split among the counts it changes.
+	// Smaller values add more noise. Defaults to 1.
+	Epsilon float64 `mapstructure:"epsilon"`
+	// MinCount omits the top queries whose noised count of searches is below it.
+	MinCount int `mapstructure:"min_count"`
+	// Secret keys the noise, which is the same for requests with the same counts. Servers
+	// sharing the secret add the same noise. If empty, each server generates a key at
+	// startup.
+	Secret string `mapstructure:"secret"`
+}
+
+// SearchCacheConfig configures an in-process cache of search results. Writes to a session
+// or collection through this server invalidate its cached results. Writes by other
+// servers sharing the database are seen once results expire.
+type SearchCacheConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TTL is how long results are cached, in seconds. Defaults to 10.
+	TTL int `mapstructure:"ttl"`
+	// MaxEntries is the number of results cached. The least recently used results are
+	// evicted first. Defaults to 1000.
+	MaxEntries int `mapstructure:"max_entries"`
 }
 
 type StoreConfig struct {
 	Type     string         `mapstructure:"type"`
 	Postgres PostgresConfig `mapstructure:"postgres"`
+	// VectorStore selects where the embeddings of document collections are stored.
+	VectorStore VectorStoreConfig `mapstructure:"vector_store"`
+	// MemoryStore selects where sessions, messages and summaries are stored.
+	MemoryStore MemoryStoreConfig `mapstructure:"memory_store"`
+}
+
+// MemoryStoreConfig selects the store of sessions, messages, summaries and their
+// embeddings. Users, documents and the other records are always stored in Postgres.
+type MemoryStoreConfig struct {
+	// Type is postgres or opensearch. Defaults to postgres.
+	Type       string           `mapstructure:"type"`
+	OpenSearch OpenSearchConfig `mapstructure:"opensearch"`
+}
+
+type OpenSearchConfig struct {
+	// URL is the address of the OpenSearch cluster, such as https://localhost:9200.
+	URL      string `mapstructure:"url"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// IndexPrefix is prepended to the names of Zep's indexes, so that several deployments
+	// can share a cluster.
+	IndexPrefix string `mapstructure:"index_prefix"`
+	// Timeout is the timeout of requests to OpenSearch, in seconds. Defaults to 10.
+	Timeout int `mapstructure:"timeout"`
+}
+
+// VectorStoreConfig selects the store of document embeddings. Message and summary
+// embeddings are always stored in Postgres.
+type VectorStoreConfig struct {
+	// Type is postgres, storing embeddings in the collections' tables using pgvector, or
+	// qdrant. Defaults to postgres.
+	Type   string       `mapstructure:"type"`
+	Qdrant QdrantConfig `mapstructure:"qdrant"`
+}
+
+type QdrantConfig struct {
+	// URL is the address of Qdrant's REST API, such as http://localhost:6333.
+	URL    string `mapstructure:"url"`
+	APIKey string `mapstructure:"api_key"`
+	// CollectionPrefix is prepended to the names of Zep's Qdrant collections, so that
+	// several deployments can share a Qdrant cluster.
+	CollectionPrefix string `mapstructure:"collection_prefix"`
+	// Timeout is the timeout of requests to Qdrant, in seconds. Defaults to 10.
+	Timeout int `mapstructure:"timeout"`
 }
 
 type LLM struct {
@@ -31,6 +190,31 @@ type LLM struct {
 	AzureOpenAIModel    AzureOpenAIConfig `mapstructure:"azure_openai"`
 	OpenAIEndpoint      string            `mapstructure:"openai_endpoint"`
 	OpenAIOrgID         string            `mapstructure:"openai_org_id"`
+	// OpenAIEmbeddingModel is the model of OpenAI embeddings. Defaults to
+	// text-embedding-ada-002.
+	OpenAIEmbeddingModel string `mapstructure:"openai_embedding_model"`
+	// Tenants lets each tenant use its own LLM and embedding provider.
+	Tenants TenantProvidersConfig `mapstructure:"tenants"`
+	// TLS applies to requests to the OpenAI service, including OpenAIEndpoint and
+	// AzureOpenAIEndpoint. It isn't supported by the anthropic service, nor applied to
+	// the endpoints of tenant overrides.
+	TLS TLSConfig `mapstructure:"tls"`
+}
+
+// TenantProvidersConfig lets each tenant override the LLM and embedding providers, so that
+// a host serving several tenants can honor each tenant's data processing agreement. The
+// tenant is named by a JWT claim or, if configured, a request header set by a gateway.
+type TenantProvidersConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Claim is the JWT claim naming the tenant. Defaults to "tenant".
+	Claim string `mapstructure:"claim"`
+	// Header is the request header naming the tenant of requests whose token has no tenant
+	// claim. Unset by default, as clients could otherwise use another tenant's provider.
+	Header string `mapstructure:"header"`
+	// EncryptionKey is the base64-encoded 32-byte key encrypting the stored overrides.
+	EncryptionKey string `mapstructure:"encryption_key"`
+	// CacheTTL is how long a tenant's clients are cached, in seconds. Defaults to 60.
+	CacheTTL int `mapstructure:"cache_ttl"`
 }
 
 type AzureOpenAIConfig struct {
@@ -40,15 +224,214 @@ type AzureOpenAIConfig struct {
 
 type NLP struct {
 	ServerURL string `mapstructure:"server_url"`
+	// TLS applies to requests to the NLP server, for local embeddings and entities.
+	TLS TLSConfig `mapstructure:"tls"`
+}
+
+// TLSConfig configures the TLS of requests to a provider, such as a self-hosted endpoint
+// whose certificate is issued by a private CA.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of CA certificates trusted in addition to the system's. It's
+	// read once, so changes take effect on restart.
+	CAFile string `mapstructure:"ca_file"`
+	// MinVersion is the minimum TLS version, "1.2" or "1.3". Defaults to 1.2.
+	MinVersion string `mapstructure:"min_version"`
 }
 
 type MemoryConfig struct {
 	MessageWindow int `mapstructure:"message_window"`
+	// MaxSessionMessages is a soft limit on the number of messages in a session. Once exceeded,
+	// the oldest messages are summarized and their content pruned. If set to 0, sessions are unbounded.
+	MaxSessionMessages int `mapstructure:"max_session_messages"`
+	// RetainCompactedEmbeddings keeps compacted messages and their embeddings searchable.
+	RetainCompactedEmbeddings bool `mapstructure:"retain_compacted_embeddings"`
+	// WriteBuffer coalesces bursts of message writes to a session into batched inserts.
+	WriteBuffer WriteBufferConfig `mapstructure:"write_buffer"`
+	// WriteHooks validate or transform memory before it's stored.
+	WriteHooks WriteHooksConfig `mapstructure:"write_hooks"`
+	// Roles registers the roles messages may have.
+	Roles RolesConfig `mapstructure:"roles"`
+	// Importance configures the use of the scores of the importance extractor.
+	Importance ImportanceConfig `mapstructure:"importance"`
+	// MMRLambda is the lambda of MMR message and summary searches that don't set one,
+	// from 0, favoring diversity, to 1, favoring relevance. Defaults to 0.5.
+	MMRLambda float32 `mapstructure:"mmr_lambda"`
+	// MessageRegex limits the regular expressions messages are listed or deleted by.
+	MessageRegex MessageRegexConfig `mapstructure:"message_regex"`
+}
+
+// MessageRegexConfig limits the regular expressions that filter messages by content, so
+// that an expensive expression can't tie up the database.
+type MessageRegexConfig struct {
+	// MaxComplexity is the most instructions an expression may compile to. Defaults to 1000.
+	MaxComplexity int `mapstructure:"max_complexity"`
+	// Timeout of a query filtering messages by an expression, in milliseconds. Defaults
+	// to 5000.
+	Timeout int `mapstructure:"timeout"`
+}
+
+// ImportanceConfig configures how message importance scores, from 0 to 1, are used.
+type ImportanceConfig struct {
+	// RetainThreshold keeps messages scoring at least this much when their session is
+	// compacted, so sessions may exceed max_session_messages by the number of messages
+	// kept. Zero keeps none.
+	RetainThreshold float64 `mapstructure:"retain_threshold"`
+	// SearchWeight is added to the similarity of message search results for each unit of
+	// importance when ranking them. Zero ranks by similarity alone.
+	SearchWeight float64 `mapstructure:"search_weight"`
+}
+
+// RolesConfig registers message roles. Written roles that are aliases of a registered
+// role are stored as the role's name. If no roles are registered, roles are stored as
+// written.
+type RolesConfig struct {
+	Registry []RoleConfig `mapstructure:"registry"`
+	// Enforce rejects messages whose role isn't a registered name or alias, rather than
+	// storing them with their role as written.
+	Enforce bool `mapstructure:"enforce"`
+	// ExcludeFromSummary lists the roles, or kinds of roles, whose messages are left out
+	// of session summaries.
+	ExcludeFromSummary []string `mapstructure:"exclude_from_summary"`
+	// ExcludeFromSearch lists the roles, or kinds of roles, whose messages aren't embedded
+	// or returned by message search.
+	ExcludeFromSearch []string `mapstructure:"exclude_from_search"`
+}
+
+// RoleConfig is a registered message role.
+type RoleConfig struct {
+	Name string `mapstructure:"name"`
+	// Kind is one of user, assistant, tool, or system, and tells extractors who a message
+	// of this role is from. Defaults to the kind of a common role of the same name, such
+	// as assistant for "ai".
+	Kind string `mapstructure:"kind"`
+	// Aliases are case-insensitive roles written by clients that are mapped to Name.
+	Aliases []string `mapstructure:"aliases"`
+}
+
+// WriteHooksConfig configures the hooks run over the memory written to a session. The
+// named hooks run in order, followed by the HTTP hook if its URL is set.
+type WriteHooksConfig struct {
+	// Hooks are the names of built-in hooks, such as drop_empty_messages and
+	// normalize_roles.
+	Hooks []string            `mapstructure:"hooks"`
+	HTTP  WriteHookHTTPConfig `mapstructure:"http"`
+}
+
+// WriteHookHTTPConfig configures a hook POSTing written memory to an external endpoint.
+type WriteHookHTTPConfig struct {
+	URL string `mapstructure:"url"`
+	// Secret signs requests, like webhook events. See the X-Zep-Signature header.
+	Secret string `mapstructure:"secret"`
+	// Timeout of a call, in milliseconds. Defaults to 5000.
+	Timeout int `mapstructure:"timeout"`
+	// FailOpen accepts writes unchanged if the endpoint can't be reached or fails, rather
+	// than failing them.
+	FailOpen bool `mapstructure:"fail_open"`
+}
+
+// WriteBufferConfig configures the buffering of memory writes. Messages written to a
+// session within FlushInterval of each other are inserted together.
+type WriteBufferConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// FlushInterval is how long a session's first buffered write waits for others, in
+	// milliseconds. Defaults to 5.
+	FlushInterval int `mapstructure:"flush_interval"`
+	// MaxMessages flushes a session's buffer early once it holds this many messages.
+	// Defaults to 100.
+	MaxMessages int `mapstructure:"max_messages"`
+	// Durability is either "sync" or "async". With "sync", the default, a write returns
+	// once its batch is inserted, so acknowledged writes are durable. With "async", a write
+	// returns once buffered: buffered messages are lost if the server crashes, write errors
+	// are only logged, and messages may not be read back until flushed.
+	Durability string `mapstructure:"durability"`
 }
 
 type PostgresConfig struct {
 	DSN              string           `mapstructure:"dsn"`
 	AvailableIndexes AvailableIndexes `mapstructure:"available_indexes"`
+	Ingest           IngestConfig     `mapstructure:"ingest"`
+	// PreparedStatements caches prepared statements for memory and document searches
+	// without metadata filters.
+	PreparedStatements bool `mapstructure:"prepared_statements"`
+	// Retry controls retries of writes that fail with transient errors.
+	Retry RetryConfig `mapstructure:"retry"`
+	// Warmup pre-warms indexes and recent sessions when the server starts.
+	Warmup WarmupConfig `mapstructure:"warmup"`
+	// DualWrite mirrors writes into the tables of another schema during a migration.
+	DualWrite DualWriteConfig `mapstructure:"dual_write"`
+	// DataMigrations runs the data migrations of an upgrade when the server starts.
+	DataMigrations DataMigrationsConfig `mapstructure:"data_migrations"`
+	// VectorIndex selects the vector index of the message and summary embeddings.
+	VectorIndex VectorIndexConfig `mapstructure:"vector_index"`
+}
+
+// VectorIndexConfig configures the pgvector indexes of the message and summary embeddings,
+// and the searches of HNSW indexes, including those of document collections.
+type VectorIndexConfig struct {
+	// Type is hnsw or ivfflat. Defaults to hnsw if the vector extension supports it, and
+	// otherwise ivfflat. IVFFlat indexes are built when the server starts, once the table
+	// has enough rows, with lists sized for its rows at the time.
+	Type string `mapstructure:"type"`
+	// EFSearch is the hnsw.ef_search set for each search of an HNSW index, the size of its
+	// candidate list. Higher values improve recall at the cost of latency. Defaults to 100.
+	EFSearch int `mapstructure:"ef_search"`
+}
+
+// DataMigrationsConfig controls the data migrations run after an upgrade, such as
+// backfills of new columns, which may take too long to run with the schema migrations.
+// They run in batches as an async task, resuming where they stopped if interrupted, and
+// can also be run with `zep migrate-data`.
+type DataMigrationsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// BatchSize is the number of rows migrated per transaction. Defaults to 1000.
+	BatchSize int `mapstructure:"batch_size"`
+}
+
+// DualWriteConfig controls dual writes for blue/green schema migrations. While enabled,
+// every row written to Zep's tables is also written to the table of the same name in
+// TargetSchema, whose tables are created by the new version of Zep. Columns are matched
+// by name, so columns added by the new version need defaults. Existing rows are copied
+// when dual writes are enabled, and `zep dual-write verify` compares the two schemas.
+type DualWriteConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	TargetSchema string `mapstructure:"target_schema"`
+	// Tables restricts dual writes to these tables. By default, every table of Zep's
+	// that exists in the target schema is written.
+	Tables []string `mapstructure:"tables"`
+}
+
+// WarmupConfig controls the warm-up run before the server starts accepting requests, so
+// that the first requests after a deploy or restart aren't served from a cold cache.
+type WarmupConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Sessions is the number of most recently updated sessions whose memory is read.
+	// Defaults to 100.
+	Sessions int `mapstructure:"sessions"`
+	// Timeout bounds the warm-up, in seconds. Startup continues once it's exceeded.
+	// Defaults to 60.
+	Timeout int `mapstructure:"timeout"`
+}
+
+// RetryConfig controls exponential backoff for writes failing with serialization failures,
+// deadlocks, or connection errors. Zero values use the store's defaults. Set MaxAttempts
+// to 1 to disable retries.
+type RetryConfig struct {
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// InitialInterval is the delay before the first retry, in milliseconds.
+	InitialInterval int `mapstructure:"initial_interval"`
+	// MaxInterval is the maximum delay between retries, in milliseconds.
+	MaxInterval int `mapstructure:"max_interval"`
+	// Jitter is the fraction of each delay, between 0 and 1, that is randomized.
+	Jitter float64 `mapstructure:"jitter"`
+}
+
+// IngestConfig controls how large document batches are split across workers on ingest.
+// Batches of at least CopyThreshold rows are inserted using COPY. A negative
+// CopyThreshold disables COPY.
+type IngestConfig struct {
+	BatchSize     int `mapstructure:"batch_size"`
+	Workers       int `mapstructure:"workers"`
+	CopyThreshold int `mapstructure:"copy_threshold"`
 }
 
 type AvailableIndexes struct {
@@ -61,6 +444,37 @@ type ServerConfig struct {
 	Port           int    `mapstructure:"port"`
 	WebEnabled     bool   `mapstructure:"web_enabled"`
 	MaxRequestSize int64  `mapstructure:"max_request_size"`
+	// ProfilingEnabled exposes pprof and runtime metrics at /debug, for tokens with the admin scope.
+	ProfilingEnabled bool `mapstructure:"profiling_enabled"`
+	// StrictJSON rejects API request bodies with unknown fields, rather than ignoring them.
+	StrictJSON bool `mapstructure:"strict_json"`
+	// V1Deprecation sets the Deprecation and Sunset headers of /api/v1 responses.
+	V1Deprecation APIDeprecationConfig `mapstructure:"v1_deprecation"`
+	Limits        LimitsConfig         `mapstructure:"limits"`
+}
+
+// LimitsConfig sets, for each kind of API request, the number of results returned if the
+// request doesn't set a limit, and the largest limit a request may set.
+type LimitsConfig struct {
+	MemorySearch   LimitConfig `mapstructure:"memory_search"`
+	DocumentSearch LimitConfig `mapstructure:"document_search"`
+	Messages       LimitConfig `mapstructure:"messages"`
+	// Pages applies to the other paginated lists, such as those of sessions and users.
+	Pages LimitConfig `mapstructure:"pages"`
+}
+
+// LimitConfig is the default and maximum limit of a request. Zero values fall back to
+// the server's built-in limits.
+type LimitConfig struct {
+	Default int `mapstructure:"default"`
+	Max     int `mapstructure:"max"`
+}
+
+// APIDeprecationConfig holds the dates, in RFC 3339 format, on which an API version was
+// deprecated and on which it will be removed. Either may be empty.
+type APIDeprecationConfig struct {
+	DeprecatedAt string `mapstructure:"deprecated_at"`
+	SunsetAt     string `mapstructure:"sunset_at"`
 }
 
 type LogConfig struct {
@@ -71,6 +485,43 @@ type OpenTelemetryConfig struct {
 	Enabled bool `mapstructure:"enabled"`
 }
 
+// MetricsConfig exposes Prometheus metrics at /metrics, including the service level
+// indicators of memory reads and writes and of searches.
+type MetricsConfig struct {
+	Enabled bool                `mapstructure:"enabled"`
+	SLO     SLOConfig           `mapstructure:"slo"`
+	Tenants TenantMetricsConfig `mapstructure:"tenants"`
+}
+
+// TenantMetricsConfig labels request metrics by tenant. Zep has no tenants of its own, so
+// the tenant is named by a JWT claim or, failing that, a request header set by a gateway.
+type TenantMetricsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Claim is the JWT claim naming the tenant. Defaults to "tenant".
+	Claim string `mapstructure:"claim"`
+	// Header is the request header naming the tenant. Defaults to "X-Zep-Tenant". Only
+	// trust it if a gateway sets it, as clients can set it to anything.
+	Header string `mapstructure:"header"`
+	// Allow lists the tenants that get their own label. If empty, any tenant does, up to
+	// MaxTenants. Other tenants are labeled "other".
+	Allow []string `mapstructure:"allow"`
+	// MaxTenants caps the number of tenant labels. Defaults to 100.
+	MaxTenants int `mapstructure:"max_tenants"`
+}
+
+// SLOConfig sets the service level objectives used to generate alerting rules. Zero
+// values use the defaults.
+type SLOConfig struct {
+	// Availability is the target fraction of memory and search requests that don't fail
+	// with a server error. Defaults to 0.999.
+	Availability float64 `mapstructure:"availability"`
+	// SearchLatency is the target fraction of successful searches completing within
+	// SearchLatencyThreshold. Defaults to 0.99.
+	SearchLatency float64 `mapstructure:"search_latency"`
+	// SearchLatencyThreshold is in milliseconds. Defaults to 500.
+	SearchLatencyThreshold int `mapstructure:"search_latency_threshold"`
+}
+
 type AuthConfig struct {
 	Secret   string `mapstructure:"secret"`
 	Required bool   `mapstructure:"required"`
@@ -79,7 +530,155 @@ type AuthConfig struct {
 type DataConfig struct {
 	// PurgeEvery is the period between hard deletes, in minutes.
 	// If set to 0, hard deletes will not be performed.
-	PurgeEvery int `mapstructure:"purge_every"`
+	PurgeEvery     int                  `mapstructure:"purge_every"`
+	SessionExpiry  SessionExpiryConfig  `mapstructure:"session_expiry"`
+	Deduplication  DeduplicationConfig  `mapstructure:"deduplication"`
+	Topics         TopicsConfig         `mapstructure:"topics"`
+	Reflection     ReflectionConfig     `mapstructure:"reflection"`
+	EmbeddingDrift EmbeddingDriftConfig `mapstructure:"embedding_drift"`
+}
+
+// SchedulerConfig schedules the background jobs, keyed by job name. The purge,
+// session_expiry, deduplication, topics, reflection, and embedding_drift jobs fall back to
+// their intervals in DataConfig if they aren't listed.
+type SchedulerConfig struct {
+	Jobs map[string]ScheduledJobConfig `mapstructure:"jobs"`
+}
+
+type ScheduledJobConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Schedule is a five-field cron expression, such as "*/15 * * * *", a descriptor
+	// such as "@daily", or an interval such as "@every 30m".
+	Schedule string `mapstructure:"schedule"`
+}
+
+type TopicsConfig struct {
+	// RunEvery is the period between topic modeling runs, in minutes. Each run clusters
+	// the message embeddings of users with new messages and labels the clusters using
+	// the LLM. If set to 0, topics are not modeled.
+	RunEvery int `mapstructure:"run_every"`
+	// MaxTopics is the maximum number of topics per user. Defaults to 8.
+	MaxTopics int `mapstructure:"max_topics"`
+	// MinTopicSize is the fewest messages a cluster needs to be kept as a topic.
+	// Defaults to 3.
+	MinTopicSize int `mapstructure:"min_topic_size"`
+	// MaxMessages limits the messages clustered per user, taken from their most recent
+	// sessions first. Defaults to 2000.
+	MaxMessages int `mapstructure:"max_messages"`
+}
+
+type ReflectionConfig struct {
+	// RunEvery is the period between reflection runs, in minutes. Each run reviews the
+	// important messages users sent since their last reflection, and has the LLM
+	// synthesize them into facts about the user. If set to 0, users are not reflected on.
+	RunEvery int `mapstructure:"run_every"`
+	// MinImportance is the importance score, from 0 to 1, a message needs to be reflected
+	// on. Requires the message_importance extractor. Defaults to 0.6.
+	MinImportance float64 `mapstructure:"min_importance"`
+	// MaxMessages limits the messages read per session, taken from the most recent.
+	// Defaults to 200.
+	MaxMessages int `mapstructure:"max_messages"`
+	// MaxFacts is the maximum number of facts kept per user. Defaults to 20.
+	MaxFacts int `mapstructure:"max_facts"`
+}
+
+type EmbeddingDriftConfig struct {
+	// RunEvery is the period between embedding drift checks, in minutes. Each check
+	// embeds the text of a sample of stored message embeddings again, and alerts if the
+	// fresh embeddings differ from those stored, such as after a change of embedding
+	// model or service. If set to 0, drift is not checked periodically.
+	RunEvery int `mapstructure:"run_every"`
+	// CheckOnStartup checks for drift once when the server starts.
+	CheckOnStartup bool `mapstructure:"check_on_startup"`
+	// SampleSize is the number of stored embeddings checked, each from a different
+	// session. Defaults to 20.
+	SampleSize int `mapstructure:"sample_size"`
+	// MinSimilarity is the lowest mean cosine similarity, between the stored and fresh
+	// embeddings, that isn't drift. Defaults to 0.95.
+	MinSimilarity float64 `mapstructure:"min_similarity"`
+}
+
+type DeduplicationConfig struct {
+	// RunEvery is the period between near-duplicate message embedding checks, in minutes.
+	// If set to 0, messages are not deduplicated.
+	RunEvery int `mapstructure:"run_every"`
+	// SimilarityThreshold is the cosine similarity at or above which two messages are
+	// considered near-duplicates.
+	SimilarityThreshold float32 `mapstructure:"similarity_threshold"`
+	// Scope is either "session" or "user". Defaults to "session".
+	Scope string `mapstructure:"scope"`
+}
+
+type SessionExpiryConfig struct {
+	// CheckEvery is the period between checks for expiring sessions, in minutes.
+	// If set to 0, sessions are never expired.
+	CheckEvery int `mapstructure:"check_every"`
+	// WarnBefore is how long before a session's expires_at a session.expiring
+	// webhook is sent, in minutes. If set to 0, no warning is sent.
+	WarnBefore int `mapstructure:"warn_before"`
+}
+
+type WebhooksConfig struct {
+	// URL is the endpoint webhook events are POSTed to. Webhooks are disabled if empty.
+	URL string `mapstructure:"url"`
+	// Secret is used to sign webhook payloads. See the X-Zep-Signature header.
+	Secret string `mapstructure:"secret"`
+}
+
+// EgressConfig routes outbound HTTP requests through a proxy and restricts the hosts they
+// may reach. It applies to the clients of LLM and embedding services, webhooks, write
+// hooks and the Qdrant and OpenSearch stores. Host patterns are a host name or IP, or
+// *. followed by a domain, which matches the domain's subdomains.
+type EgressConfig struct {
+	// ProxyURL is the proxy requests are sent through, such as http://proxy:3128. If
+	// empty, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used.
+	ProxyURL string `mapstructure:"proxy_url"`
+	// NoProxy lists host patterns reached directly rather than through ProxyURL.
+	NoProxy []string `mapstructure:"no_proxy"`
+	// AllowedHosts lists the host patterns requests may be sent to. Requests to other
+	// hosts fail without being sent. If empty, any host may be reached.
+	AllowedHosts []string `mapstructure:"allowed_hosts"`
+}
+
+// FaultInjectionConfig adds latency and errors to memory, document, and user store calls
+// and to LLM calls, for testing client retries and the resilience of the extractors.
+// It is ignored unless Development is true.
+type FaultInjectionConfig struct {
+	Enabled bool        `mapstructure:"enabled"`
+	Store   FaultConfig `mapstructure:"store"`
+	LLM     FaultConfig `mapstructure:"llm"`
+	// Seed seeds the random source deciding which calls are faulted. If 0, a random seed
+	// is used.
+	Seed int64 `mapstructure:"seed"`
+}
+
+// FaultConfig sets the rates at which faults are injected into calls.
+type FaultConfig struct {
+	// ErrorRate is the fraction of calls, between 0 and 1, that fail without being made.
+	ErrorRate float64 `mapstructure:"error_rate"`
+	// LatencyRate is the fraction of calls, between 0 and 1, that are delayed.
+	LatencyRate float64 `mapstructure:"latency_rate"`
+	// Latency is the maximum delay of a delayed call, in milliseconds. Delays are
+	// uniformly distributed up to Latency.
+	Latency int `mapstructure:"latency"`
+}
+
+// ResourcesConfig declares resources that should exist. Webhooks, retention, and auth
+// are configured by their own sections, so only document collections are declared here.
+type ResourcesConfig struct {
+	Collections []CollectionResource `mapstructure:"collections"`
+}
+
+// CollectionResource declares a document collection. EmbeddingDimensions,
+// IsAutoEmbedded and ContextualChunkHeaders can't be changed once the collection is
+// created.
+type CollectionResource struct {
+	Name                   string                 `mapstructure:"name"`
+	Description            string                 `mapstructure:"description"`
+	Metadata               map[string]interface{} `mapstructure:"metadata"`
+	EmbeddingDimensions    int                    `mapstructure:"embedding_dimensions"`
+	IsAutoEmbedded         bool                   `mapstructure:"is_auto_embedded"`
+	ContextualChunkHeaders bool                   `mapstructure:"contextual_chunk_headers"`
 }
 
 type ExtractorsConfig struct {
@@ -89,24 +688,65 @@ type ExtractorsConfig struct {
 
 // MessageExtractorsConfig holds the configuration for all extractors
 type MessageExtractorsConfig struct {
-	Summarizer SummarizerConfig      `mapstructure:"summarizer"`
-	Embeddings EmbeddingsConfig      `mapstructure:"embeddings"`
-	Entities   EntityExtractorConfig `mapstructure:"entities"`
-	Intent     IntentExtractorConfig `mapstructure:"intent"`
+	Summarizer     SummarizerConfig             `mapstructure:"summarizer"`
+	Embeddings     EmbeddingsConfig             `mapstructure:"embeddings"`
+	Entities       EntityExtractorConfig        `mapstructure:"entities"`
+	Intent         IntentExtractorConfig        `mapstructure:"intent"`
+	Title          TitleExtractorConfig         `mapstructure:"title"`
+	FollowUp       FollowUpExtractorConfig      `mapstructure:"follow_up"`
+	TaskState      TaskStateExtractorConfig     `mapstructure:"task_state"`
+	Enrichment     EnrichmentExtractorConfig    `mapstructure:"enrichment"`
+	Episodes       EpisodeExtractorConfig       `mapstructure:"episodes"`
+	Importance     ImportanceExtractorConfig    `mapstructure:"importance"`
+	Contradictions ContradictionExtractorConfig `mapstructure:"contradictions"`
 }
 
 type DocumentExtractorsConfig struct {
-	Embeddings EmbeddingsConfig `mapstructure:"embeddings"`
+	Embeddings EmbeddingsConfig         `mapstructure:"embeddings"`
+	Summarizer DocumentSummarizerConfig `mapstructure:"summarizer"`
+}
+
+// DocumentSummarizerConfig configures the summarization of documents as they're created.
+type DocumentSummarizerConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MinLength is the shortest document content, in characters, that is summarized.
+	// Shorter documents are left unsummarized, as a summary would save little.
+	// Defaults to 500.
+	MinLength int              `mapstructure:"min_length"`
+	Workers   WorkerPoolConfig `mapstructure:"workers"`
 }
 
 type SummarizerConfig struct {
 	Enabled    bool                  `mapstructure:"enabled"`
 	Embeddings EmbeddingsConfig      `mapstructure:"embeddings"`
 	Entities   EntityExtractorConfig `mapstructure:"entities"`
+	Workers    WorkerPoolConfig      `mapstructure:"workers"`
+}
+
+// WorkerPoolConfig sizes the worker pool processing an extractor's task queue.
+// Zero values fall back to the defaults in the tasks package.
+type WorkerPoolConfig struct {
+	// Concurrency is the number of tasks processed concurrently. Defaults to 1, in which
+	// case tasks are processed synchronously and acknowledged once complete.
+	Concurrency int `mapstructure:"concurrency"`
+	// BatchSize is the number of queued tasks fetched from the database at once.
+	BatchSize int `mapstructure:"batch_size"`
+	// QueueLength is the number of fetched tasks buffered in memory waiting for a worker.
+	// Only used if Concurrency is greater than 1. Defaults to Concurrency.
+	QueueLength int `mapstructure:"queue_length"`
+	// RateLimit is the maximum number of tasks started per second. If 0, only the
+	// router-wide limit applies.
+	RateLimit int `mapstructure:"rate_limit"`
 }
 
 type CustomPromptsConfig struct {
 	SummarizerPrompts ExtractorPromptsConfig `mapstructure:"summarizer_prompts"`
+	// FollowUpPrompt replaces the default follow-up questions prompt. It must include
+	// {{.MessagesJoined}}.
+	FollowUpPrompt string `mapstructure:"follow_up_prompt"`
+	// DocumentSummaryPrompt replaces the default document summary prompt. It must include
+	// {{.Content}}.
+	DocumentSummaryPrompt string `mapstructure:"document_summary_prompt"`
 }
 
 type ExtractorPromptsConfig struct {
@@ -120,12 +760,117 @@ type EmbeddingsConfig struct {
 	Service    string `mapstructure:"service"`
 	// ChunkSize is the number of documents to embed in a single task.
 	ChunkSize int `mapstructure:"chunk_size"`
+	// Quantization is one of "halfvec" or "bit". Requires pgvector >= 0.7.0.
+	// Only documents support "bit". Leave empty to store full precision vectors.
+	Quantization string `mapstructure:"quantization"`
+	// DistanceFunction is one of "cosine", "dot_product" or "l2", selecting the pgvector
+	// operator searches use and the operator class of the indexes. Defaults to cosine. For
+	// documents, it's the default of new collections.
+	DistanceFunction string `mapstructure:"distance_function"`
+	// Matryoshka truncates embeddings to Dimensions and re-normalizes them. Only use with
+	// models trained to support truncation, such as OpenAI's text-embedding-3 models.
+	Matryoshka bool `mapstructure:"matryoshka"`
+	// Window embeds each message together with the Window-1 messages preceding it, so
+	// that short turns such as "yes" are embedded with their context. Consecutive windows
+	// overlap by Window-1 messages. Only applies to message embeddings; 0 or 1 embeds
+	// messages individually.
+	Window int `mapstructure:"window"`
+	// TurnPairs embeds each user message together with the assistant's reply, which is
+	// returned with the user message by search. Only applies to message embeddings, and
+	// can't be combined with Window.
+	TurnPairs bool `mapstructure:"turn_pairs"`
+	// Normalize strips boilerplate from texts before they're embedded, and from the text
+	// of searches.
+	Normalize TextNormalizationConfig `mapstructure:"normalize"`
+	Workers   WorkerPoolConfig        `mapstructure:"workers"`
+}
+
+// TextNormalizationConfig configures the boilerplate stripped from texts before they're
+// embedded, such as the greetings, signatures, and disclaimers of support emails. A text
+// left empty is embedded as is.
+type TextNormalizationConfig struct {
+	// Greetings strips an opening salutation line, such as "Hi team,".
+	Greetings bool `mapstructure:"greetings"`
+	// Signatures strips a sign-off line, such as "Best regards,", a "-- " signature
+	// delimiter, or a "Sent from my" line, and everything after it.
+	Signatures bool `mapstructure:"signatures"`
+	// Patterns are regular expressions whose matches are stripped, such as a disclaimer.
+	Patterns []string `mapstructure:"patterns"`
 }
 
 type EntityExtractorConfig struct {
-	Enabled bool `mapstructure:"enabled"`
+	Enabled bool             `mapstructure:"enabled"`
+	Workers WorkerPoolConfig `mapstructure:"workers"`
 }
 
 type IntentExtractorConfig struct {
+	Enabled bool             `mapstructure:"enabled"`
+	Workers WorkerPoolConfig `mapstructure:"workers"`
+}
+
+// TitleExtractorConfig configures the generation of short session titles.
+type TitleExtractorConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MinMessages is the number of messages a session must have before a title is
+	// generated. Defaults to 4.
+	MinMessages int `mapstructure:"min_messages"`
+	// RefreshEvery regenerates the title each time the session grows by this many
+	// messages, so that it tracks a drifting conversation. Zero never refreshes a title.
+	RefreshEvery int              `mapstructure:"refresh_every"`
+	Workers      WorkerPoolConfig `mapstructure:"workers"`
+}
+
+// FollowUpExtractorConfig configures the suggestion of follow-up questions after each
+// assistant message.
+type FollowUpExtractorConfig struct {
+	Enabled bool             `mapstructure:"enabled"`
+	Workers WorkerPoolConfig `mapstructure:"workers"`
+}
+
+// TaskStateExtractorConfig configures the tracking of a session's task state: its goal,
+// the steps done, and any blockers. The state is returned with the session's memory.
+type TaskStateExtractorConfig struct {
+	Enabled bool             `mapstructure:"enabled"`
+	Workers WorkerPoolConfig `mapstructure:"workers"`
+}
+
+// EpisodeExtractorConfig configures the segmentation of sessions into episodes at each
+// shift of topic. Each episode is summarized once it ends.
+type EpisodeExtractorConfig struct {
+	Enabled bool             `mapstructure:"enabled"`
+	Workers WorkerPoolConfig `mapstructure:"workers"`
+}
+
+// ImportanceExtractorConfig configures the scoring of each new message's long-term
+// importance, stored under system.importance.
+type ImportanceExtractorConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Scorer is either "heuristic", the default, or "llm". The heuristic scorer weighs
+	// directives, such as "remember" or "from now on", emotional language, and novelty
+	// relative to recent messages, without calling the LLM.
+	Scorer  string           `mapstructure:"scorer"`
+	Workers WorkerPoolConfig `mapstructure:"workers"`
+}
+
+// ContradictionExtractorConfig configures the detection of new messages contradicting the
+// facts synthesized about a session's user. Conflicts are recorded on the fact.
+type ContradictionExtractorConfig struct {
 	Enabled bool `mapstructure:"enabled"`
+	// Resolve has the LLM resolve each conflict as it's detected, by revising or
+	// retracting the fact, or keeping it. Otherwise conflicts are left unresolved, and
+	// the facts disputed, until the user is next reflected on.
+	Resolve bool             `mapstructure:"resolve"`
+	Workers WorkerPoolConfig `mapstructure:"workers"`
+}
+
+// EnrichmentExtractorConfig configures the enrichment of new messages by an external
+// service, which returns metadata to store with each message.
+type EnrichmentExtractorConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	URL     string `mapstructure:"url"`
+	// Secret signs requests, like webhook events. See the X-Zep-Signature header.
+	Secret string `mapstructure:"secret"`
+	// Timeout of a request, in seconds. Defaults to 10.
+	Timeout int              `mapstructure:"timeout"`
+	Workers WorkerPoolConfig `mapstructure:"workers"`
 }
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+)
+
+func TestCollectionTokens(t *testing.T) {
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Secret: "test-secret",
+		},
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router := chi.NewRouter()
+	router.Use(JWTVerifier(cfg))
+	router.Use(jwtauth.Authenticator)
+	router.With(DenyCollectionTokens).Get("/sessions", ok)
+	router.Route("/collection/{collectionName}", func(r chi.Router) {
+		r.With(AllowCollectionToken("collectionName")).Post("/search", ok)
+		r.With(DenyCollectionTokens).Post("/document", ok)
+	})
+
+	docsToken := GenerateCollectionJWT(cfg, "Docs")
+	unscopedToken := GenerateJWT(cfg)
+	const (
+		get  = http.MethodGet
+		post = http.MethodPost
+	)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"search own collection", post, "/collection/docs/search", docsToken, http.StatusOK},
+		{"search other collection", post, "/collection/faq/search", docsToken, http.StatusForbidden},
+		{"modify own collection", post, "/collection/docs/document", docsToken, http.StatusForbidden},
+		{"read memory", get, "/sessions", docsToken, http.StatusForbidden},
+		{"unscoped search", post, "/collection/faq/search", unscopedToken, http.StatusOK},
+		{"unscoped modify", post, "/collection/docs/document", unscopedToken, http.StatusOK},
+		{"unscoped memory", get, "/sessions", unscopedToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			res := httptest.NewRecorder()
+
+			router.ServeHTTP(res, req)
+
+			require.Equal(t, tt.want, res.Code)
+		})
+	}
+}
+
+func TestCollectionTokensWithoutAuth(t *testing.T) {
+	router := chi.NewRouter()
+	router.With(DenyCollectionTokens).
+		Get("/sessions", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/sessions", nil))
+
+	require.Equal(t, http.StatusOK, res.Code)
+}
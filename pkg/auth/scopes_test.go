@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+)
+
+func TestRequireScope(t *testing.T) {
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Secret: "test-secret",
+		},
+	}
+	router := chi.NewRouter()
+	router.Use(JWTVerifier(cfg))
+	router.Use(jwtauth.Authenticator)
+	router.Use(RequireScope(ScopeAdmin))
+	router.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"admin token", GenerateScopedJWT(cfg, ScopeAdmin), http.StatusOK},
+		{"unscoped token", GenerateJWT(cfg), http.StatusForbidden},
+		{"other scope", GenerateScopedJWT(cfg, "read"), http.StatusForbidden},
+		{"invalid token", "invalid-token", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			res := httptest.NewRecorder()
+
+			router.ServeHTTP(res, req)
+
+			require.Equal(t, tt.want, res.Code)
+		})
+	}
+}
@@ -0,0 +1,255 @@
+// Package bench generates synthetic sessions, messages, and document collections and
+// measures ingest throughput and search latency against a running Zep deployment.
+package bench
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+var log = internal.GetLogger()
+
+const (
+	DefaultSessions               = 10
+	DefaultMessagesPerSession     = 100
+	DefaultMessageBatchSize       = 10
+	DefaultCollections            = 1
+	DefaultDocumentsPerCollection = 1000
+	DefaultDocumentBatchSize      = 100
+	DefaultSearches               = 200
+	DefaultSearchLimit            = 10
+	DefaultConcurrency            = 8
+	DefaultTimeout                = 30 * time.Second
+)
+
+// Config configures a load test run.
+type Config struct {
+	BaseURL string
+	APIKey  string
+
+	Sessions           int
+	MessagesPerSession int
+	// MessageBatchSize is the number of messages sent in each memory request.
+	MessageBatchSize int
+
+	Collections            int
+	DocumentsPerCollection int
+	DocumentBatchSize      int
+	// EmbeddingDimensions is the width of document embeddings. If AutoEmbed is set, this
+	// must match the document embedding dimensions configured on the server.
+	EmbeddingDimensions int
+	// AutoEmbed creates auto-embedded collections, embedded by the server. Otherwise,
+	// documents are created with random embeddings and searched by embedding.
+	AutoEmbed bool
+
+	// Searches is the number of memory and document searches run.
+	Searches    int
+	SearchLimit int
+	Concurrency int
+	Timeout     time.Duration
+	Seed        int64
+	// Cleanup deletes the generated sessions and collections after the run.
+	Cleanup bool
+}
+
+func (c *Config) setDefaults() {
+	setDefault(&c.Sessions, DefaultSessions)
+	setDefault(&c.MessagesPerSession, DefaultMessagesPerSession)
+	setDefault(&c.MessageBatchSize, DefaultMessageBatchSize)
+	setDefault(&c.Collections, DefaultCollections)
+	setDefault(&c.DocumentsPerCollection, DefaultDocumentsPerCollection)
+	setDefault(&c.DocumentBatchSize, DefaultDocumentBatchSize)
+	setDefault(&c.Searches, DefaultSearches)
+	setDefault(&c.SearchLimit, DefaultSearchLimit)
+	setDefault(&c.Concurrency, DefaultConcurrency)
+	setDefault(&c.EmbeddingDimensions, 384)
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+}
+
+func setDefault(v *int, d int) {
+	if *v <= 0 {
+		*v = d
+	}
+}
+
+// Report is the result of a load test run.
+type Report struct {
+	Summaries []Summary `json:"summaries"`
+}
+
+// Run runs the load test, ingesting messages and documents and then running memory and
+// document searches. Each phase runs with Config.Concurrency concurrent requests.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("base URL is required")
+	}
+	cfg.setDefaults()
+
+	client := NewClient(cfg.BaseURL, cfg.APIKey, cfg.Timeout)
+	gen := NewGenerator(cfg.Seed)
+
+	sessionIDs := make([]string, cfg.Sessions)
+	for i := range sessionIDs {
+		sessionIDs[i] = gen.Name("bench_")
+	}
+	collectionNames := make([]string, cfg.Collections)
+	for i := range collectionNames {
+		collectionNames[i] = gen.Name("bench")
+	}
+
+	if cfg.Cleanup {
+		defer cleanup(client, sessionIDs, collectionNames)
+	}
+
+	report := &Report{}
+
+	// Ingest messages
+	var jobs []func(context.Context) (int, error)
+	for _, sessionID := range sessionIDs {
+		sessionID := sessionID
+		if err := client.CreateSession(ctx, sessionID); err != nil {
+			return nil, err
+		}
+		messages := gen.Messages(cfg.MessagesPerSession)
+		for _, batch := range batches(messages, cfg.MessageBatchSize) {
+			batch := batch
+			jobs = append(jobs, func(ctx context.Context) (int, error) {
+				err := client.PutMemory(ctx, sessionID, &models.Memory{Messages: batch})
+				return len(batch), err
+			})
+		}
+	}
+	report.Summaries = append(report.Summaries, runPhase(ctx, "memory_ingest", cfg.Concurrency, jobs))
+
+	// Ingest documents
+	jobs = nil
+	dimensions := cfg.EmbeddingDimensions
+	if cfg.AutoEmbed {
+		// auto-embedded collections may not be given embeddings
+		dimensions = 0
+	}
+	for _, name := range collectionNames {
+		name := name
+		isAutoEmbedded := cfg.AutoEmbed
+		err := client.CreateCollection(ctx, &models.CreateDocumentCollectionRequest{
+			Name:                name,
+			Description:         "load test collection",
+			EmbeddingDimensions: cfg.EmbeddingDimensions,
+			IsAutoEmbedded:      &isAutoEmbedded,
+		})
+		if err != nil {
+			return nil, err
+		}
+		documents := gen.Documents(cfg.DocumentsPerCollection, dimensions)
+		for _, batch := range batches(documents, cfg.DocumentBatchSize) {
+			batch := batch
+			jobs = append(jobs, func(ctx context.Context) (int, error) {
+				return len(batch), client.CreateDocuments(ctx, name, batch)
+			})
+		}
+	}
+	report.Summaries = append(report.Summaries, runPhase(ctx, "document_ingest", cfg.Concurrency, jobs))
+
+	// Search memory
+	jobs = nil
+	for i := 0; i < cfg.Searches && len(sessionIDs) > 0; i++ {
+		sessionID := sessionIDs[i%len(sessionIDs)]
+		payload := &models.MemorySearchPayload{Text: gen.Query()}
+		jobs = append(jobs, func(ctx context.Context) (int, error) {
+			_, err := client.SearchMemory(ctx, sessionID, payload, cfg.SearchLimit)
+			return 1, err
+		})
+	}
+	report.Summaries = append(report.Summaries, runPhase(ctx, "memory_search", cfg.Concurrency, jobs))
+
+	// Search documents
+	jobs = nil
+	for i := 0; i < cfg.Searches && len(collectionNames) > 0; i++ {
+		name := collectionNames[i%len(collectionNames)]
+		payload := &models.DocumentSearchPayload{CollectionName: name}
+		if cfg.AutoEmbed {
+			payload.Text = gen.Query()
+		} else {
+			payload.Embedding = gen.Embedding(cfg.EmbeddingDimensions)
+		}
+		jobs = append(jobs, func(ctx context.Context) (int, error) {
+			return 1, client.SearchDocuments(ctx, name, payload, cfg.SearchLimit)
+		})
+	}
+	report.Summaries = append(report.Summaries, runPhase(ctx, "document_search", cfg.Concurrency, jobs))
+
+	return report, nil
+}
+
+// runPhase runs the jobs with the given concurrency, recording the latency of each.
+func runPhase(
+	ctx context.Context,
+	name string,
+	concurrency int,
+	jobs []func(context.Context) (int, error),
+) Summary {
+	recorder := NewRecorder(name)
+	queue := make(chan func(context.Context) (int, error))
+
+	var wg sync.WaitGroup
+	recorder.Start()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				start := time.Now()
+				items, err := job(ctx)
+				if err != nil {
+					log.Debugf("%s request failed: %v", name, err)
+				}
+				recorder.Record(time.Since(start), items, err)
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+	recorder.Stop()
+
+	return recorder.Summary()
+}
+
+func cleanup(client *Client, sessionIDs, collectionNames []string) {
+	ctx := context.Background()
+	for _, sessionID := range sessionIDs {
+		if err := client.DeleteSession(ctx, sessionID); err != nil {
+			log.Warnf("failed to delete session %s: %v", sessionID, err)
+		}
+	}
+	for _, name := range collectionNames {
+		if err := client.DeleteCollection(ctx, name); err != nil {
+			log.Warnf("failed to delete collection %s: %v", name, err)
+		}
+	}
+}
+
+func batches[T any](items []T, size int) [][]T {
+	var chunks [][]T
+	for i := 0; i < len(items); i += size {
+		end := i + size
+		if end > len(items) {
+			end = len(items)
+		}
+		chunks = append(chunks, items[i:end])
+	}
+	return chunks
+}
@@ -0,0 +1,123 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, 50*time.Millisecond, Percentile(latencies, 50))
+	assert.Equal(t, 90*time.Millisecond, Percentile(latencies, 90))
+	assert.Equal(t, 99*time.Millisecond, Percentile(latencies, 99))
+	assert.Equal(t, 100*time.Millisecond, Percentile(latencies, 100))
+	assert.Equal(t, time.Duration(0), Percentile(nil, 50))
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder("op")
+	r.Start()
+	r.Record(2*time.Millisecond, 10, nil)
+	r.Record(1*time.Millisecond, 10, nil)
+	r.Record(5*time.Millisecond, 10, errors.New("failed"))
+	r.Stop()
+
+	s := r.Summary()
+	assert.Equal(t, 3, s.Requests)
+	assert.Equal(t, 1, s.Errors)
+	assert.Equal(t, 20, s.Items)
+	assert.Equal(t, 1*time.Millisecond, s.P50)
+	assert.Equal(t, 2*time.Millisecond, s.Max)
+	assert.Greater(t, s.ItemsPerSec, 0.0)
+}
+
+func TestRun(t *testing.T) {
+	var messages, documents, searches atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		path := r.URL.Path
+		switch {
+		case strings.HasSuffix(path, "/memory"):
+			var memory models.Memory
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&memory))
+			messages.Add(int64(len(memory.Messages)))
+		case strings.HasSuffix(path, "/document"):
+			var docs []models.CreateDocumentRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&docs))
+			for _, d := range docs {
+				assert.Len(t, d.Embedding, 8)
+			}
+			documents.Add(int64(len(docs)))
+		case strings.HasSuffix(path, "/search"):
+			searches.Add(1)
+			_, _ = w.Write([]byte("[]"))
+		}
+	}))
+	defer server.Close()
+
+	report, err := Run(context.Background(), Config{
+		BaseURL:                server.URL,
+		APIKey:                 "key",
+		Sessions:               3,
+		MessagesPerSession:     20,
+		MessageBatchSize:       5,
+		Collections:            2,
+		DocumentsPerCollection: 30,
+		DocumentBatchSize:      10,
+		EmbeddingDimensions:    8,
+		Searches:               10,
+		Concurrency:            4,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(60), messages.Load())
+	assert.Equal(t, int64(60), documents.Load())
+	assert.Equal(t, int64(20), searches.Load())
+
+	require.Len(t, report.Summaries, 4)
+	assert.Equal(t, 12, report.Summaries[0].Requests)
+	assert.Equal(t, 60, report.Summaries[0].Items)
+	assert.Equal(t, 6, report.Summaries[1].Requests)
+	for _, s := range report.Summaries {
+		assert.Zero(t, s.Errors, s.Name)
+	}
+}
+
+// BenchmarkLoad runs the load test against the deployment at ZEP_BENCH_URL.
+func BenchmarkLoad(b *testing.B) {
+	url := os.Getenv("ZEP_BENCH_URL")
+	if url == "" {
+		b.Skip("ZEP_BENCH_URL is not set")
+	}
+
+	for i := 0; i < b.N; i++ {
+		report, err := Run(context.Background(), Config{
+			BaseURL: url,
+			APIKey:  os.Getenv("ZEP_BENCH_API_KEY"),
+			Seed:    int64(i),
+			Cleanup: true,
+		})
+		require.NoError(b, err)
+		for _, s := range report.Summaries {
+			b.ReportMetric(s.ItemsPerSec, s.Name+"_items/s")
+			b.ReportMetric(float64(s.P99.Microseconds()), s.Name+"_p99_us")
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// Client is a minimal client for the Zep API endpoints exercised by the load test.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL, apiKey string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/") + "/api/v1",
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Client) CreateSession(ctx context.Context, sessionID string) error {
+	return c.do(ctx, http.MethodPost, "/sessions", &models.CreateSessionRequest{
+		SessionID: sessionID,
+	}, nil)
+}
+
+func (c *Client) DeleteSession(ctx context.Context, sessionID string) error {
+	return c.do(ctx, http.MethodDelete, "/sessions/"+url.PathEscape(sessionID)+"/memory", nil, nil)
+}
+
+func (c *Client) PutMemory(ctx context.Context, sessionID string, memory *models.Memory) error {
+	return c.do(ctx, http.MethodPost, "/sessions/"+url.PathEscape(sessionID)+"/memory", memory, nil)
+}
+
+func (c *Client) SearchMemory(
+	ctx context.Context,
+	sessionID string,
+	payload *models.MemorySearchPayload,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	var results []models.MemorySearchResult
+	path := fmt.Sprintf("/sessions/%s/search?limit=%d", url.PathEscape(sessionID), limit)
+	err := c.do(ctx, http.MethodPost, path, payload, &results)
+	return results, err
+}
+
+func (c *Client) CreateCollection(
+	ctx context.Context,
+	request *models.CreateDocumentCollectionRequest,
+) error {
+	return c.do(ctx, http.MethodPost, "/collection/"+url.PathEscape(request.Name), request, nil)
+}
+
+func (c *Client) DeleteCollection(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/collection/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) CreateDocuments(
+	ctx context.Context,
+	collectionName string,
+	documents []models.CreateDocumentRequest,
+) error {
+	return c.do(
+		ctx,
+		http.MethodPost,
+		"/collection/"+url.PathEscape(collectionName)+"/document",
+		documents,
+		nil,
+	)
+}
+
+func (c *Client) SearchDocuments(
+	ctx context.Context,
+	collectionName string,
+	payload *models.DocumentSearchPayload,
+	limit int,
+) error {
+	path := fmt.Sprintf("/collection/%s/search?limit=%d", url.PathEscape(collectionName), limit)
+	return c.do(ctx, http.MethodPost, path, payload, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if result == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
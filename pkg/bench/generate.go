@@ -0,0 +1,74 @@
+package bench
+
+import (
+	"math/rand"
+
+	"github.com/brianvoe/gofakeit/v6"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// Generator creates synthetic sessions, messages, and documents. Generators with the same
+// seed create the same data.
+type Generator struct {
+	faker *gofakeit.Faker
+	rand  *rand.Rand
+}
+
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		faker: gofakeit.New(seed),
+		rand:  rand.New(rand.NewSource(seed)), //nolint:gosec
+	}
+}
+
+func (g *Generator) Name(prefix string) string {
+	return prefix + g.faker.LetterN(12)
+}
+
+// Messages returns n messages alternating between human and ai roles.
+func (g *Generator) Messages(n int) []models.Message {
+	messages := make([]models.Message, n)
+	for i := range messages {
+		role := "human"
+		if i%2 == 1 {
+			role = "ai"
+		}
+		messages[i] = models.Message{
+			Role:    role,
+			Content: g.faker.Sentence(8 + g.rand.Intn(24)),
+		}
+	}
+	return messages
+}
+
+// Documents returns n documents. If dimensions is greater than 0, each document is given
+// a random embedding of that width.
+func (g *Generator) Documents(n, dimensions int) []models.CreateDocumentRequest {
+	documents := make([]models.CreateDocumentRequest, n)
+	for i := range documents {
+		documents[i] = models.CreateDocumentRequest{
+			DocumentID: g.faker.UUID(),
+			Content:    g.faker.Paragraph(1, 3, 16, " "),
+			Metadata:   map[string]interface{}{"topic": g.faker.BuzzWord()},
+		}
+		if dimensions > 0 {
+			documents[i].Embedding = g.Embedding(dimensions)
+		}
+	}
+	return documents
+}
+
+// Embedding returns a random vector of the given width.
+func (g *Generator) Embedding(dimensions int) []float32 {
+	v := make([]float32, dimensions)
+	for i := range v {
+		v[i] = g.rand.Float32()*2 - 1
+	}
+	return v
+}
+
+// Query returns a short search query.
+func (g *Generator) Query() string {
+	return g.faker.Phrase()
+}
@@ -0,0 +1,139 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Recorder collects the latencies and errors of an operation. It is safe for concurrent use.
+type Recorder struct {
+	Name      string
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    int
+	items     int
+	start     time.Time
+	end       time.Time
+}
+
+func NewRecorder(name string) *Recorder {
+	return &Recorder{Name: name}
+}
+
+// Record records a single request taking d, which carried items records (e.g. messages
+// or documents). Failed requests are counted but excluded from the latency percentiles.
+func (r *Recorder) Record(d time.Duration, items int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors++
+		return
+	}
+	r.latencies = append(r.latencies, d)
+	r.items += items
+}
+
+// Start marks the start of the measured period, used to calculate throughput.
+func (r *Recorder) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.start = time.Now()
+}
+
+// Stop marks the end of the measured period.
+func (r *Recorder) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.end = time.Now()
+}
+
+// Summary is a summary of the latencies and throughput of an operation.
+type Summary struct {
+	Name        string        `json:"name"`
+	Requests    int           `json:"requests"`
+	Errors      int           `json:"errors"`
+	Items       int           `json:"items"`
+	Duration    time.Duration `json:"duration"`
+	ItemsPerSec float64       `json:"items_per_sec"`
+	P50         time.Duration `json:"p50"`
+	P90         time.Duration `json:"p90"`
+	P99         time.Duration `json:"p99"`
+	Max         time.Duration `json:"max"`
+}
+
+func (r *Recorder) Summary() Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	latencies := make([]time.Duration, len(r.latencies))
+	copy(latencies, r.latencies)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	s := Summary{
+		Name:     r.Name,
+		Requests: len(latencies) + r.errors,
+		Errors:   r.errors,
+		Items:    r.items,
+		P50:      Percentile(latencies, 50),
+		P90:      Percentile(latencies, 90),
+		P99:      Percentile(latencies, 99),
+	}
+	if len(latencies) > 0 {
+		s.Max = latencies[len(latencies)-1]
+	}
+	if !r.start.IsZero() && r.end.After(r.start) {
+		s.Duration = r.end.Sub(r.start)
+		s.ItemsPerSec = float64(r.items) / s.Duration.Seconds()
+	}
+	return s
+}
+
+// Percentile returns the pth percentile of the sorted latencies using the nearest-rank method.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// WriteSummaries writes a table of summaries to w.
+func WriteSummaries(w io.Writer, summaries []Summary) error {
+	_, err := fmt.Fprintf(
+		w,
+		"%-18s %9s %7s %9s %11s %10s %10s %10s %10s\n",
+		"operation", "requests", "errors", "items", "items/sec", "p50", "p90", "p99", "max",
+	)
+	if err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		_, err = fmt.Fprintf(
+			w,
+			"%-18s %9d %7d %9d %11.1f %10s %10s %10s %10s\n",
+			s.Name,
+			s.Requests,
+			s.Errors,
+			s.Items,
+			s.ItemsPerSec,
+			s.P50.Round(time.Microsecond),
+			s.P90.Round(time.Microsecond),
+			s.P99.Round(time.Microsecond),
+			s.Max.Round(time.Microsecond),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
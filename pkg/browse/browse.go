@@ -0,0 +1,96 @@
+// Package browse is a terminal UI for browsing the sessions of a running Zep server: their
+// messages, summaries, and metadata, and searches of their memory.
+package browse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/getzep/zep/pkg/client"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	// sessionPageSize is the number of sessions loaded at a time.
+	sessionPageSize = 100
+	// memoryMessages is the number of recent messages shown for a session.
+	memoryMessages = 200
+	searchLimit    = 20
+)
+
+// API is the part of the Zep API used by the browser. It's implemented by client.Client.
+type API interface {
+	ListSessions(ctx context.Context, cursor int64, limit int) ([]*models.Session, error)
+	GetMemory(ctx context.Context, sessionID string, lastN int) (*models.Memory, error)
+	SearchMemory(
+		ctx context.Context,
+		sessionID string,
+		payload *models.MemorySearchPayload,
+		limit int,
+	) ([]models.MemorySearchResult, error)
+}
+
+var _ API = &client.Client{}
+
+// Run runs the browser until the user quits or ctx is canceled.
+func Run(ctx context.Context, api API) error {
+	p := tea.NewProgram(NewModel(ctx, api), tea.WithAltScreen(), tea.WithContext(ctx))
+	_, err := p.Run()
+	if errors.Is(err, tea.ErrProgramKilled) {
+		return nil
+	}
+	return err
+}
+
+type sessionsLoadedMsg struct {
+	sessions []*models.Session
+	// more is true if the page was full, so there may be more sessions.
+	more   bool
+	append bool
+	err    error
+}
+
+type memoryLoadedMsg struct {
+	memory *models.Memory
+	err    error
+}
+
+type searchResultsMsg struct {
+	results []models.MemorySearchResult
+	err     error
+}
+
+func loadSessions(ctx context.Context, api API, cursor int64, append bool) tea.Cmd {
+	return func() tea.Msg {
+		sessions, err := api.ListSessions(ctx, cursor, sessionPageSize)
+		return sessionsLoadedMsg{
+			sessions: sessions,
+			more:     len(sessions) == sessionPageSize,
+			append:   append,
+			err:      err,
+		}
+	}
+}
+
+func loadMemory(ctx context.Context, api API, sessionID string) tea.Cmd {
+	return func() tea.Msg {
+		memory, err := api.GetMemory(ctx, sessionID, memoryMessages)
+		// a session without messages has no memory
+		var apiErr *client.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return memoryLoadedMsg{memory: &models.Memory{}}
+		}
+		return memoryLoadedMsg{memory: memory, err: err}
+	}
+}
+
+func search(ctx context.Context, api API, sessionID, query string) tea.Cmd {
+	return func() tea.Msg {
+		payload := &models.MemorySearchPayload{Text: query}
+		results, err := api.SearchMemory(ctx, sessionID, payload, searchLimit)
+		return searchResultsMsg{results: results, err: err}
+	}
+}
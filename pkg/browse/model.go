@@ -0,0 +1,430 @@
+package browse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+type view int
+
+const (
+	sessionsView view = iota
+	sessionView
+	searchView
+)
+
+// defaultWidth and defaultHeight are used until the terminal reports its size.
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// Model is the browser's state. The session list opens a session's memory, from which
+// the session's memory can be searched.
+type Model struct {
+	ctx context.Context
+	api API
+
+	view          view
+	width, height int
+	loading       bool
+	err           error
+	showMetadata  bool
+
+	sessions     []*models.Session
+	moreSessions bool
+	selected     int
+
+	memory *models.Memory
+	// offset is the first line shown of the session view.
+	offset int
+
+	query          string
+	editing        bool
+	results        []models.MemorySearchResult
+	resultSelected int
+}
+
+func NewModel(ctx context.Context, api API) *Model {
+	return &Model{
+		ctx:     ctx,
+		api:     api,
+		width:   defaultWidth,
+		height:  defaultHeight,
+		loading: true,
+	}
+}
+
+func (m *Model) Init() tea.Cmd {
+	return loadSessions(m.ctx, m.api, 0, false)
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+	case sessionsLoadedMsg:
+		m.loading = false
+		m.err = msg.err
+		if msg.err == nil {
+			if msg.append {
+				m.sessions = append(m.sessions, msg.sessions...)
+			} else {
+				m.sessions = msg.sessions
+				m.selected = 0
+			}
+			m.moreSessions = msg.more
+		}
+		return m, nil
+	case memoryLoadedMsg:
+		m.loading = false
+		m.err = msg.err
+		m.memory = msg.memory
+		m.offset = 0
+		return m, nil
+	case searchResultsMsg:
+		m.loading = false
+		m.err = msg.err
+		m.results = msg.results
+		m.resultSelected = 0
+		return m, nil
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		switch m.view {
+		case sessionsView:
+			return m.updateSessions(msg)
+		case sessionView:
+			return m.updateSession(msg)
+		case searchView:
+			return m.updateSearch(msg)
+		}
+	}
+	return m, nil
+}
+
+func (m *Model) updateSessions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		return m, tea.Quit
+	case "up", "k":
+		m.selected = max(m.selected-1, 0)
+	case "down", "j":
+		m.selected = min(m.selected+1, max(len(m.sessions)-1, 0))
+		// load the next page on reaching the end of the list
+		if m.selected == len(m.sessions)-1 && m.moreSessions && !m.loading {
+			m.loading = true
+			return m, loadSessions(m.ctx, m.api, m.sessions[m.selected].ID, true)
+		}
+	case "r":
+		m.loading = true
+		return m, loadSessions(m.ctx, m.api, 0, false)
+	case "m":
+		m.showMetadata = !m.showMetadata
+	case "enter":
+		if len(m.sessions) == 0 {
+			return m, nil
+		}
+		m.view = sessionView
+		m.memory = nil
+		m.loading = true
+		return m, loadMemory(m.ctx, m.api, m.currentSession().SessionID)
+	}
+	return m, nil
+}
+
+func (m *Model) updateSession(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "esc", "backspace":
+		m.view = sessionsView
+		m.err = nil
+	case "up", "k":
+		m.offset = max(m.offset-1, 0)
+	case "down", "j":
+		m.offset = min(m.offset+1, max(len(m.sessionLines())-m.bodyHeight(), 0))
+	case "m":
+		m.showMetadata = !m.showMetadata
+	case "r":
+		m.loading = true
+		return m, loadMemory(m.ctx, m.api, m.currentSession().SessionID)
+	case "/", "s":
+		m.view = searchView
+		m.editing = true
+		m.err = nil
+	}
+	return m, nil
+}
+
+func (m *Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.editing {
+		switch msg.Type {
+		case tea.KeyEnter:
+			if strings.TrimSpace(m.query) == "" {
+				return m, nil
+			}
+			m.editing = false
+			m.loading = true
+			return m, search(m.ctx, m.api, m.currentSession().SessionID, m.query)
+		case tea.KeyEsc:
+			m.editing = false
+			if m.results == nil {
+				m.view = sessionView
+			}
+		case tea.KeyBackspace:
+			if r := []rune(m.query); len(r) > 0 {
+				m.query = string(r[:len(r)-1])
+			}
+		case tea.KeySpace:
+			m.query += " "
+		case tea.KeyRunes:
+			m.query += string(msg.Runes)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "esc", "backspace":
+		m.view = sessionView
+		m.results = nil
+		m.query = ""
+		m.err = nil
+	case "/", "s":
+		m.editing = true
+	case "up", "k":
+		m.resultSelected = max(m.resultSelected-1, 0)
+	case "down", "j":
+		m.resultSelected = min(m.resultSelected+1, max(len(m.results)-1, 0))
+	case "m":
+		m.showMetadata = !m.showMetadata
+	}
+	return m, nil
+}
+
+func (m *Model) currentSession() *models.Session {
+	return m.sessions[m.selected]
+}
+
+// bodyHeight is the number of lines between the header and footer.
+func (m *Model) bodyHeight() int {
+	return max(m.height-4, 1)
+}
+
+func (m *Model) View() string {
+	var header, footer string
+	var body []string
+	switch m.view {
+	case sessionsView:
+		header = fmt.Sprintf("Sessions (%d)", len(m.sessions))
+		footer = "↑/↓ select • enter open • m metadata • r refresh • q quit"
+		body = m.sessionsLines()
+	case sessionView:
+		header = "Session " + m.currentSession().SessionID
+		footer = "↑/↓ scroll • / search • m metadata • r refresh • esc back • q quit"
+		lines := m.sessionLines()
+		end := min(m.offset+m.bodyHeight(), len(lines))
+		body = lines[min(m.offset, end):end]
+	case searchView:
+		header = "Search " + m.currentSession().SessionID + ": " + m.query
+		if m.editing {
+			header += "█"
+			footer = "enter search • esc cancel"
+		} else {
+			footer = "↑/↓ select • / new search • m metadata • esc back • q quit"
+		}
+		body = m.searchLines()
+	}
+
+	status := ""
+	if m.loading {
+		status = "Loading..."
+	} else if m.err != nil {
+		status = "Error: " + m.err.Error()
+	}
+
+	var b strings.Builder
+	b.WriteString(m.truncate(header) + "\n")
+	b.WriteString(m.truncate(status) + "\n")
+	for _, line := range body {
+		b.WriteString(m.truncate(line) + "\n")
+	}
+	for i := len(body); i < m.bodyHeight(); i++ {
+		b.WriteString("\n")
+	}
+	b.WriteString(m.truncate(footer))
+	return b.String()
+}
+
+// sessionsLines lists the sessions, scrolled to keep the selected session visible.
+func (m *Model) sessionsLines() []string {
+	lines := make([]string, 0, len(m.sessions))
+	for i, session := range m.sessions {
+		line := cursor(i == m.selected) + session.SessionID
+		if session.UserID != nil {
+			line += "  user:" + *session.UserID
+		}
+		if session.Title != "" {
+			line += "  " + session.Title
+		}
+		line += "  " + session.CreatedAt.Format("2006-01-02 15:04")
+		if m.showMetadata && len(session.Metadata) > 0 {
+			line += "  " + compactJSON(session.Metadata)
+		}
+		lines = append(lines, line)
+	}
+	return window(lines, m.selected, m.bodyHeight())
+}
+
+// sessionLines shows the session's metadata, summary, and messages.
+func (m *Model) sessionLines() []string {
+	session := m.currentSession()
+	var lines []string
+	if session.Title != "" {
+		lines = append(lines, "Title: "+session.Title)
+	}
+	if session.UserID != nil {
+		lines = append(lines, "User: "+*session.UserID)
+	}
+	lines = append(lines, "Created: "+session.CreatedAt.Format("2006-01-02 15:04:05"))
+	if m.showMetadata {
+		lines = append(lines, "Metadata:")
+		lines = append(lines, indentedJSON(session.Metadata)...)
+		if session.TaskState != nil {
+			lines = append(lines, "Task state:")
+			lines = append(lines, indentedJSON(session.TaskState)...)
+		}
+	}
+	if m.memory == nil {
+		return lines
+	}
+
+	lines = append(lines, "")
+	if m.memory.Summary != nil {
+		lines = append(lines, "Summary:")
+		lines = append(lines, m.wrap("  ", m.memory.Summary.Content)...)
+		lines = append(lines, "")
+	}
+	lines = append(lines, fmt.Sprintf("Messages (%d):", len(m.memory.Messages)))
+	for _, message := range m.memory.Messages {
+		lines = append(lines, m.messageLines(&message, "")...)
+	}
+	return lines
+}
+
+func (m *Model) searchLines() []string {
+	if m.results == nil {
+		return nil
+	}
+	if len(m.results) == 0 {
+		return []string{"No results"}
+	}
+
+	var lines []string
+	selectedLine := 0
+	for i, result := range m.results {
+		if i == m.resultSelected {
+			selectedLine = len(lines)
+		}
+		prefix := cursor(i == m.resultSelected) + fmt.Sprintf("[%.3f] ", result.Dist)
+		switch {
+		case result.Message != nil:
+			lines = append(lines, m.messageLines(result.Message, prefix)...)
+		case result.Summary != nil:
+			lines = append(lines, m.wrap(prefix+"summary: ", result.Summary.Content)...)
+		}
+		if m.showMetadata && len(result.Metadata) > 0 {
+			lines = append(lines, "    "+compactJSON(result.Metadata))
+		}
+	}
+	return window(lines, selectedLine, m.bodyHeight())
+}
+
+func (m *Model) messageLines(message *models.Message, prefix string) []string {
+	lines := m.wrap(prefix+message.Role+": ", message.Content)
+	if m.showMetadata && len(message.Metadata) > 0 {
+		lines = append(lines, "    "+compactJSON(message.Metadata))
+	}
+	return lines
+}
+
+// wrap wraps text to the terminal's width, prefixing the first line. Following lines are
+// indented to the width of the prefix.
+func (m *Model) wrap(prefix, text string) []string {
+	indent := strings.Repeat(" ", len([]rune(prefix)))
+	width := max(m.width-len([]rune(prefix)), 20)
+
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	for i := range lines {
+		if i == 0 {
+			lines[i] = prefix + lines[i]
+		} else {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return lines
+}
+
+func (m *Model) truncate(line string) string {
+	r := []rune(line)
+	if len(r) <= m.width {
+		return line
+	}
+	return string(r[:max(m.width-1, 0)]) + "…"
+}
+
+// window returns at most height lines, including the line at index.
+func window(lines []string, index, height int) []string {
+	if len(lines) <= height {
+		return lines
+	}
+	start := min(max(index-height/2, 0), len(lines)-height)
+	return lines[start : start+height]
+}
+
+func cursor(selected bool) string {
+	if selected {
+		return "> "
+	}
+	return "  "
+}
+
+func compactJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func indentedJSON(v any) []string {
+	b, err := json.MarshalIndent(v, "  ", "  ")
+	if err != nil {
+		return []string{"  " + fmt.Sprint(v)}
+	}
+	return strings.Split("  "+string(b), "\n")
+}
@@ -0,0 +1,177 @@
+package browse
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/client"
+	"github.com/getzep/zep/pkg/models"
+)
+
+type fakeAPI struct {
+	sessions []*models.Session
+	memory   map[string]*models.Memory
+	results  []models.MemorySearchResult
+	query    string
+}
+
+func (f *fakeAPI) ListSessions(_ context.Context, _ int64, _ int) ([]*models.Session, error) {
+	return f.sessions, nil
+}
+
+func (f *fakeAPI) GetMemory(_ context.Context, sessionID string, _ int) (*models.Memory, error) {
+	memory, ok := f.memory[sessionID]
+	if !ok {
+		return nil, &client.APIError{StatusCode: 404, Message: "not found"}
+	}
+	return memory, nil
+}
+
+func (f *fakeAPI) SearchMemory(
+	_ context.Context,
+	_ string,
+	payload *models.MemorySearchPayload,
+	_ int,
+) ([]models.MemorySearchResult, error) {
+	f.query = payload.Text
+	if payload.Text == "fail" {
+		return nil, errors.New("search failed")
+	}
+	return f.results, nil
+}
+
+// send updates the model with msg, and with the message of any command returned, as
+// the program would.
+func send(t *testing.T, m *Model, msg tea.Msg) {
+	_, cmd := m.Update(msg)
+	if cmd != nil {
+		next := cmd()
+		if _, ok := next.(tea.QuitMsg); ok {
+			return
+		}
+		send(t, m, next)
+	}
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func newTestModel(t *testing.T) (*Model, *fakeAPI) {
+	userID := "alice"
+	api := &fakeAPI{
+		sessions: []*models.Session{
+			{ID: 1, SessionID: "empty"},
+			{ID: 2, SessionID: "chat", UserID: &userID, Metadata: map[string]interface{}{"k": "v"}},
+		},
+		memory: map[string]*models.Memory{
+			"chat": {
+				Summary: &models.Summary{Content: "The user ordered a pizza."},
+				Messages: []models.Message{
+					{Role: "user", Content: "I'd like a pizza", Metadata: map[string]interface{}{
+						"intent": "order",
+					}},
+					{Role: "assistant", Content: "Coming right up"},
+				},
+			},
+		},
+		results: []models.MemorySearchResult{
+			{Message: &models.Message{Role: "user", Content: "I'd like a pizza"}, Dist: 0.9},
+		},
+	}
+	m := NewModel(context.Background(), api)
+	send(t, m, m.Init()())
+	return m, api
+}
+
+func TestBrowseSessions(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	view := m.View()
+	assert.Contains(t, view, "Sessions (2)")
+	assert.Contains(t, view, "> empty")
+	assert.Contains(t, view, "chat  user:alice")
+	assert.NotContains(t, view, `{"k":"v"}`)
+
+	send(t, m, key("m"))
+	assert.Contains(t, m.View(), `{"k":"v"}`)
+
+	send(t, m, key("down"))
+	assert.Contains(t, m.View(), "> chat")
+}
+
+func TestBrowseSession(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	send(t, m, key("down"))
+	send(t, m, key("enter"))
+	view := m.View()
+	assert.Contains(t, view, "Session chat")
+	assert.Contains(t, view, "The user ordered a pizza.")
+	assert.Contains(t, view, "Messages (2):")
+	assert.Contains(t, view, "user: I'd like a pizza")
+	assert.Contains(t, view, "assistant: Coming right up")
+	assert.NotContains(t, view, `"intent":"order"`)
+
+	send(t, m, key("m"))
+	assert.Contains(t, m.View(), `"intent":"order"`)
+
+	send(t, m, key("esc"))
+	assert.Contains(t, m.View(), "Sessions (2)")
+
+	// a session without messages has no memory
+	send(t, m, key("k"))
+	send(t, m, key("enter"))
+	assert.Nil(t, m.err)
+	assert.Contains(t, m.View(), "Messages (0):")
+}
+
+func TestBrowseSearch(t *testing.T) {
+	m, api := newTestModel(t)
+
+	send(t, m, key("down"))
+	send(t, m, key("enter"))
+	send(t, m, key("/"))
+	for _, r := range "pizza" {
+		send(t, m, key(string(r)))
+	}
+	assert.Contains(t, m.View(), "Search chat: pizza")
+
+	send(t, m, key("enter"))
+	assert.Equal(t, "pizza", api.query)
+	assert.Contains(t, m.View(), "> [0.900] user: I'd like a pizza")
+
+	send(t, m, key("/"))
+	for range "pizza" {
+		send(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	for _, r := range "fail" {
+		send(t, m, key(string(r)))
+	}
+	send(t, m, key("enter"))
+	assert.Contains(t, m.View(), "Error: search failed")
+
+	send(t, m, key("esc"))
+	assert.Contains(t, m.View(), "Session chat")
+}
+
+func TestWrap(t *testing.T) {
+	m := &Model{width: 30}
+	lines := m.wrap("user: ", "one two three four five six seven eight nine ten")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "user: one two three four five", lines[0])
+	assert.Equal(t, "      six seven eight nine ten", lines[1])
+}
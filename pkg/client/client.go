@@ -0,0 +1,199 @@
+// Package client is a client for the Zep API, used by the CLI's client-mode commands.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	// DefaultBaseURL is the address of a Zep server running locally with the default
+	// config.
+	DefaultBaseURL = "http://localhost:8000"
+	DefaultTimeout = 30 * time.Second
+)
+
+// Client calls the Zep API of a running server.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// New returns a Client for the server at baseURL. apiKey may be empty if the server
+// doesn't require auth.
+func New(baseURL, apiKey string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/") + "/api/v1",
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// APIError is returned for a response with an error status.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// ListSessions returns up to limit sessions following cursor. A limit of 0 uses the
+// server's default.
+func (c *Client) ListSessions(
+	ctx context.Context,
+	cursor int64,
+	limit int,
+) ([]*models.Session, error) {
+	query := url.Values{}
+	if cursor > 0 {
+		query.Set("cursor", fmt.Sprint(cursor))
+	}
+	if limit > 0 {
+		query.Set("limit", fmt.Sprint(limit))
+	}
+
+	var sessions []*models.Session
+	err := c.do(ctx, http.MethodGet, withQuery("/sessions", query), nil, &sessions)
+	return sessions, err
+}
+
+// GetMemory returns a session's summary and most recent messages. A lastN of 0 uses the
+// server's memory window.
+func (c *Client) GetMemory(
+	ctx context.Context,
+	sessionID string,
+	lastN int,
+) (*models.Memory, error) {
+	query := url.Values{}
+	if lastN > 0 {
+		query.Set("lastn", fmt.Sprint(lastN))
+	}
+	path := withQuery("/sessions/"+url.PathEscape(sessionID)+"/memory", query)
+
+	var memory models.Memory
+	if err := c.do(ctx, http.MethodGet, path, nil, &memory); err != nil {
+		return nil, err
+	}
+	return &memory, nil
+}
+
+// SearchMemory searches a session's memory. A limit of 0 uses the server's default.
+func (c *Client) SearchMemory(
+	ctx context.Context,
+	sessionID string,
+	payload *models.MemorySearchPayload,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", fmt.Sprint(limit))
+	}
+	path := withQuery("/sessions/"+url.PathEscape(sessionID)+"/search", query)
+
+	var results []models.MemorySearchResult
+	err := c.do(ctx, http.MethodPost, path, payload, &results)
+	return results, err
+}
+
+// SearchUserMemory searches the memory of a user's sessions most similar to the payload's
+// text. A sessions or limit of 0 uses the server's default.
+func (c *Client) SearchUserMemory(
+	ctx context.Context,
+	userID string,
+	payload *models.MemorySearchPayload,
+	sessions int,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	query := url.Values{}
+	if sessions > 0 {
+		query.Set("sessions", fmt.Sprint(sessions))
+	}
+	if limit > 0 {
+		query.Set("limit", fmt.Sprint(limit))
+	}
+	path := withQuery("/user/"+url.PathEscape(userID)+"/search", query)
+
+	var results []models.MemorySearchResult
+	err := c.do(ctx, http.MethodPost, path, payload, &results)
+	return results, err
+}
+
+func (c *Client) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	var user models.User
+	err := c.do(ctx, http.MethodGet, "/user/"+url.PathEscape(userID), nil, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) GetCollection(
+	ctx context.Context,
+	name string,
+) (*models.DocumentCollectionResponse, error) {
+	var collection models.DocumentCollectionResponse
+	err := c.do(ctx, http.MethodGet, "/collection/"+url.PathEscape(name), nil, &collection)
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+
+	if result == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
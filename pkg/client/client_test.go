@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/sessions":
+			assert.Equal(t, "5", r.URL.Query().Get("limit"))
+			assert.Equal(t, "10", r.URL.Query().Get("cursor"))
+			_ = json.NewEncoder(w).Encode([]*models.Session{{SessionID: "s1"}})
+		case "POST /api/v1/sessions/s 1/search":
+			var payload models.MemorySearchPayload
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "hello", payload.Text)
+			assert.Empty(t, r.URL.Query().Get("limit"))
+			_ = json.NewEncoder(w).Encode([]models.MemorySearchResult{{Dist: 0.9}})
+		case "GET /api/v1/sessions/s1/memory":
+			assert.Equal(t, "50", r.URL.Query().Get("lastn"))
+			_ = json.NewEncoder(w).Encode(&models.Memory{
+				Messages: []models.Message{{Role: "user", Content: "hi"}},
+			})
+		case "POST /api/v1/user/alice/search":
+			assert.Equal(t, "3", r.URL.Query().Get("sessions"))
+			assert.Equal(t, "5", r.URL.Query().Get("limit"))
+			_ = json.NewEncoder(w).Encode([]models.MemorySearchResult{{SessionID: "s1"}})
+		case "GET /api/v1/user/alice":
+			_ = json.NewEncoder(w).Encode(&models.User{UserID: "alice"})
+		case "GET /api/v1/collection/docs":
+			_ = json.NewEncoder(w).Encode(&models.DocumentCollectionResponse{
+				Name: "docs",
+				DocumentCollectionCounts: &models.DocumentCollectionCounts{
+					DocumentCount: 3,
+				},
+			})
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/", "key", DefaultTimeout)
+
+	sessions, err := c.ListSessions(ctx, 10, 5)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "s1", sessions[0].SessionID)
+
+	memory, err := c.GetMemory(ctx, "s1", 50)
+	require.NoError(t, err)
+	require.Len(t, memory.Messages, 1)
+	assert.Equal(t, "hi", memory.Messages[0].Content)
+
+	results, err := c.SearchMemory(ctx, "s 1", &models.MemorySearchPayload{Text: "hello"}, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, 0.9, results[0].Dist)
+
+	results, err = c.SearchUserMemory(
+		ctx,
+		"alice",
+		&models.MemorySearchPayload{Text: "hello"},
+		3,
+		5,
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "s1", results[0].SessionID)
+
+	user, err := c.GetUser(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.UserID)
+
+	collection, err := c.GetCollection(ctx, "docs")
+	require.NoError(t, err)
+	assert.Equal(t, 3, collection.DocumentCount)
+
+	_, err = c.GetUser(ctx, "bob")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "not found", apiErr.Message)
+}
@@ -0,0 +1,196 @@
+// Package egress applies the outbound proxy and host allow-list of config.EgressConfig to
+// Zep's HTTP clients. Configure installs the policy as the default, which is used by the
+// retryable clients of the llms and tasks packages and by http.DefaultTransport. Clients
+// of providers with a config.TLSConfig also trust its CA bundle.
+package egress
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/getzep/zep/config"
+)
+
+// ErrHostNotAllowed is wrapped by the errors of requests to hosts not on the allow-list.
+var ErrHostNotAllowed = errors.New("egress to host not allowed")
+
+// baseTransport is copied for each Policy's transport, before Configure replaces
+// http.DefaultTransport.
+var baseTransport = http.DefaultTransport.(*http.Transport).Clone()
+
+var current atomic.Pointer[Policy]
+
+func init() {
+	current.Store(&Policy{})
+}
+
+// Policy decides the proxy of outbound requests and whether they may be sent. The zero
+// Policy uses the proxy environment variables and allows any host.
+type Policy struct {
+	proxy        *url.URL
+	noProxy      []string
+	allowedHosts []string
+}
+
+// NewPolicy returns the Policy of cfg, or an error if its proxy URL or a host pattern is
+// invalid.
+func NewPolicy(cfg *config.EgressConfig) (*Policy, error) {
+	p := &Policy{}
+	if cfg.ProxyURL != "" {
+		proxy, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid egress proxy_url: %w", err)
+		}
+		switch {
+		case proxy.Scheme != "http" && proxy.Scheme != "https" && proxy.Scheme != "socks5":
+			return nil, fmt.Errorf(
+				"egress proxy_url scheme must be http, https or socks5: %s", cfg.ProxyURL,
+			)
+		case proxy.Host == "":
+			return nil, fmt.Errorf("egress proxy_url has no host: %s", cfg.ProxyURL)
+		}
+		p.proxy = proxy
+	}
+
+	var err error
+	if p.noProxy, err = hostPatterns("no_proxy", cfg.NoProxy); err != nil {
+		return nil, err
+	}
+	if p.allowedHosts, err = hostPatterns("allowed_hosts", cfg.AllowedHosts); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// hostPatterns returns the lowercased patterns, or an error if any of them isn't a host or
+// a *. wildcard followed by a domain.
+func hostPatterns(name string, patterns []string) ([]string, error) {
+	out := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		host := normalizeHost(pattern)
+		domain := strings.TrimPrefix(host, "*.")
+		if domain == "" || (strings.ContainsAny(domain, "*/: ") && net.ParseIP(domain) == nil) {
+			return nil, fmt.Errorf("invalid egress %s host: %q", name, pattern)
+		}
+		out = append(out, host)
+	}
+	return out, nil
+}
+
+// Allowed is true if requests may be sent to host.
+func (p *Policy) Allowed(host string) bool {
+	return len(p.allowedHosts) == 0 || matchHost(p.allowedHosts, host)
+}
+
+// Proxy returns the proxy of req, or nil if req is sent directly. It has the signature of
+// http.Transport.Proxy.
+func (p *Policy) Proxy(req *http.Request) (*url.URL, error) {
+	if p.proxy == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+	host := req.URL.Hostname()
+	if isLoopback(host) || matchHost(p.noProxy, host) {
+		return nil, nil
+	}
+	return p.proxy, nil
+}
+
+// Transport returns a transport sending requests through the Policy's proxy and failing
+// requests to hosts that aren't allowed with ErrHostNotAllowed. As redirects are sent with
+// the transport, they're checked too. If tlsConfig is nil, the default TLS settings are
+// used.
+func (p *Policy) Transport(tlsConfig *tls.Config) http.RoundTripper {
+	transport := baseTransport.Clone()
+	transport.Proxy = p.Proxy
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig.Clone()
+	}
+	return &allowListTransport{policy: p, next: transport}
+}
+
+// Current returns the Policy installed by Configure.
+func Current() *Policy {
+	return current.Load()
+}
+
+// Configure installs the Policy of cfg as the default, and replaces http.DefaultTransport
+// with its transport, so clients using http.DefaultClient, such as the Anthropic client,
+// are covered.
+func Configure(cfg *config.EgressConfig) error {
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	current.Store(p)
+	http.DefaultTransport = p.Transport(nil)
+	return nil
+}
+
+// ConfigureClient sends the requests of client with the transport of the current Policy
+// and tlsConfig, which may be nil. Requests to hosts that aren't allowed aren't retried.
+func ConfigureClient(client *retryablehttp.Client, tlsConfig *tls.Config) {
+	client.HTTPClient.Transport = Current().Transport(tlsConfig)
+	checkRetry := client.CheckRetry
+	client.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if errors.Is(err, ErrHostNotAllowed) {
+			return false, err
+		}
+		return checkRetry(ctx, resp, err)
+	}
+}
+
+type allowListTransport struct {
+	policy *Policy
+	next   http.RoundTripper
+}
+
+func (t *allowListTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if host := req.URL.Hostname(); !t.policy.Allowed(host) {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// matchHost is true if host is one of patterns, or a subdomain of a *. pattern's domain.
+func matchHost(patterns []string, host string) bool {
+	host = normalizeHost(host)
+	for _, pattern := range patterns {
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	host = strings.TrimSuffix(host, ".")
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+// isLoopback is true for localhost and loopback IPs, which, as with the proxy environment
+// variables, are never proxied.
+func isLoopback(host string) bool {
+	host = normalizeHost(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
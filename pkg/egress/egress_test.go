@@ -0,0 +1,150 @@
+package egress
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+)
+
+// configure installs the Policy of cfg, restoring the previous Policy and
+// http.DefaultTransport when the test ends.
+func configure(t *testing.T, cfg *config.EgressConfig) {
+	policy, transport := Current(), http.DefaultTransport
+	t.Cleanup(func() {
+		current.Store(policy)
+		http.DefaultTransport = transport
+	})
+	require.NoError(t, Configure(cfg))
+}
+
+func newRetryableClient() *http.Client {
+	client := retryablehttp.NewClient()
+	client.RetryMax = 2
+	client.RetryWaitMin = time.Millisecond
+	client.RetryWaitMax = time.Millisecond
+	client.Logger = nil
+	ConfigureClient(client, nil)
+	return client.StandardClient()
+}
+
+func TestNewPolicy(t *testing.T) {
+	for _, cfg := range []config.EgressConfig{
+		{ProxyURL: "ftp://proxy:21"},
+		{ProxyURL: "http://"},
+		{ProxyURL: "http://proxy:3128/%zz"},
+		{AllowedHosts: []string{"*"}},
+		{AllowedHosts: []string{"api.*.com"}},
+		{AllowedHosts: []string{"https://api.openai.com"}},
+		{NoProxy: []string{""}},
+	} {
+		_, err := NewPolicy(&cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+
+	p, err := NewPolicy(&config.EgressConfig{
+		AllowedHosts: []string{"API.openai.com.", "*.openai.azure.com", "::1", "10.0.0.1"},
+	})
+	require.NoError(t, err)
+	for host, allowed := range map[string]bool{
+		"api.openai.com":           true,
+		"API.OpenAI.com.":          true,
+		"openai.com":               false,
+		"evil-api.openai.com":      false,
+		"zep.openai.azure.com":     true,
+		"a.b.openai.azure.com":     true,
+		"openai.azure.com":         false,
+		"openai.azure.com.evil.io": false,
+		"::1":                      true,
+		"10.0.0.1":                 true,
+		"10.0.0.2":                 false,
+	} {
+		assert.Equal(t, allowed, p.Allowed(host), host)
+	}
+
+	p, err = NewPolicy(&config.EgressConfig{})
+	require.NoError(t, err)
+	assert.True(t, p.Allowed("anywhere.example.com"))
+}
+
+func TestProxy(t *testing.T) {
+	p, err := NewPolicy(&config.EgressConfig{
+		ProxyURL: "http://proxy.internal:3128",
+		NoProxy:  []string{"*.svc.cluster.local", "qdrant"},
+	})
+	require.NoError(t, err)
+
+	for rawURL, proxied := range map[string]bool{
+		"https://api.openai.com/v1/embeddings": true,
+		"http://qdrant:6333/collections":       false,
+		"http://opensearch.svc.cluster.local":  false,
+		"http://localhost:8080/embeddings":     false,
+		"http://127.0.0.1:8080/embeddings":     false,
+	} {
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		require.NoError(t, err)
+		proxy, err := p.Proxy(req)
+		require.NoError(t, err)
+		if proxied {
+			assert.Equal(t, "proxy.internal:3128", proxy.Host, rawURL)
+		} else {
+			assert.Nil(t, proxy, rawURL)
+		}
+	}
+}
+
+func TestConfigureClientThroughProxy(t *testing.T) {
+	var requested atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a proxied request has the absolute URL of its destination
+		requested.Store(r.URL.String())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	configure(t, &config.EgressConfig{ProxyURL: proxy.URL})
+	resp, err := newRetryableClient().Get("http://api.example.com/v1/models")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "http://api.example.com/v1/models", requested.Load())
+}
+
+func TestConfigureClientDeniesHost(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Redirect(w, r, "http://denied.example.com/", http.StatusFound)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	configure(t, &config.EgressConfig{AllowedHosts: []string{serverURL.Hostname()}})
+	client := newRetryableClient()
+
+	_, err = client.Get("http://denied.example.com/")
+	assert.ErrorIs(t, err, ErrHostNotAllowed)
+
+	// the redirect is followed with the same transport, and isn't retried
+	_, err = client.Get(server.URL)
+	assert.ErrorIs(t, err, ErrHostNotAllowed)
+	assert.Equal(t, int64(1), calls.Load())
+
+	// http.DefaultClient follows the policy too
+	req, err := http.NewRequestWithContext(
+		context.Background(), http.MethodGet, "http://denied.example.com/", nil,
+	)
+	require.NoError(t, err)
+	_, err = http.DefaultClient.Do(req)
+	assert.ErrorIs(t, err, ErrHostNotAllowed)
+}
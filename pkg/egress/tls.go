@@ -0,0 +1,54 @@
+package egress
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/getzep/zep/config"
+)
+
+// tlsConfigs caches the *tls.Config of each config.TLSConfig, so that CA bundles are read
+// once rather than for each client.
+var tlsConfigs sync.Map
+
+// LoadTLSConfig returns the *tls.Config of cfg, or nil if cfg is the zero TLSConfig. The
+// certificates of cfg.CAFile are trusted in addition to the system's.
+func LoadTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	if cfg == (config.TLSConfig{}) {
+		return nil, nil
+	}
+	if cached, ok := tlsConfigs.Load(cfg); ok {
+		return cached.(*tls.Config), nil
+	}
+
+	tlsConfig := &tls.Config{}
+	switch cfg.MinVersion {
+	case "", "1.2":
+		tlsConfig.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("tls min_version must be 1.2 or 1.3: %q", cfg.MinVersion)
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls ca_file %s has no PEM certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	cached, _ := tlsConfigs.LoadOrStore(cfg, tlsConfig)
+	return cached.(*tls.Config), nil
+}
@@ -0,0 +1,76 @@
+package egress
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+)
+
+// writeCAFile writes the certificate of server to a PEM file, returning its path.
+func writeCAFile(t *testing.T, server *httptest.Server) string {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+	return path
+}
+
+func getWithTLS(t *testing.T, url string, tlsConfig *tls.Config) error {
+	client := retryablehttp.NewClient()
+	client.RetryMax = 0
+	client.Logger = nil
+	ConfigureClient(client, tlsConfig)
+	resp, err := client.StandardClient().Get(url)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	caFile := writeCAFile(t, server)
+
+	tlsConfig, err := LoadTLSConfig(config.TLSConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+	assert.Error(t, getWithTLS(t, server.URL, tlsConfig), "the private CA isn't trusted")
+
+	tlsConfig, err = LoadTLSConfig(config.TLSConfig{CAFile: caFile})
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.NoError(t, getWithTLS(t, server.URL, tlsConfig))
+
+	cached, err := LoadTLSConfig(config.TLSConfig{CAFile: caFile})
+	require.NoError(t, err)
+	assert.Same(t, tlsConfig, cached)
+
+	tlsConfig, err = LoadTLSConfig(config.TLSConfig{CAFile: caFile, MinVersion: "1.3"})
+	require.NoError(t, err)
+	assert.Error(t, getWithTLS(t, server.URL, tlsConfig), "the server only supports TLS 1.2")
+
+	notPEM := filepath.Join(t.TempDir(), "ca.txt")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+	for _, cfg := range []config.TLSConfig{
+		{MinVersion: "1.1"},
+		{CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		{CAFile: notPEM},
+	} {
+		_, err := LoadTLSConfig(cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
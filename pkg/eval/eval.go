@@ -0,0 +1,183 @@
+// Package eval measures the retrieval quality of memory and document search against
+// labeled queries, reporting recall@k and mean reciprocal rank.
+package eval
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/experiment"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// DefaultK is the number of results retrieved for each query if a run doesn't set it.
+const DefaultK = 10
+
+// Run searches each case of set with the current search settings, overridden by req or the
+// preset of req's variant, and scores the results. A case whose search fails is reported
+// with its error rather than failing the run.
+func Run(
+	ctx context.Context,
+	appState *models.AppState,
+	set *models.EvalSet,
+	req models.EvalRunRequest,
+) (*models.EvalReport, error) {
+	k := req.K
+	if k <= 0 {
+		k = DefaultK
+	}
+	if req.Variant != "" {
+		preset := experiment.Preset(appState.Config.Experiments.Retrieval, req.Variant)
+		req.SearchType = models.SearchType(preset.SearchType)
+		req.MMRLambda = preset.MMRLambda
+	}
+	report := &models.EvalReport{
+		SetName:   set.Name,
+		Variant:   req.Variant,
+		Exact:     req.Exact,
+		K:         k,
+		StartedAt: time.Now(),
+		Cases:     make([]models.EvalCaseResult, 0, len(set.Cases)),
+	}
+
+	for _, c := range set.Cases {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result := models.EvalCaseResult{Query: c.Query}
+		retrieved, err := search(ctx, appState, c, req, k)
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed++
+		} else {
+			result.Retrieved = uuids(retrieved)
+			result.RecallAtK, result.ReciprocalRank = score(retrieved, c.Relevant)
+		}
+		report.RecallAtK += result.RecallAtK
+		report.MRR += result.ReciprocalRank
+		report.Cases = append(report.Cases, result)
+	}
+
+	if n := len(report.Cases); n > 0 {
+		report.RecallAtK /= float64(n)
+		report.MRR /= float64(n)
+	}
+	report.Duration = time.Since(report.StartedAt).Seconds()
+	return report, nil
+}
+
+// result holds the labels a case may use for a search result. The first is its UUID.
+type result []string
+
+func search(
+	ctx context.Context,
+	appState *models.AppState,
+	c models.EvalCase,
+	req models.EvalRunRequest,
+	k int,
+) ([]result, error) {
+	if c.SessionID != "" {
+		results, err := appState.MemoryStore.SearchMemory(
+			ctx,
+			c.SessionID,
+			&models.MemorySearchPayload{
+				Text:       c.Query,
+				SearchType: req.SearchType,
+				MMRLambda:  req.MMRLambda,
+				Exact:      req.Exact,
+			},
+			k,
+		)
+		if err != nil {
+			return nil, err
+		}
+		retrieved := make([]result, 0, len(results))
+		for _, r := range results {
+			switch {
+			case r.Message != nil:
+				retrieved = append(retrieved, result{r.Message.UUID.String()})
+			case r.Summary != nil:
+				retrieved = append(retrieved, result{r.Summary.UUID.String()})
+			}
+		}
+		return retrieved, nil
+	}
+
+	searchType := req.SearchType
+	if searchType == "" {
+		searchType = models.SearchTypeSimilarity
+	}
+	page, err := appState.DocumentStore.SearchCollection(
+		ctx,
+		&models.DocumentSearchPayload{
+			CollectionName: c.CollectionName,
+			Text:           c.Query,
+			SearchType:     searchType,
+			MMRLambda:      req.MMRLambda,
+			Exact:          req.Exact,
+		},
+		k,
+		0,
+		0,
+	)
+	if err != nil {
+		return nil, err
+	}
+	retrieved := make([]result, 0, len(page.Results))
+	for _, r := range page.Results {
+		if r.DocumentResponse == nil {
+			continue
+		}
+		labels := result{r.UUID.String()}
+		if r.DocumentID != "" {
+			labels = append(labels, r.DocumentID)
+		}
+		retrieved = append(retrieved, labels)
+	}
+	return retrieved, nil
+}
+
+// score returns the fraction of relevant labels retrieved, and the reciprocal rank of the
+// first relevant result.
+func score(retrieved []result, relevant []string) (recall float64, reciprocalRank float64) {
+	want := make(map[string]bool, len(relevant))
+	for _, label := range relevant {
+		want[normalize(label)] = true
+	}
+	if len(want) == 0 {
+		return 0, 0
+	}
+
+	found := make(map[string]bool, len(want))
+	for i, r := range retrieved {
+		for _, label := range r {
+			label = normalize(label)
+			if !want[label] {
+				continue
+			}
+			found[label] = true
+			if reciprocalRank == 0 {
+				reciprocalRank = 1 / float64(i+1)
+			}
+		}
+	}
+	return float64(len(found)) / float64(len(want)), reciprocalRank
+}
+
+// normalize makes UUID labels case insensitive. Document IDs are compared as given.
+func normalize(label string) string {
+	if id, err := uuid.Parse(label); err == nil {
+		return id.String()
+	}
+	return label
+}
+
+func uuids(retrieved []result) []string {
+	ids := make([]string, len(retrieved))
+	for i, r := range retrieved {
+		ids[i] = r[0]
+	}
+	return ids
+}
@@ -0,0 +1,149 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// fakeMemoryStore returns messages with the given UUIDs, in order. Only SearchMemory is
+// implemented.
+type fakeMemoryStore struct {
+	models.MemoryStore[any]
+	results map[string][]uuid.UUID
+	limits  []int
+	queries []models.MemorySearchPayload
+}
+
+func (s *fakeMemoryStore) SearchMemory(
+	_ context.Context,
+	sessionID string,
+	query *models.MemorySearchPayload,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	s.limits = append(s.limits, limit)
+	s.queries = append(s.queries, *query)
+	ids, ok := s.results[sessionID]
+	if !ok {
+		return nil, models.NewNotFoundError("session " + sessionID)
+	}
+	results := make([]models.MemorySearchResult, len(ids))
+	for i, id := range ids {
+		results[i] = models.MemorySearchResult{Message: &models.Message{UUID: id}}
+	}
+	return results, nil
+}
+
+type fakeDocumentStore struct {
+	models.DocumentStore[any]
+	results []models.DocumentSearchResult
+	err     error
+}
+
+func (s *fakeDocumentStore) SearchCollection(
+	_ context.Context,
+	query *models.DocumentSearchPayload,
+	_, _, _ int,
+) (*models.DocumentSearchResultPage, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if query.SearchType != models.SearchTypeSimilarity {
+		return nil, errors.New("unexpected search type")
+	}
+	return &models.DocumentSearchResultPage{Results: s.results}, nil
+}
+
+func TestRun(t *testing.T) {
+	m1, m2, m3 := uuid.New(), uuid.New(), uuid.New()
+	d1, d2 := uuid.New(), uuid.New()
+	memoryStore := &fakeMemoryStore{results: map[string][]uuid.UUID{"s1": {m1, m2, m3}}}
+	documentStore := &fakeDocumentStore{results: []models.DocumentSearchResult{
+		{DocumentResponse: &models.DocumentResponse{UUID: d1, DocumentID: "doc-1"}},
+		{DocumentResponse: &models.DocumentResponse{UUID: d2}},
+	}}
+	appState := &models.AppState{MemoryStore: memoryStore, DocumentStore: documentStore}
+
+	set := &models.EvalSet{
+		Name: "golden",
+		Cases: []models.EvalCase{
+			// the first relevant message is ranked second, and one of two is retrieved
+			{Query: "q1", SessionID: "s1", Relevant: []string{m2.String(), uuid.NewString()}},
+			// documents may be labeled by document ID or UUID
+			{Query: "q2", CollectionName: "c1", Relevant: []string{"doc-1", d2.String()}},
+			{Query: "q3", SessionID: "missing", Relevant: []string{m1.String()}},
+		},
+	}
+	report, err := Run(context.Background(), appState, set, models.EvalRunRequest{})
+	require.NoError(t, err)
+
+	assert.Equal(t, DefaultK, report.K)
+	assert.Equal(t, []int{DefaultK, DefaultK}, memoryStore.limits)
+	require.Len(t, report.Cases, 3)
+
+	assert.Equal(t, 0.5, report.Cases[0].RecallAtK)
+	assert.Equal(t, 0.5, report.Cases[0].ReciprocalRank)
+	assert.Equal(t, []string{m1.String(), m2.String(), m3.String()}, report.Cases[0].Retrieved)
+
+	assert.Equal(t, 1.0, report.Cases[1].RecallAtK)
+	assert.Equal(t, 1.0, report.Cases[1].ReciprocalRank)
+	assert.Equal(t, []string{d1.String(), d2.String()}, report.Cases[1].Retrieved)
+
+	assert.NotEmpty(t, report.Cases[2].Error)
+	assert.Equal(t, 1, report.Failed)
+
+	assert.InDelta(t, 1.5/3, report.RecallAtK, 1e-9)
+	assert.InDelta(t, 1.5/3, report.MRR, 1e-9)
+}
+
+func TestRunVariant(t *testing.T) {
+	memoryStore := &fakeMemoryStore{results: map[string][]uuid.UUID{"s1": {}}}
+	appState := &models.AppState{Config: &config.Config{}, MemoryStore: memoryStore}
+	appState.Config.Experiments.Retrieval = config.RetrievalExperimentConfig{
+		Enabled: true,
+		Name:    "mmr",
+		B:       config.RetrievalPreset{SearchType: "mmr", MMRLambda: 0.7},
+	}
+	set := &models.EvalSet{
+		Name:  "golden",
+		Cases: []models.EvalCase{{Query: "q1", SessionID: "s1", Relevant: []string{"a"}}},
+	}
+
+	report, err := Run(context.Background(), appState, set, models.EvalRunRequest{
+		K:          5,
+		SearchType: models.SearchTypeSimilarity,
+		Variant:    "b",
+		Exact:      true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "b", report.Variant)
+	assert.True(t, report.Exact)
+	assert.Equal(t, 5, report.K)
+	require.Len(t, memoryStore.queries, 1)
+	assert.Equal(t, models.SearchTypeMMR, memoryStore.queries[0].SearchType)
+	assert.Equal(t, float32(0.7), memoryStore.queries[0].MMRLambda)
+	assert.True(t, memoryStore.queries[0].Exact)
+}
+
+func TestScore(t *testing.T) {
+	id := uuid.New()
+	retrieved := []result{{"a"}, {id.String()}, {"b"}}
+
+	recall, rr := score(retrieved, []string{"x"})
+	assert.Zero(t, recall)
+	assert.Zero(t, rr)
+
+	// UUIDs are matched regardless of case, unlike document IDs, and duplicate labels
+	// count once
+	recall, rr = score(retrieved, []string{"B", "b", "b", strings.ToUpper(id.String())})
+	assert.InDelta(t, 2.0/3, recall, 1e-9)
+	assert.Equal(t, 0.5, rr)
+}
@@ -0,0 +1,114 @@
+// Package experiment assigns sessions and collections to the variants of an A/B
+// experiment on search settings.
+package experiment
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	VariantA = "a"
+	VariantB = "b"
+
+	// VariantHeader is the response header naming the variant a search was made with.
+	VariantHeader = "X-Zep-Experiment-Variant"
+
+	DefaultSplit = 0.5
+)
+
+// ValidateRetrieval returns an error if the retrieval experiment is enabled with invalid
+// settings.
+func ValidateRetrieval(cfg config.RetrievalExperimentConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Name == "" {
+		return errors.New("experiments.retrieval.name is required")
+	}
+	if cfg.Split < 0 || cfg.Split > 1 {
+		return fmt.Errorf("experiments.retrieval.split must be between 0 and 1, got %v", cfg.Split)
+	}
+	for variant, preset := range map[string]config.RetrievalPreset{
+		VariantA: cfg.A,
+		VariantB: cfg.B,
+	} {
+		switch models.SearchType(preset.SearchType) {
+		case "", models.SearchTypeSimilarity, models.SearchTypeMMR:
+		default:
+			return fmt.Errorf(
+				"invalid experiments.retrieval.%s.search_type %q",
+				variant,
+				preset.SearchType,
+			)
+		}
+		if preset.MMRLambda < 0 || preset.MMRLambda > 1 {
+			return fmt.Errorf("experiments.retrieval.%s.mmr_lambda must be between 0 and 1", variant)
+		}
+	}
+	return nil
+}
+
+// Assign returns the variant of a session or collection. Assignments are stable for an
+// experiment's name and split.
+func Assign(cfg config.RetrievalExperimentConfig, unit string) string {
+	split := cfg.Split
+	if split == 0 {
+		split = DefaultSplit
+	}
+	// FNV hashes of similar IDs, such as sequential session IDs, aren't spread evenly
+	sum := sha256.Sum256([]byte(cfg.Name + "/" + unit))
+	if float64(binary.BigEndian.Uint64(sum[:8]))/math.MaxUint64 < split {
+		return VariantB
+	}
+	return VariantA
+}
+
+// Preset returns the preset of a variant.
+func Preset(cfg config.RetrievalExperimentConfig, variant string) config.RetrievalPreset {
+	if variant == VariantB {
+		return cfg.B
+	}
+	return cfg.A
+}
+
+// ApplyToMemorySearch applies the preset of the session's variant to query and returns
+// the variant, unless the experiment is disabled or query sets a search type, in which
+// case "" is returned.
+func ApplyToMemorySearch(
+	cfg config.RetrievalExperimentConfig,
+	sessionID string,
+	query *models.MemorySearchPayload,
+) string {
+	if !cfg.Enabled || query.SearchType != "" {
+		return ""
+	}
+	variant := Assign(cfg, sessionID)
+	preset := Preset(cfg, variant)
+	query.SearchType = models.SearchType(preset.SearchType)
+	query.MMRLambda = preset.MMRLambda
+	return variant
+}
+
+// ApplyToDocumentSearch applies the preset of the collection's variant to query and
+// returns the variant, unless the experiment is disabled or query sets a search type, in
+// which case "" is returned.
+func ApplyToDocumentSearch(
+	cfg config.RetrievalExperimentConfig,
+	query *models.DocumentSearchPayload,
+) string {
+	if !cfg.Enabled || query.SearchType != "" {
+		return ""
+	}
+	variant := Assign(cfg, query.CollectionName)
+	preset := Preset(cfg, variant)
+	query.SearchType = models.SearchType(preset.SearchType)
+	query.MMRLambda = preset.MMRLambda
+	return variant
+}
@@ -0,0 +1,77 @@
+package experiment
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+func testConfig() config.RetrievalExperimentConfig {
+	return config.RetrievalExperimentConfig{
+		Enabled: true,
+		Name:    "mmr",
+		A:       config.RetrievalPreset{SearchType: "similarity"},
+		B:       config.RetrievalPreset{SearchType: "mmr", MMRLambda: 0.7},
+	}
+}
+
+func TestAssign(t *testing.T) {
+	cfg := testConfig()
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		session := fmt.Sprintf("session-%d", i)
+		variant := Assign(cfg, session)
+		assert.Equal(t, variant, Assign(cfg, session), "assignments are stable")
+		counts[variant]++
+	}
+	assert.InDelta(t, 500, counts[VariantB], 75)
+
+	cfg.Split = 0.1
+	counts = map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[Assign(cfg, fmt.Sprintf("session-%d", i))]++
+	}
+	assert.InDelta(t, 100, counts[VariantB], 50)
+}
+
+func TestApplyToMemorySearch(t *testing.T) {
+	cfg := testConfig()
+	cfg.Split = 1
+
+	query := &models.MemorySearchPayload{Text: "pizza"}
+	assert.Equal(t, VariantB, ApplyToMemorySearch(cfg, "s1", query))
+	assert.Equal(t, models.SearchTypeMMR, query.SearchType)
+	assert.Equal(t, float32(0.7), query.MMRLambda)
+
+	// searches setting a search type aren't part of the experiment
+	query = &models.MemorySearchPayload{Text: "pizza", SearchType: models.SearchTypeSimilarity}
+	assert.Empty(t, ApplyToMemorySearch(cfg, "s1", query))
+	assert.Equal(t, models.SearchTypeSimilarity, query.SearchType)
+
+	cfg.Enabled = false
+	query = &models.MemorySearchPayload{Text: "pizza"}
+	assert.Empty(t, ApplyToMemorySearch(cfg, "s1", query))
+	assert.Empty(t, query.SearchType)
+}
+
+func TestValidateRetrieval(t *testing.T) {
+	assert.NoError(t, ValidateRetrieval(config.RetrievalExperimentConfig{}))
+	assert.NoError(t, ValidateRetrieval(testConfig()))
+
+	cfg := testConfig()
+	cfg.Name = ""
+	assert.Error(t, ValidateRetrieval(cfg))
+
+	cfg = testConfig()
+	cfg.Split = 1.5
+	assert.Error(t, ValidateRetrieval(cfg))
+
+	cfg = testConfig()
+	cfg.B.SearchType = "rerank"
+	assert.ErrorContains(t, ValidateRetrieval(cfg), "experiments.retrieval.b.search_type")
+}
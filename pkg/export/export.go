@@ -0,0 +1,544 @@
+// Package export writes the users, sessions, and document collections of a Zep deployment
+// to an archive and imports them into another deployment. Zep has no tenant partitioning,
+// so a tenant sharing a deployment is identified by its user IDs and collection names.
+//
+// An archive is a stream of JSON records, one per line, starting with a header. Users are
+// written before sessions, so that a session's user exists when it is imported.
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+var log = internal.GetLogger()
+
+// ArchiveVersion is the version of the archive format written by Export.
+const ArchiveVersion = 1
+
+const (
+	listPageSize     = 100
+	messagePageSize  = 1000
+	documentPageSize = 1000
+)
+
+// RecordKind is the type of an archive record.
+type RecordKind string
+
+const (
+	RecordHeader     RecordKind = "header"
+	RecordUser       RecordKind = "user"
+	RecordSession    RecordKind = "session"
+	RecordCollection RecordKind = "collection"
+	RecordDocuments  RecordKind = "documents"
+)
+
+// Record is a line of an archive. Only the fields of its Kind are set.
+type Record struct {
+	Kind RecordKind `json:"kind"`
+
+	// header
+	Version   int        `json:"version,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	User *models.User `json:"user,omitempty"`
+
+	// session
+	Session           *models.Session   `json:"session,omitempty"`
+	Messages          []models.Message  `json:"messages,omitempty"`
+	MessageEmbeddings []models.TextData `json:"message_embeddings,omitempty"`
+	Summaries         []models.Summary  `json:"summaries,omitempty"`
+
+	Collection *models.DocumentCollection `json:"collection,omitempty"`
+
+	// documents
+	CollectionName string            `json:"collection_name,omitempty"`
+	Documents      []models.Document `json:"documents,omitempty"`
+}
+
+// Options scope an export. A nil list exports everything of its kind: if UserIDs is nil,
+// all users and all sessions, including sessions without a user, are exported. Otherwise
+// only the given users and their sessions are. Use an empty, non-nil list to export none.
+type Options struct {
+	UserIDs     []string
+	Collections []string
+}
+
+// Stats counts the records exported or imported.
+type Stats struct {
+	Users       int `json:"users"`
+	Sessions    int `json:"sessions"`
+	Messages    int `json:"messages"`
+	Summaries   int `json:"summaries"`
+	Collections int `json:"collections"`
+	Documents   int `json:"documents"`
+}
+
+// Export writes the users, sessions, and collections selected by opts to w.
+func Export(
+	ctx context.Context,
+	appState *models.AppState,
+	w io.Writer,
+	opts Options,
+) (*Stats, error) {
+	e := &exporter{
+		appState: appState,
+		enc:      json.NewEncoder(w),
+		stats:    &Stats{},
+	}
+
+	createdAt := time.Now().UTC()
+	err := e.write(&Record{Kind: RecordHeader, Version: ArchiveVersion, CreatedAt: &createdAt})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.exportUsers(ctx, opts.UserIDs); err != nil {
+		return e.stats, err
+	}
+	if err := e.exportCollections(ctx, opts.Collections); err != nil {
+		return e.stats, err
+	}
+
+	return e.stats, nil
+}
+
+type exporter struct {
+	appState *models.AppState
+	enc      *json.Encoder
+	stats    *Stats
+}
+
+func (e *exporter) write(rec *Record) error {
+	if err := e.enc.Encode(rec); err != nil {
+		return fmt.Errorf("failed to write %s record: %w", rec.Kind, err)
+	}
+	return nil
+}
+
+func (e *exporter) exportUsers(ctx context.Context, userIDs []string) error {
+	if userIDs == nil {
+		return e.exportAllUsers(ctx)
+	}
+
+	for _, userID := range userIDs {
+		user, err := e.appState.UserStore.Get(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user %s: %w", userID, err)
+		}
+		if err := e.write(&Record{Kind: RecordUser, User: user}); err != nil {
+			return err
+		}
+		e.stats.Users++
+	}
+
+	for _, userID := range userIDs {
+		sessions, err := e.appState.UserStore.GetSessions(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get sessions of user %s: %w", userID, err)
+		}
+		for _, session := range sessions {
+			if err := e.exportSession(ctx, session); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *exporter) exportAllUsers(ctx context.Context) error {
+	var cursor int64
+	for {
+		users, err := e.appState.UserStore.ListAll(ctx, cursor, listPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list users: %w", err)
+		}
+		for _, user := range users {
+			if err := e.write(&Record{Kind: RecordUser, User: user}); err != nil {
+				return err
+			}
+			e.stats.Users++
+			cursor = user.ID
+		}
+		if len(users) < listPageSize {
+			break
+		}
+	}
+
+	cursor = 0
+	for {
+		sessions, err := e.appState.MemoryStore.ListSessions(ctx, cursor, listPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list sessions: %w", err)
+		}
+		for _, session := range sessions {
+			if err := e.exportSession(ctx, session); err != nil {
+				return err
+			}
+			cursor = session.ID
+		}
+		if len(sessions) < listPageSize {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (e *exporter) exportSession(ctx context.Context, session *models.Session) error {
+	rec := &Record{Kind: RecordSession, Session: session}
+
+	for page := 1; ; page++ {
+		list, err := e.appState.MemoryStore.GetMessageList(
+			ctx,
+			session.SessionID,
+			page,
+			messagePageSize,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get messages of session %s: %w", session.SessionID, err)
+		}
+		rec.Messages = append(rec.Messages, list.Messages...)
+		if len(list.Messages) < messagePageSize {
+			break
+		}
+	}
+
+	embeddings, err := e.appState.MemoryStore.GetMessageEmbeddings(ctx, session.SessionID)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to get message embeddings of session %s: %w",
+			session.SessionID,
+			err,
+		)
+	}
+	rec.MessageEmbeddings = embeddings
+
+	for page := 1; ; page++ {
+		list, err := e.appState.MemoryStore.GetSummaryList(
+			ctx,
+			session.SessionID,
+			page,
+			messagePageSize,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get summaries of session %s: %w", session.SessionID, err)
+		}
+		rec.Summaries = append(rec.Summaries, list.Summaries...)
+		if len(list.Summaries) < messagePageSize {
+			break
+		}
+	}
+
+	if err := e.write(rec); err != nil {
+		return err
+	}
+	e.stats.Sessions++
+	e.stats.Messages += len(rec.Messages)
+	e.stats.Summaries += len(rec.Summaries)
+
+	return nil
+}
+
+func (e *exporter) exportCollections(ctx context.Context, names []string) error {
+	if e.appState.DocumentStore == nil {
+		if len(names) > 0 {
+			return errors.New("no document store is configured")
+		}
+		return nil
+	}
+
+	var collections []models.DocumentCollection
+	if names == nil {
+		var err error
+		collections, err = e.appState.DocumentStore.GetCollectionList(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list collections: %w", err)
+		}
+	} else {
+		for _, name := range names {
+			collection, err := e.appState.DocumentStore.GetCollection(ctx, name)
+			if err != nil {
+				return fmt.Errorf("failed to get collection %s: %w", name, err)
+			}
+			collections = append(collections, collection)
+		}
+	}
+
+	for i := range collections {
+		if err := e.exportCollection(ctx, &collections[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *exporter) exportCollection(ctx context.Context, collection *models.DocumentCollection) error {
+	if err := e.write(&Record{Kind: RecordCollection, Collection: collection}); err != nil {
+		return err
+	}
+	e.stats.Collections++
+
+	documents, err := e.appState.DocumentStore.GetDocuments(ctx, collection.Name, nil, nil)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return fmt.Errorf("failed to get documents of collection %s: %w", collection.Name, err)
+	}
+
+	for start := 0; start < len(documents); start += documentPageSize {
+		batch := documents[start:min(start+documentPageSize, len(documents))]
+		err := e.write(&Record{
+			Kind:           RecordDocuments,
+			CollectionName: collection.Name,
+			Documents:      batch,
+		})
+		if err != nil {
+			return err
+		}
+		e.stats.Documents += len(batch)
+	}
+
+	return nil
+}
+
+// Import creates the users, sessions, and collections of an archive written by Export.
+// Existing records are never modified: if a user, session, or collection of the archive
+// already exists, Import stops with a models.ConflictError. Records imported before the
+// conflict are kept, so archives are best imported into an empty deployment.
+//
+// The content of messages and documents is verified against the hashes they were exported
+// with, and a record whose content doesn't match is rejected with a models.ValidationError.
+// Archives written before content was hashed have no hashes to verify.
+//
+// Message UUIDs, metadata, and embeddings are preserved, and the imported messages are not
+// sent to the extractors again. Summaries are recreated, and their embeddings and entities
+// are extracted by the extractors. Documents of auto-embedded collections are embedded
+// again by the importing deployment.
+func Import(ctx context.Context, appState *models.AppState, r io.Reader) (*Stats, error) {
+	stats := &Stats{}
+
+	scanner := bufio.NewScanner(r)
+	// records of large sessions or document batches can be long
+	scanner.Buffer(make([]byte, 0, 1024*1024), 1024*1024*1024)
+
+	autoEmbedded := make(map[string]bool)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return stats, fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+		if line == 1 {
+			if rec.Kind != RecordHeader {
+				return stats, models.NewValidationError("archive does not start with a header")
+			}
+			if rec.Version != ArchiveVersion {
+				return stats, models.NewValidationError(
+					fmt.Sprintf("unsupported archive version %d", rec.Version),
+				)
+			}
+			continue
+		}
+
+		var err error
+		switch rec.Kind {
+		case RecordUser:
+			err = importUser(ctx, appState, &rec, stats)
+		case RecordSession:
+			err = importSession(ctx, appState, &rec, stats)
+		case RecordCollection:
+			err = importCollection(ctx, appState, &rec, stats)
+			if err == nil {
+				autoEmbedded[rec.Collection.Name] = rec.Collection.IsAutoEmbedded
+			}
+		case RecordDocuments:
+			err = importDocuments(ctx, appState, &rec, autoEmbedded, stats)
+		default:
+			err = models.NewValidationError(fmt.Sprintf("unknown record kind %q", rec.Kind))
+		}
+		if err != nil {
+			return stats, fmt.Errorf("failed to import line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if line == 0 {
+		return stats, models.NewValidationError("archive is empty")
+	}
+
+	return stats, nil
+}
+
+func importUser(ctx context.Context, appState *models.AppState, rec *Record, stats *Stats) error {
+	if rec.User == nil {
+		return models.NewValidationError("user record has no user")
+	}
+	_, err := appState.UserStore.Create(ctx, &models.CreateUserRequest{
+		UserID:    rec.User.UserID,
+		Email:     rec.User.Email,
+		FirstName: rec.User.FirstName,
+		LastName:  rec.User.LastName,
+		Metadata:  rec.User.Metadata,
+	})
+	if err != nil {
+		return err
+	}
+	stats.Users++
+	return nil
+}
+
+func importSession(ctx context.Context, appState *models.AppState, rec *Record, stats *Stats) error {
+	if rec.Session == nil {
+		return models.NewValidationError("session record has no session")
+	}
+	sessionID := rec.Session.SessionID
+	for _, message := range rec.Messages {
+		err := verifyContentHash("message", message.UUID, message.ContentHash, message.Content)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := appState.MemoryStore.CreateSession(ctx, &models.CreateSessionRequest{
+		SessionID: sessionID,
+		UserID:    rec.Session.UserID,
+		Metadata:  rec.Session.Metadata,
+		ExpiresAt: rec.Session.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(rec.Messages) > 0 {
+		err = appState.MemoryStore.PutMemory(ctx, sessionID, &models.Memory{
+			Messages: rec.Messages,
+		}, true)
+		if err != nil {
+			return fmt.Errorf("failed to import messages of session %s: %w", sessionID, err)
+		}
+	}
+
+	if len(rec.MessageEmbeddings) > 0 {
+		err = appState.MemoryStore.CreateMessageEmbeddings(ctx, sessionID, rec.MessageEmbeddings)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to import message embeddings of session %s: %w",
+				sessionID,
+				err,
+			)
+		}
+	}
+
+	// summaries are listed oldest first, so the last one created is the session's current summary
+	for i := range rec.Summaries {
+		summary := rec.Summaries[i]
+		summary.UUID = uuid.Nil
+		if err := appState.MemoryStore.CreateSummary(ctx, sessionID, &summary); err != nil {
+			return fmt.Errorf("failed to import summary of session %s: %w", sessionID, err)
+		}
+	}
+
+	stats.Sessions++
+	stats.Messages += len(rec.Messages)
+	stats.Summaries += len(rec.Summaries)
+	log.Debugf("imported session %s", sessionID)
+
+	return nil
+}
+
+func importCollection(
+	ctx context.Context,
+	appState *models.AppState,
+	rec *Record,
+	stats *Stats,
+) error {
+	if rec.Collection == nil {
+		return models.NewValidationError("collection record has no collection")
+	}
+	if appState.DocumentStore == nil {
+		return errors.New("no document store is configured")
+	}
+
+	// the table, index, and counts belong to the exporting deployment
+	collection := *rec.Collection
+	collection.UUID = uuid.Nil
+	collection.TableName = ""
+	collection.IsIndexed = false
+	collection.DocumentCollectionCounts = nil
+
+	if err := appState.DocumentStore.CreateCollection(ctx, collection); err != nil {
+		return err
+	}
+	stats.Collections++
+	return nil
+}
+
+func importDocuments(
+	ctx context.Context,
+	appState *models.AppState,
+	rec *Record,
+	autoEmbedded map[string]bool,
+	stats *Stats,
+) error {
+	isAutoEmbedded, ok := autoEmbedded[rec.CollectionName]
+	if !ok {
+		return models.NewValidationError(
+			"documents record precedes its collection: " + rec.CollectionName,
+		)
+	}
+
+	documents := make([]models.Document, len(rec.Documents))
+	for i, document := range rec.Documents {
+		err := verifyContentHash("document", document.UUID, document.ContentHash, document.Content)
+		if err != nil {
+			return err
+		}
+		documents[i] = models.Document{
+			DocumentBase: models.DocumentBase{
+				UUID:       document.UUID,
+				DocumentID: document.DocumentID,
+				Content:    document.Content,
+				Metadata:   document.Metadata,
+			},
+		}
+		// documents can't be created with embeddings in an auto-embedded collection
+		if !isAutoEmbedded {
+			documents[i].Embedding = document.Embedding
+		}
+	}
+
+	_, err := appState.DocumentStore.CreateDocuments(ctx, rec.CollectionName, documents)
+	if err != nil {
+		return err
+	}
+	stats.Documents += len(documents)
+	return nil
+}
+
+// verifyContentHash returns a ValidationError if an archived message or document has a
+// content hash that its content doesn't match, as when the archive has been corrupted.
+func verifyContentHash(kind string, id uuid.UUID, hash, content string) error {
+	if hash == "" || hash == models.ContentHash(content) {
+		return nil
+	}
+	return models.NewValidationError(
+		fmt.Sprintf("content of %s %s doesn't match its content hash", kind, id),
+	)
+}
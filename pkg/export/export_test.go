@@ -0,0 +1,197 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(models.TaskTopic, map[string]string, any) error { return nil }
+
+func (nopPublisher) PublishMessage(map[string]string, []models.MessageTask) error { return nil }
+
+func (nopPublisher) Close() error { return nil }
+
+func newAppState() *models.AppState {
+	db := inmemory.NewDB()
+	appState := &models.AppState{Config: &config.Config{}, TaskPublisher: nopPublisher{}}
+	appState.MemoryStore = inmemory.NewMemoryStore(appState, db)
+	appState.UserStore = inmemory.NewUserStore(db)
+	return appState
+}
+
+func seed(t *testing.T, appState *models.AppState) {
+	ctx := context.Background()
+
+	for _, userID := range []string{"alice", "bob"} {
+		_, err := appState.UserStore.Create(ctx, &models.CreateUserRequest{
+			UserID:   userID,
+			Metadata: map[string]interface{}{"tenant": userID},
+		})
+		require.NoError(t, err)
+
+		sessionID := userID + "-session"
+		_, err = appState.MemoryStore.CreateSession(ctx, &models.CreateSessionRequest{
+			SessionID: sessionID,
+			UserID:    &userID,
+		})
+		require.NoError(t, err)
+
+		err = appState.MemoryStore.PutMemory(ctx, sessionID, &models.Memory{
+			Messages: []models.Message{
+				{Role: "human", Content: "hello from " + userID},
+				{Role: "ai", Content: "hi " + userID, Metadata: map[string]interface{}{"a": "1"}},
+			},
+		}, true)
+		require.NoError(t, err)
+
+		memory, err := appState.MemoryStore.GetMemory(ctx, sessionID, 0)
+		require.NoError(t, err)
+		err = appState.MemoryStore.CreateSummary(ctx, sessionID, &models.Summary{
+			Content:          "a greeting",
+			SummaryPointUUID: memory.Messages[1].UUID,
+		})
+		require.NoError(t, err)
+		err = appState.MemoryStore.CreateMessageEmbeddings(ctx, sessionID, []models.TextData{{
+			TextUUID:  memory.Messages[0].UUID,
+			Embedding: []float32{0.6, 0.8},
+		}})
+		require.NoError(t, err)
+	}
+
+	// a session without a user
+	err := appState.MemoryStore.PutMemory(ctx, "anonymous", &models.Memory{
+		Messages: []models.Message{{Role: "human", Content: "anyone there?"}},
+	}, true)
+	require.NoError(t, err)
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := newAppState()
+	seed(t, source)
+
+	var archive bytes.Buffer
+	stats, err := Export(ctx, source, &archive, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, &Stats{Users: 2, Sessions: 3, Messages: 5, Summaries: 2}, stats)
+
+	target := newAppState()
+	stats, err = Import(ctx, target, bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, &Stats{Users: 2, Sessions: 3, Messages: 5, Summaries: 2}, stats)
+
+	user, err := target.UserStore.Get(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"tenant": "alice"}, user.Metadata)
+
+	session, err := target.MemoryStore.GetSession(ctx, "alice-session")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", *session.UserID)
+
+	want, err := source.MemoryStore.GetMemory(ctx, "alice-session", 0)
+	require.NoError(t, err)
+	got, err := target.MemoryStore.GetMemory(ctx, "alice-session", 0)
+	require.NoError(t, err)
+	require.Len(t, got.Messages, len(want.Messages))
+	for i := range want.Messages {
+		assert.Equal(t, want.Messages[i].UUID, got.Messages[i].UUID)
+		assert.Equal(t, want.Messages[i].Content, got.Messages[i].Content)
+		assert.Equal(t, want.Messages[i].Metadata, got.Messages[i].Metadata)
+	}
+	assert.Equal(t, "a greeting", got.Summary.Content)
+	assert.Equal(t, want.Summary.SummaryPointUUID, got.Summary.SummaryPointUUID)
+
+	embeddings, err := target.MemoryStore.GetMessageEmbeddings(ctx, "alice-session")
+	require.NoError(t, err)
+	require.Len(t, embeddings, 1)
+	assert.Equal(t, []float32{0.6, 0.8}, embeddings[0].Embedding)
+
+	// importing again conflicts, and doesn't modify the existing records
+	_, err = Import(ctx, target, bytes.NewReader(archive.Bytes()))
+	assert.ErrorIs(t, err, models.ErrConflict)
+	list, err := target.MemoryStore.GetMessageList(ctx, "alice-session", 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.TotalCount)
+}
+
+func TestImportContentHashMismatch(t *testing.T) {
+	ctx := context.Background()
+	source := newAppState()
+	seed(t, source)
+
+	var archive bytes.Buffer
+	_, err := Export(ctx, source, &archive, Options{})
+	require.NoError(t, err)
+	assert.Contains(t, archive.String(), models.ContentHash("hello from alice"))
+
+	// content modified after the export doesn't match its hash
+	tampered := strings.Replace(archive.String(), "hello from alice", "hello from mallory", 1)
+	target := newAppState()
+	_, err = Import(ctx, target, strings.NewReader(tampered))
+	assert.ErrorIs(t, err, models.ErrValidation)
+	_, err = target.MemoryStore.GetSession(ctx, "alice-session")
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestExportUsers(t *testing.T) {
+	ctx := context.Background()
+	source := newAppState()
+	seed(t, source)
+
+	var archive bytes.Buffer
+	stats, err := Export(ctx, source, &archive, Options{UserIDs: []string{"bob"}})
+	require.NoError(t, err)
+	assert.Equal(t, &Stats{Users: 1, Sessions: 1, Messages: 2, Summaries: 1}, stats)
+	assert.NotContains(t, archive.String(), "alice")
+	assert.NotContains(t, archive.String(), "anonymous")
+
+	// users are written before their sessions
+	target := newAppState()
+	_, err = Import(ctx, target, &archive)
+	require.NoError(t, err)
+	sessions, err := target.UserStore.GetSessions(ctx, "bob")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	_, err = Export(ctx, source, &bytes.Buffer{}, Options{UserIDs: []string{"carol"}})
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	// the in-memory store has no document store
+	_, err = Export(ctx, source, &bytes.Buffer{}, Options{Collections: []string{"docs"}})
+	assert.Error(t, err)
+}
+
+func TestImportInvalidArchive(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		archive string
+	}{
+		{"empty", ""},
+		{"no header", `{"kind":"user","user":{"user_id":"alice"}}`},
+		{"unsupported version", `{"kind":"header","version":99}`},
+		{"unknown kind", `{"kind":"header","version":1}` + "\n" + `{"kind":"widget"}`},
+		{
+			"documents without collection",
+			`{"kind":"header","version":1}` + "\n" + `{"kind":"documents","collection_name":"docs"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Import(ctx, newAppState(), strings.NewReader(tt.archive))
+			assert.ErrorIs(t, err, models.ErrValidation)
+		})
+	}
+}
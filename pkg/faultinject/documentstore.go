@@ -0,0 +1,147 @@
+package faultinject
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.DocumentStore[any] = &DocumentStore{}
+
+// DocumentStore injects faults into the calls of a models.DocumentStore. OnStart,
+// Shutdown, and GetClient are not faulted.
+type DocumentStore struct {
+	models.DocumentStore[any]
+	injector *Injector
+}
+
+// NewDocumentStore wraps documentStore with a DocumentStore injecting faults using injector.
+func NewDocumentStore(documentStore models.DocumentStore[any], injector *Injector) *DocumentStore {
+	return &DocumentStore{DocumentStore: documentStore, injector: injector}
+}
+
+func (s *DocumentStore) CreateCollection(
+	ctx context.Context,
+	collection models.DocumentCollection,
+) error {
+	if err := s.injector.Inject(ctx, "CreateCollection"); err != nil {
+		return err
+	}
+	return s.DocumentStore.CreateCollection(ctx, collection)
+}
+
+func (s *DocumentStore) UpdateCollection(
+	ctx context.Context,
+	collection models.DocumentCollection,
+) error {
+	if err := s.injector.Inject(ctx, "UpdateCollection"); err != nil {
+		return err
+	}
+	return s.DocumentStore.UpdateCollection(ctx, collection)
+}
+
+func (s *DocumentStore) GetCollection(
+	ctx context.Context,
+	collectionName string,
+) (models.DocumentCollection, error) {
+	if err := s.injector.Inject(ctx, "GetCollection"); err != nil {
+		return models.DocumentCollection{}, err
+	}
+	return s.DocumentStore.GetCollection(ctx, collectionName)
+}
+
+func (s *DocumentStore) GetCollectionList(ctx context.Context) ([]models.DocumentCollection, error) {
+	if err := s.injector.Inject(ctx, "GetCollectionList"); err != nil {
+		return nil, err
+	}
+	return s.DocumentStore.GetCollectionList(ctx)
+}
+
+func (s *DocumentStore) DeleteCollection(ctx context.Context, collectionName string) error {
+	if err := s.injector.Inject(ctx, "DeleteCollection"); err != nil {
+		return err
+	}
+	return s.DocumentStore.DeleteCollection(ctx, collectionName)
+}
+
+func (s *DocumentStore) CreateDocuments(
+	ctx context.Context,
+	collectionName string,
+	documents []models.Document,
+) ([]uuid.UUID, error) {
+	if err := s.injector.Inject(ctx, "CreateDocuments"); err != nil {
+		return nil, err
+	}
+	return s.DocumentStore.CreateDocuments(ctx, collectionName, documents)
+}
+
+func (s *DocumentStore) IngestDocuments(
+	ctx context.Context,
+	collectionName string,
+	documents []models.Document,
+) (*models.DocumentIngestResponse, error) {
+	if err := s.injector.Inject(ctx, "IngestDocuments"); err != nil {
+		return nil, err
+	}
+	return s.DocumentStore.IngestDocuments(ctx, collectionName, documents)
+}
+
+func (s *DocumentStore) UpdateDocuments(
+	ctx context.Context,
+	collectionName string,
+	documents []models.Document,
+) error {
+	if err := s.injector.Inject(ctx, "UpdateDocuments"); err != nil {
+		return err
+	}
+	return s.DocumentStore.UpdateDocuments(ctx, collectionName, documents)
+}
+
+func (s *DocumentStore) GetDocuments(
+	ctx context.Context,
+	collectionName string,
+	uuids []uuid.UUID,
+	documentIDs []string,
+) ([]models.Document, error) {
+	if err := s.injector.Inject(ctx, "GetDocuments"); err != nil {
+		return nil, err
+	}
+	return s.DocumentStore.GetDocuments(ctx, collectionName, uuids, documentIDs)
+}
+
+func (s *DocumentStore) DeleteDocuments(
+	ctx context.Context,
+	collectionName string,
+	documentUUIDs []uuid.UUID,
+) error {
+	if err := s.injector.Inject(ctx, "DeleteDocuments"); err != nil {
+		return err
+	}
+	return s.DocumentStore.DeleteDocuments(ctx, collectionName, documentUUIDs)
+}
+
+func (s *DocumentStore) SearchCollection(
+	ctx context.Context,
+	query *models.DocumentSearchPayload,
+	limit int,
+	pageNumber int,
+	pageSize int,
+) (*models.DocumentSearchResultPage, error) {
+	if err := s.injector.Inject(ctx, "SearchCollection"); err != nil {
+		return nil, err
+	}
+	return s.DocumentStore.SearchCollection(ctx, query, limit, pageNumber, pageSize)
+}
+
+func (s *DocumentStore) CreateCollectionIndex(
+	ctx context.Context,
+	collectionName string,
+	force bool,
+) error {
+	if err := s.injector.Inject(ctx, "CreateCollectionIndex"); err != nil {
+		return err
+	}
+	return s.DocumentStore.CreateCollectionIndex(ctx, collectionName, force)
+}
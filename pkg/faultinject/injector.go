@@ -0,0 +1,145 @@
+// Package faultinject wraps the memory, document, and user stores and the LLM client to
+// inject latency and errors into their calls. It is used in development to test client
+// retries and the resilience of the extractor pipeline. See config.FaultInjectionConfig.
+package faultinject
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+var log = internal.GetLogger()
+
+// ErrInjectedFault is wrapped by the errors of faulted calls. The errors also wrap
+// models.ErrDependencyFailure, so the API responds with a 502.
+var ErrInjectedFault = errors.New("injected fault")
+
+// Injector decides which calls to a dependency are delayed or failed.
+type Injector struct {
+	dependency string
+	cfg        config.FaultConfig
+
+	mu   sync.Mutex
+	rand *rand.Rand
+
+	calls   atomic.Int64
+	delayed atomic.Int64
+	failed  atomic.Int64
+}
+
+// NewInjector returns an Injector for calls to the named dependency. If seed is 0, a
+// random seed is used.
+func NewInjector(dependency string, cfg config.FaultConfig, seed int64) *Injector {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Injector{
+		dependency: dependency,
+		cfg:        cfg,
+		rand:       rand.New(rand.NewSource(seed)), //nolint:gosec
+	}
+}
+
+// Enabled is true if the Injector injects any faults.
+func (i *Injector) Enabled() bool {
+	return i.cfg.ErrorRate > 0 || (i.cfg.LatencyRate > 0 && i.cfg.Latency > 0)
+}
+
+// Inject is called before the operation op. It may sleep, and returns an error if the call
+// should fail. The sleep is cut short if ctx is done.
+func (i *Injector) Inject(ctx context.Context, op string) error {
+	i.calls.Add(1)
+
+	delay, fail := i.draw()
+	if delay > 0 {
+		i.delayed.Add(1)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if fail {
+		i.failed.Add(1)
+		log.Debugf("fault injection: failing %s %s", i.dependency, op)
+		return models.NewDependencyFailureError(i.dependency+" "+op, ErrInjectedFault)
+	}
+	return nil
+}
+
+// Stats returns the number of calls seen, delayed, and failed.
+func (i *Injector) Stats() (calls, delayed, failed int64) {
+	return i.calls.Load(), i.delayed.Load(), i.failed.Load()
+}
+
+func (i *Injector) draw() (time.Duration, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var delay time.Duration
+	if i.cfg.Latency > 0 && i.rand.Float64() < i.cfg.LatencyRate {
+		delay = time.Duration(i.rand.Int63n(int64(i.cfg.Latency)*int64(time.Millisecond)) + 1)
+	}
+	fail := i.rand.Float64() < i.cfg.ErrorRate
+	return delay, fail
+}
+
+// Wrap replaces the stores and LLM client of appState with fault injecting wrappers, as
+// configured by cfg.FaultInjection. Nothing is wrapped unless fault injection is enabled
+// and the config is in development mode.
+func Wrap(appState *models.AppState) {
+	cfg := appState.Config
+	if !cfg.FaultInjection.Enabled {
+		return
+	}
+	if !cfg.Development {
+		log.Warn("fault_injection is enabled but development mode is not. no faults will be injected")
+		return
+	}
+
+	seed := cfg.FaultInjection.Seed
+	store := NewInjector("store", cfg.FaultInjection.Store, seed)
+	if store.Enabled() {
+		log.Warnf(
+			"fault injection enabled for stores: error_rate=%v latency_rate=%v latency=%dms",
+			cfg.FaultInjection.Store.ErrorRate,
+			cfg.FaultInjection.Store.LatencyRate,
+			cfg.FaultInjection.Store.Latency,
+		)
+		if appState.MemoryStore != nil {
+			appState.MemoryStore = NewMemoryStore(appState.MemoryStore, store)
+		}
+		if appState.DocumentStore != nil {
+			appState.DocumentStore = NewDocumentStore(appState.DocumentStore, store)
+		}
+		if appState.UserStore != nil {
+			appState.UserStore = NewUserStore(appState.UserStore, store)
+		}
+	}
+
+	// offset the seed so that store and LLM faults aren't correlated
+	if seed != 0 {
+		seed++
+	}
+	llm := NewInjector("llm", cfg.FaultInjection.LLM, seed)
+	if llm.Enabled() && appState.LLMClient != nil {
+		log.Warnf(
+			"fault injection enabled for the LLM: error_rate=%v latency_rate=%v latency=%dms",
+			cfg.FaultInjection.LLM.ErrorRate,
+			cfg.FaultInjection.LLM.LatencyRate,
+			cfg.FaultInjection.LLM.Latency,
+		)
+		appState.LLMClient = NewLLM(appState.LLMClient, llm)
+	}
+}
@@ -0,0 +1,115 @@
+package faultinject
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestInjector(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no faults", func(t *testing.T) {
+		injector := NewInjector("store", config.FaultConfig{}, 1)
+		assert.False(t, injector.Enabled())
+		for i := 0; i < 100; i++ {
+			require.NoError(t, injector.Inject(ctx, "op"))
+		}
+		calls, delayed, failed := injector.Stats()
+		assert.Equal(t, int64(100), calls)
+		assert.Zero(t, delayed)
+		assert.Zero(t, failed)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		injector := NewInjector("store", config.FaultConfig{ErrorRate: 1}, 1)
+		err := injector.Inject(ctx, "GetSession")
+		assert.ErrorIs(t, err, ErrInjectedFault)
+		assert.ErrorIs(t, err, models.ErrDependencyFailure)
+		assert.Contains(t, err.Error(), "store GetSession")
+	})
+
+	t.Run("error rate", func(t *testing.T) {
+		injector := NewInjector("store", config.FaultConfig{ErrorRate: 0.25}, 1)
+		for i := 0; i < 1000; i++ {
+			_ = injector.Inject(ctx, "op")
+		}
+		_, _, failed := injector.Stats()
+		assert.InDelta(t, 250, failed, 50)
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		injector := NewInjector("llm", config.FaultConfig{LatencyRate: 1, Latency: 20}, 1)
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			require.NoError(t, injector.Inject(ctx, "Call"))
+		}
+		assert.Less(t, time.Since(start), 5*20*time.Millisecond+time.Second)
+		_, delayed, _ := injector.Stats()
+		assert.Equal(t, int64(5), delayed)
+	})
+
+	t.Run("latency is cut short by the context", func(t *testing.T) {
+		injector := NewInjector("llm", config.FaultConfig{LatencyRate: 1, Latency: 60_000}, 1)
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		err := injector.Inject(ctx, "Call")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestWrap(t *testing.T) {
+	newAppState := func(development bool) *models.AppState {
+		cfg := &config.Config{Development: development}
+		cfg.FaultInjection = config.FaultInjectionConfig{
+			Enabled: true,
+			Store:   config.FaultConfig{ErrorRate: 1},
+			LLM:     config.FaultConfig{ErrorRate: 1},
+		}
+		llm, err := llms.NewFakeLLM(context.Background(), cfg)
+		require.NoError(t, err)
+		db := inmemory.NewDB()
+		return &models.AppState{
+			Config:      cfg,
+			LLMClient:   llm,
+			MemoryStore: inmemory.NewMemoryStore(nil, db),
+			UserStore:   inmemory.NewUserStore(db),
+		}
+	}
+
+	t.Run("development", func(t *testing.T) {
+		appState := newAppState(true)
+		Wrap(appState)
+
+		_, err := appState.MemoryStore.GetSession(context.Background(), "session")
+		assert.ErrorIs(t, err, ErrInjectedFault)
+		_, err = appState.UserStore.Get(context.Background(), "user")
+		assert.ErrorIs(t, err, ErrInjectedFault)
+		_, err = appState.LLMClient.Call(context.Background(), "prompt")
+		assert.ErrorIs(t, err, ErrInjectedFault)
+
+		// calls that aren't faulted are passed through
+		tokens, err := appState.LLMClient.GetTokenCount("one two")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, tokens)
+		assert.NoError(t, appState.MemoryStore.Close())
+	})
+
+	t.Run("not in development", func(t *testing.T) {
+		appState := newAppState(false)
+		Wrap(appState)
+
+		_, err := appState.MemoryStore.GetSession(context.Background(), "session")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		_, ok := appState.MemoryStore.(*MemoryStore)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,36 @@
+package faultinject
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.ZepLLM = &LLM{}
+
+// LLM injects faults into the completion and embedding calls of a models.ZepLLM.
+type LLM struct {
+	models.ZepLLM
+	injector *Injector
+}
+
+// NewLLM wraps llm with an LLM injecting faults using injector.
+func NewLLM(llm models.ZepLLM, injector *Injector) *LLM {
+	return &LLM{ZepLLM: llm, injector: injector}
+}
+
+func (l *LLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	if err := l.injector.Inject(ctx, "Call"); err != nil {
+		return "", err
+	}
+	return l.ZepLLM.Call(ctx, prompt, options...)
+}
+
+func (l *LLM) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := l.injector.Inject(ctx, "EmbedTexts"); err != nil {
+		return nil, err
+	}
+	return l.ZepLLM.EmbedTexts(ctx, texts)
+}
@@ -0,0 +1,402 @@
+package faultinject
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.MemoryStore[any] = &MemoryStore{}
+
+// MemoryStore injects faults into the calls of a models.MemoryStore. Close is not faulted.
+type MemoryStore struct {
+	models.MemoryStore[any]
+	injector *Injector
+}
+
+// NewMemoryStore wraps memoryStore with a MemoryStore injecting faults using injector.
+func NewMemoryStore(memoryStore models.MemoryStore[any], injector *Injector) *MemoryStore {
+	return &MemoryStore{MemoryStore: memoryStore, injector: injector}
+}
+
+func (s *MemoryStore) CreateSession(
+	ctx context.Context,
+	session *models.CreateSessionRequest,
+) (*models.Session, error) {
+	if err := s.injector.Inject(ctx, "CreateSession"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.CreateSession(ctx, session)
+}
+
+func (s *MemoryStore) CreateSessions(
+	ctx context.Context,
+	sessions []models.CreateSessionRequest,
+) (*models.CreateSessionsResponse, error) {
+	if err := s.injector.Inject(ctx, "CreateSessions"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.CreateSessions(ctx, sessions)
+}
+
+func (s *MemoryStore) GetSession(ctx context.Context, sessionID string) (*models.Session, error) {
+	if err := s.injector.Inject(ctx, "GetSession"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetSession(ctx, sessionID)
+}
+
+func (s *MemoryStore) UpdateSession(
+	ctx context.Context,
+	session *models.UpdateSessionRequest,
+) (*models.Session, error) {
+	if err := s.injector.Inject(ctx, "UpdateSession"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.UpdateSession(ctx, session)
+}
+
+func (s *MemoryStore) PatchSessionMetadata(
+	ctx context.Context,
+	sessionID string,
+	patch models.MetadataPatch,
+) (*models.Session, error) {
+	if err := s.injector.Inject(ctx, "PatchSessionMetadata"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.PatchSessionMetadata(ctx, sessionID, patch)
+}
+
+func (s *MemoryStore) DeleteSession(ctx context.Context, sessionID string) error {
+	if err := s.injector.Inject(ctx, "DeleteSession"); err != nil {
+		return err
+	}
+	return s.MemoryStore.DeleteSession(ctx, sessionID)
+}
+
+func (s *MemoryStore) ListSessions(
+	ctx context.Context,
+	cursor int64,
+	limit int,
+) ([]*models.Session, error) {
+	if err := s.injector.Inject(ctx, "ListSessions"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.ListSessions(ctx, cursor, limit)
+}
+
+func (s *MemoryStore) ListSessionsOrdered(
+	ctx context.Context,
+	pageNumber int,
+	pageSize int,
+	orderedBy string,
+	asc bool,
+) (*models.SessionListResponse, error) {
+	if err := s.injector.Inject(ctx, "ListSessionsOrdered"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.ListSessionsOrdered(ctx, pageNumber, pageSize, orderedBy, asc)
+}
+
+func (s *MemoryStore) ListExpiringSessions(
+	ctx context.Context,
+	before time.Time,
+) ([]*models.Session, error) {
+	if err := s.injector.Inject(ctx, "ListExpiringSessions"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.ListExpiringSessions(ctx, before)
+}
+
+func (s *MemoryStore) MarkSessionsExpiryWarned(ctx context.Context, sessionIDs []string) error {
+	if err := s.injector.Inject(ctx, "MarkSessionsExpiryWarned"); err != nil {
+		return err
+	}
+	return s.MemoryStore.MarkSessionsExpiryWarned(ctx, sessionIDs)
+}
+
+func (s *MemoryStore) DeleteExpiredSessions(ctx context.Context) ([]*models.Session, error) {
+	if err := s.injector.Inject(ctx, "DeleteExpiredSessions"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.DeleteExpiredSessions(ctx)
+}
+
+func (s *MemoryStore) UpdateSessionTitle(ctx context.Context, sessionID string, title string) error {
+	if err := s.injector.Inject(ctx, "UpdateSessionTitle"); err != nil {
+		return err
+	}
+	return s.MemoryStore.UpdateSessionTitle(ctx, sessionID, title)
+}
+
+func (s *MemoryStore) UpdateSessionTaskState(
+	ctx context.Context,
+	sessionID string,
+	state *models.TaskState,
+) error {
+	if err := s.injector.Inject(ctx, "UpdateSessionTaskState"); err != nil {
+		return err
+	}
+	return s.MemoryStore.UpdateSessionTaskState(ctx, sessionID, state)
+}
+
+func (s *MemoryStore) UpdateSessionEpisodes(
+	ctx context.Context,
+	sessionID string,
+	episodes []models.Episode,
+) error {
+	if err := s.injector.Inject(ctx, "UpdateSessionEpisodes"); err != nil {
+		return err
+	}
+	return s.MemoryStore.UpdateSessionEpisodes(ctx, sessionID, episodes)
+}
+
+func (s *MemoryStore) GetMemory(
+	ctx context.Context,
+	sessionID string,
+	lastNMessages int,
+) (*models.Memory, error) {
+	if err := s.injector.Inject(ctx, "GetMemory"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetMemory(ctx, sessionID, lastNMessages)
+}
+
+func (s *MemoryStore) PutMemory(
+	ctx context.Context,
+	sessionID string,
+	memoryMessages *models.Memory,
+	skipNotify bool,
+) error {
+	if err := s.injector.Inject(ctx, "PutMemory"); err != nil {
+		return err
+	}
+	return s.MemoryStore.PutMemory(ctx, sessionID, memoryMessages, skipNotify)
+}
+
+func (s *MemoryStore) SearchMemory(
+	ctx context.Context,
+	sessionID string,
+	query *models.MemorySearchPayload,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	if err := s.injector.Inject(ctx, "SearchMemory"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.SearchMemory(ctx, sessionID, query, limit)
+}
+
+func (s *MemoryStore) SearchUserMemory(
+	ctx context.Context,
+	userID string,
+	query *models.MemorySearchPayload,
+	sessions int,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	if err := s.injector.Inject(ctx, "SearchUserMemory"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.SearchUserMemory(ctx, userID, query, sessions, limit)
+}
+
+func (s *MemoryStore) UpdateMessages(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+	isPrivileged bool,
+	includeContent bool,
+) error {
+	if err := s.injector.Inject(ctx, "UpdateMessages"); err != nil {
+		return err
+	}
+	return s.MemoryStore.UpdateMessages(ctx, sessionID, messages, isPrivileged, includeContent)
+}
+
+func (s *MemoryStore) GetMessagesByUUID(
+	ctx context.Context,
+	sessionID string,
+	uuids []uuid.UUID,
+) ([]models.Message, error) {
+	if err := s.injector.Inject(ctx, "GetMessagesByUUID"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetMessagesByUUID(ctx, sessionID, uuids)
+}
+
+func (s *MemoryStore) GetMessageList(
+	ctx context.Context,
+	sessionID string,
+	pageNumber int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	if err := s.injector.Inject(ctx, "GetMessageList"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetMessageList(ctx, sessionID, pageNumber, pageSize)
+}
+
+func (s *MemoryStore) GetMatchingMessageList(
+	ctx context.Context,
+	sessionID string,
+	filter *models.MessageFilter,
+	pageNumber int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	if err := s.injector.Inject(ctx, "GetMatchingMessageList"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetMatchingMessageList(ctx, sessionID, filter, pageNumber, pageSize)
+}
+
+func (s *MemoryStore) DeleteMatchingMessages(
+	ctx context.Context,
+	sessionID string,
+	filter *models.MessageFilter,
+) (int, error) {
+	if err := s.injector.Inject(ctx, "DeleteMatchingMessages"); err != nil {
+		return 0, err
+	}
+	return s.MemoryStore.DeleteMatchingMessages(ctx, sessionID, filter)
+}
+
+func (s *MemoryStore) CreateMessageEmbeddings(
+	ctx context.Context,
+	sessionID string,
+	embeddings []models.TextData,
+) error {
+	if err := s.injector.Inject(ctx, "CreateMessageEmbeddings"); err != nil {
+		return err
+	}
+	return s.MemoryStore.CreateMessageEmbeddings(ctx, sessionID, embeddings)
+}
+
+func (s *MemoryStore) GetMessageEmbeddings(
+	ctx context.Context,
+	sessionID string,
+) ([]models.TextData, error) {
+	if err := s.injector.Inject(ctx, "GetMessageEmbeddings"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetMessageEmbeddings(ctx, sessionID)
+}
+
+func (s *MemoryStore) GetCompactionCandidates(
+	ctx context.Context,
+	sessionID string,
+	maxMessages int,
+) (*models.CompactionCandidates, error) {
+	if err := s.injector.Inject(ctx, "GetCompactionCandidates"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetCompactionCandidates(ctx, sessionID, maxMessages)
+}
+
+func (s *MemoryStore) CompactMessages(
+	ctx context.Context,
+	sessionID string,
+	throughUUID uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	if err := s.injector.Inject(ctx, "CompactMessages"); err != nil {
+		return err
+	}
+	return s.MemoryStore.CompactMessages(ctx, sessionID, throughUUID, retainEmbeddings)
+}
+
+func (s *MemoryStore) PruneMessages(
+	ctx context.Context,
+	sessionID string,
+	messageUUIDs []uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	if err := s.injector.Inject(ctx, "PruneMessages"); err != nil {
+		return err
+	}
+	return s.MemoryStore.PruneMessages(ctx, sessionID, messageUUIDs, retainEmbeddings)
+}
+
+func (s *MemoryStore) GetSummary(ctx context.Context, sessionID string) (*models.Summary, error) {
+	if err := s.injector.Inject(ctx, "GetSummary"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetSummary(ctx, sessionID)
+}
+
+func (s *MemoryStore) GetSummaryByUUID(
+	ctx context.Context,
+	sessionID string,
+	summaryUUID uuid.UUID,
+) (*models.Summary, error) {
+	if err := s.injector.Inject(ctx, "GetSummaryByUUID"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetSummaryByUUID(ctx, sessionID, summaryUUID)
+}
+
+func (s *MemoryStore) GetSummaryList(
+	ctx context.Context,
+	sessionID string,
+	pageNumber int,
+	pageSize int,
+) (*models.SummaryListResponse, error) {
+	if err := s.injector.Inject(ctx, "GetSummaryList"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetSummaryList(ctx, sessionID, pageNumber, pageSize)
+}
+
+func (s *MemoryStore) CreateSummary(
+	ctx context.Context,
+	sessionID string,
+	summary *models.Summary,
+) error {
+	if err := s.injector.Inject(ctx, "CreateSummary"); err != nil {
+		return err
+	}
+	return s.MemoryStore.CreateSummary(ctx, sessionID, summary)
+}
+
+func (s *MemoryStore) UpdateSummary(
+	ctx context.Context,
+	sessionID string,
+	summary *models.Summary,
+	includeContent bool,
+) error {
+	if err := s.injector.Inject(ctx, "UpdateSummary"); err != nil {
+		return err
+	}
+	return s.MemoryStore.UpdateSummary(ctx, sessionID, summary, includeContent)
+}
+
+func (s *MemoryStore) PutSummaryEmbedding(
+	ctx context.Context,
+	sessionID string,
+	embedding *models.TextData,
+) error {
+	if err := s.injector.Inject(ctx, "PutSummaryEmbedding"); err != nil {
+		return err
+	}
+	return s.MemoryStore.PutSummaryEmbedding(ctx, sessionID, embedding)
+}
+
+func (s *MemoryStore) PurgeDeleted(ctx context.Context) error {
+	if err := s.injector.Inject(ctx, "PurgeDeleted"); err != nil {
+		return err
+	}
+	return s.MemoryStore.PurgeDeleted(ctx)
+}
+
+func (s *MemoryStore) DeduplicateMessageEmbeddings(
+	ctx context.Context,
+	since time.Time,
+	threshold float32,
+	scope models.DeduplicationScope,
+) (int, error) {
+	if err := s.injector.Inject(ctx, "DeduplicateMessageEmbeddings"); err != nil {
+		return 0, err
+	}
+	return s.MemoryStore.DeduplicateMessageEmbeddings(ctx, since, threshold, scope)
+}
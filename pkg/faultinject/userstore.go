@@ -0,0 +1,106 @@
+package faultinject
+
+import (
+	"context"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.UserStore = &UserStore{}
+
+// UserStore injects faults into the calls of a models.UserStore.
+type UserStore struct {
+	models.UserStore
+	injector *Injector
+}
+
+// NewUserStore wraps userStore with a UserStore injecting faults using injector.
+func NewUserStore(userStore models.UserStore, injector *Injector) *UserStore {
+	return &UserStore{UserStore: userStore, injector: injector}
+}
+
+func (s *UserStore) Create(
+	ctx context.Context,
+	user *models.CreateUserRequest,
+) (*models.User, error) {
+	if err := s.injector.Inject(ctx, "CreateUser"); err != nil {
+		return nil, err
+	}
+	return s.UserStore.Create(ctx, user)
+}
+
+func (s *UserStore) Get(ctx context.Context, userID string) (*models.User, error) {
+	if err := s.injector.Inject(ctx, "GetUser"); err != nil {
+		return nil, err
+	}
+	return s.UserStore.Get(ctx, userID)
+}
+
+func (s *UserStore) Update(
+	ctx context.Context,
+	user *models.UpdateUserRequest,
+	isPrivileged bool,
+) (*models.User, error) {
+	if err := s.injector.Inject(ctx, "UpdateUser"); err != nil {
+		return nil, err
+	}
+	return s.UserStore.Update(ctx, user, isPrivileged)
+}
+
+func (s *UserStore) PatchMetadata(
+	ctx context.Context,
+	userID string,
+	patch models.MetadataPatch,
+	isPrivileged bool,
+) (*models.User, error) {
+	if err := s.injector.Inject(ctx, "PatchUserMetadata"); err != nil {
+		return nil, err
+	}
+	return s.UserStore.PatchMetadata(ctx, userID, patch, isPrivileged)
+}
+
+func (s *UserStore) Delete(ctx context.Context, userID string) error {
+	if err := s.injector.Inject(ctx, "DeleteUser"); err != nil {
+		return err
+	}
+	return s.UserStore.Delete(ctx, userID)
+}
+
+func (s *UserStore) GetSessions(ctx context.Context, userID string) ([]*models.Session, error) {
+	if err := s.injector.Inject(ctx, "GetUserSessions"); err != nil {
+		return nil, err
+	}
+	return s.UserStore.GetSessions(ctx, userID)
+}
+
+func (s *UserStore) ListAll(ctx context.Context, cursor int64, limit int) ([]*models.User, error) {
+	if err := s.injector.Inject(ctx, "ListUsers"); err != nil {
+		return nil, err
+	}
+	return s.UserStore.ListAll(ctx, cursor, limit)
+}
+
+func (s *UserStore) ListAllOrdered(
+	ctx context.Context,
+	pageNumber int,
+	pageSize int,
+	orderBy string,
+	asc bool,
+) (*models.UserListResponse, error) {
+	if err := s.injector.Inject(ctx, "ListUsersOrdered"); err != nil {
+		return nil, err
+	}
+	return s.UserStore.ListAllOrdered(ctx, pageNumber, pageSize, orderBy, asc)
+}
+
+func (s *UserStore) Anonymize(
+	ctx context.Context,
+	userID string,
+	anonymousID string,
+	metadata map[string]interface{},
+) (*models.User, error) {
+	if err := s.injector.Inject(ctx, "AnonymizeUser"); err != nil {
+		return nil, err
+	}
+	return s.UserStore.Anonymize(ctx, userID, anonymousID, metadata)
+}
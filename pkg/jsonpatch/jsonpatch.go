@@ -0,0 +1,341 @@
+// Package jsonpatch applies JSON Patch (RFC 6902) and JSON Merge Patch (RFC 7396)
+// documents to decoded JSON values.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ContentType is the media type of a JSON Patch document.
+	ContentType = "application/json-patch+json"
+	// MergeContentType is the media type of a JSON Merge Patch document.
+	MergeContentType = "application/merge-patch+json"
+)
+
+// ErrTestFailed is returned when a test operation doesn't match the document.
+var ErrTestFailed = errors.New("json patch test failed")
+
+// Operation is a single JSON Patch operation. Value is kept raw so that a null value can
+// be told apart from a missing one.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch is a JSON Patch document.
+type Patch []Operation
+
+// Apply applies the operations of p in order to a copy of doc. If an operation fails, an
+// error is returned and no change is made.
+func (p Patch) Apply(doc interface{}) (interface{}, error) {
+	doc, err := normalize(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		doc, err = op.apply(doc)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func (op Operation) apply(doc interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, errors.New("value is required")
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			// replace is a remove followed by an add, and requires the value to exist
+			if len(path) == 0 {
+				return value, nil
+			}
+			if doc, _, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if op.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, errors.New("cannot move a value into one of its children")
+			}
+			if doc, value, err = remove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			if value, err = get(doc, from); err != nil {
+				return nil, err
+			}
+			if value, err = normalize(value); err != nil {
+				return nil, err
+			}
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+// MergePatch is a JSON Merge Patch document. A null member removes the member of the
+// target, and an object member is merged recursively.
+type MergePatch map[string]interface{}
+
+// Apply merges p into a copy of doc. If doc isn't an object, it's replaced.
+func (p MergePatch) Apply(doc interface{}) (interface{}, error) {
+	doc, err := normalize(doc)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := normalize(map[string]interface{}(p))
+	if err != nil {
+		return nil, err
+	}
+	return merge(doc, patch), nil
+}
+
+func merge(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+		} else {
+			object[name] = merge(object[name], value)
+		}
+	}
+	return object
+}
+
+// unescaper unescapes a reference token. Replacements are made in a single pass, so "~01"
+// is unescaped to "~1".
+var unescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = unescaper.Replace(t)
+	}
+	return tokens, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch c := doc.(type) {
+		case map[string]interface{}:
+			value, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			doc = value
+		case []interface{}:
+			i, err := index(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = c[i]
+		default:
+			return nil, fmt.Errorf("cannot traverse %q of a scalar value", token)
+		}
+	}
+	return doc, nil
+}
+
+// add sets the value at path, inserting it if the parent is an array, and returns the
+// updated document.
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			c[token] = value
+			return c, nil
+		}
+		child, ok := c[token]
+		if !ok {
+			return nil, fmt.Errorf("member %q not found", token)
+		}
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		c[token] = child
+		return c, nil
+	case []interface{}:
+		if len(rest) == 0 {
+			i := len(c)
+			if token != "-" {
+				var err error
+				if i, err = index(token, len(c)); err != nil {
+					return nil, err
+				}
+			}
+			inserted := make([]interface{}, 0, len(c)+1)
+			inserted = append(inserted, c[:i]...)
+			inserted = append(inserted, value)
+			return append(inserted, c[i:]...), nil
+		}
+		i, err := index(token, len(c)-1)
+		if err != nil {
+			return nil, err
+		}
+		if c[i], err = add(c[i], rest, value); err != nil {
+			return nil, err
+		}
+		return c, nil
+	default:
+		return nil, fmt.Errorf("cannot traverse %q of a scalar value", token)
+	}
+}
+
+// remove removes the value at path and returns the updated document and the removed value.
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+	token, rest := path[0], path[1:]
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		child, ok := c[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("member %q not found", token)
+		}
+		if len(rest) == 0 {
+			delete(c, token)
+			return c, child, nil
+		}
+		child, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		c[token] = child
+		return c, removed, nil
+	case []interface{}:
+		i, err := index(token, len(c)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := c[i]
+			return append(c[:i:i], c[i+1:]...), removed, nil
+		}
+		child, removed, err := remove(c[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		c[i] = child
+		return c, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot traverse %q of a scalar value", token)
+	}
+}
+
+// index parses an array index, which must be between 0 and max.
+func index(token string, max int) (int, error) {
+	// leading zeros aren't allowed
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equal(a, b interface{}) bool {
+	ab, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ab, bb)
+}
+
+// normalize deep copies v into the types of a decoded JSON value.
+func normalize(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decode(b)
+}
+
+func decode(b []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
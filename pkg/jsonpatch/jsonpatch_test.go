@@ -0,0 +1,146 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "add member",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":"qux"}]`,
+			want:  `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			name:  "add array element",
+			doc:   `{"foo":["bar","baz"]}`,
+			patch: `[{"op":"add","path":"/foo/1","value":"qux"},{"op":"add","path":"/foo/-","value":1}]`,
+			want:  `{"foo":["bar","qux","baz",1]}`,
+		},
+		{
+			name:  "add null",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a","value":null}]`,
+			want:  `{"a":null}`,
+		},
+		{
+			name:  "remove",
+			doc:   `{"baz":"qux","foo":["bar","qux","baz"]}`,
+			patch: `[{"op":"remove","path":"/baz"},{"op":"remove","path":"/foo/1"}]`,
+			want:  `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:  "replace",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"replace","path":"/baz","value":{"nested":true}}]`,
+			want:  `{"baz":{"nested":true},"foo":"bar"}`,
+		},
+		{
+			name:  "move",
+			doc:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:  "copy is independent of its source",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			want:  `{"a":{"b":1},"c":{"b":2}}`,
+		},
+		{
+			name:  "test and escaped pointer",
+			doc:   `{"a/b":{"m~n":[1,2]}}`,
+			patch: `[{"op":"test","path":"/a~1b/m~0n","value":[1,2]},{"op":"remove","path":"/a~1b"}]`,
+			want:  `{}`,
+		},
+		{
+			name:    "a later failure discards earlier operations",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"remove","path":"/a"},{"op":"test","path":"/b","value":1}]`,
+			wantErr: nil,
+		},
+		{
+			name:    "test mismatch",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"test","path":"/a","value":2}]`,
+			wantErr: ErrTestFailed,
+		},
+		{name: "missing member", doc: `{}`, patch: `[{"op":"remove","path":"/a"}]`},
+		{name: "missing parent", doc: `{}`, patch: `[{"op":"add","path":"/a/b","value":1}]`},
+		{name: "bad index", doc: `{"a":[1]}`, patch: `[{"op":"add","path":"/a/01","value":1}]`},
+		{name: "index out of range", doc: `{"a":[1]}`, patch: `[{"op":"replace","path":"/a/1","value":1}]`},
+		{name: "missing value", doc: `{}`, patch: `[{"op":"add","path":"/a"}]`},
+		{name: "unknown op", doc: `{}`, patch: `[{"op":"merge","path":"/a","value":1}]`},
+		{name: "invalid path", doc: `{}`, patch: `[{"op":"add","path":"a","value":1}]`},
+		{
+			name:  "move into child",
+			doc:   `{"a":{"b":{}}}`,
+			patch: `[{"op":"move","from":"/a","path":"/a/b/c"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.doc), &doc))
+			var patch Patch
+			require.NoError(t, json.Unmarshal([]byte(tt.patch), &patch))
+
+			got, err := patch.Apply(doc)
+			if tt.want == "" {
+				assert.Error(t, err)
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+				// the document is unchanged by a failed patch
+				b, _ := json.Marshal(doc)
+				assert.JSONEq(t, tt.doc, string(b))
+				return
+			}
+			require.NoError(t, err)
+			b, err := json.Marshal(got)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(b))
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	doc := map[string]interface{}{
+		"title":  "Goodbye!",
+		"author": map[string]interface{}{"givenName": "John", "familyName": "Doe"},
+		"tags":   []interface{}{"example", "sample"},
+		"count":  1,
+	}
+	var patch MergePatch
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"title": "Hello!",
+		"author": {"familyName": null},
+		"tags": ["example"],
+		"phoneNumber": "+01-123-456-7890"
+	}`), &patch))
+
+	got, err := patch.Apply(doc)
+	require.NoError(t, err)
+	b, err := json.Marshal(got)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"title": "Hello!",
+		"author": {"givenName": "John"},
+		"tags": ["example"],
+		"count": 1,
+		"phoneNumber": "+01-123-456-7890"
+	}`, string(b))
+	// doc isn't modified
+	assert.Equal(t, "Goodbye!", doc["title"])
+}
@@ -3,6 +3,8 @@ package llms
 import (
 	"context"
 	"errors"
+	"math"
+	"time"
 
 	"github.com/getzep/zep/config"
 
@@ -24,31 +26,94 @@ func EmbedTexts(
 		return nil, errors.New(InvalidLLMModelError)
 	}
 
-	if model.Service == "local" {
-		return embedTextsLocal(ctx, appState, documentType, text)
+	cfg, err := embeddingsConfig(appState, documentType)
+	if err != nil {
+		return nil, err
 	}
-	return appState.LLMClient.EmbedTexts(ctx, text)
+	text, err = NormalizeTexts(cfg.Normalize, text)
+	if err != nil {
+		return nil, err
+	}
+
+	var embeddings [][]float32
+	started := time.Now()
+	switch model.Service {
+	case "local":
+		embeddings, err = embedTextsLocal(ctx, appState, documentType, text)
+	case FakeLLMService:
+		embeddings = FakeEmbeddings(text, model.Dimensions)
+	default:
+		embeddings, err = appState.LLMClient.EmbedTexts(ctx, text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	models.SearchDebugFromContext(ctx).AddEmbedding(model, time.Since(started))
+
+	if model.Matryoshka && model.Dimensions > 0 {
+		return TruncateEmbeddings(embeddings, model.Dimensions), nil
+	}
+	return embeddings, nil
+}
+
+// TruncateEmbeddings shortens each embedding to the given number of dimensions and
+// re-normalizes it to unit length. Embeddings already within the limit are left unchanged.
+func TruncateEmbeddings(embeddings [][]float32, dimensions int) [][]float32 {
+	truncated := make([][]float32, len(embeddings))
+	for i, e := range embeddings {
+		if len(e) <= dimensions {
+			truncated[i] = e
+			continue
+		}
+		t := make([]float32, dimensions)
+		copy(t, e[:dimensions])
+
+		var norm float64
+		for _, v := range t {
+			norm += float64(v) * float64(v)
+		}
+		norm = math.Sqrt(norm)
+		if norm > 0 {
+			for j := range t {
+				t[j] = float32(float64(t[j]) / norm)
+			}
+		}
+		truncated[i] = t
+	}
+	return truncated
 }
 
 func GetEmbeddingModel(
 	appState *models.AppState,
 	documentType string,
 ) (*models.EmbeddingModel, error) {
-	var cfg config.EmbeddingsConfig
+	cfg, err := embeddingsConfig(appState, documentType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EmbeddingModel{
+		Service:          cfg.Service,
+		Dimensions:       cfg.Dimensions,
+		Quantization:     models.Quantization(cfg.Quantization),
+		DistanceFunction: models.DistanceFunction(cfg.DistanceFunction),
+		Matryoshka:       cfg.Matryoshka,
+	}, nil
+}
 
+// embeddingsConfig returns the embeddings config of a document type.
+func embeddingsConfig(
+	appState *models.AppState,
+	documentType string,
+) (config.EmbeddingsConfig, error) {
 	switch documentType {
 	case "message":
-		cfg = appState.Config.Extractors.Messages.Embeddings
+		return appState.Config.Extractors.Messages.Embeddings, nil
 	case "summary":
-		cfg = appState.Config.Extractors.Messages.Summarizer.Embeddings
+		return appState.Config.Extractors.Messages.Summarizer.Embeddings, nil
 	case "document":
-		cfg = appState.Config.Extractors.Documents.Embeddings
+		return appState.Config.Extractors.Documents.Embeddings, nil
 	default:
-		return nil, errors.New("invalid document type")
+		return config.EmbeddingsConfig{}, errors.New("invalid document type")
 	}
-
-	return &models.EmbeddingModel{
-		Service:    cfg.Service,
-		Dimensions: cfg.Dimensions,
-	}, nil
 }
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/egress"
 	"github.com/getzep/zep/pkg/models"
 )
 
@@ -53,7 +55,7 @@ func embedTextsLocal(
 		return nil, err
 	}
 
-	bodyBytes, err := makeEmbedRequest(ctx, url, jsonBody)
+	bodyBytes, err := makeEmbedRequest(ctx, url, appState.Config.NLP.TLS, jsonBody)
 	if err != nil {
 		return nil, err
 	}
@@ -74,17 +76,28 @@ func embedTextsLocal(
 
 // makeEmbedRequest makes a POST request to the local embeddings service. It
 // returns the response body as a byte slice. A retryablehttp.Client is used to
-// make the request.
-func makeEmbedRequest(ctx context.Context, url string, jsonBody []byte) ([]byte, error) {
+// make the request, with the TLS settings of tlsCfg.
+func makeEmbedRequest(
+	ctx context.Context,
+	url string,
+	tlsCfg config.TLSConfig,
+	jsonBody []byte,
+) ([]byte, error) {
+	tlsConfig, err := egress.LoadTLSConfig(tlsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nlp.tls: %w", err)
+	}
+
 	// we set both the context and the request timeout (below) to the same value
 	// so that the request will be cancelled if the context times out and/or the
 	// request times out
 	ctx, cancel := context.WithTimeout(ctx, LocalEmbedderTimeout)
 	defer cancel()
 
-	httpClient := NewRetryableHTTPClient(
+	httpClient := NewRetryableHTTPClientWithTLS(
 		MaxLocalEmbedderRetryAttempts,
 		LocalEmbedderTimeout,
+		tlsConfig,
 	)
 
 	req, err := http.NewRequestWithContext(
@@ -0,0 +1,28 @@
+package llms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viterin/vek/vek32"
+)
+
+func TestTruncateEmbeddings(t *testing.T) {
+	embeddings := [][]float32{
+		{3, 4, 12},
+		{0.6, 0.8},
+		{0, 0, 1},
+	}
+
+	truncated := TruncateEmbeddings(embeddings, 2)
+
+	assert.Equal(t, []float32{0.6, 0.8}, truncated[0])
+	// already within the limit
+	assert.Equal(t, []float32{0.6, 0.8}, truncated[1])
+	// zero vectors are not normalized
+	assert.Equal(t, []float32{0, 0}, truncated[2])
+
+	assert.InDelta(t, 1.0, vek32.Norm(truncated[0]), 1e-6)
+	// the input is not modified
+	assert.Equal(t, []float32{3, 4, 12}, embeddings[0])
+}
@@ -94,6 +94,10 @@ func (zllm *ZepAnthropicLLM) configureClient(cfg *config.Config) ([]anthropic.Op
 	if apiKey == "" {
 		log.Fatal(AnthropicAPIKeyNotSetError)
 	}
+	// the anthropic client can't be given an HTTP client
+	if cfg.LLM.TLS != (config.TLSConfig{}) {
+		return nil, errors.New("llm.tls is not supported by the anthropic service")
+	}
 
 	options := make([]anthropic.Option, 0)
 	options = append(
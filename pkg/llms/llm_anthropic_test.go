@@ -31,6 +31,19 @@ func TestZepAnthropicLLM_Init(t *testing.T) {
 	assert.NotNil(t, a.client, "Expected client to be initialized")
 }
 
+func TestZepAnthropicLLM_InitTLS(t *testing.T) {
+	cfg := &config.Config{
+		LLM: config.LLM{
+			Model:           "claude-2",
+			AnthropicAPIKey: "test-key",
+			TLS:             config.TLSConfig{MinVersion: "1.3"},
+		},
+	}
+
+	_, err := NewAnthropicLLM(context.Background(), cfg)
+	assert.Error(t, err, "Expected llm.tls to be refused")
+}
+
 func TestZepAnthropicLLM_Call(t *testing.T) {
 	cfg := testutils.NewTestConfig()
 	cfg.LLM.Model = "claude-2"
@@ -2,6 +2,7 @@ package llms
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"net/http/httptrace"
@@ -18,6 +19,7 @@ import (
 	"github.com/hashicorp/go-retryablehttp"
 
 	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/egress"
 
 	"github.com/getzep/zep/internal"
 )
@@ -80,6 +82,8 @@ func NewLLMClient(ctx context.Context, cfg *config.Config) (models.ZepLLM, error
 			)
 		}
 		return NewAnthropicLLM(ctx, cfg)
+	case FakeLLMService:
+		return NewFakeLLM(ctx, cfg)
 	case "":
 		// for backward compatibility
 		return NewOpenAILLM(ctx, cfg)
@@ -199,6 +203,10 @@ func GetLLMModelName(cfg *config.Config) (string, error) {
 	if cfg.LLM.OpenAIEndpoint != "" || cfg.LLM.AzureOpenAIEndpoint != "" {
 		return llmModel, nil
 	}
+	// The fake service accepts any model name
+	if cfg.LLM.Service == FakeLLMService {
+		return llmModel, nil
+	}
 	if llmModel == "" || !ValidLLMMap[llmModel] {
 		return "", NewLLMError(InvalidLLMModelError, nil)
 	}
@@ -218,6 +226,16 @@ func Float64ToFloat32Matrix(in [][]float64) [][]float32 {
 }
 
 func NewRetryableHTTPClient(retryMax int, timeout time.Duration) *http.Client {
+	return NewRetryableHTTPClientWithTLS(retryMax, timeout, nil)
+}
+
+// NewRetryableHTTPClientWithTLS returns a retryable HTTP client like NewRetryableHTTPClient,
+// whose TLS connections use tlsConfig, such as that of egress.LoadTLSConfig.
+func NewRetryableHTTPClientWithTLS(
+	retryMax int,
+	timeout time.Duration,
+	tlsConfig *tls.Config,
+) *http.Client {
 	leveledLogger := internal.NewLeveledLogrus(log)
 
 	client := retryablehttp.NewClient()
@@ -226,6 +244,7 @@ func NewRetryableHTTPClient(retryMax int, timeout time.Duration) *http.Client {
 	client.Logger = leveledLogger
 	client.Backoff = retryablehttp.DefaultBackoff
 	client.CheckRetry = retryPolicy
+	egress.ConfigureClient(client, tlsConfig)
 
 	httpClient := &http.Client{
 		Transport: otelhttp.NewTransport(
@@ -0,0 +1,127 @@
+package llms
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// FakeLLMService is the llm.service and embeddings service name of the fake provider.
+const FakeLLMService = "fake"
+
+// DefaultFakeEmbeddingDimensions is used when no embedding dimensions are configured.
+const DefaultFakeEmbeddingDimensions = 1536
+
+var _ models.ZepLLM = &ZepFakeLLM{}
+
+// NewFakeLLM returns an LLM that needs no provider or API key. Completions are canned and
+// embeddings are derived from a hash of the text, so results are deterministic. It is
+// intended for tests and offline demo deployments.
+func NewFakeLLM(ctx context.Context, cfg *config.Config) (models.ZepLLM, error) {
+	zllm := &ZepLLM{
+		llm: &ZepFakeLLM{},
+	}
+	err := zllm.Init(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return zllm, nil
+}
+
+type ZepFakeLLM struct {
+	dimensions int
+}
+
+func (zllm *ZepFakeLLM) Init(_ context.Context, cfg *config.Config) error {
+	zllm.dimensions = cfg.Extractors.Messages.Embeddings.Dimensions
+	if zllm.dimensions <= 0 {
+		zllm.dimensions = DefaultFakeEmbeddingDimensions
+	}
+	return nil
+}
+
+// Call returns a canned completion for the prompt. Intent prompts get an intent response
+// so that the intent extractor has something to store; all other prompts get a short
+// text identifying the prompt by its hash.
+func (zllm *ZepFakeLLM) Call(
+	ctx context.Context,
+	prompt string,
+	_ ...llms.CallOption,
+) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if strings.Contains(prompt, "Intent:") {
+		return "Intent: None", nil
+	}
+	sum := sha256.Sum256([]byte(prompt))
+	return fmt.Sprintf(
+		"This is a canned completion from the fake LLM service (prompt %s).",
+		hex.EncodeToString(sum[:4]),
+	), nil
+}
+
+func (zllm *ZepFakeLLM) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return FakeEmbeddings(texts, zllm.dimensions), nil
+}
+
+// GetTokenCount approximates the token count as the number of whitespace separated words.
+func (zllm *ZepFakeLLM) GetTokenCount(text string) (int, error) {
+	return len(strings.Fields(text)), nil
+}
+
+// FakeEmbeddings returns a unit length embedding of the given dimensions for each text.
+// Each embedding is generated from a SHA-256 hash of its text, so equal texts always
+// have equal embeddings.
+func FakeEmbeddings(texts []string, dimensions int) [][]float32 {
+	if dimensions <= 0 {
+		dimensions = DefaultFakeEmbeddingDimensions
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = fakeEmbedding(text, dimensions)
+	}
+	return embeddings
+}
+
+func fakeEmbedding(text string, dimensions int) []float32 {
+	embedding := make([]float32, dimensions)
+	seed := sha256.Sum256([]byte(text))
+
+	// Extend the hash with a counter to fill the vector, mapping each 4 bytes to [-1, 1).
+	var block [sha256.Size]byte
+	var counter [8]byte
+	var norm float64
+	for j := 0; j < dimensions; j++ {
+		k := j % (sha256.Size / 4)
+		if k == 0 {
+			binary.BigEndian.PutUint64(counter[:], uint64(j))
+			block = sha256.Sum256(append(seed[:], counter[:]...))
+		}
+		v := float64(binary.BigEndian.Uint32(block[k*4:]))/float64(1<<31) - 1
+		embedding[j] = float32(v)
+		norm += v * v
+	}
+
+	norm = math.Sqrt(norm)
+	if norm > 0 {
+		for j := range embedding {
+			embedding[j] = float32(float64(embedding[j]) / norm)
+		}
+	}
+	return embedding
+}
@@ -0,0 +1,73 @@
+package llms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viterin/vek/vek32"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestNewLLMClient_Fake(t *testing.T) {
+	cfg := &config.Config{
+		LLM: config.LLM{Service: FakeLLMService},
+	}
+	cfg.Extractors.Messages.Embeddings.Dimensions = 384
+
+	zllm, err := NewLLMClient(context.Background(), cfg)
+	assert.NoError(t, err)
+
+	completion, err := zllm.Call(context.Background(), "Summarize the conversation.")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, completion)
+
+	again, err := zllm.Call(context.Background(), "Summarize the conversation.")
+	assert.NoError(t, err)
+	assert.Equal(t, completion, again)
+
+	intent, err := zllm.Call(context.Background(), "Respond with Intent: followed by the intent.")
+	assert.NoError(t, err)
+	assert.Equal(t, "Intent: None", intent)
+
+	embeddings, err := zllm.EmbedTexts(context.Background(), []string{"hello"})
+	assert.NoError(t, err)
+	assert.Len(t, embeddings[0], 384)
+
+	tokens, err := zllm.GetTokenCount("one two  three")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, tokens)
+
+	modelName, err := GetLLMModelName(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "", modelName)
+}
+
+func TestFakeEmbeddings(t *testing.T) {
+	embeddings := FakeEmbeddings([]string{"hello", "world", "hello"}, 100)
+
+	assert.Len(t, embeddings, 3)
+	for _, e := range embeddings {
+		assert.Len(t, e, 100)
+		assert.InDelta(t, 1.0, vek32.Norm(e), 1e-5)
+	}
+	assert.Equal(t, embeddings[0], embeddings[2])
+	assert.NotEqual(t, embeddings[0], embeddings[1])
+
+	// stable across calls and dimensions default when unset
+	assert.Equal(t, embeddings[0], FakeEmbeddings([]string{"hello"}, 100)[0])
+	assert.Len(t, FakeEmbeddings([]string{"hello"}, 0)[0], DefaultFakeEmbeddingDimensions)
+}
+
+func TestEmbedTexts_Fake(t *testing.T) {
+	zllm, err := NewFakeLLM(context.Background(), &config.Config{})
+	assert.NoError(t, err)
+	appState := &models.AppState{LLMClient: zllm}
+
+	model := &models.EmbeddingModel{Service: FakeLLMService, Dimensions: 64}
+	embeddings, err := EmbedTexts(context.Background(), appState, model, "message", []string{"hi"})
+	assert.NoError(t, err)
+	assert.Equal(t, FakeEmbeddings([]string{"hi"}, 64), embeddings)
+}
@@ -2,6 +2,7 @@ package llms
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/tmc/langchaingo/schema"
@@ -9,6 +10,7 @@ import (
 	"github.com/tmc/langchaingo/llms"
 
 	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/egress"
 	"github.com/getzep/zep/pkg/models"
 	"github.com/pkoukk/tiktoken-go"
 	"github.com/tmc/langchaingo/llms/openai"
@@ -123,8 +125,17 @@ func (zllm *ZepOpenAILLM) configureClient(cfg *config.Config) ([]openai.Option,
 		log.Fatal("only one of AzureOpenAIEndpoint or OpenAIEndpoint can be set")
 	}
 
+	tlsConfig, err := egress.LoadTLSConfig(cfg.LLM.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid llm.tls: %w", err)
+	}
+
 	// Set up the HTTP client and config OpenTelemetry wrapper
-	httpClient := NewRetryableHTTPClient(MaxOpenAIAPIRequestAttempts, OpenAIAPITimeout)
+	httpClient := NewRetryableHTTPClientWithTLS(
+		MaxOpenAIAPIRequestAttempts,
+		OpenAIAPITimeout,
+		tlsConfig,
+	)
 
 	options := make([]openai.Option, 0)
 	options = append(
@@ -158,6 +169,10 @@ func (zllm *ZepOpenAILLM) configureClient(cfg *config.Config) ([]openai.Option,
 	case cfg.LLM.OpenAIOrgID != "":
 		options = append(options, openai.WithOrganization(cfg.LLM.OpenAIOrgID))
 	}
+	// Azure embeddings are served by their deployment
+	if cfg.LLM.OpenAIEmbeddingModel != "" && cfg.LLM.AzureOpenAIEndpoint == "" {
+		options = append(options, openai.WithEmbeddingModel(cfg.LLM.OpenAIEmbeddingModel))
+	}
 
 	return options, nil
 }
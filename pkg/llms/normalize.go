@@ -0,0 +1,96 @@
+package llms
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/getzep/zep/config"
+)
+
+// greetingRegex matches a line that's only a salutation, such as "Dear support team:".
+var greetingRegex = regexp.MustCompile(`(?i)^(hi|hello|hey|dear|greetings|` +
+	`good (morning|afternoon|evening))\b[^,.!?:]{0,30}[,.!:]?$`)
+
+// signOffRegex matches a line ending a message before the sender's name and signature.
+var signOffRegex = regexp.MustCompile(`(?i)^(thanks|thank you|many thanks|thanks again|best|` +
+	`best regards|kind regards|warm regards|regards|cheers|sincerely|yours truly)[,.!]?$`)
+
+// signatureRegex matches a signature delimiter, or a mobile client's signature.
+var signatureRegex = regexp.MustCompile(`(?i)^(--|sent from my\b.*)$`)
+
+var blankLinesRegex = regexp.MustCompile(`\n{3,}`)
+
+// ValidateNormalization returns an error if a pattern of cfg doesn't compile.
+func ValidateNormalization(cfg config.TextNormalizationConfig) error {
+	_, err := compilePatterns(cfg.Patterns)
+	return err
+}
+
+// NormalizeTexts strips the boilerplate configured by cfg from each text. Texts that would
+// be left empty are returned unchanged, as are all texts if nothing is configured.
+func NormalizeTexts(cfg config.TextNormalizationConfig, texts []string) ([]string, error) {
+	if !cfg.Greetings && !cfg.Signatures && len(cfg.Patterns) == 0 {
+		return texts, nil
+	}
+	patterns, err := compilePatterns(cfg.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := make([]string, len(texts))
+	for i, text := range texts {
+		normalized[i] = normalizeText(cfg, patterns, text)
+	}
+	return normalized, nil
+}
+
+func normalizeText(cfg config.TextNormalizationConfig, patterns []*regexp.Regexp, text string) string {
+	stripped := text
+	for _, p := range patterns {
+		stripped = p.ReplaceAllString(stripped, "")
+	}
+
+	lines := strings.Split(stripped, "\n")
+	if cfg.Signatures {
+		for i, line := range lines {
+			line = strings.TrimSpace(line)
+			// a sign-off opening the text is the whole message, such as "Thanks!"
+			if i > 0 && (signOffRegex.MatchString(line) || signatureRegex.MatchString(line)) {
+				lines = lines[:i]
+				break
+			}
+		}
+	}
+	if cfg.Greetings {
+		for i, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if greetingRegex.MatchString(line) {
+				lines = lines[i+1:]
+			}
+			break
+		}
+	}
+
+	stripped = strings.TrimSpace(strings.Join(lines, "\n"))
+	stripped = blankLinesRegex.ReplaceAllString(stripped, "\n\n")
+	if stripped == "" {
+		return text
+	}
+	return stripped
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid normalization pattern %q: %w", p, err)
+		}
+		compiled[i] = re
+	}
+	return compiled, nil
+}
@@ -0,0 +1,45 @@
+package llms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+)
+
+func TestNormalizeTexts(t *testing.T) {
+	cfg := config.TextNormalizationConfig{
+		Greetings:  true,
+		Signatures: true,
+		Patterns:   []string{`(?is)this email is confidential.*`},
+	}
+	texts := []string{
+		"Hi team,\n\nMy invoice shows the wrong address.\n\nBest regards,\nAna\nAcme Corp",
+		"Dear Support:\nThe export fails.\n-- \nBob | +1 555 0100",
+		"Hello, the export fails.\nSent from my iPhone",
+		"The export fails.\n\n\n\nThis email is confidential. If you received it in error...",
+		"Thanks!",
+	}
+	normalized, err := NormalizeTexts(cfg, texts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"My invoice shows the wrong address.",
+		"The export fails.",
+		// a greeting followed by the message on the same line is kept
+		"Hello, the export fails.",
+		"The export fails.",
+		// texts left empty are embedded as is
+		"Thanks!",
+	}, normalized)
+
+	unchanged, err := NormalizeTexts(config.TextNormalizationConfig{}, texts)
+	require.NoError(t, err)
+	assert.Equal(t, texts, unchanged)
+
+	cfg.Patterns = []string{"("}
+	_, err = NormalizeTexts(cfg, texts)
+	assert.Error(t, err)
+	assert.Error(t, ValidateNormalization(cfg))
+}
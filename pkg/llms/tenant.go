@@ -0,0 +1,253 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// DefaultTenantCacheTTL is how long a tenant's clients are cached if no TTL is configured.
+const DefaultTenantCacheTTL = 60 * time.Second
+
+var _ models.TenantProviders = &TenantProviders{}
+
+// TenantProviders resolves the provider overrides of tenants to LLM clients. A tenant's
+// clients are created from its override when first used, and cached for the TTL, which
+// bounds how long servers sharing the store use an override after it changes.
+type TenantProviders struct {
+	cfg   *config.Config
+	store models.ProviderOverrideStore
+	ttl   time.Duration
+	// newLLM and newEmbedder create the clients of an override's config.
+	newLLM      func(ctx context.Context, cfg *config.Config) (models.ZepLLM, error)
+	newEmbedder func(ctx context.Context, cfg *config.Config) (models.ZepLLM, error)
+
+	mu      sync.Mutex
+	tenants map[string]*tenantClients
+	// generation is incremented by Invalidate, so that clients created from an override
+	// read before it changed aren't cached.
+	generation uint64
+}
+
+type tenantClients struct {
+	llm      models.ZepLLM
+	embedder models.ZepLLM
+	expires  time.Time
+}
+
+// NewTenantProviders returns TenantProviders resolving the overrides of store, with
+// clients configured like those of cfg.
+func NewTenantProviders(cfg *config.Config, store models.ProviderOverrideStore) *TenantProviders {
+	ttl := time.Duration(cfg.LLM.Tenants.CacheTTL) * time.Second
+	if ttl <= 0 {
+		ttl = DefaultTenantCacheTTL
+	}
+	return &TenantProviders{
+		cfg:         cfg,
+		store:       store,
+		ttl:         ttl,
+		newLLM:      NewLLMClient,
+		newEmbedder: NewOpenAILLM,
+		tenants:     make(map[string]*tenantClients),
+	}
+}
+
+// Clients returns the LLM and embedding clients of the tenant of ctx. Without an
+// embeddings override, the tenant's LLM embeds texts. Both are nil if ctx has no tenant
+// or the tenant has no override. If the override can't be resolved an error is returned,
+// rather than falling back to the configured LLM, as the tenant's data mustn't be sent to
+// another provider.
+func (p *TenantProviders) Clients(ctx context.Context) (models.ZepLLM, models.ZepLLM, error) {
+	tenant := models.TenantFromContext(ctx)
+	if tenant == "" {
+		return nil, nil, nil
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	cached, ok := p.tenants[tenant]
+	generation := p.generation
+	p.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.llm, cached.embedder, nil
+	}
+
+	clients := &tenantClients{expires: now.Add(p.ttl)}
+	override, err := p.store.Get(ctx, tenant)
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+	case err != nil:
+		return nil, nil, fmt.Errorf("failed to get provider override of tenant %s: %w", tenant, err)
+	default:
+		clients.llm, clients.embedder, err = p.newClients(ctx, override)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create clients of tenant %s: %w", tenant, err)
+		}
+	}
+
+	p.mu.Lock()
+	if p.generation == generation {
+		for t, c := range p.tenants {
+			if !now.Before(c.expires) {
+				delete(p.tenants, t)
+			}
+		}
+		p.tenants[tenant] = clients
+	}
+	p.mu.Unlock()
+	return clients.llm, clients.embedder, nil
+}
+
+// Invalidate drops the cached clients of a tenant.
+func (p *TenantProviders) Invalidate(tenant string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.tenants, tenant)
+	p.generation++
+}
+
+func (p *TenantProviders) newClients(
+	ctx context.Context,
+	override *models.ProviderOverride,
+) (llm, embedder models.ZepLLM, err error) {
+	if override.LLM != nil {
+		llm, err = p.newLLM(ctx, overrideLLMConfig(p.cfg, override.LLM))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	embedder = llm
+	if override.Embeddings != nil {
+		embedder, err = p.newEmbedder(ctx, overrideEmbeddingsConfig(p.cfg, override.Embeddings))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return llm, embedder, nil
+}
+
+// overrideLLMConfig returns a copy of cfg configuring the LLM of an override. The
+// configured OpenAI embedding model is kept, so that the LLM's embeddings have the
+// configured dimensions.
+func overrideLLMConfig(cfg *config.Config, provider *models.LLMProvider) *config.Config {
+	c := *cfg
+	c.LLM = config.LLM{
+		Service:              provider.Service,
+		Model:                provider.Model,
+		OpenAIEmbeddingModel: cfg.LLM.OpenAIEmbeddingModel,
+	}
+	switch provider.Service {
+	case models.ProviderServiceAnthropic:
+		c.LLM.AnthropicAPIKey = provider.APIKey
+	default:
+		c.LLM.OpenAIAPIKey = provider.APIKey
+		c.LLM.OpenAIEndpoint = provider.Endpoint
+	}
+	return &c
+}
+
+// overrideEmbeddingsConfig returns a copy of cfg configuring the OpenAI embeddings of an
+// override.
+func overrideEmbeddingsConfig(
+	cfg *config.Config,
+	provider *models.EmbeddingProvider,
+) *config.Config {
+	c := *cfg
+	c.LLM = config.LLM{
+		Service:              models.ProviderServiceOpenAI,
+		OpenAIAPIKey:         provider.APIKey,
+		OpenAIEndpoint:       provider.Endpoint,
+		OpenAIEmbeddingModel: provider.Model,
+	}
+	return &c
+}
+
+// ValidateProviderOverride returns a ValidationError if the LLM of an override isn't a
+// known model of its service. Models of custom OpenAI endpoints aren't validated.
+func ValidateProviderOverride(override *models.ProviderOverride) error {
+	if override.LLM == nil {
+		return nil
+	}
+	switch override.LLM.Service {
+	case models.ProviderServiceAnthropic:
+		if override.LLM.Endpoint != "" {
+			return models.NewValidationError("endpoint is only supported by the openai service")
+		}
+		if !ValidAnthropicLLMs[override.LLM.Model] {
+			return models.NewValidationError(
+				fmt.Sprintf("invalid llm model %q for anthropic", override.LLM.Model),
+			)
+		}
+	case models.ProviderServiceOpenAI:
+		if override.LLM.Endpoint == "" && !ValidOpenAILLMs[override.LLM.Model] {
+			return models.NewValidationError(
+				fmt.Sprintf("invalid llm model %q for openai", override.LLM.Model),
+			)
+		}
+	}
+	return nil
+}
+
+var _ models.ZepLLM = &TenantLLM{}
+
+// TenantLLM is the LLM client of a server with tenant provider overrides. Calls on behalf
+// of a tenant with an override use the tenant's clients, and others the wrapped LLM.
+// Token counts are always those of the wrapped LLM.
+type TenantLLM struct {
+	models.ZepLLM
+	providers models.TenantProviders
+}
+
+// NewTenantLLM wraps llm with a TenantLLM using the clients of providers.
+func NewTenantLLM(llm models.ZepLLM, providers models.TenantProviders) *TenantLLM {
+	return &TenantLLM{ZepLLM: llm, providers: providers}
+}
+
+func (l *TenantLLM) Call(
+	ctx context.Context,
+	prompt string,
+	options ...llms.CallOption,
+) (string, error) {
+	llm, _, err := l.providers.Clients(ctx)
+	if err != nil {
+		return "", err
+	}
+	if llm != nil {
+		return llm.Call(ctx, prompt, options...)
+	}
+	return l.ZepLLM.Call(ctx, prompt, options...)
+}
+
+func (l *TenantLLM) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	_, embedder, err := l.providers.Clients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if embedder != nil {
+		return embedder.EmbedTexts(ctx, texts)
+	}
+	return l.ZepLLM.EmbedTexts(ctx, texts)
+}
+
+// WrapTenantProviders replaces the LLM client of appState with a TenantLLM resolving the
+// overrides of its provider override store, if tenant providers are enabled.
+func WrapTenantProviders(appState *models.AppState) error {
+	if !appState.Config.LLM.Tenants.Enabled {
+		return nil
+	}
+	if appState.ProviderOverrideStore == nil {
+		return errors.New("tenant providers require a provider override store")
+	}
+	providers := NewTenantProviders(appState.Config, appState.ProviderOverrideStore)
+	appState.TenantProviders = providers
+	appState.LLMClient = NewTenantLLM(appState.LLMClient, providers)
+	log.Info("tenant provider overrides enabled")
+	return nil
+}
@@ -0,0 +1,180 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// namedLLM completes every prompt with its name, and embeds texts as its name's length.
+type namedLLM struct {
+	models.ZepLLM
+	name string
+}
+
+func (l *namedLLM) Call(context.Context, string, ...llms.CallOption) (string, error) {
+	return l.name, nil
+}
+
+func (l *namedLLM) EmbedTexts(_ context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i := range texts {
+		embeddings[i] = []float32{float32(len(l.name))}
+	}
+	return embeddings, nil
+}
+
+type overrideStore struct {
+	models.ProviderOverrideStore
+	overrides map[string]*models.ProviderOverride
+	gets      int
+	err       error
+}
+
+func (s *overrideStore) Get(_ context.Context, tenant string) (*models.ProviderOverride, error) {
+	s.gets++
+	if s.err != nil {
+		return nil, s.err
+	}
+	override, ok := s.overrides[tenant]
+	if !ok {
+		return nil, models.NewNotFoundError("provider override of tenant " + tenant)
+	}
+	return override, nil
+}
+
+func TestTenantLLM(t *testing.T) {
+	store := &overrideStore{overrides: map[string]*models.ProviderOverride{
+		"acme": {
+			Tenant: "acme",
+			LLM: &models.LLMProvider{
+				Service: models.ProviderServiceAnthropic,
+				Model:   "claude-3-haiku-20240307",
+				APIKey:  "sk-ant",
+			},
+		},
+		"globex": {
+			Tenant: "globex",
+			LLM: &models.LLMProvider{
+				Service:  models.ProviderServiceOpenAI,
+				Model:    "mistral",
+				APIKey:   "sk-globex",
+				Endpoint: "https://eu.example.com/v1",
+			},
+			Embeddings: &models.EmbeddingProvider{
+				Service: models.ProviderServiceOpenAI,
+				Model:   "text-embedding-3-small",
+				APIKey:  "sk-embed",
+			},
+		},
+	}}
+	cfg := &config.Config{LLM: config.LLM{Service: "openai", Model: "gpt-4o-mini"}}
+	providers := NewTenantProviders(cfg, store)
+	var llmConfigs, embedderConfigs []config.LLM
+	providers.newLLM = func(_ context.Context, c *config.Config) (models.ZepLLM, error) {
+		llmConfigs = append(llmConfigs, c.LLM)
+		return &namedLLM{name: c.LLM.Model}, nil
+	}
+	providers.newEmbedder = func(_ context.Context, c *config.Config) (models.ZepLLM, error) {
+		embedderConfigs = append(embedderConfigs, c.LLM)
+		return &namedLLM{name: c.LLM.OpenAIEmbeddingModel}, nil
+	}
+	llm := NewTenantLLM(&namedLLM{name: "default"}, providers)
+
+	call := func(tenant string) string {
+		completion, err := llm.Call(models.WithTenant(context.Background(), tenant), "prompt")
+		require.NoError(t, err)
+		return completion
+	}
+	embed := func(tenant string) float32 {
+		embeddings, err := llm.EmbedTexts(
+			models.WithTenant(context.Background(), tenant),
+			[]string{"text"},
+		)
+		require.NoError(t, err)
+		return embeddings[0][0]
+	}
+
+	assert.Equal(t, "default", call(""))
+	assert.Equal(t, "default", call("initech"))
+	assert.Equal(t, "claude-3-haiku-20240307", call("acme"))
+	// without an embeddings override, the tenant's LLM embeds texts
+	assert.Equal(t, float32(len("claude-3-haiku-20240307")), embed("acme"))
+	assert.Equal(t, "mistral", call("globex"))
+	assert.Equal(t, float32(len("text-embedding-3-small")), embed("globex"))
+
+	require.Len(t, llmConfigs, 2)
+	assert.Equal(t, "sk-ant", llmConfigs[0].AnthropicAPIKey)
+	assert.Empty(t, llmConfigs[0].OpenAIAPIKey)
+	assert.Equal(t, "sk-globex", llmConfigs[1].OpenAIAPIKey)
+	assert.Equal(t, "https://eu.example.com/v1", llmConfigs[1].OpenAIEndpoint)
+	require.Len(t, embedderConfigs, 1)
+	assert.Equal(t, "sk-embed", embedderConfigs[0].OpenAIAPIKey)
+	assert.Equal(t, "gpt-4o-mini", cfg.LLM.Model, "the config isn't modified")
+
+	// clients are cached, including the absence of an override, until invalidated
+	gets := store.gets
+	call("acme")
+	call("initech")
+	assert.Equal(t, gets, store.gets)
+	store.overrides["acme"].LLM.Model = "claude-3-opus-20240229"
+	providers.Invalidate("acme")
+	assert.Equal(t, "claude-3-opus-20240229", call("acme"))
+
+	// a tenant's calls don't fall back to the default LLM if its override can't be read
+	store.err = errors.New("database unavailable")
+	providers.Invalidate("acme")
+	_, err := llm.Call(models.WithTenant(context.Background(), "acme"), "prompt")
+	assert.ErrorContains(t, err, "database unavailable")
+	_, err = llm.EmbedTexts(models.WithTenant(context.Background(), "acme"), []string{"text"})
+	assert.Error(t, err)
+	assert.Equal(t, "default", call(""))
+}
+
+func TestValidateProviderOverride(t *testing.T) {
+	tests := []struct {
+		name  string
+		llm   *models.LLMProvider
+		valid bool
+	}{
+		{"no llm", nil, true},
+		{"openai", &models.LLMProvider{Service: "openai", Model: "gpt-4o"}, true},
+		{"unknown openai model", &models.LLMProvider{Service: "openai", Model: "gpt-9"}, false},
+		{
+			"custom endpoint",
+			&models.LLMProvider{Service: "openai", Model: "gpt-9", Endpoint: "https://x.io"},
+			true,
+		},
+		{
+			"anthropic",
+			&models.LLMProvider{Service: "anthropic", Model: "claude-3-haiku-20240307"},
+			true,
+		},
+		{
+			"anthropic endpoint",
+			&models.LLMProvider{
+				Service:  "anthropic",
+				Model:    "claude-3-haiku-20240307",
+				Endpoint: "https://x.io",
+			},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProviderOverride(&models.ProviderOverride{Tenant: "acme", LLM: tt.llm})
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, models.ErrBadRequest)
+			}
+		})
+	}
+}
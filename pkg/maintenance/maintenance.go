@@ -0,0 +1,70 @@
+// Package maintenance holds the API's maintenance mode, in which writes are rejected while
+// migrations, reindexing, or restores run, and reads continue to be served.
+package maintenance
+
+import (
+	"sync"
+	"time"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// DefaultRetryAfter is the wait, in seconds, suggested to clients whose writes are
+// rejected, if none is given.
+const DefaultRetryAfter = 60
+
+var log = internal.GetLogger()
+
+var _ models.MaintenanceMode = &Mode{}
+
+// Mode is the maintenance mode of a server. It's held in memory, so it must be enabled on
+// each server sharing a database.
+type Mode struct {
+	mu     sync.RWMutex
+	status models.MaintenanceStatus
+	now    func() time.Time
+}
+
+// New returns a disabled Mode.
+func New() *Mode {
+	return &Mode{now: time.Now}
+}
+
+func (m *Mode) Status() models.MaintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Enable enables maintenance mode. If it's already enabled, the reason and retryAfter are
+// updated, and Since is kept. A retryAfter of 0 uses DefaultRetryAfter.
+func (m *Mode) Enable(reason string, retryAfter int) {
+	if retryAfter <= 0 {
+		retryAfter = DefaultRetryAfter
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	since := m.status.Since
+	if since == nil {
+		now := m.now()
+		since = &now
+	}
+	m.status = models.MaintenanceStatus{
+		Enabled:    true,
+		Reason:     reason,
+		Since:      since,
+		RetryAfter: retryAfter,
+	}
+	log.Warnf("maintenance mode enabled, writes are rejected: %s", reason)
+}
+
+func (m *Mode) Disable() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.status.Enabled {
+		log.Info("maintenance mode disabled")
+	}
+	m.status = models.MaintenanceStatus{}
+}
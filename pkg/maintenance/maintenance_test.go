@@ -0,0 +1,37 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMode(t *testing.T) {
+	m := New()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return start }
+	assert.False(t, m.Status().Enabled)
+
+	m.Enable("restore", 0)
+	status := m.Status()
+	assert.True(t, status.Enabled)
+	assert.Equal(t, "restore", status.Reason)
+	assert.Equal(t, DefaultRetryAfter, status.RetryAfter)
+	require.NotNil(t, status.Since)
+	assert.Equal(t, start, *status.Since)
+
+	// enabling again keeps the time maintenance started
+	m.now = func() time.Time { return start.Add(time.Hour) }
+	m.Enable("reindex", 30)
+	status = m.Status()
+	assert.Equal(t, "reindex", status.Reason)
+	assert.Equal(t, 30, status.RetryAfter)
+	assert.Equal(t, start, *status.Since)
+
+	m.Disable()
+	status = m.Status()
+	assert.False(t, status.Enabled)
+	assert.Nil(t, status.Since)
+}
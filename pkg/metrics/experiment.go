@@ -0,0 +1,42 @@
+package metrics
+
+import "time"
+
+// ExperimentMetrics counts the searches of each variant of the retrieval experiment and
+// their latency, for comparing the variants.
+type ExperimentMetrics struct {
+	experiment string
+	searches   *CounterVec
+	duration   *HistogramVec
+}
+
+// NewExperimentMetrics registers the metrics of the named retrieval experiment with r.
+func NewExperimentMetrics(r *Registry, experiment string) *ExperimentMetrics {
+	return &ExperimentMetrics{
+		experiment: experiment,
+		searches: r.NewCounterVec(
+			"zep_experiment_searches_total",
+			"Searches made in a retrieval experiment, by variant, operation, and outcome.",
+			"experiment", "variant", "operation", "outcome",
+		),
+		duration: r.NewHistogramVec(
+			"zep_experiment_search_duration_seconds",
+			"Duration of successful searches made in a retrieval experiment.",
+			searchBuckets,
+			"experiment", "variant", "operation",
+		),
+	}
+}
+
+// Observe records a search made with the variant that returned status after duration.
+func (m *ExperimentMetrics) Observe(
+	variant, operation string,
+	status int,
+	duration time.Duration,
+) {
+	o := outcome(status)
+	m.searches.Inc(m.experiment, variant, operation, o)
+	if o == outcomeSuccess {
+		m.duration.Observe(duration.Seconds(), m.experiment, variant, operation)
+	}
+}
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/getzep/zep/config"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("requests_total", "Requests.", "code")
+	c.Inc("500")
+	c.Add(2, "200")
+	r.NewGaugeVec("up", "Up.").Set(1)
+	h := r.NewHistogramVec("duration_seconds", "Duration.", []float64{1, 0.5, 1}, "op")
+	h.Observe(0.25, "get")
+	h.Observe(0.75, "get")
+	h.Observe(2, "get")
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{code="200"} 2
+requests_total{code="500"} 1
+# HELP up Up.
+# TYPE up gauge
+up 1
+# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{op="get",le="0.5"} 1
+duration_seconds_bucket{op="get",le="1"} 2
+duration_seconds_bucket{op="get",le="+Inf"} 3
+duration_seconds_sum{op="get"} 3
+duration_seconds_count{op="get"} 3
+`, rec.Body.String())
+}
+
+func TestSLIMetrics(t *testing.T) {
+	r := NewRegistry()
+	m := NewSLIMetrics(r, config.SLOConfig{SearchLatencyThreshold: 300})
+	assert.Equal(t, DefaultAvailabilityObjective, m.SLOs.Availability)
+	assert.Equal(t, 300*time.Millisecond, m.SLOs.SearchLatencyThreshold)
+
+	m.Observe(OperationMemoryRead, http.StatusOK, time.Millisecond)
+	m.Observe(OperationMemoryRead, http.StatusNotFound, time.Millisecond)
+	m.Observe(OperationMemoryWrite, http.StatusServiceUnavailable, time.Millisecond)
+	m.Observe(OperationMemorySearch, http.StatusOK, 100*time.Millisecond)
+	m.Observe(OperationMemorySearch, http.StatusOK, time.Second)
+	m.Observe(OperationDocumentSearch, http.StatusInternalServerError, time.Second)
+
+	assert.Equal(t, 2.0, m.requests.Value(OperationMemoryRead, outcomeSuccess))
+	assert.Equal(t, 1.0, m.requests.Value(OperationMemoryWrite, outcomeError))
+	assert.Equal(t, 1.0, m.slowSearches.Value(OperationMemorySearch))
+	// failed searches only count against availability
+	assert.Equal(t, 0.0, m.slowSearches.Value(OperationDocumentSearch))
+
+	var b strings.Builder
+	require.NoError(t, r.Write(&b))
+	out := b.String()
+	assert.Contains(
+		t,
+		out,
+		`zep_sli_search_duration_seconds_bucket{operation="memory_search",le="0.3"} 1`,
+	)
+	assert.Contains(t, out, `zep_sli_search_duration_seconds_count{operation="memory_search"} 2`)
+	assert.Contains(t, out, `zep_slo_objective_ratio{slo="search_latency"} 0.99`)
+	assert.Contains(t, out, `zep_slo_search_latency_threshold_seconds 0.3`)
+	assert.Contains(t, out, `zep_sli_requests_total{operation="memory_write",outcome="success"} 0`)
+}
+
+func TestAlertRules(t *testing.T) {
+	slos := NewSLOs(config.SLOConfig{Availability: 0.995})
+	b, err := MarshalAlertRules(slos)
+	require.NoError(t, err)
+
+	var file RuleFile
+	require.NoError(t, yaml.Unmarshal(b, &file))
+	require.Len(t, file.Groups, 4)
+
+	recording := file.Groups[0]
+	assert.Equal(t, "zep-slo-availability-recording", recording.Name)
+	require.Len(t, recording.Rules, 5)
+	assert.Equal(t, "zep:slo_availability_error_ratio:rate5m", recording.Rules[0].Record)
+
+	alerts := file.Groups[1]
+	require.Len(t, alerts.Rules, len(burnRateAlerts))
+	page := alerts.Rules[0]
+	assert.Equal(t, "ZepAvailabilityErrorBudgetBurn", page.Alert)
+	assert.Equal(
+		t,
+		"zep:slo_availability_error_ratio:rate1h > 0.072 and "+
+			"zep:slo_availability_error_ratio:rate5m > 0.072",
+		page.Expr,
+	)
+	assert.Equal(t, "page", page.Labels["severity"])
+	assert.Contains(t, page.Annotations["description"], "objective 99.5%")
+
+	latency := file.Groups[3]
+	assert.Contains(t, latency.Rules[0].Expr, "> 0.144")
+	assert.Contains(t, latency.Rules[0].Annotations["description"], "slower than 500ms")
+}
+
+func TestTenantLabeler(t *testing.T) {
+	l := NewTenantLabeler(nil, 2)
+	assert.Equal(t, "acme", l.Label("acme"))
+	assert.Equal(t, "globex", l.Label("globex"))
+	assert.Equal(t, TenantOther, l.Label("initech"))
+	assert.Equal(t, "acme", l.Label("acme"))
+	assert.Equal(t, TenantUnknown, l.Label(""))
+	assert.Equal(t, TenantOther, l.Label(strings.Repeat("a", maxTenantLength+1)))
+
+	l = NewTenantLabeler([]string{"acme"}, 0)
+	assert.Equal(t, DefaultMaxTenants, l.max)
+	assert.Equal(t, "acme", l.Label("acme"))
+	assert.Equal(t, TenantOther, l.Label("globex"))
+	// a tenant can't pose as the label of other tenants
+	assert.Equal(t, TenantOther, l.Label(TenantUnknown))
+}
@@ -0,0 +1,320 @@
+// Package metrics exposes counters, gauges, and histograms in the Prometheus text format,
+// and records the service level indicators of the API.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metrics and writes them in the Prometheus text exposition format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w io.Writer) error
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes the metrics in registration order.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the metrics for scraping.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.Write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// vec holds the series of a metric, keyed by their label values.
+type vec[T any] struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series[T]
+	newT   func() *T
+}
+
+type series[T any] struct {
+	labelValues []string
+	value       *T
+}
+
+func (v *vec[T]) with(labelValues []string) *T {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf(
+			"metric %s has %d labels, got %d values",
+			v.name,
+			len(v.labels),
+			len(labelValues),
+		))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series[T]{labelValues: append([]string(nil), labelValues...), value: v.newT()}
+		v.series[key] = s
+	}
+	return s.value
+}
+
+// each calls fn with each series, ordered by label values, while holding the lock.
+func (v *vec[T]) each(fn func(labels string, value *T) error) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := v.series[k]
+		if err := fn(formatLabels(v.labels, s.labelValues), s.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *vec[T]) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+	return err
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	vec[float64]
+}
+
+// NewCounterVec registers a counter. Counter names should end in _total.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec[float64]{
+		name:   name,
+		help:   help,
+		kind:   "counter",
+		labels: labels,
+		series: make(map[string]*series[float64]),
+		newT:   func() *float64 { return new(float64) },
+	}}
+	r.register(c)
+	return c
+}
+
+// Inc adds 1 to the counter with the given label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the counter with the given label values.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	value := c.with(labelValues)
+	c.mu.Lock()
+	*value += v
+	c.mu.Unlock()
+}
+
+// Value returns the counter with the given label values.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	value := c.with(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return *value
+}
+
+func (c *CounterVec) write(w io.Writer) error {
+	if err := c.writeHeader(w); err != nil {
+		return err
+	}
+	return c.each(func(labels string, value *float64) error {
+		_, err := fmt.Fprintf(w, "%s%s %s\n", c.name, labels, formatFloat(*value))
+		return err
+	})
+}
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct {
+	vec[float64]
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec[float64]{
+		name:   name,
+		help:   help,
+		kind:   "gauge",
+		labels: labels,
+		series: make(map[string]*series[float64]),
+		newT:   func() *float64 { return new(float64) },
+	}}
+	r.register(g)
+	return g
+}
+
+// Set sets the gauge with the given label values.
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	value := g.with(labelValues)
+	g.mu.Lock()
+	*value = v
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) write(w io.Writer) error {
+	if err := g.writeHeader(w); err != nil {
+		return err
+	}
+	return g.each(func(labels string, value *float64) error {
+		_, err := fmt.Fprintf(w, "%s%s %s\n", g.name, labels, formatFloat(*value))
+		return err
+	})
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	vec[histogram]
+	buckets []float64
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds, which are
+// sorted and deduplicated.
+func (r *Registry) NewHistogramVec(
+	name, help string,
+	buckets []float64,
+	labels ...string,
+) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	deduped := sorted[:0]
+	for i, b := range sorted {
+		if i == 0 || b != sorted[i-1] {
+			deduped = append(deduped, b)
+		}
+	}
+
+	h := &HistogramVec{
+		vec: vec[histogram]{
+			name:   name,
+			help:   help,
+			kind:   "histogram",
+			labels: labels,
+			series: make(map[string]*series[histogram]),
+			newT: func() *histogram {
+				return &histogram{counts: make([]uint64, len(deduped))}
+			},
+		},
+		buckets: deduped,
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records v in the histogram with the given label values.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	value := h.with(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, b := range h.buckets {
+		if v <= b {
+			value.counts[i]++
+		}
+	}
+	value.count++
+	value.sum += v
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	if err := h.writeHeader(w); err != nil {
+		return err
+	}
+	return h.each(func(labels string, value *histogram) error {
+		for i, b := range h.buckets {
+			_, err := fmt.Fprintf(
+				w,
+				"%s_bucket%s %d\n",
+				h.name,
+				withLabel(labels, "le", formatFloat(b)),
+				value.counts[i],
+			)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(
+			w,
+			"%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.name, withLabel(labels, "le", "+Inf"), value.count,
+			h.name, labels, formatFloat(value.sum),
+			h.name, labels, value.count,
+		)
+		return err
+	})
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(values[i])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// withLabel adds a label to formatted labels.
+func withLabel(labels, name, value string) string {
+	pair := name + "=" + strconv.Quote(value)
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RuleFile is a Prometheus rule file.
+type RuleFile struct {
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+type RuleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule is either a recording rule or an alerting rule.
+type Rule struct {
+	Record      string            `yaml:"record,omitempty"`
+	Alert       string            `yaml:"alert,omitempty"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// burnRateAlert fires when the error budget burns faster than BurnRate over both the
+// long and the short window. The short window resets the alert soon after the burn stops.
+type burnRateAlert struct {
+	Long     string
+	Short    string
+	BurnRate float64
+	Severity string
+}
+
+// burnRateAlerts are the multi-window alerts recommended by the Google SRE workbook. At
+// these rates, 2% of a 30 day budget is spent in 1 hour, 5% in 6 hours, or 10% in 3 days.
+var burnRateAlerts = []burnRateAlert{
+	{Long: "1h", Short: "5m", BurnRate: 14.4, Severity: "page"},
+	{Long: "6h", Short: "30m", BurnRate: 6, Severity: "page"},
+	{Long: "3d", Short: "6h", BurnRate: 1, Severity: "ticket"},
+}
+
+// sli is the ratio of bad events of an SLO, as a function of a rate window.
+type sli struct {
+	name        string
+	title       string
+	objective   float64
+	errorRatio  func(window string) string
+	description string
+}
+
+// AlertRules returns recording rules for the error ratios of the SLOs over each window,
+// and burn-rate alerts on them.
+func AlertRules(slos SLOs) *RuleFile {
+	slis := []sli{
+		{
+			name:      "availability",
+			title:     "Availability",
+			objective: slos.Availability,
+			errorRatio: func(window string) string {
+				return fmt.Sprintf(
+					`sum(rate(zep_sli_requests_total{outcome="error"}[%s]))`+
+						` / sum(rate(zep_sli_requests_total[%s]))`,
+					window, window,
+				)
+			},
+			description: "of memory and search requests are failing with server errors",
+		},
+		{
+			name:      "search_latency",
+			title:     "SearchLatency",
+			objective: slos.SearchLatency,
+			errorRatio: func(window string) string {
+				return fmt.Sprintf(
+					`sum(rate(zep_sli_search_slow_total[%s]))`+
+						` / sum(rate(zep_sli_search_duration_seconds_count[%s]))`,
+					window, window,
+				)
+			},
+			description: fmt.Sprintf(
+				"of searches are slower than %s",
+				slos.SearchLatencyThreshold,
+			),
+		},
+	}
+
+	var windows []string
+	seen := make(map[string]bool)
+	for _, a := range burnRateAlerts {
+		for _, w := range []string{a.Short, a.Long} {
+			if !seen[w] {
+				seen[w] = true
+				windows = append(windows, w)
+			}
+		}
+	}
+
+	file := &RuleFile{}
+	for _, s := range slis {
+		recording := RuleGroup{Name: "zep-slo-" + s.name + "-recording"}
+		for _, w := range windows {
+			recording.Rules = append(recording.Rules, Rule{
+				Record: errorRatioRecord(s.name, w),
+				Expr:   s.errorRatio(w),
+			})
+		}
+
+		budget := 1 - s.objective
+		alerts := RuleGroup{Name: "zep-slo-" + s.name + "-alerts"}
+		for _, a := range burnRateAlerts {
+			threshold := formatRatio(a.BurnRate * budget)
+			alerts.Rules = append(alerts.Rules, Rule{
+				Alert: "Zep" + s.title + "ErrorBudgetBurn",
+				Expr: fmt.Sprintf(
+					"%s > %s and %s > %s",
+					errorRatioRecord(s.name, a.Long), threshold,
+					errorRatioRecord(s.name, a.Short), threshold,
+				),
+				Labels: map[string]string{
+					"severity": a.Severity,
+					"slo":      s.name,
+					"window":   a.Long,
+				},
+				Annotations: map[string]string{
+					"summary": fmt.Sprintf(
+						"Zep is burning its %s error budget %sx faster than sustainable",
+						s.name, formatRatio(a.BurnRate),
+					),
+					"description": fmt.Sprintf(
+						"Over the last %s, more than %s %s (objective %s).",
+						a.Long,
+						formatPercent(a.BurnRate*budget),
+						s.description,
+						formatPercent(s.objective),
+					),
+				},
+			})
+		}
+		file.Groups = append(file.Groups, recording, alerts)
+	}
+	return file
+}
+
+// MarshalAlertRules returns the alert rules of the SLOs as a YAML rule file.
+func MarshalAlertRules(slos SLOs) ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(AlertRules(slos)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func errorRatioRecord(slo, window string) string {
+	return "zep:slo_" + slo + "_error_ratio:rate" + window
+}
+
+func formatRatio(v float64) string {
+	// round away floating point noise, e.g. 14.4 * 0.001
+	return strconv.FormatFloat(v, 'g', 10, 64)
+}
+
+func formatPercent(v float64) string {
+	return formatRatio(v*100) + "%"
+}
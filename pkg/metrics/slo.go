@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/getzep/zep/config"
+)
+
+const (
+	DefaultAvailabilityObjective  = 0.999
+	DefaultSearchLatencyObjective = 0.99
+	DefaultSearchLatencyThreshold = 500 * time.Millisecond
+)
+
+// Operations measured by the SLIs.
+const (
+	OperationMemoryRead     = "memory_read"
+	OperationMemoryWrite    = "memory_write"
+	OperationMemorySearch   = "memory_search"
+	OperationDocumentSearch = "document_search"
+)
+
+const (
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+)
+
+// searchBuckets are the latency buckets of searches, in seconds. The SLO threshold is
+// added so that the latency SLI can also be computed from the histogram.
+var searchBuckets = []float64{0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// SLOs are the service level objectives, with defaults applied.
+type SLOs struct {
+	Availability           float64
+	SearchLatency          float64
+	SearchLatencyThreshold time.Duration
+}
+
+func NewSLOs(cfg config.SLOConfig) SLOs {
+	slos := SLOs{
+		Availability:           cfg.Availability,
+		SearchLatency:          cfg.SearchLatency,
+		SearchLatencyThreshold: time.Duration(cfg.SearchLatencyThreshold) * time.Millisecond,
+	}
+	if slos.Availability <= 0 || slos.Availability >= 1 {
+		slos.Availability = DefaultAvailabilityObjective
+	}
+	if slos.SearchLatency <= 0 || slos.SearchLatency >= 1 {
+		slos.SearchLatency = DefaultSearchLatencyObjective
+	}
+	if slos.SearchLatencyThreshold <= 0 {
+		slos.SearchLatencyThreshold = DefaultSearchLatencyThreshold
+	}
+	return slos
+}
+
+// SLIMetrics counts good and bad events of memory reads, writes, and searches.
+//
+// Requests failing with a server error count against availability. Client errors are the
+// caller's fault and count as successes. Successful searches slower than the latency
+// threshold count against the latency objective.
+type SLIMetrics struct {
+	SLOs SLOs
+
+	requests       *CounterVec
+	searchDuration *HistogramVec
+	slowSearches   *CounterVec
+}
+
+// NewSLIMetrics registers the SLI metrics, and the objectives of the SLOs, with r.
+func NewSLIMetrics(r *Registry, cfg config.SLOConfig) *SLIMetrics {
+	slos := NewSLOs(cfg)
+	m := &SLIMetrics{
+		SLOs: slos,
+		requests: r.NewCounterVec(
+			"zep_sli_requests_total",
+			"Memory and search requests, by operation and outcome.",
+			"operation", "outcome",
+		),
+		searchDuration: r.NewHistogramVec(
+			"zep_sli_search_duration_seconds",
+			"Duration of successful searches.",
+			append(searchBuckets, slos.SearchLatencyThreshold.Seconds()),
+			"operation",
+		),
+		slowSearches: r.NewCounterVec(
+			"zep_sli_search_slow_total",
+			"Successful searches slower than the SLO latency threshold.",
+			"operation",
+		),
+	}
+
+	objectives := r.NewGaugeVec(
+		"zep_slo_objective_ratio",
+		"Target ratio of good events of each SLO.",
+		"slo",
+	)
+	objectives.Set(slos.Availability, "availability")
+	objectives.Set(slos.SearchLatency, "search_latency")
+	r.NewGaugeVec(
+		"zep_slo_search_latency_threshold_seconds",
+		"Latency within which searches count towards the search latency SLO.",
+	).Set(slos.SearchLatencyThreshold.Seconds())
+
+	// initialize the series so that rates are defined before the first error
+	for _, op := range []string{
+		OperationMemoryRead,
+		OperationMemoryWrite,
+		OperationMemorySearch,
+		OperationDocumentSearch,
+	} {
+		m.requests.Add(0, op, outcomeSuccess)
+		m.requests.Add(0, op, outcomeError)
+	}
+	for _, op := range []string{OperationMemorySearch, OperationDocumentSearch} {
+		m.slowSearches.Add(0, op)
+	}
+
+	return m
+}
+
+// Observe records a request of the operation that returned status after duration.
+func (m *SLIMetrics) Observe(operation string, status int, duration time.Duration) {
+	o := outcome(status)
+	m.requests.Inc(operation, o)
+	if o == outcomeError ||
+		(operation != OperationMemorySearch && operation != OperationDocumentSearch) {
+		return
+	}
+	m.searchDuration.Observe(duration.Seconds(), operation)
+	if duration > m.SLOs.SearchLatencyThreshold {
+		m.slowSearches.Inc(operation)
+	}
+}
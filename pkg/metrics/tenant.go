@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/getzep/zep/config"
+)
+
+const (
+	DefaultMaxTenants = 100
+
+	// TenantUnknown labels requests that don't name a tenant.
+	TenantUnknown = "unknown"
+	// TenantOther labels tenants that aren't allowed, or are beyond the cap.
+	TenantOther = "other"
+
+	// maxTenantLength is the longest tenant name used as a label.
+	maxTenantLength = 128
+)
+
+// TenantLabeler maps tenants to metric labels, bounding the number of labels. Tenants
+// are labeled in the order they are first seen until the cap is reached.
+type TenantLabeler struct {
+	allow map[string]bool
+	max   int
+
+	mu       sync.Mutex
+	labeled  map[string]bool
+	overflow *CounterVec
+}
+
+func NewTenantLabeler(allow []string, maxTenants int) *TenantLabeler {
+	if maxTenants <= 0 {
+		maxTenants = DefaultMaxTenants
+	}
+	l := &TenantLabeler{max: maxTenants, labeled: make(map[string]bool)}
+	if len(allow) > 0 {
+		l.allow = make(map[string]bool, len(allow))
+		for _, tenant := range allow {
+			l.allow[tenant] = true
+		}
+	}
+	return l
+}
+
+// Label returns the label of the tenant.
+func (l *TenantLabeler) Label(tenant string) string {
+	if tenant == "" {
+		return TenantUnknown
+	}
+	if len(tenant) > maxTenantLength || tenant == TenantOther || tenant == TenantUnknown {
+		return l.other()
+	}
+	if l.allow != nil && !l.allow[tenant] {
+		return l.other()
+	}
+
+	l.mu.Lock()
+	if !l.labeled[tenant] && len(l.labeled) >= l.max {
+		l.mu.Unlock()
+		return l.other()
+	}
+	l.labeled[tenant] = true
+	l.mu.Unlock()
+	return tenant
+}
+
+func (l *TenantLabeler) other() string {
+	if l.overflow != nil {
+		l.overflow.Inc()
+	}
+	return TenantOther
+}
+
+// TenantMetrics counts API requests and their duration by tenant, for monitoring and
+// billing tenants sharing a deployment.
+type TenantMetrics struct {
+	labeler  *TenantLabeler
+	requests *CounterVec
+	duration *CounterVec
+}
+
+// NewTenantMetrics registers the tenant metrics with r.
+func NewTenantMetrics(r *Registry, cfg config.TenantMetricsConfig) *TenantMetrics {
+	labeler := NewTenantLabeler(cfg.Allow, cfg.MaxTenants)
+	labeler.overflow = r.NewCounterVec(
+		"zep_tenant_label_overflow_total",
+		"Requests labeled as tenant \"other\" because the tenant isn't allowed or the "+
+			"number of tenant labels is capped.",
+	)
+	labeler.overflow.Add(0)
+	r.NewGaugeVec(
+		"zep_tenant_labels_max",
+		"Maximum number of tenant labels.",
+	).Set(float64(labeler.max))
+
+	return &TenantMetrics{
+		labeler: labeler,
+		requests: r.NewCounterVec(
+			"zep_tenant_requests_total",
+			"API requests, by tenant, operation, and outcome.",
+			"tenant", "operation", "outcome",
+		),
+		duration: r.NewCounterVec(
+			"zep_tenant_request_duration_seconds_total",
+			"Total duration of API requests, by tenant and operation.",
+			"tenant", "operation",
+		),
+	}
+}
+
+// Observe records a request of the tenant's that returned status after duration.
+func (m *TenantMetrics) Observe(
+	tenant, operation string,
+	status int,
+	duration time.Duration,
+) {
+	label := m.labeler.Label(tenant)
+	m.requests.Inc(label, operation, outcome(status))
+	m.duration.Add(duration.Seconds(), label, operation)
+}
+
+func outcome(status int) string {
+	if status >= 500 {
+		return outcomeError
+	}
+	return outcomeSuccess
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AnonymizationReport is the outcome of anonymizing a user. It doesn't record the user's
+// original ID, so that the report doesn't identify the user.
+type AnonymizationReport struct {
+	// UserID is the random ID the user was re-keyed to.
+	UserID   string `json:"user_id"`
+	Sessions int    `json:"sessions"`
+	Messages int    `json:"messages"`
+	// MessagesScrubbed counts the messages whose content had PII replaced.
+	MessagesScrubbed int       `json:"messages_scrubbed"`
+	AnonymizedAt     time.Time `json:"anonymized_at"`
+}
@@ -7,11 +7,25 @@ import (
 // AppState is a struct that holds the state of the application
 // Use cmd.NewAppState to create a new instance
 type AppState struct {
-	LLMClient     ZepLLM
-	MemoryStore   MemoryStore[any]
-	DocumentStore DocumentStore[any]
-	UserStore     UserStore
+	LLMClient           ZepLLM
+	MemoryStore         MemoryStore[any]
+	DocumentStore       DocumentStore[any]
+	UserStore           UserStore
+	AsyncTaskStore      AsyncTaskStore
+	MemorySnapshotStore MemorySnapshotStore
+	ProvenanceStore     ProvenanceStore
+	QueryLogStore       QueryLogStore
+	EvalSetStore        EvalSetStore
+	LegalHoldStore      LegalHoldStore
+	// ProviderOverrideStore and TenantProviders are set if tenant providers are enabled.
+	ProviderOverrideStore ProviderOverrideStore
+	TenantProviders       TenantProviders
+	// VectorStore stores the embeddings of document collections. If nil, they're stored in
+	// the collections' tables using pgvector.
+	VectorStore   VectorStore
 	TaskRouter    TaskRouter
 	TaskPublisher TaskPublisher
+	Scheduler     JobScheduler
+	Maintenance   MaintenanceMode
 	Config        *config.Config
 }
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AsyncTaskStatus is the state of a long-running operation tracked as an AsyncTask.
+type AsyncTaskStatus string
+
+const (
+	AsyncTaskPending   AsyncTaskStatus = "pending"
+	AsyncTaskRunning   AsyncTaskStatus = "running"
+	AsyncTaskSucceeded AsyncTaskStatus = "succeeded"
+	AsyncTaskFailed    AsyncTaskStatus = "failed"
+	AsyncTaskCanceled  AsyncTaskStatus = "canceled"
+)
+
+// IsFinished is true for the statuses a task can't leave.
+func (s AsyncTaskStatus) IsFinished() bool {
+	return s == AsyncTaskSucceeded || s == AsyncTaskFailed || s == AsyncTaskCanceled
+}
+
+// AsyncTask tracks a long-running operation, such as reindexing a collection, that runs
+// after the request starting it has returned. Completed and Total report progress in
+// units of the task's choosing, and Total is 0 while unknown.
+type AsyncTask struct {
+	UUID       uuid.UUID              `json:"uuid"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+	Type       string                 `json:"type"`
+	Status     AsyncTaskStatus        `json:"status"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Completed  int                    `json:"completed"`
+	Total      int                    `json:"total"`
+	Error      string                 `json:"error,omitempty"`
+	Result     map[string]interface{} `json:"result,omitempty"`
+	StartedAt  *time.Time             `json:"started_at,omitempty"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+}
+
+// AsyncTaskListOptions filters the tasks returned by AsyncTaskStore.List. Empty fields
+// don't filter.
+type AsyncTaskListOptions struct {
+	Type   string
+	Status AsyncTaskStatus
+	Limit  int
+}
+
+type AsyncTaskStore interface {
+	// Create stores a new pending task with the given type and params.
+	Create(ctx context.Context, taskType string, params map[string]interface{}) (*AsyncTask, error)
+	Get(ctx context.Context, taskUUID uuid.UUID) (*AsyncTask, error)
+	// List returns tasks newest first.
+	List(ctx context.Context, opts AsyncTaskListOptions) ([]*AsyncTask, error)
+	// Update stores the task's status, progress, error, result and start and finish
+	// times. Returns a ConflictError if the stored task is already finished, as when it
+	// has been canceled.
+	Update(ctx context.Context, task *AsyncTask) (*AsyncTask, error)
+	// Cancel marks an unfinished task as canceled. Returns a ConflictError if the task is
+	// already finished.
+	Cancel(ctx context.Context, taskUUID uuid.UUID) (*AsyncTask, error)
+	// FailAbandoned fails the unfinished tasks not updated since before, as their runner
+	// has stopped. Returns the number of tasks failed.
+	FailAbandoned(ctx context.Context, before time.Time) (int, error)
+}
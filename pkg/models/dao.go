@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MessageDAO is a data access object for the messages and message embeddings of a single
+// session. Implementations are bound to a sessionID when constructed.
+type MessageDAO interface {
+	// Create creates a new message for the session. The session is not created if it
+	// doesn't exist.
+	Create(ctx context.Context, message *Message) (*Message, error)
+	// CreateMany creates a batch of messages for the session.
+	CreateMany(ctx context.Context, messages []Message) ([]Message, error)
+	// Get retrieves a message by its UUID.
+	Get(ctx context.Context, messageUUID uuid.UUID) (*Message, error)
+	// GetLastN retrieves the last N messages for the session, in ascending order of
+	// creation. If beforeUUID is not uuid.Nil, only messages up to and including
+	// beforeUUID are considered.
+	GetLastN(ctx context.Context, lastNMessages int, beforeUUID uuid.UUID) ([]Message, error)
+	// GetSinceLastSummary retrieves up to memoryWindow messages since lastSummary's
+	// SummaryPoint, in ascending order of creation.
+	GetSinceLastSummary(
+		ctx context.Context,
+		lastSummary *Summary,
+		memoryWindow int,
+	) ([]Message, error)
+	// GetListByUUID retrieves the messages with the given UUIDs.
+	GetListByUUID(ctx context.Context, messageUUIDs []uuid.UUID) ([]Message, error)
+	// GetListBySession retrieves a page of the session's messages, oldest first.
+	GetListBySession(ctx context.Context, currentPage int, pageSize int) (*MessageListResponse, error)
+	// Update updates a message by its UUID. Metadata is deep merged. If includeContent is
+	// true, the role and content are updated, too. If isPrivileged is false, the top-level
+	// `system` metadata key is not updated.
+	Update(ctx context.Context, message *Message, includeContent bool, isPrivileged bool) error
+	// UpdateMany updates a batch of messages by their UUIDs. See Update.
+	UpdateMany(ctx context.Context, messages []Message, includeContent bool, isPrivileged bool) error
+	// Delete hard deletes a message and its embedding.
+	Delete(ctx context.Context, messageUUID uuid.UUID) error
+	// GetCompactionCandidates returns the oldest uncompacted messages in excess of maxMessages.
+	GetCompactionCandidates(ctx context.Context, maxMessages int) (*CompactionCandidates, error)
+	// Compact prunes the content of all messages up to and including throughUUID. If
+	// retainEmbeddings is false, the messages and their embeddings are deleted.
+	Compact(ctx context.Context, throughUUID uuid.UUID, retainEmbeddings bool) error
+	// CreateEmbeddings stores embeddings for the session's messages.
+	CreateEmbeddings(ctx context.Context, embeddings []TextData) error
+	// GetEmbedding retrieves the embedding for a message.
+	GetEmbedding(ctx context.Context, messageUUID uuid.UUID) (*TextData, error)
+	// GetEmbeddingListBySession retrieves all message embeddings for the session.
+	GetEmbeddingListBySession(ctx context.Context) ([]TextData, error)
+}
+
+// SummaryDAO is a data access object for the summaries and summary embeddings of a single
+// session. Implementations are bound to a sessionID when constructed.
+type SummaryDAO interface {
+	// Create stores a new summary for the session.
+	Create(ctx context.Context, summary *Summary) (*Summary, error)
+	// Update updates a summary by its UUID. Metadata is deep merged. If includeContent is
+	// true, the content is updated, too.
+	Update(ctx context.Context, summary *Summary, includeContent bool) (*Summary, error)
+	// Get returns the most recent summary for the session, or an empty Summary if the
+	// session has none.
+	Get(ctx context.Context) (*Summary, error)
+	// GetByUUID returns a summary by its UUID.
+	GetByUUID(ctx context.Context, uuid uuid.UUID) (*Summary, error)
+	// PutEmbedding stores a summary embedding.
+	PutEmbedding(ctx context.Context, embedding *TextData) error
+	// GetEmbeddings returns all summary embeddings for the session, without the summary content.
+	GetEmbeddings(ctx context.Context) ([]TextData, error)
+	// GetList returns a page of the session's summaries, oldest first.
+	GetList(ctx context.Context, currentPage int, pageSize int) (*SummaryListResponse, error)
+}
+
+// SessionDAO is a data access object for sessions.
+type SessionDAO interface {
+	SessionManager
+	// ListAllOrdered returns a page of sessions ordered by orderBy, and the total count of
+	// sessions.
+	ListAllOrdered(
+		ctx context.Context,
+		pageNumber int,
+		pageSize int,
+		orderBy string,
+		asc bool,
+	) (*SessionListResponse, error)
+	// ListExpiring returns undeleted sessions expiring before the given time that have not
+	// yet had an expiry warning sent, excluding sessions under legal hold.
+	ListExpiring(ctx context.Context, before time.Time) ([]*Session, error)
+	// MarkExpiryWarned records that an expiry warning has been sent for the given sessionIDs.
+	MarkExpiryWarned(ctx context.Context, sessionIDs []string) error
+	// DeleteExpired soft deletes all expired sessions, along with their messages, message
+	// embeddings, and summaries, skipping sessions under legal hold. The deleted sessions
+	// are returned.
+	DeleteExpired(ctx context.Context) ([]*Session, error)
+}
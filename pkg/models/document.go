@@ -9,6 +9,19 @@ import (
 type IndexType string
 type DistanceFunction string
 
+// Types of pgvector index.
+const (
+	IndexTypeIVFFlat IndexType = "ivfflat"
+	IndexTypeHNSW    IndexType = "hnsw"
+)
+
+// Distance functions comparing embeddings, each served by its pgvector operator.
+const (
+	DistanceFunctionCosine     DistanceFunction = "cosine"
+	DistanceFunctionDotProduct DistanceFunction = "dot_product"
+	DistanceFunctionL2         DistanceFunction = "l2"
+)
+
 /* Collection  Models */
 
 type DocumentCollection struct {
@@ -21,13 +34,14 @@ type DocumentCollection struct {
 	TableName                 string                 `bun:",notnull"                                                    yaml:"table_name"`
 	EmbeddingModelName        string                 `bun:",notnull"                                                    yaml:"embedding_model_name"`
 	EmbeddingDimensions       int                    `bun:",notnull"                                                    yaml:"embedding_dimensions"`
-	IsAutoEmbedded            bool                   `bun:",notnull"                                                    yaml:"is_auto_embedded"`  // Is the collection automatically embedded by Zep?
-	DistanceFunction          DistanceFunction       `bun:",notnull"                                                    yaml:"distance_function"` // Distance function to use for index
-	IsNormalized              bool                   `bun:",notnull"                                                    yaml:"is_normalized"`     // Are the embeddings normalized?
-	IsIndexed                 bool                   `bun:",notnull"                                                    yaml:"is_indexed"`        // Has an index been created on the collection table?
-	IndexType                 IndexType              `bun:",notnull"                                                    yaml:"index_type"`        // Type of index to use
-	ListCount                 int                    `bun:",notnull"                                                    yaml:"list_count"`        // Number of lists in the collection index
-	ProbeCount                int                    `bun:",notnull"                                                    yaml:"probe_count"`       // Number of probes to use when searching the index
+	IsAutoEmbedded            bool                   `bun:",notnull"                                                    yaml:"is_auto_embedded"`         // Is the collection automatically embedded by Zep?
+	DistanceFunction          DistanceFunction       `bun:",notnull"                                                    yaml:"distance_function"`        // Distance function to use for index
+	IsNormalized              bool                   `bun:",notnull"                                                    yaml:"is_normalized"`            // Are the embeddings normalized?
+	IsIndexed                 bool                   `bun:",notnull"                                                    yaml:"is_indexed"`               // Has an index been created on the collection table?
+	IndexType                 IndexType              `bun:",notnull"                                                    yaml:"index_type"`               // Type of index to use
+	ListCount                 int                    `bun:",notnull"                                                    yaml:"list_count"`               // Number of lists in the collection index
+	ProbeCount                int                    `bun:",notnull"                                                    yaml:"probe_count"`              // Number of probes to use when searching the index
+	ContextualChunkHeaders    bool                   `bun:",notnull,default:false"                                      yaml:"contextual_chunk_headers"` // Is an LLM-written context line embedded with each document?
 	*DocumentCollectionCounts ` yaml:"document_collection_counts,inline"`
 }
 
@@ -43,6 +57,20 @@ type CreateDocumentCollectionRequest struct {
 	EmbeddingDimensions int                    `json:"embedding_dimensions" validate:"required,numeric,min=8,max=2000"`
 	// these needs to be pointers so that we can distinguish between false and unset when validating
 	IsAutoEmbedded *bool `json:"is_auto_embedded"     validate:"required,boolean"`
+	// ContextualChunkHeaders requires IsAutoEmbedded, and can't be changed once the
+	// collection is created.
+	ContextualChunkHeaders bool `json:"contextual_chunk_headers,omitempty"`
+	// IndexType is the type of the collection's vector index, ivfflat or hnsw. Defaults to
+	// hnsw if the vector extension supports it. IVFFlat indexes are built once the
+	// collection has enough documents, while HNSW indexes are maintained as documents are
+	// added.
+	IndexType IndexType `json:"index_type,omitempty" validate:"omitempty,oneof=ivfflat hnsw"`
+	// DistanceFunction compares the collection's embeddings, cosine, dot_product or l2, and
+	// can't be changed once the collection is created. Defaults to the distance function of
+	// the document embedding model. Search scores are of the distance function: the cosine
+	// similarity normalized to between 0 and 1, the inner product, or 1 / (1 + the Euclidean
+	// distance).
+	DistanceFunction DistanceFunction `json:"distance_function,omitempty" validate:"omitempty,oneof=cosine dot_product l2"`
 }
 
 type UpdateDocumentCollectionRequest struct {
@@ -51,17 +79,20 @@ type UpdateDocumentCollectionRequest struct {
 }
 
 type DocumentCollectionResponse struct {
-	UUID                uuid.UUID              `json:"uuid"`
-	CreatedAt           time.Time              `json:"created_at"`
-	UpdatedAt           time.Time              `json:"updated_at"`
-	Name                string                 `json:"name"`
-	Description         string                 `json:"description"`
-	Metadata            map[string]interface{} `json:"metadata,omitempty"`
-	EmbeddingModelName  string                 `json:"embedding_model_name,omitempty"`
-	EmbeddingDimensions int                    `json:"embedding_dimensions"`
-	IsAutoEmbedded      bool                   `json:"is_auto_embedded"`
-	IsNormalized        bool                   `json:"is_normalized"`
-	IsIndexed           bool                   `json:"is_indexed"`
+	UUID                   uuid.UUID              `json:"uuid"`
+	CreatedAt              time.Time              `json:"created_at"`
+	UpdatedAt              time.Time              `json:"updated_at"`
+	Name                   string                 `json:"name"`
+	Description            string                 `json:"description"`
+	Metadata               map[string]interface{} `json:"metadata,omitempty"`
+	EmbeddingModelName     string                 `json:"embedding_model_name,omitempty"`
+	EmbeddingDimensions    int                    `json:"embedding_dimensions"`
+	IsAutoEmbedded         bool                   `json:"is_auto_embedded"`
+	IsNormalized           bool                   `json:"is_normalized"`
+	IsIndexed              bool                   `json:"is_indexed"`
+	IndexType              IndexType              `json:"index_type,omitempty"`
+	DistanceFunction       DistanceFunction       `json:"distance_function,omitempty"`
+	ContextualChunkHeaders bool                   `json:"contextual_chunk_headers"`
 	*DocumentCollectionCounts
 }
 
@@ -76,6 +107,10 @@ type DocumentBase struct {
 	Content    string                 `bun:",nullzero"`
 	Metadata   map[string]interface{} `bun:"type:jsonb,nullzero,json_use_number"`
 	IsEmbedded bool                   `bun:",nullzero"`
+	// Summary is generated by the document summarizer, if enabled.
+	Summary string `bun:",nullzero"`
+	// ContentHash is the ContentHash of Content when it was stored. It's set by the store.
+	ContentHash string `bun:",nullzero"`
 }
 
 type Document struct {
@@ -95,9 +130,31 @@ type CreateDocumentRequest struct {
 	Embedding  []float32              `json:"embedding,omitempty"   validate:"required_without=Content,omitempty"`
 }
 
+// DocumentIngestResult reports the outcome of ingesting a single document. Index is the
+// position of the document in the ingest request.
+type DocumentIngestResult struct {
+	Index      int        `json:"index"`
+	UUID       *uuid.UUID `json:"uuid,omitempty"`
+	DocumentID string     `json:"document_id,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+type DocumentIngestResponse struct {
+	Results            []DocumentIngestResult `json:"results"`
+	Succeeded          int                    `json:"succeeded"`
+	Failed             int                    `json:"failed"`
+	DurationMs         int64                  `json:"duration_ms"`
+	DocumentsPerSecond float64                `json:"documents_per_second"`
+}
+
+// UpdateDocumentRequest updates the given fields of a Document. Updating the content of a
+// document in an auto-embedded collection re-embeds it, while a collection that isn't
+// auto-embedded requires a new embedding with the content.
 type UpdateDocumentRequest struct {
-	DocumentID string                 `json:"document_id"        validate:"printascii,max=40,omitempty"`
-	Metadata   map[string]interface{} `json:"metadata,omitempty" validate:"omitempty"`
+	DocumentID string                 `json:"document_id"         validate:"printascii,max=40,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"  validate:"omitempty"`
+	Content    string                 `json:"content,omitempty"`
+	Embedding  []float32              `json:"embedding,omitempty"`
 }
 
 type UpdateDocumentListRequest struct {
@@ -124,6 +181,9 @@ type DocumentResponse struct {
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 	Embedding  []float32              `json:"embedding"`
 	IsEmbedded bool                   `json:"is_embedded"`
+	Summary    string                 `json:"summary,omitempty"`
+	// ContentHash is the hash of the content when it was stored.
+	ContentHash string `json:"content_hash,omitempty"`
 }
 
 type DocEmbeddingTask struct {
@@ -38,6 +38,14 @@ type DocumentStore[T any] interface {
 		collectionName string,
 		documents []Document,
 	) ([]uuid.UUID, error)
+	// IngestDocuments creates a large batch of Documents, split across concurrent workers.
+	// Unlike CreateDocuments, a failed Document does not fail the batch. The outcome of
+	// each Document is reported in the response.
+	IngestDocuments(
+		ctx context.Context,
+		collectionName string,
+		documents []Document,
+	) (*DocumentIngestResponse, error)
 	// UpdateDocuments updates a batch of Documents.
 	// The provided Document UUIDs must match existing documents.
 	UpdateDocuments(
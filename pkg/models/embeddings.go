@@ -1,11 +1,34 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Quantization is the storage format of an embedding column.
+type Quantization string
+
+const (
+	// QuantizationNone stores full precision float vectors.
+	QuantizationNone Quantization = ""
+	// QuantizationHalfVec stores half precision float vectors, halving storage and index size.
+	QuantizationHalfVec Quantization = "halfvec"
+	// QuantizationBit stores full precision float vectors, indexed as binary quantized bit vectors.
+	// Search candidates are found using the bit index and then reranked using the full vectors.
+	// Only supported for documents.
+	QuantizationBit Quantization = "bit"
+)
 
 type EmbeddingModel struct {
-	Service      string `json:"service"`
-	Dimensions   int    `json:"dimensions"`
-	IsNormalized bool   `json:"normalized"`
+	Service      string       `json:"service"`
+	Dimensions   int          `json:"dimensions"`
+	IsNormalized bool         `json:"normalized"`
+	Quantization Quantization `json:"quantization,omitempty"`
+	// DistanceFunction compares the embeddings. Empty is cosine.
+	DistanceFunction DistanceFunction `json:"distance_function,omitempty"`
+	// Matryoshka embeddings are truncated to Dimensions and re-normalized.
+	Matryoshka bool `json:"matryoshka,omitempty"`
 }
 
 type TextData struct {
@@ -13,6 +36,9 @@ type TextData struct {
 	Text      string    `json:"text"`
 	Embedding []float32 `json:"embedding,omitempty"`
 	Language  string    `json:"language"`
+	// Window lists the messages embedded together with a message, oldest first, if the
+	// message was embedded with a sliding window.
+	Window []uuid.UUID `json:"window,omitempty"`
 }
 
 type TextEmbeddingCollection struct {
@@ -20,3 +46,14 @@ type TextEmbeddingCollection struct {
 	Name       string     `json:"name,omitempty"`
 	Embeddings []TextData `json:"documents"`
 }
+
+// EmbeddingDriftReport compares a sample of stored message embeddings with fresh
+// embeddings of the same text. Embeddings whose dimensions differ have a similarity of 0.
+type EmbeddingDriftReport struct {
+	Sampled             int       `json:"sampled"`
+	MeanSimilarity      float64   `json:"mean_similarity"`
+	MinSimilarity       float64   `json:"min_similarity"`
+	DimensionMismatches int       `json:"dimension_mismatches"`
+	Drifted             bool      `json:"drifted"`
+	CheckedAt           time.Time `json:"checked_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EpisodeMetadataKey is the key, under system, of the ID of the episode a message belongs
+// to.
+const EpisodeMetadataKey = "episode"
+
+// Episode is a run of a session's messages about a single topic. A session's episodes
+// are numbered from 1, and only its latest episode is open to new messages.
+type Episode struct {
+	ID               int       `json:"id"`
+	Topic            string    `json:"topic"`
+	StartMessageUUID uuid.UUID `json:"start_message_uuid"`
+	EndMessageUUID   uuid.UUID `json:"end_message_uuid"`
+	MessageCount     int       `json:"message_count"`
+	// Summary is generated once the episode has ended.
+	Summary   string     `json:"summary,omitempty"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+type EpisodeListResponse struct {
+	Episodes []Episode `json:"episodes"`
+}
+
+// MessageEpisode returns the ID of the episode stored in the metadata of m, or 0 if m
+// hasn't been segmented into an episode.
+func MessageEpisode(m *Message) int {
+	system, ok := m.Metadata["system"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	switch id := system[EpisodeMetadataKey].(type) {
+	case int:
+		return id
+	case float64:
+		return int(id)
+	case json.Number:
+		n, _ := id.Int64()
+		return int(n)
+	}
+	return 0
+}
@@ -5,6 +5,10 @@ import (
 	"fmt"
 )
 
+// Errors returned by the store and API layers should match one of the sentinels below
+// using errors.Is, so that they can be mapped to a response in a single place. Errors
+// that don't match any of them are treated as internal errors.
+
 /* NotFoundError */
 
 var ErrNotFound = errors.New("not found")
@@ -25,28 +29,157 @@ func NewNotFoundError(resource string) error {
 	return &NotFoundError{Resource: resource}
 }
 
-/* BadRequestError */
+/* ValidationError */
+
+var ErrValidation = errors.New("validation failed")
 
-var ErrBadRequest = errors.New("bad request")
+// ErrBadRequest is an alias for ErrValidation.
+var ErrBadRequest = ErrValidation
 
-type BadRequestError struct {
+type ValidationError struct {
 	Message string
 }
 
-func (e *BadRequestError) Error() string {
+func (e *ValidationError) Error() string {
 	return fmt.Sprintf("bad request: %s", e.Message)
 }
 
-func (e *BadRequestError) Unwrap() error {
-	return ErrBadRequest
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+func NewValidationError(message string) error {
+	return &ValidationError{Message: message}
 }
 
+// BadRequestError is an alias for ValidationError.
+type BadRequestError = ValidationError
+
+// NewBadRequestError is an alias for NewValidationError.
 func NewBadRequestError(message string) error {
-	return &BadRequestError{Message: message}
+	return NewValidationError(message)
+}
+
+/* ConflictError */
+
+var ErrConflict = errors.New("conflict")
+
+// ConflictError is returned when a request conflicts with the current state of a
+// resource, such as creating a resource that already exists.
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflict: %s", e.Message)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+func NewConflictError(message string) error {
+	return &ConflictError{Message: message}
+}
+
+/* PreconditionFailedError */
+
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// PreconditionFailedError is returned when a conditional request, such as one with an
+// If-Match header, doesn't match the current version of a resource.
+type PreconditionFailedError struct {
+	Message string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return fmt.Sprintf("precondition failed: %s", e.Message)
+}
+
+func (e *PreconditionFailedError) Unwrap() error {
+	return ErrPreconditionFailed
+}
+
+func NewPreconditionFailedError(message string) error {
+	return &PreconditionFailedError{Message: message}
+}
+
+/* RateLimitedError */
+
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitedError is returned when a request is rejected by a rate limit. RetryAfter
+// is the suggested wait before retrying, in seconds, or 0 if unknown.
+type RateLimitedError struct {
+	Message    string
+	RetryAfter int
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited: %s", e.Message)
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return ErrRateLimited
+}
+
+func NewRateLimitedError(message string, retryAfter int) error {
+	return &RateLimitedError{Message: message, RetryAfter: retryAfter}
 }
 
+/* UnavailableError */
+
+var ErrUnavailable = errors.New("service unavailable")
+
+// UnavailableError is returned when a request can't be served for now, such as a write
+// while the API is in maintenance mode. RetryAfter is the suggested wait before
+// retrying, in seconds, or 0 if unknown.
+type UnavailableError struct {
+	Message    string
+	RetryAfter int
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("service unavailable: %s", e.Message)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return ErrUnavailable
+}
+
+func NewUnavailableError(message string, retryAfter int) error {
+	return &UnavailableError{Message: message, RetryAfter: retryAfter}
+}
+
+/* DependencyFailureError */
+
+var ErrDependencyFailure = errors.New("dependency failure")
+
+// DependencyFailureError is returned when a call to an external dependency, such as an
+// LLM or embedding service, fails.
+type DependencyFailureError struct {
+	Dependency string
+	Err        error
+}
+
+func (e *DependencyFailureError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.Dependency, e.Err)
+}
+
+func (e *DependencyFailureError) Unwrap() []error {
+	return []error{ErrDependencyFailure, e.Err}
+}
+
+func NewDependencyFailureError(dependency string, err error) error {
+	return &DependencyFailureError{Dependency: dependency, Err: err}
+}
+
+/* AdvisoryLockError */
+
 var ErrLockAcquisitionFailed = errors.New("failed to acquire advisory lock")
 
+// AdvisoryLockError is returned when a lock is not acquired before the lock timeout. It
+// matches ErrConflict, as the lock is held by a concurrent request.
 type AdvisoryLockError struct {
 	Err error
 }
@@ -58,8 +191,8 @@ func (e AdvisoryLockError) Error() string {
 	return ErrLockAcquisitionFailed.Error()
 }
 
-func (e AdvisoryLockError) Unwrap() error {
-	return ErrLockAcquisitionFailed
+func (e AdvisoryLockError) Unwrap() []error {
+	return []error{ErrLockAcquisitionFailed, ErrConflict}
 }
 
 func NewAdvisoryLockError(err error) error {
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// EvalSet is a named set of labeled search queries, used to measure retrieval quality
+// before rolling out changes to prompts, indexes, or search settings.
+type EvalSet struct {
+	Name        string     `json:"name"                  validate:"required,alphanum,min=3,max=40"`
+	Description string     `json:"description,omitempty" validate:"omitempty,max=1000"`
+	Cases       []EvalCase `json:"cases"                 validate:"required,min=1,max=1000,dive"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// EvalCase is a query and the results relevant to it. The query searches the memory of
+// SessionID or the documents of CollectionName.
+type EvalCase struct {
+	Query          string `json:"query"                     validate:"required"`
+	SessionID      string `json:"session_id,omitempty"      validate:"required_without=CollectionName,excluded_with=CollectionName"`
+	CollectionName string `json:"collection_name,omitempty" validate:"required_without=SessionID"`
+	// Relevant are the UUIDs of the relevant messages or summaries, or the UUIDs or
+	// document IDs of the relevant documents.
+	Relevant []string `json:"relevant" validate:"required,min=1"`
+}
+
+// EvalRunRequest configures a run of an EvalSet. Zero values use the search defaults.
+type EvalRunRequest struct {
+	// K is the number of results retrieved for each query. Defaults to 10.
+	K          int        `json:"k,omitempty"           validate:"omitempty,min=1,max=100"`
+	SearchType SearchType `json:"search_type,omitempty" validate:"omitempty,oneof=similarity mmr"`
+	MMRLambda  float32    `json:"mmr_lambda,omitempty"`
+	// Variant searches with the preset of a variant of the retrieval experiment, so that
+	// the variants can be compared. It overrides SearchType and MMRLambda.
+	Variant string `json:"variant,omitempty" validate:"omitempty,oneof=a b"`
+	// Exact searches without the approximate vector indexes, measuring the retrieval
+	// quality of the embeddings rather than of the indexes.
+	Exact bool `json:"exact,omitempty"`
+}
+
+// EvalReport holds the retrieval metrics of a run of an EvalSet. RecallAtK and MRR are
+// averaged over the cases.
+type EvalReport struct {
+	SetName   string           `json:"set_name"`
+	Variant   string           `json:"variant,omitempty"`
+	Exact     bool             `json:"exact,omitempty"`
+	K         int              `json:"k"`
+	RecallAtK float64          `json:"recall_at_k"`
+	MRR       float64          `json:"mrr"`
+	Failed    int              `json:"failed"`
+	StartedAt time.Time        `json:"started_at"`
+	Duration  float64          `json:"duration_seconds"`
+	Cases     []EvalCaseResult `json:"cases"`
+}
+
+// EvalCaseResult holds the retrieval metrics of a case. A case whose search failed has an
+// Error and scores 0.
+type EvalCaseResult struct {
+	Query          string   `json:"query"`
+	Retrieved      []string `json:"retrieved"`
+	RecallAtK      float64  `json:"recall_at_k"`
+	ReciprocalRank float64  `json:"reciprocal_rank"`
+	Error          string   `json:"error,omitempty"`
+}
+
+type EvalSetStore interface {
+	// Put creates or replaces an eval set.
+	Put(ctx context.Context, set *EvalSet) (*EvalSet, error)
+	Get(ctx context.Context, name string) (*EvalSet, error)
+	// List returns the eval sets, ordered by name.
+	List(ctx context.Context) ([]*EvalSet, error)
+	Delete(ctx context.Context, name string) error
+}
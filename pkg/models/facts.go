@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserFactsMetadataKey is the key under a user's system metadata at which the facts
+// synthesized about them are stored.
+const UserFactsMetadataKey = "facts"
+
+// FactResolution is how a conflict between a fact and a message was resolved.
+type FactResolution string
+
+const (
+	// FactRevised conflicts were resolved by revising the fact.
+	FactRevised FactResolution = "revised"
+	// FactRetracted conflicts were resolved by retracting the fact.
+	FactRetracted FactResolution = "retracted"
+	// FactKept conflicts were resolved in favor of the fact.
+	FactKept FactResolution = "kept"
+)
+
+// UserFact is an insight about a user, such as a preference or a deadline, synthesized by
+// the LLM from the user's important messages. MessageUUIDs are the messages it was drawn
+// from. Conflicts are the messages found to contradict it, oldest first.
+type UserFact struct {
+	Fact         string         `json:"fact"`
+	MessageUUIDs []uuid.UUID    `json:"message_uuids"`
+	Conflicts    []FactConflict `json:"conflicts,omitempty"`
+}
+
+// Disputed returns true if the fact has a conflict that hasn't been resolved, and so may
+// be stale.
+func (f *UserFact) Disputed() bool {
+	for _, c := range f.Conflicts {
+		if c.Resolution == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// FactConflict is a message contradicting a fact. Resolution is empty until the conflict
+// is resolved, and PreviousFact is the fact as it was before a revision.
+type FactConflict struct {
+	MessageUUID  uuid.UUID      `json:"message_uuid"`
+	SessionID    string         `json:"session_id"`
+	Explanation  string         `json:"explanation"`
+	DetectedAt   time.Time      `json:"detected_at"`
+	Resolution   FactResolution `json:"resolution,omitempty"`
+	PreviousFact string         `json:"previous_fact,omitempty"`
+}
+
+// UserFacts are the facts synthesized about a user. ReflectedAt is when the user's
+// messages were last reflected on; later messages are reviewed by the next reflection.
+// Retracted are the facts retracted to resolve a conflict, kept with their conflicts as a
+// record of the resolution.
+type UserFacts struct {
+	Facts       []UserFact `json:"facts"`
+	Retracted   []UserFact `json:"retracted,omitempty"`
+	ReflectedAt time.Time  `json:"reflected_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "encoding/json"
+
+// ImportanceMetadataKey is the key, under system, of a message's importance score, from
+// 0 to 1, as scored by the importance extractor.
+const ImportanceMetadataKey = "importance"
+
+// MessageImportance returns the importance score stored in the metadata of m. ok is false
+// if m hasn't been scored.
+func MessageImportance(m *Message) (importance float64, ok bool) {
+	system, _ := m.Metadata["system"].(map[string]interface{})
+	switch v := system[ImportanceMetadataKey].(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
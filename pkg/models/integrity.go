@@ -0,0 +1,41 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContentHash returns the hex-encoded SHA-256 hash of content. The hash is stored with
+// each message and document when its content is written, so that content modified since,
+// outside of Zep or by corruption, no longer matches its hash.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// IntegrityMismatch is a message or document whose content doesn't match its stored hash.
+type IntegrityMismatch struct {
+	// Kind is "message" or "document".
+	Kind           string    `json:"kind"`
+	SessionID      string    `json:"session_id,omitempty"`
+	CollectionName string    `json:"collection_name,omitempty"`
+	UUID           uuid.UUID `json:"uuid"`
+	StoredHash     string    `json:"stored_hash"`
+	ContentHash    string    `json:"content_hash"`
+}
+
+// IntegrityReport is the outcome of verifying the content hashes of the stored messages
+// and documents. Mismatches lists at most the first 100 of MismatchCount mismatches.
+type IntegrityReport struct {
+	Messages  int `json:"messages"`
+	Documents int `json:"documents"`
+	// Unhashed counts the messages and documents without a stored hash, which can't be
+	// verified.
+	Unhashed      int                 `json:"unhashed"`
+	MismatchCount int                 `json:"mismatch_count"`
+	Mismatches    []IntegrityMismatch `json:"mismatches,omitempty"`
+	CheckedAt     time.Time           `json:"checked_at"`
+}
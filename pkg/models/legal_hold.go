@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Subjects of legal holds.
+const (
+	LegalHoldSubjectUser    = "user"
+	LegalHoldSubjectSession = "session"
+)
+
+// Actions recorded by legal hold events.
+const (
+	LegalHoldPlaced = "placed"
+	LegalHoldLifted = "lifted"
+)
+
+// LegalHold prevents a user or session from being deleted, whether manually, by the
+// deletion of its user, or by session expiry, until the hold is lifted. A hold on a user
+// also holds the user's sessions.
+type LegalHold struct {
+	SubjectType string    `json:"subject_type"`
+	SubjectID   string    `json:"subject_id"`
+	Reason      string    `json:"reason"`
+	PlacedBy    string    `json:"placed_by"`
+	PlacedAt    time.Time `json:"placed_at"`
+}
+
+// LegalHoldEvent records a legal hold being placed or lifted.
+type LegalHoldEvent struct {
+	UUID        uuid.UUID `json:"uuid"`
+	CreatedAt   time.Time `json:"created_at"`
+	SubjectType string    `json:"subject_type"`
+	SubjectID   string    `json:"subject_id"`
+	// Action is "placed" or "lifted".
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+	Actor  string `json:"actor"`
+}
+
+type LegalHoldRequest struct {
+	Reason string `json:"reason" validate:"required,max=1000"`
+}
+
+// NewLegalHoldError returns the error returned when deleting a subject under legal hold.
+func NewLegalHoldError(subjectType, subjectID string) error {
+	return &ConflictError{
+		Message: fmt.Sprintf("%s %s is under legal hold", subjectType, subjectID),
+	}
+}
+
+// LegalHoldStore stores legal holds and the events placing and lifting them. The memory
+// and user stores sharing its database refuse to delete held users and sessions.
+type LegalHoldStore interface {
+	// Place places a hold, recording a "placed" event. Placing a hold on a subject already
+	// held replaces its reason.
+	Place(ctx context.Context, hold *LegalHold) (*LegalHold, error)
+	// Lift lifts the hold on a subject, recording a "lifted" event with the given actor and
+	// reason. Returns a NotFoundError if the subject isn't held.
+	Lift(ctx context.Context, subjectType, subjectID, actor, reason string) error
+	// Get returns the hold on a subject, or a NotFoundError if the subject isn't held.
+	Get(ctx context.Context, subjectType, subjectID string) (*LegalHold, error)
+	// List returns the holds in place, most recently placed first.
+	List(ctx context.Context) ([]*LegalHold, error)
+	// ListEvents returns the events of a subject, or of all subjects if subjectType is
+	// empty, oldest first.
+	ListEvents(ctx context.Context, subjectType, subjectID string) ([]*LegalHoldEvent, error)
+}
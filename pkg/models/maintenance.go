@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// MaintenanceMode switches the API to read-only, while migrations, reindexing, or restores
+// run, rather than taking the whole service down.
+type MaintenanceMode interface {
+	Status() MaintenanceStatus
+	// Enable rejects writes, asking clients to retry after retryAfter seconds.
+	Enable(reason string, retryAfter int)
+	Disable()
+}
+
+// MaintenanceStatus is a point-in-time snapshot of the maintenance mode.
+type MaintenanceStatus struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason,omitempty"`
+	// Since is when maintenance mode was enabled. It's unset while disabled.
+	Since *time.Time `json:"since,omitempty"`
+	// RetryAfter is the wait, in seconds, suggested to clients whose writes are rejected.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// MaintenanceRequest enables or disables maintenance mode.
+type MaintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+	// RetryAfter defaults to 60 seconds.
+	RetryAfter int `json:"retry_after" validate:"gte=0"`
+}
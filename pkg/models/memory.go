@@ -14,6 +14,9 @@ type Message struct {
 	Content    string                 `json:"content"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 	TokenCount int                    `json:"token_count"`
+	// ContentHash is the ContentHash of Content when it was stored. It's set by the store,
+	// and ignored when messages are created or updated.
+	ContentHash string `json:"content_hash,omitempty"`
 }
 
 type MessageListResponse struct {
@@ -37,8 +40,70 @@ type Summary struct {
 	TokenCount       int                    `json:"token_count"`
 }
 
+// CompactedMetadataKey is the key, under system, set to true on messages whose content
+// was pruned by compaction or truncation while their embeddings were retained. Such
+// messages have empty content and no tokens.
+const CompactedMetadataKey = "compacted"
+
+// MessageCompacted returns true if the content of m was pruned, keeping its embedding.
+func MessageCompacted(m *Message) bool {
+	system, _ := m.Metadata["system"].(map[string]interface{})
+	compacted, _ := system[CompactedMetadataKey].(bool)
+	return compacted
+}
+
+// CompactedMessageMetadata returns the metadata merged into messages whose content is
+// pruned while their embeddings are retained.
+func CompactedMessageMetadata() map[string]interface{} {
+	return map[string]interface{}{
+		"system": map[string]interface{}{CompactedMetadataKey: true},
+	}
+}
+
+// CompactionCandidates are the oldest messages in a session in excess of the session message limit.
+type CompactionCandidates struct {
+	// Messages are the messages to be compacted, oldest first.
+	Messages []Message
+	// Unsummarized is the subset of Messages more recent than the session's current SummaryPoint.
+	// These need to be summarized before they are compacted.
+	Unsummarized []Message
+}
+
+// TruncateStrategy selects the messages kept when a session is truncated.
+type TruncateStrategy string
+
+const (
+	// TruncateKeepLastN keeps the N most recent messages.
+	TruncateKeepLastN TruncateStrategy = "keep_last_n"
+	// TruncateKeepLastNTokens keeps the most recent messages totalling at most N tokens.
+	TruncateKeepLastNTokens TruncateStrategy = "keep_last_n_tokens"
+	// TruncateSummaryOnly keeps no messages other than pinned ones.
+	TruncateSummaryOnly TruncateStrategy = "summary_only"
+)
+
+// PinnedMetadataKey is the message metadata key that, when true, pins a message. Pinned
+// messages are never truncated and don't count towards a strategy's N.
+const PinnedMetadataKey = "pinned"
+
+type TruncateSessionRequest struct {
+	Strategy TruncateStrategy `json:"strategy" validate:"required,oneof=keep_last_n keep_last_n_tokens summary_only"`
+	// N is the number of messages or tokens to keep. It is ignored by summary_only.
+	N int `json:"n" validate:"min=0"`
+}
+
+type TruncateSessionResponse struct {
+	// TruncatedCount is the number of messages whose content was pruned.
+	TruncatedCount int `json:"truncated_count"`
+	// RetainedCount is the number of messages still holding content, including pinned messages.
+	RetainedCount int `json:"retained_count"`
+	// Summary is the session's summary once the truncated messages have been summarized.
+	Summary *Summary `json:"summary,omitempty"`
+}
+
 type Memory struct {
 	Messages []Message              `json:"messages"`
 	Summary  *Summary               `json:"summary,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// TaskState is the session's task state, if the task state extractor is enabled.
+	TaskState *TaskState `json:"task_state,omitempty"`
 }
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -14,6 +15,15 @@ type MemoryStore[T any] interface {
 	SummaryStorer
 	// PurgeDeleted hard deletes all deleted data in the MemoryStore.
 	PurgeDeleted(ctx context.Context) error
+	// DeduplicateMessageEmbeddings marks near-duplicate message embeddings, excluding them from search.
+	// Only sessions or users with messages embedded since the given time are deduplicated.
+	// Returns the number of embeddings marked as duplicates.
+	DeduplicateMessageEmbeddings(
+		ctx context.Context,
+		since time.Time,
+		threshold float32,
+		scope DeduplicationScope,
+	) (int, error)
 	// Close is called when the application is shutting down. This is a good place to clean up any resources used by
 	// the MemoryStore implementation.
 	Close() error
@@ -25,6 +35,13 @@ type SessionStorer interface {
 		ctx context.Context,
 		session *CreateSessionRequest,
 	) (*Session, error)
+	// CreateSessions creates a batch of Sessions in a single transaction. A session that can't be
+	// created, such as one with an existing sessionID or an unknown user, is reported in its
+	// result without failing the others.
+	CreateSessions(
+		ctx context.Context,
+		sessions []CreateSessionRequest,
+	) (*CreateSessionsResponse, error)
 	// GetSession retrieves a Session for a given sessionID.
 	GetSession(
 		ctx context.Context,
@@ -35,8 +52,15 @@ type SessionStorer interface {
 		ctx context.Context,
 		session *UpdateSessionRequest,
 	) (*Session, error)
+	// PatchSessionMetadata applies a JSON Patch or JSON Merge Patch to the metadata of a
+	// Session. Unlike UpdateSession, a patch may remove metadata keys.
+	PatchSessionMetadata(
+		ctx context.Context,
+		sessionID string,
+		patch MetadataPatch,
+	) (*Session, error)
 	// DeleteSession deletes all records for a given sessionID. This is a soft delete. Related Messages
-	// and MessageEmbeddings are also soft deleted.
+	// and MessageEmbeddings are also soft deleted. Sessions under legal hold aren't deleted.
 	DeleteSession(ctx context.Context, sessionID string) error
 	// ListSessions returns a list of all Sessions, paginated by cursor and limit.
 	ListSessions(
@@ -54,6 +78,25 @@ type SessionStorer interface {
 		orderedBy string,
 		asc bool,
 	) (*SessionListResponse, error)
+	// ListExpiringSessions returns undeleted sessions that expire before the given time
+	// and for which an expiry warning has not yet been sent. Sessions under legal hold,
+	// which won't be deleted on expiry, aren't listed.
+	ListExpiringSessions(
+		ctx context.Context,
+		before time.Time,
+	) ([]*Session, error)
+	// MarkSessionsExpiryWarned records that an expiry warning has been sent for the given sessionIDs.
+	MarkSessionsExpiryWarned(ctx context.Context, sessionIDs []string) error
+	// DeleteExpiredSessions soft deletes all sessions whose expiry has passed, along with their
+	// messages, message embeddings, and summaries. The deleted sessions are returned.
+	// Sessions under legal hold aren't deleted.
+	DeleteExpiredSessions(ctx context.Context) ([]*Session, error)
+	// UpdateSessionTitle sets the title of a session.
+	UpdateSessionTitle(ctx context.Context, sessionID string, title string) error
+	// UpdateSessionTaskState replaces the task state of a session.
+	UpdateSessionTaskState(ctx context.Context, sessionID string, state *TaskState) error
+	// UpdateSessionEpisodes replaces the episodes of a session.
+	UpdateSessionEpisodes(ctx context.Context, sessionID string, episodes []Episode) error
 }
 
 type MessageStorer interface {
@@ -77,6 +120,21 @@ type MessageStorer interface {
 		pageNumber int,
 		pageSize int,
 	) (*MessageListResponse, error)
+	// GetMatchingMessageList retrieves a page of the messages of a session matching the
+	// filter, oldest first. TotalCount is the number of matching messages.
+	GetMatchingMessageList(ctx context.Context,
+		sessionID string,
+		filter *MessageFilter,
+		pageNumber int,
+		pageSize int,
+	) (*MessageListResponse, error)
+	// DeleteMatchingMessages deletes the messages of a session matching the filter, and
+	// their embeddings, returning the number of messages deleted. Messages of a session
+	// under legal hold aren't deleted.
+	DeleteMatchingMessages(ctx context.Context,
+		sessionID string,
+		filter *MessageFilter,
+	) (int, error)
 	// CreateMessageEmbeddings stores a collection of TextData for a given sessionID.
 	CreateMessageEmbeddings(ctx context.Context,
 		sessionID string,
@@ -84,6 +142,24 @@ type MessageStorer interface {
 	// GetMessageEmbeddings retrieves a collection of TextData for a given sessionID.
 	GetMessageEmbeddings(ctx context.Context,
 		sessionID string) ([]TextData, error)
+	// GetCompactionCandidates returns the oldest uncompacted messages for a given sessionID in excess
+	// of maxMessages. No messages are returned if the session is within the limit.
+	GetCompactionCandidates(ctx context.Context,
+		sessionID string,
+		maxMessages int) (*CompactionCandidates, error)
+	// CompactMessages prunes the content of all messages up to and including throughUUID. If
+	// retainEmbeddings is false, the messages and their embeddings are deleted. Messages of
+	// a session under legal hold aren't pruned.
+	CompactMessages(ctx context.Context,
+		sessionID string,
+		throughUUID uuid.UUID,
+		retainEmbeddings bool) error
+	// PruneMessages prunes the content of the given messages, as CompactMessages does. The
+	// messages need not be contiguous.
+	PruneMessages(ctx context.Context,
+		sessionID string,
+		messageUUIDs []uuid.UUID,
+		retainEmbeddings bool) error
 }
 
 type MemoryStorer interface {
@@ -109,6 +185,16 @@ type MemoryStorer interface {
 		sessionID string,
 		query *MemorySearchPayload,
 		limit int) ([]MemorySearchResult, error)
+	// SearchUserMemory searches the messages of a user's sessions in two stages: the
+	// sessions most similar to the query are found first, then searched. Results have their
+	// SessionID set. A default number of sessions is searched if sessions is 0. Only
+	// similarity searches of messages are supported, and they can't be paged.
+	SearchUserMemory(
+		ctx context.Context,
+		userID string,
+		query *MemorySearchPayload,
+		sessions int,
+		limit int) ([]MemorySearchResult, error)
 }
 
 type SummaryStorer interface {
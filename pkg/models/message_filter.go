@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+	"regexp/syntax"
+)
+
+// DefaultMaxMessageRegexComplexity is the complexity of the most complex ContentRegex a
+// MessageFilter may have, if memory.message_regex.max_complexity isn't set.
+const DefaultMaxMessageRegexComplexity = 1000
+
+// MessageFilter selects the messages of a session to list or delete.
+type MessageFilter struct {
+	// ContentRegex is a regular expression the content of messages must match, such as
+	// sk-[A-Za-z0-9]{20,} to find leaked API keys. It's matched case-sensitively unless it
+	// starts with (?i). Only the syntax shared by Go and Postgres regular expressions is
+	// supported, so backreferences, and Postgres' \m, \M and \y, aren't.
+	ContentRegex string `json:"content_regex"`
+}
+
+// Validate returns a ValidationError if the filter has no ContentRegex, or if it isn't a
+// valid regular expression or is more complex than maxComplexity. An expression's
+// complexity is the number of instructions it compiles to, which grows with its length
+// and the counts of its repetitions, such as a{100}.
+func (f *MessageFilter) Validate(maxComplexity int) error {
+	if f.ContentRegex == "" {
+		return NewValidationError("content_regex is required")
+	}
+	re, err := syntax.Parse(f.ContentRegex, syntax.Perl)
+	if err != nil {
+		return NewValidationError(fmt.Sprintf("invalid content_regex: %v", err))
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return NewValidationError(fmt.Sprintf("invalid content_regex: %v", err))
+	}
+	if maxComplexity <= 0 {
+		maxComplexity = DefaultMaxMessageRegexComplexity
+	}
+	if len(prog.Inst) > maxComplexity {
+		return NewValidationError(fmt.Sprintf(
+			"content_regex is too complex: %d instructions, more than %d",
+			len(prog.Inst),
+			maxComplexity,
+		))
+	}
+	return nil
+}
+
+// DeleteMessagesResponse is the result of deleting the messages matching a MessageFilter.
+type DeleteMessagesResponse struct {
+	DeletedCount int `json:"deleted_count"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/getzep/zep/pkg/jsonpatch"
+)
+
+// MetadataPatch edits a metadata document, such as a jsonpatch.Patch or
+// jsonpatch.MergePatch. Apply returns the patched document and must not modify doc.
+type MetadataPatch interface {
+	Apply(doc interface{}) (interface{}, error)
+}
+
+// PatchMetadata applies patch to metadata. The patched metadata must be an object and,
+// unless isPrivileged, the top-level system key may not be changed. A failed JSON Patch
+// test operation is returned as a ConflictError, and other patch errors as a
+// ValidationError.
+func PatchMetadata(
+	metadata map[string]interface{},
+	patch MetadataPatch,
+	isPrivileged bool,
+) (map[string]interface{}, error) {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	doc, err := patch.Apply(metadata)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, NewConflictError(err.Error())
+		}
+		return nil, NewValidationError("invalid metadata patch: " + err.Error())
+	}
+	patched, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, NewValidationError("patched metadata must be an object")
+	}
+
+	if !isPrivileged {
+		before, err := json.Marshal(metadata["system"])
+		if err != nil {
+			return nil, err
+		}
+		after, err := json.Marshal(patched["system"])
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(before, after) {
+			return nil, NewValidationError("the system metadata key cannot be changed")
+		}
+	}
+	return patched, nil
+}
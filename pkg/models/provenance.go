@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of responses whose context provenance is recorded.
+const (
+	ProvenanceMemory         = "memory"
+	ProvenanceMemorySearch   = "memory_search"
+	ProvenanceDocumentSearch = "document_search"
+)
+
+// ContextProvenance records the messages, summaries, and documents returned in a response
+// assembling context for an LLM, so that a generation can be audited against the context
+// it was given. Only UUIDs are recorded, not content.
+type ContextProvenance struct {
+	ResponseID     uuid.UUID   `json:"response_id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	Kind           string      `json:"kind"`
+	SessionID      string      `json:"session_id,omitempty"`
+	CollectionName string      `json:"collection_name,omitempty"`
+	Messages       []uuid.UUID `json:"messages"`
+	Summaries      []uuid.UUID `json:"summaries"`
+	Documents      []uuid.UUID `json:"documents"`
+}
+
+// ProvenanceStore stores context provenance records. Records aren't deleted with their
+// session or collection.
+type ProvenanceStore interface {
+	Put(ctx context.Context, provenance *ContextProvenance) error
+	// Get returns a NotFoundError if no provenance was recorded for the response.
+	Get(ctx context.Context, responseID uuid.UUID) (*ContextProvenance, error)
+	// DeleteBefore deletes the records created before the given time, returning the
+	// number deleted.
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Services of provider overrides.
+const (
+	ProviderServiceOpenAI    = "openai"
+	ProviderServiceAnthropic = "anthropic"
+)
+
+// TenantMetadataKey is the task metadata key holding the tenant of the request that
+// published a task, so that its extractors use the tenant's providers.
+const TenantMetadataKey = "tenant"
+
+// ProviderOverride sets the LLM and embedding providers used for a tenant's requests, and
+// for the extractors processing its messages and documents, in place of the configured
+// ones. API keys are write-only: they're stored encrypted and never returned.
+type ProviderOverride struct {
+	Tenant string `json:"tenant"`
+	// LLM overrides the LLM. If nil, the configured LLM is used.
+	LLM *LLMProvider `json:"llm,omitempty"`
+	// Embeddings overrides the embedding model of embeddings served by the LLM service.
+	// If nil, the LLM override, or else the configured LLM, embeds texts.
+	Embeddings *EmbeddingProvider `json:"embeddings,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// LLMProvider is the LLM of a provider override.
+type LLMProvider struct {
+	Service string `json:"service"           validate:"required,oneof=openai anthropic"`
+	Model   string `json:"model"             validate:"required,max=100"`
+	APIKey  string `json:"api_key,omitempty" validate:"required"`
+	// Endpoint is an OpenAI-compatible API endpoint. Only used by the openai service.
+	Endpoint string `json:"endpoint,omitempty" validate:"omitempty,url"`
+}
+
+// EmbeddingProvider is the embedding model of a provider override. Its embeddings must
+// have the configured dimensions, as they're stored alongside those of other tenants.
+type EmbeddingProvider struct {
+	Service string `json:"service"           validate:"required,oneof=openai"`
+	Model   string `json:"model"             validate:"required,max=100"`
+	APIKey  string `json:"api_key,omitempty" validate:"required"`
+	// Endpoint is an OpenAI-compatible API endpoint.
+	Endpoint string `json:"endpoint,omitempty" validate:"omitempty,url"`
+}
+
+type ProviderOverrideRequest struct {
+	LLM        *LLMProvider       `json:"llm,omitempty"        validate:"required_without=Embeddings"`
+	Embeddings *EmbeddingProvider `json:"embeddings,omitempty" validate:"required_without=LLM"`
+}
+
+// Redacted returns a copy of the override without its API keys.
+func (o *ProviderOverride) Redacted() *ProviderOverride {
+	c := *o
+	if o.LLM != nil {
+		llm := *o.LLM
+		llm.APIKey = ""
+		c.LLM = &llm
+	}
+	if o.Embeddings != nil {
+		embeddings := *o.Embeddings
+		embeddings.APIKey = ""
+		c.Embeddings = &embeddings
+	}
+	return &c
+}
+
+// ProviderOverrideStore stores the provider overrides of tenants.
+type ProviderOverrideStore interface {
+	// Put creates a tenant's override, or replaces its providers.
+	Put(ctx context.Context, override *ProviderOverride) (*ProviderOverride, error)
+	Get(ctx context.Context, tenant string) (*ProviderOverride, error)
+	// List returns the overrides ordered by tenant.
+	List(ctx context.Context) ([]*ProviderOverride, error)
+	Delete(ctx context.Context, tenant string) error
+}
+
+// TenantProviders resolves the provider overrides of tenants to LLM clients.
+type TenantProviders interface {
+	// Clients returns the LLM and embedding clients of the tenant of ctx. Either is nil
+	// if the tenant doesn't override it.
+	Clients(ctx context.Context) (llm ZepLLM, embedder ZepLLM, err error)
+	// Invalidate drops the cached clients of a tenant, once its override changes.
+	Invalidate(tenant string)
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx naming the tenant it's on behalf of.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	if tenant == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of ctx, or "" if it has none.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// WithTenantMetadata returns the metadata of a task published on behalf of ctx, with its
+// tenant added if it has one.
+func WithTenantMetadata(ctx context.Context, metadata map[string]string) map[string]string {
+	if tenant := TenantFromContext(ctx); tenant != "" {
+		metadata[TenantMetadataKey] = tenant
+	}
+	return metadata
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of searches recorded in the query log.
+const (
+	SearchKindMemory   = "memory"
+	SearchKindDocument = "document"
+)
+
+// LoggedSearch is a memory or document search recorded in the query log, for finding the
+// queries that sessions and collections can't answer.
+type LoggedSearch struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Kind           string     `json:"kind"`
+	SessionID      string     `json:"session_id,omitempty"`
+	CollectionName string     `json:"collection_name,omitempty"`
+	Text           string     `json:"text"`
+	SearchType     SearchType `json:"search_type,omitempty"`
+	LatencyMS      int64      `json:"latency_ms"`
+	ResultCount    int        `json:"result_count"`
+	ZeroResults    bool       `json:"zero_results"`
+}
+
+// SearchAnalyticsFilter selects the logged searches aggregated. Zero values select all
+// searches.
+type SearchAnalyticsFilter struct {
+	Kind           string
+	CollectionName string
+	Since          time.Time
+	// Limit is the number of top queries returned.
+	Limit int
+}
+
+// SearchAnalytics aggregates logged searches. Counts are of the logged searches, so they're
+// a sample of all searches if query_log.sample_rate is below 1.
+type SearchAnalytics struct {
+	Searches       int     `json:"searches"`
+	ZeroResults    int     `json:"zero_results"`
+	ZeroResultRate float64 `json:"zero_result_rate"`
+	// AvgLatencyMS and the AvgResults of top queries are omitted if the analytics are
+	// noised.
+	AvgLatencyMS float64 `json:"avg_latency_ms,omitempty"`
+	// TopQueries are the most frequent query texts, compared case insensitively, and
+	// TopZeroResultQueries the most frequent of those returning no results.
+	TopQueries           []QueryCount `json:"top_queries"`
+	TopZeroResultQueries []QueryCount `json:"top_zero_result_queries"`
+	// Epsilon is the privacy budget of any one search, split among the counts it changes,
+	// if the analytics are noised. Rates are of the noised counts.
+	Epsilon float64 `json:"epsilon,omitempty"`
+}
+
+// QueryCount is the number of logged searches with a query text, lowercased.
+type QueryCount struct {
+	Text        string  `json:"text"`
+	Searches    int     `json:"searches"`
+	ZeroResults int     `json:"zero_results"`
+	AvgResults  float64 `json:"avg_results,omitempty"`
+}
+
+// QueryLogStore stores logged searches. Searches aren't deleted with their session or
+// collection.
+type QueryLogStore interface {
+	Put(ctx context.Context, search *LoggedSearch) error
+	// Analytics aggregates the logged searches selected by filter. Searches without text,
+	// which only filter by metadata, are counted, but aren't among the top queries.
+	Analytics(ctx context.Context, filter *SearchAnalyticsFilter) (*SearchAnalytics, error)
+	// DeleteBefore deletes the searches logged before the given time, returning the number
+	// deleted.
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
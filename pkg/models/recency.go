@@ -0,0 +1,50 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultRecencyHalfLife is the half-life, in hours, of the recency boost of a memory search
+// without a half_life.
+const DefaultRecencyHalfLife = 24.0
+
+// RecencyHalfLife returns the half-life, in hours, of the search's recency boost.
+func (p *MemorySearchPayload) RecencyHalfLife() float64 {
+	if p.HalfLife > 0 {
+		return p.HalfLife
+	}
+	return DefaultRecencyHalfLife
+}
+
+// ValidateRecency returns a ValidationError if the search's recency weight or half-life is
+// negative, or if recency weights a search without text, of summaries, or being paged.
+// Paging isn't supported as the boost decays between pages.
+func (p *MemorySearchPayload) ValidateRecency() error {
+	if p.RecencyWeight < 0 || p.HalfLife < 0 {
+		return NewValidationError("recency_weight and half_life must not be negative")
+	}
+	if p.RecencyWeight == 0 {
+		return nil
+	}
+	if p.Text == "" {
+		return NewValidationError("recency_weight requires text to score results by")
+	}
+	if p.SearchScope == SearchScopeSummary {
+		return NewValidationError("recency_weight can only weight message searches")
+	}
+	if p.Cursor != "" {
+		return NewValidationError("recency-weighted searches can't be paged")
+	}
+	return nil
+}
+
+// RecencyBoost returns the boost of a message created at createdAt in the search: the
+// recency weight for a new message, halving every half-life.
+func (p *MemorySearchPayload) RecencyBoost(createdAt, now time.Time) float64 {
+	if p.RecencyWeight == 0 {
+		return 0
+	}
+	age := max(now.Sub(createdAt).Hours(), 0)
+	return p.RecencyWeight * math.Pow(0.5, age/p.RecencyHalfLife())
+}
@@ -0,0 +1,31 @@
+package models
+
+import "encoding/json"
+
+// MessageRulesKey is the key, under system, of the outcome of the message rules a message
+// matched when it was written.
+const MessageRulesKey = "rules"
+
+// MessageRules is the outcome of the message rules a message matched. Extractors are the
+// topics of the routed extractors the message is routed to.
+type MessageRules struct {
+	Matched           []string `json:"matched"`
+	Extractors        []string `json:"extractors,omitempty"`
+	SkipSummarization bool     `json:"skip_summarization,omitempty"`
+}
+
+// MessageRulesOf returns the outcome of the message rules stored in the metadata of m. It
+// returns an empty MessageRules if none is stored or it can't be read.
+func MessageRulesOf(m *Message) MessageRules {
+	var rules MessageRules
+	system, ok := m.Metadata["system"].(map[string]interface{})
+	if !ok || system[MessageRulesKey] == nil {
+		return rules
+	}
+	b, err := json.Marshal(system[MessageRulesKey])
+	if err != nil {
+		return rules
+	}
+	_ = json.Unmarshal(b, &rules)
+	return rules
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// JobScheduler runs background jobs, such as purges and index maintenance, on their
+// configured schedules.
+type JobScheduler interface {
+	JobStatus() []ScheduledJobStatus
+}
+
+// ScheduledJobStatus is a point-in-time snapshot of a scheduled job and its last run.
+type ScheduledJobStatus struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule,omitempty"`
+	Enabled  bool   `json:"enabled"`
+	Running  bool   `json:"running"`
+	// NextRunAt is unset for a disabled job.
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	// LastRunAt is when the last run started. It's unset if the job hasn't run.
+	LastRunAt    *time.Time `json:"last_run_at,omitempty"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	// LastError is the error of the last run, or empty if it succeeded.
+	LastError string `json:"last_error,omitempty"`
+	Runs      int64  `json:"runs"`
+	Failures  int64  `json:"failures"`
+}
@@ -1,10 +1,26 @@
 package models
 
+import (
+	"bytes"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
 type SearchType string
 
 const (
 	SearchTypeSimilarity SearchType = "similarity"
 	SearchTypeMMR        SearchType = "mmr"
+	// SearchTypeHybrid fuses the ranking by embedding similarity with a full-text ranking.
+	// Only message searches are hybrid.
+	SearchTypeHybrid SearchType = "hybrid"
+	// SearchTypeLiteral matches the messages or summaries containing the text,
+	// case-insensitively, without embedding it, for exact lookups such as of an order
+	// number. Only memory searches are literal.
+	SearchTypeLiteral SearchType = "literal"
 )
 
 type SearchScope string
@@ -14,20 +30,175 @@ const (
 	SearchScopeSummary  SearchScope = "summary"
 )
 
+// DeduplicationScope determines which message embeddings are compared when
+// finding near-duplicates.
+type DeduplicationScope string
+
+const (
+	DeduplicationScopeSession DeduplicationScope = "session"
+	DeduplicationScopeUser    DeduplicationScope = "user"
+)
+
 type MemorySearchResult struct {
+	// SessionID is only set on the results of a user's memory search.
+	SessionID string                 `json:"session_id,omitempty" bun:"session_id"`
 	Message   *Message               `json:"message"`
 	Summary   *Summary               `json:"summary"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	Dist      float64                `json:"dist"`
 	Embedding []float32              `json:"embedding"`
+	// Explanation is only set for searches with explain set.
+	Explanation *SearchExplanation `json:"explanation,omitempty"`
+	// Window holds the messages the result's message was embedded with, oldest first, such
+	// as its turn pair. See the window and turn_pairs message embedding options.
+	Window []Message `json:"window,omitempty" bun:"-"`
+	// WindowUUIDs are scanned by stores to load Window.
+	WindowUUIDs []uuid.UUID `json:"-" bun:"window_uuids"`
+	// Cursor is passed as the cursor of a search to page through the results following
+	// this one. It's only set on the results of similarity searches with text.
+	Cursor string `json:"cursor,omitempty"`
+	// RankScore is the score results are ordered by, scanned by stores to encode Cursor.
+	RankScore float64 `json:"-" bun:"rank_score"`
+	// Highlights are the spans of the content of the message or summary matching the terms
+	// of the search's text. They're only set for searches with highlight set.
+	Highlights []TextSpan `json:"highlights,omitempty" bun:"-"`
+}
+
+// TextSpan is a span of text, from the character at offset Start up to, but excluding, the
+// character at End. Offsets are in characters, not bytes.
+type TextSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SearchExplanation explains why a memory search result matched and how it was ranked,
+// for tuning retrieval.
+type SearchExplanation struct {
+	// VectorScore is the similarity of the result's embedding to the query's. Stores that
+	// match by term rather than embedding score the share of query terms matched.
+	VectorScore float64 `json:"vector_score"`
+	// MetadataHits are the JSONPath expressions of the metadata filter the result matches,
+	// and the start_date and end_date filters, if set.
+	MetadataHits []string `json:"metadata_hits,omitempty"`
+	// ImportanceBoost is added to VectorScore for a message's importance. See
+	// memory.importance.search_weight.
+	ImportanceBoost float64 `json:"importance_boost"`
+	// RecencyBoost is added to VectorScore for a message's recency, in searches with a
+	// recency_weight.
+	RecencyBoost float64 `json:"recency_boost,omitempty"`
+	// Score is the combined score results are ranked by, before any re-ranking, and Rank
+	// is the result's position in that ranking, from 1. Hybrid searches rank results by
+	// their fused score.
+	Score float64 `json:"score"`
+	Rank  int     `json:"rank"`
+	// MMRScore is the result's marginal relevance when the results are re-ranked by MMR.
+	MMRScore *float64 `json:"mmr_score,omitempty"`
 }
 
 type MemorySearchPayload struct {
 	Text        string                 `json:"text"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 	SearchScope SearchScope            `json:"search_scope,omitempty"`
-	SearchType  SearchType             `json:"search_type,omitempty"`
-	MMRLambda   float32                `json:"mmr_lambda,omitempty"`
+	// SearchType "mmr" re-ranks results by Maximal Marginal Relevance, diversifying them.
+	// SearchType "hybrid" fuses the similarity ranking of messages with their full-text
+	// ranking by Reciprocal Rank Fusion. SearchType "literal" returns the messages or
+	// summaries containing the text, most recent first.
+	SearchType SearchType `json:"search_type,omitempty"`
+	// MMRLambda trades off relevance, at 1, against diversity, at 0, in MMR searches. If
+	// unset, memory.mmr_lambda is used.
+	MMRLambda float32 `json:"mmr_lambda,omitempty"`
+	// Episode restricts a message search to the messages of the episode with this ID. See
+	// the episode extractor.
+	Episode int `json:"episode,omitempty"`
+	// Roles restricts a message search to the messages of these roles, such as "user".
+	// Roles are matched case-insensitively, and registered roles match their aliases.
+	Roles []string `json:"roles,omitempty"`
+	// Explain returns an explanation of each result's score with the result.
+	Explain bool `json:"explain,omitempty"`
+	// Fallback retries a search returning no results without its date filters, then
+	// without any filters. The X-Zep-Search-Fallback response header names the fallback
+	// the results were found with.
+	Fallback bool `json:"fallback,omitempty"`
+	// MinScore excludes results less similar to the text than this score, before the
+	// limit is applied. Importance boosts don't count towards the score.
+	MinScore float64 `json:"min_score,omitempty"`
+	// Cursor returns the results following the result with this cursor. Only similarity
+	// searches with text can be paged.
+	Cursor string `json:"cursor,omitempty"`
+	// RecencyWeight boosts the score of messages by their recency: a new message is boosted
+	// by the weight, decaying exponentially with the message's age. Like importance boosts,
+	// recency boosts don't count towards MinScore.
+	RecencyWeight float64 `json:"recency_weight,omitempty"`
+	// HalfLife is the age, in hours, at which a message's recency boost is halved. It's 24
+	// hours if unset.
+	HalfLife float64 `json:"half_life,omitempty"`
+	// Highlight returns the spans of each result's content matching the terms of the text,
+	// such as those matched by the full-text ranking of a hybrid search.
+	Highlight bool `json:"highlight,omitempty"`
+	// MaxTokens returns as many of the top-ranked results as fit within this many tokens,
+	// counted by the stored token_count of each result's message or summary, rather than a
+	// fixed number of results. Results are searched up to the maximum memory search limit,
+	// or the limit query parameter if set.
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Exact compares the text's embedding with every message or summary embedding of the
+	// session, rather than searching the approximate vector index, for when recall matters
+	// more than latency, as in offline evaluations. User memory searches are always exact.
+	Exact bool `json:"exact,omitempty"`
+}
+
+// ValidateSearchType returns a ValidationError if the search type is unknown, if an MMR
+// search has no text to embed or a lambda outside 0 to 1, if a hybrid search has no
+// text or searches summaries, or if a literal search has no text or a min_score.
+func (p *MemorySearchPayload) ValidateSearchType() error {
+	switch p.SearchType {
+	case "", SearchTypeSimilarity:
+		return nil
+	case SearchTypeLiteral:
+		if p.Text == "" {
+			return NewValidationError("literal searches require text")
+		}
+		if p.MinScore != 0 {
+			return NewValidationError("literal search results aren't scored")
+		}
+		return nil
+	case SearchTypeHybrid:
+		if p.Text == "" {
+			return NewValidationError("hybrid searches require text")
+		}
+		if p.SearchScope == SearchScopeSummary {
+			return NewValidationError("hybrid searches can only search messages")
+		}
+		return nil
+	case SearchTypeMMR:
+	default:
+		return NewValidationError("invalid search type: " + string(p.SearchType))
+	}
+	if p.Text == "" {
+		return NewValidationError("mmr searches require text")
+	}
+	if p.MMRLambda < 0 || p.MMRLambda > 1 {
+		return NewValidationError("mmr_lambda must be between 0 and 1")
+	}
+	return nil
+}
+
+// ValidateUserSearch returns a ValidationError if the payload can't search a user's
+// memory. Sessions are found by the similarity of their messages to the text, so a user
+// search must be a similarity search of messages with text, and can't be paged.
+func (p *MemorySearchPayload) ValidateUserSearch() error {
+	if p.Text == "" {
+		return NewValidationError("user searches require text")
+	}
+	if p.SearchScope != "" && p.SearchScope != SearchScopeMessages {
+		return NewValidationError("user searches can only search messages")
+	}
+	if p.SearchType != "" && p.SearchType != SearchTypeSimilarity {
+		return NewValidationError("user searches can only be similarity searches")
+	}
+	if p.Cursor != "" {
+		return NewValidationError("user searches can't be paged")
+	}
+	return nil
 }
 
 type DocumentSearchPayload struct {
@@ -37,17 +208,137 @@ type DocumentSearchPayload struct {
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
 	SearchType     SearchType             `json:"search_type"`
 	MMRLambda      float32                `json:"mmr_lambda,omitempty"`
+	// ReturnSummaries returns the summary of each document as its content, where one
+	// has been generated, to reduce the tokens used by the results.
+	ReturnSummaries bool `json:"return_summaries,omitempty"`
+	// Fallback retries a search returning no results without its metadata filter. See
+	// MemorySearchPayload.Fallback.
+	Fallback bool `json:"fallback,omitempty"`
+	// MinScore excludes documents scoring less than this, before the limit is applied.
+	MinScore float64 `json:"min_score,omitempty"`
+	// Cursor returns the page of results following the page with this next_cursor. Only
+	// similarity searches with text or an embedding can be paged.
+	Cursor string `json:"cursor,omitempty"`
+	// Highlight returns the spans of each result's content matching the terms of the text.
+	Highlight bool `json:"highlight,omitempty"`
+	// Exact compares the query's embedding with every document's, rather than searching
+	// the collection's approximate vector index. See MemorySearchPayload.Exact.
+	Exact bool `json:"exact,omitempty"`
 }
 
 type DocumentSearchResult struct {
 	*DocumentResponse
 	Score float64 `json:"score"`
+	// Highlights are the spans of the returned content matching the terms of the search's
+	// text. They're only set for searches with highlight set.
+	Highlights []TextSpan `json:"highlights,omitempty"`
 }
 
 type DocumentSearchResultPage struct {
 	Results     []DocumentSearchResult `json:"results"`
 	QueryVector []float32              `json:"query_vector"`
 	ResultCount int                    `json:"result_count"`
-	TotalPages  int                    `json:"total_pages"`
-	CurrentPage int                    `json:"current_page"`
+	// NextCursor is the cursor of the next page of results, if the page is full.
+	NextCursor  string `json:"next_cursor,omitempty"`
+	TotalPages  int    `json:"total_pages"`
+	CurrentPage int    `json:"current_page"`
+	// Debug is how the search was run, if the debug query parameter was set.
+	Debug *SearchDebug `json:"debug,omitempty"`
+}
+
+// MaxSearchCollections is the number of collections a multi-collection search may search.
+const MaxSearchCollections = 20
+
+// MultiCollectionSearchPayload searches several document collections with the same
+// query. Results can't be paged or fall back, as each collection is searched once.
+type MultiCollectionSearchPayload struct {
+	CollectionNames []string `json:"collection_names"`
+	DocumentSearchPayload
+}
+
+// Validate returns a ValidationError if the payload can't search several collections.
+func (p *MultiCollectionSearchPayload) Validate() error {
+	if len(p.CollectionNames) == 0 {
+		return NewValidationError("collection_names is required")
+	}
+	if len(p.CollectionNames) > MaxSearchCollections {
+		return NewValidationError(
+			"at most " + strconv.Itoa(MaxSearchCollections) + " collections can be searched",
+		)
+	}
+	seen := make(map[string]bool, len(p.CollectionNames))
+	for _, name := range p.CollectionNames {
+		if name == "" {
+			return NewValidationError("collection names can't be empty")
+		}
+		if seen[strings.ToLower(name)] {
+			return NewValidationError("collection " + name + " is listed more than once")
+		}
+		seen[strings.ToLower(name)] = true
+	}
+	if p.CollectionName != "" {
+		return NewValidationError("use collection_names to name the collections searched")
+	}
+	if p.Cursor != "" {
+		return NewValidationError("multi-collection searches can't be paged")
+	}
+	if p.Fallback {
+		return NewValidationError("multi-collection searches can't fall back")
+	}
+	return nil
+}
+
+// MultiCollectionSearchResult is a result of a multi-collection search. Its score is
+// normalized to between 0 and 1 among the results of its collection, so that results
+// are comparable across collections of different models or search types.
+type MultiCollectionSearchResult struct {
+	DocumentSearchResult
+	CollectionName string `json:"collection_name"`
+	// RawScore is the result's score in the search of its collection.
+	RawScore float64 `json:"raw_score"`
+}
+
+type MultiCollectionSearchResultPage struct {
+	Results     []MultiCollectionSearchResult `json:"results"`
+	ResultCount int                           `json:"result_count"`
+}
+
+// SearchCursor is the position of a result in the results of a similarity search, which
+// are ordered by descending score, then UUID. Results after the cursor are found by keyset
+// rather than offset, so pages are stable while results are added.
+type SearchCursor struct {
+	Score float64
+	UUID  uuid.UUID
+}
+
+// Encode returns the cursor as an opaque string.
+func (c SearchCursor) Encode() string {
+	s := strconv.FormatFloat(c.Score, 'g', -1, 64) + ":" + c.UUID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// After reports whether a result with the given score and UUID comes after the cursor.
+func (c SearchCursor) After(score float64, id uuid.UUID) bool {
+	return score < c.Score || (score == c.Score && bytes.Compare(id[:], c.UUID[:]) < 0)
+}
+
+// DecodeSearchCursor decodes an encoded SearchCursor. It returns a ValidationError if the
+// cursor is invalid.
+func DecodeSearchCursor(cursor string) (*SearchCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, NewValidationError("invalid cursor")
+	}
+	score, id, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return nil, NewValidationError("invalid cursor")
+	}
+	c := &SearchCursor{}
+	if c.Score, err = strconv.ParseFloat(score, 64); err != nil {
+		return nil, NewValidationError("invalid cursor")
+	}
+	if c.UUID, err = uuid.Parse(id); err != nil {
+		return nil, NewValidationError("invalid cursor")
+	}
+	return c, nil
 }
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SearchDebug collects how a search was run, for searches made with the debug query
+// parameter, to tune limits and index settings. Stores record into the SearchDebug of a
+// search's context, if any. Its methods do nothing on a nil SearchDebug.
+type SearchDebug struct {
+	mu sync.Mutex
+	// Queries are the SQL statements run, in order. Vector literals are abbreviated.
+	Queries []string `json:"queries,omitempty"`
+	// Settings are the index settings the queries were run with, such as ivfflat.probes.
+	Settings       map[string]string `json:"settings,omitempty"`
+	EmbeddingModel *EmbeddingModel   `json:"embedding_model,omitempty"`
+	// Distances are the distances of the results to the query, as returned by the pgvector
+	// operator of the embeddings' distance function, in the order of the results: the
+	// cosine distance, the negative inner product, or the Euclidean distance.
+	Distances []float64     `json:"distances"`
+	Timings   SearchTimings `json:"timings"`
+}
+
+// SearchTimings are the milliseconds spent in each stage of a search. Stages run more
+// than once, such as by hybrid searches or fallbacks, are summed.
+type SearchTimings struct {
+	EmbeddingMS float64 `json:"embedding_ms"`
+	DatabaseMS  float64 `json:"database_ms"`
+	RerankMS    float64 `json:"rerank_ms"`
+}
+
+// MemorySearchDebugResponse is the response of a memory search with debug set.
+type MemorySearchDebugResponse struct {
+	Results []MemorySearchResult `json:"results"`
+	Debug   *SearchDebug         `json:"debug"`
+}
+
+type searchDebugKey struct{}
+
+// vectorLiteral matches the vector arguments inlined into queries.
+var vectorLiteral = regexp.MustCompile(`'\[[-+0-9.eE, ]+\]'`)
+
+// WithSearchDebug returns a copy of ctx carrying debug.
+func WithSearchDebug(ctx context.Context, debug *SearchDebug) context.Context {
+	return context.WithValue(ctx, searchDebugKey{}, debug)
+}
+
+// SearchDebugFromContext returns the SearchDebug of ctx, or nil if the search isn't
+// debugged.
+func SearchDebugFromContext(ctx context.Context) *SearchDebug {
+	debug, _ := ctx.Value(searchDebugKey{}).(*SearchDebug)
+	return debug
+}
+
+// AddQuery records a query run in elapsed time.
+func (d *SearchDebug) AddQuery(query string, elapsed time.Duration) {
+	if d == nil {
+		return
+	}
+	query = vectorLiteral.ReplaceAllStringFunc(query, func(v string) string {
+		return fmt.Sprintf("'[%d dims]'", strings.Count(v, ",")+1)
+	})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Queries = append(d.Queries, query)
+	d.Timings.DatabaseMS += milliseconds(elapsed)
+}
+
+// SetSetting records an index setting the queries were run with.
+func (d *SearchDebug) SetSetting(name, value string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.Settings == nil {
+		d.Settings = make(map[string]string)
+	}
+	d.Settings[name] = value
+}
+
+// AddEmbedding records the embedding of the query by model in elapsed time.
+func (d *SearchDebug) AddEmbedding(model *EmbeddingModel, elapsed time.Duration) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.EmbeddingModel = model
+	d.Timings.EmbeddingMS += milliseconds(elapsed)
+}
+
+// AddRerank records a re-ranking of the results, such as by MMR, in elapsed time.
+func (d *SearchDebug) AddRerank(elapsed time.Duration) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Timings.RerankMS += milliseconds(elapsed)
+}
+
+// SetDistances records the distances of the results to the query, replacing those of any
+// earlier search, such as one that fell back.
+func (d *SearchDebug) SetDistances(distances []float64) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Distances = distances
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
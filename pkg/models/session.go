@@ -17,6 +17,27 @@ type Session struct {
 	Metadata  map[string]interface{} `json:"metadata"`
 	// Must be a pointer to allow for null values
 	UserID *string `json:"user_id"`
+	// ExpiresAt is the time at which the session is deleted. Nil if the session doesn't expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Title is generated by the title extractor, if enabled.
+	Title string `json:"title,omitempty"`
+	// TaskState is maintained by the task state extractor, if enabled.
+	TaskState *TaskState `json:"task_state,omitempty"`
+	// Episodes are segmented by the episode extractor, if enabled. They're listed by the
+	// session's episodes endpoint rather than returned with the session.
+	Episodes []Episode `json:"-"`
+}
+
+// TaskState tracks the progress of a task-oriented conversation. Unlike the summary, which
+// narrates the conversation, it records where the task stands.
+type TaskState struct {
+	// Stage is a short description of the conversation's current stage, such as
+	// "gathering requirements" or "awaiting confirmation".
+	Stage     string    `json:"stage"`
+	Goal      string    `json:"goal"`
+	StepsDone []string  `json:"steps_done"`
+	Blockers  []string  `json:"blockers"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type SessionListResponse struct {
@@ -30,17 +51,43 @@ type CreateSessionRequest struct {
 	// Must be a pointer to allow for null values
 	UserID   *string                `json:"user_id"`
 	Metadata map[string]interface{} `json:"metadata"`
+	// ExpiresAt is optional. If set, the session is deleted once this time has passed.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateSessionsResult reports the outcome of creating a single session of a batch. Index
+// is the position of the session in the request.
+type CreateSessionsResult struct {
+	Index     int      `json:"index"`
+	SessionID string   `json:"session_id"`
+	Session   *Session `json:"session,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type CreateSessionsResponse struct {
+	Results   []CreateSessionsResult `json:"results"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
 }
 
 type UpdateSessionRequest struct {
 	SessionID string                 `json:"session_id"`
 	Metadata  map[string]interface{} `json:"metadata"`
+	// ExpiresAt is optional. If set, the session's expiry is moved to this time.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type SessionManager interface {
 	Create(ctx context.Context, session *CreateSessionRequest) (*Session, error)
+	CreateBatch(ctx context.Context, sessions []CreateSessionRequest) (*CreateSessionsResponse, error)
 	Get(ctx context.Context, sessionID string) (*Session, error)
 	Update(ctx context.Context, session *UpdateSessionRequest, isPrivileged bool) (*Session, error)
+	PatchMetadata(
+		ctx context.Context,
+		sessionID string,
+		patch MetadataPatch,
+		isPrivileged bool,
+	) (*Session, error)
 	Delete(ctx context.Context, sessionID string) error
 	ListAll(ctx context.Context, cursor int64, limit int) ([]*Session, error)
 }
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemorySnapshot is the memory returned for a turn of a session, stored so that an
+// offline evaluation can replay the context given to the LLM at each turn.
+type MemorySnapshot struct {
+	UUID      uuid.UUID `json:"uuid"`
+	CreatedAt time.Time `json:"created_at"`
+	SessionID string    `json:"session_id"`
+	// TurnUUID identifies the turn. By default, it's the UUID of the last message in
+	// the memory.
+	TurnUUID uuid.UUID `json:"turn_uuid"`
+	Memory   *Memory   `json:"memory"`
+}
+
+type MemorySnapshotStore interface {
+	// Put stores a snapshot of a session's memory, replacing any snapshot of the same
+	// turn. Returns a NotFoundError if the session doesn't exist.
+	Put(ctx context.Context, snapshot *MemorySnapshot) (*MemorySnapshot, error)
+	Get(ctx context.Context, sessionID string, turnUUID uuid.UUID) (*MemorySnapshot, error)
+	// List returns a session's snapshots, oldest first. If limit is 0, all are returned.
+	List(ctx context.Context, sessionID string, limit int) ([]*MemorySnapshot, error)
+}
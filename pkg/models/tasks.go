@@ -5,6 +5,8 @@ import (
 
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/google/uuid"
+
+	"github.com/getzep/zep/config"
 )
 
 type TaskTopic string
@@ -18,8 +20,33 @@ const (
 	DocumentEmbedderTopic       TaskTopic = "document_embedder"
 	MessageSummaryEmbedderTopic TaskTopic = "message_summary_embedder"
 	MessageSummaryNERTopic      TaskTopic = "message_summary_ner"
+	MessageCompactorTopic       TaskTopic = "message_compactor"
+	MessageTitleTopic           TaskTopic = "message_title"
+	MessageFollowUpTopic        TaskTopic = "message_follow_up"
+	MessageTaskStateTopic       TaskTopic = "message_task_state"
+	MessageEnrichmentTopic      TaskTopic = "message_enrichment"
+	MessageEpisodeTopic         TaskTopic = "message_episode"
+	MessageImportanceTopic      TaskTopic = "message_importance"
+	MessageContradictionTopic   TaskTopic = "message_contradiction"
+	DocumentSummarizerTopic     TaskTopic = "document_summarizer"
 )
 
+// MessageTopics are the topics new messages are published to.
+var MessageTopics = []TaskTopic{
+	MessageSummarizerTopic,
+	MessageEmbedderTopic,
+	MessageNerTopic,
+	MessageIntentTopic,
+	MessageTitleTopic,
+	MessageFollowUpTopic,
+	MessageTaskStateTopic,
+	MessageEnrichmentTopic,
+	MessageEpisodeTopic,
+	MessageImportanceTopic,
+	MessageContradictionTopic,
+	MessageTokenCountTopic,
+}
+
 type Task interface {
 	Execute(ctx context.Context, event *message.Message) error
 	HandleError(err error)
@@ -27,12 +54,32 @@ type Task interface {
 
 type TaskRouter interface {
 	Run(ctx context.Context) error
-	AddTask(ctx context.Context, name string, taskType TaskTopic, task Task)
+	AddTask(
+		ctx context.Context,
+		name string,
+		taskType TaskTopic,
+		task Task,
+		workers config.WorkerPoolConfig,
+	)
+	WorkerStats() []TaskWorkerStats
 	RunHandlers(ctx context.Context) error
 	IsRunning() bool
 	Close() error
 }
 
+// TaskWorkerStats is a point-in-time snapshot of a task's worker pool.
+type TaskWorkerStats struct {
+	Task        string `json:"task"`
+	Concurrency int    `json:"concurrency"`
+	BatchSize   int    `json:"batch_size"`
+	QueueLength int    `json:"queue_length"`
+	RateLimit   int    `json:"rate_limit"`
+	Queued      int    `json:"queued"`
+	InFlight    int64  `json:"in_flight"`
+	Processed   int64  `json:"processed"`
+	Failed      int64  `json:"failed"`
+}
+
 type TaskPublisher interface {
 	Publish(taskType TaskTopic, metadata map[string]string, payload any) error
 	PublishMessage(metadata map[string]string, payload []MessageTask) error
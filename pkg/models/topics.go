@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserTopicsMetadataKey is the key under a user's system metadata at which their topics
+// are stored.
+const UserTopicsMetadataKey = "topics"
+
+// UserTopic is a cluster of related messages from a user's sessions, labeled by the LLM.
+type UserTopic struct {
+	Label        string      `json:"label"`
+	MessageCount int         `json:"message_count"`
+	MessageUUIDs []uuid.UUID `json:"message_uuids"`
+}
+
+// UserTopics are the topics of a user's messages, largest first. MessageCount is the
+// number of messages across the user's sessions when the topics were modeled.
+type UserTopics struct {
+	Topics       []UserTopic `json:"topics"`
+	MessageCount int         `json:"message_count"`
+	ModeledAt    time.Time   `json:"modeled_at"`
+}
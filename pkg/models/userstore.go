@@ -47,7 +47,25 @@ type UserStore interface {
 	Create(ctx context.Context, user *CreateUserRequest) (*User, error)
 	Get(ctx context.Context, userID string) (*User, error)
 	Update(ctx context.Context, user *UpdateUserRequest, isPrivileged bool) (*User, error)
+	PatchMetadata(
+		ctx context.Context,
+		userID string,
+		patch MetadataPatch,
+		isPrivileged bool,
+	) (*User, error)
+	// Delete soft deletes a user and the user's sessions, unless the user or any of the
+	// sessions is under legal hold.
 	Delete(ctx context.Context, userID string) error
+	// Anonymize re-keys an undeleted user, and the user's sessions, to anonymousID, clears
+	// the user's email and name, and replaces the user's metadata with metadata. Returns a
+	// ConflictError if the user or any of the user's sessions is under legal hold, or if
+	// anonymousID is taken.
+	Anonymize(
+		ctx context.Context,
+		userID string,
+		anonymousID string,
+		metadata map[string]interface{},
+	) (*User, error)
 	GetSessions(ctx context.Context, userID string) ([]*Session, error)
 	ListAll(ctx context.Context, cursor int64, limit int) ([]*User, error)
 	ListAllOrdered(ctx context.Context,
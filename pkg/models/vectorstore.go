@@ -0,0 +1,45 @@
+package models
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// VectorStore stores the embeddings of a document collection's documents, keyed by the
+// documents' UUIDs. The documents themselves are stored by the DocumentStore.
+type VectorStore interface {
+	// Put creates or replaces the embeddings of records.
+	Put(ctx context.Context, collection *DocumentCollection, records []VectorRecord) error
+	// Delete deletes the embeddings of the documents of uuids, or every embedding of the
+	// collection if uuids is nil. Missing embeddings are ignored.
+	Delete(ctx context.Context, collection *DocumentCollection, uuids []uuid.UUID) error
+	// Search returns up to query.Limit documents most similar to query.Embedding, by
+	// descending score. Scores are of the collection's distance function, as in
+	// document search results.
+	Search(
+		ctx context.Context,
+		collection *DocumentCollection,
+		query *VectorQuery,
+	) ([]VectorResult, error)
+}
+
+type VectorRecord struct {
+	UUID      uuid.UUID
+	Embedding []float32
+}
+
+type VectorQuery struct {
+	Embedding []float32
+	Limit     int
+	// Exact searches every embedding rather than an approximate index.
+	Exact bool
+	// WithEmbeddings returns the embeddings of the results, as used to rerank them.
+	WithEmbeddings bool
+}
+
+type VectorResult struct {
+	UUID      uuid.UUID
+	Score     float64
+	Embedding []float32
+}
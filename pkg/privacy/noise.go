@@ -0,0 +1,157 @@
+// Package privacy adds noise to aggregate analytics in the manner of differential privacy,
+// so that they show usage patterns without exposing the behavior of a few users.
+package privacy
+
+import (
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// DefaultEpsilon is the privacy budget of noised analytics if none is configured.
+const DefaultEpsilon = 1.0
+
+// countsPerSearch is the number of noised counts a single search changes, by at most 1
+// each: the searches and searches returning no results, and the same two counts of its
+// query among both the top queries and the top queries returning no results. The privacy
+// budget is split evenly among them.
+const countsPerSearch = 6
+
+// Laplace returns a sample of the Laplace distribution centered on 0 with the given scale.
+func Laplace(rng *rand.Rand, scale float64) float64 {
+	u := rng.Float64()
+	for u == 0 {
+		u = rng.Float64()
+	}
+	u -= 0.5
+	if u < 0 {
+		return scale * math.Log(1+2*u)
+	}
+	return -scale * math.Log(1-2*u)
+}
+
+// NoiseCount returns a count with Laplace noise of scale 1/epsilon added, rounded and
+// clamped to 0. A count changed by at most 1 by any one search is then
+// epsilon-differentially private.
+func NoiseCount(rng *rand.Rand, count int, epsilon float64) int {
+	noised := int(math.Round(float64(count) + Laplace(rng, 1/epsilon)))
+	return max(noised, 0)
+}
+
+// Noiser draws the noise of each count from a keyed hash of the count's identity, its
+// value and the privacy budget, rather than at random. Repeating a request, or varying it
+// without changing the counts, returns the same noise, which therefore can't be averaged
+// away. Servers sharing a secret add the same noise.
+type Noiser struct {
+	key []byte
+}
+
+// NewNoiser returns a Noiser keyed with secret. If secret is empty, a random key is used,
+// so that noise differs between servers and restarts.
+func NewNoiser(secret string) *Noiser {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, sha256.Size)
+		if _, err := cryptorand.Read(key); err != nil {
+			panic("failed to generate a noise key: " + err.Error())
+		}
+	}
+	return &Noiser{key: key}
+}
+
+// NoiseCount returns a count with noise added as the package's NoiseCount does, the noise
+// being determined by id, which identifies the count, its value and epsilon.
+func (n *Noiser) NoiseCount(id string, count int, epsilon float64) int {
+	mac := hmac.New(sha256.New, n.key)
+	mac.Write([]byte(id))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.Itoa(count)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatFloat(epsilon, 'g', -1, 64)))
+	seed := int64(binary.BigEndian.Uint64(mac.Sum(nil)))
+	return NoiseCount(rand.New(rand.NewSource(seed)), count, epsilon) //nolint:gosec
+}
+
+// NoiseSearchAnalytics returns a copy of analytics with noise added to its counts, and the
+// rates recomputed from them. The privacy budget epsilon is that of any one search, split
+// among the counts it changes. scope identifies the searches aggregated, such as their
+// kind and collection, so that the counts of different searches get independent noise.
+//
+// The top queries are selected by their noised counts, so analytics should hold more
+// candidates than the limit returned. Those with fewer than minCount noised searches are
+// omitted, as their text would expose the searches of a few users. Averages are omitted,
+// as a single search's latency or result count can change them without bound.
+func NoiseSearchAnalytics(
+	analytics *models.SearchAnalytics,
+	noiser *Noiser,
+	scope string,
+	epsilon float64,
+	minCount int,
+	limit int,
+) *models.SearchAnalytics {
+	if epsilon <= 0 {
+		epsilon = DefaultEpsilon
+	}
+	countEpsilon := epsilon / countsPerSearch
+	noise := func(id string, count int) int {
+		return noiser.NoiseCount(scope+"\x00"+id, count, countEpsilon)
+	}
+
+	noised := *analytics
+	noised.Epsilon = epsilon
+	noised.Searches = noise("searches", analytics.Searches)
+	noised.ZeroResults = min(noise("zero_results", analytics.ZeroResults), noised.Searches)
+	noised.ZeroResultRate = 0
+	if noised.Searches > 0 {
+		noised.ZeroResultRate = float64(noised.ZeroResults) / float64(noised.Searches)
+	}
+	noised.AvgLatencyMS = 0
+	noised.TopQueries = noiseQueryCounts(
+		func(id string, count int) int { return noise("top\x00"+id, count) },
+		analytics.TopQueries,
+		minCount,
+		limit,
+	)
+	noised.TopZeroResultQueries = noiseQueryCounts(
+		func(id string, count int) int { return noise("top_zero_results\x00"+id, count) },
+		analytics.TopZeroResultQueries,
+		minCount,
+		limit,
+	)
+	return &noised
+}
+
+func noiseQueryCounts(
+	noise func(id string, count int) int,
+	queries []models.QueryCount,
+	minCount int,
+	limit int,
+) []models.QueryCount {
+	noised := make([]models.QueryCount, 0, len(queries))
+	for _, query := range queries {
+		query.Searches = noise("searches\x00"+query.Text, query.Searches)
+		query.ZeroResults = min(noise("zero_results\x00"+query.Text, query.ZeroResults), query.Searches)
+		query.AvgResults = 0
+		if query.Searches == 0 || query.Searches < minCount {
+			continue
+		}
+		noised = append(noised, query)
+	}
+	sort.SliceStable(noised, func(i, j int) bool {
+		if noised[i].Searches != noised[j].Searches {
+			return noised[i].Searches > noised[j].Searches
+		}
+		return noised[i].Text < noised[j].Text
+	})
+	if limit > 0 && len(noised) > limit {
+		noised = noised[:limit]
+	}
+	return noised
+}
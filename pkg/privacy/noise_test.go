@@ -0,0 +1,109 @@
+package privacy
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestLaplace(t *testing.T) {
+	rng := rand.New(rand.NewSource(1)) //nolint:gosec
+	const n, scale = 100000, 2.0
+	var sum, absSum float64
+	for i := 0; i < n; i++ {
+		x := Laplace(rng, scale)
+		require.False(t, math.IsInf(x, 0))
+		sum += x
+		absSum += math.Abs(x)
+	}
+	// the mean is 0 and the mean absolute deviation is the scale
+	assert.InDelta(t, 0, sum/n, 0.05)
+	assert.InDelta(t, scale, absSum/n, 0.05)
+}
+
+func TestNoiseCount(t *testing.T) {
+	rng := rand.New(rand.NewSource(1)) //nolint:gosec
+	var changed bool
+	for i := 0; i < 100; i++ {
+		noised := NoiseCount(rng, 0, 0.5)
+		assert.GreaterOrEqual(t, noised, 0)
+		changed = changed || noised != 0
+	}
+	assert.True(t, changed)
+
+	// with a large budget, counts barely change
+	assert.InDelta(t, 1000, NoiseCount(rng, 1000, 100), 1)
+}
+
+func TestNoiser(t *testing.T) {
+	noiser := NewNoiser("secret")
+	var changed bool
+	for i := 0; i < 100; i++ {
+		id := strconv.Itoa(i)
+		noised := noiser.NoiseCount(id, 10, 0.5)
+		// the same count always gets the same noise
+		assert.Equal(t, noised, noiser.NoiseCount(id, 10, 0.5))
+		assert.Equal(t, noised, NewNoiser("secret").NoiseCount(id, 10, 0.5))
+		changed = changed || noised != 10
+	}
+	assert.True(t, changed)
+
+	// a random key is generated if there's no secret
+	var differs bool
+	for i := 0; i < 100 && !differs; i++ {
+		id := strconv.Itoa(i)
+		differs = NewNoiser("").NoiseCount(id, 10, 0.5) != NewNoiser("").NoiseCount(id, 10, 0.5)
+	}
+	assert.True(t, differs)
+}
+
+func TestNoiseSearchAnalytics(t *testing.T) {
+	analytics := &models.SearchAnalytics{
+		Searches:       1000,
+		ZeroResults:    100,
+		ZeroResultRate: 0.1,
+		AvgLatencyMS:   12,
+		TopQueries: []models.QueryCount{
+			{Text: "lisbon", Searches: 500, ZeroResults: 10, AvgResults: 3},
+			{Text: "tokyo", Searches: 400, AvgResults: 2},
+			{Text: "oslo", Searches: 300, AvgResults: 2},
+			{Text: "my ssn", Searches: 1, ZeroResults: 1},
+		},
+		TopZeroResultQueries: []models.QueryCount{
+			{Text: "lisbon", Searches: 10, ZeroResults: 10},
+			{Text: "my ssn", Searches: 1, ZeroResults: 1},
+		},
+	}
+	noiser := NewNoiser("secret")
+	noised := NoiseSearchAnalytics(analytics, noiser, "memory", 6, 5, 2)
+
+	assert.Equal(t, 6.0, noised.Epsilon)
+	assert.InDelta(t, 1000, noised.Searches, 20)
+	assert.InDelta(t, 100, noised.ZeroResults, 20)
+	assert.Equal(t, float64(noised.ZeroResults)/float64(noised.Searches), noised.ZeroResultRate)
+	assert.Zero(t, noised.AvgLatencyMS)
+
+	// the rare query is omitted, and only the limit of the most frequent are returned
+	require.Len(t, noised.TopQueries, 2)
+	assert.Equal(t, "lisbon", noised.TopQueries[0].Text)
+	assert.Equal(t, "tokyo", noised.TopQueries[1].Text)
+	assert.Zero(t, noised.TopQueries[0].AvgResults)
+	assert.LessOrEqual(t, noised.TopQueries[1].ZeroResults, noised.TopQueries[1].Searches)
+	require.Len(t, noised.TopZeroResultQueries, 1)
+	assert.Equal(t, "lisbon", noised.TopZeroResultQueries[0].Text)
+
+	// the noise can't be averaged away by repeating the request
+	assert.Equal(t, noised, NoiseSearchAnalytics(analytics, noiser, "memory", 6, 5, 2))
+
+	// the analytics aren't modified
+	assert.Equal(t, 1000, analytics.Searches)
+	assert.Equal(t, 12.0, analytics.AvgLatencyMS)
+	assert.Len(t, analytics.TopQueries, 4)
+	assert.Zero(t, analytics.Epsilon)
+}
@@ -0,0 +1,179 @@
+// Package provision ensures that declared resources exist, so that they can be managed
+// from version control or by infrastructure tools rather than by one-off API calls.
+package provision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+var log = internal.GetLogger()
+
+// Action is the change EnsureCollection made to a collection.
+type Action string
+
+const (
+	ActionCreated   Action = "created"
+	ActionUpdated   Action = "updated"
+	ActionUnchanged Action = "unchanged"
+)
+
+// Result lists the names of the declared collections by the action taken.
+type Result struct {
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// Apply ensures each declared collection exists, using EnsureCollection. Collections that
+// aren't declared are left alone. All declarations are validated before any collection is
+// changed.
+func Apply(
+	ctx context.Context,
+	documentStore models.DocumentStore[any],
+	resources *config.ResourcesConfig,
+) (*Result, error) {
+	if err := validate(resources); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, resource := range resources.Collections {
+		name := strings.ToLower(resource.Name)
+		action, err := EnsureCollection(ctx, documentStore, models.DocumentCollection{
+			Name:                   name,
+			Description:            resource.Description,
+			Metadata:               resource.Metadata,
+			EmbeddingDimensions:    resource.EmbeddingDimensions,
+			IsAutoEmbedded:         resource.IsAutoEmbedded,
+			ContextualChunkHeaders: resource.ContextualChunkHeaders,
+		})
+		if err != nil {
+			return result, err
+		}
+		switch action {
+		case ActionCreated:
+			log.Infof("provision: created collection %s", name)
+			result.Created = append(result.Created, name)
+		case ActionUpdated:
+			log.Infof("provision: updated collection %s", name)
+			result.Updated = append(result.Updated, name)
+		default:
+			result.Unchanged = append(result.Unchanged, name)
+		}
+	}
+
+	return result, nil
+}
+
+// EnsureCollection creates the collection if it doesn't exist, and otherwise updates its
+// description and metadata if they differ. Repeating a call has no further effect. If the
+// collection exists with different embedding dimensions, auto-embedding or contextual chunk
+// headers, which can't be changed, a ConflictError is returned.
+func EnsureCollection(
+	ctx context.Context,
+	documentStore models.DocumentStore[any],
+	collection models.DocumentCollection,
+) (Action, error) {
+	name := collection.Name
+	existing, err := documentStore.GetCollection(ctx, name)
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		if err := documentStore.CreateCollection(ctx, collection); err != nil {
+			return "", fmt.Errorf("failed to create collection %s: %w", name, err)
+		}
+		return ActionCreated, nil
+	case err != nil:
+		return "", fmt.Errorf("failed to get collection %s: %w", name, err)
+	}
+
+	if existing.EmbeddingDimensions != collection.EmbeddingDimensions ||
+		existing.IsAutoEmbedded != collection.IsAutoEmbedded {
+		return "", models.NewConflictError(fmt.Sprintf(
+			"collection %s exists with embedding_dimensions %d and is_auto_embedded %t, "+
+				"which can't be changed",
+			name, existing.EmbeddingDimensions, existing.IsAutoEmbedded,
+		))
+	}
+	if existing.ContextualChunkHeaders != collection.ContextualChunkHeaders {
+		return "", models.NewConflictError(fmt.Sprintf(
+			"collection %s exists with contextual_chunk_headers %t, which can't be changed",
+			name, existing.ContextualChunkHeaders,
+		))
+	}
+	if existing.Description == collection.Description &&
+		metadataContains(existing.Metadata, collection.Metadata) {
+		return ActionUnchanged, nil
+	}
+
+	err = documentStore.UpdateCollection(ctx, models.DocumentCollection{
+		Name:        name,
+		Description: collection.Description,
+		Metadata:    collection.Metadata,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to update collection %s: %w", name, err)
+	}
+	return ActionUpdated, nil
+}
+
+func validate(resources *config.ResourcesConfig) error {
+	seen := make(map[string]bool, len(resources.Collections))
+	for _, resource := range resources.Collections {
+		name := strings.ToLower(resource.Name)
+		if len(name) < 3 || len(name) > 40 || !isAlphanumeric(name) {
+			return models.NewValidationError(fmt.Sprintf(
+				"collection name %q must be 3 to 40 alphanumeric characters", resource.Name,
+			))
+		}
+		if seen[name] {
+			return models.NewValidationError(
+				fmt.Sprintf("collection %s is declared more than once", name),
+			)
+		}
+		seen[name] = true
+		if resource.EmbeddingDimensions < 8 || resource.EmbeddingDimensions > 2000 {
+			return models.NewValidationError(fmt.Sprintf(
+				"collection %s: embedding_dimensions must be between 8 and 2000", name,
+			))
+		}
+		if resource.ContextualChunkHeaders && !resource.IsAutoEmbedded {
+			return models.NewValidationError(fmt.Sprintf(
+				"collection %s: contextual_chunk_headers requires is_auto_embedded", name,
+			))
+		}
+	}
+	return nil
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// metadataContains is true if every declared key is present in metadata with an equal
+// value. Values are compared by their string form, as the store may decode numbers
+// differently from the config loader.
+func metadataContains(metadata, declared map[string]interface{}) bool {
+	for k, v := range declared {
+		existing, ok := metadata[k]
+		if !ok {
+			return false
+		}
+		if !reflect.DeepEqual(existing, v) && fmt.Sprint(existing) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package provision
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// collectionStore implements the collection methods of DocumentStore.
+type collectionStore struct {
+	models.DocumentStore[any]
+	collections map[string]models.DocumentCollection
+	updates     int
+}
+
+func newCollectionStore() *collectionStore {
+	return &collectionStore{collections: map[string]models.DocumentCollection{}}
+}
+
+func (s *collectionStore) GetCollection(
+	_ context.Context,
+	name string,
+) (models.DocumentCollection, error) {
+	c, ok := s.collections[name]
+	if !ok {
+		return models.DocumentCollection{}, models.NewNotFoundError("collection " + name)
+	}
+	return c, nil
+}
+
+func (s *collectionStore) CreateCollection(
+	_ context.Context,
+	collection models.DocumentCollection,
+) error {
+	s.collections[collection.Name] = collection
+	return nil
+}
+
+func (s *collectionStore) UpdateCollection(
+	_ context.Context,
+	collection models.DocumentCollection,
+) error {
+	c := s.collections[collection.Name]
+	c.Description = collection.Description
+	c.Metadata = collection.Metadata
+	s.collections[collection.Name] = c
+	s.updates++
+	return nil
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	store := newCollectionStore()
+	resources := &config.ResourcesConfig{
+		Collections: []config.CollectionResource{
+			{Name: "Docs", Description: "docs", EmbeddingDimensions: 384, IsAutoEmbedded: true},
+			{
+				Name:                "faq",
+				Metadata:            map[string]interface{}{"team": "support", "version": 2},
+				EmbeddingDimensions: 1536,
+			},
+		},
+	}
+
+	result, err := Apply(ctx, store, resources)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs", "faq"}, result.Created)
+	assert.Equal(t, 384, store.collections["docs"].EmbeddingDimensions)
+	assert.True(t, store.collections["docs"].IsAutoEmbedded)
+
+	// applying again is a no-op, even if the store returns numbers as strings
+	faq := store.collections["faq"]
+	faq.Metadata = map[string]interface{}{"team": "support", "version": "2", "extra": true}
+	store.collections["faq"] = faq
+	result, err = Apply(ctx, store, resources)
+	require.NoError(t, err)
+	assert.Empty(t, result.Created)
+	assert.Empty(t, result.Updated)
+	assert.Equal(t, []string{"docs", "faq"}, result.Unchanged)
+	assert.Equal(t, 0, store.updates)
+
+	resources.Collections[0].Description = "updated"
+	result, err = Apply(ctx, store, resources)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs"}, result.Updated)
+	assert.Equal(t, "updated", store.collections["docs"].Description)
+}
+
+func TestApplyImmutableFields(t *testing.T) {
+	ctx := context.Background()
+	store := newCollectionStore()
+	store.collections["docs"] = models.DocumentCollection{
+		Name:                "docs",
+		EmbeddingDimensions: 384,
+	}
+
+	_, err := Apply(ctx, store, &config.ResourcesConfig{
+		Collections: []config.CollectionResource{{Name: "docs", EmbeddingDimensions: 768}},
+	})
+	assert.ErrorIs(t, err, models.ErrConflict)
+	assert.Equal(t, 384, store.collections["docs"].EmbeddingDimensions)
+}
+
+func TestApplyValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		collections []config.CollectionResource
+	}{
+		{"short name", []config.CollectionResource{{Name: "ab", EmbeddingDimensions: 384}}},
+		{"invalid name", []config.CollectionResource{{Name: "my-docs", EmbeddingDimensions: 384}}},
+		{"no dimensions", []config.CollectionResource{{Name: "docs"}}},
+		{"duplicate", []config.CollectionResource{
+			{Name: "docs", EmbeddingDimensions: 384},
+			{Name: "DOCS", EmbeddingDimensions: 384},
+		}},
+		{"headers without auto-embedding", []config.CollectionResource{
+			{Name: "docs", EmbeddingDimensions: 384, ContextualChunkHeaders: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newCollectionStore()
+			_, err := Apply(
+				context.Background(),
+				store,
+				&config.ResourcesConfig{Collections: tt.collections},
+			)
+			assert.ErrorIs(t, err, models.ErrValidation)
+			assert.Empty(t, store.collections)
+		})
+	}
+}
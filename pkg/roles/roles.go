@@ -0,0 +1,271 @@
+// Package roles maps the roles of written messages to the roles registered by a
+// deployment, tells extractors which kind of participant each role is, and decides which
+// roles are summarized and searchable.
+package roles
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/writehooks"
+)
+
+var log = internal.GetLogger()
+
+var _ writehooks.Hook = &Registry{}
+
+// Kind is the kind of participant a role is.
+type Kind string
+
+const (
+	User      Kind = "user"
+	Assistant Kind = "assistant"
+	Tool      Kind = "tool"
+	System    Kind = "system"
+)
+
+// defaultKinds are the kinds of the roles commonly written by clients. They're used for
+// roles that aren't registered.
+var defaultKinds = map[string]Kind{
+	"user":      User,
+	"human":     User,
+	"assistant": Assistant,
+	"ai":        Assistant,
+	"tool":      Tool,
+	"function":  Tool,
+	"system":    System,
+}
+
+// Registry holds the registered roles. A nil Registry, or one without roles, accepts any
+// role as written. It's a write hook.
+type Registry struct {
+	enforce bool
+	// names maps lowercased names and aliases to the registered name
+	names map[string]string
+	kinds map[string]Kind
+	// lowercased roles and kinds left out of summaries and search
+	excludeFromSummary map[string]bool
+	excludeFromSearch  map[string]bool
+}
+
+// New builds the registry of the roles of cfg. It returns an error if a role's kind is
+// invalid, or if a name or alias is registered twice.
+func New(cfg config.RolesConfig) (*Registry, error) {
+	if cfg.Enforce && len(cfg.Registry) == 0 {
+		return nil, fmt.Errorf("memory.roles.enforce requires registered roles")
+	}
+
+	r := &Registry{
+		enforce:            cfg.Enforce,
+		names:              make(map[string]string),
+		kinds:              make(map[string]Kind),
+		excludeFromSummary: make(map[string]bool),
+		excludeFromSearch:  make(map[string]bool),
+	}
+	for i, rc := range cfg.Registry {
+		name := strings.TrimSpace(rc.Name)
+		if name == "" {
+			return nil, fmt.Errorf("role %d: name is required", i)
+		}
+		kind := Kind(strings.ToLower(rc.Kind))
+		if kind == "" {
+			kind = defaultKinds[strings.ToLower(name)]
+		}
+		switch kind {
+		case User, Assistant, Tool, System:
+		case "":
+			return nil, fmt.Errorf("role %q: kind is required", name)
+		default:
+			return nil, fmt.Errorf(
+				"role %q: kind must be one of user, assistant, tool or system, not %q",
+				name,
+				rc.Kind,
+			)
+		}
+		r.kinds[name] = kind
+
+		for _, alias := range append([]string{name}, rc.Aliases...) {
+			key := normalize(alias)
+			if other, ok := r.names[key]; ok {
+				return nil, fmt.Errorf("role %q: %q is already registered for %q", name, alias, other)
+			}
+			r.names[key] = name
+		}
+	}
+
+	for _, role := range cfg.ExcludeFromSummary {
+		r.excludeFromSummary[normalize(role)] = true
+	}
+	for _, role := range cfg.ExcludeFromSearch {
+		r.excludeFromSearch[normalize(role)] = true
+	}
+	return r, nil
+}
+
+// FromConfig returns the registry of the roles of cfg, or nil if cfg is nil or its roles
+// are invalid. Invalid roles are rejected by Wrap on startup.
+func FromConfig(cfg *config.Config) *Registry {
+	if cfg == nil {
+		return nil
+	}
+	r, err := New(cfg.Memory.Roles)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+// Name returns the registered name of role, which may be an alias. ok is false if role
+// isn't registered.
+func (r *Registry) Name(role string) (name string, ok bool) {
+	if r == nil {
+		return "", false
+	}
+	name, ok = r.names[normalize(role)]
+	return name, ok
+}
+
+// Kind returns the kind of role. Roles that aren't registered have the kind of the
+// common role of the same name, such as "ai" for Assistant, or none.
+func (r *Registry) Kind(role string) Kind {
+	if name, ok := r.Name(role); ok {
+		return r.kinds[name]
+	}
+	return defaultKinds[normalize(role)]
+}
+
+// Label returns how role is shown to an LLM. Registered roles whose name doesn't say what
+// kind of participant they are are followed by their kind, e.g. "billing_bot (assistant)".
+func (r *Registry) Label(role string) string {
+	name, ok := r.Name(role)
+	if !ok {
+		return role
+	}
+	kind := r.kinds[name]
+	if defaultKinds[normalize(name)] == kind {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, kind)
+}
+
+// Summarized reports whether messages of role are included in session summaries.
+func (r *Registry) Summarized(role string) bool {
+	return r == nil || !r.excludes(r.excludeFromSummary, role)
+}
+
+// Searchable reports whether messages of role are embedded and returned by search.
+func (r *Registry) Searchable(role string) bool {
+	return r == nil || !r.excludes(r.excludeFromSearch, role)
+}
+
+// Unsearchable returns the lowercased roles that aren't searchable, for stores to exclude
+// from message search. These include the aliases of unsearchable registered roles, as
+// messages may have been stored with them before the roles were registered.
+func (r *Registry) Unsearchable() []string {
+	if r == nil || len(r.excludeFromSearch) == 0 {
+		return nil
+	}
+	candidates := make(map[string]bool)
+	for role := range r.names {
+		candidates[role] = true
+	}
+	for role := range defaultKinds {
+		candidates[role] = true
+	}
+	for role := range r.excludeFromSearch {
+		candidates[role] = true
+	}
+
+	var unsearchable []string
+	for role := range candidates {
+		if !r.Searchable(role) {
+			unsearchable = append(unsearchable, role)
+		}
+	}
+	sort.Strings(unsearchable)
+	return unsearchable
+}
+
+// Matching returns the lowercased roles that messages of the given roles may be stored
+// with, for stores to filter message search by. A registered role, or one of its aliases,
+// matches the role's name and all its aliases, as messages may have been stored with them
+// before the role was registered.
+func (r *Registry) Matching(roles []string) []string {
+	matching := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		matching[normalize(role)] = true
+		name, ok := r.Name(role)
+		if !ok {
+			continue
+		}
+		for alias, aliasName := range r.names {
+			if aliasName == name {
+				matching[alias] = true
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(matching))
+	for role := range matching {
+		sorted = append(sorted, role)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// excludes reports whether role, its registered name, or its kind is in exclude.
+func (r *Registry) excludes(exclude map[string]bool, role string) bool {
+	if len(exclude) == 0 {
+		return false
+	}
+	if name, ok := r.Name(role); ok && exclude[normalize(name)] {
+		return true
+	}
+	if kind := r.Kind(role); kind != "" && exclude[string(kind)] {
+		return true
+	}
+	return exclude[normalize(role)]
+}
+
+// Apply replaces the roles of the messages of memory that are aliases with their
+// registered name. If roles are enforced, memory with an unregistered role is rejected.
+func (r *Registry) Apply(_ context.Context, _ string, memory *models.Memory) error {
+	for i := range memory.Messages {
+		m := &memory.Messages[i]
+		if name, ok := r.Name(m.Role); ok {
+			m.Role = name
+			continue
+		}
+		if r.enforce {
+			return models.NewValidationError(
+				fmt.Sprintf("message %d: unknown role %q", i, m.Role),
+			)
+		}
+	}
+	return nil
+}
+
+// Wrap replaces the memory store of appState with one mapping message roles to the
+// registered roles, if any roles are registered.
+func Wrap(appState *models.AppState) error {
+	cfg := appState.Config.Memory.Roles
+	if appState.MemoryStore == nil || (len(cfg.Registry) == 0 && !cfg.Enforce) {
+		return nil
+	}
+	r, err := New(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid memory.roles: %w", err)
+	}
+	log.Infof("message roles registered: roles=%d enforce=%t", len(r.kinds), r.enforce)
+	appState.MemoryStore = writehooks.NewMemoryStore(appState.MemoryStore, r)
+	return nil
+}
+
+func normalize(role string) string {
+	return strings.ToLower(strings.TrimSpace(role))
+}
@@ -0,0 +1,128 @@
+package roles
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.RolesConfig
+		want string
+	}{
+		{
+			name: "enforce without roles",
+			cfg:  config.RolesConfig{Enforce: true},
+			want: "requires registered roles",
+		},
+		{
+			name: "custom role without kind",
+			cfg:  config.RolesConfig{Registry: []config.RoleConfig{{Name: "billing_agent"}}},
+			want: `role "billing_agent": kind is required`,
+		},
+		{
+			name: "invalid kind",
+			cfg: config.RolesConfig{Registry: []config.RoleConfig{
+				{Name: "billing_agent", Kind: "robot"},
+			}},
+			want: "kind must be one of",
+		},
+		{
+			name: "duplicate alias",
+			cfg: config.RolesConfig{Registry: []config.RoleConfig{
+				{Name: "user", Aliases: []string{"customer"}},
+				{Name: "assistant", Aliases: []string{"Customer"}},
+			}},
+			want: `"Customer" is already registered for "user"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r, err := New(config.RolesConfig{
+		Registry: []config.RoleConfig{
+			{Name: "user", Aliases: []string{"human", "customer"}},
+			{Name: "assistant", Aliases: []string{"ai"}},
+			{Name: "billing_agent", Kind: "Assistant"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, User, r.Kind(" Customer"))
+	assert.Equal(t, Assistant, r.Kind("billing_agent"))
+	// unregistered roles have the kind of the common role of the same name
+	assert.Equal(t, Tool, r.Kind("function"))
+	assert.Equal(t, Kind(""), r.Kind("narrator"))
+
+	assert.Equal(t, "user", r.Label("HUMAN"))
+	assert.Equal(t, "billing_agent (assistant)", r.Label("billing_agent"))
+	assert.Equal(t, "narrator", r.Label("narrator"))
+
+	assert.Equal(
+		t,
+		[]string{"billing_agent", "customer", "human", "narrator", "user"},
+		r.Matching([]string{"Customer", "narrator", "billing_agent"}),
+	)
+
+	var empty *Registry
+	assert.Equal(t, Assistant, empty.Kind("AI"))
+	assert.Equal(t, "AI", empty.Label("AI"))
+	assert.Equal(t, []string{"ai"}, empty.Matching([]string{"AI"}))
+
+	memory := &models.Memory{Messages: []models.Message{
+		{Role: "Customer", Content: "Why was I charged twice?"},
+		{Role: "billing_agent", Content: "Let me check."},
+		{Role: "narrator", Content: "..."},
+	}}
+	require.NoError(t, r.Apply(context.Background(), "s1", memory))
+	assert.Equal(t, "user", memory.Messages[0].Role)
+	assert.Equal(t, "billing_agent", memory.Messages[1].Role)
+	assert.Equal(t, "narrator", memory.Messages[2].Role)
+
+	r.enforce = true
+	err = r.Apply(context.Background(), "s1", memory)
+	assert.ErrorIs(t, err, models.ErrValidation)
+	assert.ErrorContains(t, err, `message 2: unknown role "narrator"`)
+}
+
+func TestPolicies(t *testing.T) {
+	r, err := New(config.RolesConfig{
+		Registry: []config.RoleConfig{
+			{Name: "user", Aliases: []string{"human"}},
+			{Name: "assistant", Aliases: []string{"ai"}},
+			{Name: "billing_agent", Kind: "assistant"},
+			{Name: "tool", Aliases: []string{"function"}},
+		},
+		ExcludeFromSummary: []string{"tool", "System"},
+		ExcludeFromSearch:  []string{"billing_agent", "system"},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, r.Summarized("human"))
+	assert.False(t, r.Summarized("Function"))
+	// unregistered roles are matched by their common kind, or as written
+	assert.False(t, r.Summarized("system"))
+	assert.True(t, r.Summarized("narrator"))
+
+	assert.True(t, r.Searchable("assistant"))
+	assert.False(t, r.Searchable("billing_agent"))
+	assert.Equal(t, []string{"billing_agent", "system"}, r.Unsearchable())
+
+	var empty *Registry
+	assert.True(t, empty.Summarized("system"))
+	assert.True(t, empty.Searchable("system"))
+	assert.Nil(t, empty.Unsearchable())
+}
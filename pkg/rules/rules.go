@@ -0,0 +1,209 @@
+// Package rules applies rules, configured as CEL expressions, to messages as they're
+// written. A rule matching a message may set metadata fields, route the message to
+// extractors that only process routed messages, or leave it out of session summaries.
+package rules
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/writehooks"
+)
+
+var log = internal.GetLogger()
+
+var _ writehooks.Hook = &Engine{}
+
+// Engine applies the message rules to the messages of each write. It's a write hook.
+type Engine struct {
+	rules []*rule
+}
+
+type rule struct {
+	name              string
+	program           cel.Program
+	setMetadata       map[string]interface{}
+	extractors        []string
+	skipSummarization bool
+}
+
+// New compiles the message rules of cfg. It returns an error if an expression doesn't
+// compile or evaluate to a bool, or if a rule routes messages to an extractor that isn't
+// a routed extractor.
+func New(cfg config.RulesConfig) (*Engine, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("message", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("session_id", cel.StringType),
+		ext.Strings(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, topic := range cfg.RoutedExtractors {
+		if !slices.Contains(models.MessageTopics, models.TaskTopic(topic)) {
+			return nil, fmt.Errorf("unknown rules.routed_extractors %q", topic)
+		}
+	}
+
+	e := &Engine{}
+	for i, rc := range cfg.Messages {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i)
+		}
+		if slices.ContainsFunc(e.rules, func(r *rule) bool { return r.name == name }) {
+			return nil, fmt.Errorf("rule %q: names must be unique", name)
+		}
+		if rc.When == "" {
+			return nil, fmt.Errorf("rule %q: when is required", name)
+		}
+		ast, issues := env.Compile(rc.When)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("rule %q: %w", name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf(
+				"rule %q: when must evaluate to a bool, not %s",
+				name,
+				ast.OutputType(),
+			)
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", name, err)
+		}
+		if _, ok := rc.SetMetadata["system"]; ok {
+			return nil, fmt.Errorf("rule %q: the system metadata key cannot be set", name)
+		}
+		for _, topic := range rc.Extractors {
+			if !slices.Contains(cfg.RoutedExtractors, topic) {
+				return nil, fmt.Errorf(
+					"rule %q: %q must be listed in rules.routed_extractors",
+					name,
+					topic,
+				)
+			}
+		}
+		e.rules = append(e.rules, &rule{
+			name:              name,
+			program:           program,
+			setMetadata:       rc.SetMetadata,
+			extractors:        rc.Extractors,
+			skipSummarization: rc.SkipSummarization,
+		})
+	}
+	return e, nil
+}
+
+// Apply applies the rules to each message of memory, and stores the outcome under the
+// system.rules metadata key of the messages matching a rule. An outcome set by the client
+// is removed, so that clients can't route their own messages.
+func (e *Engine) Apply(_ context.Context, sessionID string, memory *models.Memory) error {
+	for i := range memory.Messages {
+		m := &memory.Messages[i]
+		// the metadata may be shared with the caller, so it's copied before it's modified
+		metadata := make(map[string]interface{}, len(m.Metadata))
+		for k, v := range m.Metadata {
+			metadata[k] = v
+		}
+		system := map[string]interface{}{}
+		if s, ok := metadata["system"].(map[string]interface{}); ok {
+			for k, v := range s {
+				system[k] = v
+			}
+		}
+		delete(system, models.MessageRulesKey)
+
+		if matched := e.match(sessionID, m); len(matched) > 0 {
+			var outcome models.MessageRules
+			for _, r := range matched {
+				outcome.Matched = append(outcome.Matched, r.name)
+				for _, topic := range r.extractors {
+					if !slices.Contains(outcome.Extractors, topic) {
+						outcome.Extractors = append(outcome.Extractors, topic)
+					}
+				}
+				outcome.SkipSummarization = outcome.SkipSummarization || r.skipSummarization
+				for k, v := range r.setMetadata {
+					metadata[k] = v
+				}
+			}
+			system[models.MessageRulesKey] = map[string]interface{}{
+				"matched":            outcome.Matched,
+				"extractors":         outcome.Extractors,
+				"skip_summarization": outcome.SkipSummarization,
+			}
+		}
+
+		if len(system) > 0 {
+			metadata["system"] = system
+		} else {
+			delete(metadata, "system")
+		}
+		if len(metadata) == 0 && m.Metadata == nil {
+			continue
+		}
+		m.Metadata = metadata
+	}
+	return nil
+}
+
+// match returns the rules matching m. A rule whose expression fails to evaluate, such as
+// by reading a missing metadata field, doesn't match.
+func (e *Engine) match(sessionID string, m *models.Message) []*rule {
+	metadata := m.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	vars := map[string]interface{}{
+		"session_id": sessionID,
+		"message": map[string]interface{}{
+			"role":        m.Role,
+			"content":     m.Content,
+			"metadata":    metadata,
+			"token_count": m.TokenCount,
+		},
+	}
+
+	var matched []*rule
+	for _, r := range e.rules {
+		out, _, err := r.program.Eval(vars)
+		if err != nil {
+			log.Debugf("rule %q failed to evaluate: %s", r.name, err)
+			continue
+		}
+		if ok, _ := out.Value().(bool); ok {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// Wrap replaces the memory store of appState with one applying the configured rules, if
+// any rules or routed extractors are configured.
+func Wrap(appState *models.AppState) error {
+	cfg := appState.Config.Rules
+	if appState.MemoryStore == nil ||
+		(len(cfg.Messages) == 0 && len(cfg.RoutedExtractors) == 0) {
+		return nil
+	}
+	e, err := New(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid rules: %w", err)
+	}
+	log.Infof(
+		"message rules enabled: rules=%d routed_extractors=%v",
+		len(e.rules),
+		cfg.RoutedExtractors,
+	)
+	appState.MemoryStore = writehooks.NewMemoryStore(appState.MemoryStore, e)
+	return nil
+}
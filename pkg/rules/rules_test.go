@@ -0,0 +1,121 @@
+package rules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.RulesConfig
+		want string
+	}{
+		{
+			name: "syntax error",
+			cfg:  config.RulesConfig{Messages: []config.MessageRuleConfig{{When: "message.role =="}}},
+			want: `rule "rule 0"`,
+		},
+		{
+			name: "not a bool",
+			cfg:  config.RulesConfig{Messages: []config.MessageRuleConfig{{When: "session_id"}}},
+			want: "must evaluate to a bool",
+		},
+		{
+			name: "unknown routed extractor",
+			cfg:  config.RulesConfig{RoutedExtractors: []string{"message_translator"}},
+			want: `unknown rules.routed_extractors "message_translator"`,
+		},
+		{
+			name: "extractor isn't routed",
+			cfg: config.RulesConfig{Messages: []config.MessageRuleConfig{
+				{Name: "r", When: "true", Extractors: []string{"message_intent"}},
+			}},
+			want: "must be listed in rules.routed_extractors",
+		},
+		{
+			name: "system metadata",
+			cfg: config.RulesConfig{Messages: []config.MessageRuleConfig{
+				{Name: "r", When: "true", SetMetadata: map[string]interface{}{"system": 1}},
+			}},
+			want: "system metadata key cannot be set",
+		},
+		{
+			name: "duplicate name",
+			cfg: config.RulesConfig{Messages: []config.MessageRuleConfig{
+				{Name: "r", When: "true"},
+				{Name: "r", When: "false"},
+			}},
+			want: "names must be unique",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	e, err := New(config.RulesConfig{
+		RoutedExtractors: []string{"message_enrichment"},
+		Messages: []config.MessageRuleConfig{
+			{
+				Name: "billing",
+				When: `message.role == "user" && ` +
+					`message.content.lowerAscii().contains("invoice")`,
+				SetMetadata: map[string]interface{}{"topic": "billing"},
+				Extractors:  []string{"message_enrichment"},
+			},
+			{
+				Name:              "small_talk",
+				When:              "size(message.content) < 10",
+				SkipSummarization: true,
+			},
+			// fails to evaluate for messages without a priority
+			{Name: "urgent", When: `message.metadata.priority == "high"`},
+		},
+	})
+	require.NoError(t, err)
+
+	spoofed := map[string]interface{}{"system": map[string]interface{}{
+		"intent":               "none",
+		models.MessageRulesKey: map[string]interface{}{"extractors": []string{"x"}},
+	}}
+	memory := &models.Memory{Messages: []models.Message{
+		{Role: "user", Content: "Where is my INVOICE?"},
+		{Role: "user", Content: "thanks", Metadata: spoofed},
+		{Role: "assistant", Content: "You're very welcome.", Metadata: spoofed},
+	}}
+	require.NoError(t, e.Apply(context.Background(), "s1", memory))
+
+	billing := memory.Messages[0]
+	assert.Equal(t, "billing", billing.Metadata["topic"])
+	assert.Equal(t, models.MessageRules{
+		Matched:    []string{"billing"},
+		Extractors: []string{"message_enrichment"},
+	}, models.MessageRulesOf(&billing))
+
+	smallTalk := memory.Messages[1]
+	assert.Equal(t, models.MessageRules{
+		Matched:           []string{"small_talk"},
+		SkipSummarization: true,
+	}, models.MessageRulesOf(&smallTalk))
+	assert.Equal(t, "none", smallTalk.Metadata["system"].(map[string]interface{})["intent"])
+
+	// the client's outcome is removed, without modifying the client's metadata
+	unmatched := memory.Messages[2]
+	assert.Equal(t, models.MessageRules{}, models.MessageRulesOf(&unmatched))
+	assert.Equal(t,
+		map[string]interface{}{"system": map[string]interface{}{"intent": "none"}},
+		unmatched.Metadata,
+	)
+	assert.Contains(t, spoofed["system"], models.MessageRulesKey)
+}
@@ -0,0 +1,166 @@
+// Package scheduler runs background jobs, such as purges and index maintenance, on
+// cron-style schedules, and records the outcome of each job's last run.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+var log = internal.GetLogger()
+
+var _ models.JobScheduler = &Scheduler{}
+
+// JobFunc performs a run of a job. ctx is canceled when the scheduler stops.
+type JobFunc func(ctx context.Context) error
+
+// Scheduler runs each of its jobs on the job's schedule. A job's runs don't overlap: a
+// run due while the previous run is still running is skipped.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*job
+	started bool
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       JobFunc
+	status   models.ScheduledJobStatus
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		jobs: make(map[string]*job),
+	}
+}
+
+// Add registers a job to run on spec, which is parsed with Parse. A job with an empty
+// spec is disabled, and is only listed in the job status.
+func (s *Scheduler) Add(name, spec string, fn JobFunc) error {
+	var schedule Schedule
+	if spec != "" {
+		var err error
+		schedule, err = Parse(spec)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", name, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return fmt.Errorf("job %s: scheduler already started", name)
+	}
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job %s already added", name)
+	}
+	s.jobs[name] = &job{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		fn:       fn,
+		status: models.ScheduledJobStatus{
+			Name:     name,
+			Schedule: spec,
+			Enabled:  schedule != nil,
+		},
+	}
+	return nil
+}
+
+// Start runs the enabled jobs until ctx is canceled.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.started = true
+	for _, j := range s.jobs {
+		if j.schedule == nil {
+			log.Debugf("scheduled job %s disabled", j.name)
+			continue
+		}
+		log.Infof("Scheduling job %s: %s", j.name, j.spec)
+		go s.run(ctx, j)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j *job) {
+	for {
+		next := s.setNextRun(j)
+		if next.IsZero() {
+			log.Warnf("scheduled job %s will never run: %s", j.name, j.spec)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Infof("Stopping scheduled job %s", j.name)
+			return
+		case <-timer.C:
+		}
+
+		s.runOnce(ctx, j)
+	}
+}
+
+func (s *Scheduler) setNextRun(j *job) time.Time {
+	next := j.schedule.Next(time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.status.NextRunAt = nil
+	if !next.IsZero() {
+		j.status.NextRunAt = &next
+	}
+	return next
+}
+
+// runOnce runs the job and records the outcome.
+func (s *Scheduler) runOnce(ctx context.Context, j *job) {
+	started := time.Now()
+	s.mu.Lock()
+	j.status.Running = true
+	j.status.LastRunAt = &started
+	s.mu.Unlock()
+
+	err := j.fn(ctx)
+	duration := time.Since(started)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.status.Running = false
+	j.status.LastDuration = duration.String()
+	j.status.Runs++
+	j.status.LastError = ""
+	if err != nil {
+		j.status.Failures++
+		j.status.LastError = err.Error()
+		log.Errorf("scheduled job %s failed: %v", j.name, err)
+	}
+}
+
+// JobStatus returns the status of each job, ordered by name.
+func (s *Scheduler) JobStatus() []models.ScheduledJobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make([]models.ScheduledJobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		status = append(status, j.status)
+	}
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Name < status[j].Name
+	})
+	return status
+}
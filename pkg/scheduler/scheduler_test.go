@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerRunsJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int64
+	s := New()
+	require.NoError(t, s.Add("ok", "@every 1s", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}))
+	require.NoError(t, s.Add("failing", "@every 1s", func(ctx context.Context) error {
+		return errors.New("boom")
+	}))
+	require.NoError(t, s.Add("disabled", "", func(ctx context.Context) error {
+		t.Error("disabled job ran")
+		return nil
+	}))
+	s.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		status := s.JobStatus()
+		return status[1].Runs > 0 && status[2].Runs > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, runs.Load(), int64(1))
+
+	status := s.JobStatus()
+	require.Len(t, status, 3)
+
+	disabled := status[0]
+	assert.Equal(t, "disabled", disabled.Name)
+	assert.False(t, disabled.Enabled)
+	assert.Nil(t, disabled.NextRunAt)
+	assert.Nil(t, disabled.LastRunAt)
+
+	failing := status[1]
+	assert.Equal(t, "failing", failing.Name)
+	assert.True(t, failing.Enabled)
+	assert.Equal(t, "boom", failing.LastError)
+	assert.Equal(t, failing.Runs, failing.Failures)
+	assert.NotNil(t, failing.LastRunAt)
+
+	ok := status[2]
+	assert.Equal(t, "ok", ok.Name)
+	assert.Equal(t, "@every 1s", ok.Schedule)
+	assert.Empty(t, ok.LastError)
+	assert.Zero(t, ok.Failures)
+	assert.NotEmpty(t, ok.LastDuration)
+	assert.NotNil(t, ok.NextRunAt)
+}
+
+func TestSchedulerAdd(t *testing.T) {
+	s := New()
+	noop := func(ctx context.Context) error { return nil }
+
+	require.NoError(t, s.Add("job", "@daily", noop))
+	assert.Error(t, s.Add("job", "@daily", noop), "duplicate name")
+	assert.Error(t, s.Add("invalid", "* *", noop))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	assert.Error(t, s.Add("late", "@daily", noop), "added after start")
+}
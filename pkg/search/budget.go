@@ -0,0 +1,32 @@
+package search
+
+import "github.com/getzep/zep/pkg/models"
+
+// FitTokenBudget returns the top-ranked results that fit within maxTokens, in order. A
+// result's tokens are the stored token count of its message or summary, so that results
+// whose tokens haven't been counted yet count for none. The results stop at the first
+// that doesn't fit, rather than skipping it for lower-ranked results that do.
+func FitTokenBudget(
+	results []models.MemorySearchResult,
+	maxTokens int,
+) []models.MemorySearchResult {
+	tokens := 0
+	for i, result := range results {
+		tokens += resultTokenCount(&result)
+		if tokens > maxTokens {
+			return results[:i]
+		}
+	}
+	return results
+}
+
+func resultTokenCount(result *models.MemorySearchResult) int {
+	switch {
+	case result.Message != nil:
+		return result.Message.TokenCount
+	case result.Summary != nil:
+		return result.Summary.TokenCount
+	default:
+		return 0
+	}
+}
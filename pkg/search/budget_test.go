@@ -0,0 +1,38 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestFitTokenBudget(t *testing.T) {
+	results := []models.MemorySearchResult{
+		{Message: &models.Message{Content: "first", TokenCount: 40}},
+		{Summary: &models.Summary{Content: "second", TokenCount: 30}},
+		{Message: &models.Message{Content: "uncounted"}},
+		{Message: &models.Message{Content: "fourth", TokenCount: 50}},
+		{Message: &models.Message{Content: "fifth", TokenCount: 10}},
+	}
+	contents := func(results []models.MemorySearchResult) []string {
+		c := make([]string, len(results))
+		for i, r := range results {
+			if r.Message != nil {
+				c[i] = r.Message.Content
+			} else {
+				c[i] = r.Summary.Content
+			}
+		}
+		return c
+	}
+
+	fitted := []string{"first", "second", "uncounted"}
+	assert.Equal(t, fitted, contents(FitTokenBudget(results, 100)))
+	assert.Equal(t, fitted, contents(FitTokenBudget(results, 70)))
+	// lower-ranked results that would fit aren't returned after one that doesn't
+	assert.Len(t, FitTokenBudget(results, 119), 3)
+	assert.Len(t, FitTokenBudget(results, 130), 5)
+	assert.Empty(t, FitTokenBudget(results, 39))
+}
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"fmt"
+
+	"github.com/viterin/vek/vek32"
+)
+
+// NearDuplicates greedily clusters near-duplicate embeddings. Embeddings are expected
+// to be ordered oldest first, so that the oldest embedding in a cluster becomes its
+// representative. It returns a slice where the value at [i] is the index of the
+// representative embedding i is a duplicate of, or -1 if i is a representative.
+// Two embeddings are near-duplicates if their cosine similarity is >= threshold.
+func NearDuplicates(embeddingList [][]float32, threshold float32) ([]int, error) {
+	duplicateOf := make([]int, len(embeddingList))
+	var representatives []int
+
+	for i, embedding := range embeddingList {
+		duplicateOf[i] = -1
+		for _, r := range representatives {
+			if len(embedding) != len(embeddingList[r]) {
+				return nil, fmt.Errorf(
+					"vector lengths do not match: %d != %d",
+					len(embedding),
+					len(embeddingList[r]),
+				)
+			}
+			if vek32.CosineSimilarity(embedding, embeddingList[r]) >= threshold {
+				duplicateOf[i] = r
+				break
+			}
+		}
+		if duplicateOf[i] == -1 {
+			representatives = append(representatives, i)
+		}
+	}
+
+	return duplicateOf, nil
+}
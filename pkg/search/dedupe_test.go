@@ -0,0 +1,48 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNearDuplicates(t *testing.T) {
+	t.Run("MismatchedVectorWidths", func(t *testing.T) {
+		embeddingList := [][]float32{
+			{0.1, 0.2, 0.3},
+			{0.2, 0.3, 0.4, 0.5, 0.5},
+		}
+		_, err := NearDuplicates(embeddingList, 0.9)
+		assert.Error(t, err)
+	})
+
+	t.Run("Clusters", func(t *testing.T) {
+		embeddingList := [][]float32{
+			{1.0, 0.0, 0.0},
+			{0.0, 1.0, 0.0},
+			{0.99, 0.01, 0.0},
+			{0.0, 0.0, 1.0},
+			{0.01, 0.99, 0.0},
+			{1.0, 0.0, 0.0},
+		}
+		result, err := NearDuplicates(embeddingList, 0.95)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{-1, -1, 0, -1, 1, 0}, result)
+	})
+
+	t.Run("ThresholdOfOne", func(t *testing.T) {
+		embeddingList := [][]float32{
+			{1.0, 0.0, 0.0},
+			{0.99, 0.01, 0.0},
+		}
+		result, err := NearDuplicates(embeddingList, 1.0)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{-1, -1}, result)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		result, err := NearDuplicates([][]float32{}, 0.95)
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+	})
+}
@@ -0,0 +1,124 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// stopWords are query terms too common to be worth highlighting, like those full-text
+// search ignores.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "do": true, "for": true, "from": true, "how": true, "i": true, "in": true,
+	"is": true, "it": true, "me": true, "my": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "was": true, "we": true,
+	"what": true, "when": true, "where": true, "who": true, "why": true, "with": true,
+	"you": true,
+}
+
+// stemSuffixes are stripped from words, repeatedly, before they're compared, so that
+// "embeddings" matches "embedded".
+var stemSuffixes = []string{"ing", "ed", "es", "s"}
+
+// MatchedSpans returns the spans of content matching the terms of the query text, in
+// order, so that clients can show why a result matched. Terms are matched by word,
+// case-insensitively and ignoring common suffixes. Matched words separated only by spaces
+// are merged into a single span. Offsets are in characters, not bytes.
+func MatchedSpans(content, text string) []models.TextSpan {
+	terms := make(map[string]bool)
+	for _, w := range words(text) {
+		term := strings.ToLower(w.text)
+		if !stopWords[term] {
+			terms[stem(term)] = true
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+
+	runes := []rune(content)
+	var spans []models.TextSpan
+	for _, w := range words(content) {
+		if !terms[stem(strings.ToLower(w.text))] {
+			continue
+		}
+		if n := len(spans); n > 0 && onlySpaces(runes[spans[n-1].End:w.start]) {
+			spans[n-1].End = w.end
+			continue
+		}
+		spans = append(spans, models.TextSpan{Start: w.start, End: w.end})
+	}
+	return spans
+}
+
+// LiteralSpans returns the spans of content containing the text, case-insensitively, as
+// matched by literal searches. Offsets are in characters, not bytes.
+func LiteralSpans(content, text string) []models.TextSpan {
+	needle := []rune(text)
+	if len(needle) == 0 {
+		return nil
+	}
+	runes := []rune(content)
+	var spans []models.TextSpan
+	for i := 0; i+len(needle) <= len(runes); {
+		if strings.EqualFold(string(runes[i:i+len(needle)]), text) {
+			spans = append(spans, models.TextSpan{Start: i, End: i + len(needle)})
+			i += len(needle)
+			continue
+		}
+		i++
+	}
+	return spans
+}
+
+type word struct {
+	text       string
+	start, end int
+}
+
+// words splits s into runs of letters and digits, with their character offsets.
+func words(s string) []word {
+	var result []word
+	start := -1
+	runes := []rune(s)
+	for i, r := range runes {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			result = append(result, word{string(runes[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		result = append(result, word{string(runes[start:]), start, len(runes)})
+	}
+	return result
+}
+
+func stem(w string) string {
+	for stripped := true; stripped; {
+		stripped = false
+		for _, suffix := range stemSuffixes {
+			// short words are left alone, so that "is" and "bus" aren't stemmed
+			if len(w) > len(suffix)+2 && strings.HasSuffix(w, suffix) {
+				w = strings.TrimSuffix(w, suffix)
+				stripped = true
+				break
+			}
+		}
+	}
+	return w
+}
+
+func onlySpaces(runes []rune) bool {
+	for _, r := range runes {
+		if r != ' ' {
+			return false
+		}
+	}
+	return true
+}
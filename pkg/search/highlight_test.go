@@ -0,0 +1,85 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestMatchedSpans(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		text    string
+		want    []models.TextSpan
+	}{
+		{
+			name:    "case insensitive",
+			content: "Who was Octavia Butler?",
+			text:    "octavia",
+			want:    []models.TextSpan{{Start: 8, End: 15}},
+		},
+		{
+			name:    "adjacent words merged",
+			content: "Who was Octavia Butler?",
+			text:    "butler octavia",
+			want:    []models.TextSpan{{Start: 8, End: 22}},
+		},
+		{
+			name:    "suffixes ignored",
+			content: "Embeddings are embedded by the embedder.",
+			text:    "embedding",
+			want:    []models.TextSpan{{Start: 0, End: 10}, {Start: 15, End: 23}},
+		},
+		{
+			name:    "stop words ignored",
+			content: "the book is on the table",
+			text:    "the table",
+			want:    []models.TextSpan{{Start: 19, End: 24}},
+		},
+		{
+			name:    "offsets in characters",
+			content: "café crème brûlée",
+			text:    "brûlée",
+			want:    []models.TextSpan{{Start: 11, End: 17}},
+		},
+		{
+			name:    "punctuation separates spans",
+			content: "Parable, Sower",
+			text:    "parable sower",
+			want:    []models.TextSpan{{Start: 0, End: 7}, {Start: 9, End: 14}},
+		},
+		{
+			name:    "no match",
+			content: "Kindred",
+			text:    "dawn",
+		},
+		{
+			name:    "only stop words",
+			content: "the",
+			text:    "the",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchedSpans(tt.content, tt.text))
+		})
+	}
+}
+
+func TestLiteralSpans(t *testing.T) {
+	assert.Equal(
+		t,
+		[]models.TextSpan{{Start: 9, End: 21}, {Start: 32, End: 44}},
+		LiteralSpans("I pasted Order #12345 and later order #12345.", "order #12345"),
+	)
+	assert.Equal(
+		t,
+		[]models.TextSpan{{Start: 5, End: 10}},
+		LiteralSpans("café CRÈME brûlée", "crème"),
+	)
+	assert.Nil(t, LiteralSpans("aaa", ""))
+	assert.Nil(t, LiteralSpans("Kindred", "kindred dawn"))
+}
@@ -0,0 +1,118 @@
+package search
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/viterin/vek/vek32"
+)
+
+// KMeans clusters embeddings into at most k clusters by cosine similarity (spherical
+// k-means). It returns a slice where the value at [i] is the cluster embedding i is
+// assigned to, and the unit length centroid of each cluster. Initial centroids are
+// chosen by farthest-point traversal starting from the first embedding, so the result
+// is deterministic for a given input order. Fewer than k clusters are returned if there
+// are fewer than k distinct embeddings.
+func KMeans(embeddingList [][]float32, k int, maxIterations int) ([]int, [][]float32, error) {
+	if len(embeddingList) == 0 || k <= 0 {
+		return []int{}, [][]float32{}, nil
+	}
+	width := len(embeddingList[0])
+	normalized := make([][]float32, len(embeddingList))
+	for i, embedding := range embeddingList {
+		if len(embedding) != width {
+			return nil, nil, fmt.Errorf(
+				"vector lengths do not match: %d != %d",
+				len(embedding),
+				width,
+			)
+		}
+		normalized[i] = unitVector(embedding)
+	}
+
+	centroids := initialCentroids(normalized, k)
+	assignments := make([]int, len(normalized))
+	for i := range assignments {
+		assignments[i] = -1
+	}
+
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		changed := false
+		for i, embedding := range normalized {
+			nearest := nearestCentroid(embedding, centroids)
+			if nearest != assignments[i] {
+				assignments[i] = nearest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sums := make([][]float32, len(centroids))
+		for i := range sums {
+			sums[i] = make([]float32, width)
+		}
+		for i, embedding := range normalized {
+			vek32.Add_Inplace(sums[assignments[i]], embedding)
+		}
+		for i, sum := range sums {
+			// an empty cluster keeps its previous centroid
+			if vek32.Norm(sum) > 0 {
+				centroids[i] = unitVector(sum)
+			}
+		}
+	}
+
+	return assignments, centroids, nil
+}
+
+// initialCentroids picks up to k embeddings, each the least similar to those already
+// picked. Embeddings identical to a picked one are never picked again.
+func initialCentroids(embeddingList [][]float32, k int) [][]float32 {
+	centroids := [][]float32{embeddingList[0]}
+	// maxSimilarity[i] is the similarity of embedding i to its nearest centroid
+	maxSimilarity := make([]float32, len(embeddingList))
+	for i, embedding := range embeddingList {
+		maxSimilarity[i] = vek32.Dot(embedding, centroids[0])
+	}
+
+	for len(centroids) < k {
+		next := -1
+		lowest := float32(math.Inf(1))
+		for i, similarity := range maxSimilarity {
+			if similarity < lowest {
+				next, lowest = i, similarity
+			}
+		}
+		if next == -1 || lowest >= 1-1e-6 {
+			break
+		}
+		centroids = append(centroids, embeddingList[next])
+		for i, embedding := range embeddingList {
+			maxSimilarity[i] = max(maxSimilarity[i], vek32.Dot(embedding, embeddingList[next]))
+		}
+	}
+
+	return centroids
+}
+
+func nearestCentroid(embedding []float32, centroids [][]float32) int {
+	nearest := 0
+	highest := float32(math.Inf(-1))
+	for i, centroid := range centroids {
+		if similarity := vek32.Dot(embedding, centroid); similarity > highest {
+			nearest, highest = i, similarity
+		}
+	}
+	return nearest
+}
+
+// unitVector returns a copy of v scaled to unit length. Zero vectors are returned as is.
+func unitVector(v []float32) []float32 {
+	norm := vek32.Norm(v)
+	if norm == 0 {
+		return append([]float32(nil), v...)
+	}
+	return vek32.DivNumber(v, norm)
+}
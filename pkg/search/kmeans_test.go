@@ -0,0 +1,50 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKMeans(t *testing.T) {
+	t.Run("MismatchedVectorWidths", func(t *testing.T) {
+		_, _, err := KMeans([][]float32{{0.1, 0.2}, {0.1, 0.2, 0.3}}, 2, 10)
+		assert.Error(t, err)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		assignments, centroids, err := KMeans(nil, 3, 10)
+		require.NoError(t, err)
+		assert.Empty(t, assignments)
+		assert.Empty(t, centroids)
+	})
+
+	t.Run("Clusters", func(t *testing.T) {
+		embeddingList := [][]float32{
+			{1.0, 0.1, 0.0},
+			{0.0, 1.0, 0.1},
+			{2.0, 0.0, 0.1},
+			{0.1, 0.0, 1.0},
+			{0.1, 3.0, 0.0},
+			{0.0, 0.1, 0.9},
+		}
+		assignments, centroids, err := KMeans(embeddingList, 3, 10)
+		require.NoError(t, err)
+		require.Len(t, centroids, 3)
+		assert.Equal(t, assignments[0], assignments[2])
+		assert.Equal(t, assignments[1], assignments[4])
+		assert.Equal(t, assignments[3], assignments[5])
+		assert.NotEqual(t, assignments[0], assignments[1])
+		assert.NotEqual(t, assignments[0], assignments[3])
+		assert.NotEqual(t, assignments[1], assignments[3])
+	})
+
+	t.Run("FewerDistinctThanK", func(t *testing.T) {
+		embeddingList := [][]float32{{1.0, 0.0}, {2.0, 0.0}, {0.0, 1.0}}
+		assignments, centroids, err := KMeans(embeddingList, 5, 10)
+		require.NoError(t, err)
+		assert.Len(t, centroids, 2)
+		assert.Equal(t, []int{0, 0, 1}, assignments)
+	})
+}
@@ -0,0 +1,54 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// MergeCollectionResults merges the result pages of searches of the named collections,
+// ordered by score, into at most limit results. Scores are min-max normalized among the
+// results of each collection, as the raw scores of collections of different embedding
+// models or search types aren't comparable: each collection's best result scores 1 and
+// its worst 0. A collection's only result, or results of equal scores, score 1. Ties are
+// broken by raw score, then by the order of the collections.
+func MergeCollectionResults(
+	collectionNames []string,
+	pages []*models.DocumentSearchResultPage,
+	limit int,
+) []models.MultiCollectionSearchResult {
+	merged := make([]models.MultiCollectionSearchResult, 0)
+	for i, page := range pages {
+		if page == nil || len(page.Results) == 0 {
+			continue
+		}
+		lowest, highest := page.Results[0].Score, page.Results[0].Score
+		for _, result := range page.Results {
+			lowest = min(lowest, result.Score)
+			highest = max(highest, result.Score)
+		}
+		for _, result := range page.Results {
+			normalized := models.MultiCollectionSearchResult{
+				DocumentSearchResult: result,
+				CollectionName:       collectionNames[i],
+				RawScore:             result.Score,
+			}
+			normalized.Score = 1
+			if highest > lowest {
+				normalized.Score = (result.Score - lowest) / (highest - lowest)
+			}
+			merged = append(merged, normalized)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Score != merged[j].Score {
+			return merged[i].Score > merged[j].Score
+		}
+		return merged[i].RawScore > merged[j].RawScore
+	})
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
@@ -0,0 +1,54 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestMergeCollectionResults(t *testing.T) {
+	page := func(scores ...float64) *models.DocumentSearchResultPage {
+		p := &models.DocumentSearchResultPage{}
+		for _, score := range scores {
+			p.Results = append(p.Results, models.DocumentSearchResult{
+				DocumentResponse: &models.DocumentResponse{UUID: uuid.New()},
+				Score:            score,
+			})
+		}
+		return p
+	}
+	pages := []*models.DocumentSearchResultPage{
+		page(0.9, 0.8, 0.7),
+		page(0.5, 0.3),
+		page(),
+		page(0.6),
+	}
+	names := []string{"a", "b", "c", "d"}
+
+	merged := MergeCollectionResults(names, pages, 0)
+	assert.InDeltaSlice(t, []float64{1, 1, 1, 0.5, 0, 0}, scores(merged), 1e-9)
+	assert.Equal(t, []string{"a", "d", "b", "a", "a", "b"}, collections(merged))
+	assert.Equal(t, 0.7, merged[4].RawScore)
+
+	assert.Len(t, MergeCollectionResults(names, pages, 2), 2)
+	assert.Empty(t, MergeCollectionResults(names[2:3], pages[2:3], 10))
+}
+
+func scores(results []models.MultiCollectionSearchResult) []float64 {
+	s := make([]float64, len(results))
+	for i, r := range results {
+		s[i] = r.Score
+	}
+	return s
+}
+
+func collections(results []models.MultiCollectionSearchResult) []string {
+	s := make([]string, len(results))
+	for i, r := range results {
+		s[i] = r.CollectionName
+	}
+	return s
+}
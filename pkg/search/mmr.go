@@ -50,14 +50,29 @@ func MaximalMarginalRelevance(
 	lambdaMult float32,
 	k int,
 ) ([]int, error) {
+	idxs, _, err := MaximalMarginalRelevanceScores(queryEmbedding, embeddingList, lambdaMult, k)
+	return idxs, err
+}
+
+// MaximalMarginalRelevanceScores is MaximalMarginalRelevance, also returning the marginal
+// relevance of each embedding when it was selected. The first embedding, having nothing
+// selected before it, scores lambdaMult times its similarity to the query.
+func MaximalMarginalRelevanceScores(
+	queryEmbedding []float32,
+	embeddingList [][]float32,
+	lambdaMult float32,
+	k int,
+) ([]int, []float32, error) {
 	// if either k or the length of the embedding list is 0, return an empty list
 	if min(k, len(embeddingList)) <= 0 {
-		return []int{}, nil
+		return []int{}, []float32{}, nil
 	}
 
 	// We expect the query embedding and the embeddings in the list to have the same width
 	if len(queryEmbedding) != len(embeddingList[0]) {
-		return []int{}, errors.New("query embedding width does not match embedding vector width")
+		return []int{}, []float32{}, errors.New(
+			"query embedding width does not match embedding vector width",
+		)
 	}
 
 	similarityToQueryMatrix, err := pairwiseCosineSimilarity(
@@ -65,12 +80,13 @@ func MaximalMarginalRelevance(
 		embeddingList,
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	similarityToQuery := similarityToQueryMatrix[0]
 
 	mostSimilar := vek32.ArgMax(similarityToQuery)
 	idxs := []int{mostSimilar}
+	scores := []float32{lambdaMult * similarityToQuery[mostSimilar]}
 	selected := [][]float32{embeddingList[mostSimilar]}
 
 	for len(idxs) < min(k, len(embeddingList)) {
@@ -78,7 +94,7 @@ func MaximalMarginalRelevance(
 		idxToAdd := -1
 		similarityToSelected, err := pairwiseCosineSimilarity(embeddingList, selected)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		for i, queryScore := range similarityToQuery {
@@ -93,9 +109,10 @@ func MaximalMarginalRelevance(
 			}
 		}
 		idxs = append(idxs, idxToAdd)
+		scores = append(scores, bestScore)
 		selected = append(selected, embeddingList[idxToAdd])
 	}
-	return idxs, nil
+	return idxs, scores, nil
 }
 
 // contains returns true if the slice contains the value
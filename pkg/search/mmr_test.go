@@ -47,4 +47,19 @@ func TestMaximalMarginalRelevance(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, expected, result)
 	})
+
+	// Test case for the marginal relevance of each selection
+	t.Run("Scores", func(t *testing.T) {
+		queryEmbedding := []float32{1, 0}
+		embeddingList := [][]float32{
+			{1, 0},
+			{1, 0},
+			{0.8, 0.6},
+		}
+		idxs, scores, err := MaximalMarginalRelevanceScores(queryEmbedding, embeddingList, 0.3, 2)
+		assert.NoError(t, err)
+		// the duplicate of the first selection is penalized below the less similar embedding
+		assert.Equal(t, []int{0, 2}, idxs)
+		assert.InDeltaSlice(t, []float32{0.3, 0.3*0.8 - 0.7*0.8}, scores, 1e-6)
+	})
 }
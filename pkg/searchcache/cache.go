@@ -0,0 +1,217 @@
+// Package searchcache caches the results of memory and document searches, so that
+// repeated identical searches, as made by chat UIs when re-rendering, don't reach the
+// database or the embedding service.
+package searchcache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	DefaultTTL        = 10 * time.Second
+	DefaultMaxEntries = 1000
+
+	// maxVersionsPerEntry bounds the number of scope versions tracked, relative to
+	// MaxEntries, before the cache is reset.
+	maxVersionsPerEntry = 10
+)
+
+var log = internal.GetLogger()
+
+// Cache holds search results keyed on the search's scope, the scope's version, and a hash
+// of the query. Invalidating a scope increments its version, so results cached for the
+// previous version are never returned, even those of searches in flight.
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru orders entries from the most to the least recently used.
+	lru *list.List
+	// versions holds the version of each scope that has been invalidated.
+	versions map[string]uint64
+	// counter is the last version assigned. Versions are unique across scopes.
+	counter uint64
+	// generation is incremented when every scope is invalidated.
+	generation uint64
+}
+
+type entry struct {
+	key     string
+	value   any
+	expires time.Time
+}
+
+// New returns a Cache of at most maxEntries results, each cached for ttl. Zero values
+// use the defaults.
+func New(ttl time.Duration, maxEntries int) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		versions:   make(map[string]uint64),
+	}
+}
+
+// Wrap replaces the memory, document, and user stores of appState with wrappers caching
+// search results, if the search cache is enabled.
+func Wrap(appState *models.AppState) {
+	cfg := appState.Config.SearchCache
+	if !cfg.Enabled {
+		return
+	}
+	c := New(time.Duration(cfg.TTL)*time.Second, cfg.MaxEntries)
+	log.Infof("search cache enabled: ttl=%s max_entries=%d", c.ttl, c.maxEntries)
+
+	if appState.MemoryStore != nil {
+		appState.MemoryStore = NewMemoryStore(appState.MemoryStore, c)
+	}
+	if appState.DocumentStore != nil {
+		appState.DocumentStore = NewDocumentStore(appState.DocumentStore, c)
+	}
+	if appState.UserStore != nil {
+		appState.UserStore = NewUserStore(appState.UserStore, c)
+	}
+}
+
+// Key returns the key of a query of the scope. It must be taken before the search is
+// made, so that a write during the search invalidates its result.
+func (c *Cache) Key(scope string, query any) (string, error) {
+	b, err := json.Marshal(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal search query: %w", err)
+	}
+	hash := sha256.Sum256(b)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fmt.Sprintf("%d/%d/%s/%x", c.generation, c.versions[scope], scope, hash), nil
+}
+
+// Get returns the result cached at key, if it hasn't expired.
+func (c *Cache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if c.now().After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e.value, true
+}
+
+// Set caches value at key, evicting the least recently used result if the cache is full.
+func (c *Cache) Set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry)
+		e.value, e.expires = value, expires
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&entry{key: key, value: value, expires: expires})
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Invalidate discards the results cached for the scope.
+func (c *Cache) Invalidate(scope string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.versions) >= c.maxEntries*maxVersionsPerEntry {
+		c.reset()
+		return
+	}
+	c.counter++
+	c.versions[scope] = c.counter
+}
+
+// InvalidateAll discards every cached result, as after writes to many sessions.
+func (c *Cache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reset()
+}
+
+// Len returns the number of cached results, including those expired but not yet evicted.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *Cache) reset() {
+	c.generation++
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.versions = make(map[string]uint64)
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*entry).key)
+}
+
+func sessionScope(sessionID string) string {
+	return "session:" + sessionID
+}
+
+// collectionScope lower-cases collectionName, as collection names are case-insensitive.
+func collectionScope(collectionName string) string {
+	return "collection:" + strings.ToLower(collectionName)
+}
+
+// search returns the cached result of the query of the scope, or searches and caches the
+// result. Errors are not cached. Debugged searches bypass the cache, as their debug
+// information is only collected when the search is made.
+func search[T any](
+	ctx context.Context,
+	c *Cache,
+	scope string,
+	query any,
+	fn func() (T, error),
+) (T, error) {
+	if models.SearchDebugFromContext(ctx) != nil {
+		return fn()
+	}
+	key, err := c.Key(scope, query)
+	if err != nil {
+		return fn()
+	}
+	if v, ok := c.Get(key); ok {
+		return v.(T), nil
+	}
+	result, err := fn()
+	if err == nil {
+		c.Set(key, result)
+	}
+	return result, err
+}
@@ -0,0 +1,191 @@
+package searchcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// fakeMemoryStore counts searches. Only the methods called by the tests are implemented.
+type fakeMemoryStore struct {
+	models.MemoryStore[any]
+	searches int
+	err      error
+}
+
+func (s *fakeMemoryStore) SearchMemory(
+	_ context.Context,
+	_ string,
+	query *models.MemorySearchPayload,
+	_ int,
+) ([]models.MemorySearchResult, error) {
+	s.searches++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []models.MemorySearchResult{{Summary: &models.Summary{Content: query.Text}}}, nil
+}
+
+func (s *fakeMemoryStore) PutMemory(context.Context, string, *models.Memory, bool) error {
+	return nil
+}
+
+func (s *fakeMemoryStore) PurgeDeleted(context.Context) error {
+	return nil
+}
+
+type fakeDocumentStore struct {
+	models.DocumentStore[any]
+	searches int
+}
+
+func (s *fakeDocumentStore) SearchCollection(
+	_ context.Context,
+	_ *models.DocumentSearchPayload,
+	_, _, _ int,
+) (*models.DocumentSearchResultPage, error) {
+	s.searches++
+	return &models.DocumentSearchResultPage{ResultCount: s.searches}, nil
+}
+
+func (s *fakeDocumentStore) DeleteDocuments(context.Context, string, []uuid.UUID) error {
+	return nil
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeMemoryStore{}
+	store := NewMemoryStore(fake, New(time.Minute, 0))
+	query := &models.MemorySearchPayload{Text: "pizza"}
+
+	search := func(sessionID string, query *models.MemorySearchPayload, limit int) {
+		t.Helper()
+		results, err := store.SearchMemory(ctx, sessionID, query, limit)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, query.Text, results[0].Summary.Content)
+	}
+
+	search("s1", query, 10)
+	search("s1", &models.MemorySearchPayload{Text: "pizza"}, 10)
+	assert.Equal(t, 1, fake.searches)
+
+	// a different query, limit, or session isn't cached
+	search("s1", &models.MemorySearchPayload{Text: "pasta"}, 10)
+	search("s1", query, 5)
+	search("s2", query, 10)
+	assert.Equal(t, 4, fake.searches)
+
+	// writing to a session invalidates only its results
+	require.NoError(t, store.PutMemory(ctx, "s1", &models.Memory{}, false))
+	search("s1", query, 10)
+	search("s2", query, 10)
+	assert.Equal(t, 5, fake.searches)
+
+	require.NoError(t, store.PurgeDeleted(ctx))
+	search("s2", query, 10)
+	assert.Equal(t, 6, fake.searches)
+
+	// debugged searches bypass the cache
+	debugCtx := models.WithSearchDebug(ctx, &models.SearchDebug{})
+	_, err := store.SearchMemory(debugCtx, "s2", query, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 7, fake.searches)
+
+	// errors aren't cached
+	fake.err = errors.New("search failed")
+	query = &models.MemorySearchPayload{Text: "salad"}
+	for i := 0; i < 2; i++ {
+		_, err := store.SearchMemory(ctx, "s1", query, 10)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 9, fake.searches)
+}
+
+func TestDocumentStore(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeDocumentStore{}
+	store := NewDocumentStore(fake, New(time.Minute, 0))
+
+	search := func(collectionName string, pageNumber int) int {
+		t.Helper()
+		page, err := store.SearchCollection(
+			ctx,
+			&models.DocumentSearchPayload{CollectionName: collectionName, Text: "pizza"},
+			10,
+			pageNumber,
+			5,
+		)
+		require.NoError(t, err)
+		return page.ResultCount
+	}
+
+	assert.Equal(t, 1, search("docs", 1))
+	assert.Equal(t, 1, search("docs", 1))
+	assert.Equal(t, 2, search("docs", 2))
+
+	require.NoError(t, store.DeleteDocuments(ctx, "Docs", nil))
+	assert.Equal(t, 3, search("docs", 1))
+	assert.Equal(t, 3, fake.searches)
+}
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	c := New(time.Minute, 2)
+	c.now = func() time.Time { return now }
+
+	key := func(query string) string {
+		k, err := c.Key("scope", query)
+		require.NoError(t, err)
+		return k
+	}
+
+	t.Run("expiry", func(t *testing.T) {
+		c.Set(key("a"), 1)
+		v, ok := c.Get(key("a"))
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+
+		now = now.Add(time.Minute + time.Second)
+		_, ok = c.Get(key("a"))
+		assert.False(t, ok)
+		assert.Zero(t, c.Len())
+	})
+
+	t.Run("least recently used results are evicted", func(t *testing.T) {
+		c.Set(key("a"), 1)
+		c.Set(key("b"), 2)
+		_, _ = c.Get(key("a"))
+		c.Set(key("c"), 3)
+		assert.Equal(t, 2, c.Len())
+		_, ok := c.Get(key("b"))
+		assert.False(t, ok)
+		_, ok = c.Get(key("a"))
+		assert.True(t, ok)
+	})
+
+	t.Run("results of searches in flight during a write are not returned", func(t *testing.T) {
+		before := key("d")
+		c.Invalidate("scope")
+		c.Set(before, 4)
+		_, ok := c.Get(key("d"))
+		assert.False(t, ok)
+	})
+
+	t.Run("versions are bounded", func(t *testing.T) {
+		c.Set(key("e"), 5)
+		for i := 0; i < 2*maxVersionsPerEntry; i++ {
+			c.Invalidate(uuid.NewString())
+		}
+		assert.LessOrEqual(t, len(c.versions), 2*maxVersionsPerEntry)
+		_, ok := c.Get(key("e"))
+		assert.False(t, ok)
+	})
+}
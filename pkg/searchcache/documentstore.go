@@ -0,0 +1,123 @@
+package searchcache
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.DocumentStore[any] = &DocumentStore{}
+
+// DocumentStore caches the results of a models.DocumentStore's SearchCollection. Writes to
+// a collection or its documents invalidate its cached results. Cached results are shared,
+// so callers must not modify them.
+type DocumentStore struct {
+	models.DocumentStore[any]
+	cache *Cache
+}
+
+// NewDocumentStore wraps documentStore with a DocumentStore caching search results in
+// cache.
+func NewDocumentStore(documentStore models.DocumentStore[any], cache *Cache) *DocumentStore {
+	return &DocumentStore{DocumentStore: documentStore, cache: cache}
+}
+
+type documentSearchQuery struct {
+	Query      *models.DocumentSearchPayload `json:"query"`
+	Limit      int                           `json:"limit"`
+	PageNumber int                           `json:"page_number"`
+	PageSize   int                           `json:"page_size"`
+}
+
+func (s *DocumentStore) SearchCollection(
+	ctx context.Context,
+	query *models.DocumentSearchPayload,
+	limit int,
+	pageNumber int,
+	pageSize int,
+) (*models.DocumentSearchResultPage, error) {
+	return search(
+		ctx,
+		s.cache,
+		collectionScope(query.CollectionName),
+		documentSearchQuery{
+			Query:      query,
+			Limit:      limit,
+			PageNumber: pageNumber,
+			PageSize:   pageSize,
+		},
+		func() (*models.DocumentSearchResultPage, error) {
+			return s.DocumentStore.SearchCollection(ctx, query, limit, pageNumber, pageSize)
+		},
+	)
+}
+
+func (s *DocumentStore) CreateCollection(
+	ctx context.Context,
+	collection models.DocumentCollection,
+) error {
+	defer s.cache.Invalidate(collectionScope(collection.Name))
+	return s.DocumentStore.CreateCollection(ctx, collection)
+}
+
+func (s *DocumentStore) UpdateCollection(
+	ctx context.Context,
+	collection models.DocumentCollection,
+) error {
+	defer s.cache.Invalidate(collectionScope(collection.Name))
+	return s.DocumentStore.UpdateCollection(ctx, collection)
+}
+
+func (s *DocumentStore) DeleteCollection(ctx context.Context, collectionName string) error {
+	defer s.cache.Invalidate(collectionScope(collectionName))
+	return s.DocumentStore.DeleteCollection(ctx, collectionName)
+}
+
+func (s *DocumentStore) CreateDocuments(
+	ctx context.Context,
+	collectionName string,
+	documents []models.Document,
+) ([]uuid.UUID, error) {
+	defer s.cache.Invalidate(collectionScope(collectionName))
+	return s.DocumentStore.CreateDocuments(ctx, collectionName, documents)
+}
+
+func (s *DocumentStore) IngestDocuments(
+	ctx context.Context,
+	collectionName string,
+	documents []models.Document,
+) (*models.DocumentIngestResponse, error) {
+	defer s.cache.Invalidate(collectionScope(collectionName))
+	return s.DocumentStore.IngestDocuments(ctx, collectionName, documents)
+}
+
+func (s *DocumentStore) UpdateDocuments(
+	ctx context.Context,
+	collectionName string,
+	documents []models.Document,
+) error {
+	defer s.cache.Invalidate(collectionScope(collectionName))
+	return s.DocumentStore.UpdateDocuments(ctx, collectionName, documents)
+}
+
+func (s *DocumentStore) DeleteDocuments(
+	ctx context.Context,
+	collectionName string,
+	documentUUIDs []uuid.UUID,
+) error {
+	defer s.cache.Invalidate(collectionScope(collectionName))
+	return s.DocumentStore.DeleteDocuments(ctx, collectionName, documentUUIDs)
+}
+
+// CreateCollectionIndex invalidates the collection's results, as approximate indexes may
+// return different results.
+func (s *DocumentStore) CreateCollectionIndex(
+	ctx context.Context,
+	collectionName string,
+	force bool,
+) error {
+	defer s.cache.Invalidate(collectionScope(collectionName))
+	return s.DocumentStore.CreateCollectionIndex(ctx, collectionName, force)
+}
@@ -0,0 +1,176 @@
+package searchcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.MemoryStore[any] = &MemoryStore{}
+
+// MemoryStore caches the results of a models.MemoryStore's SearchMemory. Writes to a
+// session's messages or summaries invalidate its cached results. Cached results are
+// shared, so callers must not modify them.
+type MemoryStore struct {
+	models.MemoryStore[any]
+	cache *Cache
+}
+
+// NewMemoryStore wraps memoryStore with a MemoryStore caching search results in cache.
+func NewMemoryStore(memoryStore models.MemoryStore[any], cache *Cache) *MemoryStore {
+	return &MemoryStore{MemoryStore: memoryStore, cache: cache}
+}
+
+type memorySearchQuery struct {
+	Query *models.MemorySearchPayload `json:"query"`
+	Limit int                         `json:"limit"`
+}
+
+func (s *MemoryStore) SearchMemory(
+	ctx context.Context,
+	sessionID string,
+	query *models.MemorySearchPayload,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	return search(
+		ctx,
+		s.cache,
+		sessionScope(sessionID),
+		memorySearchQuery{Query: query, Limit: limit},
+		func() ([]models.MemorySearchResult, error) {
+			return s.MemoryStore.SearchMemory(ctx, sessionID, query, limit)
+		},
+	)
+}
+
+func (s *MemoryStore) UpdateSession(
+	ctx context.Context,
+	session *models.UpdateSessionRequest,
+) (*models.Session, error) {
+	defer s.cache.Invalidate(sessionScope(session.SessionID))
+	return s.MemoryStore.UpdateSession(ctx, session)
+}
+
+func (s *MemoryStore) PatchSessionMetadata(
+	ctx context.Context,
+	sessionID string,
+	patch models.MetadataPatch,
+) (*models.Session, error) {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.PatchSessionMetadata(ctx, sessionID, patch)
+}
+
+func (s *MemoryStore) DeleteSession(ctx context.Context, sessionID string) error {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.DeleteSession(ctx, sessionID)
+}
+
+func (s *MemoryStore) DeleteExpiredSessions(ctx context.Context) ([]*models.Session, error) {
+	defer s.cache.InvalidateAll()
+	return s.MemoryStore.DeleteExpiredSessions(ctx)
+}
+
+func (s *MemoryStore) PutMemory(
+	ctx context.Context,
+	sessionID string,
+	memoryMessages *models.Memory,
+	skipNotify bool,
+) error {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.PutMemory(ctx, sessionID, memoryMessages, skipNotify)
+}
+
+func (s *MemoryStore) UpdateMessages(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+	isPrivileged bool,
+	includeContent bool,
+) error {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.UpdateMessages(ctx, sessionID, messages, isPrivileged, includeContent)
+}
+
+func (s *MemoryStore) CreateMessageEmbeddings(
+	ctx context.Context,
+	sessionID string,
+	embeddings []models.TextData,
+) error {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.CreateMessageEmbeddings(ctx, sessionID, embeddings)
+}
+
+func (s *MemoryStore) CompactMessages(
+	ctx context.Context,
+	sessionID string,
+	throughUUID uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.CompactMessages(ctx, sessionID, throughUUID, retainEmbeddings)
+}
+
+func (s *MemoryStore) DeleteMatchingMessages(
+	ctx context.Context,
+	sessionID string,
+	filter *models.MessageFilter,
+) (int, error) {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.DeleteMatchingMessages(ctx, sessionID, filter)
+}
+
+func (s *MemoryStore) PruneMessages(
+	ctx context.Context,
+	sessionID string,
+	messageUUIDs []uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.PruneMessages(ctx, sessionID, messageUUIDs, retainEmbeddings)
+}
+
+func (s *MemoryStore) CreateSummary(
+	ctx context.Context,
+	sessionID string,
+	summary *models.Summary,
+) error {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.CreateSummary(ctx, sessionID, summary)
+}
+
+func (s *MemoryStore) UpdateSummary(
+	ctx context.Context,
+	sessionID string,
+	summary *models.Summary,
+	includeContent bool,
+) error {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.UpdateSummary(ctx, sessionID, summary, includeContent)
+}
+
+func (s *MemoryStore) PutSummaryEmbedding(
+	ctx context.Context,
+	sessionID string,
+	embedding *models.TextData,
+) error {
+	defer s.cache.Invalidate(sessionScope(sessionID))
+	return s.MemoryStore.PutSummaryEmbedding(ctx, sessionID, embedding)
+}
+
+func (s *MemoryStore) PurgeDeleted(ctx context.Context) error {
+	defer s.cache.InvalidateAll()
+	return s.MemoryStore.PurgeDeleted(ctx)
+}
+
+func (s *MemoryStore) DeduplicateMessageEmbeddings(
+	ctx context.Context,
+	since time.Time,
+	threshold float32,
+	scope models.DeduplicationScope,
+) (int, error) {
+	defer s.cache.InvalidateAll()
+	return s.MemoryStore.DeduplicateMessageEmbeddings(ctx, since, threshold, scope)
+}
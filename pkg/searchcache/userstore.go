@@ -0,0 +1,36 @@
+package searchcache
+
+import (
+	"context"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.UserStore = &UserStore{}
+
+// UserStore invalidates the search cache when a user, and so their sessions, is deleted or
+// anonymized.
+type UserStore struct {
+	models.UserStore
+	cache *Cache
+}
+
+// NewUserStore wraps userStore with a UserStore invalidating cache.
+func NewUserStore(userStore models.UserStore, cache *Cache) *UserStore {
+	return &UserStore{UserStore: userStore, cache: cache}
+}
+
+func (s *UserStore) Delete(ctx context.Context, userID string) error {
+	defer s.cache.InvalidateAll()
+	return s.UserStore.Delete(ctx, userID)
+}
+
+func (s *UserStore) Anonymize(
+	ctx context.Context,
+	userID string,
+	anonymousID string,
+	metadata map[string]interface{},
+) (*models.User, error) {
+	defer s.cache.InvalidateAll()
+	return s.UserStore.Anonymize(ctx, userID, anonymousID, metadata)
+}
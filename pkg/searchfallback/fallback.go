@@ -0,0 +1,76 @@
+// Package searchfallback relaxes the filters of searches returning no results, so that
+// they can be retried with a query more likely to match.
+package searchfallback
+
+import (
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	// Header is the response header naming the fallback a search's results were found with.
+	Header = "X-Zep-Search-Fallback"
+
+	// WidenDates drops the start_date and end_date filters of a memory search.
+	WidenDates = "widen_dates"
+	// DropMetadata drops all metadata filters of a search, and a memory search's episode.
+	DropMetadata = "drop_metadata"
+)
+
+// MemoryFallback is a relaxed memory search.
+type MemoryFallback struct {
+	Name  string
+	Query *models.MemorySearchPayload
+}
+
+// DocumentFallback is a relaxed document search.
+type DocumentFallback struct {
+	Name  string
+	Query *models.DocumentSearchPayload
+}
+
+// Memory returns the fallbacks of a memory search, from the least to the most relaxed.
+// Each fallback relaxes a filter left by the previous fallback, and none leave a search
+// without text or filters.
+func Memory(query *models.MemorySearchPayload) []MemoryFallback {
+	var fallbacks []MemoryFallback
+
+	_, hasStart := query.Metadata["start_date"]
+	_, hasEnd := query.Metadata["end_date"]
+	relaxed := *query
+	if hasStart || hasEnd {
+		relaxed.Metadata = nil
+		for k, v := range query.Metadata {
+			if k == "start_date" || k == "end_date" {
+				continue
+			}
+			if relaxed.Metadata == nil {
+				relaxed.Metadata = make(map[string]interface{}, len(query.Metadata))
+			}
+			relaxed.Metadata[k] = v
+		}
+		if relaxed.Text != "" || len(relaxed.Metadata) > 0 {
+			widened := relaxed
+			fallbacks = append(fallbacks, MemoryFallback{Name: WidenDates, Query: &widened})
+		}
+	}
+
+	if relaxed.Text != "" && (len(relaxed.Metadata) > 0 || relaxed.Episode != 0) {
+		dropped := relaxed
+		dropped.Metadata = nil
+		dropped.Episode = 0
+		fallbacks = append(fallbacks, MemoryFallback{Name: DropMetadata, Query: &dropped})
+	}
+	return fallbacks
+}
+
+// Documents returns the fallbacks of a document search, from the least to the most
+// relaxed. Document searches only filter by metadata, which is dropped if the search has
+// text or an embedding to search by.
+func Documents(query *models.DocumentSearchPayload) []DocumentFallback {
+	if len(query.Metadata) == 0 || (query.Text == "" && len(query.Embedding) == 0) {
+		return nil
+	}
+	dropped := *query
+	dropped.Metadata = nil
+	return []DocumentFallback{{Name: DropMetadata, Query: &dropped}}
+}
@@ -0,0 +1,106 @@
+package searchfallback
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestMemory(t *testing.T) {
+	where := map[string]interface{}{"jsonpath": `$.system.entities[*] ? (@.Label == "ORG")`}
+	tests := []struct {
+		name  string
+		query models.MemorySearchPayload
+		want  map[string]*models.MemorySearchPayload
+		names []string
+	}{
+		{
+			name: "dates and where",
+			query: models.MemorySearchPayload{
+				Text: "invoice",
+				Metadata: map[string]interface{}{
+					"start_date": "2024-01-01",
+					"end_date":   "2024-01-31",
+					"where":      where,
+				},
+			},
+			names: []string{WidenDates, DropMetadata},
+			want: map[string]*models.MemorySearchPayload{
+				WidenDates: {
+					Text:     "invoice",
+					Metadata: map[string]interface{}{"where": where},
+				},
+				DropMetadata: {Text: "invoice"},
+			},
+		},
+		{
+			// widening the dates drops every filter
+			name: "dates only",
+			query: models.MemorySearchPayload{
+				Text:     "invoice",
+				Metadata: map[string]interface{}{"start_date": "2024-01-01"},
+			},
+			names: []string{WidenDates},
+			want: map[string]*models.MemorySearchPayload{
+				WidenDates: {Text: "invoice"},
+			},
+		},
+		{
+			name:  "episode",
+			query: models.MemorySearchPayload{Text: "invoice", Episode: 2},
+			names: []string{DropMetadata},
+			want: map[string]*models.MemorySearchPayload{
+				DropMetadata: {Text: "invoice"},
+			},
+		},
+		{
+			// without text, a search without filters has nothing to search by
+			name: "metadata only",
+			query: models.MemorySearchPayload{
+				Metadata: map[string]interface{}{"where": where, "end_date": "2024-01-31"},
+			},
+			names: []string{WidenDates},
+			want: map[string]*models.MemorySearchPayload{
+				WidenDates: {Metadata: map[string]interface{}{"where": where}},
+			},
+		},
+		{
+			name:  "no filters",
+			query: models.MemorySearchPayload{Text: "invoice"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fallbacks := Memory(&tt.query)
+			var names []string
+			for _, fallback := range fallbacks {
+				names = append(names, fallback.Name)
+				assert.Equal(t, tt.want[fallback.Name], fallback.Query, fallback.Name)
+			}
+			assert.Equal(t, tt.names, names)
+		})
+	}
+}
+
+func TestDocuments(t *testing.T) {
+	query := &models.DocumentSearchPayload{
+		CollectionName: "docs",
+		Text:           "refunds",
+		Metadata:       map[string]interface{}{"where": map[string]interface{}{"jsonpath": "$.a"}},
+	}
+	fallbacks := Documents(query)
+	if assert.Len(t, fallbacks, 1) {
+		assert.Equal(t, DropMetadata, fallbacks[0].Name)
+		assert.Equal(
+			t,
+			&models.DocumentSearchPayload{CollectionName: "docs", Text: "refunds"},
+			fallbacks[0].Query,
+		)
+	}
+	assert.NotNil(t, query.Metadata)
+
+	assert.Empty(t, Documents(&models.DocumentSearchPayload{Text: "refunds"}))
+	assert.Empty(t, Documents(&models.DocumentSearchPayload{Metadata: query.Metadata}))
+}
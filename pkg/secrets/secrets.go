@@ -0,0 +1,74 @@
+// Package secrets encrypts secrets stored in the database, such as the API keys of
+// provider overrides, with AES-256-GCM.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size of encryption keys, in bytes.
+const KeySize = 32
+
+// ErrDecrypt is returned when a secret can't be decrypted, because it was encrypted with
+// another key or modified.
+var ErrDecrypt = errors.New("failed to decrypt secret")
+
+// Box encrypts and decrypts secrets with a key.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox returns a Box encrypting with a KeySize-byte key.
+func NewBox(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, not %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// NewBoxFromBase64 returns a Box encrypting with a base64-encoded key, as set in the
+// config.
+func NewBoxFromBase64(encoded string) (*Box, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key isn't valid base64: %w", err)
+	}
+	return NewBox(key)
+}
+
+// Seal encrypts plaintext, binding it to associatedData, such as the ID of the record
+// holding it, so that it can't be decrypted as another record's. The random nonce
+// prefixes the returned ciphertext.
+func (b *Box) Seal(plaintext, associatedData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+// Open decrypts a ciphertext returned by Seal with the same associatedData.
+func (b *Box) Open(ciphertext, associatedData []byte) ([]byte, error) {
+	if len(ciphertext) < b.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, sealed := ciphertext[:b.aead.NonceSize()], ciphertext[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, sealed, associatedData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,53 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBox(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, KeySize)
+	box, err := NewBoxFromBase64(base64.StdEncoding.EncodeToString(key))
+	require.NoError(t, err)
+
+	plaintext := []byte(`{"api_key": "sk-secret"}`)
+	sealed, err := box.Seal(plaintext, []byte("acme"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "sk-secret")
+
+	// each seal has its own nonce
+	again, err := box.Seal(plaintext, []byte("acme"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+
+	opened, err := box.Open(sealed, []byte("acme"))
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	// the secret is bound to its associated data
+	_, err = box.Open(sealed, []byte("globex"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+	_, err = box.Open(tampered, []byte("acme"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+	_, err = box.Open(sealed[:4], []byte("acme"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	other, err := NewBox(bytes.Repeat([]byte{8}, KeySize))
+	require.NoError(t, err)
+	_, err = other.Open(sealed, []byte("acme"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestNewBoxInvalidKey(t *testing.T) {
+	_, err := NewBox([]byte("short"))
+	assert.Error(t, err)
+	_, err = NewBoxFromBase64("not base64!")
+	assert.Error(t, err)
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/auth"
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/maintenance"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/scheduler"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestAdminJobsRoute(t *testing.T) {
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Secret: "test-secret",
+		},
+	}
+	s := scheduler.New()
+	require.NoError(t, s.Add("purge", "@hourly", nil))
+	router := setupRouter(&models.AppState{Config: cfg, Scheduler: s})
+
+	get := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("").Code)
+	assert.Equal(t, http.StatusForbidden, get(auth.GenerateJWT(cfg)).Code)
+
+	res := get(auth.GenerateScopedJWT(cfg, auth.ScopeAdmin))
+	require.Equal(t, http.StatusOK, res.Code)
+
+	var jobs []models.ScheduledJobStatus
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&jobs))
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "purge", jobs[0].Name)
+	assert.Equal(t, "@hourly", jobs[0].Schedule)
+	assert.True(t, jobs[0].Enabled)
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Secret: "test-secret",
+		},
+	}
+	router := setupRouter(&models.AppState{Config: cfg, Maintenance: maintenance.New()})
+	adminToken := auth.GenerateScopedJWT(cfg, auth.ScopeAdmin)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	res := do(
+		http.MethodPut,
+		"/api/v1/admin/maintenance",
+		`{"enabled": true, "reason": "restoring backup", "retry_after": 120}`,
+	)
+	require.Equal(t, http.StatusOK, res.Code)
+	var status models.MaintenanceStatus
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&status))
+	assert.True(t, status.Enabled)
+	assert.Equal(t, "restoring backup", status.Reason)
+	assert.NotNil(t, status.Since)
+
+	for _, path := range []string{"/api/v1/sessions", "/api/v2/sessions"} {
+		res = do(http.MethodPost, path, `{"session_id": "s"}`)
+		assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+		assert.Equal(t, "120", res.Header().Get("Retry-After"))
+		assert.Contains(t, res.Body.String(), "restoring backup")
+	}
+
+	res = do(http.MethodGet, "/api/v1/admin/maintenance", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&status))
+	assert.True(t, status.Enabled)
+
+	res = do(http.MethodPut, "/api/v1/admin/maintenance", `{"enabled": false}`)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&status))
+	assert.False(t, status.Enabled)
+}
+
+func TestReadOnly(t *testing.T) {
+	mode := maintenance.New()
+	handler := ReadOnly(mode)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(method, path string) int {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+		return res.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodPost, "/api/v1/sessions"))
+
+	mode.Enable("reindexing", 10)
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/api/v1/sessions/s/memory", http.StatusNoContent},
+		{http.MethodPost, "/api/v1/sessions/s/search", http.StatusNoContent},
+		{http.MethodPost, "/api/v1/sessions/s/search/", http.StatusNoContent},
+		{http.MethodPost, "/api/v1/user/u/search", http.StatusNoContent},
+		{http.MethodPost, "/api/v1/collection/c/document/list/get", http.StatusNoContent},
+		{http.MethodPost, "/api/v1/sessions/s/memory", http.StatusServiceUnavailable},
+		{http.MethodPatch, "/api/v1/user/u", http.StatusServiceUnavailable},
+		{http.MethodDelete, "/api/v1/collection/c", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/v1/collection/c/document/list/delete", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, serve(tt.method, tt.path), "%s %s", tt.method, tt.path)
+	}
+}
+
+func TestLegalHoldRoutes(t *testing.T) {
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Secret: "test-secret",
+		},
+	}
+	db := inmemory.NewDB()
+	holdState := &models.AppState{
+		Config:         cfg,
+		UserStore:      inmemory.NewUserStore(db),
+		LegalHoldStore: inmemory.NewLegalHoldStore(db),
+	}
+	holdState.MemoryStore = inmemory.NewMemoryStore(holdState, db)
+	router := setupRouter(holdState)
+	adminToken := auth.GenerateScopedJWT(cfg, auth.ScopeAdmin)
+
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	_, err := holdState.MemoryStore.CreateSession(
+		context.Background(),
+		&models.CreateSessionRequest{SessionID: "held"},
+	)
+	require.NoError(t, err)
+
+	holdPath := "/api/v1/admin/legal-holds/session/held"
+	hold := `{"reason": "litigation"}`
+	res := do(http.MethodPut, holdPath, hold, auth.GenerateJWT(cfg))
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	assert.Equal(
+		t,
+		http.StatusBadRequest,
+		do(http.MethodPut, "/api/v1/admin/legal-holds/collection/held", hold, adminToken).Code,
+	)
+	assert.Equal(
+		t,
+		http.StatusNotFound,
+		do(http.MethodPut, "/api/v1/admin/legal-holds/session/missing", hold, adminToken).Code,
+	)
+
+	res = do(http.MethodPut, holdPath, hold, adminToken)
+	require.Equal(t, http.StatusOK, res.Code)
+	var placed models.LegalHold
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&placed))
+	assert.Equal(t, "litigation", placed.Reason)
+	assert.Equal(t, "admin", placed.PlacedBy)
+
+	res = do(http.MethodDelete, "/api/v1/sessions/held/memory", "", "")
+	assert.Equal(t, http.StatusConflict, res.Code)
+
+	res = do(http.MethodDelete, holdPath+"?reason=settled", "", adminToken)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, holdPath, "", adminToken).Code)
+
+	res = do(
+		http.MethodGet,
+		"/api/v1/admin/legal-holds/events?subject_type=session&subject_id=held",
+		"",
+		adminToken,
+	)
+	require.Equal(t, http.StatusOK, res.Code)
+	var events []models.LegalHoldEvent
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&events))
+	require.Len(t, events, 2)
+	assert.Equal(t, models.LegalHoldPlaced, events[0].Action)
+	assert.Equal(t, models.LegalHoldLifted, events[1].Action)
+	assert.Equal(t, "settled", events[1].Reason)
+
+	res = do(http.MethodDelete, "/api/v1/sessions/held/memory", "", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestProviderOverrideRoutes(t *testing.T) {
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Secret: "test-secret",
+		},
+		LLM: config.LLM{
+			Service: llms.FakeLLMService,
+			Tenants: config.TenantProvidersConfig{Enabled: true},
+		},
+	}
+	llm, err := llms.NewLLMClient(context.Background(), cfg)
+	require.NoError(t, err)
+	overrideState := &models.AppState{
+		Config:                cfg,
+		LLMClient:             llm,
+		ProviderOverrideStore: inmemory.NewProviderOverrideStore(inmemory.NewDB()),
+	}
+	require.NoError(t, llms.WrapTenantProviders(overrideState))
+	router := setupRouter(overrideState)
+	adminToken := auth.GenerateScopedJWT(cfg, auth.ScopeAdmin)
+
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	path := "/api/v1/admin/provider-overrides/acme"
+	override := `{
+		"llm": {"service": "anthropic", "model": "claude-3-haiku-20240307", "api_key": "sk-ant"},
+		"embeddings": {"service": "openai", "model": "text-embedding-3-small", "api_key": "sk-oai"}
+	}`
+	res := do(http.MethodPut, path, override, auth.GenerateJWT(cfg))
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	for _, invalid := range []string{
+		`{}`,
+		`{"llm": {"service": "anthropic", "model": "claude-3-haiku-20240307"}}`,
+		`{"llm": {"service": "anthropic", "model": "gpt-4o", "api_key": "sk-ant"}}`,
+		`{"llm": {"service": "cohere", "model": "command", "api_key": "sk"}}`,
+	} {
+		res = do(http.MethodPut, path, invalid, adminToken)
+		assert.Equal(t, http.StatusBadRequest, res.Code, invalid)
+	}
+
+	res = do(http.MethodPut, path, override, adminToken)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.NotContains(t, res.Body.String(), "sk-ant")
+	var stored models.ProviderOverride
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&stored))
+	assert.Equal(t, "acme", stored.Tenant)
+	assert.Equal(t, "claude-3-haiku-20240307", stored.LLM.Model)
+
+	// the API keys are stored, but never returned
+	got, err := overrideState.ProviderOverrideStore.Get(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-oai", got.Embeddings.APIKey)
+	res = do(http.MethodGet, "/api/v1/admin/provider-overrides", "", adminToken)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.NotContains(t, res.Body.String(), "sk-")
+	var overrides []models.ProviderOverride
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&overrides))
+	require.Len(t, overrides, 1)
+
+	require.Equal(t, http.StatusOK, do(http.MethodDelete, path, "", adminToken).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, path, "", adminToken).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, path, "", adminToken).Code)
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/apihandlers"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestSearchAnalyticsRoutes(t *testing.T) {
+	ctx := context.Background()
+	db := inmemory.NewDB()
+	analyticsState := &models.AppState{
+		Config:        &config.Config{QueryLog: config.QueryLogConfig{Enabled: true}},
+		QueryLogStore: inmemory.NewQueryLogStore(db),
+	}
+	analyticsState.MemoryStore = inmemory.NewMemoryStore(analyticsState, db)
+	router := chi.NewRouter()
+	setupSessionRoutes(router, analyticsState)
+	router.Get("/analytics/search", apihandlers.GetSearchAnalyticsHandler(analyticsState))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	err := analyticsState.MemoryStore.PutMemory(ctx, "s1", &models.Memory{
+		Messages: []models.Message{{Role: "user", Content: "my flight to Lisbon"}},
+	}, true)
+	require.NoError(t, err)
+
+	for _, text := range []string{"lisbon", "Lisbon", "tokyo"} {
+		body, err := json.Marshal(models.MemorySearchPayload{Text: text})
+		require.NoError(t, err)
+		resp, err := http.Post(
+			server.URL+"/sessions/s1/search",
+			"application/json",
+			bytes.NewReader(body),
+		)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	resp, err := http.Get(server.URL + "/analytics/search?kind=memory")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var analytics models.SearchAnalytics
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&analytics))
+	resp.Body.Close()
+	assert.Equal(t, 3, analytics.Searches)
+	assert.Equal(t, 1, analytics.ZeroResults)
+	require.NotEmpty(t, analytics.TopQueries)
+	assert.Equal(t, models.QueryCount{Text: "lisbon", Searches: 2, AvgResults: 1},
+		analytics.TopQueries[0])
+	assert.Equal(t, []models.QueryCount{{Text: "tokyo", Searches: 1, ZeroResults: 1}},
+		analytics.TopZeroResultQueries)
+
+	resp, err = http.Get(server.URL + "/analytics/search?kind=memory&epsilon=0.5")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var noised models.SearchAnalytics
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&noised))
+	resp.Body.Close()
+	assert.Equal(t, 0.5, noised.Epsilon)
+	assert.Zero(t, noised.AvgLatencyMS)
+
+	// the same analytics get the same noise
+	resp, err = http.Get(server.URL + "/analytics/search?kind=memory&epsilon=0.5")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var repeated models.SearchAnalytics
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&repeated))
+	resp.Body.Close()
+	assert.Equal(t, noised, repeated)
+
+	for _, query := range []string{
+		"kind=summary",
+		"since=yesterday",
+		"limit=1000",
+		"noise=maybe",
+		"epsilon=0",
+	} {
+		resp, err = http.Get(server.URL + "/analytics/search?" + query)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestAPIVersions(t *testing.T) {
+	ctx := context.Background()
+	db := inmemory.NewDB()
+	versionState := &models.AppState{Config: &config.Config{}}
+	versionState.Config.Server.V1Deprecation = config.APIDeprecationConfig{
+		DeprecatedAt: "2026-01-01T00:00:00Z",
+		SunsetAt:     "2026-12-31T00:00:00Z",
+	}
+	versionState.MemoryStore = inmemory.NewMemoryStore(versionState, db)
+	versionState.UserStore = inmemory.NewUserStore(db)
+	router := chi.NewRouter()
+	setupAPIRoutes(router, versionState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := versionState.MemoryStore.CreateSession(
+			ctx,
+			&models.CreateSessionRequest{SessionID: fmt.Sprintf("s%d", i)},
+		)
+		require.NoError(t, err)
+	}
+
+	resp, err := http.Get(server.URL + "/api/v1/sessions")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "@1767225600", resp.Header.Get("Deprecation"))
+	assert.Equal(t, "Thu, 31 Dec 2026 00:00:00 GMT", resp.Header.Get("Sunset"))
+	assert.Equal(t, `</api/v2>; rel="successor-version"`, resp.Header.Get("Link"))
+
+	type page struct {
+		Data  []models.Session `json:"data"`
+		Error *struct {
+			Status int `json:"status"`
+		} `json:"error"`
+		Meta struct {
+			NextCursor string `json:"next_cursor"`
+		} `json:"meta"`
+	}
+	get := func(path string) page {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Empty(t, resp.Header.Get("Deprecation"))
+		var p page
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+		return p
+	}
+
+	// v2 lists are paginated with opaque cursors
+	var sessionIDs []string
+	path := "/api/v2/sessions?limit=2"
+	for pages := 0; path != ""; pages++ {
+		require.Less(t, pages, 3)
+		p := get(path)
+		require.Nil(t, p.Error)
+		for _, s := range p.Data {
+			sessionIDs = append(sessionIDs, s.SessionID)
+		}
+		path = ""
+		if p.Meta.NextCursor != "" {
+			path = "/api/v2/sessions?limit=2&cursor=" + p.Meta.NextCursor
+		}
+	}
+	assert.Equal(t, []string{"s0", "s1", "s2"}, sessionIDs)
+
+	p := get("/api/v2/sessions/missing")
+	require.NotNil(t, p.Error)
+	assert.Equal(t, http.StatusNotFound, p.Error.Status)
+
+	p = get("/api/v2/sessions?cursor=invalid!")
+	require.NotNil(t, p.Error)
+	assert.Equal(t, http.StatusBadRequest, p.Error.Status)
+}
@@ -0,0 +1,193 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/handlertools"
+	"github.com/getzep/zep/pkg/tasks"
+)
+
+// ListScheduledJobsHandler godoc
+//
+//	@Summary		List scheduled jobs
+//	@Description	list the background jobs with their schedules and the outcome of their last runs
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{array}		[]models.ScheduledJobStatus
+//	@Failure		403	{object}	APIError	"Forbidden"
+//	@Failure		500	{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/jobs [get]
+func ListScheduledJobsHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		jobs := []models.ScheduledJobStatus{}
+		if appState.Scheduler != nil {
+			jobs = appState.Scheduler.JobStatus()
+		}
+
+		if err := handlertools.EncodeJSON(w, jobs); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetMaintenanceHandler godoc
+//
+//	@Summary		Get maintenance mode
+//	@Description	get whether the API is in read-only maintenance mode
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	models.MaintenanceStatus
+//	@Failure		403	{object}	APIError	"Forbidden"
+//	@Failure		500	{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/maintenance [get]
+func GetMaintenanceHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		var status models.MaintenanceStatus
+		if appState.Maintenance != nil {
+			status = appState.Maintenance.Status()
+		}
+
+		if err := handlertools.EncodeJSON(w, status); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// PutMaintenanceHandler godoc
+//
+//	@Summary		Set maintenance mode
+//	@Description	enable or disable read-only maintenance mode, in which writes are rejected with a 503 status and a Retry-After header
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			maintenance	body		models.MaintenanceRequest	true	"Maintenance mode"
+//	@Success		200			{object}	models.MaintenanceStatus
+//	@Failure		400			{object}	APIError	"Bad Request"
+//	@Failure		403			{object}	APIError	"Forbidden"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/maintenance [put]
+func PutMaintenanceHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if appState.Maintenance == nil {
+			handlertools.RenderError(
+				w,
+				errors.New("maintenance mode is not available"),
+				http.StatusInternalServerError,
+			)
+			return
+		}
+
+		var req models.MaintenanceRequest
+		if err := handlertools.DecodeJSON(r, &req); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if req.Enabled {
+			appState.Maintenance.Enable(req.Reason, req.RetryAfter)
+		} else {
+			appState.Maintenance.Disable()
+		}
+
+		if err := handlertools.EncodeJSON(w, appState.Maintenance.Status()); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// VerifyContentIntegrityHandler godoc
+//
+//	@Summary		Verify content integrity
+//	@Description	start verifying the content of all messages and documents against the hashes stored when it was written, detecting corruption or modification outside of Zep. The task's result holds the models.IntegrityReport, and the task fails if any content doesn't match its hash
+//	@Tags			admin
+//	@Produce		json
+//	@Success		202	{object}	models.AsyncTask
+//	@Failure		403	{object}	APIError	"Forbidden"
+//	@Failure		500	{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/integrity [post]
+func VerifyContentIntegrityHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		task, err := tasks.StartAsyncTask(
+			r.Context(),
+			appState.AsyncTaskStore,
+			tasks.ContentIntegrityTaskType,
+			nil,
+			func(ctx context.Context, p *tasks.AsyncTaskProgress) (map[string]interface{}, error) {
+				report, err := tasks.VerifyContentHashes(ctx, appState, p.Set)
+				if report == nil {
+					return nil, err
+				}
+				return map[string]interface{}{"report": report}, err
+			},
+		)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		if err := handlertools.EncodeJSON(w, task); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// AnonymizeUserHandler godoc
+//
+//	@Summary		Anonymize a user
+//	@Description	start anonymizing a user, short of deleting the user: PII detected in the user's messages is replaced with placeholders, and the user is re-keyed to a random ID with the user's email and name cleared. Embeddings and summaries are kept for aggregate analytics. The task's result holds the models.AnonymizationReport, with the user's new ID. The task doesn't record the user's original ID
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userId	path		string	true	"User ID"
+//	@Success		202		{object}	models.AsyncTask
+//	@Failure		403		{object}	APIError	"Forbidden"
+//	@Failure		404		{object}	APIError	"Not Found"
+//	@Failure		409		{object}	APIError	"Conflict: the user or one of the user's sessions is under legal hold"
+//	@Failure		500		{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/users/{userId}/anonymize [post]
+func AnonymizeUserHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := chi.URLParam(r, "userId")
+		if err := tasks.CheckUserAnonymizable(r.Context(), appState, userID); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		task, err := tasks.StartAsyncTask(
+			r.Context(),
+			appState.AsyncTaskStore,
+			tasks.UserAnonymizationTaskType,
+			nil,
+			func(ctx context.Context, p *tasks.AsyncTaskProgress) (map[string]interface{}, error) {
+				report, err := tasks.AnonymizeUser(ctx, appState, userID, p.Set)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"report": report}, nil
+			},
+		)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		if err := handlertools.EncodeJSON(w, task); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package apihandlers
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/privacy"
+	"github.com/getzep/zep/pkg/server/handlertools"
+)
+
+const (
+	// maxTopQueries is the largest number of top queries that can be requested.
+	maxTopQueries = 100
+	// defaultTopQueries is the number of top queries returned if no limit is requested.
+	defaultTopQueries = 10
+	// noisedTopQueryCandidates is the number of top queries noised, of which the most
+	// frequent after noise are returned, so that which queries are returned doesn't depend
+	// on their exact counts.
+	noisedTopQueryCandidates = 10 * maxTopQueries
+)
+
+// GetSearchAnalyticsHandler godoc
+//
+//	@Summary		Returns search analytics
+//	@Description	get the number of logged searches, the rate of searches returning no results, and
+//	@Description	the most frequent queries, to find what sessions and collections can't answer.
+//	@Description	Searches are logged if query_log.enabled is set. With noise, Laplace noise is added to the
+//	@Description	counts, top queries with few searches are omitted, and averages aren't returned, so that
+//	@Description	the analytics don't expose the searches of a few users. The same counts always get the
+//	@Description	same noise. Noise is always added if query_log.noise.required is set.
+//	@Tags			analytics
+//	@Accept			json
+//	@Produce		json
+//	@Param			kind		query		string	false	"memory or document"
+//	@Param			collection	query		string	false	"Only searches of this collection"
+//	@Param			since		query		string	false	"Only searches since this RFC 3339 time"
+//	@Param			limit		query		integer	false	"Number of top queries returned. Defaults to 10"
+//	@Param			noise		query		boolean	false	"Add noise to the counts"
+//	@Param			epsilon		query		number	false	"Privacy budget of any one search, split among the counts it changes, smaller adding more noise. Implies noise. Defaults to query_log.noise.epsilon, which it can't exceed if noise is required"
+//	@Success		200			{object}	models.SearchAnalytics
+//	@Failure		400			{object}	APIError	"Bad Request"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/analytics/search [get]
+func GetSearchAnalyticsHandler(appState *models.AppState) http.HandlerFunc {
+	noiser := privacy.NewNoiser(appState.Config.QueryLog.Noise.Secret)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if appState.QueryLogStore == nil {
+			handlertools.RenderError(
+				w,
+				errors.New("the query log is not available"),
+				http.StatusNotFound,
+			)
+			return
+		}
+
+		query := r.URL.Query()
+		filter := &models.SearchAnalyticsFilter{
+			Kind:           query.Get("kind"),
+			CollectionName: query.Get("collection"),
+		}
+		switch filter.Kind {
+		case "", models.SearchKindMemory, models.SearchKindDocument:
+		default:
+			handlertools.RenderError(
+				w,
+				fmt.Errorf("invalid kind %q", filter.Kind),
+				http.StatusBadRequest,
+			)
+			return
+		}
+		if since := query.Get("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				handlertools.RenderError(
+					w,
+					fmt.Errorf("unable to parse since: %w", err),
+					http.StatusBadRequest,
+				)
+				return
+			}
+			filter.Since = t
+		}
+		limit, err := handlertools.IntFromQuery[int](r, "limit")
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if limit < 0 || limit > maxTopQueries {
+			handlertools.RenderError(
+				w,
+				fmt.Errorf("limit must be between 1 and %d", maxTopQueries),
+				http.StatusBadRequest,
+			)
+			return
+		}
+		filter.Limit = limit
+
+		noiseCfg := appState.Config.QueryLog.Noise
+		noise, err := handlertools.BoolFromQuery(r, "noise")
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		noise = noise || noiseCfg.Required
+		epsilon := noiseCfg.Epsilon
+		if epsilon <= 0 {
+			epsilon = privacy.DefaultEpsilon
+		}
+		if e := query.Get("epsilon"); e != "" {
+			requested, err := strconv.ParseFloat(e, 64)
+			if err != nil || requested <= 0 {
+				handlertools.RenderError(
+					w,
+					fmt.Errorf("epsilon must be a positive number"),
+					http.StatusBadRequest,
+				)
+				return
+			}
+			if noiseCfg.Required && requested > epsilon {
+				handlertools.RenderError(
+					w,
+					fmt.Errorf("epsilon can't exceed %g", epsilon),
+					http.StatusBadRequest,
+				)
+				return
+			}
+			epsilon, noise = requested, true
+		}
+
+		if noise {
+			if limit == 0 {
+				limit = defaultTopQueries
+			}
+			filter.Limit = noisedTopQueryCandidates
+		}
+		analytics, err := appState.QueryLogStore.Analytics(r.Context(), filter)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if noise {
+			analytics = privacy.NoiseSearchAnalytics(
+				analytics,
+				noiser,
+				filter.Kind+"\x00"+filter.CollectionName,
+				epsilon,
+				noiseCfg.MinCount,
+				limit,
+			)
+		}
+
+		if err := handlertools.EncodeJSON(w, analytics); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// logSearch logs a search that took since started, if the query log is enabled and the
+// search is sampled. A failure is logged rather than failing the search.
+func logSearch(
+	r *http.Request,
+	appState *models.AppState,
+	started time.Time,
+	search *models.LoggedSearch,
+) {
+	cfg := appState.Config.QueryLog
+	if !cfg.Enabled || appState.QueryLogStore == nil {
+		return
+	}
+	if cfg.SampleRate > 0 && cfg.SampleRate < 1 && rand.Float64() >= cfg.SampleRate { //nolint:gosec
+		return
+	}
+	search.ID = uuid.New()
+	search.LatencyMS = time.Since(started).Milliseconds()
+	search.ZeroResults = search.ResultCount == 0
+	if err := appState.QueryLogStore.Put(r.Context(), search); err != nil {
+		log.Errorf("failed to log search: %v", err)
+	}
+}
@@ -1,28 +1,42 @@
 package apihandlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/getzep/zep/pkg/provision"
 	"github.com/getzep/zep/pkg/server/handlertools"
+	"github.com/getzep/zep/pkg/tasks"
 
 	"github.com/go-playground/validator/v10"
 
 	"github.com/google/uuid"
 
+	"github.com/getzep/zep/pkg/experiment"
 	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/search"
+	"github.com/getzep/zep/pkg/searchfallback"
 	"github.com/go-chi/chi/v5"
 )
 
 var validate = validator.New()
 
+// collectionIndexTaskType is the type of the async task creating a collection's index.
+const collectionIndexTaskType = "collection_index"
+
 // CreateCollectionHandler godoc
 //
 //	@Summary		Creates a new DocumentCollection
-//	@Description	If a collection with the same name already exists, an error will be returned.
+//	@Description	If a collection with the same name already exists, an error will be returned,
+//	@Description	unless it matches the request, so that a create can be safely retried.
 //	@Tags			collection
 //	@Accept			json
 //	@Produce		json
@@ -32,6 +46,7 @@ var validate = validator.New()
 //	@Failure		400				{object}	APIError								"Bad Request"
 //	@Failure		401				{object}	APIError								"Unauthorized"
 //	@Failure		404				{object}	APIError								"Not Found"
+//	@Failure		409				{object}	APIError								"Conflict"
 //	@Failure		500				{object}	APIError								"Internal Server Error"
 //
 //	@Security		Bearer
@@ -51,24 +66,27 @@ func CreateCollectionHandler(appState *models.AppState) http.HandlerFunc {
 		}
 
 		var collectionRequest models.CreateDocumentCollectionRequest
-		err := json.NewDecoder(r.Body).Decode(&collectionRequest)
+		err := handlertools.DecodeJSON(r, &collectionRequest)
 		if err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
 
-		if err := validate.Struct(collectionRequest); err != nil {
+		if err := validateCreateCollectionRequest(collectionRequest); err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
 
 		collection := documentCollectionFromCreateRequest(collectionRequest)
 		err = store.CreateCollection(r.Context(), collection)
-		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
+		if errors.Is(err, models.ErrConflict) {
+			// a retried create succeeds if the existing collection is unchanged
+			existing, getErr := store.GetCollection(r.Context(), collection.Name)
+			if getErr == nil && collectionMatches(existing, collection) {
+				err = nil
 			}
+		}
+		if err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -82,10 +100,107 @@ func CreateCollectionHandler(appState *models.AppState) http.HandlerFunc {
 	}
 }
 
+// PutCollectionHandler godoc
+//
+//	@Summary		Creates or updates a DocumentCollection
+//	@Description	Creates the collection if it doesn't exist, and otherwise updates its description and metadata.
+//	@Description	Repeating a request has no further effect. The embedding dimensions, auto-embedding and
+//	@Description	contextual chunk headers of an existing collection can't be changed. If an If-Match header is
+//	@Description	sent, the collection must exist and match the ETag.
+//	@Tags			collection
+//	@Accept			json
+//	@Produce		json
+//	@Param			collectionName	path		string									true	"Name of the Document Collection"
+//	@Param			collection		body		models.CreateDocumentCollectionRequest	true	"Document Collection"
+//	@Success		200				{object}	models.DocumentCollectionResponse		"OK"
+//	@Success		201				{object}	models.DocumentCollectionResponse		"Created"
+//	@Failure		400				{object}	APIError								"Bad Request"
+//	@Failure		401				{object}	APIError								"Unauthorized"
+//	@Failure		409				{object}	APIError								"Conflict"
+//	@Failure		412				{object}	APIError								"Precondition Failed"
+//	@Failure		500				{object}	APIError								"Internal Server Error"
+//
+//	@Security		Bearer
+//
+//	@Router			/api/v1/collection/{collectionName} [put]
+func PutCollectionHandler(appState *models.AppState) http.HandlerFunc {
+	store := appState.DocumentStore
+	return func(w http.ResponseWriter, r *http.Request) {
+		collectionName := strings.ToLower(chi.URLParam(r, "collectionName"))
+		if collectionName == "" {
+			handlertools.RenderError(
+				w,
+				errors.New("collectionName is required"),
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		var collectionRequest models.CreateDocumentCollectionRequest
+		if err := handlertools.DecodeJSON(r, &collectionRequest); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if collectionRequest.Name == "" {
+			collectionRequest.Name = collectionName
+		}
+		if strings.ToLower(collectionRequest.Name) != collectionName {
+			handlertools.RenderError(
+				w,
+				errors.New("name does not match collectionName"),
+				http.StatusBadRequest,
+			)
+			return
+		}
+		collectionRequest.Name = collectionName
+
+		if err := validateCreateCollectionRequest(collectionRequest); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err := checkCollectionIfMatch(r, store, collectionName); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		action, err := provision.EnsureCollection(
+			r.Context(),
+			store,
+			documentCollectionFromCreateRequest(collectionRequest),
+		)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		collection, err := store.GetCollection(r.Context(), collectionName)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		etag, err := collectionETag(collection)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		if action == provision.ActionCreated {
+			w.WriteHeader(http.StatusCreated)
+		}
+		if err := handlertools.EncodeJSON(w, collectionToCollectionResponse(collection)); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 // UpdateCollectionHandler godoc
 //
-//	@Summary	Updates a DocumentCollection
-//	@Tags		collection
+//	@Summary		Updates a DocumentCollection
+//	@Description	If an If-Match header is sent, the collection must match the ETag.
+//	@Tags			collection
 //	@Accept		json
 //	@Produce	json
 //	@Param		collectionName	path		string									true	"Name of the Document Collection"
@@ -94,6 +209,7 @@ func CreateCollectionHandler(appState *models.AppState) http.HandlerFunc {
 //	@Failure	400				{object}	APIError								"Bad Request"
 //	@Failure	401				{object}	APIError								"Unauthorized"
 //	@Failure	404				{object}	APIError								"Not Found"
+//	@Failure	412				{object}	APIError								"Precondition Failed"
 //	@Failure	500				{object}	APIError								"Internal Server Error"
 //
 //	@Security	Bearer
@@ -112,7 +228,7 @@ func UpdateCollectionHandler(appState *models.AppState) http.HandlerFunc {
 			return
 		}
 		var collectionRequest models.UpdateDocumentCollectionRequest
-		if err := json.NewDecoder(r.Body).Decode(&collectionRequest); err != nil {
+		if err := handlertools.DecodeJSON(r, &collectionRequest); err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
@@ -122,13 +238,14 @@ func UpdateCollectionHandler(appState *models.AppState) http.HandlerFunc {
 			return
 		}
 
+		if err := checkCollectionIfMatch(r, store, collectionName); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
 		collection := documentCollectionFromUpdateRequest(collectionName, collectionRequest)
 		err := store.UpdateCollection(r.Context(), collection)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -154,6 +271,7 @@ func UpdateCollectionHandler(appState *models.AppState) http.HandlerFunc {
 //	@Failure		400				{object}	APIError	"Bad Request"
 //	@Failure		401				{object}	APIError	"Unauthorized"
 //	@Failure		404				{object}	APIError	"Not Found"
+//	@Failure		412				{object}	APIError	"Precondition Failed"
 //	@Failure		500				{object}	APIError	"Internal Server Error"
 //
 //	@Security		Bearer
@@ -172,12 +290,13 @@ func DeleteCollectionHandler(appState *models.AppState) http.HandlerFunc {
 			return
 		}
 
+		if err := checkCollectionIfMatch(r, store, collectionName); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
 		err := store.DeleteCollection(r.Context(), collectionName)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -210,10 +329,6 @@ func GetCollectionListHandler(appState *models.AppState) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		collections, err := store.GetCollectionList(r.Context())
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -230,7 +345,8 @@ func GetCollectionListHandler(appState *models.AppState) http.HandlerFunc {
 // GetCollectionHandler godoc
 //
 //	@Summary		Gets a DocumentCollection
-//	@Description	Returns a DocumentCollection if it exists.
+//	@Description	Returns a DocumentCollection if it exists, with an ETag header. If an If-None-Match
+//	@Description	header matches the ETag, 304 Not Modified is returned.
 //	@Tags			collection
 //	@Accept			json
 //	@Produce		json
@@ -259,14 +375,21 @@ func GetCollectionHandler(appState *models.AppState) http.HandlerFunc {
 
 		collection, err := store.GetCollection(r.Context(), collectionName)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
 
+		etag, err := collectionETag(collection)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		if handlertools.NotModified(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
 		collectionResponse := collectionToCollectionResponse(collection)
 
 		if err := handlertools.EncodeJSON(w, collectionResponse); err != nil {
@@ -307,7 +430,7 @@ func CreateDocumentsHandler(appState *models.AppState) http.HandlerFunc {
 		}
 
 		var documentListRequest []models.CreateDocumentRequest
-		if err := json.NewDecoder(r.Body).Decode(&documentListRequest); err != nil {
+		if err := handlertools.DecodeJSON(r, &documentListRequest); err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
@@ -333,24 +456,85 @@ func CreateDocumentsHandler(appState *models.AppState) http.HandlerFunc {
 	}
 }
 
+// IngestDocumentsHandler godoc
+//
+//	@Summary		Ingests a large batch of Documents into a DocumentCollection
+//	@Description	Creates Documents in a specified DocumentCollection using concurrent workers.
+//	@Description	Documents that fail to be created do not fail the batch. The outcome of each Document is returned.
+//	@Tags			document
+//	@Accept			json
+//	@Produce		json
+//	@Param			collectionName	path		string							true	"Name of the Document Collection"
+//	@Param			documents		body		[]models.CreateDocumentRequest	true	"Array of Documents to be created"
+//	@Success		200				{object}	models.DocumentIngestResponse	"OK"
+//	@Failure		400				{object}	APIError						"Bad Request"
+//	@Failure		401				{object}	APIError						"Unauthorized"
+//	@Failure		404				{object}	APIError						"Not Found"
+//	@Failure		500				{object}	APIError						"Internal Server Error"
+//
+//	@Security		Bearer
+//
+//	@Router			/api/v1/collection/{collectionName}/document/ingest [post]
+func IngestDocumentsHandler(appState *models.AppState) http.HandlerFunc {
+	store := appState.DocumentStore
+	return func(w http.ResponseWriter, r *http.Request) {
+		collectionName := strings.ToLower(chi.URLParam(r, "collectionName"))
+		if collectionName == "" {
+			handlertools.RenderError(
+				w,
+				errors.New("collectionName is required"),
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		var documentListRequest []models.CreateDocumentRequest
+		if err := handlertools.DecodeJSON(r, &documentListRequest); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		documents, err := documentListFromDocumentCreateRequestList(
+			documentListRequest,
+		)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		response, err := store.IngestDocuments(r.Context(), collectionName, documents)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, response); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 // UpdateDocumentHandler godoc
 //
-//	@Summary	Updates a Document in a DocumentCollection by UUID
-//	@Tags		document
-//	@Accept		json
-//	@Produce	json
-//	@Param		collectionName	path		string							true	"Name of the Document Collection"
-//	@Param		documentUUID	path		string							true	"UUID of the Document to be updated"
-//	@Param		document		body		models.UpdateDocumentRequest	true	"Document to be updated"
-//	@Success	200				{object}	string							"OK"
-//	@Failure	400				{object}	APIError						"Bad Request"
-//	@Failure	401				{object}	APIError						"Unauthorized"
-//	@Failure	404				{object}	APIError						"Not Found"
-//	@Failure	500				{object}	APIError						"Internal Server Error"
+//	@Summary		Updates a Document in a DocumentCollection by UUID
+//	@Description	Updating the content re-embeds the document if the collection is auto-embedded, and
+//	@Description	otherwise requires a new embedding. Metadata and document_id updates aren't re-embedded.
+//	@Tags			document
+//	@Accept			json
+//	@Produce		json
+//	@Param			collectionName	path		string							true	"Name of the Document Collection"
+//	@Param			documentUUID	path		string							true	"UUID of the Document to be updated"
+//	@Param			document		body		models.UpdateDocumentRequest	true	"Document to be updated"
+//	@Success		200				{object}	string							"OK"
+//	@Failure		400				{object}	APIError						"Bad Request"
+//	@Failure		401				{object}	APIError						"Unauthorized"
+//	@Failure		404				{object}	APIError						"Not Found"
+//	@Failure		500				{object}	APIError						"Internal Server Error"
 //
-//	@Security	Bearer
+//	@Security		Bearer
 //
-//	@Router		/api/v1/collection/{collectionName}/document/uuid/{documentUUID} [patch]
+//	@Router			/api/v1/collection/{collectionName}/document/uuid/{documentUUID} [patch]
 func UpdateDocumentHandler(appState *models.AppState) http.HandlerFunc {
 	store := appState.DocumentStore
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -375,7 +559,7 @@ func UpdateDocumentHandler(appState *models.AppState) http.HandlerFunc {
 		}
 
 		var documentRequest models.UpdateDocumentRequest
-		if err := json.NewDecoder(r.Body).Decode(&documentRequest); err != nil {
+		if err := handlertools.DecodeJSON(r, &documentRequest); err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
@@ -389,10 +573,6 @@ func UpdateDocumentHandler(appState *models.AppState) http.HandlerFunc {
 		documents := []models.Document{document}
 		err := store.UpdateDocuments(r.Context(), collectionName, documents)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -437,7 +617,7 @@ func UpdateDocumentListHandler(appState *models.AppState) http.HandlerFunc {
 		}
 
 		var documentsRequest []models.UpdateDocumentListRequest
-		if err := json.NewDecoder(r.Body).Decode(&documentsRequest); err != nil {
+		if err := handlertools.DecodeJSON(r, &documentsRequest); err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
@@ -450,10 +630,6 @@ func UpdateDocumentListHandler(appState *models.AppState) http.HandlerFunc {
 
 		err = store.UpdateDocuments(r.Context(), collectionName, documents)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -516,10 +692,6 @@ func GetDocumentHandler(appState *models.AppState) http.HandlerFunc {
 		)
 
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -539,7 +711,7 @@ func GetDocumentHandler(appState *models.AppState) http.HandlerFunc {
 //	@Description	Returns Documents from a DocumentCollection specified by UUID or ID.
 //	@Tags			document
 //	@Accept			json
-//	@Produce		json
+//	@Produce		json,application/msgpack
 //	@Param			collectionName	path		string							true	"Name of the Document Collection"
 //	@Param			documentRequest	body		models.GetDocumentListRequest	true	"UUIDs and IDs of the Documents to be fetched"
 //	@Success		200				{array}		[]models.DocumentResponse		"OK"
@@ -564,7 +736,7 @@ func GetDocumentListHandler(appState *models.AppState) http.HandlerFunc {
 		}
 
 		var docRequest models.GetDocumentListRequest
-		if err := json.NewDecoder(r.Body).Decode(&docRequest); err != nil {
+		if err := handlertools.DecodeJSON(r, &docRequest); err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
@@ -576,16 +748,12 @@ func GetDocumentListHandler(appState *models.AppState) http.HandlerFunc {
 			docRequest.DocumentIDs,
 		)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
 
 		documentResponses := documentBatchResponseFromDocumentList(documents)
-		if err := handlertools.EncodeJSON(w, documentResponses); err != nil {
+		if err := handlertools.EncodeFields(w, r, documentResponses, nil); err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -639,10 +807,6 @@ func DeleteDocumentHandler(appState *models.AppState) http.HandlerFunc {
 		uuids := []uuid.UUID{documentUUID}
 		err := store.DeleteDocuments(r.Context(), collectionName, uuids)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -689,17 +853,13 @@ func DeleteDocumentListHandler(appState *models.AppState) http.HandlerFunc {
 		}
 
 		var documentUUIDs []uuid.UUID
-		if err := json.NewDecoder(r.Body).Decode(&documentUUIDs); err != nil {
+		if err := handlertools.DecodeJSON(r, &documentUUIDs); err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
 
 		err := store.DeleteDocuments(r.Context(), collectionName, documentUUIDs)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -724,8 +884,10 @@ func DeleteDocumentListHandler(appState *models.AppState) http.HandlerFunc {
 //	@Produce		json
 //	@Param			collectionName	path		string		true	"Name of the Document Collection"
 //	@Param			force			query		bool		false	"Force index creation, even if there are too few documents to index"
+//	@Param			async			query		bool		false	"Create the index in the background, returning a task to poll"
 //
 //	@Success		200				{object}	string		"OK"
+//	@Success		202				{object}	models.AsyncTask
 //	@Failure		400				{object}	APIError	"Bad Request"
 //	@Failure		401				{object}	APIError	"Unauthorized"
 //	@Failure		500				{object}	APIError	"Internal Server Error"
@@ -757,7 +919,25 @@ func CreateCollectionIndexHandler(appState *models.AppState) http.HandlerFunc {
 			}
 		}
 
-		err := store.CreateCollectionIndex(r.Context(), collectionName, force)
+		async, err := handlertools.BoolFromQuery(r, "async")
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if async {
+			task, err := startCollectionIndexTask(r.Context(), appState, collectionName, force)
+			if err != nil {
+				handlertools.RenderError(w, err, http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			if err := handlertools.EncodeJSON(w, task); err != nil {
+				handlertools.RenderError(w, err, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		err = store.CreateCollectionIndex(r.Context(), collectionName, force)
 		if err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
@@ -772,6 +952,36 @@ func CreateCollectionIndexHandler(appState *models.AppState) http.HandlerFunc {
 	}
 }
 
+// startCollectionIndexTask creates the collection's index in an async task. The
+// collection is checked first, so that a missing collection is reported to the caller.
+func startCollectionIndexTask(
+	ctx context.Context,
+	appState *models.AppState,
+	collectionName string,
+	force bool,
+) (*models.AsyncTask, error) {
+	store := appState.DocumentStore
+	if _, err := store.GetCollection(ctx, collectionName); err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{"collection_name": collectionName, "force": force}
+	return tasks.StartAsyncTask(
+		ctx,
+		appState.AsyncTaskStore,
+		collectionIndexTaskType,
+		params,
+		func(ctx context.Context, p *tasks.AsyncTaskProgress) (map[string]interface{}, error) {
+			p.Set(0, 1)
+			if err := store.CreateCollectionIndex(ctx, collectionName, force); err != nil {
+				return nil, err
+			}
+			p.Set(1, 1)
+			return nil, nil
+		},
+	)
+}
+
 // SearchDocumentsHandler godoc
 //
 //	@Summary		Searches Documents in a DocumentCollection
@@ -780,9 +990,11 @@ func CreateCollectionIndexHandler(appState *models.AppState) http.HandlerFunc {
 //	@Tags			document
 //
 //	@Accept			json
-//	@Produce		json
+//	@Produce		json,application/msgpack
 //	@Param			collectionName	path		string							true	"Name of the Document Collection"
 //	@Param			limit			query		int								false	"Limit the number of returned documents"
+//	@Param			fields			query		string							false	"Comma separated fields to return, such as results.uuid,results.score"
+//	@Param			debug			query		boolean							false	"Return how the search was run with the results"
 //	@Param			searchPayload	body		models.DocumentSearchPayload	true	"Search criteria"
 //	@Success		200				{object}	[]models.Document				"OK"
 //	@Failure		400				{object}	APIError						"Bad Request"
@@ -805,50 +1017,321 @@ func SearchDocumentsHandler(appState *models.AppState) http.HandlerFunc {
 			return
 		}
 
-		limit, err := handlertools.IntFromQuery[int](r, "limit")
+		limit, err := handlertools.LimitFromQuery(
+			r,
+			handlertools.Limits(appState.Config).DocumentSearch,
+		)
 		if err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
 
+		fields, err := handlertools.FieldsFromQuery(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		debug, err := searchDebugFromQuery(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		r = r.WithContext(models.WithSearchDebug(r.Context(), debug))
+
 		var searchPayload models.DocumentSearchPayload
-		if err := json.NewDecoder(r.Body).Decode(&searchPayload); err != nil {
+		if err := handlertools.DecodeJSON(r, &searchPayload); err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
 
 		searchPayload.CollectionName = collectionName
+		variant := experiment.ApplyToDocumentSearch(
+			appState.Config.Experiments.Retrieval,
+			&searchPayload,
+		)
+		if variant != "" {
+			w.Header().Set(experiment.VariantHeader, variant)
+		}
 
+		started := time.Now()
 		results, err := store.SearchCollection(r.Context(), &searchPayload, limit, 0, 0)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, err, http.StatusNotFound)
-				return
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		if len(results.Results) == 0 && searchPayload.Fallback {
+			for _, fallback := range searchfallback.Documents(&searchPayload) {
+				results, err = store.SearchCollection(r.Context(), fallback.Query, limit, 0, 0)
+				if err != nil {
+					handlertools.RenderError(w, err, http.StatusInternalServerError)
+					return
+				}
+				if len(results.Results) > 0 {
+					w.Header().Set(searchfallback.Header, fallback.Name)
+					break
+				}
 			}
+		}
+		logSearch(r, appState, started, &models.LoggedSearch{
+			Kind:           models.SearchKindDocument,
+			CollectionName: collectionName,
+			Text:           searchPayload.Text,
+			SearchType:     searchPayload.SearchType,
+			ResultCount:    len(results.Results),
+		})
+		recordProvenance(w, r, appState, documentSearchProvenance(collectionName, results))
+		if searchPayload.Highlight {
+			results = highlightDocumentResults(searchPayload.Text, results)
+		}
+		if debug != nil {
+			// the page is copied as it may be shared by the search cache
+			debugged := *results
+			debugged.Debug = debug
+			results = &debugged
+			if fields != nil {
+				fields["debug"] = nil
+			}
+		}
+
+		if err := handlertools.EncodeFields(w, r, results, fields); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// SearchCollectionsHandler godoc
+//
+//	@Summary		Searches Documents in several DocumentCollections
+//	@Description	Searches each of the named DocumentCollections concurrently, and merges their
+//	@Description	results by score. Scores are normalized among the results of each collection.
+//
+//	@Tags			document
+//
+//	@Accept			json
+//	@Produce		json,application/msgpack
+//	@Param			limit			query		int										false	"Limit the number of returned documents, 20 by default"
+//	@Param			fields			query		string									false	"Comma separated fields to return, such as results.uuid,results.score"
+//	@Param			searchPayload	body		models.MultiCollectionSearchPayload		true	"Search criteria"
+//	@Success		200				{object}	models.MultiCollectionSearchResultPage	"OK"
+//	@Failure		400				{object}	APIError								"Bad Request"
+//	@Failure		401				{object}	APIError								"Unauthorized"
+//	@Failure		404				{object}	APIError								"Not Found"
+//	@Failure		500				{object}	APIError								"Internal Server Error"
+//
+//	@Security		Bearer
+//
+//	@Router			/api/v1/collection/search [post]
+func SearchCollectionsHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := handlertools.LimitFromQuery(
+			r,
+			handlertools.Limits(appState.Config).DocumentSearch,
+		)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		fields, err := handlertools.FieldsFromQuery(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		var searchPayload models.MultiCollectionSearchPayload
+		if err := handlertools.DecodeJSON(r, &searchPayload); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := searchPayload.Validate(); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		started := time.Now()
+		pages, err := searchCollections(r.Context(), appState, &searchPayload, limit)
+		if err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
+		for i, page := range pages {
+			logSearch(r, appState, started, &models.LoggedSearch{
+				Kind:           models.SearchKindDocument,
+				CollectionName: strings.ToLower(searchPayload.CollectionNames[i]),
+				Text:           searchPayload.Text,
+				SearchType:     searchPayload.SearchType,
+				ResultCount:    len(page.Results),
+			})
+		}
 
-		if err := handlertools.EncodeJSON(w, results); err != nil {
+		results := search.MergeCollectionResults(searchPayload.CollectionNames, pages, limit)
+		if searchPayload.Highlight {
+			// results are copies, so highlighting them doesn't modify cached pages
+			for i := range results {
+				if results[i].DocumentResponse != nil {
+					results[i].Highlights = search.MatchedSpans(
+						results[i].Content,
+						searchPayload.Text,
+					)
+				}
+			}
+		}
+		resultPage := &models.MultiCollectionSearchResultPage{
+			Results:     results,
+			ResultCount: len(results),
+		}
+		if err := handlertools.EncodeFields(w, r, resultPage, fields); err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
+// searchCollections searches each of the payload's collections concurrently, returning
+// their result pages in the order of the collections, or the first error.
+func searchCollections(
+	ctx context.Context,
+	appState *models.AppState,
+	searchPayload *models.MultiCollectionSearchPayload,
+	limit int,
+) ([]*models.DocumentSearchResultPage, error) {
+	pages := make([]*models.DocumentSearchResultPage, len(searchPayload.CollectionNames))
+	errs := make([]error, len(searchPayload.CollectionNames))
+	var wg sync.WaitGroup
+	for i, collectionName := range searchPayload.CollectionNames {
+		wg.Add(1)
+		go func(i int, collectionName string) {
+			defer wg.Done()
+			query := searchPayload.DocumentSearchPayload
+			query.CollectionName = strings.ToLower(collectionName)
+			pages[i], errs[i] = appState.DocumentStore.SearchCollection(ctx, &query, limit, 0, 0)
+		}(i, collectionName)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to search collection %s: %w",
+				searchPayload.CollectionNames[i],
+				err,
+			)
+		}
+	}
+	return pages, nil
+}
+
+// highlightDocumentResults returns a copy of the page with the spans of its results'
+// content matching the text highlighted. See highlightMemoryResults.
+func highlightDocumentResults(
+	text string,
+	page *models.DocumentSearchResultPage,
+) *models.DocumentSearchResultPage {
+	highlighted := *page
+	highlighted.Results = make([]models.DocumentSearchResult, len(page.Results))
+	for i, result := range page.Results {
+		if result.DocumentResponse != nil {
+			result.Highlights = search.MatchedSpans(result.Content, text)
+		}
+		highlighted.Results[i] = result
+	}
+	return &highlighted
+}
+
 // documentCollectionFromCreateRequest converts a CreateDocumentCollectionRequest to a DocumentCollection.
 func documentCollectionFromCreateRequest(
 	collectionRequest models.CreateDocumentCollectionRequest,
 ) models.DocumentCollection {
 	return models.DocumentCollection{
-		Name:                collectionRequest.Name,
-		Description:         collectionRequest.Description,
-		Metadata:            collectionRequest.Metadata,
-		EmbeddingDimensions: collectionRequest.EmbeddingDimensions,
-		IsAutoEmbedded:      *collectionRequest.IsAutoEmbedded,
+		Name:                   collectionRequest.Name,
+		Description:            collectionRequest.Description,
+		Metadata:               collectionRequest.Metadata,
+		EmbeddingDimensions:    collectionRequest.EmbeddingDimensions,
+		IsAutoEmbedded:         *collectionRequest.IsAutoEmbedded,
+		ContextualChunkHeaders: collectionRequest.ContextualChunkHeaders,
+		IndexType:              collectionRequest.IndexType,
+		DistanceFunction:       collectionRequest.DistanceFunction,
 	}
 }
 
+// validateCreateCollectionRequest validates the request, including that contextual chunk
+// headers are only requested for an auto-embedded collection, as Zep doesn't embed the
+// documents of other collections.
+func validateCreateCollectionRequest(collectionRequest models.CreateDocumentCollectionRequest) error {
+	if err := validate.Struct(collectionRequest); err != nil {
+		return err
+	}
+	if collectionRequest.ContextualChunkHeaders && !*collectionRequest.IsAutoEmbedded {
+		return errors.New("contextual_chunk_headers requires is_auto_embedded")
+	}
+	return nil
+}
+
+// collectionETag returns the ETag of a collection. Document counts are excluded, so the
+// ETag only changes when the collection itself is changed.
+func collectionETag(collection models.DocumentCollection) (string, error) {
+	return handlertools.ETag(struct {
+		UUID                uuid.UUID
+		Name                string
+		Description         string
+		Metadata            map[string]interface{}
+		EmbeddingDimensions int
+		IsAutoEmbedded      bool
+	}{
+		collection.UUID,
+		collection.Name,
+		collection.Description,
+		collection.Metadata,
+		collection.EmbeddingDimensions,
+		collection.IsAutoEmbedded,
+	})
+}
+
+// checkCollectionIfMatch checks the request's If-Match header, if any, against the
+// collection's current ETag.
+func checkCollectionIfMatch(
+	r *http.Request,
+	store models.DocumentStore[any],
+	collectionName string,
+) error {
+	if r.Header.Get("If-Match") == "" {
+		return nil
+	}
+	collection, err := store.GetCollection(r.Context(), collectionName)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.NewPreconditionFailedError("collection does not exist")
+		}
+		return err
+	}
+	etag, err := collectionETag(collection)
+	if err != nil {
+		return err
+	}
+	return handlertools.CheckIfMatch(r, etag)
+}
+
+// collectionMatches is true if existing has the same settings as collection.
+func collectionMatches(existing, collection models.DocumentCollection) bool {
+	return existing.EmbeddingDimensions == collection.EmbeddingDimensions &&
+		existing.IsAutoEmbedded == collection.IsAutoEmbedded &&
+		existing.ContextualChunkHeaders == collection.ContextualChunkHeaders &&
+		existing.Description == collection.Description &&
+		metadataEqual(existing.Metadata, collection.Metadata)
+}
+
+// metadataEqual compares metadata by its JSON encoding, as the store may decode numbers
+// differently from the request.
+func metadataEqual(a, b map[string]interface{}) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(aJSON, bJSON)
+}
+
 // documentCollectionFromUpdateRequest converts a UpdateDocumentCollectionRequest to a DocumentCollection.
 func documentCollectionFromUpdateRequest(
 	collectionName string,
@@ -884,6 +1367,9 @@ func collectionToCollectionResponse(
 		IsAutoEmbedded:           collection.IsAutoEmbedded,
 		IsNormalized:             collection.IsNormalized,
 		IsIndexed:                collection.IsIndexed,
+		IndexType:                collection.IndexType,
+		DistanceFunction:         collection.DistanceFunction,
+		ContextualChunkHeaders:   collection.ContextualChunkHeaders,
 		DocumentCollectionCounts: counts,
 	}
 }
@@ -937,7 +1423,9 @@ func documentFromDocumentUpdateRequest(
 			UUID:       documentUUID,
 			DocumentID: request.DocumentID,
 			Metadata:   request.Metadata,
+			Content:    request.Content,
 		},
+		Embedding: request.Embedding,
 	}
 }
 
@@ -960,14 +1448,16 @@ func documentListFromDocumentBatchUpdateRequest(
 // documentResponseFromDocument converts a models.Document to a models.DocumentResponse
 func documentResponseFromDocument(document models.Document) models.DocumentResponse {
 	return models.DocumentResponse{
-		UUID:       document.UUID,
-		CreatedAt:  document.CreatedAt,
-		UpdatedAt:  document.UpdatedAt,
-		DocumentID: document.DocumentID,
-		Content:    document.Content,
-		Metadata:   document.Metadata,
-		Embedding:  document.Embedding,
-		IsEmbedded: document.IsEmbedded,
+		UUID:        document.UUID,
+		CreatedAt:   document.CreatedAt,
+		UpdatedAt:   document.UpdatedAt,
+		DocumentID:  document.DocumentID,
+		Content:     document.Content,
+		Metadata:    document.Metadata,
+		Embedding:   document.Embedding,
+		IsEmbedded:  document.IsEmbedded,
+		Summary:     document.Summary,
+		ContentHash: document.ContentHash,
 	}
 }
 
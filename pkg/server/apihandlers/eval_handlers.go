@@ -0,0 +1,176 @@
+package apihandlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/getzep/zep/pkg/eval"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/handlertools"
+)
+
+// PutEvalSetHandler godoc
+//
+//	@Summary		Creates or replaces an eval set
+//	@Description	upload labeled queries and the messages or documents relevant to them
+//	@Tags			eval
+//	@Accept			json
+//	@Produce		json
+//	@Param			evalSet	body		models.EvalSet	true	"Eval set"
+//	@Success		200		{object}	models.EvalSet
+//	@Failure		400		{object}	APIError	"Bad Request"
+//	@Failure		500		{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/evals [post]
+func PutEvalSetHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var set models.EvalSet
+		if err := handlertools.DecodeJSON(r, &set); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(set); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		stored, err := appState.EvalSetStore.Put(r.Context(), &set)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, stored); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// ListEvalSetsHandler godoc
+//
+//	@Summary		List eval sets
+//	@Description	list eval sets, ordered by name
+//	@Tags			eval
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		[]models.EvalSet	"Successfully retrieved list of eval sets"
+//	@Failure		500	{object}	APIError			"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/evals [get]
+func ListEvalSetsHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sets, err := appState.EvalSetStore.List(r.Context())
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, sets); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetEvalSetHandler godoc
+//
+//	@Summary		Returns an eval set by name
+//	@Description	get an eval set and its labeled queries
+//	@Tags			eval
+//	@Accept			json
+//	@Produce		json
+//	@Param			evalSetName	path		string	true	"Name of the eval set"
+//	@Success		200			{object}	models.EvalSet
+//	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/evals/{evalSetName} [get]
+func GetEvalSetHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		set, err := appState.EvalSetStore.Get(r.Context(), chi.URLParam(r, "evalSetName"))
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, set); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// DeleteEvalSetHandler godoc
+//
+//	@Summary		Deletes an eval set
+//	@Description	delete an eval set by name
+//	@Tags			eval
+//	@Accept			json
+//	@Produce		json
+//	@Param			evalSetName	path		string		true	"Name of the eval set"
+//	@Success		200			{string}	string		"OK"
+//	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/evals/{evalSetName} [delete]
+func DeleteEvalSetHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := appState.EvalSetStore.Delete(r.Context(), chi.URLParam(r, "evalSetName"))
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		_, _ = w.Write([]byte(OKResponse))
+	}
+}
+
+// RunEvalSetHandler godoc
+//
+//	@Summary		Runs an eval set
+//	@Description	search each query of an eval set with the current search settings and report recall@k and MRR
+//	@Tags			eval
+//	@Accept			json
+//	@Produce		json
+//	@Param			evalSetName	path		string					true	"Name of the eval set"
+//	@Param			runRequest	body		models.EvalRunRequest	false	"Search settings overrides"
+//	@Success		200			{object}	models.EvalReport
+//	@Failure		400			{object}	APIError	"Bad Request"
+//	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/evals/{evalSetName}/run [post]
+func RunEvalSetHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.EvalRunRequest
+		if err := handlertools.DecodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		set, err := appState.EvalSetStore.Get(r.Context(), chi.URLParam(r, "evalSetName"))
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		report, err := eval.Run(r.Context(), appState, set, req)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, report); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package apihandlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/getzep/zep/pkg/auth"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/handlertools"
+)
+
+// defaultLegalHoldActor is recorded as the actor of hold events made with an admin token
+// that has no subject.
+const defaultLegalHoldActor = "admin"
+
+// ListLegalHoldsHandler godoc
+//
+//	@Summary		List legal holds
+//	@Description	list the users and sessions under legal hold, most recently placed first
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{array}		[]models.LegalHold
+//	@Failure		403	{object}	APIError	"Forbidden"
+//	@Failure		500	{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/legal-holds [get]
+func ListLegalHoldsHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !legalHoldsAvailable(w, appState) {
+			return
+		}
+
+		holds, err := appState.LegalHoldStore.List(r.Context())
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, holds); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// ListLegalHoldEventsHandler godoc
+//
+//	@Summary		List legal hold events
+//	@Description	list the audit trail of legal holds placed and lifted, oldest first, for a subject or for all subjects
+//	@Tags			admin
+//	@Produce		json
+//	@Param			subject_type	query		string	false	"user or session. Requires subject_id"
+//	@Param			subject_id		query		string	false	"User or session ID"
+//	@Success		200				{array}		[]models.LegalHoldEvent
+//	@Failure		400				{object}	APIError	"Bad Request"
+//	@Failure		403				{object}	APIError	"Forbidden"
+//	@Failure		500				{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/legal-holds/events [get]
+func ListLegalHoldEventsHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !legalHoldsAvailable(w, appState) {
+			return
+		}
+
+		subjectType := r.URL.Query().Get("subject_type")
+		subjectID := r.URL.Query().Get("subject_id")
+		if subjectType != "" || subjectID != "" {
+			if err := validateLegalHoldSubject(subjectType, subjectID); err != nil {
+				handlertools.RenderError(w, err, http.StatusBadRequest)
+				return
+			}
+		}
+
+		events, err := appState.LegalHoldStore.ListEvents(r.Context(), subjectType, subjectID)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, events); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// PlaceLegalHoldHandler godoc
+//
+//	@Summary		Place a legal hold
+//	@Description	place a legal hold on a user or session, preventing it from being deleted manually, with its user, or on expiry until the hold is lifted. A hold on a user also holds the user's sessions. Placing a hold on a held subject replaces the hold
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			subjectType	path		string					true	"user or session"
+//	@Param			subjectId	path		string					true	"User or session ID"
+//	@Param			hold		body		models.LegalHoldRequest	true	"Legal hold"
+//	@Success		200			{object}	models.LegalHold
+//	@Failure		400			{object}	APIError	"Bad Request"
+//	@Failure		403			{object}	APIError	"Forbidden"
+//	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/legal-holds/{subjectType}/{subjectId} [put]
+func PlaceLegalHoldHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !legalHoldsAvailable(w, appState) {
+			return
+		}
+
+		subjectType := chi.URLParam(r, "subjectType")
+		subjectID := chi.URLParam(r, "subjectId")
+		if err := validateLegalHoldSubject(subjectType, subjectID); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		var req models.LegalHoldRequest
+		if err := handlertools.DecodeJSON(r, &req); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		// only existing subjects can be held
+		var err error
+		if subjectType == models.LegalHoldSubjectUser {
+			_, err = appState.UserStore.Get(r.Context(), subjectID)
+		} else {
+			_, err = appState.MemoryStore.GetSession(r.Context(), subjectID)
+		}
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		hold, err := appState.LegalHoldStore.Place(r.Context(), &models.LegalHold{
+			SubjectType: subjectType,
+			SubjectID:   subjectID,
+			Reason:      req.Reason,
+			PlacedBy:    legalHoldActor(r),
+		})
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		log.Infof("legal hold placed on %s %s by %s", subjectType, subjectID, hold.PlacedBy)
+
+		if err := handlertools.EncodeJSON(w, hold); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// LiftLegalHoldHandler godoc
+//
+//	@Summary		Lift a legal hold
+//	@Description	lift the legal hold on a user or session, allowing it to be deleted again
+//	@Tags			admin
+//	@Produce		json
+//	@Param			subjectType	path		string		true	"user or session"
+//	@Param			subjectId	path		string		true	"User or session ID"
+//	@Param			reason		query		string		false	"Reason the hold is lifted, recorded with the event"
+//	@Success		200			{string}	string		"OK"
+//	@Failure		400			{object}	APIError	"Bad Request"
+//	@Failure		403			{object}	APIError	"Forbidden"
+//	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/legal-holds/{subjectType}/{subjectId} [delete]
+func LiftLegalHoldHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !legalHoldsAvailable(w, appState) {
+			return
+		}
+
+		subjectType := chi.URLParam(r, "subjectType")
+		subjectID := chi.URLParam(r, "subjectId")
+		if err := validateLegalHoldSubject(subjectType, subjectID); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		actor := legalHoldActor(r)
+		err := appState.LegalHoldStore.Lift(
+			r.Context(),
+			subjectType,
+			subjectID,
+			actor,
+			r.URL.Query().Get("reason"),
+		)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		log.Infof("legal hold on %s %s lifted by %s", subjectType, subjectID, actor)
+
+		_, _ = w.Write([]byte(OKResponse))
+	}
+}
+
+func legalHoldsAvailable(w http.ResponseWriter, appState *models.AppState) bool {
+	if appState.LegalHoldStore == nil {
+		handlertools.RenderError(
+			w,
+			errors.New("legal holds are not available"),
+			http.StatusInternalServerError,
+		)
+		return false
+	}
+	return true
+}
+
+func validateLegalHoldSubject(subjectType, subjectID string) error {
+	if subjectType != models.LegalHoldSubjectUser &&
+		subjectType != models.LegalHoldSubjectSession {
+		return models.NewValidationError("subject type must be user or session")
+	}
+	if subjectID == "" {
+		return models.NewValidationError("subject id is required")
+	}
+	return nil
+}
+
+// legalHoldActor returns the subject of the request's admin token, recorded with the hold
+// events it makes.
+func legalHoldActor(r *http.Request) string {
+	_, claims, _ := jwtauth.FromContext(r.Context())
+	if actor := auth.TokenSubject(claims); actor != "" {
+		return actor
+	}
+	return defaultLegalHoldActor
+}
@@ -1,28 +1,43 @@
 package apihandlers
 
 import (
-	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/getzep/zep/pkg/server/handlertools"
 
+	"github.com/getzep/zep/pkg/experiment"
 	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/search"
+	"github.com/getzep/zep/pkg/searchfallback"
+	"github.com/getzep/zep/pkg/tasks"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 const OKResponse = "OK"
 
+// MaxSessionBatchSize is the largest number of sessions that may be created in one request.
+const MaxSessionBatchSize = 1000
+
 // GetMemoryHandler godoc
 //
 //	@Summary		Returns a memory (latest summary and list of messages) for a given session
-//	@Description	get memory by session id
+//	@Description	get memory by session id, with an ETag header. If an If-None-Match header
+//	@Description	matches the ETag, 304 Not Modified is returned. With a query, the messages
+//	@Description	are the k most relevant to the query, most relevant first, rather than the last.
 //	@Tags			memory
 //	@Accept			json
-//	@Produce		json
+//	@Produce		json,application/msgpack
 //	@Param			sessionId	path		string	true	"Session ID"
 //	@Param			lastn		query		integer	false	"Last N messages. Overrides memory_window configuration"
+//	@Param			snapshot	query		boolean	false	"Store a snapshot of the returned memory for the turn"
+//	@Param			turn		query		string	false	"Turn UUID of the snapshot. Defaults to the UUID of the last message"
+//	@Param			query		query		string	false	"Return the messages most relevant to this text"
+//	@Param			k			query		integer	false	"Number of relevant messages. Defaults to lastn, then memory_window"
 //	@Success		200			{object}	[]models.Memory
+//	@Failure		400			{object}	APIError	"Bad Request"
 //	@Failure		404			{object}	APIError	"Not Found"
 //	@Failure		500			{object}	APIError	"Internal Server Error"
 //	@Security		Bearer
@@ -35,6 +50,37 @@ func GetMemoryHandler(appState *models.AppState) http.HandlerFunc {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
+		snapshot, err := handlertools.BoolFromQuery(r, "snapshot")
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		query := r.URL.Query().Get("query")
+		k, err := handlertools.IntFromQuery[int](r, "k")
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if k < 0 || (k > 0 && query == "") {
+			handlertools.RenderError(
+				w,
+				fmt.Errorf("k must be positive, and requires a query"),
+				http.StatusBadRequest,
+			)
+			return
+		}
+		var turnUUID uuid.UUID
+		if turn := r.URL.Query().Get("turn"); turn != "" {
+			turnUUID, err = uuid.Parse(turn)
+			if err != nil {
+				handlertools.RenderError(
+					w,
+					fmt.Errorf("unable to parse turn UUID: %w", err),
+					http.StatusBadRequest,
+				)
+				return
+			}
+		}
 
 		sessionMemory, err := appState.MemoryStore.GetMemory(r.Context(), sessionID, lastN)
 		if err != nil {
@@ -46,21 +92,83 @@ func GetMemoryHandler(appState *models.AppState) http.HandlerFunc {
 			return
 		}
 
-		if err := handlertools.EncodeJSON(w, sessionMemory); err != nil {
+		if query != "" {
+			if k == 0 {
+				k = lastN
+			}
+			if k == 0 {
+				k = appState.Config.Memory.MessageWindow
+			}
+			sessionMemory.Messages, err = relevantMessages(r, appState, sessionID, query, k)
+			if err != nil {
+				handlertools.RenderError(w, err, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if appState.Config.Extractors.Messages.TaskState.Enabled {
+			session, err := appState.MemoryStore.GetSession(r.Context(), sessionID)
+			if err != nil {
+				handlertools.RenderError(w, err, http.StatusInternalServerError)
+				return
+			}
+			sessionMemory.TaskState = session.TaskState
+		}
+
+		if snapshot {
+			turnUUID, err = putMemorySnapshot(r, appState, sessionID, turnUUID, sessionMemory)
+			if err != nil {
+				handlertools.RenderError(w, err, http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set(SnapshotTurnHeader, turnUUID.String())
+		}
+		recordProvenance(w, r, appState, memoryProvenance(sessionID, sessionMemory))
+
+		if err := handlertools.EncodeFieldsIfModified(w, r, sessionMemory, nil); err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
+// relevantMessages returns the k messages of a session most relevant to query, most
+// relevant first.
+func relevantMessages(
+	r *http.Request,
+	appState *models.AppState,
+	sessionID string,
+	query string,
+	k int,
+) ([]models.Message, error) {
+	results, err := appState.MemoryStore.SearchMemory(
+		r.Context(),
+		sessionID,
+		&models.MemorySearchPayload{Text: query, SearchScope: models.SearchScopeMessages},
+		k,
+	)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]models.Message, 0, len(results))
+	for _, result := range results {
+		if result.Message != nil {
+			messages = append(messages, *result.Message)
+		}
+	}
+	return messages, nil
+}
+
 // GetSessionHandler godoc
 //
 //	@Summary		Returns a session by ID
-//	@Description	get session by id
+//	@Description	get session by id, with an ETag header. If an If-None-Match header matches
+//	@Description	the ETag, 304 Not Modified is returned.
 //	@Tags			session
 //	@Accept			json
-//	@Produce		json
+//	@Produce		json,application/msgpack
 //	@Param			sessionId	path		string	true	"Session ID"
+//	@Param			fields		query		string	false	"Comma separated fields to return, such as session_id,created_at"
 //	@Success		200			{object}	models.Session
 //	@Failure		404			{object}	APIError	"Not Found"
 //	@Failure		500			{object}	APIError	"Internal Server Error"
@@ -69,18 +177,55 @@ func GetMemoryHandler(appState *models.AppState) http.HandlerFunc {
 func GetSessionHandler(appState *models.AppState) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		sessionID := chi.URLParam(r, "sessionId")
+		fields, err := handlertools.FieldsFromQuery(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
 
 		session, err := appState.MemoryStore.GetSession(r.Context(), sessionID)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, fmt.Errorf("not found"), http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
 
-		if err := handlertools.EncodeJSON(w, session); err != nil {
+		if err := handlertools.EncodeFieldsIfModified(w, r, session, fields); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetSessionEpisodesHandler godoc
+//
+//	@Summary		Returns a session's episodes
+//	@Description	get the episodes a session has been segmented into by the episode extractor,
+//	@Description	oldest first. Only the latest episode may be open.
+//	@Tags			session
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionId	path		string	true	"Session ID"
+//	@Success		200			{object}	models.EpisodeListResponse
+//	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/sessions/{sessionId}/episodes [get]
+func GetSessionEpisodesHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "sessionId")
+
+		session, err := appState.MemoryStore.GetSession(r.Context(), sessionID)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		episodes := session.Episodes
+		if episodes == nil {
+			episodes = []models.Episode{}
+		}
+		response := models.EpisodeListResponse{Episodes: episodes}
+		if err := handlertools.EncodeJSON(w, response); err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -122,24 +267,88 @@ func CreateSessionHandler(appState *models.AppState) http.HandlerFunc {
 	}
 }
 
+// CreateSessionsHandler godoc
+//
+//	@Summary		Add a batch of sessions
+//	@Description	add up to 1000 sessions in a single transaction. Sessions that fail to be created,
+//	@Description	such as those with an existing session ID or unknown user, do not fail the batch.
+//	@Description	The outcome of each session is returned.
+//	@Tags			session
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessions	body		[]models.CreateSessionRequest	true	"Sessions"
+//	@Success		200			{object}	models.CreateSessionsResponse
+//	@Failure		400			{object}	APIError	"Bad Request"
+//	@failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/sessions/batch [post]
+func CreateSessionsHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var sessions []models.CreateSessionRequest
+		if err := handlertools.DecodeJSON(r, &sessions); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if len(sessions) == 0 || len(sessions) > MaxSessionBatchSize {
+			handlertools.RenderError(
+				w,
+				fmt.Errorf("between 1 and %d sessions may be created at once", MaxSessionBatchSize),
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		response, err := appState.MemoryStore.CreateSessions(r.Context(), sessions)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, response); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 // UpdateSessionHandler godoc
 //
 //	@Summary		Add a session
-//	@Description	add session by id
+//	@Description	add session by id. Metadata is deep merged. A body with a Content-Type of
+//	@Description	application/json-patch+json or application/merge-patch+json is instead applied
+//	@Description	to the session's metadata as a JSON Patch or JSON Merge Patch, which may remove keys.
 //	@Tags			session
-//	@Accept			json
+//	@Accept			json,application/json-patch+json,application/merge-patch+json
 //	@Produce		json
 //	@Param			sessionId	path		string						true	"Session ID"
 //	@Param			session		body		models.UpdateSessionRequest	true	"Session"
 //	@Success		200			{object}	models.Session
 //	@Failure		400			{object}	APIError	"Bad Request"
 //	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		409			{object}	APIError	"JSON Patch test failed"
 //	@failure		500			{object}	APIError	"Internal Server Error"
 //	@Security		Bearer
 //	@Router			/api/v1/sessions/{sessionId} [patch]
 func UpdateSessionHandler(appState *models.AppState) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		sessionID := chi.URLParam(r, "sessionId")
+		patch, err := metadataPatchFromRequest(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if patch != nil {
+			patched, err := appState.MemoryStore.PatchSessionMetadata(r.Context(), sessionID, patch)
+			if err != nil {
+				handlertools.RenderError(w, err, http.StatusInternalServerError)
+				return
+			}
+			if err := handlertools.EncodeJSON(w, patched); err != nil {
+				handlertools.RenderError(w, err, http.StatusInternalServerError)
+			}
+			return
+		}
+
 		var session models.UpdateSessionRequest
 		if err := handlertools.DecodeJSON(r, &session); err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
@@ -149,10 +358,6 @@ func UpdateSessionHandler(appState *models.AppState) http.HandlerFunc {
 
 		updatedSession, err := appState.MemoryStore.UpdateSession(r.Context(), &session)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, fmt.Errorf("not found"), http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -166,12 +371,14 @@ func UpdateSessionHandler(appState *models.AppState) http.HandlerFunc {
 // GetSessionListHandler godoc
 //
 //	@Summary		Returns all sessions
-//	@Description	get all sessions with optional limit and cursor for pagination
+//	@Description	get all sessions with optional limit and cursor for pagination. In v2, the cursor is
+//	@Description	the opaque meta.next_cursor of the previous page.
 //	@Tags			session
 //	@Accept			json
 //	@Produce		json
 //	@Param			limit	query		integer	false	"Limit the number of results returned"
 //	@Param			cursor	query		int64	false	"Cursor for pagination"
+//	@Param			fields	query		string	false	"Comma separated fields to return, such as session_id,created_at"
 //	@Success		200		{array}		[]models.Session
 //	@Failure		400		{object}	APIError	"Bad Request"
 //	@Failure		500		{object}	APIError	"Internal Server Error"
@@ -181,23 +388,38 @@ func UpdateSessionHandler(appState *models.AppState) http.HandlerFunc {
 //	@Router			/api/v1/sessions [get]
 func GetSessionListHandler(appState *models.AppState) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var limit int
-		var err error
-		if limit, err = handlertools.IntFromQuery[int](r, "limit"); err != nil {
+		fields, err := handlertools.FieldsFromQuery(r)
+		if err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
+		var limit int
 		var cursor int64
-		if cursor, err = handlertools.IntFromQuery[int64](r, "cursor"); err != nil {
-			handlertools.RenderError(w, err, http.StatusBadRequest)
-			return
+		if handlertools.IsEnveloped(r) {
+			if cursor, limit, err = handlertools.PageFromQuery(r, handlertools.Limits(appState.Config).Pages); err != nil {
+				handlertools.RenderError(w, err, http.StatusBadRequest)
+				return
+			}
+		} else {
+			if limit, err = handlertools.IntFromQuery[int](r, "limit"); err != nil {
+				handlertools.RenderError(w, err, http.StatusBadRequest)
+				return
+			}
+			if cursor, err = handlertools.IntFromQuery[int64](r, "cursor"); err != nil {
+				handlertools.RenderError(w, err, http.StatusBadRequest)
+				return
+			}
 		}
 		sessions, err := appState.MemoryStore.ListSessions(r.Context(), cursor, limit)
 		if err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
-		if err := handlertools.EncodeJSON(w, sessions); err != nil {
+		// sessions are listed in ID order, so the last ID is the cursor of the next page
+		if len(sessions) > 0 && len(sessions) == limit {
+			handlertools.SetNextCursor(w, sessions[len(sessions)-1].ID)
+		}
+		if err := handlertools.EncodeJSONFields(w, sessions, fields); err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -249,6 +471,7 @@ func PostMemoryHandler(appState *models.AppState) http.HandlerFunc {
 //	@Param			sessionId	path		string		true	"Session ID"
 //	@Success		200			{string}	string		"OK"
 //	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		409			{object}	APIError	"Session under legal hold"
 //	@Failure		500			{object}	APIError	"Internal Server Error"
 //	@Security		Bearer
 //	@Router			/api/v1/sessions/{sessionId}/memory [delete]
@@ -257,10 +480,6 @@ func DeleteMemoryHandler(appState *models.AppState) http.HandlerFunc {
 		sessionID := chi.URLParam(r, "sessionId")
 
 		if err := appState.MemoryStore.DeleteSession(r.Context(), sessionID); err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, fmt.Errorf("not found"), http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -268,17 +487,64 @@ func DeleteMemoryHandler(appState *models.AppState) http.HandlerFunc {
 	}
 }
 
+// TruncateSessionHandler godoc
+//
+//	@Summary		Truncate the messages of a given session
+//	@Description	Prunes the content of the session's oldest messages, keeping those chosen by the strategy:
+//	@Description	keep_last_n keeps the n most recent messages, keep_last_n_tokens keeps the most recent messages
+//	@Description	totalling at most n tokens, and summary_only keeps none. Messages with "pinned": true in their
+//	@Description	metadata are always kept. Truncated messages are summarized first if the summarizer is enabled.
+//	@Tags			memory
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionId	path		string							true	"Session ID"
+//	@Param			request		body		models.TruncateSessionRequest	true	"Truncation strategy"
+//	@Success		200			{object}	models.TruncateSessionResponse
+//	@Failure		400			{object}	APIError	"Bad Request"
+//	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/sessions/{sessionId}/truncate [post]
+func TruncateSessionHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "sessionId")
+		var request models.TruncateSessionRequest
+		if err := handlertools.DecodeJSON(r, &request); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(request); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		response, err := tasks.TruncateSession(r.Context(), appState, sessionID, &request)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		if err := handlertools.EncodeJSON(w, response); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 // SearchMemoryHandler godoc
 //
 //	@Summary		Search memory messages for a given session
 //	@Description	search memory messages by session id and query
 //	@Tags			search
 //	@Accept			json
-//	@Produce		json
+//	@Produce		json,application/msgpack
 //	@Param			sessionId		path		string						true	"Session ID"
-//	@Param			limit			query		integer						false	"Limit the number of results returned"
+//	@Param			limit			query		integer						false	"Limit the number of results returned, also with max_tokens set"
+//	@Param			fields			query		string						false	"Comma separated fields to return, such as message.uuid,dist"
+//	@Param			debug			query		boolean						false	"Return the results with how the search was run"
 //	@Param			searchPayload	body		models.MemorySearchPayload	true	"Search query"
 //	@Success		200				{object}	[]models.MemorySearchResult
+//	@Success		200				{object}	models.MemorySearchDebugResponse	"With debug set"
+//	@Failure		400				{object}	APIError	"Bad Request"
 //	@Failure		404				{object}	APIError	"Not Found"
 //	@Failure		500				{object}	APIError	"Internal Server Error"
 //	@Security		Bearer
@@ -291,11 +557,31 @@ func SearchMemoryHandler(appState *models.AppState) http.HandlerFunc {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
-		limit, err := handlertools.IntFromQuery[int](r, "limit")
+		limit, err := memorySearchLimit(r, appState, &payload)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		fields, err := handlertools.FieldsFromQuery(r)
 		if err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
 			return
 		}
+		debug, err := searchDebugFromQuery(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		r = r.WithContext(models.WithSearchDebug(r.Context(), debug))
+		variant := experiment.ApplyToMemorySearch(
+			appState.Config.Experiments.Retrieval,
+			sessionID,
+			&payload,
+		)
+		if variant != "" {
+			w.Header().Set(experiment.VariantHeader, variant)
+		}
+		started := time.Now()
 		searchResult, err := appState.MemoryStore.SearchMemory(
 			r.Context(),
 			sessionID,
@@ -306,9 +592,116 @@ func SearchMemoryHandler(appState *models.AppState) http.HandlerFunc {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
-		if err := handlertools.EncodeJSON(w, searchResult); err != nil {
+		if len(searchResult) == 0 && payload.Fallback {
+			for _, fallback := range searchfallback.Memory(&payload) {
+				searchResult, err = appState.MemoryStore.SearchMemory(
+					r.Context(),
+					sessionID,
+					fallback.Query,
+					limit,
+				)
+				if err != nil {
+					handlertools.RenderError(w, err, http.StatusInternalServerError)
+					return
+				}
+				if len(searchResult) > 0 {
+					w.Header().Set(searchfallback.Header, fallback.Name)
+					break
+				}
+			}
+		}
+		if payload.MaxTokens > 0 {
+			searchResult = search.FitTokenBudget(searchResult, payload.MaxTokens)
+		}
+		logSearch(r, appState, started, &models.LoggedSearch{
+			Kind:        models.SearchKindMemory,
+			SessionID:   sessionID,
+			Text:        payload.Text,
+			SearchType:  payload.SearchType,
+			ResultCount: len(searchResult),
+		})
+		recordProvenance(w, r, appState, memorySearchProvenance(sessionID, searchResult))
+		if payload.Highlight {
+			searchResult = highlightMemoryResults(&payload, searchResult)
+		}
+		if err := encodeMemorySearch(w, r, searchResult, debug, fields); err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
 	}
 }
+
+// memorySearchLimit returns the number of results to search for. A search with a token
+// budget searches up to the maximum limit, unless the limit query parameter is set, as
+// its results are limited by the budget.
+func memorySearchLimit(
+	r *http.Request,
+	appState *models.AppState,
+	payload *models.MemorySearchPayload,
+) (int, error) {
+	if payload.MaxTokens < 0 {
+		return 0, models.NewValidationError("max_tokens must not be negative")
+	}
+	limits := handlertools.Limits(appState.Config).MemorySearch
+	if payload.MaxTokens > 0 && !r.URL.Query().Has("limit") {
+		return limits.Max, nil
+	}
+	return handlertools.LimitFromQuery(r, limits)
+}
+
+// searchDebugFromQuery returns a SearchDebug to collect how a search is run if the debug
+// query parameter is set, or nil.
+func searchDebugFromQuery(r *http.Request) (*models.SearchDebug, error) {
+	debug, err := handlertools.BoolFromQuery(r, "debug")
+	if err != nil || !debug {
+		return nil, err
+	}
+	return &models.SearchDebug{Distances: []float64{}}, nil
+}
+
+// encodeMemorySearch encodes the results of a memory search, with how the search was run
+// if it was debugged. The fields of debugged searches select those of the results.
+func encodeMemorySearch(
+	w http.ResponseWriter,
+	r *http.Request,
+	results []models.MemorySearchResult,
+	debug *models.SearchDebug,
+	fields handlertools.Fields,
+) error {
+	if debug == nil {
+		return handlertools.EncodeFields(w, r, results, fields)
+	}
+	if fields != nil {
+		fields = handlertools.Fields{"results": fields, "debug": nil}
+	}
+	return handlertools.EncodeFields(
+		w,
+		r,
+		models.MemorySearchDebugResponse{Results: results, Debug: debug},
+		fields,
+	)
+}
+
+// highlightMemoryResults returns a copy of the results with the spans of their content
+// matching the payload's text highlighted: its terms, or the text itself for literal
+// searches. Results are copied as they may be shared by the search cache.
+func highlightMemoryResults(
+	payload *models.MemorySearchPayload,
+	results []models.MemorySearchResult,
+) []models.MemorySearchResult {
+	spans := search.MatchedSpans
+	if payload.SearchType == models.SearchTypeLiteral {
+		spans = search.LiteralSpans
+	}
+	highlighted := make([]models.MemorySearchResult, len(results))
+	for i, result := range results {
+		switch {
+		case result.Message != nil:
+			result.Highlights = spans(result.Message.Content, payload.Text)
+		case result.Summary != nil:
+			result.Highlights = spans(result.Summary.Content, payload.Text)
+		}
+		highlighted[i] = result
+	}
+	return highlighted
+}
@@ -1,9 +1,6 @@
 package apihandlers
 
 import (
-	"encoding/json"
-	"errors"
-	"fmt"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
@@ -14,8 +11,6 @@ import (
 	"github.com/google/uuid"
 )
 
-const DefaultMessageLimit = 100
-
 // UpdateMessageMetadataHandler updates the metadata of a specific message.
 //
 // This function handles HTTP PATCH requests at the /api/v1/session/{sessionId}/message/{messageId} endpoint.
@@ -46,7 +41,7 @@ func UpdateMessageMetadataHandler(appState *models.AppState) http.HandlerFunc {
 
 		message := models.Message{}
 		message.UUID = messageUUID
-		err := json.NewDecoder(r.Body).Decode(&message)
+		err := handlertools.DecodeJSON(r, &message)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -54,24 +49,14 @@ func UpdateMessageMetadataHandler(appState *models.AppState) http.HandlerFunc {
 
 		err = appState.MemoryStore.UpdateMessages(r.Context(), sessionID, []models.Message{message}, false, false)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, fmt.Errorf("not found"), http.StatusNotFound)
-				return
-			} else {
-				handlertools.RenderError(w, err, http.StatusInternalServerError)
-				return
-			}
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
 		}
 
 		messages, err := appState.MemoryStore.GetMessagesByUUID(r.Context(), sessionID, []uuid.UUID{messageUUID})
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, fmt.Errorf("not found"), http.StatusNotFound)
-				return
-			} else {
-				handlertools.RenderError(w, err, http.StatusInternalServerError)
-				return
-			}
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
 		}
 
 		if err := handlertools.EncodeJSON(w, messages[0]); err != nil {
@@ -97,6 +82,7 @@ func UpdateMessageMetadataHandler(appState *models.AppState) http.HandlerFunc {
 //	@Produce		json
 //	@Param			sessionId	path		string	true	"Session ID"
 //	@Param			messageId	path		string	true	"Message ID"
+//	@Param			fields		query		string	false	"Comma separated fields to return, such as uuid,content"
 //	@Success		200			{object}	models.Message
 //	@Failure		404			{object}	APIError	"Not Found"
 //	@Failure		500			{object}	APIError	"Internal Server Error"
@@ -106,20 +92,20 @@ func GetMessageHandler(appState *models.AppState) http.HandlerFunc {
 		sessionID := chi.URLParam(r, "sessionId")
 		messageUUID := handlertools.UUIDFromURL(r, w, "messageId")
 		log.Debugf("GetMessageHandler: sessionID: %s, messageID: %s", sessionID, messageUUID)
+		fields, err := handlertools.FieldsFromQuery(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
 
 		messageIDs := []uuid.UUID{messageUUID}
 		messages, err := appState.MemoryStore.GetMessagesByUUID(r.Context(), sessionID, messageIDs)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, fmt.Errorf("not found"), http.StatusNotFound)
-				return
-			} else {
-				handlertools.RenderError(w, err, http.StatusInternalServerError)
-				return
-			}
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
 		}
 
-		if err := handlertools.EncodeJSON(w, messages[0]); err != nil {
+		if err := handlertools.EncodeJSONFields(w, messages[0], fields); err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -136,12 +122,18 @@ func GetMessageHandler(appState *models.AppState) http.HandlerFunc {
 // If there is an error while fetching the messages, the function responds with a 500 Internal Server Error status code.
 //
 //	@Summary		Retrieves all messages for a specific session
-//	@Description	get messages by session id
+//	@Description	get messages by session id, with an ETag header. If an If-None-Match header
+//	@Description	matches the ETag, 304 Not Modified is returned.
 //	@Tags			messages
 //	@Accept			json
-//	@Produce		json
+//	@Produce		json,application/msgpack
 //	@Param			sessionId	path		string	true	"Session ID"
+//	@Param			limit		query		int		false	"Limit the number of messages returned"
+//	@Param			cursor		query		string	false	"Page number, or in v2 the opaque meta.next_cursor of the previous page"
+//	@Param			fields		query		string	false	"Comma separated fields to return, such as messages.uuid"
+//	@Param			content_regex	query	string	false	"Only return messages whose content matches this regular expression"
 //	@Success		200			{array}		models.Message
+//	@Failure		400			{object}	APIError	"Bad Request"
 //	@Failure		404			{object}	APIError	"Not Found"
 //	@Failure		500			{object}	APIError	"Internal Server Error"
 //	@Router			/api/v1/session/{sessionId}/messages [get]
@@ -149,26 +141,101 @@ func GetMessagesForSessionHandler(appState *models.AppState) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		sessionID := chi.URLParam(r, "sessionId")
 
-		var limit int
-		var err error
-		if limit, err = handlertools.IntFromQuery[int](r, "limit"); err != nil {
-			limit = DefaultMessageLimit
+		fields, err := handlertools.FieldsFromQuery(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
 		}
 
+		limits := handlertools.Limits(appState.Config).Messages
+		var limit int
 		var cursor int
-		if cursor, err = handlertools.IntFromQuery[int](r, "cursor"); err != nil {
-			cursor = 1
+		if handlertools.IsEnveloped(r) {
+			// the cursor is a page number
+			var page int64
+			if page, limit, err = handlertools.PageFromQuery(r, limits); err != nil {
+				handlertools.RenderError(w, err, http.StatusBadRequest)
+				return
+			}
+			cursor = max(int(page), 1)
+		} else {
+			if limit, err = handlertools.LimitFromQuery(r, limits); err != nil {
+				handlertools.RenderError(w, err, http.StatusBadRequest)
+				return
+			}
+			if cursor, err = handlertools.IntFromQuery[int](r, "cursor"); err != nil {
+				cursor = 1
+			}
 		}
 
 		log.Debugf("GetMessagesForSessionHandler - SessionId %s Limit %d Cursor %d", sessionID, limit, cursor)
 
-		messages, err := appState.MemoryStore.GetMessageList(r.Context(), sessionID, cursor, limit)
+		var messages *models.MessageListResponse
+		if contentRegex := r.URL.Query().Get("content_regex"); contentRegex != "" {
+			messages, err = appState.MemoryStore.GetMatchingMessageList(
+				r.Context(),
+				sessionID,
+				&models.MessageFilter{ContentRegex: contentRegex},
+				cursor,
+				limit,
+			)
+		} else {
+			messages, err = appState.MemoryStore.GetMessageList(r.Context(), sessionID, cursor, limit)
+		}
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		if cursor*limit < messages.TotalCount {
+			handlertools.SetNextCursor(w, int64(cursor+1))
+		}
+
+		if err := handlertools.EncodeFieldsIfModified(w, r, messages, fields); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// DeleteMessagesHandler godoc
+//
+//	@Summary		Deletes the messages of a session matching a regular expression
+//	@Description	Deletes the messages of a session whose content matches content_regex, and their
+//	@Description	embeddings, such as to remove leaked credentials. Expressions are limited in complexity,
+//	@Description	and time out, by memory.message_regex.
+//	@Tags			messages
+//	@Produce		json
+//	@Param			sessionId		path		string	true	"Session ID"
+//	@Param			content_regex	query		string	true	"Regular expression the content of deleted messages matches"
+//	@Success		200				{object}	models.DeleteMessagesResponse
+//	@Failure		400				{object}	APIError	"Bad Request"
+//	@Failure		404				{object}	APIError	"Not Found"
+//	@Failure		409				{object}	APIError	"Session under legal hold"
+//	@Failure		500				{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/sessions/{sessionId}/messages [delete]
+func DeleteMessagesHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "sessionId")
+		filter := &models.MessageFilter{ContentRegex: r.URL.Query().Get("content_regex")}
+		if err := filter.Validate(appState.Config.Memory.MessageRegex.MaxComplexity); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if _, err := appState.MemoryStore.GetSession(r.Context(), sessionID); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		deleted, err := appState.MemoryStore.DeleteMatchingMessages(r.Context(), sessionID, filter)
 		if err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
+		log.Infof("deleted %d messages of session %s matching %q", deleted, sessionID, filter.ContentRegex)
 
-		if err := handlertools.EncodeJSON(w, messages); err != nil {
+		response := models.DeleteMessagesResponse{DeletedCount: deleted}
+		if err := handlertools.EncodeJSON(w, response); err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
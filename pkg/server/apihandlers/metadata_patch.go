@@ -0,0 +1,36 @@
+package apihandlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/getzep/zep/pkg/jsonpatch"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// metadataPatchFromRequest decodes a JSON Patch or JSON Merge Patch of metadata from the
+// body of r if r's Content-Type is one of theirs, and otherwise returns nil. Patches aren't
+// decoded strictly, as RFC 6902 requires unknown members of an operation to be ignored.
+func metadataPatchFromRequest(r *http.Request) (models.MetadataPatch, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil
+	}
+	switch mediaType {
+	case jsonpatch.ContentType:
+		var patch jsonpatch.Patch
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			return nil, err
+		}
+		return patch, nil
+	case jsonpatch.MergeContentType:
+		var patch jsonpatch.MergePatch
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			return nil, err
+		}
+		return patch, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,125 @@
+package apihandlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/handlertools"
+)
+
+// ResponseIDHeader is the response header holding the ID by which the provenance of the
+// response's context can be looked up.
+const ResponseIDHeader = "X-Zep-Response-Id"
+
+// GetProvenanceHandler godoc
+//
+//	@Summary		Returns the provenance of a response's context
+//	@Description	get the messages, summaries, and documents returned in a response, by the response's X-Zep-Response-Id
+//	@Tags			provenance
+//	@Accept			json
+//	@Produce		json
+//	@Param			responseId	path		string	true	"Response ID"
+//	@Success		200			{object}	models.ContextProvenance
+//	@Failure		400			{object}	APIError	"Bad Request"
+//	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/provenance/{responseId} [get]
+func GetProvenanceHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseID, err := uuid.Parse(chi.URLParam(r, "responseId"))
+		if err != nil {
+			handlertools.RenderError(
+				w,
+				fmt.Errorf("unable to parse response ID: %w", err),
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		provenance, err := appState.ProvenanceStore.Get(r.Context(), responseID)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, provenance); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// recordProvenance stores provenance under a new response ID and sets the
+// ResponseIDHeader, if provenance is enabled. A failure is logged rather than failing the
+// response, which has no ID then.
+func recordProvenance(
+	w http.ResponseWriter,
+	r *http.Request,
+	appState *models.AppState,
+	provenance *models.ContextProvenance,
+) {
+	if !appState.Config.Provenance.Enabled || appState.ProvenanceStore == nil {
+		return
+	}
+	provenance.ResponseID = uuid.New()
+	if err := appState.ProvenanceStore.Put(r.Context(), provenance); err != nil {
+		log.Errorf("failed to record context provenance: %v", err)
+		return
+	}
+	w.Header().Set(ResponseIDHeader, provenance.ResponseID.String())
+}
+
+func memoryProvenance(sessionID string, memory *models.Memory) *models.ContextProvenance {
+	provenance := &models.ContextProvenance{
+		Kind:      models.ProvenanceMemory,
+		SessionID: sessionID,
+	}
+	for _, m := range memory.Messages {
+		provenance.Messages = append(provenance.Messages, m.UUID)
+	}
+	if memory.Summary != nil && memory.Summary.UUID != uuid.Nil {
+		provenance.Summaries = append(provenance.Summaries, memory.Summary.UUID)
+	}
+	return provenance
+}
+
+func memorySearchProvenance(
+	sessionID string,
+	results []models.MemorySearchResult,
+) *models.ContextProvenance {
+	provenance := &models.ContextProvenance{
+		Kind:      models.ProvenanceMemorySearch,
+		SessionID: sessionID,
+	}
+	for _, result := range results {
+		if result.Message != nil {
+			provenance.Messages = append(provenance.Messages, result.Message.UUID)
+		}
+		if result.Summary != nil {
+			provenance.Summaries = append(provenance.Summaries, result.Summary.UUID)
+		}
+	}
+	return provenance
+}
+
+func documentSearchProvenance(
+	collectionName string,
+	page *models.DocumentSearchResultPage,
+) *models.ContextProvenance {
+	provenance := &models.ContextProvenance{
+		Kind:           models.ProvenanceDocumentSearch,
+		CollectionName: collectionName,
+	}
+	for _, result := range page.Results {
+		if result.DocumentResponse != nil {
+			provenance.Documents = append(provenance.Documents, result.UUID)
+		}
+	}
+	return provenance
+}
@@ -0,0 +1,173 @@
+package apihandlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/handlertools"
+)
+
+// ListProviderOverridesHandler godoc
+//
+//	@Summary		List provider overrides
+//	@Description	list the LLM and embedding provider overrides of tenants, ordered by tenant. API keys aren't returned
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{array}		[]models.ProviderOverride
+//	@Failure		403	{object}	APIError	"Forbidden"
+//	@Failure		500	{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/provider-overrides [get]
+func ListProviderOverridesHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !providerOverridesAvailable(w, appState) {
+			return
+		}
+
+		overrides, err := appState.ProviderOverrideStore.List(r.Context())
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		for i, override := range overrides {
+			overrides[i] = override.Redacted()
+		}
+
+		if err := handlertools.EncodeJSON(w, overrides); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetProviderOverrideHandler godoc
+//
+//	@Summary		Get a provider override
+//	@Description	get the LLM and embedding provider override of a tenant. API keys aren't returned
+//	@Tags			admin
+//	@Produce		json
+//	@Param			tenant	path		string	true	"Tenant"
+//	@Success		200		{object}	models.ProviderOverride
+//	@Failure		403		{object}	APIError	"Forbidden"
+//	@Failure		404		{object}	APIError	"Not Found"
+//	@Failure		500		{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/provider-overrides/{tenant} [get]
+func GetProviderOverrideHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !providerOverridesAvailable(w, appState) {
+			return
+		}
+
+		override, err := appState.ProviderOverrideStore.Get(r.Context(), chi.URLParam(r, "tenant"))
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, override.Redacted()); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// PutProviderOverrideHandler godoc
+//
+//	@Summary		Set a provider override
+//	@Description	set the LLM and embedding providers used for a tenant's requests and for the extractors processing its data, replacing any override of the tenant. API keys are stored encrypted. Embeddings must have the configured dimensions
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			tenant		path		string							true	"Tenant"
+//	@Param			override	body		models.ProviderOverrideRequest	true	"Providers"
+//	@Success		200			{object}	models.ProviderOverride
+//	@Failure		400			{object}	APIError	"Bad Request"
+//	@Failure		403			{object}	APIError	"Forbidden"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/provider-overrides/{tenant} [put]
+func PutProviderOverrideHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !providerOverridesAvailable(w, appState) {
+			return
+		}
+
+		var req models.ProviderOverrideRequest
+		if err := handlertools.DecodeJSON(r, &req); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		override := &models.ProviderOverride{
+			Tenant:     chi.URLParam(r, "tenant"),
+			LLM:        req.LLM,
+			Embeddings: req.Embeddings,
+		}
+		if err := llms.ValidateProviderOverride(override); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		stored, err := appState.ProviderOverrideStore.Put(r.Context(), override)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		appState.TenantProviders.Invalidate(stored.Tenant)
+		log.Infof("provider override of tenant %s set", stored.Tenant)
+
+		if err := handlertools.EncodeJSON(w, stored.Redacted()); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
+
+// DeleteProviderOverrideHandler godoc
+//
+//	@Summary		Delete a provider override
+//	@Description	delete the provider override of a tenant, whose requests then use the configured providers
+//	@Tags			admin
+//	@Produce		json
+//	@Param			tenant	path		string		true	"Tenant"
+//	@Success		200		{string}	string		"OK"
+//	@Failure		403		{object}	APIError	"Forbidden"
+//	@Failure		404		{object}	APIError	"Not Found"
+//	@Failure		500		{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/admin/provider-overrides/{tenant} [delete]
+func DeleteProviderOverrideHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !providerOverridesAvailable(w, appState) {
+			return
+		}
+
+		tenant := chi.URLParam(r, "tenant")
+		if err := appState.ProviderOverrideStore.Delete(r.Context(), tenant); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		appState.TenantProviders.Invalidate(tenant)
+		log.Infof("provider override of tenant %s deleted", tenant)
+
+		_, _ = w.Write([]byte(OKResponse))
+	}
+}
+
+func providerOverridesAvailable(w http.ResponseWriter, appState *models.AppState) bool {
+	if appState.ProviderOverrideStore == nil || appState.TenantProviders == nil {
+		handlertools.RenderError(
+			w,
+			errors.New("tenant providers are not enabled"),
+			http.StatusInternalServerError,
+		)
+		return false
+	}
+	return true
+}
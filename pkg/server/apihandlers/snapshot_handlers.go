@@ -0,0 +1,122 @@
+package apihandlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/handlertools"
+)
+
+// SnapshotTurnHeader is the response header holding the turn UUID of the memory snapshot
+// stored by GetMemoryHandler.
+const SnapshotTurnHeader = "X-Zep-Snapshot-Turn"
+
+// ListMemorySnapshotsHandler godoc
+//
+//	@Summary		List memory snapshots
+//	@Description	list the memory snapshots of a session, oldest first
+//	@Tags			memory
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionId	path		string						true	"Session ID"
+//	@Param			limit		query		int							false	"Limit"
+//	@Success		200			{array}		[]models.MemorySnapshot	"Successfully retrieved list of snapshots"
+//	@Failure		400			{object}	APIError					"Bad Request"
+//	@Failure		500			{object}	APIError					"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/sessions/{sessionId}/snapshots [get]
+func ListMemorySnapshotsHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "sessionId")
+		limit, err := handlertools.IntFromQuery[int](r, "limit")
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		snapshots, err := appState.MemorySnapshotStore.List(r.Context(), sessionID, limit)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, snapshots); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetMemorySnapshotHandler godoc
+//
+//	@Summary		Returns the memory snapshot of a turn
+//	@Description	get the memory returned for a turn of a session, for replaying it
+//	@Tags			memory
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionId	path		string	true	"Session ID"
+//	@Param			turnId		path		string	true	"Turn UUID"
+//	@Success		200			{object}	models.MemorySnapshot
+//	@Failure		400			{object}	APIError	"Bad Request"
+//	@Failure		404			{object}	APIError	"Not Found"
+//	@Failure		500			{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/sessions/{sessionId}/snapshots/{turnId} [get]
+func GetMemorySnapshotHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "sessionId")
+		turnUUID, err := uuid.Parse(chi.URLParam(r, "turnId"))
+		if err != nil {
+			handlertools.RenderError(
+				w,
+				fmt.Errorf("unable to parse turn UUID: %w", err),
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		snapshot, err := appState.MemorySnapshotStore.Get(r.Context(), sessionID, turnUUID)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, snapshot); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// putMemorySnapshot stores memory as the snapshot of turnUUID, or of the last message of
+// memory if turnUUID is nil, and returns the turn UUID.
+func putMemorySnapshot(
+	r *http.Request,
+	appState *models.AppState,
+	sessionID string,
+	turnUUID uuid.UUID,
+	memory *models.Memory,
+) (uuid.UUID, error) {
+	if turnUUID == uuid.Nil {
+		if len(memory.Messages) == 0 {
+			return uuid.Nil, models.NewValidationError(
+				"a turn is required to snapshot memory without messages",
+			)
+		}
+		turnUUID = memory.Messages[len(memory.Messages)-1].UUID
+	}
+
+	_, err := appState.MemorySnapshotStore.Put(r.Context(), &models.MemorySnapshot{
+		SessionID: sessionID,
+		TurnUUID:  turnUUID,
+		Memory:    memory,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to store memory snapshot: %w", err)
+	}
+	return turnUUID, nil
+}
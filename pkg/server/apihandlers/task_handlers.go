@@ -0,0 +1,132 @@
+package apihandlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/handlertools"
+)
+
+// ListTasksHandler godoc
+//
+//	@Summary		List tasks
+//	@Description	list long-running tasks, newest first
+//	@Tags			task
+//	@Accept			json
+//	@Produce		json
+//	@Param			type	query		string				false	"Task type"
+//	@Param			status	query		string				false	"Task status"
+//	@Param			limit	query		int					false	"Limit"
+//	@Success		200		{array}		[]models.AsyncTask	"Successfully retrieved list of tasks"
+//	@Failure		400		{object}	APIError			"Bad Request"
+//	@Failure		500		{object}	APIError			"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/tasks [get]
+func ListTasksHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := handlertools.IntFromQuery[int](r, "limit")
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		opts := models.AsyncTaskListOptions{
+			Type:   r.URL.Query().Get("type"),
+			Status: models.AsyncTaskStatus(r.URL.Query().Get("status")),
+			Limit:  limit,
+		}
+		tasks, err := appState.AsyncTaskStore.List(r.Context(), opts)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, tasks); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetTaskHandler godoc
+//
+//	@Summary		Returns a task by ID
+//	@Description	get a long-running task's status, progress, and result
+//	@Tags			task
+//	@Accept			json
+//	@Produce		json
+//	@Param			taskId	path		string	true	"Task UUID"
+//	@Success		200		{object}	models.AsyncTask
+//	@Failure		400		{object}	APIError	"Bad Request"
+//	@Failure		404		{object}	APIError	"Not Found"
+//	@Failure		500		{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/tasks/{taskId} [get]
+func GetTaskHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskUUID, err := taskUUIDFromURL(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		task, err := appState.AsyncTaskStore.Get(r.Context(), taskUUID)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, task); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// CancelTaskHandler godoc
+//
+//	@Summary		Cancels a task
+//	@Description	cancel a pending or running task. A running task stops at its next heartbeat.
+//	@Tags			task
+//	@Accept			json
+//	@Produce		json
+//	@Param			taskId	path		string	true	"Task UUID"
+//	@Success		200		{object}	models.AsyncTask
+//	@Failure		400		{object}	APIError	"Bad Request"
+//	@Failure		404		{object}	APIError	"Not Found"
+//	@Failure		409		{object}	APIError	"Conflict"
+//	@Failure		500		{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/tasks/{taskId}/cancel [post]
+func CancelTaskHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskUUID, err := taskUUIDFromURL(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		task, err := appState.AsyncTaskStore.Cancel(r.Context(), taskUUID)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, task); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func taskUUIDFromURL(r *http.Request) (uuid.UUID, error) {
+	taskUUID, err := uuid.Parse(chi.URLParam(r, "taskId"))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unable to parse task UUID: %w", err)
+	}
+	return taskUUID, nil
+}
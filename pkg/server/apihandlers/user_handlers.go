@@ -2,12 +2,14 @@ package apihandlers
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/getzep/zep/pkg/server/handlertools"
 
 	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/search"
+	"github.com/getzep/zep/pkg/tasks"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -65,10 +67,6 @@ func GetUserHandler(appState *models.AppState) http.HandlerFunc {
 
 		user, err := appState.UserStore.Get(r.Context(), userId)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, fmt.Errorf("not found"), http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -83,21 +81,41 @@ func GetUserHandler(appState *models.AppState) http.HandlerFunc {
 // UpdateUserHandler godoc
 //
 //	@Summary		Update a user
-//	@Description	update user by id
+//	@Description	update user by id. Metadata is deep merged. A body with a Content-Type of
+//	@Description	application/json-patch+json or application/merge-patch+json is instead applied
+//	@Description	to the user's metadata as a JSON Patch or JSON Merge Patch, which may remove keys.
 //	@Tags			user
-//	@Accept			json
+//	@Accept			json,application/json-patch+json,application/merge-patch+json
 //	@Produce		json
 //	@Param			userId	path		string						true	"User ID"
 //	@Param			user	body		models.UpdateUserRequest	true	"User"
 //	@Success		200		{object}	models.User
 //	@Failure		400		{object}	APIError	"Bad Request"
 //	@Failure		404		{object}	APIError	"Not Found"
+//	@Failure		409		{object}	APIError	"JSON Patch test failed"
 //	@Failure		500		{object}	APIError	"Internal Server Error"
 //	@Security		Bearer
 //	@Router			/api/v1/user/{userId} [patch]
 func UpdateUserHandler(appState *models.AppState) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID := chi.URLParam(r, "userId")
+		patch, err := metadataPatchFromRequest(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if patch != nil {
+			patched, err := appState.UserStore.PatchMetadata(r.Context(), userID, patch, true)
+			if err != nil {
+				handlertools.RenderError(w, err, http.StatusInternalServerError)
+				return
+			}
+			if err := handlertools.EncodeJSON(w, patched); err != nil {
+				handlertools.RenderError(w, err, http.StatusInternalServerError)
+			}
+			return
+		}
+
 		var user models.UpdateUserRequest
 		if err := handlertools.DecodeJSON(r, &user); err != nil {
 			handlertools.RenderError(w, err, http.StatusBadRequest)
@@ -108,10 +126,6 @@ func UpdateUserHandler(appState *models.AppState) http.HandlerFunc {
 
 		updatedUser, err := appState.UserStore.Update(r.Context(), &user, true)
 		if err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, fmt.Errorf("not found"), http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -133,6 +147,7 @@ func UpdateUserHandler(appState *models.AppState) http.HandlerFunc {
 //	@Param			userId	path		string		true	"User ID"
 //	@Success		200		{string}	string		"OK"
 //	@Failure		404		{object}	APIError	"Not Found"
+//	@Failure		409		{object}	APIError	"User or session under legal hold"
 //	@Failure		500		{object}	APIError	"Internal Server Error"
 //	@Security		Bearer
 //	@Router			/api/v1/user/{userId} [delete]
@@ -141,10 +156,6 @@ func DeleteUserHandler(appState *models.AppState) http.HandlerFunc {
 		userID := chi.URLParam(r, "userId")
 
 		if err := appState.UserStore.Delete(r.Context(), userID); err != nil {
-			if errors.Is(err, models.ErrNotFound) {
-				handlertools.RenderError(w, fmt.Errorf("not found"), http.StatusNotFound)
-				return
-			}
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -156,7 +167,8 @@ func DeleteUserHandler(appState *models.AppState) http.HandlerFunc {
 // ListAllUsersHandler godoc
 //
 //	@Summary		List all users
-//	@Description	list all users with pagination
+//	@Description	list all users with pagination. In v2, the cursor is the opaque meta.next_cursor of
+//	@Description	the previous page.
 //	@Tags			user
 //	@Accept			json
 //	@Produce		json
@@ -169,16 +181,23 @@ func DeleteUserHandler(appState *models.AppState) http.HandlerFunc {
 //	@Router			/api/v1/user [get]
 func ListAllUsersHandler(appState *models.AppState) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		limit, err := handlertools.IntFromQuery[int](r, "limit")
-		if err != nil {
-			handlertools.RenderError(w, err, http.StatusBadRequest)
-			return
-		}
-
-		cursor, err := handlertools.IntFromQuery[int64](r, "cursor")
-		if err != nil {
-			handlertools.RenderError(w, err, http.StatusBadRequest)
-			return
+		var limit int
+		var cursor int64
+		var err error
+		if handlertools.IsEnveloped(r) {
+			if cursor, limit, err = handlertools.PageFromQuery(r, handlertools.Limits(appState.Config).Pages); err != nil {
+				handlertools.RenderError(w, err, http.StatusBadRequest)
+				return
+			}
+		} else {
+			if limit, err = handlertools.IntFromQuery[int](r, "limit"); err != nil {
+				handlertools.RenderError(w, err, http.StatusBadRequest)
+				return
+			}
+			if cursor, err = handlertools.IntFromQuery[int64](r, "cursor"); err != nil {
+				handlertools.RenderError(w, err, http.StatusBadRequest)
+				return
+			}
 		}
 
 		users, err := appState.UserStore.ListAll(r.Context(), cursor, limit)
@@ -186,6 +205,9 @@ func ListAllUsersHandler(appState *models.AppState) http.HandlerFunc {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
+		if len(users) > 0 && len(users) == limit {
+			handlertools.SetNextCursor(w, users[len(users)-1].ID)
+		}
 
 		if err := handlertools.EncodeJSON(w, users); err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
@@ -197,11 +219,14 @@ func ListAllUsersHandler(appState *models.AppState) http.HandlerFunc {
 // ListUserSessionsHandler godoc
 //
 //	@Summary		List all sessions for a user
-//	@Description	list all sessions for a user by user id
+//	@Description	list all sessions for a user by user id. In v2, sessions are paginated using limit
+//	@Description	and cursor.
 //	@Tags			user
 //	@Accept			json
 //	@Produce		json
 //	@Param			userId	path		string	true	"User ID"
+//	@Param			limit	query		int		false	"Limit (v2 only)"
+//	@Param			cursor	query		string	false	"Cursor (v2 only)"
 //	@Success		200		{array}		models.Session
 //	@Failure		500		{object}	APIError	"Internal Server Error"
 //	@Security		Bearer
@@ -216,9 +241,190 @@ func ListUserSessionsHandler(appState *models.AppState) http.HandlerFunc {
 			return
 		}
 
+		if handlertools.IsEnveloped(r) {
+			// the cursor is an offset into the user's sessions
+			offset, limit, err := handlertools.PageFromQuery(r, handlertools.Limits(appState.Config).Pages)
+			if err != nil {
+				handlertools.RenderError(w, err, http.StatusBadRequest)
+				return
+			}
+			start := min(int(offset), len(sessions))
+			end := min(start+limit, len(sessions))
+			if end < len(sessions) {
+				handlertools.SetNextCursor(w, int64(end))
+			}
+			sessions = sessions[start:end]
+		}
+
 		if err := handlertools.EncodeJSON(w, sessions); err != nil {
 			handlertools.RenderError(w, err, http.StatusInternalServerError)
 			return
 		}
 	}
 }
+
+// GetUserTopicsHandler godoc
+//
+//	@Summary		Returns a user's topics
+//	@Description	get the topics last modeled from a user's messages, largest first
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			userId	path		string	true	"User ID"
+//	@Success		200		{object}	models.UserTopics
+//	@Failure		404		{object}	APIError	"Not Found"
+//	@Failure		500		{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/user/{userId}/topics [get]
+func GetUserTopicsHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := chi.URLParam(r, "userId")
+
+		user, err := appState.UserStore.Get(r.Context(), userID)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		topics, err := tasks.GetUserTopics(user)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, topics); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetUserFactsHandler godoc
+//
+//	@Summary		Returns the facts about a user
+//	@Description	get the facts synthesized from a user's important messages, most useful first
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			userId	path		string	true	"User ID"
+//	@Success		200		{object}	models.UserFacts
+//	@Failure		404		{object}	APIError	"Not Found"
+//	@Failure		500		{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/user/{userId}/facts [get]
+func GetUserFactsHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := chi.URLParam(r, "userId")
+
+		user, err := appState.UserStore.Get(r.Context(), userID)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		facts, err := tasks.GetUserFacts(user)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err := handlertools.EncodeJSON(w, facts); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// SearchUserMemoryHandler godoc
+//
+//	@Summary		Search the memory of a user's sessions
+//	@Description	search the messages of the user's sessions most similar to the query, found
+//	@Description	by their session embedding. Only similarity searches of messages are supported.
+//	@Tags			search
+//	@Accept			json
+//	@Produce		json,application/msgpack
+//	@Param			userId			path		string						true	"User ID"
+//	@Param			limit			query		integer						false	"Limit the number of results returned, also with max_tokens set"
+//	@Param			sessions		query		integer						false	"Number of sessions to search, 5 by default"
+//	@Param			fields			query		string						false	"Comma separated fields to return, such as message.uuid,dist"
+//	@Param			debug			query		boolean						false	"Return the results with how the search was run"
+//	@Param			searchPayload	body		models.MemorySearchPayload	true	"Search query"
+//	@Success		200				{object}	[]models.MemorySearchResult
+//	@Success		200				{object}	models.MemorySearchDebugResponse	"With debug set"
+//	@Failure		400				{object}	APIError	"Bad Request"
+//	@Failure		404				{object}	APIError	"Not Found"
+//	@Failure		500				{object}	APIError	"Internal Server Error"
+//	@Security		Bearer
+//	@Router			/api/v1/user/{userId}/search [post]
+func SearchUserMemoryHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := chi.URLParam(r, "userId")
+		var payload models.MemorySearchPayload
+		if err := handlertools.DecodeJSON(r, &payload); err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		limit, err := memorySearchLimit(r, appState, &payload)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		sessions, err := handlertools.IntFromQuery[int](r, "sessions")
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		if sessions < 0 {
+			handlertools.RenderError(
+				w,
+				errors.New("sessions must not be negative"),
+				http.StatusBadRequest,
+			)
+			return
+		}
+		fields, err := handlertools.FieldsFromQuery(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+		debug, err := searchDebugFromQuery(r)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if _, err := appState.UserStore.Get(r.Context(), userID); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		started := time.Now()
+		searchResult, err := appState.MemoryStore.SearchUserMemory(
+			models.WithSearchDebug(r.Context(), debug),
+			userID,
+			&payload,
+			sessions,
+			limit,
+		)
+		if err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+		if payload.MaxTokens > 0 {
+			searchResult = search.FitTokenBudget(searchResult, payload.MaxTokens)
+		}
+		logSearch(r, appState, started, &models.LoggedSearch{
+			Kind:        models.SearchKindMemory,
+			Text:        payload.Text,
+			SearchType:  payload.SearchType,
+			ResultCount: len(searchResult),
+		})
+		if payload.Highlight {
+			searchResult = highlightMemoryResults(&payload, searchResult)
+		}
+		if err := encodeMemorySearch(w, r, searchResult, debug, fields); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
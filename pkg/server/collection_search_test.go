@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// fakeSearchDocumentStore returns the results of each collection, with the scores given.
+// Only the methods called by the tests are implemented.
+type fakeSearchDocumentStore struct {
+	models.DocumentStore[any]
+	scores map[string][]float64
+}
+
+func (s *fakeSearchDocumentStore) SearchCollection(
+	_ context.Context,
+	query *models.DocumentSearchPayload,
+	_, _, _ int,
+) (*models.DocumentSearchResultPage, error) {
+	scores, ok := s.scores[query.CollectionName]
+	if !ok {
+		return nil, models.NewNotFoundError("collection " + query.CollectionName)
+	}
+	page := &models.DocumentSearchResultPage{}
+	for _, score := range scores {
+		page.Results = append(page.Results, models.DocumentSearchResult{
+			DocumentResponse: &models.DocumentResponse{
+				UUID:    uuid.New(),
+				Content: "an invoice from " + query.CollectionName,
+			},
+			Score: score,
+		})
+	}
+	return page, nil
+}
+
+func (s *fakeSearchDocumentStore) GetCollection(
+	_ context.Context,
+	collectionName string,
+) (models.DocumentCollection, error) {
+	return models.DocumentCollection{Name: collectionName}, nil
+}
+
+func TestSearchCollections(t *testing.T) {
+	searchState := &models.AppState{Config: &config.Config{}}
+	searchState.DocumentStore = &fakeSearchDocumentStore{scores: map[string][]float64{
+		"invoices": {0.9, 0.5},
+		"receipts": {0.6},
+	}}
+	router := chi.NewRouter()
+	setupCollectionRoutes(router, searchState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	search := func(payload string) *http.Response {
+		resp, err := http.Post(
+			server.URL+"/collection/search",
+			"application/json",
+			bytes.NewReader([]byte(payload)),
+		)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := search(`{"collection_names": ["Invoices", "receipts"], "text": "invoice", "highlight": true}`)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var page models.MultiCollectionSearchResultPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	require.Equal(t, 3, page.ResultCount)
+	assert.Equal(t, "Invoices", page.Results[0].CollectionName)
+	assert.Equal(t, "receipts", page.Results[1].CollectionName)
+	assert.Equal(t, 1.0, page.Results[1].Score)
+	assert.Equal(t, 0.6, page.Results[1].RawScore)
+	assert.Equal(t, 0.0, page.Results[2].Score)
+	assert.Equal(t, []models.TextSpan{{Start: 3, End: 10}}, page.Results[0].Highlights)
+
+	for payload, status := range map[string]int{
+		`{"collection_names": [], "text": "invoice"}`:                       http.StatusBadRequest,
+		`{"collection_names": ["invoices", "INVOICES"], "text": "invoice"}`: http.StatusBadRequest,
+		`{"collection_names": ["invoices"], "cursor": "abc", "text": "a"}`:  http.StatusBadRequest,
+		`{"collection_names": ["invoices", "missing"], "text": "invoice"}`:  http.StatusNotFound,
+		`{"collection_names": ["invoices"], "collection_name": "receipts"}`: http.StatusBadRequest,
+	} {
+		resp := search(payload)
+		resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, payload)
+	}
+
+	// a limit above server.limits.document_search.max is rejected
+	searchState.Config.Server.Limits.DocumentSearch.Max = 2
+	resp, err := http.Post(
+		server.URL+"/collection/search?limit=3",
+		"application/json",
+		bytes.NewReader([]byte(`{"collection_names": ["invoices"], "text": "invoice"}`)),
+	)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// the search route doesn't hide a collection named search
+	resp, err = http.Get(server.URL + "/collection/search")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+
+	"github.com/getzep/zep/pkg/auth"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/handlertools"
+)
+
+// setupDebugRoutes mounts net/http/pprof, runtime metrics and task worker pool gauges under
+// /debug. The routes always require a token with the admin scope, regardless of whether
+// auth is required for the API.
+func setupDebugRoutes(router chi.Router, appState *models.AppState) {
+	if appState.Config.Auth.Secret == "" {
+		log.Warn("profiling is enabled but auth.secret is not set. profiling endpoints are disabled")
+		return
+	}
+
+	log.Info("Profiling endpoints enabled at /debug")
+	router.Route("/debug", func(r chi.Router) {
+		r.Use(auth.JWTVerifier(appState.Config))
+		r.Use(jwtauth.Authenticator)
+		r.Use(auth.RequireScope(auth.ScopeAdmin))
+
+		r.Get("/runtime", RuntimeMetricsHandler)
+		r.Get("/tasks", TaskWorkerStatsHandler(appState))
+		r.Mount("/", middleware.Profiler())
+	})
+}
+
+// RuntimeMetrics is a snapshot of the Go runtime's goroutine, heap, and GC statistics.
+type RuntimeMetrics struct {
+	Goroutines     int     `json:"goroutines"`
+	HeapAlloc      uint64  `json:"heap_alloc_bytes"`
+	HeapInuse      uint64  `json:"heap_inuse_bytes"`
+	HeapObjects    uint64  `json:"heap_objects"`
+	Sys            uint64  `json:"sys_bytes"`
+	NumGC          uint32  `json:"num_gc"`
+	LastGCPause    string  `json:"last_gc_pause"`
+	TotalGCPause   string  `json:"total_gc_pause"`
+	GCCPUFraction  float64 `json:"gc_cpu_fraction"`
+	LastGC         string  `json:"last_gc,omitempty"`
+	NextGCHeapSize uint64  `json:"next_gc_heap_bytes"`
+}
+
+// RuntimeMetricsHandler returns the current RuntimeMetrics.
+func RuntimeMetricsHandler(w http.ResponseWriter, _ *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	metrics := RuntimeMetrics{
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAlloc:      m.HeapAlloc,
+		HeapInuse:      m.HeapInuse,
+		HeapObjects:    m.HeapObjects,
+		Sys:            m.Sys,
+		NumGC:          m.NumGC,
+		LastGCPause:    time.Duration(m.PauseNs[(m.NumGC+255)%256]).String(),
+		TotalGCPause:   time.Duration(m.PauseTotalNs).String(),
+		GCCPUFraction:  m.GCCPUFraction,
+		NextGCHeapSize: m.NextGC,
+	}
+	if m.LastGC > 0 {
+		metrics.LastGC = time.Unix(0, int64(m.LastGC)).UTC().Format(time.RFC3339)
+	}
+
+	if err := handlertools.EncodeJSON(w, metrics); err != nil {
+		handlertools.RenderError(w, err, http.StatusInternalServerError)
+	}
+}
+
+// TaskWorkerStatsHandler returns the current size, queue depth, and counters of each
+// task's worker pool.
+func TaskWorkerStatsHandler(appState *models.AppState) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		stats := []models.TaskWorkerStats{}
+		if appState.TaskRouter != nil {
+			stats = appState.TaskRouter.WorkerStats()
+		}
+
+		if err := handlertools.EncodeJSON(w, stats); err != nil {
+			handlertools.RenderError(w, err, http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/auth"
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestDebugRoutes(t *testing.T) {
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Secret: "test-secret",
+		},
+		Server: config.ServerConfig{
+			ProfilingEnabled: true,
+		},
+	}
+	router := setupRouter(&models.AppState{Config: cfg})
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("requires admin scope", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, get("/debug/runtime", "").Code)
+		assert.Equal(t, http.StatusForbidden, get("/debug/runtime", auth.GenerateJWT(cfg)).Code)
+	})
+
+	t.Run("runtime metrics", func(t *testing.T) {
+		res := get("/debug/runtime", auth.GenerateScopedJWT(cfg, auth.ScopeAdmin))
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var metrics RuntimeMetrics
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&metrics))
+		assert.Greater(t, metrics.Goroutines, 0)
+		assert.Greater(t, metrics.HeapAlloc, uint64(0))
+	})
+
+	t.Run("task worker stats", func(t *testing.T) {
+		res := get("/debug/tasks", auth.GenerateScopedJWT(cfg, auth.ScopeAdmin))
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var stats []models.TaskWorkerStats
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&stats))
+		assert.Empty(t, stats)
+	})
+
+	t.Run("pprof", func(t *testing.T) {
+		res := get("/debug/pprof/goroutine?debug=1", auth.GenerateScopedJWT(cfg, auth.ScopeAdmin))
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cfg := *cfg
+		cfg.Server.ProfilingEnabled = false
+		router := setupRouter(&models.AppState{Config: &cfg})
+		req := httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+		req.Header.Set("Authorization", "Bearer "+auth.GenerateScopedJWT(&cfg, auth.ScopeAdmin))
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
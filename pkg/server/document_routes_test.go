@@ -187,6 +187,79 @@ func TestGetCollectionHandler(t *testing.T) {
 	assert.Equal(t, rc.Metadata["key"], "value")
 }
 
+func TestPutCollectionHandler(t *testing.T) {
+	collectionName := strings.ToLower(testutils.GenerateRandomString(10))
+	url := testServer.URL + "/api/v1/collection/" + collectionName
+	client := &http.Client{}
+
+	autoEmbedded := false
+	collection := &models.CreateDocumentCollectionRequest{
+		Description:         "Test collection",
+		Metadata:            map[string]interface{}{"key": "value"},
+		EmbeddingDimensions: 128,
+		IsAutoEmbedded:      &autoEmbedded,
+	}
+	put := func(body any, ifMatch string) *http.Response {
+		b, err := json.Marshal(body)
+		assert.NoError(t, err)
+		req, err := http.NewRequest("PUT", url, bytes.NewBuffer(b))
+		assert.NoError(t, err)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// the first PUT creates the collection, and repeating it changes nothing
+	resp := put(collection, "")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	resp = put(collection, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, etag, resp.Header.Get("ETag"))
+
+	// a retried POST of the same collection succeeds
+	collection.Name = collectionName
+	b, err := json.Marshal(collection)
+	assert.NoError(t, err)
+	resp, err = client.Post(url, "application/json", bytes.NewBuffer(b))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err := http.NewRequest("GET", url, nil)
+	assert.NoError(t, err)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	collection.Description = "Updated Test collection"
+	resp = put(collection, etag)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+
+	// the old ETag is now stale
+	resp = put(collection, etag)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	// embedding dimensions can't be changed
+	collection.EmbeddingDimensions = 256
+	resp = put(collection, "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	rc, err := appState.DocumentStore.GetCollection(testCtx, collectionName)
+	assert.NoError(t, err)
+	assert.Equal(t, "Updated Test collection", rc.Description)
+	assert.Equal(t, 128, rc.EmbeddingDimensions)
+}
+
 func TestCreateDocumentsHandler(t *testing.T) {
 	collectionName := testutils.GenerateRandomString(10)
 	// Create a collection
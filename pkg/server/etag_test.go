@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestSessionETags(t *testing.T) {
+	ctx := context.Background()
+	db := inmemory.NewDB()
+	etagState := &models.AppState{Config: &config.Config{}}
+	etagState.MemoryStore = inmemory.NewMemoryStore(etagState, db)
+	router := chi.NewRouter()
+	setupSessionRoutes(router, etagState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	get := func(path, ifNoneMatch string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	_, err := etagState.MemoryStore.CreateSession(
+		ctx,
+		&models.CreateSessionRequest{SessionID: "s1"},
+	)
+	require.NoError(t, err)
+	err = etagState.MemoryStore.PutMemory(ctx, "s1", &models.Memory{
+		Messages: []models.Message{{Role: "user", Content: "hello"}},
+	}, true)
+	require.NoError(t, err)
+
+	for _, path := range []string{
+		"/sessions/s1",
+		"/sessions/s1/memory",
+		"/sessions/s1/messages",
+	} {
+		t.Run(path, func(t *testing.T) {
+			resp := get(path, "")
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			etag := resp.Header.Get("ETag")
+			require.NotEmpty(t, etag)
+
+			resp = get(path, etag)
+			assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+			assert.Equal(t, etag, resp.Header.Get("ETag"))
+		})
+	}
+
+	// a different selection of fields is a different representation
+	etag := get("/sessions/s1", "").Header.Get("ETag")
+	assert.Equal(t, http.StatusOK, get("/sessions/s1?fields=uuid", etag).StatusCode)
+
+	etag = get("/sessions/s1/messages", "").Header.Get("ETag")
+	err = etagState.MemoryStore.PutMemory(ctx, "s1", &models.Memory{
+		Messages: []models.Message{{Role: "user", Content: "again"}},
+	}, true)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, get("/sessions/s1/messages", etag).StatusCode)
+}
@@ -0,0 +1,159 @@
+package handlertools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	// DefaultPageSize is the number of items in a page of an enveloped list response if
+	// the request doesn't set a limit and server.limits.pages.default isn't configured.
+	DefaultPageSize = 50
+	// MaxPageSize is the largest page of an enveloped list response if
+	// server.limits.pages.max isn't configured.
+	MaxPageSize = 1000
+)
+
+// EnvelopeMeta is the metadata of an enveloped response. NextCursor is set on a page of
+// a list that has more items.
+type EnvelopeMeta struct {
+	RequestID  string `json:"request_id,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// EnvelopeError is the error of an enveloped response.
+type EnvelopeError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// envelope is the body of an enveloped response. Data is set if the request succeeded,
+// and Error if it didn't.
+type envelope struct {
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error *EnvelopeError  `json:"error,omitempty"`
+	Meta  EnvelopeMeta    `json:"meta"`
+}
+
+type envelopeKey struct{}
+
+// Envelope is middleware wrapping JSON responses in an envelope with data, error and meta
+// members. Handlers write their responses as usual: a successful JSON response becomes
+// data, other successful responses, such as "OK", become a null data, and the message of
+// an error response becomes error. Responses without a body, such as 304 Not Modified,
+// are passed through.
+func Envelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ew := &envelopeWriter{ResponseWriter: w}
+		ew.meta.RequestID = middleware.GetReqID(r.Context())
+		ctx := context.WithValue(r.Context(), envelopeKey{}, true)
+		next.ServeHTTP(ew, r.WithContext(ctx))
+		ew.flush()
+	})
+}
+
+// IsEnveloped reports whether the request passed through Envelope. List handlers use
+// opaque cursors, read with PageFromQuery, for enveloped requests.
+func IsEnveloped(r *http.Request) bool {
+	enveloped, _ := r.Context().Value(envelopeKey{}).(bool)
+	return enveloped
+}
+
+// SetNextCursor sets the cursor of the next page of an enveloped list response. It does
+// nothing if the response isn't enveloped.
+func SetNextCursor(w http.ResponseWriter, cursor int64) {
+	if ew, ok := w.(*envelopeWriter); ok {
+		ew.meta.NextCursor = EncodeCursor(cursor)
+	}
+}
+
+// EncodeCursor encodes a position in a list as an opaque cursor. Depending on the list,
+// the position is an ID, an offset or a page number, which clients shouldn't rely on.
+func EncodeCursor(position int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(position, 10)))
+}
+
+// PageFromQuery returns the position decoded from the cursor query parameter, or 0 if it
+// isn't set, and the page size from the limit query parameter, as in LimitFromQuery.
+func PageFromQuery(r *http.Request, limits config.LimitConfig) (int64, int, error) {
+	limit, err := LimitFromQuery(r, limits)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cursor := r.URL.Query().Get("cursor")
+	if cursor == "" {
+		return 0, limit, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, models.NewValidationError("invalid cursor")
+	}
+	position, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || position < 0 {
+		return 0, 0, models.NewValidationError("invalid cursor")
+	}
+	return position, limit, nil
+}
+
+// envelopeWriter buffers a response so that it can be wrapped in an envelope once the
+// handler returns. Headers are written to the underlying ResponseWriter.
+type envelopeWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	meta   EnvelopeMeta
+}
+
+func (w *envelopeWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *envelopeWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *envelopeWriter) flush() {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	env := envelope{Meta: w.meta}
+	body := bytes.TrimSpace(w.body.Bytes())
+	switch {
+	case status >= http.StatusBadRequest:
+		env.Error = &EnvelopeError{Status: status, Message: strings.TrimSpace(string(body))}
+	case len(body) > 0 && json.Valid(body):
+		env.Data = body
+	default:
+		env.Data = json.RawMessage("null")
+	}
+
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json")
+	header.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(status)
+	if err := json.NewEncoder(w.ResponseWriter).Encode(env); err != nil {
+		log.Error(err)
+	}
+}
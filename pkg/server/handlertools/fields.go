@@ -0,0 +1,153 @@
+package handlertools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FieldsParam is the query parameter selecting the fields of a response.
+const FieldsParam = "fields"
+
+// Fields is a tree of the JSON fields to keep in a response. A field with no children is
+// kept whole. Arrays are transparent: fields select the keys of each of their elements.
+type Fields map[string]Fields
+
+// FieldsFromQuery parses a comma separated list of dotted field paths, such as
+// "uuid,messages.uuid", from the fields query parameter. It returns nil if the
+// parameter isn't set, in which case responses are encoded in full.
+func FieldsFromQuery(r *http.Request) (Fields, error) {
+	value := r.URL.Query().Get(FieldsParam)
+	if value == "" {
+		return nil, nil
+	}
+	fields := Fields{}
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		node := fields
+		names := strings.Split(path, ".")
+		for i, name := range names {
+			if name == "" {
+				return nil, fmt.Errorf("invalid field %q in %s", path, FieldsParam)
+			}
+			child, ok := node[name]
+			switch {
+			case ok && child == nil:
+				// an ancestor is already kept whole
+			case i == len(names)-1:
+				node[name] = nil
+			case !ok:
+				node[name] = Fields{}
+			}
+			if node[name] == nil {
+				break
+			}
+			node = node[name]
+		}
+	}
+	return fields, nil
+}
+
+// EncodeJSONFields encodes data as JSON, keeping only the selected fields. Fields that
+// data doesn't have are ignored.
+func EncodeJSONFields(w http.ResponseWriter, data interface{}, fields Fields) error {
+	if fields == nil {
+		return EncodeJSON(w, data)
+	}
+	v, err := fields.selectFrom(data)
+	if err != nil {
+		return err
+	}
+	return EncodeJSON(w, v)
+}
+
+// EncodeFields encodes data like EncodeJSONFields, but as MessagePack if the request
+// accepts it. As the encoding depends on the Accept header, the response varies by it.
+func EncodeFields(
+	w http.ResponseWriter,
+	r *http.Request,
+	data interface{},
+	fields Fields,
+) error {
+	w.Header().Add("Vary", "Accept")
+	if !AcceptsMsgpack(r) {
+		return EncodeJSONFields(w, data, fields)
+	}
+	if fields == nil {
+		return EncodeMsgpack(w, data)
+	}
+	v, err := fields.selectFrom(data)
+	if err != nil {
+		return err
+	}
+	return EncodeMsgpack(w, msgpackNumbers(v))
+}
+
+// EncodeFieldsIfModified sets the ETag of the response, computed from data, the selected
+// fields and the encoding, and writes 304 Not Modified instead of encoding data, as
+// EncodeFields does, if the request's If-None-Match header lists the ETag.
+func EncodeFieldsIfModified(
+	w http.ResponseWriter,
+	r *http.Request,
+	data interface{},
+	fields Fields,
+) error {
+	etag, err := ETag(struct {
+		Data    interface{}
+		Fields  Fields
+		Msgpack bool
+	}{data, fields, AcceptsMsgpack(r)})
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag)
+	if NotModified(r, etag) {
+		w.Header().Add("Vary", "Accept")
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	return EncodeFields(w, r, data, fields)
+}
+
+// selectFrom returns the decoded JSON encoding of data, keeping only the selected fields.
+func (f Fields) selectFrom(data interface{}) (interface{}, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	// keep numbers as written, rather than converting them to float64
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return f.prune(v), nil
+}
+
+func (f Fields) prune(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		pruned := make(map[string]interface{}, len(f))
+		for name, children := range f {
+			value, ok := v[name]
+			if !ok {
+				continue
+			}
+			if children != nil {
+				value = children.prune(value)
+			}
+			pruned[name] = value
+		}
+		return pruned
+	case []interface{}:
+		for i, e := range v {
+			v[i] = f.prune(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
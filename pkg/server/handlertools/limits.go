@@ -0,0 +1,60 @@
+package handlertools
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// builtinLimits are applied where the server.limits config doesn't set a limit.
+var builtinLimits = config.LimitsConfig{
+	MemorySearch:   config.LimitConfig{Default: 10, Max: 100},
+	DocumentSearch: config.LimitConfig{Default: 20, Max: 100},
+	Messages:       config.LimitConfig{Default: 100, Max: 1000},
+	Pages:          config.LimitConfig{Default: DefaultPageSize, Max: MaxPageSize},
+}
+
+// Limits returns the configured limits of API requests, with the built-in limits in
+// place of those not set. cfg may be nil.
+func Limits(cfg *config.Config) config.LimitsConfig {
+	if cfg == nil {
+		return builtinLimits
+	}
+	limits := cfg.Server.Limits
+	return config.LimitsConfig{
+		MemorySearch:   withBuiltinLimit(limits.MemorySearch, builtinLimits.MemorySearch),
+		DocumentSearch: withBuiltinLimit(limits.DocumentSearch, builtinLimits.DocumentSearch),
+		Messages:       withBuiltinLimit(limits.Messages, builtinLimits.Messages),
+		Pages:          withBuiltinLimit(limits.Pages, builtinLimits.Pages),
+	}
+}
+
+func withBuiltinLimit(limit, builtin config.LimitConfig) config.LimitConfig {
+	if limit.Default <= 0 {
+		limit.Default = builtin.Default
+	}
+	if limit.Max <= 0 {
+		limit.Max = builtin.Max
+	}
+	return limit
+}
+
+// LimitFromQuery returns the limit query parameter, or limits.Default if it isn't set or
+// is 0. A limit that is negative or above limits.Max is a ValidationError.
+func LimitFromQuery(r *http.Request, limits config.LimitConfig) (int, error) {
+	limit, err := IntFromQuery[int](r, "limit")
+	if err != nil || limit < 0 {
+		return 0, models.NewValidationError("limit must be a positive integer")
+	}
+	if limit > limits.Max {
+		return 0, models.NewValidationError(
+			fmt.Sprintf("limit must not be greater than %d", limits.Max),
+		)
+	}
+	if limit == 0 {
+		limit = min(limits.Default, limits.Max)
+	}
+	return limit, nil
+}
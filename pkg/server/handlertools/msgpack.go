@@ -0,0 +1,105 @@
+package handlertools
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MsgpackContentType is the media type of MessagePack responses.
+const MsgpackContentType = "application/msgpack"
+
+// msgpackMediaTypes are the media types a client may accept MessagePack with.
+var msgpackMediaTypes = map[string]bool{
+	MsgpackContentType:        true,
+	"application/x-msgpack":   true,
+	"application/vnd.msgpack": true,
+}
+
+func init() {
+	// UUIDs are encoded as strings, as in JSON, rather than as 16 bytes
+	msgpack.Register(uuid.UUID{}, func(e *msgpack.Encoder, v reflect.Value) error {
+		return e.EncodeString(v.Interface().(uuid.UUID).String())
+	}, nil)
+}
+
+// AcceptsMsgpack reports whether the Accept header of the request prefers MessagePack to
+// JSON. If both are accepted with the same quality, the first listed wins. Enveloped
+// requests are always answered with JSON.
+func AcceptsMsgpack(r *http.Request) bool {
+	if IsEnveloped(r) {
+		return false
+	}
+	header := r.Header.Get("Accept")
+	if header == "" {
+		return false
+	}
+	var msgpackQ, jsonQ float64
+	var msgpackFirst bool
+	for _, mediaRange := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch {
+		case msgpackMediaTypes[mediaType]:
+			if q > msgpackQ {
+				msgpackFirst = msgpackFirst || jsonQ == 0
+				msgpackQ = q
+			}
+		case mediaType == "application/json" || mediaType == "application/*" ||
+			mediaType == "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return msgpackQ > jsonQ || (msgpackQ > 0 && msgpackQ == jsonQ && msgpackFirst)
+}
+
+// EncodeMsgpack encodes data as MessagePack and writes it to the response writer. Struct
+// fields are named by their json tags, and times are encoded as MessagePack timestamps.
+func EncodeMsgpack(w http.ResponseWriter, data interface{}) error {
+	w.Header().Set("Content-Type", MsgpackContentType)
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	return enc.Encode(data)
+}
+
+// msgpackNumbers replaces the json.Numbers of a decoded JSON value with integers or
+// floats, which would otherwise be encoded as MessagePack strings.
+func msgpackNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, value := range v {
+			v[name] = msgpackNumbers(value)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = msgpackNumbers(e)
+		}
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	default:
+		return v
+	}
+}
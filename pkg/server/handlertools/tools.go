@@ -1,6 +1,9 @@
 package handlertools
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -61,12 +64,106 @@ func EncodeJSON(w http.ResponseWriter, data interface{}) error {
 	return json.NewEncoder(w).Encode(data)
 }
 
-// DecodeJSON decodes a JSON request body into the provided data struct.
+// DecodeJSON decodes a JSON request body into the provided data struct. If the request
+// passed through StrictJSON, unknown fields are rejected with a ValidationError naming
+// the field.
 func DecodeJSON(r *http.Request, data interface{}) error {
-	return json.NewDecoder(r.Body).Decode(&data)
+	d := json.NewDecoder(r.Body)
+	strict, _ := r.Context().Value(strictJSONKey{}).(bool)
+	if !strict {
+		return d.Decode(&data)
+	}
+	d.DisallowUnknownFields()
+	err := d.Decode(&data)
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return nil
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields
+		return models.NewValidationError(strings.TrimPrefix(err.Error(), "json: "))
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return models.NewValidationError(fmt.Sprintf(
+			"field %q must be %s, not %s", typeErr.Field, typeErr.Type, typeErr.Value,
+		))
+	default:
+		return err
+	}
+}
+
+type strictJSONKey struct{}
+
+// StrictJSON is middleware making DecodeJSON reject request bodies with unknown fields.
+func StrictJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), strictJSONKey{}, true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
 
-// RenderError renders an error response.
+// ETag returns a strong entity tag for v, computed from a hash of its JSON encoding.
+func ETag(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// CheckIfMatch returns a PreconditionFailedError if the request has an If-Match header
+// that doesn't list etag. If-Match: * matches any existing resource.
+func CheckIfMatch(r *http.Request, etag string) error {
+	header := r.Header.Get("If-Match")
+	if header == "" || header == "*" || etagListContains(header, etag) {
+		return nil
+	}
+	return models.NewPreconditionFailedError(
+		fmt.Sprintf("If-Match %s does not match the current ETag %s", header, etag),
+	)
+}
+
+// NotModified is true if the request has an If-None-Match header listing etag.
+func NotModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	return header == "*" || (header != "" && etagListContains(header, etag))
+}
+
+func etagListContains(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		// weak comparison, as proxies may weaken the tag
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorStatus maps an error in the models error taxonomy to an HTTP status code. If err
+// doesn't match any of the taxonomy's errors, fallback is returned.
+func ErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, models.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, models.ErrDependencyFailure):
+		return http.StatusBadGateway
+	case errors.Is(err, models.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return fallback
+	}
+}
+
+// RenderError renders an error response. If err is in the models error taxonomy, the
+// status is taken from ErrorStatus and status is ignored.
 func RenderError(w http.ResponseWriter, err error, status int) {
 	if err.Error() == "http: request body too large" {
 		status = http.StatusRequestEntityTooLarge
@@ -75,18 +172,37 @@ func RenderError(w http.ResponseWriter, err error, status int) {
 		)
 	}
 
+	status = ErrorStatus(err, status)
+	if strings.Contains(err.Error(), "is deleted") {
+		status = http.StatusBadRequest
+	}
+
 	if status != http.StatusNotFound {
 		// Don't log not found errors
 		log.Error(err)
 	}
 
-	if strings.Contains(err.Error(), "is deleted") || errors.Is(err, models.ErrBadRequest) {
-		status = http.StatusBadRequest
+	if retryAfter := errorRetryAfter(err); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
 	}
 
 	http.Error(w, err.Error(), status)
 }
 
+// errorRetryAfter returns the wait before retrying suggested by a rate limited or
+// unavailable error, in seconds, or 0 if there's none.
+func errorRetryAfter(err error) int {
+	var rateLimitedErr *models.RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		return rateLimitedErr.RetryAfter
+	}
+	var unavailableErr *models.UnavailableError
+	if errors.As(err, &unavailableErr) {
+		return unavailableErr.RetryAfter
+	}
+	return 0
+}
+
 // UUIDFromURL parses a UUID from a Path parameter. If the UUID is invalid, an error is
 // rendered and uuid.Nil is returned.
 func UUIDFromURL(r *http.Request, w http.ResponseWriter, paramName string) uuid.UUID {
@@ -1,12 +1,21 @@
 package handlertools
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -39,3 +48,330 @@ func TestParseUUIDFromURL(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
 }
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", models.NewNotFoundError("session"), http.StatusNotFound},
+		{"validation", models.NewValidationError("empty"), http.StatusBadRequest},
+		{"bad request", models.NewBadRequestError("empty"), http.StatusBadRequest},
+		{"conflict", models.NewConflictError("exists"), http.StatusConflict},
+		{
+			"precondition failed",
+			models.NewPreconditionFailedError("stale"),
+			http.StatusPreconditionFailed,
+		},
+		{"rate limited", models.NewRateLimitedError("slow down", 1), http.StatusTooManyRequests},
+		{
+			"dependency failure",
+			models.NewDependencyFailureError("llm", errors.New("timeout")),
+			http.StatusBadGateway,
+		},
+		{"lock timeout", models.NewAdvisoryLockError(nil), http.StatusConflict},
+		{"unavailable", models.NewUnavailableError("maintenance", 30), http.StatusServiceUnavailable},
+		{
+			"wrapped storage error",
+			store.NewStorageError("failed", fmt.Errorf("get: %w", models.NewNotFoundError("user"))),
+			http.StatusNotFound,
+		},
+		{"embedding mismatch", store.NewEmbeddingMismatchError(errors.New("dims")), http.StatusBadRequest},
+		{"storage error", store.NewStorageError("failed", errors.New("boom")), http.StatusInternalServerError},
+		{"other", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ErrorStatus(tt.err, http.StatusInternalServerError))
+		})
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	w := httptest.NewRecorder()
+	RenderError(w, models.NewRateLimitedError("slow down", 5), http.StatusInternalServerError)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	RenderError(w, models.NewUnavailableError("maintenance", 30), http.StatusInternalServerError)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	RenderError(w, errors.New("not a taxonomy error"), http.StatusUnauthorized)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestETag(t *testing.T) {
+	etag, err := ETag(map[string]string{"name": "docs"})
+	assert.NoError(t, err)
+	same, err := ETag(map[string]string{"name": "docs"})
+	assert.NoError(t, err)
+	other, err := ETag(map[string]string{"name": "faq"})
+	assert.NoError(t, err)
+	assert.Equal(t, etag, same)
+	assert.NotEqual(t, etag, other)
+
+	tests := []struct {
+		name        string
+		ifMatch     string
+		ifNoneMatch string
+		wantErr     bool
+		notModified bool
+	}{
+		{name: "no headers"},
+		{name: "if-match matches", ifMatch: etag},
+		{name: "if-match list", ifMatch: other + ", " + etag},
+		{name: "if-match any", ifMatch: "*"},
+		{name: "if-match stale", ifMatch: other, wantErr: true},
+		{name: "if-none-match matches", ifNoneMatch: "W/" + etag, notModified: true},
+		{name: "if-none-match stale", ifNoneMatch: other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			err := CheckIfMatch(req, etag)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, models.ErrPreconditionFailed)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.notModified, NotModified(req, etag))
+		})
+	}
+}
+
+func TestFieldsFromQuery(t *testing.T) {
+	fields, err := FieldsFromQuery(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(t, err)
+	assert.Nil(t, fields)
+
+	req := httptest.NewRequest("GET", "/?fields=uuid,+messages.uuid,messages.metadata.a,summary,summary.uuid", nil)
+	fields, err = FieldsFromQuery(req)
+	assert.NoError(t, err)
+	assert.Equal(t, Fields{
+		"uuid":     nil,
+		"messages": Fields{"uuid": nil, "metadata": Fields{"a": nil}},
+		"summary":  nil,
+	}, fields)
+
+	_, err = FieldsFromQuery(httptest.NewRequest("GET", "/?fields=uuid,,content", nil))
+	assert.Error(t, err)
+	_, err = FieldsFromQuery(httptest.NewRequest("GET", "/?fields=messages.", nil))
+	assert.Error(t, err)
+}
+
+func TestEncodeJSONFields(t *testing.T) {
+	id := uuid.New()
+	list := models.MessageListResponse{
+		Messages: []models.Message{{
+			UUID:       id,
+			Content:    "hello",
+			Metadata:   map[string]interface{}{"a": 1, "b": 2},
+			TokenCount: 3,
+		}},
+		TotalCount: 1,
+	}
+
+	w := httptest.NewRecorder()
+	fields := Fields{
+		"messages":    Fields{"uuid": nil, "token_count": nil, "metadata": Fields{"a": nil}},
+		"total_count": nil,
+		"missing":     nil,
+	}
+	assert.NoError(t, EncodeJSONFields(w, list, fields))
+	assert.JSONEq(
+		t,
+		fmt.Sprintf(
+			`{"messages":[{"uuid":%q,"token_count":3,"metadata":{"a":1}}],"total_count":1}`,
+			id,
+		),
+		w.Body.String(),
+	)
+
+	w = httptest.NewRecorder()
+	assert.NoError(t, EncodeJSONFields(w, list.Messages, nil))
+	var messages []models.Message
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &messages))
+	assert.Equal(t, list.Messages[0].Content, messages[0].Content)
+}
+
+func TestDecodeJSONStrict(t *testing.T) {
+	type request struct {
+		SessionID string                 `json:"session_id"`
+		Metadata  map[string]interface{} `json:"metadata"`
+	}
+	decode := func(body string, strict bool) error {
+		var v request
+		var err error
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err = DecodeJSON(r, &v)
+		})
+		var h http.Handler = handler
+		if strict {
+			h = StrictJSON(handler)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return err
+	}
+
+	assert.NoError(t, decode(`{"session_id":"s1","metdata":{}}`, false))
+	assert.NoError(t, decode(`{"session_id":"s1","metadata":{}}`, true))
+
+	err := decode(`{"session_id":"s1","metdata":{}}`, true)
+	assert.ErrorIs(t, err, models.ErrValidation)
+	assert.ErrorContains(t, err, `unknown field "metdata"`)
+
+	err = decode(`{"session_id":"s1","metadata":[]}`, true)
+	assert.ErrorIs(t, err, models.ErrValidation)
+	assert.ErrorContains(t, err, `field "metadata"`)
+}
+
+func TestEnvelope(t *testing.T) {
+	serve := func(h http.HandlerFunc) map[string]json.RawMessage {
+		t.Helper()
+		w := httptest.NewRecorder()
+		Envelope(h).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		var body map[string]json.RawMessage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body
+	}
+
+	body := serve(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, IsEnveloped(r))
+		SetNextCursor(w, 42)
+		_ = EncodeJSON(w, []int{1, 2})
+	})
+	assert.JSONEq(t, `[1, 2]`, string(body["data"]))
+	assert.JSONEq(t, `{"next_cursor": "`+EncodeCursor(42)+`"}`, string(body["meta"]))
+	assert.NotContains(t, body, "error")
+
+	body = serve(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	})
+	assert.Equal(t, "null", string(body["data"]))
+
+	body = serve(func(w http.ResponseWriter, r *http.Request) {
+		RenderError(w, models.NewNotFoundError("session s1"), http.StatusInternalServerError)
+	})
+	assert.NotContains(t, body, "data")
+	assert.JSONEq(t, `{"status": 404, "message": "session s1 not found"}`, string(body["error"]))
+
+	// responses without a body aren't enveloped
+	w := httptest.NewRecorder()
+	Envelope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestPageFromQuery(t *testing.T) {
+	limits := Limits(nil).Pages
+	cursor, limit, err := PageFromQuery(httptest.NewRequest("GET", "/", nil), limits)
+	assert.NoError(t, err)
+	assert.Zero(t, cursor)
+	assert.Equal(t, DefaultPageSize, limit)
+
+	cursor, limit, err = PageFromQuery(
+		httptest.NewRequest("GET", "/?limit=500&cursor="+EncodeCursor(7), nil),
+		limits,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), cursor)
+	assert.Equal(t, 500, limit)
+
+	for _, query := range []string{
+		"limit=-1", "limit=x", "limit=5000", "cursor=%21", "cursor=" + EncodeCursor(-1),
+	} {
+		_, _, err = PageFromQuery(httptest.NewRequest("GET", "/?"+query, nil), limits)
+		assert.ErrorIs(t, err, models.ErrValidation, query)
+	}
+}
+
+func TestLimitFromQuery(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.Limits.MemorySearch = config.LimitConfig{Default: 5, Max: 25}
+	limits := Limits(cfg)
+	assert.Equal(t, config.LimitConfig{Default: 5, Max: 25}, limits.MemorySearch)
+	assert.Equal(t, builtinLimits.DocumentSearch, limits.DocumentSearch)
+
+	limit, err := LimitFromQuery(httptest.NewRequest("GET", "/", nil), limits.MemorySearch)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, limit)
+
+	limit, err = LimitFromQuery(
+		httptest.NewRequest("GET", "/?limit=25", nil),
+		limits.MemorySearch,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 25, limit)
+
+	_, err = LimitFromQuery(httptest.NewRequest("GET", "/?limit=26", nil), limits.MemorySearch)
+	assert.ErrorIs(t, err, models.ErrValidation)
+	assert.ErrorContains(t, err, "25")
+
+	// a default above a configured maximum is capped
+	limit, err = LimitFromQuery(
+		httptest.NewRequest("GET", "/", nil),
+		config.LimitConfig{Default: 50, Max: 10},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, limit)
+}
+
+func TestAcceptsMsgpack(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"application/msgpack", true},
+		{"application/x-msgpack", true},
+		{"*/*", false},
+		{"application/msgpack, application/json", true},
+		{"application/json, application/msgpack", false},
+		{"application/json;q=0.5, application/msgpack", true},
+		{"application/msgpack;q=0.1, */*", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", tt.accept)
+		assert.Equal(t, tt.want, AcceptsMsgpack(r), tt.accept)
+	}
+}
+
+func TestEncodeFieldsMsgpack(t *testing.T) {
+	id := uuid.New()
+	data := []models.Message{{UUID: id, Role: "user", TokenCount: 3}}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", MsgpackContentType)
+
+	for _, fields := range []Fields{nil, {"uuid": nil, "token_count": nil}} {
+		w := httptest.NewRecorder()
+		assert.NoError(t, EncodeFields(w, r, data, fields))
+		assert.Equal(t, MsgpackContentType, w.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+		var decoded []map[string]interface{}
+		assert.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &decoded))
+		assert.Len(t, decoded, 1)
+		assert.Equal(t, id.String(), decoded[0]["uuid"])
+		assert.EqualValues(t, 3, decoded[0]["token_count"])
+		if fields != nil {
+			assert.Len(t, decoded[0], 2)
+		} else {
+			assert.Equal(t, "user", decoded[0]["role"])
+		}
+	}
+}
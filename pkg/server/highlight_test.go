@@ -0,0 +1,61 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestSearchHighlights(t *testing.T) {
+	ctx := context.Background()
+	highlightState := &models.AppState{Config: &config.Config{}}
+	highlightState.MemoryStore = inmemory.NewMemoryStore(highlightState, inmemory.NewDB())
+	router := chi.NewRouter()
+	setupSessionRoutes(router, highlightState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	err := highlightState.MemoryStore.PutMemory(ctx, "s1", &models.Memory{
+		Messages: []models.Message{{Role: "user", Content: "My invoice is wrong"}},
+	}, true)
+	require.NoError(t, err)
+
+	search := func(payload models.MemorySearchPayload) []models.MemorySearchResult {
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		resp, err := http.Post(
+			server.URL+"/sessions/s1/search",
+			"application/json",
+			bytes.NewReader(body),
+		)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var results []models.MemorySearchResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+		return results
+	}
+
+	results := search(models.MemorySearchPayload{Text: "wrong invoice", Highlight: true})
+	require.Len(t, results, 1)
+	assert.Equal(
+		t,
+		[]models.TextSpan{{Start: 3, End: 10}, {Start: 14, End: 19}},
+		results[0].Highlights,
+	)
+
+	results = search(models.MemorySearchPayload{Text: "wrong invoice"})
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Highlights)
+}
@@ -94,6 +94,50 @@ func TestCreateSessionRoute(t *testing.T) {
 	assert.Equal(t, createdSession.Metadata["key"], "value")
 }
 
+func TestCreateSessionsRoute(t *testing.T) {
+	sessionStore := postgres.NewSessionDAO(testDB)
+
+	existingID := testutils.GenerateRandomString(10)
+	_, err := sessionStore.Create(testCtx, &models.CreateSessionRequest{SessionID: existingID})
+	assert.NoError(t, err)
+
+	newID := testutils.GenerateRandomString(10)
+	sessionsJSON, err := json.Marshal([]models.CreateSessionRequest{
+		{SessionID: newID, Metadata: map[string]interface{}{"key": "value"}},
+		{SessionID: existingID},
+	})
+	assert.NoError(t, err)
+
+	resp, err := http.Post(
+		testServer.URL+"/api/v1/sessions/batch",
+		"application/json",
+		bytes.NewBuffer(sessionsJSON),
+	)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response models.CreateSessionsResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, 1, response.Succeeded)
+	assert.Equal(t, 1, response.Failed)
+	assert.NotEmpty(t, response.Results[1].Error)
+
+	createdSession, err := sessionStore.Get(testCtx, newID)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", createdSession.Metadata["key"])
+
+	// an empty batch is rejected
+	resp, err = http.Post(
+		testServer.URL+"/api/v1/sessions/batch",
+		"application/json",
+		bytes.NewBufferString("[]"),
+	)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
 func TestUpdateSessionRoute(t *testing.T) {
 	// Initialize the SessionStoreDAO
 	sessionStore := postgres.NewSessionDAO(testDB)
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/jsonpatch"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestMetadataPatchRoutes(t *testing.T) {
+	ctx := context.Background()
+	db := inmemory.NewDB()
+	patchState := &models.AppState{Config: &config.Config{}, UserStore: inmemory.NewUserStore(db)}
+	patchState.MemoryStore = inmemory.NewMemoryStore(patchState, db)
+	router := chi.NewRouter()
+	setupSessionRoutes(router, patchState)
+	setupUserRoutes(router, patchState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	patch := func(path, contentType, body string) (*http.Response, map[string]interface{}) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPatch, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var v struct {
+			Metadata map[string]interface{} `json:"metadata"`
+		}
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&v))
+		}
+		return resp, v.Metadata
+	}
+
+	_, err := patchState.MemoryStore.CreateSession(ctx, &models.CreateSessionRequest{
+		SessionID: "s1",
+		Metadata:  map[string]interface{}{"a": "1", "b": "2"},
+	})
+	require.NoError(t, err)
+	_, err = patchState.UserStore.Create(ctx, &models.CreateUserRequest{
+		UserID:   "u1",
+		Metadata: map[string]interface{}{"a": "1", "system": map[string]interface{}{"c": "3"}},
+	})
+	require.NoError(t, err)
+
+	resp, metadata := patch(
+		"/sessions/s1",
+		jsonpatch.ContentType,
+		`[{"op":"remove","path":"/a"}]`,
+	)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"b": "2"}, metadata)
+
+	// a plain update still deep merges
+	resp, metadata = patch("/sessions/s1", "application/json", `{"metadata":{"c":"3"}}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"b": "2", "c": "3"}, metadata)
+
+	resp, _ = patch(
+		"/sessions/s1",
+		jsonpatch.ContentType,
+		`[{"op":"test","path":"/b","value":"1"}]`,
+	)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp, _ = patch("/sessions/s1", jsonpatch.ContentType, `{"op":"remove"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = patch("/sessions/s2", jsonpatch.MergeContentType, `{"a":null}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// user updates are privileged, so the system key may be patched
+	resp, metadata = patch(
+		"/user/u1",
+		jsonpatch.MergeContentType+"; charset=utf-8",
+		`{"a":null,"system":{"c":null}}`,
+	)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"system": map[string]interface{}{}}, metadata)
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/auth"
+	"github.com/getzep/zep/pkg/experiment"
+	"github.com/getzep/zep/pkg/metrics"
+)
+
+// apiPrefixes are the prefixes of the routes of each API version, which are measured
+// alike.
+var apiPrefixes = []string{"/api/v1", "/api/v2"}
+
+const (
+	defaultTenantClaim  = "tenant"
+	defaultTenantHeader = "X-Zep-Tenant"
+	// otherOperation labels tenant requests of API routes not measured by the SLIs.
+	otherOperation = "other"
+)
+
+// MetricsMiddleware records the outcome and latency of memory and search requests, if
+// tenants isn't nil, of each tenant's API requests, and, if experiments isn't nil, of the
+// searches made in the retrieval experiment.
+func MetricsMiddleware(
+	sli *metrics.SLIMetrics,
+	tenants *metrics.TenantMetrics,
+	tenant func(r *http.Request) string,
+	experiments *metrics.ExperimentMetrics,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r)
+
+			// the route pattern is only known once the request has been routed
+			rctx := chi.RouteContext(r.Context())
+			if rctx == nil {
+				return
+			}
+			pattern := rctx.RoutePattern()
+			if _, ok := cutAPIPrefix(pattern); !ok {
+				return
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			duration := time.Since(start)
+
+			operation := sliOperation(r.Method, pattern)
+			if operation != "" {
+				sli.Observe(operation, status, duration)
+			}
+			if experiments != nil {
+				if variant := ww.Header().Get(experiment.VariantHeader); variant != "" {
+					experiments.Observe(variant, operation, status, duration)
+				}
+			}
+			if tenants != nil {
+				if operation == "" {
+					operation = otherOperation
+				}
+				tenants.Observe(tenant(r), operation, status, duration)
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// metricsTenant returns a function naming the tenant of a request, for the tenant
+// metrics.
+func metricsTenant(cfg *config.Config) func(r *http.Request) string {
+	header := cfg.Metrics.Tenants.Header
+	if header == "" {
+		header = defaultTenantHeader
+	}
+	return tenantFromRequest(cfg, cfg.Metrics.Tenants.Claim, header)
+}
+
+// tenantFromRequest returns a function naming the tenant of a request by the tenant
+// claim of its token or, if the token doesn't have one, by the tenant header, if set. The
+// token is verified, as the auth middleware runs after the metrics middleware.
+func tenantFromRequest(cfg *config.Config, claim, header string) func(r *http.Request) string {
+	if claim == "" {
+		claim = defaultTenantClaim
+	}
+	var tokenAuth *jwtauth.JWTAuth
+	if cfg.Auth.Secret != "" {
+		tokenAuth = jwtauth.New(auth.JwtAlg, []byte(cfg.Auth.Secret), nil)
+	}
+
+	return func(r *http.Request) string {
+		if tokenAuth != nil {
+			token, err := jwtauth.VerifyRequest(tokenAuth, r, jwtauth.TokenFromHeader)
+			if err == nil {
+				if v, ok := token.Get(claim); ok {
+					if tenant, ok := v.(string); ok && tenant != "" {
+						return tenant
+					}
+				}
+			}
+		}
+		if header == "" {
+			return ""
+		}
+		return r.Header.Get(header)
+	}
+}
+
+// cutAPIPrefix returns the pattern of an API route without its version prefix, and
+// whether it's an API route.
+func cutAPIPrefix(pattern string) (string, bool) {
+	for _, prefix := range apiPrefixes {
+		if path, ok := strings.CutPrefix(pattern, prefix); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// sliOperation returns the SLI operation of an API route, or "" if the route isn't
+// measured.
+func sliOperation(method, pattern string) string {
+	path, ok := cutAPIPrefix(pattern)
+	if !ok {
+		return ""
+	}
+	path = strings.TrimSuffix(path, "/")
+
+	switch {
+	case path == "/sessions/{sessionId}/search":
+		return metrics.OperationMemorySearch
+	case path == "/collection/{collectionName}/search":
+		return metrics.OperationDocumentSearch
+	case path == "/sessions" || strings.HasPrefix(path, "/sessions/"):
+		if method == http.MethodGet {
+			return metrics.OperationMemoryRead
+		}
+		return metrics.OperationMemoryWrite
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/auth"
+	"github.com/getzep/zep/pkg/metrics"
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestSLIOperation(t *testing.T) {
+	tests := []struct {
+		method, pattern, want string
+	}{
+		{http.MethodGet, "/api/v1/sessions/{sessionId}/memory/", metrics.OperationMemoryRead},
+		{http.MethodPost, "/api/v1/sessions/{sessionId}/memory/", metrics.OperationMemoryWrite},
+		{http.MethodGet, "/api/v1/sessions", metrics.OperationMemoryRead},
+		{http.MethodPost, "/api/v1/sessions/{sessionId}/search/", metrics.OperationMemorySearch},
+		{
+			http.MethodPost,
+			"/api/v1/collection/{collectionName}/search",
+			metrics.OperationDocumentSearch,
+		},
+		{http.MethodGet, "/api/v1/collection/{collectionName}", ""},
+		{http.MethodGet, "/api/v2/sessions/{sessionId}/memory/", metrics.OperationMemoryRead},
+		{http.MethodGet, "/admin/sessions/{sessionID}/", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, sliOperation(tt.method, tt.pattern), tt.method+" "+tt.pattern)
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	cfg := &config.Config{Auth: config.AuthConfig{Secret: "secret"}}
+	registry := metrics.NewRegistry()
+	sli := metrics.NewSLIMetrics(registry, cfg.Metrics.SLO)
+	tenants := metrics.NewTenantMetrics(
+		registry,
+		config.TenantMetricsConfig{Allow: []string{"acme", "globex"}},
+	)
+	router := chi.NewRouter()
+	router.Use(MetricsMiddleware(sli, tenants, metricsTenant(cfg), nil))
+	router.Method(http.MethodGet, "/metrics", registry.Handler())
+	router.Route("/api/v1/sessions/{sessionId}", func(r chi.Router) {
+		r.Get("/memory", func(w http.ResponseWriter, r *http.Request) {})
+		r.Post("/memory", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+	})
+	router.Get("/api/v1/user", func(w http.ResponseWriter, r *http.Request) {})
+
+	tenantToken := func(tenant string) string {
+		ja := jwtauth.New(auth.JwtAlg, []byte(cfg.Auth.Secret), nil)
+		_, token, err := ja.Encode(map[string]interface{}{"tenant": tenant})
+		assert.NoError(t, err)
+		return token
+	}
+	requests := []struct {
+		method, path, header, token string
+	}{
+		{http.MethodGet, "/api/v1/sessions/s1/memory", "acme", ""},
+		{http.MethodPost, "/api/v1/sessions/s1/memory", "acme", ""},
+		{http.MethodPost, "/api/v1/sessions/s1/memory", "", ""},
+		// the token's claim takes precedence over the header
+		{http.MethodGet, "/api/v1/user", "acme", tenantToken("globex")},
+		{http.MethodGet, "/api/v1/user", "initech", ""},
+	}
+	for _, req := range requests {
+		r := httptest.NewRequest(req.method, req.path, nil)
+		if req.header != "" {
+			r.Header.Set(defaultTenantHeader, req.header)
+		}
+		if req.token != "" {
+			r.Header.Set("Authorization", "Bearer "+req.token)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		`zep_sli_requests_total{operation="memory_read",outcome="success"} 1`,
+		`zep_sli_requests_total{operation="memory_write",outcome="error"} 2`,
+		`zep_tenant_requests_total{tenant="acme",operation="memory_read",outcome="success"} 1`,
+		`zep_tenant_requests_total{tenant="acme",operation="memory_write",outcome="error"} 1`,
+		`zep_tenant_requests_total{tenant="unknown",operation="memory_write",outcome="error"} 1`,
+		`zep_tenant_requests_total{tenant="globex",operation="other",outcome="success"} 1`,
+		`zep_tenant_requests_total{tenant="other",operation="other",outcome="success"} 1`,
+		`zep_tenant_label_overflow_total 1`,
+	} {
+		assert.Contains(t, body, line)
+	}
+	assert.NotContains(t, body, "initech")
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	cfg := &config.Config{Auth: config.AuthConfig{Secret: "secret"}}
+	ja := jwtauth.New(auth.JwtAlg, []byte(cfg.Auth.Secret), nil)
+	_, token, err := ja.Encode(map[string]interface{}{"org": "acme"})
+	assert.NoError(t, err)
+
+	tenant := func(header string, r *http.Request) string {
+		var got string
+		handler := Tenant(tenantFromRequest(cfg, "org", header))(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = models.TenantFromContext(r.Context())
+			}),
+		)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		return got
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/user", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set("X-Zep-Tenant", "globex")
+	assert.Equal(t, "acme", tenant("X-Zep-Tenant", r))
+
+	// the header is only trusted if configured
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/user", nil)
+	r.Header.Set("X-Zep-Tenant", "globex")
+	assert.Equal(t, "globex", tenant("X-Zep-Tenant", r))
+	assert.Empty(t, tenant("", r))
+}
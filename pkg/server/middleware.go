@@ -1,9 +1,14 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/handlertools"
 )
 
 const versionHeader = "X-Zep-Version"
@@ -22,3 +27,112 @@ func SendVersion(next http.Handler) http.Handler {
 	}
 	return http.HandlerFunc(fn)
 }
+
+// Tenant is a middleware that serves requests on behalf of the tenant named by tenant, so
+// that they, and the extractors processing the data they write, use the tenant's LLM and
+// embedding providers.
+func Tenant(tenant func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := models.WithTenant(r.Context(), tenant(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ValidateDeprecation returns an error if the dates of cfg aren't in RFC 3339 format.
+func ValidateDeprecation(cfg config.APIDeprecationConfig) error {
+	for name, value := range map[string]string{
+		"deprecated_at": cfg.DeprecatedAt,
+		"sunset_at":     cfg.SunsetAt,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+	return nil
+}
+
+// Deprecate is a middleware that marks the responses of a deprecated API version with a
+// Deprecation header (RFC 9745), a Sunset header (RFC 8594) if a sunset date is set, and
+// a link to the successor version. Without a deprecation date, the header is "true".
+// The dates of cfg must have been validated with ValidateDeprecation.
+func Deprecate(
+	cfg config.APIDeprecationConfig,
+	successor string,
+) func(http.Handler) http.Handler {
+	deprecation := "true"
+	if t, err := time.Parse(time.RFC3339, cfg.DeprecatedAt); err == nil {
+		deprecation = fmt.Sprintf("@%d", t.Unix())
+	}
+	var sunset string
+	if t, err := time.Parse(time.RFC3339, cfg.SunsetAt); err == nil {
+		sunset = t.UTC().Format(http.TimeFormat)
+	}
+	link := fmt.Sprintf("<%s>; rel=\"successor-version\"", successor)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("Deprecation", deprecation)
+			if sunset != "" {
+				header.Set("Sunset", sunset)
+			}
+			header.Add("Link", link)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// readOnlyPostRoutes are the suffixes of the paths of POST routes that only read, and so
+// are served in maintenance mode.
+var readOnlyPostRoutes = []string{
+	"/search",
+	"/document/list/get",
+	"/run",
+}
+
+// ReadOnly is a middleware that rejects requests that write, with a 503 status and a
+// Retry-After header, while mode is enabled. Reads, including searches, are served.
+func ReadOnly(mode models.MaintenanceMode) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mode == nil || isReadRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			status := mode.Status()
+			if !status.Enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+			message := "the API is read-only during maintenance"
+			if status.Reason != "" {
+				message = fmt.Sprintf("%s: %s", message, status.Reason)
+			}
+			handlertools.RenderError(
+				w,
+				models.NewUnavailableError(message, status.RetryAfter),
+				http.StatusServiceUnavailable,
+			)
+		})
+	}
+}
+
+func isReadRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		path := strings.TrimSuffix(r.URL.Path, "/")
+		for _, suffix := range readOnlyPostRoutes {
+			if strings.HasSuffix(path, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
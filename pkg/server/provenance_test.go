@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/apihandlers"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestProvenanceRoutes(t *testing.T) {
+	ctx := context.Background()
+	db := inmemory.NewDB()
+	provenanceState := &models.AppState{
+		Config:          &config.Config{Provenance: config.ProvenanceConfig{Enabled: true}},
+		ProvenanceStore: inmemory.NewProvenanceStore(db),
+	}
+	provenanceState.MemoryStore = inmemory.NewMemoryStore(provenanceState, db)
+	router := chi.NewRouter()
+	setupSessionRoutes(router, provenanceState)
+	router.Get("/provenance/{responseId}", apihandlers.GetProvenanceHandler(provenanceState))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	err := provenanceState.MemoryStore.PutMemory(ctx, "s1", &models.Memory{
+		Messages: []models.Message{
+			{Role: "user", Content: "hello"},
+			{Role: "ai", Content: "hi"},
+		},
+	}, true)
+	require.NoError(t, err)
+
+	resp, err := http.Get(server.URL + "/sessions/s1/memory")
+	require.NoError(t, err)
+	var memory models.Memory
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&memory))
+	resp.Body.Close()
+	responseID := resp.Header.Get(apihandlers.ResponseIDHeader)
+	require.NotEmpty(t, responseID)
+
+	resp, err = http.Get(server.URL + "/provenance/" + responseID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var provenance models.ContextProvenance
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&provenance))
+	resp.Body.Close()
+	assert.Equal(t, models.ProvenanceMemory, provenance.Kind)
+	assert.Equal(t, "s1", provenance.SessionID)
+	assert.Equal(
+		t,
+		[]uuid.UUID{memory.Messages[0].UUID, memory.Messages[1].UUID},
+		provenance.Messages,
+	)
+
+	resp, err = http.Get(server.URL + "/provenance/" + uuid.NewString())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// nothing is recorded unless enabled
+	provenanceState.Config.Provenance.Enabled = false
+	resp, err = http.Get(server.URL + "/sessions/s1/memory")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, resp.Header.Get(apihandlers.ResponseIDHeader))
+}
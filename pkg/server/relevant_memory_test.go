@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestGetMemoryRelevantMessages(t *testing.T) {
+	ctx := context.Background()
+	relevantState := &models.AppState{Config: &config.Config{}}
+	relevantState.Config.Memory.MessageWindow = 2
+	relevantState.MemoryStore = inmemory.NewMemoryStore(relevantState, inmemory.NewDB())
+	router := chi.NewRouter()
+	setupSessionRoutes(router, relevantState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	contents := []string{
+		"my invoice is overdue",
+		"hello",
+		"the weather is nice",
+		"can you resend the invoice",
+		"thanks",
+	}
+	messages := make([]models.Message, len(contents))
+	for i, content := range contents {
+		messages[i] = models.Message{Role: "user", Content: content}
+	}
+	err := relevantState.MemoryStore.PutMemory(ctx, "s1", &models.Memory{Messages: messages}, true)
+	require.NoError(t, err)
+	err = relevantState.MemoryStore.CreateSummary(ctx, "s1", &models.Summary{Content: "a summary"})
+	require.NoError(t, err)
+
+	get := func(query string) (int, *models.Memory) {
+		resp, err := http.Get(server.URL + "/sessions/s1/memory" + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var memory models.Memory
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&memory))
+		}
+		return resp.StatusCode, &memory
+	}
+	contentsOf := func(memory *models.Memory) []string {
+		out := make([]string, len(memory.Messages))
+		for i, m := range memory.Messages {
+			out[i] = m.Content
+		}
+		return out
+	}
+
+	status, memory := get("")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"can you resend the invoice", "thanks"}, contentsOf(memory))
+
+	// the relevant messages, most relevant first, replace the last messages
+	status, memory = get("?query=invoice+overdue")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(
+		t,
+		[]string{"my invoice is overdue", "can you resend the invoice"},
+		contentsOf(memory),
+	)
+	require.NotNil(t, memory.Summary)
+	assert.Equal(t, "a summary", memory.Summary.Content)
+
+	_, memory = get("?query=invoice&k=1")
+	assert.Len(t, memory.Messages, 1)
+
+	for _, query := range []string{"?k=2", "?query=invoice&k=-1", "?query=invoice&k=x"} {
+		status, _ = get(query)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/getzep/zep/internal"
 
 	"github.com/getzep/zep/pkg/auth"
+	"github.com/getzep/zep/pkg/metrics"
 	"github.com/getzep/zep/pkg/server/apihandlers"
+	"github.com/getzep/zep/pkg/server/handlertools"
 	"github.com/getzep/zep/pkg/server/webhandlers"
 	"github.com/go-chi/jwtauth/v5"
 
@@ -72,6 +74,10 @@ func setupRouter(appState *models.AppState) *chi.Mux {
 		middleware.Heartbeat("/healthz"),
 	)
 
+	if appState.Config.Metrics.Enabled {
+		setupMetricsRoutes(router, appState)
+	}
+
 	// Only setup web routes if enabled
 	if appState.Config.Server.WebEnabled {
 		log.Info("Web interface enabled")
@@ -80,11 +86,40 @@ func setupRouter(appState *models.AppState) *chi.Mux {
 		log.Info("Web interface disabled")
 	}
 
+	if appState.Config.Auth.Required {
+		log.Info("JWT authentication required")
+	}
 	setupAPIRoutes(router, appState)
 
+	if appState.Config.Server.ProfilingEnabled {
+		setupDebugRoutes(router, appState)
+	}
+
 	return router
 }
 
+// setupMetricsRoutes records SLIs, and optionally per-tenant request metrics and retrieval
+// experiment metrics, and serves them, unauthenticated, at /metrics. It must be called before any routes are added to
+// the router.
+func setupMetricsRoutes(router chi.Router, appState *models.AppState) {
+	log.Info("Metrics enabled")
+	cfg := appState.Config.Metrics
+	registry := metrics.NewRegistry()
+	sli := metrics.NewSLIMetrics(registry, cfg.SLO)
+	var tenants *metrics.TenantMetrics
+	if cfg.Tenants.Enabled {
+		tenants = metrics.NewTenantMetrics(registry, cfg.Tenants)
+	}
+	var experiments *metrics.ExperimentMetrics
+	if retrieval := appState.Config.Experiments.Retrieval; retrieval.Enabled {
+		experiments = metrics.NewExperimentMetrics(registry, retrieval.Name)
+	}
+	router.Use(
+		MetricsMiddleware(sli, tenants, metricsTenant(appState.Config), experiments),
+	)
+	router.Method(http.MethodGet, "/metrics", registry.Handler())
+}
+
 func setupWebRoutes(router chi.Router, appState *models.AppState) {
 	compressor := middleware.Compress(
 		5,
@@ -147,24 +182,89 @@ func setupWebRoutes(router chi.Router, appState *models.AppState) {
 	})
 }
 
+// setupAPIRoutes mounts the API at /api/v1 and /api/v2. The versions have the same
+// routes, but v2 responses are wrapped in an envelope and its lists are paginated with
+// opaque cursors. v1 is deprecated.
 func setupAPIRoutes(router chi.Router, appState *models.AppState) {
 	router.Route("/api/v1", func(r chi.Router) {
-		// JWT authentication on all API routes
-		if appState.Config.Auth.Required {
-			log.Info("JWT authentication required")
-			r.Use(auth.JWTVerifier(appState.Config))
-			r.Use(jwtauth.Authenticator)
-		}
+		r.Use(Deprecate(appState.Config.Server.V1Deprecation, "/api/v2"))
+		setupAPIVersionRoutes(r, appState)
+	})
+	router.Route("/api/v2", func(r chi.Router) {
+		r.Use(handlertools.Envelope)
+		setupAPIVersionRoutes(r, appState)
+	})
+}
+
+func setupAPIVersionRoutes(r chi.Router, appState *models.AppState) {
+	// JWT authentication on all API routes
+	if appState.Config.Auth.Required {
+		r.Use(auth.JWTVerifier(appState.Config))
+		r.Use(jwtauth.Authenticator)
+	}
+	if appState.Config.Server.StrictJSON {
+		r.Use(handlertools.StrictJSON)
+	}
+	if tenants := appState.Config.LLM.Tenants; tenants.Enabled {
+		r.Use(Tenant(tenantFromRequest(appState.Config, tenants.Claim, tenants.Header)))
+	}
+
+	// the admin routes remain writable in maintenance mode, so that it can be disabled
+	r.Group(func(r chi.Router) {
+		r.Use(ReadOnly(appState.Maintenance))
 
-		setupSessionRoutes(r, appState)
-		setupUserRoutes(r, appState)
+		// collection tokens may only read their collection
+		r.Group(func(r chi.Router) {
+			r.Use(auth.DenyCollectionTokens)
+			setupSessionRoutes(r, appState)
+			setupUserRoutes(r, appState)
+			setupTaskRoutes(r, appState)
+			r.Get("/provenance/{responseId}", apihandlers.GetProvenanceHandler(appState))
+			r.Get("/analytics/search", apihandlers.GetSearchAnalyticsHandler(appState))
+			setupEvalRoutes(r, appState)
+		})
 		setupCollectionRoutes(r, appState)
 	})
+	setupAdminRoutes(r, appState)
+}
+
+// setupAdminRoutes mounts the administrative API under /admin. Like the debug routes, it
+// always requires a token with the admin scope, regardless of whether auth is required.
+func setupAdminRoutes(router chi.Router, appState *models.AppState) {
+	if appState.Config.Auth.Secret == "" {
+		log.Debug("auth.secret is not set. admin endpoints are disabled")
+		return
+	}
+
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(auth.JWTVerifier(appState.Config))
+		r.Use(jwtauth.Authenticator)
+		r.Use(auth.RequireScope(auth.ScopeAdmin))
+
+		r.Get("/jobs", apihandlers.ListScheduledJobsHandler(appState))
+		r.Get("/maintenance", apihandlers.GetMaintenanceHandler(appState))
+		r.Put("/maintenance", apihandlers.PutMaintenanceHandler(appState))
+		r.Post("/integrity", apihandlers.VerifyContentIntegrityHandler(appState))
+		r.Post("/users/{userId}/anonymize", apihandlers.AnonymizeUserHandler(appState))
+		r.Route("/legal-holds", func(r chi.Router) {
+			r.Get("/", apihandlers.ListLegalHoldsHandler(appState))
+			r.Get("/events", apihandlers.ListLegalHoldEventsHandler(appState))
+			r.Put("/{subjectType}/{subjectId}", apihandlers.PlaceLegalHoldHandler(appState))
+			r.Delete("/{subjectType}/{subjectId}", apihandlers.LiftLegalHoldHandler(appState))
+		})
+		r.Route("/provider-overrides", func(r chi.Router) {
+			r.Get("/", apihandlers.ListProviderOverridesHandler(appState))
+			r.Get("/{tenant}", apihandlers.GetProviderOverrideHandler(appState))
+			r.Put("/{tenant}", apihandlers.PutProviderOverrideHandler(appState))
+			r.Delete("/{tenant}", apihandlers.DeleteProviderOverrideHandler(appState))
+		})
+	})
 }
 
 func setupSessionRoutes(router chi.Router, appState *models.AppState) {
 	router.Get("/sessions", apihandlers.GetSessionListHandler(appState))
 	router.Post("/sessions", apihandlers.CreateSessionHandler(appState))
+	router.Post("/sessions/batch", apihandlers.CreateSessionsHandler(appState))
 	router.Route("/sessions/{sessionId}", func(r chi.Router) {
 		r.Get("/", apihandlers.GetSessionHandler(appState))
 		r.Patch("/", apihandlers.UpdateSessionHandler(appState))
@@ -174,10 +274,13 @@ func setupSessionRoutes(router chi.Router, appState *models.AppState) {
 			r.Post("/", apihandlers.PostMemoryHandler(appState))
 			r.Delete("/", apihandlers.DeleteMemoryHandler(appState))
 		})
+		r.Post("/truncate", apihandlers.TruncateSessionHandler(appState))
+		r.Get("/episodes", apihandlers.GetSessionEpisodesHandler(appState))
 
 		// Message-related routes
 		r.Route("/messages", func(r chi.Router) {
 			r.Get("/", apihandlers.GetMessagesForSessionHandler(appState))
+			r.Delete("/", apihandlers.DeleteMessagesHandler(appState))
 			r.Route("/{messageId}", func(r chi.Router) {
 				r.Get("/", apihandlers.GetMessageHandler(appState))
 				r.Patch("/", apihandlers.UpdateMessageMetadataHandler(appState))
@@ -188,6 +291,12 @@ func setupSessionRoutes(router chi.Router, appState *models.AppState) {
 		r.Route("/search", func(r chi.Router) {
 			r.Post("/", apihandlers.SearchMemoryHandler(appState))
 		})
+
+		// Memory snapshot-related routes
+		r.Route("/snapshots", func(r chi.Router) {
+			r.Get("/", apihandlers.ListMemorySnapshotsHandler(appState))
+			r.Get("/{turnId}", apihandlers.GetMemorySnapshotHandler(appState))
+		})
 	})
 }
 
@@ -199,38 +308,76 @@ func setupUserRoutes(router chi.Router, appState *models.AppState) {
 		r.Patch("/", apihandlers.UpdateUserHandler(appState))
 		r.Delete("/", apihandlers.DeleteUserHandler(appState))
 		r.Get("/sessions", apihandlers.ListUserSessionsHandler(appState))
+		r.Get("/topics", apihandlers.GetUserTopicsHandler(appState))
+		r.Get("/facts", apihandlers.GetUserFactsHandler(appState))
+		r.Post("/search", apihandlers.SearchUserMemoryHandler(appState))
+	})
+}
+
+func setupTaskRoutes(router chi.Router, appState *models.AppState) {
+	router.Get("/tasks", apihandlers.ListTasksHandler(appState))
+	router.Route("/tasks/{taskId}", func(r chi.Router) {
+		r.Get("/", apihandlers.GetTaskHandler(appState))
+		r.Post("/cancel", apihandlers.CancelTaskHandler(appState))
+	})
+}
+
+func setupEvalRoutes(router chi.Router, appState *models.AppState) {
+	router.Get("/evals", apihandlers.ListEvalSetsHandler(appState))
+	router.Post("/evals", apihandlers.PutEvalSetHandler(appState))
+	router.Route("/evals/{evalSetName}", func(r chi.Router) {
+		r.Get("/", apihandlers.GetEvalSetHandler(appState))
+		r.Delete("/", apihandlers.DeleteEvalSetHandler(appState))
+		r.Post("/run", apihandlers.RunEvalSetHandler(appState))
 	})
 }
 
 func setupCollectionRoutes(router chi.Router, appState *models.AppState) {
-	router.Get("/collection", apihandlers.GetCollectionListHandler(appState))
+	router.With(auth.DenyCollectionTokens).
+		Get("/collection", apihandlers.GetCollectionListHandler(appState))
+	// collection tokens may only search their own collection
+	router.With(auth.DenyCollectionTokens).
+		Post("/collection/search", apihandlers.SearchCollectionsHandler(appState))
 	router.Route("/collection/{collectionName}", func(r chi.Router) {
-		r.Post("/", apihandlers.CreateCollectionHandler(appState))
-		r.Get("/", apihandlers.GetCollectionHandler(appState))
-		r.Delete("/", apihandlers.DeleteCollectionHandler(appState))
-		r.Patch("/", apihandlers.UpdateCollectionHandler(appState))
+		// collection tokens may use the read routes of their collection
+		read, write := collectionTokenRouters(r)
+		write.Post("/", apihandlers.CreateCollectionHandler(appState))
+		write.Put("/", apihandlers.PutCollectionHandler(appState))
+		read.Get("/", apihandlers.GetCollectionHandler(appState))
+		write.Delete("/", apihandlers.DeleteCollectionHandler(appState))
+		write.Patch("/", apihandlers.UpdateCollectionHandler(appState))
 
 		// Document collection search-related routes
-		r.Post("/search", apihandlers.SearchDocumentsHandler(appState))
+		read.Post("/search", apihandlers.SearchDocumentsHandler(appState))
 
 		// Document collection index-related routes
-		r.Post("/index/create", apihandlers.CreateCollectionIndexHandler(appState))
+		write.Post("/index/create", apihandlers.CreateCollectionIndexHandler(appState))
 
 		// Document-related routes
 		r.Route("/document", func(r chi.Router) {
-			r.Post("/", apihandlers.CreateDocumentsHandler(appState))
+			_, write := collectionTokenRouters(r)
+			write.Post("/", apihandlers.CreateDocumentsHandler(appState))
+			write.Post("/ingest", apihandlers.IngestDocumentsHandler(appState))
 			// Single document routes (by UUID)
 			r.Route("/uuid/{documentUUID}", func(r chi.Router) {
-				r.Get("/", apihandlers.GetDocumentHandler(appState))
-				r.Patch("/", apihandlers.UpdateDocumentHandler(appState))
-				r.Delete("/", apihandlers.DeleteDocumentHandler(appState))
+				read, write := collectionTokenRouters(r)
+				read.Get("/", apihandlers.GetDocumentHandler(appState))
+				write.Patch("/", apihandlers.UpdateDocumentHandler(appState))
+				write.Delete("/", apihandlers.DeleteDocumentHandler(appState))
 			})
 			// Document list routes
 			r.Route("/list", func(r chi.Router) {
-				r.Post("/get", apihandlers.GetDocumentListHandler(appState))
-				r.Post("/delete", apihandlers.DeleteDocumentListHandler(appState))
-				r.Patch("/update", apihandlers.UpdateDocumentListHandler(appState))
+				read, write := collectionTokenRouters(r)
+				read.Post("/get", apihandlers.GetDocumentListHandler(appState))
+				write.Post("/delete", apihandlers.DeleteDocumentListHandler(appState))
+				write.Patch("/update", apihandlers.UpdateDocumentListHandler(appState))
 			})
 		})
 	})
 }
+
+// collectionTokenRouters returns routers for the read routes of a collection, which the
+// collection's tokens may access, and for the routes modifying it, which they may not.
+func collectionTokenRouters(r chi.Router) (read chi.Router, write chi.Router) {
+	return r.With(auth.AllowCollectionToken("collectionName")), r.With(auth.DenyCollectionTokens)
+}
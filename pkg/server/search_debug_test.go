@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestSearchDebug(t *testing.T) {
+	ctx := context.Background()
+	debugState := &models.AppState{Config: &config.Config{}}
+	debugState.MemoryStore = inmemory.NewMemoryStore(debugState, inmemory.NewDB())
+	router := chi.NewRouter()
+	setupSessionRoutes(router, debugState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	err := debugState.MemoryStore.PutMemory(ctx, "s1", &models.Memory{
+		Messages: []models.Message{{Role: "user", Content: "My invoice is wrong"}},
+	}, true)
+	require.NoError(t, err)
+
+	search := func(query string) map[string]json.RawMessage {
+		body, err := json.Marshal(models.MemorySearchPayload{Text: "invoice"})
+		require.NoError(t, err)
+		resp, err := http.Post(
+			server.URL+"/sessions/s1/search"+query,
+			"application/json",
+			bytes.NewReader(body),
+		)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var response map[string]json.RawMessage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		return response
+	}
+
+	response := search("?debug=true")
+	var results []models.MemorySearchResult
+	require.NoError(t, json.Unmarshal(response["results"], &results))
+	assert.Len(t, results, 1)
+	var debug models.SearchDebug
+	require.NoError(t, json.Unmarshal(response["debug"], &debug))
+	assert.NotNil(t, debug.Distances)
+
+	// fields select those of the results
+	response = search("?debug=true&fields=message.uuid")
+	results = nil
+	require.NoError(t, json.Unmarshal(response["results"], &results))
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Message.Content)
+	assert.NotEmpty(t, results[0].Message.UUID)
+	assert.Contains(t, response, "debug")
+
+	resp, err := http.Post(
+		server.URL+"/sessions/s1/search?debug=maybe",
+		"application/json",
+		bytes.NewReader([]byte(`{"text": "invoice"}`)),
+	)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
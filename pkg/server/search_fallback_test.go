@@ -0,0 +1,68 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/searchfallback"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestSearchFallback(t *testing.T) {
+	ctx := context.Background()
+	fallbackState := &models.AppState{Config: &config.Config{}}
+	fallbackState.MemoryStore = inmemory.NewMemoryStore(fallbackState, inmemory.NewDB())
+	router := chi.NewRouter()
+	setupSessionRoutes(router, fallbackState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	err := fallbackState.MemoryStore.PutMemory(ctx, "s1", &models.Memory{
+		Messages: []models.Message{{Role: "user", Content: "my invoice is wrong"}},
+	}, true)
+	require.NoError(t, err)
+
+	search := func(payload models.MemorySearchPayload) ([]models.MemorySearchResult, string) {
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		resp, err := http.Post(
+			server.URL+"/sessions/s1/search",
+			"application/json",
+			bytes.NewReader(body),
+		)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var results []models.MemorySearchResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+		return results, resp.Header.Get(searchfallback.Header)
+	}
+
+	// the message isn't in an episode
+	results, fallback := search(models.MemorySearchPayload{Text: "invoice", Episode: 1})
+	assert.Empty(t, results)
+	assert.Empty(t, fallback)
+
+	results, fallback = search(models.MemorySearchPayload{
+		Text:     "invoice",
+		Episode:  1,
+		Fallback: true,
+	})
+	assert.Len(t, results, 1)
+	assert.Equal(t, searchfallback.DropMetadata, fallback)
+
+	// searches with results don't fall back
+	results, fallback = search(models.MemorySearchPayload{Text: "invoice", Fallback: true})
+	assert.Len(t, results, 1)
+	assert.Empty(t, fallback)
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/server/apihandlers"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestMemorySnapshotRoutes(t *testing.T) {
+	db := inmemory.NewDB()
+	snapshotState := &models.AppState{
+		Config:              &config.Config{},
+		MemorySnapshotStore: inmemory.NewMemorySnapshotStore(db),
+	}
+	snapshotState.MemoryStore = inmemory.NewMemoryStore(snapshotState, db)
+	router := chi.NewRouter()
+	setupSessionRoutes(router, snapshotState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	get := func(path string, v any) *http.Response {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if v != nil && resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp
+	}
+	putMessage := func(content string) {
+		t.Helper()
+		err := snapshotState.MemoryStore.PutMemory(context.Background(), "s1", &models.Memory{
+			Messages: []models.Message{{Role: "user", Content: content}},
+		}, true)
+		require.NoError(t, err)
+	}
+
+	putMessage("hello")
+	var memory models.Memory
+	resp := get("/sessions/s1/memory?snapshot=true", &memory)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, memory.Messages, 1)
+	turn := memory.Messages[0].UUID.String()
+	assert.Equal(t, turn, resp.Header.Get(apihandlers.SnapshotTurnHeader))
+
+	// memory read without snapshot isn't stored
+	putMessage("again")
+	resp = get("/sessions/s1/memory", &memory)
+	assert.Empty(t, resp.Header.Get(apihandlers.SnapshotTurnHeader))
+
+	customTurn := uuid.New().String()
+	resp = get("/sessions/s1/memory?snapshot=true&turn="+customTurn, &memory)
+	assert.Equal(t, customTurn, resp.Header.Get(apihandlers.SnapshotTurnHeader))
+
+	var snapshot models.MemorySnapshot
+	resp = get("/sessions/s1/snapshots/"+turn, &snapshot)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, snapshot.Memory.Messages, 1)
+	assert.Equal(t, "hello", snapshot.Memory.Messages[0].Content)
+
+	var snapshots []models.MemorySnapshot
+	resp = get("/sessions/s1/snapshots", &snapshots)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, customTurn, snapshots[1].TurnUUID.String())
+	assert.Len(t, snapshots[1].Memory.Messages, 2)
+
+	resp = get("/sessions/s1/snapshots/"+uuid.NewString(), nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = get("/sessions/s1/snapshots/turn", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = get("/sessions/s1/memory?snapshot=true&turn=x", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestSearchTokenBudget(t *testing.T) {
+	ctx := context.Background()
+	budgetState := &models.AppState{Config: &config.Config{}}
+	budgetState.MemoryStore = inmemory.NewMemoryStore(budgetState, inmemory.NewDB())
+	router := chi.NewRouter()
+	setupSessionRoutes(router, budgetState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	messages := make([]models.Message, 15)
+	for i := range messages {
+		messages[i] = models.Message{
+			Role:       "user",
+			Content:    fmt.Sprintf("invoice %d", i),
+			TokenCount: 10,
+		}
+	}
+	err := budgetState.MemoryStore.PutMemory(ctx, "s1", &models.Memory{Messages: messages}, true)
+	require.NoError(t, err)
+
+	search := func(query string, maxTokens int) (int, []models.MemorySearchResult) {
+		body, err := json.Marshal(models.MemorySearchPayload{
+			Text:       "invoice",
+			SearchType: models.SearchTypeLiteral,
+			MaxTokens:  maxTokens,
+		})
+		require.NoError(t, err)
+		resp, err := http.Post(
+			server.URL+"/sessions/s1/search"+query,
+			"application/json",
+			bytes.NewReader(body),
+		)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var results []models.MemorySearchResult
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+		}
+		return resp.StatusCode, results
+	}
+
+	status, results := search("", 55)
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, results, 5)
+
+	// the budget, rather than the default limit of 10, limits the results
+	_, results = search("", 1000)
+	assert.Len(t, results, 15)
+	_, results = search("?limit=3", 1000)
+	assert.Len(t, results, 3)
+
+	status, _ = search("", -1)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestSearchUserMemory(t *testing.T) {
+	ctx := context.Background()
+	db := inmemory.NewDB()
+	userState := &models.AppState{Config: &config.Config{}, UserStore: inmemory.NewUserStore(db)}
+	userState.MemoryStore = inmemory.NewMemoryStore(userState, db)
+	router := chi.NewRouter()
+	setupUserRoutes(router, userState)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	_, err := userState.UserStore.Create(ctx, &models.CreateUserRequest{UserID: "alice"})
+	require.NoError(t, err)
+	userID := "alice"
+	_, err = userState.MemoryStore.CreateSession(ctx, &models.CreateSessionRequest{
+		SessionID: "s1",
+		UserID:    &userID,
+	})
+	require.NoError(t, err)
+	err = userState.MemoryStore.PutMemory(ctx, "s1", &models.Memory{
+		Messages: []models.Message{{Role: "user", Content: "my invoice is wrong"}},
+	}, true)
+	require.NoError(t, err)
+
+	search := func(path string, payload models.MemorySearchPayload) *http.Response {
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := search("/user/alice/search?sessions=2", models.MemorySearchPayload{Text: "invoice"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var results []models.MemorySearchResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+	resp.Body.Close()
+	require.Len(t, results, 1)
+	assert.Equal(t, "s1", results[0].SessionID)
+
+	for path, status := range map[string]int{
+		"/user/bob/search":               http.StatusNotFound,
+		"/user/alice/search?sessions=-1": http.StatusBadRequest,
+	} {
+		resp = search(path, models.MemorySearchPayload{Text: "invoice"})
+		resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, path)
+	}
+	resp = search("/user/alice/search", models.MemorySearchPayload{
+		Text:       "invoice",
+		SearchType: models.SearchTypeMMR,
+	})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
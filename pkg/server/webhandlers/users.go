@@ -129,7 +129,7 @@ func GetUserDetailsHandler(appState *models.AppState) http.HandlerFunc {
 		if userID == "" {
 			handleError(
 				w,
-				models.NewBadRequestError("user id not provided"),
+				models.NewValidationError("user id not provided"),
 				"user id not provided",
 			)
 			return
@@ -198,7 +198,7 @@ func PostUserDetailsHandler(appState *models.AppState) http.HandlerFunc {
 		if userID == "" {
 			handleError(
 				w,
-				models.NewBadRequestError("user id not provided"),
+				models.NewValidationError("user id not provided"),
 				"user id not provided",
 			)
 			return
@@ -245,7 +245,7 @@ func DeleteUserHandler(appState *models.AppState) http.HandlerFunc {
 		if userID == "" {
 			handleError(
 				w,
-				models.NewBadRequestError("user id not provided"),
+				models.NewValidationError("user id not provided"),
 				"user id not provided",
 			)
 			return
@@ -3,8 +3,13 @@ package store
 import (
 	"errors"
 	"fmt"
+
+	"github.com/getzep/zep/pkg/models"
 )
 
+// StorageError wraps an error returned by the storage backend. It unwraps to the
+// original error, so that errors in the models error taxonomy, such as
+// models.ErrNotFound, are preserved.
 type StorageError struct {
 	Message       string
 	OriginalError error
@@ -14,6 +19,10 @@ func (e *StorageError) Error() string {
 	return fmt.Sprintf("storage error: %s (original error: %v)", e.Message, e.OriginalError)
 }
 
+func (e *StorageError) Unwrap() error {
+	return e.OriginalError
+}
+
 func NewStorageError(message string, originalError error) *StorageError {
 	return &StorageError{Message: message, OriginalError: originalError}
 }
@@ -34,8 +43,10 @@ func (e *EmbeddingMismatchError) Error() string {
 	)
 }
 
-func (e *EmbeddingMismatchError) Unwrap() error {
-	return ErrEmbeddingMismatch
+// Unwrap matches both ErrEmbeddingMismatch and models.ErrValidation, as the mismatch is
+// caused by the embeddings provided in the request.
+func (e *EmbeddingMismatchError) Unwrap() []error {
+	return []error{ErrEmbeddingMismatch, models.ErrValidation}
 }
 
 func NewEmbeddingMismatchError(
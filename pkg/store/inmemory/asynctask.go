@@ -0,0 +1,170 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.AsyncTaskStore = &AsyncTaskStore{}
+
+// AsyncTaskStore is an in-memory implementation of models.AsyncTaskStore.
+type AsyncTaskStore struct {
+	db *DB
+}
+
+// NewAsyncTaskStore returns an AsyncTaskStore for the tasks in db.
+func NewAsyncTaskStore(db *DB) *AsyncTaskStore {
+	return &AsyncTaskStore{db: db}
+}
+
+// Create stores a new pending task.
+func (s *AsyncTaskStore) Create(
+	_ context.Context,
+	taskType string,
+	params map[string]interface{},
+) (*models.AsyncTask, error) {
+	if taskType == "" {
+		return nil, models.NewValidationError("task type cannot be empty")
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	createdAt := now()
+	task := &models.AsyncTask{
+		UUID:      newUUID(),
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		Type:      taskType,
+		Status:    models.AsyncTaskPending,
+		Params:    copyMetadata(params),
+	}
+	s.db.asyncTasks[task.UUID] = task
+
+	return copyAsyncTask(task), nil
+}
+
+// Get returns a task.
+func (s *AsyncTaskStore) Get(_ context.Context, taskUUID uuid.UUID) (*models.AsyncTask, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	task, ok := s.db.asyncTasks[taskUUID]
+	if !ok {
+		return nil, models.NewNotFoundError("task " + taskUUID.String())
+	}
+	return copyAsyncTask(task), nil
+}
+
+// List returns the tasks matching opts, newest first.
+func (s *AsyncTaskStore) List(
+	_ context.Context,
+	opts models.AsyncTaskListOptions,
+) ([]*models.AsyncTask, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	tasks := []*models.AsyncTask{}
+	for _, task := range s.db.asyncTasks {
+		if opts.Type != "" && task.Type != opts.Type {
+			continue
+		}
+		if opts.Status != "" && task.Status != opts.Status {
+			continue
+		}
+		tasks = append(tasks, copyAsyncTask(task))
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
+	})
+	if opts.Limit > 0 && len(tasks) > opts.Limit {
+		tasks = tasks[:opts.Limit]
+	}
+	return tasks, nil
+}
+
+// Update stores the task's status, progress, error, result, and start and finish times.
+func (s *AsyncTaskStore) Update(
+	_ context.Context,
+	task *models.AsyncTask,
+) (*models.AsyncTask, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.asyncTasks[task.UUID]
+	if !ok {
+		return nil, models.NewNotFoundError("task " + task.UUID.String())
+	}
+	if stored.Status.IsFinished() {
+		return nil, models.NewConflictError("task is " + string(stored.Status))
+	}
+
+	stored.Status = task.Status
+	stored.Completed = task.Completed
+	stored.Total = task.Total
+	stored.Error = task.Error
+	stored.Result = copyMetadata(task.Result)
+	stored.StartedAt = copyTime(task.StartedAt)
+	stored.FinishedAt = copyTime(task.FinishedAt)
+	stored.UpdatedAt = now()
+
+	return copyAsyncTask(stored), nil
+}
+
+// Cancel marks an unfinished task as canceled.
+func (s *AsyncTaskStore) Cancel(
+	_ context.Context,
+	taskUUID uuid.UUID,
+) (*models.AsyncTask, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	task, ok := s.db.asyncTasks[taskUUID]
+	if !ok {
+		return nil, models.NewNotFoundError("task " + taskUUID.String())
+	}
+	if task.Status.IsFinished() {
+		return nil, models.NewConflictError("task is " + string(task.Status))
+	}
+
+	finishedAt := now()
+	task.Status = models.AsyncTaskCanceled
+	task.FinishedAt = &finishedAt
+	task.UpdatedAt = finishedAt
+
+	return copyAsyncTask(task), nil
+}
+
+// FailAbandoned fails the unfinished tasks not updated since before.
+func (s *AsyncTaskStore) FailAbandoned(_ context.Context, before time.Time) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	failed := 0
+	finishedAt := now()
+	for _, task := range s.db.asyncTasks {
+		if task.Status.IsFinished() || !task.UpdatedAt.Before(before) {
+			continue
+		}
+		task.Status = models.AsyncTaskFailed
+		task.Error = "task abandoned"
+		task.FinishedAt = &finishedAt
+		task.UpdatedAt = finishedAt
+		failed++
+	}
+	return failed, nil
+}
+
+func copyAsyncTask(task *models.AsyncTask) *models.AsyncTask {
+	c := *task
+	c.Params = copyMetadata(task.Params)
+	c.Result = copyMetadata(task.Result)
+	c.StartedAt = copyTime(task.StartedAt)
+	c.FinishedAt = copyTime(task.FinishedAt)
+	return &c
+}
@@ -0,0 +1,68 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/storetest"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.RunMemoryStoreTests(t, func(t *testing.T) models.MemoryStore[any] {
+		appState := &models.AppState{Config: &config.Config{}, TaskPublisher: &recordingPublisher{}}
+		return NewMemoryStore(appState, NewDB())
+	})
+}
+
+func TestAsyncTaskStoreConformance(t *testing.T) {
+	storetest.RunAsyncTaskStoreTests(t, func(t *testing.T) models.AsyncTaskStore {
+		return NewAsyncTaskStore(NewDB())
+	})
+}
+
+func TestMemorySnapshotStoreConformance(t *testing.T) {
+	storetest.RunMemorySnapshotStoreTests(
+		t,
+		func(t *testing.T) (models.MemoryStore[any], models.MemorySnapshotStore) {
+			appState := &models.AppState{Config: &config.Config{}, TaskPublisher: &recordingPublisher{}}
+			db := NewDB()
+			return NewMemoryStore(appState, db), NewMemorySnapshotStore(db)
+		},
+	)
+}
+
+func TestProvenanceStoreConformance(t *testing.T) {
+	storetest.RunProvenanceStoreTests(t, func(t *testing.T) models.ProvenanceStore {
+		return NewProvenanceStore(NewDB())
+	})
+}
+
+func TestQueryLogStoreConformance(t *testing.T) {
+	storetest.RunQueryLogStoreTests(t, func(t *testing.T) models.QueryLogStore {
+		return NewQueryLogStore(NewDB())
+	})
+}
+
+func TestEvalSetStoreConformance(t *testing.T) {
+	storetest.RunEvalSetStoreTests(t, func(t *testing.T) models.EvalSetStore {
+		return NewEvalSetStore(NewDB())
+	})
+}
+
+func TestLegalHoldStoreConformance(t *testing.T) {
+	storetest.RunLegalHoldStoreTests(
+		t,
+		func(t *testing.T) (models.MemoryStore[any], models.UserStore, models.LegalHoldStore) {
+			appState := &models.AppState{Config: &config.Config{}, TaskPublisher: &recordingPublisher{}}
+			db := NewDB()
+			return NewMemoryStore(appState, db), NewUserStore(db), NewLegalHoldStore(db)
+		},
+	)
+}
+
+func TestProviderOverrideStoreConformance(t *testing.T) {
+	storetest.RunProviderOverrideStoreTests(t, func(t *testing.T) models.ProviderOverrideStore {
+		return NewProviderOverrideStore(NewDB())
+	})
+}
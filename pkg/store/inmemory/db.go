@@ -0,0 +1,238 @@
+// Package inmemory implements the memory store, user store, async task store, memory
+// snapshot store, provenance store, query log store, eval set store, legal hold store,
+// provider override store, and DAO interfaces in memory. It is intended for tests and for
+// applications embedding Zep as a library that don't need a Postgres database. Records are
+// not persisted.
+package inmemory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// DB holds the records of the in-memory stores. Stores created with the same DB share
+// its records, so a MemoryStore and a UserStore should be created with the same DB.
+type DB struct {
+	mu        sync.RWMutex
+	lastID    int64
+	users     map[string]*userRecord
+	sessions  map[string]*sessionRecord
+	messages  map[string][]*messageRecord
+	summaries map[string][]*summaryRecord
+	snapshots map[string][]*snapshotRecord
+	// asyncTasks are never deleted
+	asyncTasks map[uuid.UUID]*models.AsyncTask
+	provenance map[uuid.UUID]*models.ContextProvenance
+	// searches are logged in order
+	searches []*models.LoggedSearch
+	evalSets map[string]*models.EvalSet
+	// legal holds are keyed by subject, and their events are recorded in order
+	legalHolds      map[legalHoldKey]*models.LegalHold
+	legalHoldEvents []*models.LegalHoldEvent
+	// provider overrides are keyed by tenant
+	providerOverrides map[string]*models.ProviderOverride
+}
+
+type userRecord struct {
+	user models.User
+}
+
+type sessionRecord struct {
+	session        models.Session
+	expiryWarnedAt *time.Time
+}
+
+// messageRecord is a message and its embedding. A session's messages are stored in
+// order of creation, and id increases with each message created.
+type messageRecord struct {
+	id        int64
+	message   models.Message
+	embedding []float32
+	window    []uuid.UUID
+	deleted   bool
+}
+
+type summaryRecord struct {
+	summary   models.Summary
+	embedding []float32
+	deleted   bool
+}
+
+type snapshotRecord struct {
+	snapshot models.MemorySnapshot
+	deleted  bool
+}
+
+// NewDB returns a new, empty DB.
+func NewDB() *DB {
+	return &DB{
+		users:      make(map[string]*userRecord),
+		sessions:   make(map[string]*sessionRecord),
+		messages:   make(map[string][]*messageRecord),
+		summaries:  make(map[string][]*summaryRecord),
+		snapshots:  make(map[string][]*snapshotRecord),
+		asyncTasks: make(map[uuid.UUID]*models.AsyncTask),
+		provenance: make(map[uuid.UUID]*models.ContextProvenance),
+		evalSets:   make(map[string]*models.EvalSet),
+		legalHolds: make(map[legalHoldKey]*models.LegalHold),
+
+		providerOverrides: make(map[string]*models.ProviderOverride),
+	}
+}
+
+// nextID returns a new record ID. The caller must hold the write lock.
+func (db *DB) nextID() int64 {
+	db.lastID++
+	return db.lastID
+}
+
+// getSession returns the session record for sessionID, or nil if the session doesn't
+// exist or is deleted. The caller must hold the lock.
+func (db *DB) getSession(sessionID string) *sessionRecord {
+	rec, ok := db.sessions[sessionID]
+	if !ok || rec.session.DeletedAt != nil {
+		return nil
+	}
+	return rec
+}
+
+// deleteSession soft deletes a session and its messages, message embeddings, summaries,
+// and memory snapshots. The caller must hold the write lock.
+func (db *DB) deleteSession(rec *sessionRecord, now time.Time) {
+	rec.session.DeletedAt = &now
+	for _, m := range db.messages[rec.session.SessionID] {
+		m.deleted = true
+	}
+	for _, s := range db.summaries[rec.session.SessionID] {
+		s.deleted = true
+	}
+	for _, s := range db.snapshots[rec.session.SessionID] {
+		s.deleted = true
+	}
+}
+
+// undeletedSessions returns the undeleted sessions, ordered by ID. The caller must hold
+// the lock.
+func (db *DB) undeletedSessions() []*sessionRecord {
+	var sessions []*sessionRecord
+	for _, rec := range db.sessions {
+		if rec.session.DeletedAt == nil {
+			sessions = append(sessions, rec)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].session.ID < sessions[j].session.ID
+	})
+	return sessions
+}
+
+// messageIndex returns the ID of the message with the given UUID, including deleted
+// messages, or 0 if the message doesn't exist. The caller must hold the lock.
+func (db *DB) messageIndex(sessionID string, messageUUID uuid.UUID) int64 {
+	if messageUUID == uuid.Nil {
+		return 0
+	}
+	for _, m := range db.messages[sessionID] {
+		if m.message.UUID == messageUUID {
+			return m.id
+		}
+	}
+	return 0
+}
+
+// latestSummary returns the session's most recent undeleted summary, or nil. The caller
+// must hold the lock.
+func (db *DB) latestSummary(sessionID string) *summaryRecord {
+	summaries := db.summaries[sessionID]
+	for i := len(summaries) - 1; i >= 0; i-- {
+		if !summaries[i].deleted {
+			return summaries[i]
+		}
+	}
+	return nil
+}
+
+// purgeDeleted hard deletes all soft-deleted records.
+func (db *DB) purgeDeleted() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for userID, rec := range db.users {
+		if rec.user.DeletedAt != nil {
+			delete(db.users, userID)
+		}
+	}
+	for sessionID, rec := range db.sessions {
+		if rec.session.DeletedAt != nil {
+			delete(db.sessions, sessionID)
+		}
+	}
+	for sessionID, messages := range db.messages {
+		db.messages[sessionID] = filterDeleted(messages, func(m *messageRecord) bool { return m.deleted })
+	}
+	for sessionID, summaries := range db.summaries {
+		db.summaries[sessionID] = filterDeleted(summaries, func(s *summaryRecord) bool { return s.deleted })
+	}
+	for sessionID, snapshots := range db.snapshots {
+		db.snapshots[sessionID] = filterDeleted(snapshots, func(s *snapshotRecord) bool { return s.deleted })
+	}
+}
+
+func filterDeleted[T any](records []T, deleted func(T) bool) []T {
+	kept := records[:0]
+	for _, r := range records {
+		if !deleted(r) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// page returns the items on the given page, starting at 1. If pageSize is 0, all items
+// are returned.
+func page[T any](items []T, pageNumber int, pageSize int) []T {
+	if pageSize <= 0 {
+		return items
+	}
+	offset := max(pageNumber-1, 0) * pageSize
+	if offset >= len(items) {
+		return nil
+	}
+	return items[offset:min(offset+pageSize, len(items))]
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func newUUID() uuid.UUID {
+	return uuid.New()
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,80 @@
+package inmemory
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.EvalSetStore = &EvalSetStore{}
+
+// EvalSetStore is an in-memory implementation of models.EvalSetStore.
+type EvalSetStore struct {
+	db *DB
+}
+
+// NewEvalSetStore returns an EvalSetStore storing eval sets in db.
+func NewEvalSetStore(db *DB) *EvalSetStore {
+	return &EvalSetStore{db: db}
+}
+
+func (s *EvalSetStore) Put(_ context.Context, set *models.EvalSet) (*models.EvalSet, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored := copyEvalSet(set)
+	stored.UpdatedAt = now()
+	if existing, ok := s.db.evalSets[set.Name]; ok {
+		stored.CreatedAt = existing.CreatedAt
+	} else {
+		stored.CreatedAt = stored.UpdatedAt
+	}
+	s.db.evalSets[set.Name] = stored
+	return copyEvalSet(stored), nil
+}
+
+func (s *EvalSetStore) Get(_ context.Context, name string) (*models.EvalSet, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	set, ok := s.db.evalSets[name]
+	if !ok {
+		return nil, models.NewNotFoundError("eval set " + name)
+	}
+	return copyEvalSet(set), nil
+}
+
+func (s *EvalSetStore) List(_ context.Context) ([]*models.EvalSet, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	sets := make([]*models.EvalSet, 0, len(s.db.evalSets))
+	for _, set := range s.db.evalSets {
+		sets = append(sets, copyEvalSet(set))
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Name < sets[j].Name })
+	return sets, nil
+}
+
+func (s *EvalSetStore) Delete(_ context.Context, name string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.evalSets[name]; !ok {
+		return models.NewNotFoundError("eval set " + name)
+	}
+	delete(s.db.evalSets, name)
+	return nil
+}
+
+func copyEvalSet(set *models.EvalSet) *models.EvalSet {
+	c := *set
+	c.Cases = make([]models.EvalCase, len(set.Cases))
+	for i, evalCase := range set.Cases {
+		evalCase.Relevant = slices.Clone(evalCase.Relevant)
+		c.Cases[i] = evalCase
+	}
+	return &c
+}
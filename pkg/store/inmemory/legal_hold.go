@@ -0,0 +1,143 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.LegalHoldStore = &LegalHoldStore{}
+
+type legalHoldKey struct {
+	subjectType string
+	subjectID   string
+}
+
+// LegalHoldStore is an in-memory implementation of models.LegalHoldStore. The memory and
+// user stores sharing its DB refuse to delete held users and sessions.
+type LegalHoldStore struct {
+	db *DB
+}
+
+// NewLegalHoldStore returns a LegalHoldStore storing holds in db.
+func NewLegalHoldStore(db *DB) *LegalHoldStore {
+	return &LegalHoldStore{db: db}
+}
+
+func (s *LegalHoldStore) Place(
+	_ context.Context,
+	hold *models.LegalHold,
+) (*models.LegalHold, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored := *hold
+	stored.PlacedAt = now()
+	s.db.legalHolds[legalHoldKey{hold.SubjectType, hold.SubjectID}] = &stored
+	s.db.recordLegalHoldEvent(
+		hold.SubjectType,
+		hold.SubjectID,
+		models.LegalHoldPlaced,
+		hold.Reason,
+		hold.PlacedBy,
+	)
+
+	placed := stored
+	return &placed, nil
+}
+
+func (s *LegalHoldStore) Lift(
+	_ context.Context,
+	subjectType, subjectID, actor, reason string,
+) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key := legalHoldKey{subjectType, subjectID}
+	if _, ok := s.db.legalHolds[key]; !ok {
+		return models.NewNotFoundError("legal hold on " + subjectType + " " + subjectID)
+	}
+	delete(s.db.legalHolds, key)
+	s.db.recordLegalHoldEvent(subjectType, subjectID, models.LegalHoldLifted, reason, actor)
+
+	return nil
+}
+
+func (s *LegalHoldStore) Get(
+	_ context.Context,
+	subjectType, subjectID string,
+) (*models.LegalHold, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	hold, ok := s.db.legalHolds[legalHoldKey{subjectType, subjectID}]
+	if !ok {
+		return nil, models.NewNotFoundError("legal hold on " + subjectType + " " + subjectID)
+	}
+	held := *hold
+	return &held, nil
+}
+
+func (s *LegalHoldStore) List(_ context.Context) ([]*models.LegalHold, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	holds := make([]*models.LegalHold, 0, len(s.db.legalHolds))
+	for _, hold := range s.db.legalHolds {
+		held := *hold
+		holds = append(holds, &held)
+	}
+	sort.SliceStable(holds, func(i, j int) bool {
+		return holds[i].PlacedAt.After(holds[j].PlacedAt)
+	})
+	return holds, nil
+}
+
+func (s *LegalHoldStore) ListEvents(
+	_ context.Context,
+	subjectType, subjectID string,
+) ([]*models.LegalHoldEvent, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	events := []*models.LegalHoldEvent{}
+	for _, event := range s.db.legalHoldEvents {
+		if subjectType != "" &&
+			(event.SubjectType != subjectType || event.SubjectID != subjectID) {
+			continue
+		}
+		e := *event
+		events = append(events, &e)
+	}
+	return events, nil
+}
+
+// recordLegalHoldEvent records a hold being placed or lifted. The caller must hold the
+// write lock.
+func (db *DB) recordLegalHoldEvent(subjectType, subjectID, action, reason, actor string) {
+	db.legalHoldEvents = append(db.legalHoldEvents, &models.LegalHoldEvent{
+		UUID:        uuid.New(),
+		CreatedAt:   now(),
+		SubjectType: subjectType,
+		SubjectID:   subjectID,
+		Action:      action,
+		Reason:      reason,
+		Actor:       actor,
+	})
+}
+
+// sessionHeld returns true if the session, or its user, is under legal hold. The caller
+// must hold the lock.
+func (db *DB) sessionHeld(session *models.Session) bool {
+	if _, ok := db.legalHolds[legalHoldKey{models.LegalHoldSubjectSession, session.SessionID}]; ok {
+		return true
+	}
+	if session.UserID == nil {
+		return false
+	}
+	_, ok := db.legalHolds[legalHoldKey{models.LegalHoldSubjectUser, *session.UserID}]
+	return ok
+}
@@ -0,0 +1,810 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
+	"github.com/getzep/zep/pkg/store"
+)
+
+const (
+	DefaultMessageWindow      = 12
+	DefaultMemorySearchLimit  = 10
+	DefaultUserSearchSessions = 5
+)
+
+var _ models.MemoryStore[*DB] = &MemoryStore{}
+
+// MemoryStore is an in-memory implementation of models.MemoryStore. If the AppState has a
+// TaskPublisher, new messages and summaries are published to it as with the Postgres
+// store. Otherwise, no tasks are published.
+type MemoryStore struct {
+	store.BaseMemoryStore[*DB]
+	SessionStore *SessionDAO
+	appState     *models.AppState
+}
+
+// NewMemoryStore returns a MemoryStore for the records in db. appState may be nil.
+func NewMemoryStore(appState *models.AppState, db *DB) *MemoryStore {
+	return &MemoryStore{
+		BaseMemoryStore: store.BaseMemoryStore[*DB]{Client: db},
+		SessionStore:    NewSessionDAO(db),
+		appState:        appState,
+	}
+}
+
+func (ms *MemoryStore) GetSession(ctx context.Context, sessionID string) (*models.Session, error) {
+	return ms.SessionStore.Get(ctx, sessionID)
+}
+
+func (ms *MemoryStore) CreateSession(
+	ctx context.Context,
+	session *models.CreateSessionRequest,
+) (*models.Session, error) {
+	return ms.SessionStore.Create(ctx, session)
+}
+
+func (ms *MemoryStore) CreateSessions(
+	ctx context.Context,
+	sessions []models.CreateSessionRequest,
+) (*models.CreateSessionsResponse, error) {
+	return ms.SessionStore.CreateBatch(ctx, sessions)
+}
+
+func (ms *MemoryStore) UpdateSession(
+	ctx context.Context,
+	session *models.UpdateSessionRequest,
+) (*models.Session, error) {
+	return ms.SessionStore.Update(ctx, session, false)
+}
+
+func (ms *MemoryStore) PatchSessionMetadata(
+	ctx context.Context,
+	sessionID string,
+	patch models.MetadataPatch,
+) (*models.Session, error) {
+	return ms.SessionStore.PatchMetadata(ctx, sessionID, patch, false)
+}
+
+func (ms *MemoryStore) DeleteSession(ctx context.Context, sessionID string) error {
+	return ms.SessionStore.Delete(ctx, sessionID)
+}
+
+func (ms *MemoryStore) ListSessions(
+	ctx context.Context,
+	cursor int64,
+	limit int,
+) ([]*models.Session, error) {
+	return ms.SessionStore.ListAll(ctx, cursor, limit)
+}
+
+func (ms *MemoryStore) ListSessionsOrdered(
+	ctx context.Context,
+	pageNumber int,
+	pageSize int,
+	orderedBy string,
+	asc bool,
+) (*models.SessionListResponse, error) {
+	return ms.SessionStore.ListAllOrdered(ctx, pageNumber, pageSize, orderedBy, asc)
+}
+
+func (ms *MemoryStore) ListExpiringSessions(
+	ctx context.Context,
+	before time.Time,
+) ([]*models.Session, error) {
+	return ms.SessionStore.ListExpiring(ctx, before)
+}
+
+func (ms *MemoryStore) MarkSessionsExpiryWarned(ctx context.Context, sessionIDs []string) error {
+	return ms.SessionStore.MarkExpiryWarned(ctx, sessionIDs)
+}
+
+func (ms *MemoryStore) DeleteExpiredSessions(ctx context.Context) ([]*models.Session, error) {
+	return ms.SessionStore.DeleteExpired(ctx)
+}
+
+func (ms *MemoryStore) UpdateSessionTitle(ctx context.Context, sessionID string, title string) error {
+	return ms.SessionStore.UpdateTitle(ctx, sessionID, title)
+}
+
+func (ms *MemoryStore) UpdateSessionTaskState(
+	ctx context.Context,
+	sessionID string,
+	state *models.TaskState,
+) error {
+	return ms.SessionStore.UpdateTaskState(ctx, sessionID, state)
+}
+
+func (ms *MemoryStore) UpdateSessionEpisodes(
+	ctx context.Context,
+	sessionID string,
+	episodes []models.Episode,
+) error {
+	return ms.SessionStore.UpdateEpisodes(ctx, sessionID, episodes)
+}
+
+// GetMemory returns the most recent Summary and a list of messages for a given sessionID.
+// See models.MemoryStorer.
+func (ms *MemoryStore) GetMemory(
+	ctx context.Context,
+	sessionID string,
+	lastNMessages int,
+) (*models.Memory, error) {
+	if lastNMessages < 0 {
+		return nil, models.NewValidationError("cannot specify negative lastNMessages")
+	}
+
+	summaryDAO, err := NewSummaryDAO(ms.Client, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := summaryDAO.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []models.Message
+	if lastNMessages > 0 {
+		messages, err = messageDAO.GetLastN(ctx, lastNMessages, uuid.Nil)
+	} else {
+		messages, err = messageDAO.GetSinceLastSummary(ctx, summary, ms.messageWindow())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	return &models.Memory{
+		Messages: messages,
+		Summary:  summary,
+	}, nil
+}
+
+// PutMemory stores the messages of a Memory for a given sessionID, creating the session if
+// it doesn't exist.
+func (ms *MemoryStore) PutMemory(
+	ctx context.Context,
+	sessionID string,
+	memoryMessages *models.Memory,
+	skipNotify bool,
+) error {
+	_, err := ms.SessionStore.Update(ctx, &models.UpdateSessionRequest{SessionID: sessionID}, false)
+	if errors.Is(err, models.ErrNotFound) {
+		_, err = ms.SessionStore.Create(ctx, &models.CreateSessionRequest{SessionID: sessionID})
+	}
+	if err != nil {
+		return err
+	}
+
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return err
+	}
+	messages, err := messageDAO.CreateMany(ctx, memoryMessages.Messages)
+	if err != nil {
+		return fmt.Errorf("failed to put messages: %w", err)
+	}
+
+	publisher := ms.taskPublisher()
+	if skipNotify || publisher == nil {
+		return nil
+	}
+
+	mt := make([]models.MessageTask, len(messages))
+	for i, message := range messages {
+		mt[i] = models.MessageTask{UUID: message.UUID}
+	}
+	metadata := models.WithTenantMetadata(ctx, map[string]string{"session_id": sessionID})
+	if err := publisher.PublishMessage(metadata, mt); err != nil {
+		return fmt.Errorf("failed to publish new messages %w", err)
+	}
+	if ms.appState.Config != nil && ms.appState.Config.Memory.MaxSessionMessages > 0 {
+		if err := publisher.Publish(models.MessageCompactorTopic, metadata, mt); err != nil {
+			return fmt.Errorf("failed to publish message compaction task %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (ms *MemoryStore) GetMessageList(
+	ctx context.Context,
+	sessionID string,
+	pageNumber int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return messageDAO.GetListBySession(ctx, pageNumber, pageSize)
+}
+
+// GetMatchingMessageList retrieves a page of the messages of a session matching the filter.
+// Expressions are matched by Go's regexp package, rather than by Postgres.
+func (ms *MemoryStore) GetMatchingMessageList(
+	ctx context.Context,
+	sessionID string,
+	filter *models.MessageFilter,
+	pageNumber int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	if err := filter.Validate(ms.maxMessageRegexComplexity()); err != nil {
+		return nil, err
+	}
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return messageDAO.GetMatchingList(ctx, filter, pageNumber, pageSize)
+}
+
+// DeleteMatchingMessages deletes the messages of a session matching the filter.
+func (ms *MemoryStore) DeleteMatchingMessages(
+	ctx context.Context,
+	sessionID string,
+	filter *models.MessageFilter,
+) (int, error) {
+	if err := filter.Validate(ms.maxMessageRegexComplexity()); err != nil {
+		return 0, err
+	}
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	return messageDAO.DeleteMatching(ctx, filter)
+}
+
+func (ms *MemoryStore) GetCompactionCandidates(
+	ctx context.Context,
+	sessionID string,
+	maxMessages int,
+) (*models.CompactionCandidates, error) {
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return messageDAO.GetCompactionCandidates(ctx, maxMessages)
+}
+
+func (ms *MemoryStore) CompactMessages(
+	ctx context.Context,
+	sessionID string,
+	throughUUID uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return err
+	}
+	return messageDAO.Compact(ctx, throughUUID, retainEmbeddings)
+}
+
+func (ms *MemoryStore) PruneMessages(
+	ctx context.Context,
+	sessionID string,
+	messageUUIDs []uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return err
+	}
+	return messageDAO.Prune(ctx, messageUUIDs, retainEmbeddings)
+}
+
+func (ms *MemoryStore) GetMessagesByUUID(
+	ctx context.Context,
+	sessionID string,
+	uuids []uuid.UUID,
+) ([]models.Message, error) {
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return messageDAO.GetListByUUID(ctx, uuids)
+}
+
+func (ms *MemoryStore) UpdateMessages(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+	isPrivileged bool,
+	includeContent bool,
+) error {
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return err
+	}
+	return messageDAO.UpdateMany(ctx, messages, includeContent, isPrivileged)
+}
+
+func (ms *MemoryStore) CreateMessageEmbeddings(
+	ctx context.Context,
+	sessionID string,
+	embeddings []models.TextData,
+) error {
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return err
+	}
+	return messageDAO.CreateEmbeddings(ctx, embeddings)
+}
+
+func (ms *MemoryStore) GetMessageEmbeddings(
+	ctx context.Context,
+	sessionID string,
+) ([]models.TextData, error) {
+	messageDAO, err := NewMessageDAO(ms.Client, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return messageDAO.GetEmbeddingListBySession(ctx)
+}
+
+func (ms *MemoryStore) GetSummary(ctx context.Context, sessionID string) (*models.Summary, error) {
+	summaryDAO, err := NewSummaryDAO(ms.Client, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return summaryDAO.Get(ctx)
+}
+
+func (ms *MemoryStore) GetSummaryByUUID(
+	ctx context.Context,
+	sessionID string,
+	uuid uuid.UUID,
+) (*models.Summary, error) {
+	summaryDAO, err := NewSummaryDAO(ms.Client, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return summaryDAO.GetByUUID(ctx, uuid)
+}
+
+func (ms *MemoryStore) GetSummaryList(
+	ctx context.Context,
+	sessionID string,
+	pageNumber int,
+	pageSize int,
+) (*models.SummaryListResponse, error) {
+	summaryDAO, err := NewSummaryDAO(ms.Client, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return summaryDAO.GetList(ctx, pageNumber, pageSize)
+}
+
+// CreateSummary stores a new Summary for a given sessionID, publishing it to the summary
+// embedder and NER tasks.
+func (ms *MemoryStore) CreateSummary(
+	ctx context.Context,
+	sessionID string,
+	summary *models.Summary,
+) error {
+	summaryDAO, err := NewSummaryDAO(ms.Client, sessionID)
+	if err != nil {
+		return err
+	}
+	created, err := summaryDAO.Create(ctx, summary)
+	if err != nil {
+		return fmt.Errorf("failed to create summary: %w", err)
+	}
+
+	publisher := ms.taskPublisher()
+	if publisher == nil {
+		return nil
+	}
+	task := models.MessageSummaryTask{UUID: created.UUID}
+	metadata := models.WithTenantMetadata(ctx, map[string]string{"session_id": sessionID})
+	for _, topic := range []models.TaskTopic{
+		models.MessageSummaryEmbedderTopic,
+		models.MessageSummaryNERTopic,
+	} {
+		if err := publisher.Publish(topic, metadata, task); err != nil {
+			return fmt.Errorf("MessageSummaryTask publish failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (ms *MemoryStore) UpdateSummary(
+	ctx context.Context,
+	sessionID string,
+	summary *models.Summary,
+	includeContent bool,
+) error {
+	summaryDAO, err := NewSummaryDAO(ms.Client, sessionID)
+	if err != nil {
+		return err
+	}
+	_, err = summaryDAO.Update(ctx, summary, includeContent)
+	return err
+}
+
+func (ms *MemoryStore) PutSummaryEmbedding(
+	ctx context.Context,
+	sessionID string,
+	embedding *models.TextData,
+) error {
+	summaryDAO, err := NewSummaryDAO(ms.Client, sessionID)
+	if err != nil {
+		return err
+	}
+	return summaryDAO.PutEmbedding(ctx, embedding)
+}
+
+// SearchMemory searches a session's messages or summaries. The in-memory store doesn't
+// embed the query. Instead, results are scored by the fraction of the query's terms they
+// contain, and returned in descending order of score, excluding results scoring less
+// than the query's MinScore. Literal searches score the results containing the text 1,
+// and return them most recent first. Metadata filters are not supported.
+func (ms *MemoryStore) SearchMemory(
+	_ context.Context,
+	sessionID string,
+	query *models.MemorySearchPayload,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	if query == nil || query.Text == "" {
+		return nil, models.NewValidationError("empty query")
+	}
+	if len(query.Metadata) > 0 {
+		return nil, models.NewValidationError("metadata filters are not supported by the in-memory store")
+	}
+	if err := query.ValidateSearchType(); err != nil {
+		return nil, err
+	}
+	if err := query.ValidateRecency(); err != nil {
+		return nil, err
+	}
+	// results are matched by term rather than embedded, so there's no query to diversify
+	// results against
+	if query.SearchType == models.SearchTypeMMR {
+		return nil, models.NewValidationError("mmr searches are not supported by the in-memory store")
+	}
+	var cursor *models.SearchCursor
+	if query.Cursor != "" {
+		if query.SearchType == models.SearchTypeHybrid ||
+			query.SearchType == models.SearchTypeLiteral {
+			return nil, models.NewValidationError("only similarity searches with text can be paged")
+		}
+		var err error
+		if cursor, err = models.DecodeSearchCursor(query.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	if limit == 0 {
+		limit = DefaultMemorySearchLimit
+	}
+
+	terms := strings.Fields(strings.ToLower(query.Text))
+	score := func(content string) float64 {
+		return termScore(content, terms)
+	}
+	literal := query.SearchType == models.SearchTypeLiteral
+	if literal {
+		text := strings.ToLower(query.Text)
+		score = func(content string) float64 {
+			if strings.Contains(strings.ToLower(content), text) {
+				return 1
+			}
+			return 0
+		}
+	}
+
+	ms.Client.mu.RLock()
+	defer ms.Client.mu.RUnlock()
+
+	var results []models.MemorySearchResult
+	switch query.SearchScope {
+	case models.SearchScopeMessages, "":
+		registry := ms.roles()
+		var matching map[string]bool
+		if len(query.Roles) > 0 {
+			matching = make(map[string]bool)
+			for _, role := range registry.Matching(query.Roles) {
+				matching[role] = true
+			}
+		}
+		for _, rec := range ms.Client.messages[sessionID] {
+			if !registry.Searchable(rec.message.Role) ||
+				(query.Episode != 0 && models.MessageEpisode(&rec.message) != query.Episode) ||
+				(matching != nil && !matching[strings.ToLower(rec.message.Role)]) {
+				continue
+			}
+			score := score(rec.message.Content)
+			if !rec.deleted && score > 0 && score >= query.MinScore {
+				message := copyMessage(&rec.message)
+				results = append(results, models.MemorySearchResult{
+					Message:     &message,
+					Metadata:    message.Metadata,
+					Dist:        score,
+					Embedding:   copyEmbedding(rec.embedding),
+					WindowUUIDs: slices.Clone(rec.window),
+				})
+			}
+		}
+	case models.SearchScopeSummary:
+		if query.Episode != 0 {
+			return nil, models.NewValidationError("episode can only filter message searches")
+		}
+		if len(query.Roles) > 0 {
+			return nil, models.NewValidationError("roles can only filter message searches")
+		}
+		for _, rec := range ms.Client.summaries[sessionID] {
+			score := score(rec.summary.Content)
+			if !rec.deleted && score > 0 && score >= query.MinScore {
+				summary := copySummary(&rec.summary)
+				results = append(results, models.MemorySearchResult{
+					Summary:   summary,
+					Metadata:  summary.Metadata,
+					Dist:      score,
+					Embedding: copyEmbedding(rec.embedding),
+				})
+			}
+		}
+	default:
+		return nil, models.NewValidationError("invalid search scope")
+	}
+
+	// messages are boosted by their importance, if weighted
+	var weight float64
+	if ms.appState != nil && ms.appState.Config != nil {
+		weight = ms.appState.Config.Memory.Importance.SearchWeight
+	}
+	boost := func(r *models.MemorySearchResult) float64 {
+		if r.Message == nil || weight <= 0 {
+			return 0
+		}
+		importance, _ := models.MessageImportance(r.Message)
+		return weight * importance
+	}
+	searchedAt := now()
+	recencyBoost := func(r *models.MemorySearchResult) float64 {
+		if r.Message == nil {
+			return 0
+		}
+		return query.RecencyBoost(r.Message.CreatedAt, searchedAt)
+	}
+	rank := func(r *models.MemorySearchResult) float64 {
+		return r.Dist + boost(r) + recencyBoost(r)
+	}
+	resultUUID := func(r *models.MemorySearchResult) uuid.UUID {
+		if r.Message != nil {
+			return r.Message.UUID
+		}
+		return r.Summary.UUID
+	}
+	for i := range results {
+		results[i].RankScore = rank(&results[i])
+	}
+	createdAt := func(r *models.MemorySearchResult) time.Time {
+		if r.Message != nil {
+			return r.Message.CreatedAt
+		}
+		return r.Summary.CreatedAt
+	}
+	// ties are ordered by UUID, so that results can be paged
+	sort.Slice(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+		if literal && !createdAt(a).Equal(createdAt(b)) {
+			return createdAt(a).After(createdAt(b))
+		}
+		if a.RankScore != b.RankScore {
+			return a.RankScore > b.RankScore
+		}
+		return models.SearchCursor{Score: a.RankScore, UUID: resultUUID(a)}.After(
+			b.RankScore,
+			resultUUID(b),
+		)
+	})
+	if cursor != nil {
+		results = slices.DeleteFunc(results, func(r models.MemorySearchResult) bool {
+			return !cursor.After(r.RankScore, resultUUID(&r))
+		})
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	if query.Explain {
+		for i := range results {
+			r := &results[i]
+			r.Explanation = &models.SearchExplanation{
+				VectorScore:     r.Dist,
+				ImportanceBoost: boost(r),
+				RecencyBoost:    recencyBoost(r),
+				Score:           rank(r),
+				Rank:            i + 1,
+			}
+		}
+	}
+	ms.loadResultWindows(sessionID, results)
+	if query.SearchType != models.SearchTypeHybrid && !literal {
+		for i := range results {
+			r := &results[i]
+			r.Cursor = models.SearchCursor{Score: r.RankScore, UUID: resultUUID(r)}.Encode()
+		}
+	}
+	if results == nil {
+		results = []models.MemorySearchResult{}
+	}
+
+	return results, nil
+}
+
+// SearchUserMemory searches each of the user's sessions, as the in-memory store has no
+// session embeddings, and keeps the results of the sessions with the best matches.
+func (ms *MemoryStore) SearchUserMemory(
+	ctx context.Context,
+	userID string,
+	query *models.MemorySearchPayload,
+	sessions int,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	if query == nil {
+		return nil, models.NewValidationError("empty query")
+	}
+	if err := query.ValidateUserSearch(); err != nil {
+		return nil, err
+	}
+	if sessions == 0 {
+		sessions = DefaultUserSearchSessions
+	}
+	if limit == 0 {
+		limit = DefaultMemorySearchLimit
+	}
+
+	ms.Client.mu.RLock()
+	var sessionIDs []string
+	for _, rec := range ms.Client.undeletedSessions() {
+		if stringValue(rec.session.UserID) == userID {
+			sessionIDs = append(sessionIDs, rec.session.SessionID)
+		}
+	}
+	ms.Client.mu.RUnlock()
+
+	// sessions are ranked by their best result, which is their first
+	var bySession [][]models.MemorySearchResult
+	for _, sessionID := range sessionIDs {
+		results, err := ms.SearchMemory(ctx, sessionID, query, limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			continue
+		}
+		for i := range results {
+			results[i].SessionID = sessionID
+			results[i].Cursor = ""
+		}
+		bySession = append(bySession, results)
+	}
+	sort.SliceStable(bySession, func(i, j int) bool {
+		return bySession[i][0].RankScore > bySession[j][0].RankScore
+	})
+	if len(bySession) > sessions {
+		bySession = bySession[:sessions]
+	}
+
+	results := []models.MemorySearchResult{}
+	for _, sessionResults := range bySession {
+		results = append(results, sessionResults...)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RankScore > results[j].RankScore
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	for i := range results {
+		if results[i].Explanation != nil {
+			results[i].Explanation.Rank = i + 1
+		}
+	}
+	return results, nil
+}
+
+// loadResultWindows sets the Window of each message result embedded with other messages.
+// The caller must hold the lock.
+func (ms *MemoryStore) loadResultWindows(sessionID string, results []models.MemorySearchResult) {
+	var byUUID map[uuid.UUID]*messageRecord
+	for i := range results {
+		r := &results[i]
+		if len(r.WindowUUIDs) == 0 {
+			continue
+		}
+		if byUUID == nil {
+			byUUID = make(map[uuid.UUID]*messageRecord)
+			for _, rec := range ms.Client.messages[sessionID] {
+				if !rec.deleted {
+					byUUID[rec.message.UUID] = rec
+				}
+			}
+		}
+		for _, u := range r.WindowUUIDs {
+			if rec, ok := byUUID[u]; ok {
+				r.Window = append(r.Window, copyMessage(&rec.message))
+			}
+		}
+	}
+}
+
+// PurgeDeleted hard deletes all soft-deleted records.
+func (ms *MemoryStore) PurgeDeleted(_ context.Context) error {
+	ms.Client.purgeDeleted()
+	return nil
+}
+
+// DeduplicateMessageEmbeddings is a no-op, as the in-memory store doesn't search
+// embeddings.
+func (ms *MemoryStore) DeduplicateMessageEmbeddings(
+	_ context.Context,
+	_ time.Time,
+	_ float32,
+	_ models.DeduplicationScope,
+) (int, error) {
+	return 0, nil
+}
+
+func (ms *MemoryStore) Close() error {
+	return nil
+}
+
+func (ms *MemoryStore) messageWindow() int {
+	if ms.appState != nil && ms.appState.Config != nil && ms.appState.Config.Memory.MessageWindow > 0 {
+		return ms.appState.Config.Memory.MessageWindow
+	}
+	return DefaultMessageWindow
+}
+
+func (ms *MemoryStore) roles() *roles.Registry {
+	if ms.appState == nil {
+		return nil
+	}
+	return roles.FromConfig(ms.appState.Config)
+}
+
+// maxMessageRegexComplexity returns memory.message_regex.max_complexity, or 0 if there's
+// no config, for the default.
+func (ms *MemoryStore) maxMessageRegexComplexity() int {
+	if ms.appState == nil || ms.appState.Config == nil {
+		return 0
+	}
+	return ms.appState.Config.Memory.MessageRegex.MaxComplexity
+}
+
+func (ms *MemoryStore) taskPublisher() models.TaskPublisher {
+	if ms.appState == nil {
+		return nil
+	}
+	return ms.appState.TaskPublisher
+}
+
+// termScore returns the fraction of terms contained in text.
+func termScore(text string, terms []string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	text = strings.ToLower(text)
+	var matched int
+	for _, term := range terms {
+		if strings.Contains(text, term) {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(terms))
+}
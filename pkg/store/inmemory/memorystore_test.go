@@ -0,0 +1,385 @@
+package inmemory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+type recordingPublisher struct {
+	messages []models.MessageTask
+	topics   []models.TaskTopic
+}
+
+func (p *recordingPublisher) Publish(taskType models.TaskTopic, _ map[string]string, _ any) error {
+	p.topics = append(p.topics, taskType)
+	return nil
+}
+
+func (p *recordingPublisher) PublishMessage(_ map[string]string, payload []models.MessageTask) error {
+	p.messages = append(p.messages, payload...)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+func TestMemoryStore(t *testing.T) {
+	publisher := &recordingPublisher{}
+	cfg := &config.Config{}
+	cfg.Memory.MessageWindow = 2
+	memoryStore := NewMemoryStore(&models.AppState{Config: cfg, TaskPublisher: publisher}, NewDB())
+
+	err := memoryStore.PutMemory(testCtx, "session", &models.Memory{
+		Messages: []models.Message{
+			{Role: "human", Content: "where should I travel in Europe?"},
+			{Role: "ai", Content: "Paris is lovely in the spring."},
+			{Role: "human", Content: "what about Rome?"},
+		},
+	}, false)
+	require.NoError(t, err)
+	assert.Len(t, publisher.messages, 3)
+
+	// PutMemory creates the session
+	_, err = memoryStore.GetSession(testCtx, "session")
+	require.NoError(t, err)
+
+	t.Run("get memory", func(t *testing.T) {
+		memory, err := memoryStore.GetMemory(testCtx, "session", 0)
+		require.NoError(t, err)
+		require.Len(t, memory.Messages, 2)
+		assert.Equal(t, "what about Rome?", memory.Messages[1].Content)
+
+		memory, err = memoryStore.GetMemory(testCtx, "session", 3)
+		require.NoError(t, err)
+		assert.Len(t, memory.Messages, 3)
+
+		_, err = memoryStore.GetMemory(testCtx, "session", -1)
+		assert.ErrorIs(t, err, models.ErrValidation)
+	})
+
+	t.Run("summaries", func(t *testing.T) {
+		memory, err := memoryStore.GetMemory(testCtx, "session", 0)
+		require.NoError(t, err)
+
+		err = memoryStore.CreateSummary(testCtx, "session", &models.Summary{
+			Content:          "the human is planning a trip",
+			SummaryPointUUID: memory.Messages[0].UUID,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []models.TaskTopic{
+			models.MessageSummaryEmbedderTopic,
+			models.MessageSummaryNERTopic,
+		}, publisher.topics)
+
+		memory, err = memoryStore.GetMemory(testCtx, "session", 0)
+		require.NoError(t, err)
+		assert.Equal(t, "the human is planning a trip", memory.Summary.Content)
+		require.Len(t, memory.Messages, 1)
+		assert.Equal(t, "what about Rome?", memory.Messages[0].Content)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		results, err := memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+			Text: "Paris spring",
+		}, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "Paris is lovely in the spring.", results[0].Message.Content)
+		assert.Equal(t, 1.0, results[0].Dist)
+
+		// messages of roles excluded from search aren't returned, matched by kind
+		cfg.Memory.Roles.ExcludeFromSearch = []string{"assistant"}
+		results, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+			Text: "Paris spring",
+		}, 0)
+		cfg.Memory.Roles.ExcludeFromSearch = nil
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		// searches can be restricted to an episode
+		memory, err := memoryStore.GetMemory(testCtx, "session", 3)
+		require.NoError(t, err)
+		err = memoryStore.UpdateMessages(testCtx, "session", []models.Message{{
+			UUID: memory.Messages[1].UUID,
+			Metadata: map[string]interface{}{
+				"system": map[string]interface{}{models.EpisodeMetadataKey: 2},
+			},
+		}}, true, false)
+		require.NoError(t, err)
+		for episode, want := range map[int]int{1: 0, 2: 1} {
+			results, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+				Text:    "Paris spring",
+				Episode: episode,
+			}, 0)
+			require.NoError(t, err)
+			assert.Len(t, results, want)
+		}
+
+		// messages are boosted by their importance, if weighted
+		err = memoryStore.UpdateMessages(testCtx, "session", []models.Message{{
+			UUID: memory.Messages[2].UUID,
+			Metadata: map[string]interface{}{
+				"system": map[string]interface{}{models.ImportanceMetadataKey: 1.0},
+			},
+		}}, true, false)
+		require.NoError(t, err)
+		// unweighted, the messages tie, and are ordered by UUID
+		for weight, want := range map[float64][]float64{0: {0.5, 0.5}, 0.1: {0.6, 0.5}} {
+			cfg.Memory.Importance.SearchWeight = weight
+			results, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+				Text: "paris rome",
+			}, 0)
+			require.NoError(t, err)
+			require.Len(t, results, 2)
+			assert.InDeltaSlice(t, want, []float64{results[0].RankScore, results[1].RankScore}, 1e-9)
+			if weight > 0 {
+				assert.Contains(t, results[0].Message.Content, "Rome")
+			}
+			assert.Nil(t, results[0].Explanation)
+		}
+
+		// results are paged from the cursor of the last result of the previous page
+		cfg.Memory.Importance.SearchWeight = 0
+		all, err := memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+			Text: "paris rome",
+		}, 0)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		var paged []models.MemorySearchResult
+		query := models.MemorySearchPayload{Text: "paris rome"}
+		for {
+			page, err := memoryStore.SearchMemory(testCtx, "session", &query, 1)
+			require.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			paged = append(paged, page...)
+			query.Cursor = page[len(page)-1].Cursor
+		}
+		assert.Equal(t, all, paged)
+		_, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+			Text:   "paris rome",
+			Cursor: "not a cursor",
+		}, 0)
+		assert.ErrorIs(t, err, models.ErrValidation)
+
+		// explanations break down the combined score of each result
+		cfg.Memory.Importance.SearchWeight = 0.1
+		results, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+			Text:    "paris rome",
+			Explain: true,
+		}, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, &models.SearchExplanation{
+			VectorScore:     0.5,
+			ImportanceBoost: 0.1,
+			Score:           0.6,
+			Rank:            1,
+		}, results[0].Explanation)
+		assert.Equal(t, 2, results[1].Explanation.Rank)
+		assert.Zero(t, results[1].Explanation.ImportanceBoost)
+		cfg.Memory.Importance.SearchWeight = 0
+
+		results, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+			Text:        "trip",
+			SearchScope: models.SearchScopeSummary,
+		}, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.NotNil(t, results[0].Summary)
+
+		// results are matched by term, so a hybrid search is a term search
+		results, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+			Text:       "paris rome",
+			SearchType: models.SearchTypeHybrid,
+		}, 0)
+		require.NoError(t, err)
+		assert.Len(t, results, 2)
+
+		// each message contains one of the two terms, scoring 0.5
+		for minScore, want := range map[float64]int{0.5: 2, 0.6: 0} {
+			results, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+				Text:     "paris rome",
+				MinScore: minScore,
+			}, 0)
+			require.NoError(t, err)
+			assert.Len(t, results, want, minScore)
+		}
+
+		_, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{}, 0)
+		assert.ErrorIs(t, err, models.ErrValidation)
+
+		for _, query := range []models.MemorySearchPayload{
+			{Text: "trip", SearchType: "semantic"},
+			{Text: "trip", SearchType: models.SearchTypeMMR, MMRLambda: 1.5},
+			// without an embedded query, results can't be diversified
+			{Text: "trip", SearchType: models.SearchTypeMMR, MMRLambda: 0.5},
+			{Text: "trip", SearchType: models.SearchTypeHybrid, SearchScope: models.SearchScopeSummary},
+		} {
+			_, err = memoryStore.SearchMemory(testCtx, "session", &query, 0)
+			assert.ErrorIs(t, err, models.ErrValidation, query)
+		}
+	})
+
+	t.Run("purge deleted", func(t *testing.T) {
+		require.NoError(t, memoryStore.DeleteSession(testCtx, "session"))
+		require.NoError(t, memoryStore.PurgeDeleted(testCtx))
+
+		// the session can be recreated once purged
+		_, err := memoryStore.CreateSession(testCtx, &models.CreateSessionRequest{SessionID: "session"})
+		require.NoError(t, err)
+		messages, err := memoryStore.GetMessagesByUUID(testCtx, "session", []uuid.UUID{uuid.New()})
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+	})
+}
+
+func TestSearchUserMemory(t *testing.T) {
+	db := NewDB()
+	memoryStore := NewMemoryStore(nil, db)
+	_, err := NewUserStore(db).Create(testCtx, &models.CreateUserRequest{UserID: "alice"})
+	require.NoError(t, err)
+
+	userID := "alice"
+	for sessionID, content := range map[string]string{
+		"flights": "my flight to paris",
+		"hotels":  "a hotel in paris near the river",
+		"recipes": "a recipe for soup",
+		"other":   "my flight to rome",
+	} {
+		request := &models.CreateSessionRequest{SessionID: sessionID}
+		if sessionID != "other" {
+			request.UserID = &userID
+		}
+		_, err := memoryStore.CreateSession(testCtx, request)
+		require.NoError(t, err)
+		err = memoryStore.PutMemory(testCtx, sessionID, &models.Memory{
+			Messages: []models.Message{{Role: "user", Content: content}},
+		}, true)
+		require.NoError(t, err)
+	}
+
+	results, err := memoryStore.SearchUserMemory(testCtx, "alice", &models.MemorySearchPayload{
+		Text: "flight paris",
+	}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "flights", results[0].SessionID)
+	assert.Equal(t, "hotels", results[1].SessionID)
+	assert.Empty(t, results[0].Cursor)
+
+	// only the session with the best match is searched
+	results, err = memoryStore.SearchUserMemory(testCtx, "alice", &models.MemorySearchPayload{
+		Text: "flight paris",
+	}, 1, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "flights", results[0].SessionID)
+
+	for _, query := range []models.MemorySearchPayload{
+		{},
+		{Text: "paris", SearchScope: models.SearchScopeSummary},
+		{Text: "paris", SearchType: models.SearchTypeHybrid},
+		{Text: "paris", Cursor: "cursor"},
+	} {
+		_, err = memoryStore.SearchUserMemory(testCtx, "alice", &query, 0, 0)
+		assert.ErrorIs(t, err, models.ErrValidation, query)
+	}
+}
+
+func TestSearchMemoryRecency(t *testing.T) {
+	db := NewDB()
+	memoryStore := NewMemoryStore(nil, db)
+	err := memoryStore.PutMemory(testCtx, "session", &models.Memory{
+		Messages: []models.Message{
+			{Role: "user", Content: "my flight to paris"},
+			{Role: "user", Content: "my hotel in paris"},
+		},
+	}, true)
+	require.NoError(t, err)
+	// the flight was booked two days ago
+	old := db.messages["session"][0].message
+	db.messages["session"][0].message.CreatedAt = old.CreatedAt.Add(-48 * time.Hour)
+
+	results, err := memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+		Text:          "paris",
+		RecencyWeight: 0.5,
+		Explain:       true,
+	}, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "my hotel in paris", results[0].Message.Content)
+	assert.InDelta(t, 1.5, results[0].RankScore, 1e-3)
+	// two half-lives of the default 24 hours have passed
+	assert.InDelta(t, 0.125, results[1].Explanation.RecencyBoost, 1e-3)
+	assert.InDelta(t, 1.125, results[1].Explanation.Score, 1e-3)
+
+	// with a longer half-life, the flight decays less
+	results, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+		Text:          "paris",
+		RecencyWeight: 0.5,
+		HalfLife:      48,
+	}, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.InDelta(t, 1.25, results[1].RankScore, 1e-3)
+
+	for _, query := range []models.MemorySearchPayload{
+		{Text: "paris", RecencyWeight: -1},
+		{Text: "paris", RecencyWeight: 0.5, HalfLife: -1},
+		{Text: "paris", RecencyWeight: 0.5, SearchScope: models.SearchScopeSummary},
+		{Text: "paris", RecencyWeight: 0.5, Cursor: results[0].Cursor},
+	} {
+		_, err = memoryStore.SearchMemory(testCtx, "session", &query, 0)
+		assert.ErrorIs(t, err, models.ErrValidation, query)
+	}
+}
+
+func TestSearchMemoryRoles(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Memory.Roles.Registry = []config.RoleConfig{{Name: "user", Aliases: []string{"human"}}}
+	memoryStore := NewMemoryStore(&models.AppState{Config: cfg}, NewDB())
+	err := memoryStore.PutMemory(testCtx, "session", &models.Memory{
+		Messages: []models.Message{
+			{Role: "human", Content: "book a flight to paris"},
+			{Role: "assistant", Content: "which day do you fly to paris?"},
+			{Role: "User", Content: "friday, to paris"},
+		},
+	}, true)
+	require.NoError(t, err)
+
+	results, err := memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+		Text:  "paris",
+		Roles: []string{"user"},
+	}, 0)
+	require.NoError(t, err)
+	// the message stored with the alias matches, too
+	assert.Len(t, results, 2)
+	for _, r := range results {
+		assert.NotEqual(t, "assistant", r.Message.Role)
+	}
+
+	results, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+		Text:  "paris",
+		Roles: []string{"ASSISTANT"},
+	}, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "assistant", results[0].Message.Role)
+
+	_, err = memoryStore.SearchMemory(testCtx, "session", &models.MemorySearchPayload{
+		Text:        "paris",
+		SearchScope: models.SearchScopeSummary,
+		Roles:       []string{"user"},
+	}, 0)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
@@ -0,0 +1,533 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.MessageDAO = &MessageDAO{}
+
+// MessageDAO is an in-memory implementation of models.MessageDAO.
+type MessageDAO struct {
+	db        *DB
+	sessionID string
+}
+
+// NewMessageDAO returns a MessageDAO for the messages of a session in db.
+func NewMessageDAO(db *DB, sessionID string) (*MessageDAO, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	if sessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	return &MessageDAO{db: db, sessionID: sessionID}, nil
+}
+
+// Create creates a new message for the session. The session must exist.
+func (dao *MessageDAO) Create(
+	ctx context.Context,
+	message *models.Message,
+) (*models.Message, error) {
+	messages, err := dao.CreateMany(ctx, []models.Message{*message})
+	if err != nil {
+		return nil, err
+	}
+	return &messages[0], nil
+}
+
+// CreateMany creates a batch of messages for the session. The session must exist.
+func (dao *MessageDAO) CreateMany(
+	_ context.Context,
+	messages []models.Message,
+) ([]models.Message, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	// Messages may be created for a soft-deleted session, as with the Postgres store.
+	if _, ok := dao.db.sessions[dao.sessionID]; !ok {
+		return nil, models.NewNotFoundError("session " + dao.sessionID)
+	}
+
+	createdAt := now()
+	created := make([]models.Message, len(messages))
+	for i, msg := range messages {
+		if msg.UUID == uuid.Nil {
+			msg.UUID = newUUID()
+		}
+		rec := &messageRecord{
+			id: dao.db.nextID(),
+			message: models.Message{
+				UUID:        msg.UUID,
+				CreatedAt:   createdAt,
+				UpdatedAt:   createdAt,
+				Role:        msg.Role,
+				Content:     msg.Content,
+				Metadata:    copyMetadata(msg.Metadata),
+				TokenCount:  msg.TokenCount,
+				ContentHash: models.ContentHash(msg.Content),
+			},
+		}
+		dao.db.messages[dao.sessionID] = append(dao.db.messages[dao.sessionID], rec)
+		created[i] = copyMessage(&rec.message)
+	}
+
+	return created, nil
+}
+
+// Get returns a message by its UUID.
+func (dao *MessageDAO) Get(_ context.Context, messageUUID uuid.UUID) (*models.Message, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	rec := dao.get(messageUUID)
+	if rec == nil {
+		return nil, models.NewNotFoundError("message " + messageUUID.String())
+	}
+	message := copyMessage(&rec.message)
+	return &message, nil
+}
+
+// GetLastN returns the last N messages for the session, in ascending order of creation.
+// If beforeUUID is not uuid.Nil, only messages up to and including beforeUUID are
+// considered.
+func (dao *MessageDAO) GetLastN(
+	_ context.Context,
+	lastNMessages int,
+	beforeUUID uuid.UUID,
+) ([]models.Message, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	var index int64
+	if beforeUUID != uuid.Nil {
+		index = dao.db.messageIndex(dao.sessionID, beforeUUID)
+	}
+
+	records := dao.undeleted(func(rec *messageRecord) bool {
+		return beforeUUID == uuid.Nil || rec.id <= index
+	})
+	if lastNMessages > 0 && len(records) > lastNMessages {
+		records = records[len(records)-lastNMessages:]
+	}
+
+	return copyMessages(records), nil
+}
+
+// GetSinceLastSummary returns up to memoryWindow messages since lastSummary's SummaryPoint,
+// in ascending order of creation.
+func (dao *MessageDAO) GetSinceLastSummary(
+	_ context.Context,
+	lastSummary *models.Summary,
+	memoryWindow int,
+) ([]models.Message, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	summaryPointUUID := uuid.Nil
+	if lastSummary != nil {
+		summaryPointUUID = lastSummary.SummaryPointUUID
+	}
+	index := dao.db.messageIndex(dao.sessionID, summaryPointUUID)
+
+	records := dao.undeleted(func(rec *messageRecord) bool { return rec.id > index })
+	if memoryWindow > 0 && len(records) > memoryWindow {
+		records = records[len(records)-memoryWindow:]
+	}
+
+	return copyMessages(records), nil
+}
+
+// GetListByUUID returns the messages with the given UUIDs, in order of creation.
+func (dao *MessageDAO) GetListByUUID(
+	_ context.Context,
+	messageUUIDs []uuid.UUID,
+) ([]models.Message, error) {
+	if len(messageUUIDs) == 0 {
+		return []models.Message{}, nil
+	}
+
+	uuids := make(map[uuid.UUID]bool, len(messageUUIDs))
+	for _, u := range messageUUIDs {
+		uuids[u] = true
+	}
+
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	records := dao.undeleted(func(rec *messageRecord) bool { return uuids[rec.message.UUID] })
+	return copyMessages(records), nil
+}
+
+// GetListBySession returns a page of the session's messages, oldest first.
+func (dao *MessageDAO) GetListBySession(
+	_ context.Context,
+	currentPage int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	records := dao.undeleted(nil)
+	messages := copyMessages(page(records, currentPage, pageSize))
+	if len(messages) == 0 {
+		return &models.MessageListResponse{Messages: []models.Message{}}, nil
+	}
+
+	return &models.MessageListResponse{
+		Messages:   messages,
+		TotalCount: len(records),
+		RowCount:   len(messages),
+	}, nil
+}
+
+// GetMatchingList retrieves a page of the session's messages whose content matches the
+// filter's regular expression, oldest first. The filter must have been validated.
+func (dao *MessageDAO) GetMatchingList(
+	_ context.Context,
+	filter *models.MessageFilter,
+	currentPage int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	re, err := regexp.Compile(filter.ContentRegex)
+	if err != nil {
+		return nil, models.NewValidationError("invalid content_regex: " + err.Error())
+	}
+
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	records := dao.undeleted(func(rec *messageRecord) bool {
+		return re.MatchString(rec.message.Content)
+	})
+	messages := copyMessages(page(records, currentPage, pageSize))
+	if len(messages) == 0 {
+		return &models.MessageListResponse{Messages: []models.Message{}}, nil
+	}
+
+	return &models.MessageListResponse{
+		Messages:   messages,
+		TotalCount: len(records),
+		RowCount:   len(messages),
+	}, nil
+}
+
+// DeleteMatching deletes the session's messages whose content matches the filter's
+// regular expression, and their embeddings, returning the number of messages deleted. The
+// filter must have been validated.
+func (dao *MessageDAO) DeleteMatching(
+	_ context.Context,
+	filter *models.MessageFilter,
+) (int, error) {
+	re, err := regexp.Compile(filter.ContentRegex)
+	if err != nil {
+		return 0, models.NewValidationError("invalid content_regex: " + err.Error())
+	}
+
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	if err := dao.checkSessionHold(); err != nil {
+		return 0, err
+	}
+
+	records := dao.undeleted(func(rec *messageRecord) bool {
+		return re.MatchString(rec.message.Content)
+	})
+	prune(records, false)
+
+	return len(records), nil
+}
+
+// Update updates a message by its UUID. Metadata is deep merged.
+func (dao *MessageDAO) Update(
+	ctx context.Context,
+	message *models.Message,
+	includeContent bool,
+	isPrivileged bool,
+) error {
+	return dao.UpdateMany(ctx, []models.Message{*message}, includeContent, isPrivileged)
+}
+
+// UpdateMany updates a batch of messages by their UUIDs. Metadata is deep merged. No
+// messages are updated if any of them don't exist.
+func (dao *MessageDAO) UpdateMany(
+	_ context.Context,
+	messages []models.Message,
+	includeContent bool,
+	isPrivileged bool,
+) error {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	records := make([]*messageRecord, len(messages))
+	for i, msg := range messages {
+		if msg.UUID == uuid.Nil {
+			return errors.New("message UUID cannot be nil")
+		}
+		records[i] = dao.get(msg.UUID)
+		if records[i] == nil {
+			return models.NewNotFoundError("message " + msg.UUID.String())
+		}
+	}
+
+	updatedAt := now()
+	for i, msg := range messages {
+		rec := records[i]
+		rec.message.TokenCount = msg.TokenCount
+		rec.message.UpdatedAt = updatedAt
+		if includeContent {
+			rec.message.Role = msg.Role
+			rec.message.Content = msg.Content
+			rec.message.ContentHash = models.ContentHash(msg.Content)
+		}
+		if msg.Metadata != nil {
+			rec.message.Metadata = mergeMetadata(rec.message.Metadata, msg.Metadata, isPrivileged)
+		}
+	}
+
+	return nil
+}
+
+// Delete soft deletes a message and its embedding.
+func (dao *MessageDAO) Delete(_ context.Context, messageUUID uuid.UUID) error {
+	if messageUUID == uuid.Nil {
+		return errors.New("message UUID cannot be nil")
+	}
+
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	rec := dao.get(messageUUID)
+	if rec == nil {
+		return models.NewNotFoundError("message " + messageUUID.String())
+	}
+	rec.deleted = true
+	rec.embedding = nil
+
+	return nil
+}
+
+// GetCompactionCandidates returns the oldest uncompacted messages in excess of maxMessages,
+// along with the subset of these that are more recent than the current SummaryPoint.
+func (dao *MessageDAO) GetCompactionCandidates(
+	_ context.Context,
+	maxMessages int,
+) (*models.CompactionCandidates, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	records := dao.undeleted(func(rec *messageRecord) bool { return rec.message.Content != "" })
+	if len(records) <= maxMessages {
+		return &models.CompactionCandidates{}, nil
+	}
+	records = records[:len(records)-maxMessages]
+
+	var summaryPointIndex int64
+	if summary := dao.db.latestSummary(dao.sessionID); summary != nil {
+		summaryPointIndex = dao.db.messageIndex(dao.sessionID, summary.summary.SummaryPointUUID)
+	}
+
+	var unsummarized []*messageRecord
+	for i := range records {
+		if records[i].id > summaryPointIndex {
+			unsummarized = records[i:]
+			break
+		}
+	}
+
+	return &models.CompactionCandidates{
+		Messages:     copyMessages(records),
+		Unsummarized: copyMessages(unsummarized),
+	}, nil
+}
+
+// Compact prunes the content of all messages up to and including throughUUID. If
+// retainEmbeddings is false, the messages and their embeddings are deleted. A session
+// under legal hold isn't compacted, and a ConflictError is returned.
+func (dao *MessageDAO) Compact(
+	_ context.Context,
+	throughUUID uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	if throughUUID == uuid.Nil {
+		return errors.New("message UUID cannot be nil")
+	}
+
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	index := dao.db.messageIndex(dao.sessionID, throughUUID)
+	if index == 0 {
+		return models.NewNotFoundError("message " + throughUUID.String())
+	}
+	if err := dao.checkSessionHold(); err != nil {
+		return err
+	}
+
+	records := dao.undeleted(func(rec *messageRecord) bool { return rec.id <= index })
+	prune(records, retainEmbeddings)
+
+	return nil
+}
+
+// Prune prunes the content of the given messages, as Compact does.
+func (dao *MessageDAO) Prune(
+	_ context.Context,
+	messageUUIDs []uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	if err := dao.checkSessionHold(); err != nil {
+		return err
+	}
+
+	uuids := make(map[uuid.UUID]bool, len(messageUUIDs))
+	for _, u := range messageUUIDs {
+		uuids[u] = true
+	}
+	records := dao.undeleted(func(rec *messageRecord) bool { return uuids[rec.message.UUID] })
+	prune(records, retainEmbeddings)
+
+	return nil
+}
+
+// checkSessionHold returns a ConflictError if the session is under legal hold, directly or
+// through its user. The caller must hold the lock.
+func (dao *MessageDAO) checkSessionHold() error {
+	if session := dao.db.getSession(dao.sessionID); session != nil &&
+		dao.db.sessionHeld(&session.session) {
+		return models.NewLegalHoldError(models.LegalHoldSubjectSession, dao.sessionID)
+	}
+	return nil
+}
+
+func prune(records []*messageRecord, retainEmbeddings bool) {
+	for _, rec := range records {
+		if retainEmbeddings {
+			rec.message.Content = ""
+			rec.message.ContentHash = models.ContentHash("")
+			rec.message.TokenCount = 0
+			rec.message.Metadata = mergeMetadata(
+				rec.message.Metadata,
+				models.CompactedMessageMetadata(),
+				true,
+			)
+			continue
+		}
+		rec.deleted = true
+		rec.embedding = nil
+	}
+}
+
+// CreateEmbeddings stores embeddings for the session's messages.
+func (dao *MessageDAO) CreateEmbeddings(_ context.Context, embeddings []models.TextData) error {
+	if len(embeddings) == 0 {
+		return errors.New("no embeddings received")
+	}
+
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	records := make([]*messageRecord, len(embeddings))
+	for i, e := range embeddings {
+		records[i] = dao.get(e.TextUUID)
+		if records[i] == nil {
+			return models.NewNotFoundError("message " + e.TextUUID.String())
+		}
+	}
+	for i, e := range embeddings {
+		records[i].embedding = copyEmbedding(e.Embedding)
+		records[i].window = slices.Clone(e.Window)
+	}
+
+	return nil
+}
+
+// GetEmbedding returns the embedding for a message.
+func (dao *MessageDAO) GetEmbedding(
+	_ context.Context,
+	messageUUID uuid.UUID,
+) (*models.TextData, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	rec := dao.get(messageUUID)
+	if rec == nil || rec.embedding == nil {
+		return nil, models.NewNotFoundError("embedding for message " + messageUUID.String())
+	}
+	return &models.TextData{
+		TextUUID:  rec.message.UUID,
+		Text:      rec.message.Content,
+		Embedding: copyEmbedding(rec.embedding),
+		Window:    slices.Clone(rec.window),
+	}, nil
+}
+
+// GetEmbeddingListBySession returns all message embeddings for the session.
+func (dao *MessageDAO) GetEmbeddingListBySession(_ context.Context) ([]models.TextData, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	records := dao.undeleted(func(rec *messageRecord) bool { return rec.embedding != nil })
+	embeddings := make([]models.TextData, len(records))
+	for i, rec := range records {
+		embeddings[i] = models.TextData{
+			TextUUID:  rec.message.UUID,
+			Text:      rec.message.Content,
+			Embedding: copyEmbedding(rec.embedding),
+			Window:    slices.Clone(rec.window),
+		}
+	}
+	return embeddings, nil
+}
+
+// get returns the undeleted message with the given UUID, or nil. The caller must hold
+// the lock.
+func (dao *MessageDAO) get(messageUUID uuid.UUID) *messageRecord {
+	for _, rec := range dao.db.messages[dao.sessionID] {
+		if rec.message.UUID == messageUUID && !rec.deleted {
+			return rec
+		}
+	}
+	return nil
+}
+
+// undeleted returns the session's undeleted messages for which keep returns true, in
+// order of creation. If keep is nil, all undeleted messages are returned. The caller
+// must hold the lock.
+func (dao *MessageDAO) undeleted(keep func(rec *messageRecord) bool) []*messageRecord {
+	var records []*messageRecord
+	for _, rec := range dao.db.messages[dao.sessionID] {
+		if !rec.deleted && (keep == nil || keep(rec)) {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+func copyMessage(message *models.Message) models.Message {
+	c := *message
+	c.Metadata = copyMetadata(message.Metadata)
+	return c
+}
+
+func copyMessages(records []*messageRecord) []models.Message {
+	messages := make([]models.Message, len(records))
+	for i, rec := range records {
+		messages[i] = copyMessage(&rec.message)
+	}
+	return messages
+}
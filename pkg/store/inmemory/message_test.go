@@ -0,0 +1,179 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func createTestMessages(t *testing.T, db *DB, sessionID string, n int) (*MessageDAO, []models.Message) {
+	t.Helper()
+
+	_, err := NewSessionDAO(db).Create(testCtx, &models.CreateSessionRequest{SessionID: sessionID})
+	require.NoError(t, err)
+
+	dao, err := NewMessageDAO(db, sessionID)
+	require.NoError(t, err)
+
+	messages := make([]models.Message, n)
+	for i := range messages {
+		messages[i] = models.Message{Role: "human", Content: "message", TokenCount: 1}
+	}
+	messages, err = dao.CreateMany(testCtx, messages)
+	require.NoError(t, err)
+
+	return dao, messages
+}
+
+func TestMessageDAO(t *testing.T) {
+	db := NewDB()
+	dao, messages := createTestMessages(t, db, "session", 5)
+
+	t.Run("session must exist", func(t *testing.T) {
+		missing, err := NewMessageDAO(db, "missing")
+		require.NoError(t, err)
+		_, err = missing.Create(testCtx, &models.Message{Content: "hello"})
+		assert.ErrorIs(t, err, models.ErrNotFound)
+	})
+
+	t.Run("get last n", func(t *testing.T) {
+		result, err := dao.GetLastN(testCtx, 2, uuid.Nil)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{messages[3].UUID, messages[4].UUID}, messageUUIDs(result))
+
+		result, err = dao.GetLastN(testCtx, 2, messages[2].UUID)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{messages[1].UUID, messages[2].UUID}, messageUUIDs(result))
+	})
+
+	t.Run("get since last summary", func(t *testing.T) {
+		result, err := dao.GetSinceLastSummary(
+			testCtx,
+			&models.Summary{SummaryPointUUID: messages[1].UUID},
+			2,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{messages[3].UUID, messages[4].UUID}, messageUUIDs(result))
+	})
+
+	t.Run("list by session", func(t *testing.T) {
+		list, err := dao.GetListBySession(testCtx, 2, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 5, list.TotalCount)
+		assert.Equal(t, []uuid.UUID{messages[2].UUID, messages[3].UUID}, messageUUIDs(list.Messages))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		err := dao.Update(testCtx, &models.Message{
+			UUID:       messages[0].UUID,
+			Role:       "ai",
+			Content:    "updated",
+			TokenCount: 2,
+			Metadata:   map[string]interface{}{"system": "ignored", "key": "value"},
+		}, true, false)
+		require.NoError(t, err)
+
+		message, err := dao.Get(testCtx, messages[0].UUID)
+		require.NoError(t, err)
+		assert.Equal(t, "updated", message.Content)
+		assert.Equal(t, 2, message.TokenCount)
+		assert.Equal(t, map[string]interface{}{"key": "value"}, message.Metadata)
+
+		err = dao.UpdateMany(testCtx, []models.Message{
+			{UUID: messages[0].UUID, Content: "not updated"},
+			{UUID: uuid.New()},
+		}, true, false)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		message, err = dao.Get(testCtx, messages[0].UUID)
+		require.NoError(t, err)
+		assert.Equal(t, "updated", message.Content)
+	})
+
+	t.Run("embeddings", func(t *testing.T) {
+		err := dao.CreateEmbeddings(testCtx, []models.TextData{
+			{TextUUID: messages[1].UUID, Embedding: []float32{0.1, 0.2}},
+		})
+		require.NoError(t, err)
+
+		embedding, err := dao.GetEmbedding(testCtx, messages[1].UUID)
+		require.NoError(t, err)
+		assert.Equal(t, []float32{0.1, 0.2}, embedding.Embedding)
+
+		_, err = dao.GetEmbedding(testCtx, messages[2].UUID)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+
+		embeddings, err := dao.GetEmbeddingListBySession(testCtx)
+		require.NoError(t, err)
+		assert.Len(t, embeddings, 1)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, dao.Delete(testCtx, messages[4].UUID))
+		_, err := dao.Get(testCtx, messages[4].UUID)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		assert.ErrorIs(t, dao.Delete(testCtx, messages[4].UUID), models.ErrNotFound)
+	})
+}
+
+func TestMessageDAO_Compaction(t *testing.T) {
+	db := NewDB()
+	dao, messages := createTestMessages(t, db, "session", 5)
+
+	summaryDAO, err := NewSummaryDAO(db, "session")
+	require.NoError(t, err)
+	_, err = summaryDAO.Create(testCtx, &models.Summary{SummaryPointUUID: messages[0].UUID})
+	require.NoError(t, err)
+
+	candidates, err := dao.GetCompactionCandidates(testCtx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, messageUUIDs(messages[:3]), messageUUIDs(candidates.Messages))
+	assert.Equal(t, messageUUIDs(messages[1:3]), messageUUIDs(candidates.Unsummarized))
+
+	require.NoError(t, dao.Compact(testCtx, messages[1].UUID, true))
+	candidates, err = dao.GetCompactionCandidates(testCtx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, messageUUIDs(messages[2:3]), messageUUIDs(candidates.Messages))
+
+	require.NoError(t, dao.Compact(testCtx, messages[2].UUID, false))
+	list, err := dao.GetListBySession(testCtx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, messageUUIDs(messages[3:]), messageUUIDs(list.Messages))
+
+	assert.ErrorIs(t, dao.Compact(testCtx, uuid.New(), false), models.ErrNotFound)
+}
+
+func TestMessageDAO_Prune(t *testing.T) {
+	db := NewDB()
+	dao, messages := createTestMessages(t, db, "session", 4)
+
+	require.NoError(t, dao.Prune(testCtx, []uuid.UUID{messages[0].UUID, messages[2].UUID}, true))
+	list, err := dao.GetListBySession(testCtx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, list.Messages, 4)
+	assert.Empty(t, list.Messages[0].Content)
+	assert.True(t, models.MessageCompacted(&list.Messages[0]))
+	assert.Equal(t, "message", list.Messages[1].Content)
+	assert.False(t, models.MessageCompacted(&list.Messages[1]))
+	assert.Empty(t, list.Messages[2].Content)
+
+	require.NoError(t, dao.Prune(testCtx, []uuid.UUID{messages[1].UUID}, false))
+	list, err = dao.GetListBySession(testCtx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]uuid.UUID{messages[0].UUID, messages[2].UUID, messages[3].UUID},
+		messageUUIDs(list.Messages),
+	)
+}
+
+func messageUUIDs(messages []models.Message) []uuid.UUID {
+	uuids := make([]uuid.UUID, len(messages))
+	for i := range messages {
+		uuids[i] = messages[i].UUID
+	}
+	return uuids
+}
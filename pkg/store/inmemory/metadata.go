@@ -0,0 +1,64 @@
+package inmemory
+
+// mergeMetadata deep merges patch into a copy of current, as the Postgres store's
+// jsonb_deep_merge does: maps are merged key by key, and any other value in patch
+// replaces the current value. If isPrivileged is false, the top-level `system` key of
+// patch is ignored.
+func mergeMetadata(
+	current map[string]interface{},
+	patch map[string]interface{},
+	isPrivileged bool,
+) map[string]interface{} {
+	merged := copyMetadata(current)
+	if merged == nil {
+		merged = make(map[string]interface{}, len(patch))
+	}
+	for k, v := range patch {
+		if k == "system" && !isPrivileged {
+			continue
+		}
+		currentMap, currentIsMap := merged[k].(map[string]interface{})
+		patchMap, patchIsMap := v.(map[string]interface{})
+		if currentIsMap && patchIsMap {
+			merged[k] = mergeMetadata(currentMap, patchMap, true)
+			continue
+		}
+		merged[k] = copyValue(v)
+	}
+	return merged
+}
+
+// copyMetadata returns a deep copy of metadata, so that callers can't modify stored
+// records.
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		c[k] = copyValue(v)
+	}
+	return c
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copyMetadata(v)
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i := range v {
+			c[i] = copyValue(v[i])
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+func copyEmbedding(embedding []float32) []float32 {
+	if embedding == nil {
+		return nil
+	}
+	return append([]float32(nil), embedding...)
+}
@@ -0,0 +1,83 @@
+package inmemory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.ProvenanceStore = &ProvenanceStore{}
+
+// ProvenanceStore is an in-memory implementation of models.ProvenanceStore.
+type ProvenanceStore struct {
+	db *DB
+}
+
+// NewProvenanceStore returns a ProvenanceStore storing records in db.
+func NewProvenanceStore(db *DB) *ProvenanceStore {
+	return &ProvenanceStore{db: db}
+}
+
+func (s *ProvenanceStore) Put(_ context.Context, provenance *models.ContextProvenance) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.provenance[provenance.ResponseID]; ok {
+		return models.NewConflictError("provenance of response " + provenance.ResponseID.String())
+	}
+	stored := copyProvenance(provenance)
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = now()
+	}
+	s.db.provenance[provenance.ResponseID] = stored
+	return nil
+}
+
+func (s *ProvenanceStore) Get(
+	_ context.Context,
+	responseID uuid.UUID,
+) (*models.ContextProvenance, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	provenance, ok := s.db.provenance[responseID]
+	if !ok {
+		return nil, models.NewNotFoundError("provenance of response " + responseID.String())
+	}
+	return copyProvenance(provenance), nil
+}
+
+func (s *ProvenanceStore) DeleteBefore(_ context.Context, before time.Time) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var n int
+	for responseID, provenance := range s.db.provenance {
+		if provenance.CreatedAt.Before(before) {
+			delete(s.db.provenance, responseID)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func copyProvenance(provenance *models.ContextProvenance) *models.ContextProvenance {
+	c := *provenance
+	c.Messages = copyUUIDs(provenance.Messages)
+	c.Summaries = copyUUIDs(provenance.Summaries)
+	c.Documents = copyUUIDs(provenance.Documents)
+	return &c
+}
+
+// copyUUIDs returns a copy of uuids, which is empty rather than nil, as are the UUIDs
+// stored by the Postgres store.
+func copyUUIDs(uuids []uuid.UUID) []uuid.UUID {
+	if uuids == nil {
+		return []uuid.UUID{}
+	}
+	return slices.Clone(uuids)
+}
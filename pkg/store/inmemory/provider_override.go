@@ -0,0 +1,89 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.ProviderOverrideStore = &ProviderOverrideStore{}
+
+// ProviderOverrideStore is an in-memory implementation of models.ProviderOverrideStore.
+// As records aren't persisted, API keys aren't encrypted.
+type ProviderOverrideStore struct {
+	db *DB
+}
+
+// NewProviderOverrideStore returns a ProviderOverrideStore storing overrides in db.
+func NewProviderOverrideStore(db *DB) *ProviderOverrideStore {
+	return &ProviderOverrideStore{db: db}
+}
+
+func (s *ProviderOverrideStore) Put(
+	_ context.Context,
+	override *models.ProviderOverride,
+) (*models.ProviderOverride, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored := copyProviderOverride(override)
+	stored.UpdatedAt = now()
+	if existing, ok := s.db.providerOverrides[override.Tenant]; ok {
+		stored.CreatedAt = existing.CreatedAt
+	} else {
+		stored.CreatedAt = stored.UpdatedAt
+	}
+	s.db.providerOverrides[override.Tenant] = stored
+	return copyProviderOverride(stored), nil
+}
+
+func (s *ProviderOverrideStore) Get(
+	_ context.Context,
+	tenant string,
+) (*models.ProviderOverride, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	override, ok := s.db.providerOverrides[tenant]
+	if !ok {
+		return nil, models.NewNotFoundError("provider override of tenant " + tenant)
+	}
+	return copyProviderOverride(override), nil
+}
+
+func (s *ProviderOverrideStore) List(_ context.Context) ([]*models.ProviderOverride, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	overrides := make([]*models.ProviderOverride, 0, len(s.db.providerOverrides))
+	for _, override := range s.db.providerOverrides {
+		overrides = append(overrides, copyProviderOverride(override))
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Tenant < overrides[j].Tenant })
+	return overrides, nil
+}
+
+func (s *ProviderOverrideStore) Delete(_ context.Context, tenant string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.providerOverrides[tenant]; !ok {
+		return models.NewNotFoundError("provider override of tenant " + tenant)
+	}
+	delete(s.db.providerOverrides, tenant)
+	return nil
+}
+
+func copyProviderOverride(override *models.ProviderOverride) *models.ProviderOverride {
+	c := *override
+	if override.LLM != nil {
+		llm := *override.LLM
+		c.LLM = &llm
+	}
+	if override.Embeddings != nil {
+		embeddings := *override.Embeddings
+		c.Embeddings = &embeddings
+	}
+	return &c
+}
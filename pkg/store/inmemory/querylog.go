@@ -0,0 +1,142 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// defaultTopQueries is the number of top queries returned if the filter has no limit.
+const defaultTopQueries = 10
+
+var _ models.QueryLogStore = &QueryLogStore{}
+
+// QueryLogStore is an in-memory implementation of models.QueryLogStore.
+type QueryLogStore struct {
+	db *DB
+}
+
+// NewQueryLogStore returns a QueryLogStore storing searches in db.
+func NewQueryLogStore(db *DB) *QueryLogStore {
+	return &QueryLogStore{db: db}
+}
+
+func (s *QueryLogStore) Put(_ context.Context, search *models.LoggedSearch) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored := *search
+	if stored.ID == uuid.Nil {
+		stored.ID = uuid.New()
+	}
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = now()
+	}
+	s.db.searches = append(s.db.searches, &stored)
+	return nil
+}
+
+func (s *QueryLogStore) Analytics(
+	_ context.Context,
+	filter *models.SearchAnalyticsFilter,
+) (*models.SearchAnalytics, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	analytics := &models.SearchAnalytics{}
+	var latency int64
+	var counts []*models.QueryCount
+	byText := make(map[string]*models.QueryCount)
+	results := make(map[string]int)
+	for _, search := range s.db.searches {
+		if filter.Kind != "" && search.Kind != filter.Kind ||
+			filter.CollectionName != "" && search.CollectionName != filter.CollectionName ||
+			search.CreatedAt.Before(filter.Since) {
+			continue
+		}
+		analytics.Searches++
+		latency += search.LatencyMS
+		if search.ZeroResults {
+			analytics.ZeroResults++
+		}
+
+		text := strings.ToLower(strings.TrimSpace(search.Text))
+		if text == "" {
+			continue
+		}
+		count, ok := byText[text]
+		if !ok {
+			count = &models.QueryCount{Text: text}
+			byText[text] = count
+			counts = append(counts, count)
+		}
+		count.Searches++
+		if search.ZeroResults {
+			count.ZeroResults++
+		}
+		results[text] += search.ResultCount
+	}
+	if analytics.Searches > 0 {
+		analytics.ZeroResultRate = float64(analytics.ZeroResults) / float64(analytics.Searches)
+		analytics.AvgLatencyMS = float64(latency) / float64(analytics.Searches)
+	}
+	for _, count := range counts {
+		count.AvgResults = float64(results[count.Text]) / float64(count.Searches)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultTopQueries
+	}
+	analytics.TopQueries = topQueries(counts, limit, false)
+	analytics.TopZeroResultQueries = topQueries(counts, limit, true)
+	return analytics, nil
+}
+
+func (s *QueryLogStore) DeleteBefore(_ context.Context, before time.Time) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	kept := s.db.searches[:0]
+	for _, search := range s.db.searches {
+		if !search.CreatedAt.Before(before) {
+			kept = append(kept, search)
+		}
+	}
+	n := len(s.db.searches) - len(kept)
+	s.db.searches = kept
+	return n, nil
+}
+
+// topQueries returns up to limit of the query counts, ordered by their searches, then by
+// text, as the Postgres store orders them. If zeroResults is set, only the searches
+// returning no results are counted.
+func topQueries(counts []*models.QueryCount, limit int, zeroResults bool) []models.QueryCount {
+	top := []models.QueryCount{}
+	for _, c := range counts {
+		count := *c
+		if zeroResults {
+			if c.ZeroResults == 0 {
+				continue
+			}
+			count.Searches = c.ZeroResults
+			count.AvgResults = 0
+		}
+		top = append(top, count)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Searches != top[j].Searches {
+			return top[i].Searches > top[j].Searches
+		}
+		return top[i].Text < top[j].Text
+	})
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
@@ -0,0 +1,397 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.SessionDAO = &SessionDAO{}
+
+// SessionDAO is an in-memory implementation of models.SessionDAO.
+type SessionDAO struct {
+	db *DB
+}
+
+// NewSessionDAO returns a SessionDAO for the sessions in db.
+func NewSessionDAO(db *DB) *SessionDAO {
+	return &SessionDAO{db: db}
+}
+
+// Create creates a new session. Returns a models.ConflictError if the session exists.
+func (dao *SessionDAO) Create(
+	_ context.Context,
+	session *models.CreateSessionRequest,
+) (*models.Session, error) {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	return dao.create(session)
+}
+
+// CreateBatch creates sessions under a single lock. A session that can't be created is
+// reported in its result.
+func (dao *SessionDAO) CreateBatch(
+	_ context.Context,
+	sessions []models.CreateSessionRequest,
+) (*models.CreateSessionsResponse, error) {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	response := &models.CreateSessionsResponse{
+		Results: make([]models.CreateSessionsResult, len(sessions)),
+	}
+	for i := range sessions {
+		result := &response.Results[i]
+		result.Index = i
+		result.SessionID = sessions[i].SessionID
+		session, err := dao.create(&sessions[i])
+		if err != nil {
+			result.Error = err.Error()
+			response.Failed++
+			continue
+		}
+		result.Session = session
+		response.Succeeded++
+	}
+	return response, nil
+}
+
+// create creates a session. The caller must hold the write lock.
+func (dao *SessionDAO) create(session *models.CreateSessionRequest) (*models.Session, error) {
+	if session.SessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	if session.UserID != nil {
+		if _, ok := dao.db.users[*session.UserID]; !ok {
+			return nil, models.NewValidationError(
+				"user does not exist with user_id: " + *session.UserID,
+			)
+		}
+	}
+	if _, ok := dao.db.sessions[session.SessionID]; ok {
+		return nil, models.NewConflictError(
+			"session already exists with session_id: " + session.SessionID,
+		)
+	}
+
+	createdAt := now()
+	rec := &sessionRecord{
+		session: models.Session{
+			UUID:      newUUID(),
+			ID:        dao.db.nextID(),
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+			SessionID: session.SessionID,
+			Metadata:  copyMetadata(session.Metadata),
+			UserID:    copyString(session.UserID),
+			ExpiresAt: copyTime(session.ExpiresAt),
+		},
+	}
+	dao.db.sessions[session.SessionID] = rec
+
+	return copySession(&rec.session), nil
+}
+
+// Get returns an undeleted session.
+func (dao *SessionDAO) Get(_ context.Context, sessionID string) (*models.Session, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	rec := dao.db.getSession(sessionID)
+	if rec == nil {
+		return nil, models.NewNotFoundError("session " + sessionID)
+	}
+	return copySession(&rec.session), nil
+}
+
+// Update updates a session's expiry and merges its metadata. As with the Postgres store,
+// a soft-deleted session is undeleted, but its messages and summaries are not.
+func (dao *SessionDAO) Update(
+	_ context.Context,
+	session *models.UpdateSessionRequest,
+	isPrivileged bool,
+) (*models.Session, error) {
+	if session.SessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	rec, ok := dao.db.sessions[session.SessionID]
+	if !ok {
+		return nil, models.NewNotFoundError("session " + session.SessionID)
+	}
+
+	rec.session.DeletedAt = nil
+	rec.session.UpdatedAt = now()
+	if session.ExpiresAt != nil {
+		rec.session.ExpiresAt = copyTime(session.ExpiresAt)
+		rec.expiryWarnedAt = nil
+	}
+	if session.Metadata != nil {
+		rec.session.Metadata = mergeMetadata(rec.session.Metadata, session.Metadata, isPrivileged)
+	}
+
+	return copySession(&rec.session), nil
+}
+
+// PatchMetadata applies patch to the metadata of an undeleted session.
+func (dao *SessionDAO) PatchMetadata(
+	_ context.Context,
+	sessionID string,
+	patch models.MetadataPatch,
+	isPrivileged bool,
+) (*models.Session, error) {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	rec := dao.db.getSession(sessionID)
+	if rec == nil {
+		return nil, models.NewNotFoundError("session " + sessionID)
+	}
+	metadata, err := models.PatchMetadata(rec.session.Metadata, patch, isPrivileged)
+	if err != nil {
+		return nil, err
+	}
+	rec.session.Metadata = metadata
+	rec.session.UpdatedAt = now()
+
+	return copySession(&rec.session), nil
+}
+
+// UpdateTitle sets the title of an undeleted session.
+func (dao *SessionDAO) UpdateTitle(_ context.Context, sessionID string, title string) error {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	rec := dao.db.getSession(sessionID)
+	if rec == nil {
+		return models.NewNotFoundError("session " + sessionID)
+	}
+	rec.session.Title = title
+
+	return nil
+}
+
+// UpdateTaskState replaces the task state of an undeleted session.
+func (dao *SessionDAO) UpdateTaskState(
+	_ context.Context,
+	sessionID string,
+	state *models.TaskState,
+) error {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	rec := dao.db.getSession(sessionID)
+	if rec == nil {
+		return models.NewNotFoundError("session " + sessionID)
+	}
+	rec.session.TaskState = copyTaskState(state)
+
+	return nil
+}
+
+// UpdateEpisodes replaces the episodes of an undeleted session.
+func (dao *SessionDAO) UpdateEpisodes(
+	_ context.Context,
+	sessionID string,
+	episodes []models.Episode,
+) error {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	rec := dao.db.getSession(sessionID)
+	if rec == nil {
+		return models.NewNotFoundError("session " + sessionID)
+	}
+	rec.session.Episodes = copyEpisodes(episodes)
+
+	return nil
+}
+
+// Delete soft deletes a session and its messages, message embeddings, and summaries.
+// Returns a ConflictError if the session or its user is under legal hold.
+func (dao *SessionDAO) Delete(_ context.Context, sessionID string) error {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	rec := dao.db.getSession(sessionID)
+	if rec == nil {
+		return models.NewNotFoundError("session " + sessionID)
+	}
+	if dao.db.sessionHeld(&rec.session) {
+		return models.NewLegalHoldError(models.LegalHoldSubjectSession, sessionID)
+	}
+	dao.db.deleteSession(rec, now())
+
+	return nil
+}
+
+// ListAll returns up to limit undeleted sessions with an ID greater than cursor.
+func (dao *SessionDAO) ListAll(
+	_ context.Context,
+	cursor int64,
+	limit int,
+) ([]*models.Session, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	var sessions []*models.Session
+	for _, rec := range dao.db.undeletedSessions() {
+		if rec.session.ID > cursor {
+			sessions = append(sessions, copySession(&rec.session))
+		}
+	}
+	return page(sessions, 1, limit), nil
+}
+
+// ListAllOrdered returns a page of undeleted sessions ordered by orderBy, which is one of
+// id, created_at, updated_at, session_id, or user_id.
+func (dao *SessionDAO) ListAllOrdered(
+	_ context.Context,
+	pageNumber int,
+	pageSize int,
+	orderBy string,
+	asc bool,
+) (*models.SessionListResponse, error) {
+	less, err := sessionOrder(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	records := dao.db.undeletedSessions()
+	sessions := make([]*models.Session, len(records))
+	for i, rec := range records {
+		sessions[i] = copySession(&rec.session)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if asc {
+			return less(sessions[i], sessions[j])
+		}
+		return less(sessions[j], sessions[i])
+	})
+
+	sessions = page(sessions, pageNumber, pageSize)
+	return &models.SessionListResponse{
+		Sessions:   sessions,
+		TotalCount: len(records),
+		RowCount:   len(sessions),
+	}, nil
+}
+
+// ListExpiring returns undeleted sessions expiring before the given time that have not
+// yet had an expiry warning sent.
+func (dao *SessionDAO) ListExpiring(
+	_ context.Context,
+	before time.Time,
+) ([]*models.Session, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	var sessions []*models.Session
+	for _, rec := range dao.db.undeletedSessions() {
+		expiresAt := rec.session.ExpiresAt
+		if expiresAt != nil && !expiresAt.After(before) && rec.expiryWarnedAt == nil &&
+			!dao.db.sessionHeld(&rec.session) {
+			sessions = append(sessions, copySession(&rec.session))
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].ExpiresAt.Before(*sessions[j].ExpiresAt)
+	})
+	return sessions, nil
+}
+
+// MarkExpiryWarned records that an expiry warning has been sent for the given sessionIDs.
+func (dao *SessionDAO) MarkExpiryWarned(_ context.Context, sessionIDs []string) error {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	warnedAt := now()
+	for _, sessionID := range sessionIDs {
+		if rec := dao.db.getSession(sessionID); rec != nil {
+			rec.expiryWarnedAt = &warnedAt
+		}
+	}
+	return nil
+}
+
+// DeleteExpired soft deletes all sessions whose expiry has passed, along with their
+// messages, message embeddings, and summaries, skipping sessions under legal hold. Returns
+// the deleted sessions.
+func (dao *SessionDAO) DeleteExpired(_ context.Context) ([]*models.Session, error) {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	deletedAt := now()
+	var sessions []*models.Session
+	for _, rec := range dao.db.undeletedSessions() {
+		if rec.session.ExpiresAt != nil && !rec.session.ExpiresAt.After(deletedAt) &&
+			!dao.db.sessionHeld(&rec.session) {
+			dao.db.deleteSession(rec, deletedAt)
+			sessions = append(sessions, copySession(&rec.session))
+		}
+	}
+	return sessions, nil
+}
+
+// sessionOrder returns a less function for the given ListAllOrdered column.
+func sessionOrder(orderBy string) (func(a, b *models.Session) bool, error) {
+	switch orderBy {
+	case "", "id":
+		return func(a, b *models.Session) bool { return a.ID < b.ID }, nil
+	case "created_at":
+		return func(a, b *models.Session) bool { return a.CreatedAt.Before(b.CreatedAt) }, nil
+	case "updated_at":
+		return func(a, b *models.Session) bool { return a.UpdatedAt.Before(b.UpdatedAt) }, nil
+	case "session_id":
+		return func(a, b *models.Session) bool { return a.SessionID < b.SessionID }, nil
+	case "user_id":
+		return func(a, b *models.Session) bool {
+			return strings.Compare(stringValue(a.UserID), stringValue(b.UserID)) < 0
+		}, nil
+	default:
+		return nil, models.NewValidationError("invalid order by column: " + orderBy)
+	}
+}
+
+func copySession(session *models.Session) *models.Session {
+	c := *session
+	c.Metadata = copyMetadata(session.Metadata)
+	c.UserID = copyString(session.UserID)
+	c.ExpiresAt = copyTime(session.ExpiresAt)
+	c.DeletedAt = copyTime(session.DeletedAt)
+	c.TaskState = copyTaskState(session.TaskState)
+	c.Episodes = copyEpisodes(session.Episodes)
+	return &c
+}
+
+func copyEpisodes(episodes []models.Episode) []models.Episode {
+	if episodes == nil {
+		return nil
+	}
+	c := make([]models.Episode, len(episodes))
+	for i, e := range episodes {
+		c[i] = e
+		c[i].EndedAt = copyTime(e.EndedAt)
+	}
+	return c
+}
+
+func copyTaskState(state *models.TaskState) *models.TaskState {
+	if state == nil {
+		return nil
+	}
+	c := *state
+	c.StepsDone = append([]string(nil), state.StepsDone...)
+	c.Blockers = append([]string(nil), state.Blockers...)
+	return &c
+}
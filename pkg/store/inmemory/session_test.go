@@ -0,0 +1,184 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var testCtx = context.Background()
+
+func TestSessionDAO(t *testing.T) {
+	dao := NewSessionDAO(NewDB())
+
+	created, err := dao.Create(testCtx, &models.CreateSessionRequest{
+		SessionID: "session",
+		Metadata:  map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, int64(0), created.ID)
+
+	t.Run("create validates", func(t *testing.T) {
+		_, err := dao.Create(testCtx, &models.CreateSessionRequest{})
+		assert.ErrorIs(t, err, models.ErrValidation)
+
+		_, err = dao.Create(testCtx, &models.CreateSessionRequest{SessionID: "session"})
+		assert.ErrorIs(t, err, models.ErrConflict)
+
+		userID := "missing"
+		_, err = dao.Create(testCtx, &models.CreateSessionRequest{SessionID: "other", UserID: &userID})
+		assert.ErrorIs(t, err, models.ErrValidation)
+	})
+
+	t.Run("update merges metadata", func(t *testing.T) {
+		updated, err := dao.Update(testCtx, &models.UpdateSessionRequest{
+			SessionID: "session",
+			Metadata: map[string]interface{}{
+				"a":      map[string]interface{}{"c": 2},
+				"system": "ignored",
+			},
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"a": map[string]interface{}{"b": 1, "c": 2},
+		}, updated.Metadata)
+
+		// callers can't modify the stored session
+		updated.Metadata["a"] = "modified"
+		session, err := dao.Get(testCtx, "session")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"b": 1, "c": 2}, session.Metadata["a"])
+	})
+
+	t.Run("update title", func(t *testing.T) {
+		require.NoError(t, dao.UpdateTitle(testCtx, "session", "Planning a trip"))
+		session, err := dao.Get(testCtx, "session")
+		require.NoError(t, err)
+		assert.Equal(t, "Planning a trip", session.Title)
+
+		assert.ErrorIs(t, dao.UpdateTitle(testCtx, "missing", "title"), models.ErrNotFound)
+	})
+
+	t.Run("update task state", func(t *testing.T) {
+		state := &models.TaskState{Goal: "Book a flight", StepsDone: []string{"chose dates"}}
+		require.NoError(t, dao.UpdateTaskState(testCtx, "session", state))
+
+		// callers can't modify the stored task state
+		state.StepsDone[0] = "modified"
+		session, err := dao.Get(testCtx, "session")
+		require.NoError(t, err)
+		assert.Equal(t, "Book a flight", session.TaskState.Goal)
+		assert.Equal(t, []string{"chose dates"}, session.TaskState.StepsDone)
+
+		err = dao.UpdateTaskState(testCtx, "missing", state)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+	})
+
+	t.Run("update episodes", func(t *testing.T) {
+		endedAt := time.Now()
+		episodes := []models.Episode{
+			{ID: 1, Topic: "flights", MessageCount: 2, EndedAt: &endedAt},
+			{ID: 2, Topic: "hotels", MessageCount: 1},
+		}
+		require.NoError(t, dao.UpdateEpisodes(testCtx, "session", episodes))
+
+		// callers can't modify the stored episodes
+		episodes[0].Topic = "modified"
+		session, err := dao.Get(testCtx, "session")
+		require.NoError(t, err)
+		require.Len(t, session.Episodes, 2)
+		assert.Equal(t, "flights", session.Episodes[0].Topic)
+		assert.Nil(t, session.Episodes[1].EndedAt)
+
+		err = dao.UpdateEpisodes(testCtx, "missing", episodes)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+	})
+
+	t.Run("delete and undelete", func(t *testing.T) {
+		require.NoError(t, dao.Delete(testCtx, "session"))
+
+		_, err := dao.Get(testCtx, "session")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		assert.ErrorIs(t, dao.Delete(testCtx, "session"), models.ErrNotFound)
+
+		_, err = dao.Update(testCtx, &models.UpdateSessionRequest{SessionID: "session"}, false)
+		require.NoError(t, err)
+		_, err = dao.Get(testCtx, "session")
+		assert.NoError(t, err)
+	})
+
+	t.Run("update not found", func(t *testing.T) {
+		_, err := dao.Update(testCtx, &models.UpdateSessionRequest{SessionID: "missing"}, false)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+	})
+}
+
+func TestSessionDAO_List(t *testing.T) {
+	dao := NewSessionDAO(NewDB())
+	for _, id := range []string{"b", "c", "a"} {
+		_, err := dao.Create(testCtx, &models.CreateSessionRequest{SessionID: id})
+		require.NoError(t, err)
+	}
+
+	sessions, err := dao.ListAll(testCtx, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "b", sessions[0].SessionID)
+
+	sessions, err = dao.ListAll(testCtx, sessions[1].ID, 2)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "a", sessions[0].SessionID)
+
+	list, err := dao.ListAllOrdered(testCtx, 1, 2, "session_id", false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, list.TotalCount)
+	assert.Equal(t, 2, list.RowCount)
+	assert.Equal(t, "c", list.Sessions[0].SessionID)
+	assert.Equal(t, "b", list.Sessions[1].SessionID)
+
+	list, err = dao.ListAllOrdered(testCtx, 2, 2, "session_id", false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.RowCount)
+	assert.Equal(t, "a", list.Sessions[0].SessionID)
+
+	_, err = dao.ListAllOrdered(testCtx, 1, 2, "content; DROP TABLE", true)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func TestSessionDAO_Expiry(t *testing.T) {
+	db := NewDB()
+	dao := NewSessionDAO(db)
+
+	expired := time.Now().Add(-time.Minute)
+	expiring := time.Now().Add(time.Minute)
+	_, err := dao.Create(testCtx, &models.CreateSessionRequest{SessionID: "expired", ExpiresAt: &expired})
+	require.NoError(t, err)
+	_, err = dao.Create(testCtx, &models.CreateSessionRequest{SessionID: "expiring", ExpiresAt: &expiring})
+	require.NoError(t, err)
+	_, err = dao.Create(testCtx, &models.CreateSessionRequest{SessionID: "forever"})
+	require.NoError(t, err)
+
+	sessions, err := dao.ListExpiring(testCtx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "expired", sessions[0].SessionID)
+
+	require.NoError(t, dao.MarkExpiryWarned(testCtx, []string{"expiring"}))
+	sessions, err = dao.ListExpiring(testCtx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+
+	deleted, err := dao.DeleteExpired(testCtx)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, "expired", deleted[0].SessionID)
+
+	_, err = dao.Get(testCtx, "expired")
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
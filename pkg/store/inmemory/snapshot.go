@@ -0,0 +1,124 @@
+package inmemory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.MemorySnapshotStore = &MemorySnapshotStore{}
+
+// MemorySnapshotStore is an in-memory implementation of models.MemorySnapshotStore.
+// Snapshots are deleted with their session.
+type MemorySnapshotStore struct {
+	db *DB
+}
+
+// NewMemorySnapshotStore returns a MemorySnapshotStore for the sessions in db.
+func NewMemorySnapshotStore(db *DB) *MemorySnapshotStore {
+	return &MemorySnapshotStore{db: db}
+}
+
+// Put stores a snapshot, replacing any snapshot of the same turn.
+func (s *MemorySnapshotStore) Put(
+	_ context.Context,
+	snapshot *models.MemorySnapshot,
+) (*models.MemorySnapshot, error) {
+	if snapshot.TurnUUID == uuid.Nil {
+		return nil, models.NewValidationError("turn UUID cannot be empty")
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.getSession(snapshot.SessionID) == nil {
+		return nil, models.NewNotFoundError("session " + snapshot.SessionID)
+	}
+
+	stored := &snapshotRecord{snapshot: models.MemorySnapshot{
+		UUID:      newUUID(),
+		CreatedAt: now(),
+		SessionID: snapshot.SessionID,
+		TurnUUID:  snapshot.TurnUUID,
+		Memory:    copyMemory(snapshot.Memory),
+	}}
+	snapshots := s.db.snapshots[snapshot.SessionID]
+	for i, rec := range snapshots {
+		if !rec.deleted && rec.snapshot.TurnUUID == snapshot.TurnUUID {
+			// the replacement keeps the UUID, but is ordered as a new snapshot
+			stored.snapshot.UUID = rec.snapshot.UUID
+			snapshots = append(snapshots[:i], snapshots[i+1:]...)
+			break
+		}
+	}
+	s.db.snapshots[snapshot.SessionID] = append(snapshots, stored)
+
+	return copySnapshot(&stored.snapshot), nil
+}
+
+// Get returns the snapshot of a session's turn.
+func (s *MemorySnapshotStore) Get(
+	_ context.Context,
+	sessionID string,
+	turnUUID uuid.UUID,
+) (*models.MemorySnapshot, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, rec := range s.db.snapshots[sessionID] {
+		if !rec.deleted && rec.snapshot.TurnUUID == turnUUID {
+			return copySnapshot(&rec.snapshot), nil
+		}
+	}
+	return nil, models.NewNotFoundError("snapshot of turn " + turnUUID.String())
+}
+
+// List returns a session's snapshots, oldest first.
+func (s *MemorySnapshotStore) List(
+	_ context.Context,
+	sessionID string,
+	limit int,
+) ([]*models.MemorySnapshot, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	snapshots := []*models.MemorySnapshot{}
+	for _, rec := range s.db.snapshots[sessionID] {
+		if rec.deleted {
+			continue
+		}
+		if limit > 0 && len(snapshots) == limit {
+			break
+		}
+		snapshots = append(snapshots, copySnapshot(&rec.snapshot))
+	}
+	return snapshots, nil
+}
+
+func copySnapshot(snapshot *models.MemorySnapshot) *models.MemorySnapshot {
+	c := *snapshot
+	c.Memory = copyMemory(snapshot.Memory)
+	return &c
+}
+
+func copyMemory(memory *models.Memory) *models.Memory {
+	if memory == nil {
+		return nil
+	}
+	c := &models.Memory{
+		Metadata:  copyMetadata(memory.Metadata),
+		TaskState: copyTaskState(memory.TaskState),
+	}
+	if memory.Messages != nil {
+		c.Messages = make([]models.Message, len(memory.Messages))
+		for i := range memory.Messages {
+			c.Messages[i] = copyMessage(&memory.Messages[i])
+		}
+	}
+	if memory.Summary != nil {
+		c.Summary = copySummary(memory.Summary)
+	}
+	return c
+}
@@ -0,0 +1,189 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.SummaryDAO = &SummaryDAO{}
+
+// SummaryDAO is an in-memory implementation of models.SummaryDAO.
+type SummaryDAO struct {
+	db        *DB
+	sessionID string
+}
+
+// NewSummaryDAO returns a SummaryDAO for the summaries of a session in db.
+func NewSummaryDAO(db *DB, sessionID string) (*SummaryDAO, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	if sessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	return &SummaryDAO{db: db, sessionID: sessionID}, nil
+}
+
+// Create stores a new summary for the session. The session must exist.
+func (dao *SummaryDAO) Create(
+	_ context.Context,
+	summary *models.Summary,
+) (*models.Summary, error) {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	if _, ok := dao.db.sessions[dao.sessionID]; !ok {
+		return nil, models.NewNotFoundError("session " + dao.sessionID)
+	}
+
+	rec := &summaryRecord{
+		summary: models.Summary{
+			UUID:             newUUID(),
+			CreatedAt:        now(),
+			Content:          summary.Content,
+			SummaryPointUUID: summary.SummaryPointUUID,
+			Metadata:         copyMetadata(summary.Metadata),
+			TokenCount:       summary.TokenCount,
+		},
+	}
+	dao.db.summaries[dao.sessionID] = append(dao.db.summaries[dao.sessionID], rec)
+
+	return copySummary(&rec.summary), nil
+}
+
+// Update updates a summary's token count and merges its metadata. If includeContent is
+// true, the content is updated, too.
+func (dao *SummaryDAO) Update(
+	_ context.Context,
+	summary *models.Summary,
+	includeContent bool,
+) (*models.Summary, error) {
+	if summary.UUID == uuid.Nil {
+		return nil, errors.New("summary UUID cannot be empty")
+	}
+
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	rec := dao.get(summary.UUID)
+	if rec == nil {
+		return nil, models.NewNotFoundError("summary " + summary.UUID.String())
+	}
+
+	rec.summary.TokenCount = summary.TokenCount
+	if includeContent {
+		rec.summary.Content = summary.Content
+	}
+	if len(summary.Metadata) > 0 {
+		rec.summary.Metadata = mergeMetadata(rec.summary.Metadata, summary.Metadata, true)
+	}
+
+	return copySummary(&rec.summary), nil
+}
+
+// Get returns the most recent summary for the session, or an empty Summary if there is none.
+func (dao *SummaryDAO) Get(_ context.Context) (*models.Summary, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	rec := dao.db.latestSummary(dao.sessionID)
+	if rec == nil {
+		return &models.Summary{}, nil
+	}
+	return copySummary(&rec.summary), nil
+}
+
+// GetByUUID returns a summary by its UUID.
+func (dao *SummaryDAO) GetByUUID(
+	_ context.Context,
+	summaryUUID uuid.UUID,
+) (*models.Summary, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	rec := dao.get(summaryUUID)
+	if rec == nil {
+		return nil, models.NewNotFoundError("summary " + summaryUUID.String())
+	}
+	return copySummary(&rec.summary), nil
+}
+
+// PutEmbedding stores a summary embedding.
+func (dao *SummaryDAO) PutEmbedding(_ context.Context, embedding *models.TextData) error {
+	dao.db.mu.Lock()
+	defer dao.db.mu.Unlock()
+
+	rec := dao.get(embedding.TextUUID)
+	if rec == nil {
+		return models.NewNotFoundError("summary " + embedding.TextUUID.String())
+	}
+	rec.embedding = copyEmbedding(embedding.Embedding)
+
+	return nil
+}
+
+// GetEmbeddings returns all summary embeddings for the session, without the summary content.
+func (dao *SummaryDAO) GetEmbeddings(_ context.Context) ([]models.TextData, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	var embeddings []models.TextData
+	for _, rec := range dao.db.summaries[dao.sessionID] {
+		if !rec.deleted && rec.embedding != nil {
+			embeddings = append(embeddings, models.TextData{
+				TextUUID:  rec.summary.UUID,
+				Embedding: copyEmbedding(rec.embedding),
+			})
+		}
+	}
+	return embeddings, nil
+}
+
+// GetList returns a page of the session's summaries, oldest first.
+func (dao *SummaryDAO) GetList(
+	_ context.Context,
+	currentPage int,
+	pageSize int,
+) (*models.SummaryListResponse, error) {
+	dao.db.mu.RLock()
+	defer dao.db.mu.RUnlock()
+
+	var records []*summaryRecord
+	for _, rec := range dao.db.summaries[dao.sessionID] {
+		if !rec.deleted {
+			records = append(records, rec)
+		}
+	}
+
+	records = page(records, currentPage, pageSize)
+	summaries := make([]models.Summary, len(records))
+	for i, rec := range records {
+		summaries[i] = *copySummary(&rec.summary)
+	}
+
+	return &models.SummaryListResponse{
+		Summaries: summaries,
+		RowCount:  len(summaries),
+	}, nil
+}
+
+// get returns the session's undeleted summary with the given UUID, or nil. The caller
+// must hold the lock.
+func (dao *SummaryDAO) get(summaryUUID uuid.UUID) *summaryRecord {
+	for _, rec := range dao.db.summaries[dao.sessionID] {
+		if rec.summary.UUID == summaryUUID && !rec.deleted {
+			return rec
+		}
+	}
+	return nil
+}
+
+func copySummary(summary *models.Summary) *models.Summary {
+	c := *summary
+	c.Metadata = copyMetadata(summary.Metadata)
+	return &c
+}
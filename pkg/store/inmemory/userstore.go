@@ -0,0 +1,333 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.UserStore = &UserStore{}
+
+// UserStore is an in-memory implementation of models.UserStore.
+type UserStore struct {
+	db *DB
+}
+
+// NewUserStore returns a UserStore for the users in db.
+func NewUserStore(db *DB) *UserStore {
+	return &UserStore{db: db}
+}
+
+// Create creates a new user. Returns a models.ConflictError if the user exists.
+func (us *UserStore) Create(
+	_ context.Context,
+	user *models.CreateUserRequest,
+) (*models.User, error) {
+	if user.UserID == "" {
+		return nil, models.NewValidationError("UserID cannot be empty")
+	}
+
+	us.db.mu.Lock()
+	defer us.db.mu.Unlock()
+
+	if _, ok := us.db.users[user.UserID]; ok {
+		return nil, models.NewConflictError("user already exists with user_id: " + user.UserID)
+	}
+
+	createdAt := now()
+	rec := &userRecord{
+		user: models.User{
+			UUID:      newUUID(),
+			ID:        us.db.nextID(),
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+			UserID:    user.UserID,
+			Email:     user.Email,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Metadata:  copyMetadata(user.Metadata),
+		},
+	}
+	us.db.users[user.UserID] = rec
+
+	return copyUser(&rec.user), nil
+}
+
+// Get returns an undeleted user.
+func (us *UserStore) Get(_ context.Context, userID string) (*models.User, error) {
+	us.db.mu.RLock()
+	defer us.db.mu.RUnlock()
+
+	rec := us.get(userID)
+	if rec == nil {
+		return nil, models.NewNotFoundError("user " + userID)
+	}
+	return copyUser(&rec.user), nil
+}
+
+// Update updates a user's email and name, if not empty, and merges the user's metadata.
+func (us *UserStore) Update(
+	_ context.Context,
+	user *models.UpdateUserRequest,
+	isPrivileged bool,
+) (*models.User, error) {
+	if user.UserID == "" {
+		return nil, models.NewValidationError("UserID cannot be empty")
+	}
+
+	us.db.mu.Lock()
+	defer us.db.mu.Unlock()
+
+	rec := us.get(user.UserID)
+	if rec == nil {
+		return nil, models.NewNotFoundError("user " + user.UserID)
+	}
+
+	if user.Email != "" {
+		rec.user.Email = user.Email
+	}
+	if user.FirstName != "" {
+		rec.user.FirstName = user.FirstName
+	}
+	if user.LastName != "" {
+		rec.user.LastName = user.LastName
+	}
+	rec.user.UpdatedAt = now()
+	if user.Metadata != nil {
+		rec.user.Metadata = mergeMetadata(rec.user.Metadata, user.Metadata, isPrivileged)
+	}
+
+	return copyUser(&rec.user), nil
+}
+
+// PatchMetadata applies patch to the metadata of an undeleted user.
+func (us *UserStore) PatchMetadata(
+	_ context.Context,
+	userID string,
+	patch models.MetadataPatch,
+	isPrivileged bool,
+) (*models.User, error) {
+	us.db.mu.Lock()
+	defer us.db.mu.Unlock()
+
+	rec := us.get(userID)
+	if rec == nil {
+		return nil, models.NewNotFoundError("user " + userID)
+	}
+	metadata, err := models.PatchMetadata(rec.user.Metadata, patch, isPrivileged)
+	if err != nil {
+		return nil, err
+	}
+	rec.user.Metadata = metadata
+	rec.user.UpdatedAt = now()
+
+	return copyUser(&rec.user), nil
+}
+
+// Delete soft deletes a user and the user's sessions. Returns a ConflictError, deleting
+// nothing, if the user or any of the user's sessions is under legal hold.
+func (us *UserStore) Delete(_ context.Context, userID string) error {
+	us.db.mu.Lock()
+	defer us.db.mu.Unlock()
+
+	rec := us.get(userID)
+	if rec == nil {
+		return models.NewNotFoundError("user " + userID)
+	}
+	if err := us.checkHold(userID); err != nil {
+		return err
+	}
+
+	deletedAt := now()
+	for _, session := range us.sessions(userID) {
+		us.db.deleteSession(session, deletedAt)
+	}
+	rec.user.DeletedAt = &deletedAt
+
+	return nil
+}
+
+// Anonymize re-keys an undeleted user, and all of the user's sessions, to anonymousID,
+// clears the user's email and name, and replaces the user's metadata.
+func (us *UserStore) Anonymize(
+	_ context.Context,
+	userID string,
+	anonymousID string,
+	metadata map[string]interface{},
+) (*models.User, error) {
+	if anonymousID == "" {
+		return nil, models.NewValidationError("anonymous ID cannot be empty")
+	}
+
+	us.db.mu.Lock()
+	defer us.db.mu.Unlock()
+
+	rec := us.get(userID)
+	if rec == nil {
+		return nil, models.NewNotFoundError("user " + userID)
+	}
+	if _, ok := us.db.users[anonymousID]; ok {
+		return nil, models.NewConflictError("user already exists with user_id: " + anonymousID)
+	}
+	if err := us.checkHold(userID); err != nil {
+		return nil, err
+	}
+
+	updatedAt := now()
+	for _, session := range us.db.sessions {
+		if stringValue(session.session.UserID) == userID {
+			id := anonymousID
+			session.session.UserID = &id
+			session.session.UpdatedAt = updatedAt
+		}
+	}
+	delete(us.db.users, userID)
+	rec.user.UserID = anonymousID
+	rec.user.Email = ""
+	rec.user.FirstName = ""
+	rec.user.LastName = ""
+	rec.user.Metadata = copyMetadata(metadata)
+	rec.user.UpdatedAt = updatedAt
+	us.db.users[anonymousID] = rec
+
+	return copyUser(&rec.user), nil
+}
+
+// GetSessions returns the user's undeleted sessions.
+func (us *UserStore) GetSessions(_ context.Context, userID string) ([]*models.Session, error) {
+	us.db.mu.RLock()
+	defer us.db.mu.RUnlock()
+
+	records := us.sessions(userID)
+	sessions := make([]*models.Session, len(records))
+	for i, rec := range records {
+		sessions[i] = copySession(&rec.session)
+	}
+	return sessions, nil
+}
+
+// ListAll returns up to limit undeleted users with an ID greater than cursor.
+func (us *UserStore) ListAll(_ context.Context, cursor int64, limit int) ([]*models.User, error) {
+	us.db.mu.RLock()
+	defer us.db.mu.RUnlock()
+
+	var users []*models.User
+	for _, user := range us.undeleted() {
+		if user.ID > cursor {
+			users = append(users, user)
+		}
+	}
+	return page(users, 1, limit), nil
+}
+
+// ListAllOrdered returns a page of undeleted users ordered by orderBy, which is one of id,
+// created_at, updated_at, user_id, or email.
+func (us *UserStore) ListAllOrdered(
+	_ context.Context,
+	pageNumber int,
+	pageSize int,
+	orderBy string,
+	asc bool,
+) (*models.UserListResponse, error) {
+	less, err := userOrder(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	us.db.mu.RLock()
+	defer us.db.mu.RUnlock()
+
+	users := us.undeleted()
+	totalCount := len(users)
+	sort.SliceStable(users, func(i, j int) bool {
+		if asc {
+			return less(users[i], users[j])
+		}
+		return less(users[j], users[i])
+	})
+
+	users = page(users, pageNumber, pageSize)
+	return &models.UserListResponse{
+		Users:      users,
+		TotalCount: totalCount,
+		RowCount:   len(users),
+	}, nil
+}
+
+// get returns the undeleted user with the given userID, or nil. The caller must hold
+// the lock.
+func (us *UserStore) get(userID string) *userRecord {
+	rec, ok := us.db.users[userID]
+	if !ok || rec.user.DeletedAt != nil {
+		return nil
+	}
+	return rec
+}
+
+// checkHold returns a ConflictError if the user, or any of the user's undeleted sessions,
+// is under legal hold. The caller must hold the lock.
+func (us *UserStore) checkHold(userID string) error {
+	if _, ok := us.db.legalHolds[legalHoldKey{models.LegalHoldSubjectUser, userID}]; ok {
+		return models.NewLegalHoldError(models.LegalHoldSubjectUser, userID)
+	}
+	for _, session := range us.sessions(userID) {
+		if us.db.sessionHeld(&session.session) {
+			return models.NewLegalHoldError(
+				models.LegalHoldSubjectSession,
+				session.session.SessionID,
+			)
+		}
+	}
+	return nil
+}
+
+// undeleted returns copies of the undeleted users, ordered by ID. The caller must hold
+// the lock.
+func (us *UserStore) undeleted() []*models.User {
+	var users []*models.User
+	for _, rec := range us.db.users {
+		if rec.user.DeletedAt == nil {
+			users = append(users, copyUser(&rec.user))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+// sessions returns the user's undeleted sessions, ordered by ID. The caller must hold
+// the lock.
+func (us *UserStore) sessions(userID string) []*sessionRecord {
+	var sessions []*sessionRecord
+	for _, rec := range us.db.undeletedSessions() {
+		if stringValue(rec.session.UserID) == userID {
+			sessions = append(sessions, rec)
+		}
+	}
+	return sessions
+}
+
+// userOrder returns a less function for the given ListAllOrdered column.
+func userOrder(orderBy string) (func(a, b *models.User) bool, error) {
+	switch orderBy {
+	case "", "id":
+		return func(a, b *models.User) bool { return a.ID < b.ID }, nil
+	case "created_at":
+		return func(a, b *models.User) bool { return a.CreatedAt.Before(b.CreatedAt) }, nil
+	case "updated_at":
+		return func(a, b *models.User) bool { return a.UpdatedAt.Before(b.UpdatedAt) }, nil
+	case "user_id":
+		return func(a, b *models.User) bool { return a.UserID < b.UserID }, nil
+	case "email":
+		return func(a, b *models.User) bool { return a.Email < b.Email }, nil
+	default:
+		return nil, models.NewValidationError("invalid order by column: " + orderBy)
+	}
+}
+
+func copyUser(user *models.User) *models.User {
+	c := *user
+	c.Metadata = copyMetadata(user.Metadata)
+	c.DeletedAt = copyTime(user.DeletedAt)
+	return &c
+}
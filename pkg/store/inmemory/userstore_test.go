@@ -0,0 +1,134 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestUserStore(t *testing.T) {
+	db := NewDB()
+	userStore := NewUserStore(db)
+	sessionStore := NewSessionDAO(db)
+
+	user, err := userStore.Create(testCtx, &models.CreateUserRequest{
+		UserID:    "user",
+		Email:     "user@example.com",
+		FirstName: "first",
+		Metadata:  map[string]interface{}{"key": "value"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", user.Email)
+
+	_, err = userStore.Create(testCtx, &models.CreateUserRequest{UserID: "user"})
+	assert.ErrorIs(t, err, models.ErrConflict)
+	_, err = userStore.Create(testCtx, &models.CreateUserRequest{})
+	assert.ErrorIs(t, err, models.ErrValidation)
+
+	t.Run("update omits empty fields", func(t *testing.T) {
+		updated, err := userStore.Update(testCtx, &models.UpdateUserRequest{
+			UserID:   "user",
+			LastName: "last",
+			Metadata: map[string]interface{}{"other": "value"},
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, "first", updated.FirstName)
+		assert.Equal(t, "last", updated.LastName)
+		assert.Equal(t, map[string]interface{}{"key": "value", "other": "value"}, updated.Metadata)
+
+		_, err = userStore.Update(testCtx, &models.UpdateUserRequest{UserID: "missing"}, false)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+	})
+
+	t.Run("delete deletes sessions", func(t *testing.T) {
+		userID := "user"
+		_, err := sessionStore.Create(testCtx, &models.CreateSessionRequest{
+			SessionID: "session",
+			UserID:    &userID,
+		})
+		require.NoError(t, err)
+
+		sessions, err := userStore.GetSessions(testCtx, "user")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+
+		require.NoError(t, userStore.Delete(testCtx, "user"))
+		_, err = userStore.Get(testCtx, "user")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		_, err = sessionStore.Get(testCtx, "session")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		assert.ErrorIs(t, userStore.Delete(testCtx, "user"), models.ErrNotFound)
+	})
+}
+
+func TestUserStore_Anonymize(t *testing.T) {
+	db := NewDB()
+	userStore := NewUserStore(db)
+	sessionStore := NewSessionDAO(db)
+
+	userID := "identified"
+	_, err := userStore.Create(testCtx, &models.CreateUserRequest{
+		UserID:    userID,
+		Email:     "jane@example.com",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Metadata:  map[string]interface{}{"plan": "pro", "phone": "555-0100"},
+	})
+	require.NoError(t, err)
+	_, err = sessionStore.Create(testCtx, &models.CreateSessionRequest{
+		SessionID: "anonymized-session",
+		UserID:    &userID,
+	})
+	require.NoError(t, err)
+	_, err = userStore.Create(testCtx, &models.CreateUserRequest{UserID: "taken"})
+	require.NoError(t, err)
+
+	_, err = userStore.Anonymize(testCtx, userID, "taken", nil)
+	assert.ErrorIs(t, err, models.ErrConflict)
+
+	user, err := userStore.Anonymize(
+		testCtx,
+		userID,
+		"anon",
+		map[string]interface{}{"plan": "pro"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "anon", user.UserID)
+	assert.Empty(t, user.Email)
+	assert.Empty(t, user.FirstName)
+	assert.Empty(t, user.LastName)
+	assert.Equal(t, map[string]interface{}{"plan": "pro"}, user.Metadata)
+
+	_, err = userStore.Get(testCtx, userID)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+	sessions, err := userStore.GetSessions(testCtx, "anon")
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "anon", *sessions[0].UserID)
+
+	_, err = userStore.Anonymize(testCtx, "missing", "anon-2", nil)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestUserStore_List(t *testing.T) {
+	userStore := NewUserStore(NewDB())
+	for _, id := range []string{"b", "a", "c"} {
+		_, err := userStore.Create(testCtx, &models.CreateUserRequest{UserID: id})
+		require.NoError(t, err)
+	}
+
+	users, err := userStore.ListAll(testCtx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+	assert.Equal(t, "b", users[0].UserID)
+
+	list, err := userStore.ListAllOrdered(testCtx, 1, 2, "user_id", true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, list.TotalCount)
+	require.Len(t, list.Users, 2)
+	assert.Equal(t, "a", list.Users[0].UserID)
+	assert.Equal(t, "b", list.Users[1].UserID)
+}
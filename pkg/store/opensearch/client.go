@@ -0,0 +1,474 @@
+// Package opensearch implements models.MemoryStore with OpenSearch, using its REST API.
+// Sessions, messages and summaries are documents of their own indexes. Messages and
+// summaries are found by k-NN search of their embeddings, and messages also by BM25, but
+// results are scored by Zep from the returned embeddings, as the Postgres store scores
+// them, so that scores don't depend on the store.
+//
+// OpenSearch has no transactions. Documents read for an update are written back with
+// their sequence number, and updates conflicting with a concurrent write are retried, so
+// that concurrent metadata merges aren't lost.
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/llms"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	RetryMax       = 3
+	// PageSize is the number of documents read per request when all of a session's
+	// messages are read.
+	PageSize = 1000
+	// maxConflictRetries is the number of times an update conflicting with a concurrent
+	// write is retried.
+	maxConflictRetries = 5
+)
+
+// Index names, which are prefixed with the configured index prefix.
+const (
+	sessionsIndex  = "sessions"
+	messagesIndex  = "messages"
+	summariesIndex = "summaries"
+	// countersIndex holds the counter sessions, messages and summaries are numbered by.
+	countersIndex = "counters"
+)
+
+// Client sends requests to the OpenSearch REST API.
+type Client struct {
+	url      string
+	username string
+	password string
+	prefix   string
+	client   *http.Client
+}
+
+// NewClient returns a Client for the OpenSearch cluster of cfg.
+func NewClient(cfg *config.OpenSearchConfig) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("store.memory_store.opensearch.url is required")
+	}
+	timeout := DefaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	return &Client{
+		url:      strings.TrimSuffix(cfg.URL, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		prefix:   cfg.IndexPrefix,
+		client:   llms.NewRetryableHTTPClient(RetryMax, timeout),
+	}, nil
+}
+
+// index returns the prefixed name of an index.
+func (c *Client) index(name string) string {
+	return c.prefix + name
+}
+
+// version identifies the revision of a document, for optimistic concurrency control.
+type version struct {
+	SeqNo       int64 `json:"_seq_no"`
+	PrimaryTerm int64 `json:"_primary_term"`
+}
+
+func (v *version) params() url.Values {
+	return url.Values{
+		"if_seq_no":       {strconv.FormatInt(v.SeqNo, 10)},
+		"if_primary_term": {strconv.FormatInt(v.PrimaryTerm, 10)},
+	}
+}
+
+// record is a document read with its version.
+type record[T any] struct {
+	doc     T
+	version version
+}
+
+type hit struct {
+	version
+	ID     string          `json:"_id"`
+	Score  float64         `json:"_score"`
+	Source json.RawMessage `json:"_source"`
+	Sort   []any           `json:"sort"`
+}
+
+type searchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+		Hits []hit `json:"hits"`
+	} `json:"hits"`
+}
+
+// statusError is returned for unsuccessful responses.
+type statusError struct {
+	status    int
+	errorType string
+	reason    string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("opensearch returned status %d: %s: %s", e.status, e.errorType, e.reason)
+}
+
+func hasStatus(err error, status int) bool {
+	var e *statusError
+	return errors.As(err, &e) && e.status == status
+}
+
+// isConflict reports whether err is a version conflict, or the creation of a document
+// that exists.
+func isConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// get reads a document with its version, returning nil if it doesn't exist.
+func get[T any](ctx context.Context, c *Client, index, id string) (*record[T], error) {
+	var response struct {
+		version
+		Source T `json:"_source"`
+	}
+	err := c.do(ctx, http.MethodGet, docPath(c.index(index), "_doc", id), nil, nil, &response)
+	if hasStatus(err, http.StatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record[T]{doc: response.Source, version: response.version}, nil
+}
+
+// put indexes a document. If v isn't nil, the document is only replaced if it has that
+// version.
+func (c *Client) put(ctx context.Context, index, id string, doc any, v *version) error {
+	params := url.Values{}
+	if v != nil {
+		params = v.params()
+	}
+	params.Set("refresh", "wait_for")
+	return c.do(ctx, http.MethodPut, docPath(c.index(index), "_doc", id), params, doc, nil)
+}
+
+// create indexes a new document, returning a conflict if the document exists.
+func (c *Client) create(ctx context.Context, index, id string, doc any) error {
+	params := url.Values{"refresh": {"wait_for"}}
+	return c.do(ctx, http.MethodPut, docPath(c.index(index), "_create", id), params, doc, nil)
+}
+
+// bulkOp is an operation of a bulk request.
+type bulkOp struct {
+	index string
+	id    string
+	// create only creates the document, failing if it exists.
+	create bool
+	doc    any
+	// version, if set, only replaces the document if it has that version.
+	version *version
+}
+
+// bulk runs ops in a single request, returning the first failure. Operations aren't
+// atomic, so those preceding a failure may have been applied.
+func (c *Client) bulk(ctx context.Context, ops []bulkOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, op := range ops {
+		action := "index"
+		if op.create {
+			action = "create"
+		}
+		meta := map[string]any{"_index": c.index(op.index), "_id": op.id}
+		if op.version != nil {
+			meta["if_seq_no"] = op.version.SeqNo
+			meta["if_primary_term"] = op.version.PrimaryTerm
+		}
+		if err := encoder.Encode(map[string]any{action: meta}); err != nil {
+			return fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := encoder.Encode(op.doc); err != nil {
+			return fmt.Errorf("failed to marshal document %s: %w", op.id, err)
+		}
+	}
+
+	var response struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	params := url.Values{"refresh": {"wait_for"}}
+	if err := c.do(ctx, http.MethodPost, "/_bulk", params, body.Bytes(), &response); err != nil {
+		return err
+	}
+	if !response.Errors {
+		return nil
+	}
+	for _, item := range response.Items {
+		for _, result := range item {
+			if result.Status >= http.StatusMultipleChoices {
+				return fmt.Errorf("failed to write document %s: %w", result.ID, &statusError{
+					status:    result.Status,
+					errorType: result.Error.Type,
+					reason:    result.Error.Reason,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// search runs a search request of an index. Hits include their version.
+func (c *Client) search(
+	ctx context.Context,
+	index string,
+	request map[string]any,
+) (*searchResponse, error) {
+	request["seq_no_primary_term"] = true
+	var response searchResponse
+	err := c.do(ctx, http.MethodPost, "/"+c.index(index)+"/_search", nil, request, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// searchAll returns every document of an index matching query, in the order of sort,
+// which must order documents uniquely, leaving out the excluded fields. Documents are read
+// PageSize at a time.
+func (c *Client) searchAll(
+	ctx context.Context,
+	index string,
+	query map[string]any,
+	sort []map[string]any,
+	excludes ...string,
+) ([]hit, error) {
+	var hits []hit
+	var after []any
+	for {
+		request := map[string]any{"query": query, "sort": sort, "size": PageSize}
+		if len(excludes) > 0 {
+			request["_source"] = map[string]any{"excludes": excludes}
+		}
+		if after != nil {
+			request["search_after"] = after
+		}
+		response, err := c.search(ctx, index, request)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, response.Hits.Hits...)
+		if len(response.Hits.Hits) < PageSize {
+			return hits, nil
+		}
+		after = response.Hits.Hits[len(response.Hits.Hits)-1].Sort
+	}
+}
+
+// updateByQuery sets fields of the documents of an index matching query, returning the
+// number of documents updated.
+func (c *Client) updateByQuery(
+	ctx context.Context,
+	index string,
+	query map[string]any,
+	fields map[string]any,
+) (int, error) {
+	var response struct {
+		Updated int `json:"updated"`
+	}
+	err := c.do(
+		ctx,
+		http.MethodPost,
+		"/"+c.index(index)+"/_update_by_query",
+		url.Values{"refresh": {"true"}},
+		map[string]any{
+			"query": query,
+			"script": map[string]any{
+				"source": setFieldsScript,
+				"lang":   "painless",
+				"params": map[string]any{"fields": fields},
+			},
+		},
+		&response,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return response.Updated, nil
+}
+
+// setFieldsScript sets the fields of a document to those of its fields parameter.
+const setFieldsScript = "ctx._source.putAll(params.fields)"
+
+// deleteByQuery deletes the documents of an index matching query, returning the number of
+// documents deleted.
+func (c *Client) deleteByQuery(ctx context.Context, index string, query map[string]any) (int, error) {
+	var response struct {
+		Deleted int `json:"deleted"`
+	}
+	err := c.do(
+		ctx,
+		http.MethodPost,
+		"/"+c.index(index)+"/_delete_by_query",
+		url.Values{"refresh": {"true"}},
+		map[string]any{"query": query},
+		&response,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return response.Deleted, nil
+}
+
+// incrementCounterScript adds its n parameter to a counter.
+const incrementCounterScript = "ctx._source.value += params.n"
+
+// nextIDs reserves n consecutive IDs, returning the first. IDs increase across sessions,
+// messages and summaries, as the Postgres store's serial IDs do.
+func (c *Client) nextIDs(ctx context.Context, n int) (int64, error) {
+	var response struct {
+		Get struct {
+			Source struct {
+				Value int64 `json:"value"`
+			} `json:"_source"`
+		} `json:"get"`
+	}
+	err := c.do(
+		ctx,
+		http.MethodPost,
+		docPath(c.index(countersIndex), "_update", "ids"),
+		url.Values{"retry_on_conflict": {"10"}, "_source": {"true"}},
+		map[string]any{
+			"script": map[string]any{
+				"source": incrementCounterScript,
+				"lang":   "painless",
+				"params": map[string]any{"n": n},
+			},
+			"upsert": map[string]any{"value": n},
+		},
+		&response,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve ids: %w", err)
+	}
+	return response.Get.Source.Value - int64(n) + 1, nil
+}
+
+// createIndex creates an index, unless it exists.
+func (c *Client) createIndex(ctx context.Context, name string, body map[string]any) error {
+	err := c.do(ctx, http.MethodPut, "/"+c.index(name), nil, body, nil)
+	var e *statusError
+	if errors.As(err, &e) && e.errorType == "resource_already_exists_exception" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create index %s: %w", c.index(name), err)
+	}
+	return nil
+}
+
+// do sends a request to the OpenSearch API, decoding the response into result, if not
+// nil. A []byte body is sent as newline-delimited JSON.
+func (c *Client) do(
+	ctx context.Context,
+	method, path string,
+	params url.Values,
+	body any,
+	result any,
+) error {
+	var reader io.Reader
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+		contentType = "application/x-ndjson"
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	u := c.url + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to opensearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		// errors are reported as {"error": {"type": "...", "reason": "..."}}
+		var response struct {
+			Error struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&response)
+		return &statusError{
+			status:    resp.StatusCode,
+			errorType: response.Error.Type,
+			reason:    response.Error.Reason,
+		}
+	}
+	if result == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode opensearch response: %w", err)
+	}
+	return nil
+}
+
+func docPath(index, endpoint, id string) string {
+	return "/" + index + "/" + endpoint + "/" + url.PathEscape(id)
+}
+
+// retryOnConflict runs update until it doesn't fail with a version conflict, up to
+// maxConflictRetries times. Updates must be idempotent, as a bulk update may be partly
+// applied before it conflicts.
+func retryOnConflict(update func() error) error {
+	var err error
+	for i := 0; i <= maxConflictRetries; i++ {
+		if err = update(); !isConflict(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("gave up after %d conflicting updates: %w", maxConflictRetries, err)
+}
@@ -0,0 +1,586 @@
+package opensearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+)
+
+// fakeOpenSearch implements the parts of the OpenSearch REST API used by Client, over
+// documents held in memory. Writes are visible immediately, and k-NN queries score
+// embeddings by cosine similarity, whatever the space type.
+type fakeOpenSearch struct {
+	mu       sync.Mutex
+	indexes  map[string]map[string]*fakeDoc
+	mappings map[string]map[string]any
+	seqNo    int64
+	users    []string
+}
+
+type fakeDoc struct {
+	source map[string]any
+	seqNo  int64
+}
+
+const fakePrimaryTerm = 1
+
+func newFakeOpenSearch(t *testing.T) (*fakeOpenSearch, *httptest.Server) {
+	f := &fakeOpenSearch{
+		indexes:  make(map[string]map[string]*fakeDoc),
+		mappings: make(map[string]map[string]any),
+	}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+// fakeError is an error response of the API.
+type fakeError struct {
+	status    int
+	errorType string
+}
+
+func (f *fakeOpenSearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	username, _, _ := r.BasicAuth()
+	f.users = append(f.users, username)
+
+	reply := func(status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+	result, ferr := f.handle(r)
+	if ferr != nil {
+		reply(ferr.status, map[string]any{"error": map[string]any{
+			"type":   ferr.errorType,
+			"reason": ferr.errorType,
+		}})
+		return
+	}
+	reply(http.StatusOK, result)
+}
+
+func (f *fakeOpenSearch) handle(r *http.Request) (any, *fakeError) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if parts[0] == "_bulk" {
+		return f.bulk(r)
+	}
+
+	name := parts[0]
+	if len(parts) == 1 && r.Method == http.MethodPut {
+		if _, ok := f.indexes[name]; ok {
+			return nil, &fakeError{http.StatusBadRequest, "resource_already_exists_exception"}
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.indexes[name] = make(map[string]*fakeDoc)
+		f.mappings[name], _ = body["mappings"].(map[string]any)
+		return map[string]any{"acknowledged": true}, nil
+	}
+	docs, ok := f.indexes[name]
+	if !ok {
+		return nil, &fakeError{http.StatusNotFound, "index_not_found_exception"}
+	}
+
+	var body map[string]any
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+	switch {
+	case len(parts) == 3 && parts[1] == "_doc" && r.Method == http.MethodGet:
+		doc, ok := docs[parts[2]]
+		if !ok {
+			return nil, &fakeError{http.StatusNotFound, ""}
+		}
+		return map[string]any{
+			"_id":           parts[2],
+			"_seq_no":       doc.seqNo,
+			"_primary_term": fakePrimaryTerm,
+			"_source":       doc.source,
+		}, nil
+	case len(parts) == 3 && (parts[1] == "_doc" || parts[1] == "_create"):
+		return f.write(docs, parts[2], parts[1] == "_create", r.URL.Query(), body)
+	case len(parts) == 3 && parts[1] == "_update":
+		// the counter script adds n to the value of the document
+		params := body["script"].(map[string]any)["params"].(map[string]any)
+		doc, ok := docs[parts[2]]
+		if ok {
+			doc.source["value"] = doc.source["value"].(float64) + params["n"].(float64)
+		} else {
+			doc = &fakeDoc{source: body["upsert"].(map[string]any)}
+			docs[parts[2]] = doc
+		}
+		f.seqNo++
+		doc.seqNo = f.seqNo
+		return map[string]any{"get": map[string]any{"_source": doc.source}}, nil
+	case len(parts) == 2 && parts[1] == "_search":
+		return f.search(docs, body), nil
+	case len(parts) == 2 && parts[1] == "_update_by_query":
+		fields := body["script"].(map[string]any)["params"].(map[string]any)["fields"]
+		var updated int
+		for _, doc := range docs {
+			if fakeMatches(doc.source, body["query"].(map[string]any)) {
+				for k, v := range fields.(map[string]any) {
+					doc.source[k] = v
+				}
+				f.seqNo++
+				doc.seqNo = f.seqNo
+				updated++
+			}
+		}
+		return map[string]any{"updated": updated}, nil
+	case len(parts) == 2 && parts[1] == "_delete_by_query":
+		var deleted int
+		for id, doc := range docs {
+			if fakeMatches(doc.source, body["query"].(map[string]any)) {
+				delete(docs, id)
+				deleted++
+			}
+		}
+		return map[string]any{"deleted": deleted}, nil
+	}
+	return nil, &fakeError{http.StatusBadRequest, "unsupported_request"}
+}
+
+// write indexes a document, or only creates it if create is true, checking the version
+// of the document it replaces if given.
+func (f *fakeOpenSearch) write(
+	docs map[string]*fakeDoc,
+	id string,
+	create bool,
+	params url.Values,
+	source map[string]any,
+) (any, *fakeError) {
+	existing, exists := docs[id]
+	if create && exists {
+		return nil, &fakeError{http.StatusConflict, "version_conflict_engine_exception"}
+	}
+	if seqNo := params.Get("if_seq_no"); seqNo != "" {
+		if !exists || strconv.FormatInt(existing.seqNo, 10) != seqNo {
+			return nil, &fakeError{http.StatusConflict, "version_conflict_engine_exception"}
+		}
+	}
+	f.seqNo++
+	docs[id] = &fakeDoc{source: source, seqNo: f.seqNo}
+	return map[string]any{"_id": id, "result": "created"}, nil
+}
+
+func (f *fakeOpenSearch) bulk(r *http.Request) (any, *fakeError) {
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, 1<<24)
+	var items []map[string]any
+	var errors bool
+	for scanner.Scan() {
+		var action map[string]map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			return nil, &fakeError{http.StatusBadRequest, "parse_exception"}
+		}
+		var source map[string]any
+		if !scanner.Scan() || json.Unmarshal(scanner.Bytes(), &source) != nil {
+			return nil, &fakeError{http.StatusBadRequest, "parse_exception"}
+		}
+		for name, meta := range action {
+			id := meta["_id"].(string)
+			params := url.Values{}
+			if seqNo, ok := meta["if_seq_no"].(float64); ok {
+				params.Set("if_seq_no", strconv.FormatInt(int64(seqNo), 10))
+			}
+			item := map[string]any{"_id": id, "status": http.StatusCreated}
+			docs, ok := f.indexes[meta["_index"].(string)]
+			var ferr *fakeError
+			if !ok {
+				ferr = &fakeError{http.StatusNotFound, "index_not_found_exception"}
+			} else {
+				_, ferr = f.write(docs, id, name == "create", params, source)
+			}
+			if ferr != nil {
+				errors = true
+				item["status"] = ferr.status
+				item["error"] = map[string]any{"type": ferr.errorType, "reason": ferr.errorType}
+			}
+			items = append(items, map[string]any{name: item})
+		}
+	}
+	return map[string]any{"errors": errors, "items": items}, nil
+}
+
+type fakeHit struct {
+	id    string
+	doc   *fakeDoc
+	score float64
+	sort  []any
+}
+
+func (f *fakeOpenSearch) search(docs map[string]*fakeDoc, body map[string]any) any {
+	query, _ := body["query"].(map[string]any)
+	var hits []fakeHit
+	for id, doc := range docs {
+		if query == nil || fakeMatches(doc.source, query) {
+			hits = append(hits, fakeHit{id: id, doc: doc, score: fakeScore(doc.source, query)})
+		}
+	}
+
+	sortFields, _ := body["sort"].([]any)
+	for i := range hits {
+		for _, field := range sortFields {
+			for name := range field.(map[string]any) {
+				hits[i].sort = append(hits[i].sort, hits[i].doc.source[name])
+			}
+		}
+	}
+	less := func(a, b *fakeHit) bool {
+		for i, field := range sortFields {
+			for _, spec := range field.(map[string]any) {
+				order, missing := spec, "_last"
+				if s, ok := spec.(map[string]any); ok {
+					order = s["order"]
+					if m, ok := s["missing"].(string); ok {
+						missing = m
+					}
+				}
+				x, y := a.sort[i], b.sort[i]
+				if x == nil || y == nil {
+					if (x == nil) == (y == nil) {
+						continue
+					}
+					return (x == nil) == (missing == "_first")
+				}
+				c := fakeCompare(x, y)
+				if c == 0 {
+					continue
+				}
+				return (c < 0) == (order == "asc")
+			}
+		}
+		return false
+	}
+	if len(sortFields) == 0 {
+		less = func(a, b *fakeHit) bool { return a.score > b.score }
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return less(&hits[i], &hits[j]) })
+
+	if knn, ok := query["knn"].(map[string]any); ok {
+		k := int(knn["embedding"].(map[string]any)["k"].(float64))
+		hits = hits[:min(k, len(hits))]
+	}
+	total := len(hits)
+	if after, ok := body["search_after"].([]any); ok {
+		marker := fakeHit{sort: after}
+		hits = slices.DeleteFunc(hits, func(h fakeHit) bool { return !less(&marker, &h) })
+	}
+	from, size := 0, 10
+	if v, ok := body["from"].(float64); ok {
+		from = int(v)
+	}
+	if v, ok := body["size"].(float64); ok {
+		size = int(v)
+	}
+	hits = hits[min(from, len(hits)):min(from+size, len(hits))]
+
+	var excludes []any
+	if source, ok := body["_source"].(map[string]any); ok {
+		excludes, _ = source["excludes"].([]any)
+	}
+	results := make([]map[string]any, len(hits))
+	for i, h := range hits {
+		source := make(map[string]any, len(h.doc.source))
+		for k, v := range h.doc.source {
+			source[k] = v
+		}
+		for _, field := range excludes {
+			delete(source, field.(string))
+		}
+		results[i] = map[string]any{
+			"_id":           h.id,
+			"_score":        h.score,
+			"_seq_no":       h.doc.seqNo,
+			"_primary_term": fakePrimaryTerm,
+			"_source":       source,
+			"sort":          h.sort,
+		}
+	}
+	return map[string]any{"hits": map[string]any{
+		"total": map[string]any{"value": total},
+		"hits":  results,
+	}}
+}
+
+// fakeMatches reports whether a document matches a query.
+func fakeMatches(source map[string]any, query map[string]any) bool {
+	for kind, clause := range query {
+		c, _ := clause.(map[string]any)
+		switch kind {
+		case "bool":
+			for _, occur := range []string{"filter", "must", "must_not"} {
+				clauses, _ := c[occur].([]any)
+				for _, sub := range clauses {
+					if fakeMatches(source, sub.(map[string]any)) == (occur == "must_not") {
+						return false
+					}
+				}
+			}
+		case "term":
+			for field, value := range c {
+				if !fakeContains(source[field], value) {
+					return false
+				}
+			}
+		case "terms":
+			for field, values := range c {
+				var found bool
+				for _, value := range values.([]any) {
+					found = found || fakeContains(source[field], value)
+				}
+				if !found {
+					return false
+				}
+			}
+		case "exists":
+			value := source[c["field"].(string)]
+			if list, ok := value.([]any); value == nil || (ok && len(list) == 0) {
+				return false
+			}
+		case "range":
+			for field, bounds := range c {
+				value := source[field]
+				if value == nil {
+					return false
+				}
+				for op, bound := range bounds.(map[string]any) {
+					cmp := fakeCompare(value, bound)
+					if (op == "gt" && cmp <= 0) || (op == "gte" && cmp < 0) ||
+						(op == "lt" && cmp >= 0) || (op == "lte" && cmp > 0) {
+						return false
+					}
+				}
+			}
+		case "wildcard":
+			for field, spec := range c {
+				content, _ := source[strings.TrimSuffix(field, ".raw")].(string)
+				pattern := spec.(map[string]any)["value"].(string)
+				if !fakeWildcard(pattern, spec.(map[string]any)["case_insensitive"] == true).
+					MatchString(content) {
+					return false
+				}
+			}
+		case "match":
+			if fakeScore(source, query) == 0 {
+				return false
+			}
+		case "knn":
+			knn := c["embedding"].(map[string]any)
+			if source["embedding"] == nil || !fakeMatches(source, knn["filter"].(map[string]any)) {
+				return false
+			}
+		case "script_score":
+			if !fakeMatches(source, c["query"].(map[string]any)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// fakeScore scores a document matching a query: by the number of the query's terms in
+// its content for match queries, and by cosine similarity for k-NN queries.
+func fakeScore(source map[string]any, query map[string]any) float64 {
+	var score float64
+	for kind, clause := range query {
+		c, _ := clause.(map[string]any)
+		switch kind {
+		case "bool":
+			must, _ := c["must"].([]any)
+			for _, sub := range must {
+				score += fakeScore(source, sub.(map[string]any))
+			}
+		case "match":
+			content, _ := source["content"].(string)
+			words := strings.Fields(strings.ToLower(content))
+			for _, term := range strings.Fields(strings.ToLower(c["content"].(string))) {
+				for _, word := range words {
+					if strings.Trim(word, ".,?!") == term {
+						score++
+					}
+				}
+			}
+		case "knn":
+			score = fakeCosine(c["embedding"].(map[string]any)["vector"], source["embedding"])
+		case "script_score":
+			params := c["script"].(map[string]any)["params"].(map[string]any)
+			score = 1 + fakeCosine(params["query_value"], source["embedding"])
+		}
+	}
+	return score
+}
+
+func fakeCosine(a, b any) float64 {
+	x, _ := a.([]any)
+	y, _ := b.([]any)
+	if len(x) != len(y) {
+		return 0
+	}
+	var dot, nx, ny float64
+	for i := range x {
+		dot += x[i].(float64) * y[i].(float64)
+		nx += x[i].(float64) * x[i].(float64)
+		ny += y[i].(float64) * y[i].(float64)
+	}
+	if nx == 0 || ny == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(nx*ny)
+}
+
+// fakeContains reports whether a field's value, or one of its values, equals value.
+func fakeContains(field any, value any) bool {
+	if list, ok := field.([]any); ok {
+		for _, v := range list {
+			if fakeCompare(v, value) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	return field != nil && fakeCompare(field, value) == 0
+}
+
+// fakeCompare compares numbers, booleans, and strings, which are compared as times if
+// both are timestamps.
+func fakeCompare(a, b any) int {
+	switch x := a.(type) {
+	case float64:
+		y, _ := b.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case bool:
+		if y, _ := b.(bool); x == y {
+			return 0
+		}
+		return 1
+	case string:
+		y := fmt.Sprint(b)
+		tx, errX := time.Parse(time.RFC3339Nano, x)
+		ty, errY := time.Parse(time.RFC3339Nano, y)
+		if errX == nil && errY == nil {
+			return tx.Compare(ty)
+		}
+		return strings.Compare(x, y)
+	}
+	return 1
+}
+
+// fakeWildcard returns the regular expression of a wildcard pattern.
+func fakeWildcard(pattern string, caseInsensitive bool) *regexp.Regexp {
+	var expr strings.Builder
+	if caseInsensitive {
+		expr.WriteString("(?i)")
+	}
+	expr.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+func newTestClient(t *testing.T) (*fakeOpenSearch, *Client) {
+	f, server := newFakeOpenSearch(t)
+	client, err := NewClient(&config.OpenSearchConfig{
+		URL:         server.URL,
+		Username:    "admin",
+		Password:    "secret",
+		IndexPrefix: "test_",
+	})
+	require.NoError(t, err)
+	return f, client
+}
+
+func TestNewClient(t *testing.T) {
+	_, err := NewClient(&config.OpenSearchConfig{})
+	assert.Error(t, err, "the url is required")
+
+	client, err := NewClient(&config.OpenSearchConfig{URL: "https://localhost:9200/"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://localhost:9200", client.url)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	f, client := newTestClient(t)
+
+	body := map[string]any{"mappings": map[string]any{}}
+	require.NoError(t, client.createIndex(ctx, countersIndex, body))
+	require.NoError(t, client.createIndex(ctx, countersIndex, body), "existing indexes are kept")
+	assert.Contains(t, f.indexes, "test_counters")
+	assert.Contains(t, f.users, "admin")
+
+	first, err := client.nextIDs(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first)
+	next, err := client.nextIDs(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), next)
+
+	rec, err := get[map[string]any](ctx, client, countersIndex, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+
+	require.NoError(t, client.create(ctx, countersIndex, "a", map[string]any{"value": 1}))
+	err = client.create(ctx, countersIndex, "a", map[string]any{"value": 2})
+	assert.True(t, isConflict(err), "documents are only created once")
+
+	rec, err = get[map[string]any](ctx, client, countersIndex, "a")
+	require.NoError(t, err)
+	require.NoError(t, client.put(ctx, countersIndex, "a", map[string]any{"value": 3}, &rec.version))
+	err = client.put(ctx, countersIndex, "a", map[string]any{"value": 4}, &rec.version)
+	assert.True(t, isConflict(err), "a stale version is rejected")
+
+	var attempts int
+	err = retryOnConflict(func() error {
+		attempts++
+		return client.create(ctx, countersIndex, "a", nil)
+	})
+	assert.True(t, isConflict(err))
+	assert.Equal(t, maxConflictRetries+1, attempts)
+
+	_, err = client.search(ctx, messagesIndex, map[string]any{})
+	assert.True(t, hasStatus(err, http.StatusNotFound))
+}
@@ -0,0 +1,84 @@
+package opensearch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+	"github.com/getzep/zep/pkg/store/storetest"
+)
+
+type recordingPublisher struct {
+	topics []models.TaskTopic
+}
+
+func (p *recordingPublisher) Publish(taskType models.TaskTopic, _ map[string]string, _ any) error {
+	p.topics = append(p.topics, taskType)
+	return nil
+}
+
+func (p *recordingPublisher) PublishMessage(_ map[string]string, _ []models.MessageTask) error {
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+// testEmbeddingDimensions are the dimensions of the fake embeddings of messages and
+// summaries.
+const testEmbeddingDimensions = 8
+
+// newTestMemoryStore returns a MemoryStore using a fake OpenSearch, whose users and legal
+// holds are kept in memory.
+func newTestMemoryStore(t *testing.T) (*fakeOpenSearch, *MemoryStore) {
+	cfg := &config.Config{}
+	cfg.LLM.Service = llms.FakeLLMService
+	embeddings := config.EmbeddingsConfig{
+		Service:    llms.FakeLLMService,
+		Dimensions: testEmbeddingDimensions,
+	}
+	cfg.Extractors.Messages.Embeddings = embeddings
+	cfg.Extractors.Messages.Summarizer.Embeddings = embeddings
+	llmClient, err := llms.NewLLMClient(context.Background(), cfg)
+	require.NoError(t, err)
+
+	db := inmemory.NewDB()
+	appState := &models.AppState{
+		Config:         cfg,
+		LLMClient:      llmClient,
+		TaskPublisher:  &recordingPublisher{},
+		UserStore:      inmemory.NewUserStore(db),
+		LegalHoldStore: inmemory.NewLegalHoldStore(db),
+	}
+
+	f, client := newTestClient(t)
+	ms, err := NewMemoryStore(appState, client)
+	require.NoError(t, err)
+	require.NoError(t, ms.CreateIndexes(context.Background()))
+	appState.UserStore = NewUserStore(appState.UserStore, ms)
+	appState.MemoryStore = ms
+	return f, ms
+}
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.RunMemoryStoreTests(t, func(t *testing.T) models.MemoryStore[any] {
+		_, ms := newTestMemoryStore(t)
+		return ms
+	})
+}
+
+func TestLegalHoldStoreConformance(t *testing.T) {
+	storetest.RunLegalHoldStoreTests(
+		t,
+		func(t *testing.T) (models.MemoryStore[any], models.UserStore, models.LegalHoldStore) {
+			_, ms := newTestMemoryStore(t)
+			return ms, ms.appState.UserStore, ms.appState.LegalHoldStore
+		},
+	)
+}
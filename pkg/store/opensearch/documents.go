@@ -0,0 +1,312 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// MaxLiteralLength is the length of the longest content matched by literal searches, the
+// most characters of the longest term a keyword field indexes.
+const MaxLiteralLength = 8191
+
+type sessionDoc struct {
+	UUID           uuid.UUID              `json:"uuid"`
+	ID             int64                  `json:"id"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	DeletedAt      *time.Time             `json:"deleted_at,omitempty"`
+	SessionID      string                 `json:"session_id"`
+	UserID         *string                `json:"user_id,omitempty"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"`
+	ExpiryWarnedAt *time.Time             `json:"expiry_warned_at,omitempty"`
+	Title          string                 `json:"title,omitempty"`
+	TaskState      *models.TaskState      `json:"task_state,omitempty"`
+	Episodes       []models.Episode       `json:"episodes,omitempty"`
+}
+
+func (d *sessionDoc) session() *models.Session {
+	return &models.Session{
+		UUID:      d.UUID,
+		ID:        d.ID,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+		DeletedAt: d.DeletedAt,
+		SessionID: d.SessionID,
+		Metadata:  d.Metadata,
+		UserID:    d.UserID,
+		ExpiresAt: d.ExpiresAt,
+		Title:     d.Title,
+		TaskState: d.TaskState,
+		Episodes:  d.Episodes,
+	}
+}
+
+// messageDoc is a message and its embedding. A session's messages are ordered by ID.
+type messageDoc struct {
+	UUID      uuid.UUID `json:"uuid"`
+	ID        int64     `json:"id"`
+	SessionID string    `json:"session_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Role      string    `json:"role"`
+	// RoleKey is the lowercased role, which searches filter by.
+	RoleKey     string                 `json:"role_key"`
+	Content     string                 `json:"content"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	TokenCount  int                    `json:"token_count"`
+	ContentHash string                 `json:"content_hash"`
+	// Episode is the episode ID of the metadata, which searches filter by.
+	Episode   int         `json:"episode,omitempty"`
+	Embedding []float32   `json:"embedding,omitempty"`
+	Window    []uuid.UUID `json:"window,omitempty"`
+	Deleted   bool        `json:"deleted"`
+}
+
+func (d *messageDoc) message() models.Message {
+	return models.Message{
+		UUID:        d.UUID,
+		CreatedAt:   d.CreatedAt,
+		UpdatedAt:   d.UpdatedAt,
+		Role:        d.Role,
+		Content:     d.Content,
+		Metadata:    d.Metadata,
+		TokenCount:  d.TokenCount,
+		ContentHash: d.ContentHash,
+	}
+}
+
+// setMessage sets the fields of the document derived from its message.
+func (d *messageDoc) setMessage(message *models.Message) {
+	d.Role = message.Role
+	d.RoleKey = strings.ToLower(message.Role)
+	d.Content = message.Content
+	d.Metadata = message.Metadata
+	d.TokenCount = message.TokenCount
+	d.ContentHash = models.ContentHash(message.Content)
+	d.Episode = models.MessageEpisode(message)
+}
+
+type summaryDoc struct {
+	UUID             uuid.UUID              `json:"uuid"`
+	ID               int64                  `json:"id"`
+	SessionID        string                 `json:"session_id"`
+	CreatedAt        time.Time              `json:"created_at"`
+	Content          string                 `json:"content"`
+	SummaryPointUUID uuid.UUID              `json:"summary_point_uuid"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+	TokenCount       int                    `json:"token_count"`
+	Embedding        []float32              `json:"embedding,omitempty"`
+	Deleted          bool                   `json:"deleted"`
+}
+
+func (d *summaryDoc) summary() *models.Summary {
+	return &models.Summary{
+		UUID:             d.UUID,
+		CreatedAt:        d.CreatedAt,
+		Content:          d.Content,
+		SummaryPointUUID: d.SummaryPointUUID,
+		Metadata:         d.Metadata,
+		TokenCount:       d.TokenCount,
+	}
+}
+
+func decode[T any](hits []hit) ([]record[T], error) {
+	records := make([]record[T], len(hits))
+	for i := range hits {
+		if err := json.Unmarshal(hits[i].Source, &records[i].doc); err != nil {
+			return nil, fmt.Errorf("failed to decode document %s: %w", hits[i].ID, err)
+		}
+		records[i].version = hits[i].version
+	}
+	return records, nil
+}
+
+// CreateIndexes creates the store's indexes, unless they exist. Embeddings are indexed
+// as k-NN vectors of the configured dimensions and distance function, which can't be
+// changed once the indexes are created. Metadata isn't indexed, as its keys are arbitrary.
+func (ms *MemoryStore) CreateIndexes(ctx context.Context) error {
+	messageEmbeddings, err := knnVector(ms.appState, "message")
+	if err != nil {
+		return err
+	}
+	summaryEmbeddings, err := knnVector(ms.appState, "summary")
+	if err != nil {
+		return err
+	}
+	keyword := map[string]any{"type": "keyword"}
+	long := map[string]any{"type": "long"}
+	integer := map[string]any{"type": "integer"}
+	date := map[string]any{"type": "date"}
+	boolean := map[string]any{"type": "boolean"}
+	unindexed := map[string]any{"type": "object", "enabled": false}
+	// content is ranked by BM25, and matched literally by its raw keyword
+	content := map[string]any{
+		"type": "text",
+		"fields": map[string]any{
+			"raw": map[string]any{"type": "keyword", "ignore_above": MaxLiteralLength},
+		},
+	}
+
+	indexes := map[string]map[string]any{
+		sessionsIndex: {
+			"uuid":             keyword,
+			"id":               long,
+			"created_at":       date,
+			"updated_at":       date,
+			"deleted_at":       date,
+			"session_id":       keyword,
+			"user_id":          keyword,
+			"metadata":         unindexed,
+			"expires_at":       date,
+			"expiry_warned_at": date,
+			"title":            keyword,
+			"task_state":       unindexed,
+			"episodes":         unindexed,
+		},
+		messagesIndex: {
+			"uuid":         keyword,
+			"id":           long,
+			"session_id":   keyword,
+			"created_at":   date,
+			"updated_at":   date,
+			"role":         keyword,
+			"role_key":     keyword,
+			"content":      content,
+			"metadata":     unindexed,
+			"token_count":  integer,
+			"content_hash": keyword,
+			"episode":      integer,
+			"embedding":    messageEmbeddings,
+			"window":       keyword,
+			"deleted":      boolean,
+		},
+		summariesIndex: {
+			"uuid":               keyword,
+			"id":                 long,
+			"session_id":         keyword,
+			"created_at":         date,
+			"content":            content,
+			"summary_point_uuid": keyword,
+			"metadata":           unindexed,
+			"token_count":        integer,
+			"embedding":          summaryEmbeddings,
+			"deleted":            boolean,
+		},
+		countersIndex: {
+			"value": long,
+		},
+	}
+	for name, properties := range indexes {
+		err := ms.Client.createIndex(ctx, name, map[string]any{
+			"settings": map[string]any{"index": map[string]any{"knn": true}},
+			// fields that aren't mapped, such as those of newer versions, are stored
+			// without being indexed
+			"mappings": map[string]any{"dynamic": false, "properties": properties},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// knnVector returns the mapping of the embeddings of a document type.
+func knnVector(appState *models.AppState, documentType string) (map[string]any, error) {
+	model, err := llms.GetEmbeddingModel(appState, documentType)
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s embedding model: %w", documentType, err)
+	}
+	if model.Dimensions <= 0 {
+		return nil, fmt.Errorf("%s embedding dimensions must be set", documentType)
+	}
+	spaceType, err := spaceType(model.DistanceFunction)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"type":      "knn_vector",
+		"dimension": model.Dimensions,
+		// the Lucene engine filters during the search, rather than after it, so searches
+		// of a session's messages return k results
+		"method": map[string]any{"name": "hnsw", "engine": "lucene", "space_type": spaceType},
+	}, nil
+}
+
+// spaceType returns the k-NN space type of a distance function.
+func spaceType(df models.DistanceFunction) (string, error) {
+	switch df {
+	case models.DistanceFunctionCosine, "":
+		return "cosinesimil", nil
+	case models.DistanceFunctionDotProduct:
+		return "innerproduct", nil
+	case models.DistanceFunctionL2:
+		return "l2", nil
+	default:
+		return "", fmt.Errorf("invalid embedding distance function: %s", df)
+	}
+}
+
+func term(field string, value any) map[string]any {
+	return map[string]any{"term": map[string]any{field: value}}
+}
+
+func terms(field string, values any) map[string]any {
+	return map[string]any{"terms": map[string]any{field: values}}
+}
+
+func exists(field string) map[string]any {
+	return map[string]any{"exists": map[string]any{"field": field}}
+}
+
+// between matches values of a field within bounds, keyed by gt, gte, lt or lte.
+func between(field string, bounds map[string]any) map[string]any {
+	return map[string]any{"range": map[string]any{field: bounds}}
+}
+
+func not(clause map[string]any) map[string]any {
+	return map[string]any{"bool": map[string]any{"must_not": []map[string]any{clause}}}
+}
+
+// all matches the documents matching every clause, without scoring them.
+func all(clauses ...map[string]any) map[string]any {
+	return map[string]any{"bool": map[string]any{"filter": clauses}}
+}
+
+// sortBy sorts by the given fields, in order.
+func sortBy(fields ...string) []map[string]any {
+	sort := make([]map[string]any, 0, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		sort = append(sort, map[string]any{fields[i]: fields[i+1]})
+	}
+	return sort
+}
+
+var byID = sortBy("id", "asc")
+
+// undeletedSession matches sessions that aren't deleted.
+var undeletedSession = not(exists("deleted_at"))
+
+// sessionRecords matches the undeleted messages or summaries of a session.
+func sessionRecords(sessionID string, clauses ...map[string]any) map[string]any {
+	return all(append([]map[string]any{
+		term("session_id", sessionID),
+		term("deleted", false),
+	}, clauses...)...)
+}
+
+func uuidStrings(uuids []uuid.UUID) []string {
+	ids := make([]string, len(uuids))
+	for i := range uuids {
+		ids[i] = uuids[i].String()
+	}
+	return ids
+}
@@ -0,0 +1,563 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
+	"github.com/getzep/zep/pkg/store"
+)
+
+const (
+	DefaultMessageWindow      = 12
+	DefaultMemorySearchLimit  = 10
+	DefaultUserSearchSessions = 5
+)
+
+var _ models.MemoryStore[*Client] = &MemoryStore{}
+
+// MemoryStore is a models.MemoryStore backed by OpenSearch. Users and legal holds remain
+// in the stores of the AppState: a session's user must exist in its UserStore, and
+// sessions held by its LegalHoldStore aren't deleted.
+type MemoryStore struct {
+	store.BaseMemoryStore[*Client]
+	appState *models.AppState
+}
+
+// NewMemoryStore returns a MemoryStore using client. appState must have a Config.
+func NewMemoryStore(appState *models.AppState, client *Client) (*MemoryStore, error) {
+	if appState == nil || appState.Config == nil {
+		return nil, errors.New("appState config cannot be nil")
+	}
+	if client == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	return &MemoryStore{
+		BaseMemoryStore: store.BaseMemoryStore[*Client]{Client: client},
+		appState:        appState,
+	}, nil
+}
+
+func (ms *MemoryStore) GetSession(ctx context.Context, sessionID string) (*models.Session, error) {
+	rec, err := ms.getSession(ctx, sessionID, false)
+	if err != nil {
+		return nil, err
+	}
+	return rec.doc.session(), nil
+}
+
+// CreateSession creates a new session. Returns a models.ConflictError if the session
+// exists, including if it's deleted but not yet purged.
+func (ms *MemoryStore) CreateSession(
+	ctx context.Context,
+	session *models.CreateSessionRequest,
+) (*models.Session, error) {
+	if session.SessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	if session.UserID != nil && ms.appState.UserStore != nil {
+		_, err := ms.appState.UserStore.Get(ctx, *session.UserID)
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.NewValidationError(
+				"user does not exist with user_id: " + *session.UserID,
+			)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := ms.Client.nextIDs(ctx, 1)
+	if err != nil {
+		return nil, store.NewStorageError("failed to create session", err)
+	}
+	createdAt := now()
+	doc := &sessionDoc{
+		UUID:      uuid.New(),
+		ID:        id,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		SessionID: session.SessionID,
+		Metadata:  session.Metadata,
+		UserID:    session.UserID,
+		ExpiresAt: session.ExpiresAt,
+	}
+	err = ms.Client.create(ctx, sessionsIndex, session.SessionID, doc)
+	if isConflict(err) {
+		return nil, models.NewConflictError(
+			"session already exists with session_id: " + session.SessionID,
+		)
+	}
+	if err != nil {
+		return nil, store.NewStorageError("failed to create session", err)
+	}
+	return doc.session(), nil
+}
+
+// CreateSessions creates sessions one at a time, as OpenSearch has no transactions. A
+// session that can't be created is reported in its result.
+func (ms *MemoryStore) CreateSessions(
+	ctx context.Context,
+	sessions []models.CreateSessionRequest,
+) (*models.CreateSessionsResponse, error) {
+	response := &models.CreateSessionsResponse{
+		Results: make([]models.CreateSessionsResult, len(sessions)),
+	}
+	for i := range sessions {
+		result := &response.Results[i]
+		result.Index = i
+		result.SessionID = sessions[i].SessionID
+		session, err := ms.CreateSession(ctx, &sessions[i])
+		if err != nil {
+			result.Error = err.Error()
+			response.Failed++
+			continue
+		}
+		result.Session = session
+		response.Succeeded++
+	}
+	return response, nil
+}
+
+// UpdateSession updates a session's expiry and merges its metadata. As with the Postgres
+// store, a soft-deleted session is undeleted, but its messages and summaries are not.
+func (ms *MemoryStore) UpdateSession(
+	ctx context.Context,
+	session *models.UpdateSessionRequest,
+) (*models.Session, error) {
+	if session.SessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	return ms.updateSession(ctx, session.SessionID, true, func(doc *sessionDoc) error {
+		doc.DeletedAt = nil
+		doc.UpdatedAt = now()
+		if session.ExpiresAt != nil {
+			doc.ExpiresAt = session.ExpiresAt
+			doc.ExpiryWarnedAt = nil
+		}
+		if session.Metadata != nil {
+			doc.Metadata = mergeMetadata(doc.Metadata, session.Metadata, false)
+		}
+		return nil
+	})
+}
+
+func (ms *MemoryStore) PatchSessionMetadata(
+	ctx context.Context,
+	sessionID string,
+	patch models.MetadataPatch,
+) (*models.Session, error) {
+	return ms.updateSession(ctx, sessionID, false, func(doc *sessionDoc) error {
+		metadata, err := models.PatchMetadata(doc.Metadata, patch, false)
+		if err != nil {
+			return err
+		}
+		doc.Metadata = metadata
+		doc.UpdatedAt = now()
+		return nil
+	})
+}
+
+// DeleteSession soft deletes a session and its messages and summaries. Returns a
+// ConflictError if the session or its user is under legal hold.
+func (ms *MemoryStore) DeleteSession(ctx context.Context, sessionID string) error {
+	rec, err := ms.getSession(ctx, sessionID, false)
+	if err != nil {
+		return err
+	}
+	holds, err := ms.legalHolds(ctx)
+	if err != nil {
+		return err
+	}
+	if holds.sessionHeld(&rec.doc) {
+		return models.NewLegalHoldError(models.LegalHoldSubjectSession, sessionID)
+	}
+	_, err = ms.deleteSession(ctx, sessionID, now())
+	return err
+}
+
+// ListSessions returns up to limit undeleted sessions with an ID greater than cursor.
+func (ms *MemoryStore) ListSessions(
+	ctx context.Context,
+	cursor int64,
+	limit int,
+) ([]*models.Session, error) {
+	query := all(undeletedSession, between("id", map[string]any{"gt": cursor}))
+	var hits []hit
+	if limit > 0 {
+		response, err := ms.Client.search(ctx, sessionsIndex, map[string]any{
+			"query": query,
+			"sort":  byID,
+			"size":  limit,
+		})
+		if err != nil {
+			return nil, store.NewStorageError("failed to list sessions", err)
+		}
+		hits = response.Hits.Hits
+	} else {
+		var err error
+		if hits, err = ms.Client.searchAll(ctx, sessionsIndex, query, byID); err != nil {
+			return nil, store.NewStorageError("failed to list sessions", err)
+		}
+	}
+	return decodeSessions(hits)
+}
+
+// ListSessionsOrdered returns a page of undeleted sessions ordered by orderedBy, which is
+// one of id, created_at, updated_at, session_id, or user_id. Pages must end within the
+// index's max_result_window, 10,000 sessions by default.
+func (ms *MemoryStore) ListSessionsOrdered(
+	ctx context.Context,
+	pageNumber int,
+	pageSize int,
+	orderedBy string,
+	asc bool,
+) (*models.SessionListResponse, error) {
+	switch orderedBy {
+	case "":
+		orderedBy = "id"
+	case "id", "created_at", "updated_at", "session_id", "user_id":
+	default:
+		return nil, models.NewValidationError("invalid order by column: " + orderedBy)
+	}
+	order, missing := "asc", "_first"
+	if !asc {
+		order, missing = "desc", "_last"
+	}
+	// sessions without a user sort as if their user_id were empty, and ties are ordered
+	// by ID
+	sort := []map[string]any{
+		{orderedBy: map[string]any{"order": order, "missing": missing}},
+		{"id": order},
+	}
+	var hits []hit
+	var totalCount int
+	if pageSize > 0 {
+		response, err := ms.Client.search(ctx, sessionsIndex, map[string]any{
+			"query":            undeletedSession,
+			"sort":             sort,
+			"from":             max(pageNumber-1, 0) * pageSize,
+			"size":             pageSize,
+			"track_total_hits": true,
+		})
+		if err != nil {
+			return nil, store.NewStorageError("failed to list sessions", err)
+		}
+		hits, totalCount = response.Hits.Hits, response.Hits.Total.Value
+	} else {
+		var err error
+		if hits, err = ms.Client.searchAll(ctx, sessionsIndex, undeletedSession, sort); err != nil {
+			return nil, store.NewStorageError("failed to list sessions", err)
+		}
+		totalCount = len(hits)
+	}
+	sessions, err := decodeSessions(hits)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SessionListResponse{
+		Sessions:   sessions,
+		TotalCount: totalCount,
+		RowCount:   len(sessions),
+	}, nil
+}
+
+// ListExpiringSessions returns undeleted sessions expiring before the given time that
+// haven't had an expiry warning sent, skipping sessions under legal hold.
+func (ms *MemoryStore) ListExpiringSessions(
+	ctx context.Context,
+	before time.Time,
+) ([]*models.Session, error) {
+	records, err := ms.expiredSessions(ctx, before, not(exists("expiry_warned_at")))
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*models.Session, len(records))
+	for i := range records {
+		sessions[i] = records[i].doc.session()
+	}
+	return sessions, nil
+}
+
+func (ms *MemoryStore) MarkSessionsExpiryWarned(ctx context.Context, sessionIDs []string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	_, err := ms.Client.updateByQuery(
+		ctx,
+		sessionsIndex,
+		all(undeletedSession, terms("session_id", sessionIDs)),
+		map[string]any{"expiry_warned_at": now()},
+	)
+	if err != nil {
+		return store.NewStorageError("failed to mark sessions expiry warned", err)
+	}
+	return nil
+}
+
+// DeleteExpiredSessions soft deletes all sessions whose expiry has passed, along with
+// their messages and summaries, skipping sessions under legal hold. Returns the deleted
+// sessions.
+func (ms *MemoryStore) DeleteExpiredSessions(ctx context.Context) ([]*models.Session, error) {
+	deletedAt := now()
+	records, err := ms.expiredSessions(ctx, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*models.Session
+	for i := range records {
+		session, err := ms.deleteSession(ctx, records[i].doc.SessionID, deletedAt)
+		// the session was deleted concurrently
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (ms *MemoryStore) UpdateSessionTitle(ctx context.Context, sessionID string, title string) error {
+	_, err := ms.updateSession(ctx, sessionID, false, func(doc *sessionDoc) error {
+		doc.Title = title
+		return nil
+	})
+	return err
+}
+
+func (ms *MemoryStore) UpdateSessionTaskState(
+	ctx context.Context,
+	sessionID string,
+	state *models.TaskState,
+) error {
+	_, err := ms.updateSession(ctx, sessionID, false, func(doc *sessionDoc) error {
+		doc.TaskState = state
+		return nil
+	})
+	return err
+}
+
+func (ms *MemoryStore) UpdateSessionEpisodes(
+	ctx context.Context,
+	sessionID string,
+	episodes []models.Episode,
+) error {
+	_, err := ms.updateSession(ctx, sessionID, false, func(doc *sessionDoc) error {
+		doc.Episodes = episodes
+		return nil
+	})
+	return err
+}
+
+// PurgeDeleted hard deletes all soft-deleted sessions, messages and summaries.
+func (ms *MemoryStore) PurgeDeleted(ctx context.Context) error {
+	for index, query := range map[string]map[string]any{
+		messagesIndex:  term("deleted", true),
+		summariesIndex: term("deleted", true),
+		sessionsIndex:  exists("deleted_at"),
+	} {
+		if _, err := ms.Client.deleteByQuery(ctx, index, query); err != nil {
+			return store.NewStorageError("failed to purge deleted "+index, err)
+		}
+	}
+	return nil
+}
+
+// DeduplicateMessageEmbeddings is a no-op, as embedding deduplication is only supported
+// by the Postgres store.
+func (ms *MemoryStore) DeduplicateMessageEmbeddings(
+	_ context.Context,
+	_ time.Time,
+	_ float32,
+	_ models.DeduplicationScope,
+) (int, error) {
+	return 0, nil
+}
+
+func (ms *MemoryStore) Close() error {
+	return nil
+}
+
+// getSession returns a session, or a NotFoundError if it doesn't exist or, unless
+// includeDeleted is true, is deleted.
+func (ms *MemoryStore) getSession(
+	ctx context.Context,
+	sessionID string,
+	includeDeleted bool,
+) (*record[sessionDoc], error) {
+	if sessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	rec, err := get[sessionDoc](ctx, ms.Client, sessionsIndex, sessionID)
+	if err != nil {
+		return nil, store.NewStorageError("failed to get session", err)
+	}
+	if rec == nil || (rec.doc.DeletedAt != nil && !includeDeleted) {
+		return nil, models.NewNotFoundError("session " + sessionID)
+	}
+	return rec, nil
+}
+
+// updateSession applies update to a session and writes it back, reading the session
+// again if it was written concurrently.
+func (ms *MemoryStore) updateSession(
+	ctx context.Context,
+	sessionID string,
+	includeDeleted bool,
+	update func(doc *sessionDoc) error,
+) (*models.Session, error) {
+	var updated *sessionDoc
+	err := retryOnConflict(func() error {
+		rec, err := ms.getSession(ctx, sessionID, includeDeleted)
+		if err != nil {
+			return err
+		}
+		if err := update(&rec.doc); err != nil {
+			return err
+		}
+		if err := ms.Client.put(ctx, sessionsIndex, sessionID, &rec.doc, &rec.version); err != nil {
+			return err
+		}
+		updated = &rec.doc
+		return nil
+	})
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return nil, store.NewStorageError("failed to update session", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return updated.session(), nil
+}
+
+// deleteSession soft deletes an undeleted session and its messages and summaries,
+// returning the deleted session. Its messages and summaries are deleted first, so that
+// they're deleted by a retry if deleting the session fails.
+func (ms *MemoryStore) deleteSession(
+	ctx context.Context,
+	sessionID string,
+	deletedAt time.Time,
+) (*models.Session, error) {
+	fields := map[string]any{"deleted": true}
+	for _, index := range []string{messagesIndex, summariesIndex} {
+		_, err := ms.Client.updateByQuery(ctx, index, sessionRecords(sessionID), fields)
+		if err != nil {
+			return nil, store.NewStorageError("failed to delete session "+index, err)
+		}
+	}
+	return ms.updateSession(ctx, sessionID, false, func(doc *sessionDoc) error {
+		doc.DeletedAt = &deletedAt
+		return nil
+	})
+}
+
+// expiredSessions returns the undeleted sessions matching clauses that expire before the
+// given time, soonest first, skipping sessions under legal hold.
+func (ms *MemoryStore) expiredSessions(
+	ctx context.Context,
+	before time.Time,
+	clauses ...map[string]any,
+) ([]record[sessionDoc], error) {
+	holds, err := ms.legalHolds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := ms.Client.searchAll(
+		ctx,
+		sessionsIndex,
+		all(append(clauses, undeletedSession, between("expires_at", map[string]any{
+			"lte": before,
+		}))...),
+		sortBy("expires_at", "asc", "id", "asc"),
+	)
+	if err != nil {
+		return nil, store.NewStorageError("failed to list expired sessions", err)
+	}
+	records, err := decode[sessionDoc](hits)
+	if err != nil {
+		return nil, err
+	}
+	expired := records[:0]
+	for _, rec := range records {
+		if !holds.sessionHeld(&rec.doc) {
+			expired = append(expired, rec)
+		}
+	}
+	return expired, nil
+}
+
+// legalHolds are the subjects under legal hold, keyed by subject type and ID.
+type legalHolds map[[2]string]bool
+
+// legalHolds returns the subjects under legal hold, or none if the AppState has no
+// LegalHoldStore.
+func (ms *MemoryStore) legalHolds(ctx context.Context) (legalHolds, error) {
+	holds := legalHolds{}
+	if ms.appState.LegalHoldStore == nil {
+		return holds, nil
+	}
+	list, err := ms.appState.LegalHoldStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	for _, hold := range list {
+		holds[[2]string{hold.SubjectType, hold.SubjectID}] = true
+	}
+	return holds, nil
+}
+
+// checkSessionHold returns a ConflictError if the session is under legal hold, directly or
+// through its user. A session that doesn't exist isn't held.
+func (ms *MemoryStore) checkSessionHold(ctx context.Context, sessionID string) error {
+	rec, err := ms.getSession(ctx, sessionID, false)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	holds, err := ms.legalHolds(ctx)
+	if err != nil {
+		return err
+	}
+	if holds.sessionHeld(&rec.doc) {
+		return models.NewLegalHoldError(models.LegalHoldSubjectSession, sessionID)
+	}
+	return nil
+}
+
+// sessionHeld reports whether the session, or its user, is under legal hold.
+func (h legalHolds) sessionHeld(session *sessionDoc) bool {
+	if h[[2]string{models.LegalHoldSubjectSession, session.SessionID}] {
+		return true
+	}
+	return session.UserID != nil && h[[2]string{models.LegalHoldSubjectUser, *session.UserID}]
+}
+
+func decodeSessions(hits []hit) ([]*models.Session, error) {
+	records, err := decode[sessionDoc](hits)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*models.Session, len(records))
+	for i := range records {
+		sessions[i] = records[i].doc.session()
+	}
+	return sessions, nil
+}
+
+func (ms *MemoryStore) roles() *roles.Registry {
+	return roles.FromConfig(ms.appState.Config)
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,684 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store"
+)
+
+// GetMemory returns the most recent Summary and a list of messages for a given sessionID.
+// See models.MemoryStorer.
+func (ms *MemoryStore) GetMemory(
+	ctx context.Context,
+	sessionID string,
+	lastNMessages int,
+) (*models.Memory, error) {
+	if sessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	if lastNMessages < 0 {
+		return nil, models.NewValidationError("cannot specify negative lastNMessages")
+	}
+
+	summary, err := ms.GetSummary(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+
+	var records []record[messageDoc]
+	if lastNMessages > 0 {
+		records, err = ms.lastMessages(ctx, sessionID, lastNMessages)
+	} else {
+		// the messages since the summary point, including those of deleted messages
+		var summaryPointID int64
+		summaryPointID, err = ms.messageID(ctx, sessionID, summary.SummaryPointUUID)
+		if err == nil {
+			records, err = ms.lastMessages(
+				ctx,
+				sessionID,
+				ms.messageWindow(),
+				between("id", map[string]any{"gt": summaryPointID}),
+			)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	return &models.Memory{
+		Messages: messages(records),
+		Summary:  summary,
+	}, nil
+}
+
+// PutMemory stores the messages of a Memory for a given sessionID, creating the session if
+// it doesn't exist.
+func (ms *MemoryStore) PutMemory(
+	ctx context.Context,
+	sessionID string,
+	memoryMessages *models.Memory,
+	skipNotify bool,
+) error {
+	_, err := ms.UpdateSession(ctx, &models.UpdateSessionRequest{SessionID: sessionID})
+	if errors.Is(err, models.ErrNotFound) {
+		_, err = ms.CreateSession(ctx, &models.CreateSessionRequest{SessionID: sessionID})
+	}
+	if err != nil {
+		return err
+	}
+
+	messages, err := ms.createMessages(ctx, sessionID, memoryMessages.Messages)
+	if err != nil {
+		return fmt.Errorf("failed to put messages: %w", err)
+	}
+
+	publisher := ms.appState.TaskPublisher
+	if skipNotify || publisher == nil || len(messages) == 0 {
+		return nil
+	}
+
+	mt := make([]models.MessageTask, len(messages))
+	for i, message := range messages {
+		mt[i] = models.MessageTask{UUID: message.UUID}
+	}
+	metadata := models.WithTenantMetadata(ctx, map[string]string{"session_id": sessionID})
+	if err := publisher.PublishMessage(metadata, mt); err != nil {
+		return fmt.Errorf("failed to publish new messages %w", err)
+	}
+	if ms.appState.Config.Memory.MaxSessionMessages > 0 {
+		if err := publisher.Publish(models.MessageCompactorTopic, metadata, mt); err != nil {
+			return fmt.Errorf("failed to publish message compaction task %w", err)
+		}
+	}
+
+	return nil
+}
+
+// createMessages creates a batch of messages for a session, which may be soft deleted, as
+// with the Postgres store. Returns a ConflictError if a message with one of their UUIDs
+// exists.
+func (ms *MemoryStore) createMessages(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+) ([]models.Message, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	if _, err := ms.getSession(ctx, sessionID, true); err != nil {
+		return nil, err
+	}
+
+	firstID, err := ms.Client.nextIDs(ctx, len(messages))
+	if err != nil {
+		return nil, store.NewStorageError("failed to create messages", err)
+	}
+	createdAt := now()
+	created := make([]models.Message, len(messages))
+	ops := make([]bulkOp, len(messages))
+	for i, msg := range messages {
+		if msg.UUID == uuid.Nil {
+			msg.UUID = uuid.New()
+		}
+		doc := &messageDoc{
+			UUID:      msg.UUID,
+			ID:        firstID + int64(i),
+			SessionID: sessionID,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		}
+		doc.setMessage(&msg)
+		ops[i] = bulkOp{index: messagesIndex, id: msg.UUID.String(), create: true, doc: doc}
+		created[i] = doc.message()
+	}
+	err = ms.Client.bulk(ctx, ops)
+	if isConflict(err) {
+		return nil, models.NewConflictError(err.Error())
+	}
+	if err != nil {
+		return nil, store.NewStorageError("failed to create messages", err)
+	}
+	return created, nil
+}
+
+func (ms *MemoryStore) GetMessageList(
+	ctx context.Context,
+	sessionID string,
+	pageNumber int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	if sessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	var records []record[messageDoc]
+	var totalCount int
+	if pageSize > 0 {
+		response, err := ms.Client.search(ctx, messagesIndex, map[string]any{
+			"query":            sessionRecords(sessionID),
+			"sort":             byID,
+			"from":             max(pageNumber-1, 0) * pageSize,
+			"size":             pageSize,
+			"_source":          withoutEmbedding,
+			"track_total_hits": true,
+		})
+		if err != nil {
+			return nil, store.NewStorageError("failed to get messages", err)
+		}
+		if records, err = decode[messageDoc](response.Hits.Hits); err != nil {
+			return nil, err
+		}
+		totalCount = response.Hits.Total.Value
+	} else {
+		var err error
+		if records, err = ms.sessionMessages(ctx, sessionID, false); err != nil {
+			return nil, err
+		}
+		totalCount = len(records)
+	}
+	return messageList(records, totalCount), nil
+}
+
+// GetMatchingMessageList retrieves a page of the messages of a session matching the filter.
+// Expressions are matched by Go's regexp package, as OpenSearch's regular expressions
+// differ from those of Postgres.
+func (ms *MemoryStore) GetMatchingMessageList(
+	ctx context.Context,
+	sessionID string,
+	filter *models.MessageFilter,
+	pageNumber int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	records, err := ms.matchingMessages(ctx, sessionID, filter)
+	if err != nil {
+		return nil, err
+	}
+	return messageList(page(records, pageNumber, pageSize), len(records)), nil
+}
+
+// DeleteMatchingMessages deletes the messages of a session matching the filter.
+func (ms *MemoryStore) DeleteMatchingMessages(
+	ctx context.Context,
+	sessionID string,
+	filter *models.MessageFilter,
+) (int, error) {
+	records, err := ms.matchingMessages(ctx, sessionID, filter)
+	if err != nil {
+		return 0, err
+	}
+	if err := ms.checkSessionHold(ctx, sessionID); err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	uuids := make([]string, len(records))
+	for i := range records {
+		uuids[i] = records[i].doc.UUID.String()
+	}
+	if err := ms.pruneMessages(ctx, sessionID, false, terms("uuid", uuids)); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+// matchingMessages returns the session's messages whose content matches the filter's
+// regular expression, oldest first.
+func (ms *MemoryStore) matchingMessages(
+	ctx context.Context,
+	sessionID string,
+	filter *models.MessageFilter,
+) ([]record[messageDoc], error) {
+	if sessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	if err := filter.Validate(ms.appState.Config.Memory.MessageRegex.MaxComplexity); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(filter.ContentRegex)
+	if err != nil {
+		return nil, models.NewValidationError("invalid content_regex: " + err.Error())
+	}
+	records, err := ms.sessionMessages(ctx, sessionID, false)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(records, func(rec record[messageDoc]) bool {
+		return !re.MatchString(rec.doc.Content)
+	}), nil
+}
+
+// GetCompactionCandidates returns the oldest uncompacted messages in excess of
+// maxMessages, along with the subset of these that are more recent than the current
+// SummaryPoint.
+func (ms *MemoryStore) GetCompactionCandidates(
+	ctx context.Context,
+	sessionID string,
+	maxMessages int,
+) (*models.CompactionCandidates, error) {
+	records, err := ms.sessionMessages(ctx, sessionID, false)
+	if err != nil {
+		return nil, err
+	}
+	records = slices.DeleteFunc(records, func(rec record[messageDoc]) bool {
+		return rec.doc.Content == ""
+	})
+	if len(records) <= maxMessages {
+		return &models.CompactionCandidates{}, nil
+	}
+	records = records[:len(records)-maxMessages]
+
+	summary, err := ms.GetSummary(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	summaryPointID, err := ms.messageID(ctx, sessionID, summary.SummaryPointUUID)
+	if err != nil {
+		return nil, err
+	}
+	var unsummarized []record[messageDoc]
+	for i := range records {
+		if records[i].doc.ID > summaryPointID {
+			unsummarized = records[i:]
+			break
+		}
+	}
+
+	return &models.CompactionCandidates{
+		Messages:     messages(records),
+		Unsummarized: messages(unsummarized),
+	}, nil
+}
+
+// CompactMessages prunes the content of all messages up to and including throughUUID. If
+// retainEmbeddings is false, the messages and their embeddings are deleted. A session
+// under legal hold isn't compacted, and a ConflictError is returned.
+func (ms *MemoryStore) CompactMessages(
+	ctx context.Context,
+	sessionID string,
+	throughUUID uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	if throughUUID == uuid.Nil {
+		return errors.New("message UUID cannot be nil")
+	}
+	id, err := ms.messageID(ctx, sessionID, throughUUID)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		return models.NewNotFoundError("message " + throughUUID.String())
+	}
+	if err := ms.checkSessionHold(ctx, sessionID); err != nil {
+		return err
+	}
+	return ms.pruneMessages(
+		ctx,
+		sessionID,
+		retainEmbeddings,
+		between("id", map[string]any{"lte": id}),
+	)
+}
+
+// PruneMessages prunes the content of the given messages, as CompactMessages does.
+func (ms *MemoryStore) PruneMessages(
+	ctx context.Context,
+	sessionID string,
+	messageUUIDs []uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	if len(messageUUIDs) == 0 {
+		return nil
+	}
+	if err := ms.checkSessionHold(ctx, sessionID); err != nil {
+		return err
+	}
+	return ms.pruneMessages(
+		ctx,
+		sessionID,
+		retainEmbeddings,
+		terms("uuid", uuidStrings(messageUUIDs)),
+	)
+}
+
+// pruneMessages prunes the content of the session's messages matching clauses, marking
+// them as compacted. If retainEmbeddings is false, the messages and their embeddings are
+// deleted.
+func (ms *MemoryStore) pruneMessages(
+	ctx context.Context,
+	sessionID string,
+	retainEmbeddings bool,
+	clauses ...map[string]any,
+) error {
+	if retainEmbeddings {
+		return ms.compactMessages(ctx, sessionID, clauses...)
+	}
+	_, err := ms.Client.updateByQuery(
+		ctx,
+		messagesIndex,
+		sessionRecords(sessionID, clauses...),
+		map[string]any{"deleted": true, "embedding": nil},
+	)
+	if err != nil {
+		return store.NewStorageError("failed to prune messages", err)
+	}
+	return nil
+}
+
+// compactMessages blanks the content of the session's messages matching clauses, keeping
+// their embeddings, and marks them as compacted in their metadata.
+func (ms *MemoryStore) compactMessages(
+	ctx context.Context,
+	sessionID string,
+	clauses ...map[string]any,
+) error {
+	records, err := ms.sessionMessages(ctx, sessionID, false, clauses...)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	uuids := make([]uuid.UUID, len(records))
+	for i := range records {
+		uuids[i] = records[i].doc.UUID
+	}
+	err = ms.updateMessages(ctx, sessionID, uuids, func(docs []*messageDoc) {
+		for _, doc := range docs {
+			doc.Content = ""
+			doc.ContentHash = models.ContentHash("")
+			doc.TokenCount = 0
+			doc.Metadata = mergeMetadata(doc.Metadata, models.CompactedMessageMetadata(), true)
+		}
+	})
+	// messages deleted since they were read needn't be compacted
+	if errors.Is(err, models.ErrNotFound) {
+		return ms.compactMessages(ctx, sessionID, clauses...)
+	}
+	return err
+}
+
+// GetMessagesByUUID returns the session's messages with the given UUIDs, in order of
+// creation.
+func (ms *MemoryStore) GetMessagesByUUID(
+	ctx context.Context,
+	sessionID string,
+	uuids []uuid.UUID,
+) ([]models.Message, error) {
+	if len(uuids) == 0 {
+		return []models.Message{}, nil
+	}
+	records, err := ms.sessionMessages(ctx, sessionID, false, terms("uuid", uuidStrings(uuids)))
+	if err != nil {
+		return nil, err
+	}
+	return messages(records), nil
+}
+
+// UpdateMessages updates a batch of messages by their UUIDs. Metadata is deep merged. No
+// messages are updated if any of them don't exist.
+func (ms *MemoryStore) UpdateMessages(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+	isPrivileged bool,
+	includeContent bool,
+) error {
+	uuids := make([]uuid.UUID, len(messages))
+	for i, msg := range messages {
+		if msg.UUID == uuid.Nil {
+			return errors.New("message UUID cannot be nil")
+		}
+		uuids[i] = msg.UUID
+	}
+	return ms.updateMessages(ctx, sessionID, uuids, func(docs []*messageDoc) {
+		updatedAt := now()
+		for i, msg := range messages {
+			message := docs[i].message()
+			message.TokenCount = msg.TokenCount
+			if includeContent {
+				message.Role = msg.Role
+				message.Content = msg.Content
+			}
+			if msg.Metadata != nil {
+				message.Metadata = mergeMetadata(message.Metadata, msg.Metadata, isPrivileged)
+			}
+			docs[i].setMessage(&message)
+			docs[i].UpdatedAt = updatedAt
+		}
+	})
+}
+
+// CreateMessageEmbeddings stores embeddings for the session's messages.
+func (ms *MemoryStore) CreateMessageEmbeddings(
+	ctx context.Context,
+	sessionID string,
+	embeddings []models.TextData,
+) error {
+	if len(embeddings) == 0 {
+		return errors.New("no embeddings received")
+	}
+	uuids := make([]uuid.UUID, len(embeddings))
+	for i, e := range embeddings {
+		if err := ms.checkDimensions("message", e.Embedding); err != nil {
+			return err
+		}
+		uuids[i] = e.TextUUID
+	}
+	return ms.updateMessages(ctx, sessionID, uuids, func(docs []*messageDoc) {
+		for i, e := range embeddings {
+			docs[i].Embedding = e.Embedding
+			docs[i].Window = e.Window
+		}
+	})
+}
+
+// GetMessageEmbeddings returns all message embeddings for the session.
+func (ms *MemoryStore) GetMessageEmbeddings(
+	ctx context.Context,
+	sessionID string,
+) ([]models.TextData, error) {
+	records, err := ms.sessionMessages(ctx, sessionID, true)
+	if err != nil {
+		return nil, err
+	}
+	var embeddings []models.TextData
+	for _, rec := range records {
+		if rec.doc.Embedding != nil {
+			embeddings = append(embeddings, models.TextData{
+				TextUUID:  rec.doc.UUID,
+				Text:      rec.doc.Content,
+				Embedding: rec.doc.Embedding,
+				Window:    rec.doc.Window,
+			})
+		}
+	}
+	return embeddings, nil
+}
+
+// updateMessages applies update to the session's messages with the given UUIDs, in order,
+// and writes them back, reading them again if any was written concurrently. Returns a
+// NotFoundError, updating nothing, if any of the messages doesn't exist.
+func (ms *MemoryStore) updateMessages(
+	ctx context.Context,
+	sessionID string,
+	uuids []uuid.UUID,
+	update func(docs []*messageDoc),
+) error {
+	err := retryOnConflict(func() error {
+		records, err := ms.sessionMessages(ctx, sessionID, true, terms("uuid", uuidStrings(uuids)))
+		if err != nil {
+			return err
+		}
+		byUUID := make(map[uuid.UUID]*record[messageDoc], len(records))
+		for i := range records {
+			byUUID[records[i].doc.UUID] = &records[i]
+		}
+		docs := make([]*messageDoc, len(uuids))
+		for i, u := range uuids {
+			rec, ok := byUUID[u]
+			if !ok {
+				return models.NewNotFoundError("message " + u.String())
+			}
+			docs[i] = &rec.doc
+		}
+		update(docs)
+
+		ops := make([]bulkOp, 0, len(records))
+		for i := range records {
+			ops = append(ops, bulkOp{
+				index:   messagesIndex,
+				id:      records[i].doc.UUID.String(),
+				doc:     &records[i].doc,
+				version: &records[i].version,
+			})
+		}
+		return ms.Client.bulk(ctx, ops)
+	})
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return store.NewStorageError("failed to update messages", err)
+	}
+	return err
+}
+
+// sessionMessages returns the session's undeleted messages matching clauses, oldest
+// first, with their embeddings if withEmbeddings is true.
+func (ms *MemoryStore) sessionMessages(
+	ctx context.Context,
+	sessionID string,
+	withEmbeddings bool,
+	clauses ...map[string]any,
+) ([]record[messageDoc], error) {
+	if sessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	var excludes []string
+	if !withEmbeddings {
+		excludes = []string{"embedding"}
+	}
+	hits, err := ms.Client.searchAll(
+		ctx,
+		messagesIndex,
+		sessionRecords(sessionID, clauses...),
+		byID,
+		excludes...,
+	)
+	if err != nil {
+		return nil, store.NewStorageError("failed to get messages", err)
+	}
+	return decode[messageDoc](hits)
+}
+
+// lastMessages returns the last n of the session's undeleted messages matching clauses,
+// oldest first.
+func (ms *MemoryStore) lastMessages(
+	ctx context.Context,
+	sessionID string,
+	n int,
+	clauses ...map[string]any,
+) ([]record[messageDoc], error) {
+	response, err := ms.Client.search(ctx, messagesIndex, map[string]any{
+		"query":   sessionRecords(sessionID, clauses...),
+		"sort":    sortBy("id", "desc"),
+		"size":    n,
+		"_source": withoutEmbedding,
+	})
+	if err != nil {
+		return nil, store.NewStorageError("failed to get messages", err)
+	}
+	records, err := decode[messageDoc](response.Hits.Hits)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(records)
+	return records, nil
+}
+
+// messageID returns the ID of the session's message with the given UUID, including
+// deleted messages, or 0 if the message doesn't exist.
+func (ms *MemoryStore) messageID(
+	ctx context.Context,
+	sessionID string,
+	messageUUID uuid.UUID,
+) (int64, error) {
+	if messageUUID == uuid.Nil {
+		return 0, nil
+	}
+	rec, err := get[messageDoc](ctx, ms.Client, messagesIndex, messageUUID.String())
+	if err != nil {
+		return 0, store.NewStorageError("failed to get message", err)
+	}
+	if rec == nil || rec.doc.SessionID != sessionID {
+		return 0, nil
+	}
+	return rec.doc.ID, nil
+}
+
+// checkDimensions returns an EmbeddingMismatchError if the embedding doesn't have the
+// dimensions of the document type's embedding model, which its index is mapped with.
+func (ms *MemoryStore) checkDimensions(documentType string, embedding []float32) error {
+	model, err := llms.GetEmbeddingModel(ms.appState, documentType)
+	if err != nil {
+		return store.NewStorageError("failed to get "+documentType+" embedding model", err)
+	}
+	if len(embedding) != model.Dimensions {
+		return store.NewEmbeddingMismatchError(fmt.Errorf(
+			"%s embedding has %d dimensions, expected %d",
+			documentType,
+			len(embedding),
+			model.Dimensions,
+		))
+	}
+	return nil
+}
+
+func (ms *MemoryStore) messageWindow() int {
+	if ms.appState.Config.Memory.MessageWindow > 0 {
+		return ms.appState.Config.Memory.MessageWindow
+	}
+	return DefaultMessageWindow
+}
+
+// withoutEmbedding leaves embeddings out of the documents returned by a search.
+var withoutEmbedding = map[string]any{"excludes": []string{"embedding"}}
+
+func messages(records []record[messageDoc]) []models.Message {
+	messages := make([]models.Message, len(records))
+	for i := range records {
+		messages[i] = records[i].doc.message()
+	}
+	return messages
+}
+
+func messageList(records []record[messageDoc], totalCount int) *models.MessageListResponse {
+	if len(records) == 0 {
+		return &models.MessageListResponse{Messages: []models.Message{}}
+	}
+	return &models.MessageListResponse{
+		Messages:   messages(records),
+		TotalCount: totalCount,
+		RowCount:   len(records),
+	}
+}
+
+// page returns the items on the given page, starting at 1. If pageSize is 0, all items
+// are returned.
+func page[T any](items []T, pageNumber int, pageSize int) []T {
+	if pageSize <= 0 {
+		return items
+	}
+	offset := max(pageNumber-1, 0) * pageSize
+	if offset >= len(items) {
+		return nil
+	}
+	return items[offset:min(offset+pageSize, len(items))]
+}
@@ -0,0 +1,28 @@
+package opensearch
+
+// mergeMetadata deep merges patch into current, as the Postgres store's jsonb_deep_merge
+// does: maps are merged key by key, and any other value in patch replaces the current
+// value. If isPrivileged is false, the top-level `system` key of patch is ignored.
+// Documents are decoded for each update, so current is modified in place.
+func mergeMetadata(
+	current map[string]interface{},
+	patch map[string]interface{},
+	isPrivileged bool,
+) map[string]interface{} {
+	if current == nil {
+		current = make(map[string]interface{}, len(patch))
+	}
+	for k, v := range patch {
+		if k == "system" && !isPrivileged {
+			continue
+		}
+		currentMap, currentIsMap := current[k].(map[string]interface{})
+		patchMap, patchIsMap := v.(map[string]interface{})
+		if currentIsMap && patchIsMap {
+			current[k] = mergeMetadata(currentMap, patchMap, true)
+			continue
+		}
+		current[k] = v
+	}
+	return current
+}
@@ -0,0 +1,614 @@
+package opensearch
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/search"
+	"github.com/getzep/zep/pkg/store"
+)
+
+// The candidates of MMR and hybrid searches, and the constant of Reciprocal Rank Fusion,
+// are those of the Postgres store, so that both stores rank alike.
+const (
+	DefaultMMRMultiplier    = 2
+	DefaultMMRLambda        = 0.5
+	DefaultHybridMultiplier = 2
+	RRFRankConstant         = 60
+)
+
+// SearchMemory searches a session's messages or summaries by the similarity of their
+// embeddings to the query's. Candidates are found by the k-NN index, or by scoring every
+// embedding if query.Exact is set, and ranked with their importance and recency boosts.
+// Hybrid searches fuse that ranking with BM25's, and literal searches match content
+// containing the text, ignoring case. Metadata filters and cursors are only supported by
+// the Postgres store.
+func (ms *MemoryStore) SearchMemory(
+	ctx context.Context,
+	sessionID string,
+	query *models.MemorySearchPayload,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	if query == nil || query.Text == "" {
+		return nil, models.NewValidationError("empty query")
+	}
+	if len(query.Metadata) > 0 {
+		return nil, models.NewValidationError(
+			"metadata filters are not supported by the opensearch store",
+		)
+	}
+	if err := query.ValidateSearchType(); err != nil {
+		return nil, err
+	}
+	if err := query.ValidateRecency(); err != nil {
+		return nil, err
+	}
+	if query.Cursor != "" {
+		return nil, models.NewValidationError("searches can't be paged by the opensearch store")
+	}
+	if limit == 0 {
+		limit = DefaultMemorySearchLimit
+	}
+
+	// literal searches match the text itself, so it isn't embedded
+	var queryEmbedding []float32
+	if query.SearchType != models.SearchTypeLiteral {
+		var err error
+		if queryEmbedding, err = ms.embedQuery(ctx, query.Text); err != nil {
+			return nil, err
+		}
+	}
+	return ms.searchSession(ctx, sessionID, query, queryEmbedding, limit)
+}
+
+// SearchUserMemory searches each of the user's sessions, as the OpenSearch store has no
+// session embeddings, and keeps the results of the sessions with the best matches.
+func (ms *MemoryStore) SearchUserMemory(
+	ctx context.Context,
+	userID string,
+	query *models.MemorySearchPayload,
+	sessions int,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	if query == nil {
+		return nil, models.NewValidationError("empty query")
+	}
+	if err := query.ValidateUserSearch(); err != nil {
+		return nil, err
+	}
+	if err := query.ValidateRecency(); err != nil {
+		return nil, err
+	}
+	if len(query.Metadata) > 0 {
+		return nil, models.NewValidationError(
+			"metadata filters are not supported by the opensearch store",
+		)
+	}
+	if sessions == 0 {
+		sessions = DefaultUserSearchSessions
+	}
+	if limit == 0 {
+		limit = DefaultMemorySearchLimit
+	}
+
+	userSessions, err := ms.userSessions(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(userSessions) == 0 {
+		return []models.MemorySearchResult{}, nil
+	}
+	queryEmbedding, err := ms.embedQuery(ctx, query.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	// sessions are ranked by their best result, which is their first
+	var bySession [][]models.MemorySearchResult
+	for _, rec := range userSessions {
+		sessionID := rec.doc.SessionID
+		results, err := ms.searchSession(ctx, sessionID, query, queryEmbedding, limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			continue
+		}
+		for i := range results {
+			results[i].SessionID = sessionID
+		}
+		bySession = append(bySession, results)
+	}
+	sort.SliceStable(bySession, func(i, j int) bool {
+		return bySession[i][0].RankScore > bySession[j][0].RankScore
+	})
+	if len(bySession) > sessions {
+		bySession = bySession[:sessions]
+	}
+
+	results := []models.MemorySearchResult{}
+	for _, sessionResults := range bySession {
+		results = append(results, sessionResults...)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RankScore > results[j].RankScore
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	for i := range results {
+		if results[i].Explanation != nil {
+			results[i].Explanation.Rank = i + 1
+		}
+	}
+	return results, nil
+}
+
+// searchSession runs a validated memory search of a session, with the embedding of the
+// query's text unless the search is literal.
+func (ms *MemoryStore) searchSession(
+	ctx context.Context,
+	sessionID string,
+	query *models.MemorySearchPayload,
+	queryEmbedding []float32,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	index, filter, err := ms.searchFilter(sessionID, query)
+	if err != nil {
+		return nil, err
+	}
+	documentType := "message"
+	if index == summariesIndex {
+		documentType = "summary"
+	}
+	model, err := llms.GetEmbeddingModel(ms.appState, documentType)
+	if err != nil {
+		return nil, store.NewStorageError("failed to get "+documentType+" embedding model", err)
+	}
+	df := model.DistanceFunction
+
+	// MMR and hybrid searches rank more candidates than the limit, to rerank or fuse
+	k := limit
+	switch query.SearchType {
+	case models.SearchTypeHybrid:
+		k = max(limit*DefaultHybridMultiplier, 10)
+	case models.SearchTypeMMR:
+		k = max(limit*DefaultMMRMultiplier, 10)
+	}
+
+	literal := query.SearchType == models.SearchTypeLiteral
+	var request map[string]any
+	if literal {
+		// literal matches are equally relevant, so the most recent come first
+		request = map[string]any{
+			"query": all(filter, map[string]any{"wildcard": map[string]any{
+				"content.raw": map[string]any{
+					"value":            "*" + escapeWildcard(query.Text) + "*",
+					"case_insensitive": true,
+				},
+			}}),
+			"sort": sortBy("created_at", "desc", "uuid", "desc"),
+			"size": limit,
+		}
+	} else {
+		request, err = knnRequest(filter, queryEmbedding, df, k, query.Exact)
+		if err != nil {
+			return nil, err
+		}
+	}
+	results, err := ms.searchResults(ctx, index, request, queryEmbedding, df, literal, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Messages are boosted by their importance and recency, if weighted. Ties are ordered
+	// by UUID, as they are by the Postgres store.
+	weight := ms.appState.Config.Memory.Importance.SearchWeight
+	searchedAt := now()
+	rank := func(r *models.MemorySearchResult) (float64, float64, float64) {
+		if r.Message == nil {
+			return r.Dist, 0, 0
+		}
+		var boost float64
+		if importance, ok := models.MessageImportance(r.Message); ok && weight > 0 {
+			boost = weight * importance
+		}
+		return r.Dist, boost, query.RecencyBoost(r.Message.CreatedAt, searchedAt)
+	}
+	for i := range results {
+		dist, boost, recency := rank(&results[i])
+		results[i].RankScore = dist + boost + recency
+	}
+	if !literal {
+		sortResults(results)
+	}
+
+	var fusedScores []float64
+	switch {
+	case query.SearchType == models.SearchTypeHybrid:
+		textResults, err := ms.searchResults(ctx, index, map[string]any{
+			"query": map[string]any{"bool": map[string]any{
+				"must":   []map[string]any{{"match": map[string]any{"content": query.Text}}},
+				"filter": []map[string]any{filter},
+			}},
+			"size": k,
+		}, queryEmbedding, df, false, query)
+		if err != nil {
+			return nil, err
+		}
+		for i := range textResults {
+			dist, boost, recency := rank(&textResults[i])
+			textResults[i].RankScore = dist + boost + recency
+		}
+		results, fusedScores = fuseRankings(limit, results, textResults)
+	case query.SearchType != models.SearchTypeMMR && len(results) > limit:
+		results = results[:limit]
+	}
+
+	if query.Explain {
+		for i := range results {
+			dist, boost, recency := rank(&results[i])
+			results[i].Explanation = &models.SearchExplanation{
+				VectorScore:     dist,
+				ImportanceBoost: boost,
+				RecencyBoost:    recency,
+				Score:           results[i].RankScore,
+				Rank:            i + 1,
+			}
+		}
+		for i, score := range fusedScores {
+			results[i].Explanation.Score = score
+		}
+	}
+
+	if query.SearchType == models.SearchTypeMMR {
+		lambda := query.MMRLambda
+		if lambda == 0 {
+			lambda = ms.appState.Config.Memory.MMRLambda
+		}
+		if lambda <= 0 || lambda > 1 {
+			lambda = DefaultMMRLambda
+		}
+		started := time.Now()
+		results, err = rerankMMR(results, queryEmbedding, lambda, limit)
+		if err != nil {
+			return nil, err
+		}
+		models.SearchDebugFromContext(ctx).AddRerank(time.Since(started))
+	}
+
+	if err := ms.loadResultWindows(ctx, sessionID, results); err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []models.MemorySearchResult{}
+	}
+	return results, nil
+}
+
+// searchFilter returns the index a search of a session searches, and the filter of the
+// documents it matches.
+func (ms *MemoryStore) searchFilter(
+	sessionID string,
+	query *models.MemorySearchPayload,
+) (string, map[string]any, error) {
+	switch query.SearchScope {
+	case models.SearchScopeMessages, "":
+		registry := ms.roles()
+		var clauses []map[string]any
+		if unsearchable := registry.Unsearchable(); len(unsearchable) > 0 {
+			clauses = append(clauses, not(terms("role_key", unsearchable)))
+		}
+		if len(query.Roles) > 0 {
+			clauses = append(clauses, terms("role_key", registry.Matching(query.Roles)))
+		}
+		if query.Episode != 0 {
+			clauses = append(clauses, term("episode", query.Episode))
+		}
+		return messagesIndex, sessionRecords(sessionID, clauses...), nil
+	case models.SearchScopeSummary:
+		if query.Episode != 0 {
+			return "", nil, models.NewValidationError("episode can only filter message searches")
+		}
+		if len(query.Roles) > 0 {
+			return "", nil, models.NewValidationError("roles can only filter message searches")
+		}
+		return summariesIndex, sessionRecords(sessionID), nil
+	default:
+		return "", nil, models.NewValidationError("invalid search scope")
+	}
+}
+
+// knnRequest returns a search of the k embeddings most similar to the query's matching
+// filter, by the distance function. Exact searches score every embedding, rather than
+// traversing the k-NN graph.
+func knnRequest(
+	filter map[string]any,
+	queryEmbedding []float32,
+	df models.DistanceFunction,
+	k int,
+	exact bool,
+) (map[string]any, error) {
+	if !exact {
+		return map[string]any{
+			"query": map[string]any{"knn": map[string]any{"embedding": map[string]any{
+				"vector": queryEmbedding,
+				"k":      k,
+				"filter": filter,
+			}}},
+			"size": k,
+		}, nil
+	}
+	spaceType, err := spaceType(df)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"query": map[string]any{"script_score": map[string]any{
+			"query": all(filter, exists("embedding")),
+			"script": map[string]any{
+				"source": "knn_score",
+				"lang":   "knn",
+				"params": map[string]any{
+					"field":       "embedding",
+					"query_value": queryEmbedding,
+					"space_type":  spaceType,
+				},
+			},
+		}},
+		"size": k,
+	}, nil
+}
+
+// searchResults runs a search of messages or summaries and returns its hits as results,
+// in order. Unless the search is literal, each result's Dist is the similarity of its
+// embedding to the query's, as the Postgres store scores it, and results scoring less
+// than the query's MinScore are left out.
+func (ms *MemoryStore) searchResults(
+	ctx context.Context,
+	index string,
+	request map[string]any,
+	queryEmbedding []float32,
+	df models.DistanceFunction,
+	literal bool,
+	query *models.MemorySearchPayload,
+) ([]models.MemorySearchResult, error) {
+	response, err := ms.Client.search(ctx, index, request)
+	if err != nil {
+		return nil, store.NewStorageError("memory search failed", err)
+	}
+	hits := response.Hits.Hits
+
+	results := make([]models.MemorySearchResult, 0, len(hits))
+	if index == summariesIndex {
+		records, err := decode[summaryDoc](hits)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			summary := rec.doc.summary()
+			results = append(results, models.MemorySearchResult{
+				Summary:   summary,
+				Metadata:  summary.Metadata,
+				Embedding: rec.doc.Embedding,
+			})
+		}
+	} else {
+		records, err := decode[messageDoc](hits)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			message := rec.doc.message()
+			results = append(results, models.MemorySearchResult{
+				Message:     &message,
+				Metadata:    message.Metadata,
+				Embedding:   rec.doc.Embedding,
+				WindowUUIDs: rec.doc.Window,
+			})
+		}
+	}
+
+	if literal {
+		for i := range results {
+			results[i].Dist = 1
+		}
+		return results, nil
+	}
+	for i := range results {
+		results[i].Dist = similarity(df, queryEmbedding, results[i].Embedding)
+	}
+	if query.MinScore != 0 {
+		results = slices.DeleteFunc(results, func(r models.MemorySearchResult) bool {
+			return r.Dist < query.MinScore
+		})
+	}
+	return results, nil
+}
+
+// loadResultWindows sets the Window of each message result embedded with other messages.
+// Deleted messages are left out of windows.
+func (ms *MemoryStore) loadResultWindows(
+	ctx context.Context,
+	sessionID string,
+	results []models.MemorySearchResult,
+) error {
+	var uuids []uuid.UUID
+	for _, r := range results {
+		uuids = append(uuids, r.WindowUUIDs...)
+	}
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	records, err := ms.sessionMessages(ctx, sessionID, false, terms("uuid", uuidStrings(uuids)))
+	if err != nil {
+		return err
+	}
+	byUUID := make(map[uuid.UUID]models.Message, len(records))
+	for _, rec := range records {
+		byUUID[rec.doc.UUID] = rec.doc.message()
+	}
+	for i := range results {
+		for _, u := range results[i].WindowUUIDs {
+			if m, ok := byUUID[u]; ok {
+				results[i].Window = append(results[i].Window, m)
+			}
+		}
+	}
+	return nil
+}
+
+// embedQuery embeds the text of a memory search with the message embedding model.
+func (ms *MemoryStore) embedQuery(ctx context.Context, text string) ([]float32, error) {
+	model, err := llms.GetEmbeddingModel(ms.appState, "message")
+	if err != nil {
+		return nil, store.NewStorageError("failed to get message embedding model", err)
+	}
+	embeddings, err := llms.EmbedTexts(ctx, ms.appState, model, "message", []string{text})
+	if err != nil {
+		return nil, store.NewStorageError(
+			"error embedding query",
+			models.NewDependencyFailureError("query embedding", err),
+		)
+	}
+	return embeddings[0], nil
+}
+
+// sortResults sorts results by their rank score, ordering ties by UUID.
+func sortResults(results []models.MemorySearchResult) {
+	resultUUID := func(r *models.MemorySearchResult) uuid.UUID {
+		if r.Message != nil {
+			return r.Message.UUID
+		}
+		return r.Summary.UUID
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+		if a.RankScore != b.RankScore {
+			return a.RankScore > b.RankScore
+		}
+		return models.SearchCursor{Score: a.RankScore, UUID: resultUUID(a)}.After(
+			b.RankScore,
+			resultUUID(b),
+		)
+	})
+}
+
+// fuseRankings fuses rankings of message search results by Reciprocal Rank Fusion. Each
+// message scores the sum of 1 / (RRFRankConstant + rank) over the rankings it's in, ranked
+// from 1. The top limit messages are returned with their scores, in order.
+func fuseRankings(
+	limit int,
+	rankings ...[]models.MemorySearchResult,
+) ([]models.MemorySearchResult, []float64) {
+	var fused []models.MemorySearchResult
+	var scores []float64
+	index := make(map[uuid.UUID]int)
+	for _, ranking := range rankings {
+		for rank, result := range ranking {
+			i, ok := index[result.Message.UUID]
+			if !ok {
+				i = len(fused)
+				index[result.Message.UUID] = i
+				fused = append(fused, result)
+				scores = append(scores, 0)
+			}
+			scores[i] += 1 / float64(RRFRankConstant+rank+1)
+		}
+	}
+
+	order := make([]int, len(fused))
+	for i := range order {
+		order[i] = i
+	}
+	// ties keep the order of the earlier rankings
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+	if len(order) > limit {
+		order = order[:limit]
+	}
+
+	results := make([]models.MemorySearchResult, len(order))
+	resultScores := make([]float64, len(order))
+	for i, idx := range order {
+		results[i] = fused[idx]
+		resultScores[i] = scores[idx]
+	}
+	return results, resultScores
+}
+
+// rerankMMR reranks the results using the Maximal Marginal Relevance algorithm.
+func rerankMMR(
+	results []models.MemorySearchResult,
+	queryEmbedding []float32,
+	lambda float32,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	embeddings := make([][]float32, len(results))
+	for i := range results {
+		embeddings[i] = results[i].Embedding
+	}
+	idxs, scores, err := search.MaximalMarginalRelevanceScores(
+		queryEmbedding,
+		embeddings,
+		lambda,
+		limit,
+	)
+	if err != nil {
+		return nil, store.NewStorageError("error applying mmr", err)
+	}
+	reranked := make([]models.MemorySearchResult, len(idxs))
+	for i, idx := range idxs {
+		reranked[i] = results[idx]
+		if explanation := reranked[i].Explanation; explanation != nil {
+			score := float64(scores[i])
+			explanation.MMRScore = &score
+		}
+	}
+	return reranked, nil
+}
+
+// similarity returns the similarity of an embedding to the query's by the distance
+// function: their cosine similarity, their inner product, or 1 / (1 + their Euclidean
+// distance). Embeddings of other dimensions, including missing ones, score 0.
+func similarity(df models.DistanceFunction, query, embedding []float32) float64 {
+	if len(embedding) != len(query) || len(query) == 0 {
+		return 0
+	}
+	var dot, queryNorm, norm, squared float64
+	for i := range query {
+		q, e := float64(query[i]), float64(embedding[i])
+		dot += q * e
+		queryNorm += q * q
+		norm += e * e
+		squared += (q - e) * (q - e)
+	}
+	switch df {
+	case models.DistanceFunctionDotProduct:
+		return dot
+	case models.DistanceFunctionL2:
+		return 1 / (1 + math.Sqrt(squared))
+	default:
+		if queryNorm == 0 || norm == 0 {
+			return 0
+		}
+		return dot / math.Sqrt(queryNorm*norm)
+	}
+}
+
+// escapeWildcard escapes the characters of text that a wildcard query would match as
+// patterns.
+func escapeWildcard(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`).Replace(text)
+}
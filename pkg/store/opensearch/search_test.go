@@ -0,0 +1,248 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// putEmbeddedMessages stores the given contents as messages of a session, embedding all
+// but the unembedded contents with the fake embeddings of their content.
+func putEmbeddedMessages(
+	t *testing.T,
+	ms *MemoryStore,
+	sessionID string,
+	userID *string,
+	contents []string,
+	unembedded ...string,
+) []models.Message {
+	ctx := context.Background()
+	if userID != nil {
+		_, err := ms.CreateSession(ctx, &models.CreateSessionRequest{
+			SessionID: sessionID,
+			UserID:    userID,
+		})
+		require.NoError(t, err)
+	}
+	messages := make([]models.Message, len(contents))
+	for i, content := range contents {
+		messages[i] = models.Message{UUID: uuid.New(), Role: "user", Content: content}
+	}
+	require.NoError(t, ms.PutMemory(ctx, sessionID, &models.Memory{Messages: messages}, true))
+
+	var embeddings []models.TextData
+	for _, m := range messages {
+		skip := false
+		for _, content := range unembedded {
+			skip = skip || m.Content == content
+		}
+		if !skip {
+			embeddings = append(embeddings, models.TextData{
+				TextUUID:  m.UUID,
+				Text:      m.Content,
+				Embedding: llms.FakeEmbeddings([]string{m.Content}, testEmbeddingDimensions)[0],
+			})
+		}
+	}
+	require.NoError(t, ms.CreateMessageEmbeddings(ctx, sessionID, embeddings))
+	return messages
+}
+
+func resultContents(results []models.MemorySearchResult) []string {
+	contents := make([]string, len(results))
+	for i, r := range results {
+		if r.Message != nil {
+			contents[i] = r.Message.Content
+		} else {
+			contents[i] = r.Summary.Content
+		}
+	}
+	return contents
+}
+
+func TestSearchMemory(t *testing.T) {
+	ctx := context.Background()
+	_, ms := newTestMemoryStore(t)
+	contents := []string{"the cat sat", "a dog barked", "birds sing", "fish swim"}
+	putEmbeddedMessages(t, ms, "search", nil, contents, "fish swim")
+
+	search := func(query *models.MemorySearchPayload, limit int) []models.MemorySearchResult {
+		results, err := ms.SearchMemory(ctx, "search", query, limit)
+		require.NoError(t, err)
+		return results
+	}
+
+	for _, exact := range []bool{false, true} {
+		results := search(&models.MemorySearchPayload{Text: "a dog barked", Exact: exact}, 2)
+		require.Len(t, results, 2)
+		assert.Equal(t, "a dog barked", results[0].Message.Content)
+		assert.InDelta(t, 1, results[0].Dist, 1e-6)
+		assert.Equal(t, results[0].Dist, results[0].RankScore)
+		assert.GreaterOrEqual(t, results[0].RankScore, results[1].RankScore)
+	}
+
+	results := search(&models.MemorySearchPayload{Text: "a dog barked", MinScore: 0.99}, 10)
+	assert.Equal(t, []string{"a dog barked"}, resultContents(results))
+
+	results = search(&models.MemorySearchPayload{Text: "birds sing", Explain: true}, 10)
+	require.Len(t, results, 3, "messages without embeddings aren't similar")
+	for i, r := range results {
+		require.NotNil(t, r.Explanation)
+		assert.Equal(t, i+1, r.Explanation.Rank)
+		assert.Equal(t, r.Dist, r.Explanation.VectorScore)
+	}
+
+	// the unembedded message is ranked by BM25
+	results = search(&models.MemorySearchPayload{
+		Text:       "fish swim",
+		SearchType: models.SearchTypeHybrid,
+	}, 10)
+	assert.Contains(t, resultContents(results), "fish swim")
+
+	results = search(&models.MemorySearchPayload{
+		Text:       "the cat sat",
+		SearchType: models.SearchTypeMMR,
+	}, 2)
+	require.Len(t, results, 2)
+	assert.Equal(t, "the cat sat", results[0].Message.Content)
+
+	results = search(&models.MemorySearchPayload{Text: "birds sing", Roles: []string{"ai"}}, 10)
+	assert.Empty(t, results)
+	assert.NotNil(t, results)
+
+	for _, query := range []*models.MemorySearchPayload{
+		{Text: "cat", Cursor: "cursor"},
+		{Text: "cat", Metadata: map[string]interface{}{"where": map[string]interface{}{}}},
+		{Text: "cat", SearchScope: models.SearchScopeSummary, Episode: 1},
+	} {
+		_, err := ms.SearchMemory(ctx, "search", query, 10)
+		assert.ErrorIs(t, err, models.ErrValidation)
+	}
+}
+
+func TestSearchSummaries(t *testing.T) {
+	ctx := context.Background()
+	_, ms := newTestMemoryStore(t)
+	messages := putEmbeddedMessages(t, ms, "summaries", nil, []string{"one", "two"})
+
+	summary := &models.Summary{
+		UUID:             uuid.New(),
+		Content:          "a summary of one and two",
+		SummaryPointUUID: messages[1].UUID,
+	}
+	require.NoError(t, ms.CreateSummary(ctx, "summaries", summary))
+	stored, err := ms.GetSummary(ctx, "summaries")
+	require.NoError(t, err)
+	require.NoError(t, ms.PutSummaryEmbedding(ctx, "summaries", &models.TextData{
+		TextUUID:  stored.UUID,
+		Text:      stored.Content,
+		Embedding: llms.FakeEmbeddings([]string{stored.Content}, testEmbeddingDimensions)[0],
+	}))
+
+	results, err := ms.SearchMemory(ctx, "summaries", &models.MemorySearchPayload{
+		Text:        "a summary of one and two",
+		SearchScope: models.SearchScopeSummary,
+	}, 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, stored.UUID, results[0].Summary.UUID)
+	assert.InDelta(t, 1, results[0].Dist, 1e-6)
+}
+
+func TestSearchUserMemory(t *testing.T) {
+	ctx := context.Background()
+	_, ms := newTestMemoryStore(t)
+	userID := "searcher"
+	_, err := ms.appState.UserStore.Create(ctx, &models.CreateUserRequest{UserID: userID})
+	require.NoError(t, err)
+	putEmbeddedMessages(t, ms, "first", &userID, []string{"the cat sat", "a dog barked"})
+	putEmbeddedMessages(t, ms, "second", &userID, []string{"birds sing"})
+	putEmbeddedMessages(t, ms, "other", nil, []string{"birds sing"})
+
+	results, err := ms.SearchUserMemory(ctx, userID, &models.MemorySearchPayload{
+		Text:    "birds sing",
+		Explain: true,
+	}, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "second", results[0].SessionID)
+	assert.Equal(t, "birds sing", results[0].Message.Content)
+	assert.Equal(t, 2, results[1].Explanation.Rank)
+
+	results, err = ms.SearchUserMemory(ctx, userID, &models.MemorySearchPayload{
+		Text: "birds sing",
+	}, 1, 10)
+	require.NoError(t, err)
+	for _, r := range results {
+		assert.Equal(t, "second", r.SessionID, "only the best matching session is kept")
+	}
+
+	results, err = ms.SearchUserMemory(ctx, "nobody", &models.MemorySearchPayload{
+		Text: "birds sing",
+	}, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestUserStore(t *testing.T) {
+	ctx := context.Background()
+	_, ms := newTestMemoryStore(t)
+	us := ms.appState.UserStore
+	userID := "anonymized"
+	_, err := us.Create(ctx, &models.CreateUserRequest{UserID: userID})
+	require.NoError(t, err)
+	putEmbeddedMessages(t, ms, "anonymized-session", &userID, []string{"hello"})
+
+	sessions, err := us.GetSessions(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "anonymized-session", sessions[0].SessionID)
+
+	_, err = us.Anonymize(ctx, userID, "anonymous", nil)
+	require.NoError(t, err)
+	session, err := ms.GetSession(ctx, "anonymized-session")
+	require.NoError(t, err)
+	assert.Equal(t, "anonymous", *session.UserID)
+
+	require.NoError(t, us.Delete(ctx, "anonymous"))
+	_, err = ms.GetSession(ctx, "anonymized-session")
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+}
+
+func TestMemorySnapshotStore(t *testing.T) {
+	_, err := NewMemorySnapshotStore(nil).Put(context.Background(), &models.MemorySnapshot{
+		SessionID: "session",
+		TurnUUID:  uuid.New(),
+	})
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func TestCreateIndexes(t *testing.T) {
+	f, ms := newTestMemoryStore(t)
+	require.NoError(t, ms.CreateIndexes(context.Background()), "existing indexes are kept")
+
+	properties := f.mappings["test_messages"]["properties"].(map[string]any)
+	embedding := properties["embedding"].(map[string]any)
+	assert.Equal(t, "knn_vector", embedding["type"])
+	assert.Equal(t, float64(testEmbeddingDimensions), embedding["dimension"])
+	assert.Equal(t, "cosinesimil", embedding["method"].(map[string]any)["space_type"])
+
+	ms.appState.Config.Extractors.Messages.Embeddings.Dimensions = 0
+	assert.Error(t, ms.CreateIndexes(context.Background()), "dimensions are required")
+}
+
+func TestSimilarity(t *testing.T) {
+	a, b := []float32{1, 0}, []float32{3, 4}
+	assert.InDelta(t, 0.6, similarity(models.DistanceFunctionCosine, a, b), 1e-9)
+	assert.InDelta(t, 3, similarity(models.DistanceFunctionDotProduct, a, b), 1e-9)
+	// (1, 0) is 5 from (4, 4)
+	assert.InDelta(t, 1.0/6, similarity(models.DistanceFunctionL2, a, []float32{4, 4}), 1e-9)
+	assert.Zero(t, similarity(models.DistanceFunctionCosine, a, nil))
+}
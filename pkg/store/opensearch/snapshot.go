@@ -0,0 +1,31 @@
+package opensearch
+
+import (
+	"context"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.MemorySnapshotStore = &MemorySnapshotStore{}
+
+// MemorySnapshotStore is a models.MemorySnapshotStore refusing to snapshot the sessions of
+// a MemoryStore, as snapshots are kept by the Postgres store, keyed to its own sessions.
+// Existing snapshots can still be read.
+type MemorySnapshotStore struct {
+	models.MemorySnapshotStore
+}
+
+// NewMemorySnapshotStore wraps snapshotStore with a MemorySnapshotStore.
+func NewMemorySnapshotStore(snapshotStore models.MemorySnapshotStore) *MemorySnapshotStore {
+	return &MemorySnapshotStore{MemorySnapshotStore: snapshotStore}
+}
+
+// Put returns a ValidationError.
+func (s *MemorySnapshotStore) Put(
+	_ context.Context,
+	_ *models.MemorySnapshot,
+) (*models.MemorySnapshot, error) {
+	return nil, models.NewValidationError(
+		"memory snapshots are not supported by the opensearch store",
+	)
+}
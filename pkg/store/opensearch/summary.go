@@ -0,0 +1,207 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store"
+)
+
+// GetSummary returns the most recent summary for the session, or an empty Summary if
+// there is none.
+func (ms *MemoryStore) GetSummary(ctx context.Context, sessionID string) (*models.Summary, error) {
+	if sessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	response, err := ms.Client.search(ctx, summariesIndex, map[string]any{
+		"query":   sessionRecords(sessionID),
+		"sort":    sortBy("id", "desc"),
+		"size":    1,
+		"_source": withoutEmbedding,
+	})
+	if err != nil {
+		return nil, store.NewStorageError("failed to get summary", err)
+	}
+	records, err := decode[summaryDoc](response.Hits.Hits)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return &models.Summary{}, nil
+	}
+	return records[0].doc.summary(), nil
+}
+
+func (ms *MemoryStore) GetSummaryByUUID(
+	ctx context.Context,
+	sessionID string,
+	uuid uuid.UUID,
+) (*models.Summary, error) {
+	rec, err := ms.getSummary(ctx, sessionID, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return rec.doc.summary(), nil
+}
+
+// GetSummaryList returns a page of the session's summaries, oldest first.
+func (ms *MemoryStore) GetSummaryList(
+	ctx context.Context,
+	sessionID string,
+	pageNumber int,
+	pageSize int,
+) (*models.SummaryListResponse, error) {
+	if sessionID == "" {
+		return nil, models.NewValidationError("sessionID cannot be empty")
+	}
+	hits, err := ms.Client.searchAll(
+		ctx,
+		summariesIndex,
+		sessionRecords(sessionID),
+		byID,
+		"embedding",
+	)
+	if err != nil {
+		return nil, store.NewStorageError("failed to get summaries", err)
+	}
+	records, err := decode[summaryDoc](hits)
+	if err != nil {
+		return nil, err
+	}
+
+	records = page(records, pageNumber, pageSize)
+	summaries := make([]models.Summary, len(records))
+	for i := range records {
+		summaries[i] = *records[i].doc.summary()
+	}
+	return &models.SummaryListResponse{
+		Summaries: summaries,
+		RowCount:  len(summaries),
+	}, nil
+}
+
+// CreateSummary stores a new Summary for a given sessionID, publishing it to the summary
+// embedder and NER tasks.
+func (ms *MemoryStore) CreateSummary(
+	ctx context.Context,
+	sessionID string,
+	summary *models.Summary,
+) error {
+	if _, err := ms.getSession(ctx, sessionID, true); err != nil {
+		return err
+	}
+	id, err := ms.Client.nextIDs(ctx, 1)
+	if err != nil {
+		return store.NewStorageError("failed to create summary", err)
+	}
+	doc := &summaryDoc{
+		UUID:             uuid.New(),
+		ID:               id,
+		SessionID:        sessionID,
+		CreatedAt:        now(),
+		Content:          summary.Content,
+		SummaryPointUUID: summary.SummaryPointUUID,
+		Metadata:         summary.Metadata,
+		TokenCount:       summary.TokenCount,
+	}
+	if err := ms.Client.create(ctx, summariesIndex, doc.UUID.String(), doc); err != nil {
+		return store.NewStorageError("failed to create summary", err)
+	}
+
+	publisher := ms.appState.TaskPublisher
+	if publisher == nil {
+		return nil
+	}
+	task := models.MessageSummaryTask{UUID: doc.UUID}
+	metadata := models.WithTenantMetadata(ctx, map[string]string{"session_id": sessionID})
+	for _, topic := range []models.TaskTopic{
+		models.MessageSummaryEmbedderTopic,
+		models.MessageSummaryNERTopic,
+	} {
+		if err := publisher.Publish(topic, metadata, task); err != nil {
+			return fmt.Errorf("MessageSummaryTask publish failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateSummary updates a summary's token count and merges its metadata. If
+// includeContent is true, the content is updated, too.
+func (ms *MemoryStore) UpdateSummary(
+	ctx context.Context,
+	sessionID string,
+	summary *models.Summary,
+	includeContent bool,
+) error {
+	if summary.UUID == uuid.Nil {
+		return errors.New("summary UUID cannot be empty")
+	}
+	return ms.updateSummary(ctx, sessionID, summary.UUID, func(doc *summaryDoc) {
+		doc.TokenCount = summary.TokenCount
+		if includeContent {
+			doc.Content = summary.Content
+		}
+		if len(summary.Metadata) > 0 {
+			doc.Metadata = mergeMetadata(doc.Metadata, summary.Metadata, true)
+		}
+	})
+}
+
+// PutSummaryEmbedding stores a summary embedding.
+func (ms *MemoryStore) PutSummaryEmbedding(
+	ctx context.Context,
+	sessionID string,
+	embedding *models.TextData,
+) error {
+	if err := ms.checkDimensions("summary", embedding.Embedding); err != nil {
+		return err
+	}
+	return ms.updateSummary(ctx, sessionID, embedding.TextUUID, func(doc *summaryDoc) {
+		doc.Embedding = embedding.Embedding
+	})
+}
+
+// getSummary returns the session's undeleted summary with the given UUID, or a
+// NotFoundError.
+func (ms *MemoryStore) getSummary(
+	ctx context.Context,
+	sessionID string,
+	summaryUUID uuid.UUID,
+) (*record[summaryDoc], error) {
+	rec, err := get[summaryDoc](ctx, ms.Client, summariesIndex, summaryUUID.String())
+	if err != nil {
+		return nil, store.NewStorageError("failed to get summary", err)
+	}
+	if rec == nil || rec.doc.SessionID != sessionID || rec.doc.Deleted {
+		return nil, models.NewNotFoundError("summary " + summaryUUID.String())
+	}
+	return rec, nil
+}
+
+// updateSummary applies update to a summary and writes it back, reading the summary
+// again if it was written concurrently.
+func (ms *MemoryStore) updateSummary(
+	ctx context.Context,
+	sessionID string,
+	summaryUUID uuid.UUID,
+	update func(doc *summaryDoc),
+) error {
+	err := retryOnConflict(func() error {
+		rec, err := ms.getSummary(ctx, sessionID, summaryUUID)
+		if err != nil {
+			return err
+		}
+		update(&rec.doc)
+		return ms.Client.put(ctx, summariesIndex, summaryUUID.String(), &rec.doc, &rec.version)
+	})
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return store.NewStorageError("failed to update summary", err)
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store"
+)
+
+var _ models.UserStore = &UserStore{}
+
+// UserStore is a models.UserStore keeping users in another store, whose users' sessions
+// are those of a MemoryStore. Deleting or anonymizing a user deletes or re-keys the
+// user's sessions in OpenSearch, and is refused if any of them is under legal hold.
+type UserStore struct {
+	models.UserStore
+	memoryStore *MemoryStore
+}
+
+// NewUserStore wraps userStore with a UserStore for the sessions of memoryStore.
+func NewUserStore(userStore models.UserStore, memoryStore *MemoryStore) *UserStore {
+	return &UserStore{UserStore: userStore, memoryStore: memoryStore}
+}
+
+// Delete soft deletes a user and the user's sessions, unless the user or any of the
+// sessions is under legal hold.
+func (us *UserStore) Delete(ctx context.Context, userID string) error {
+	sessions, err := us.unheldSessions(ctx, userID, false)
+	if err != nil {
+		return err
+	}
+	if err := us.UserStore.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	deletedAt := now()
+	for _, rec := range sessions {
+		_, err := us.memoryStore.deleteSession(ctx, rec.doc.SessionID, deletedAt)
+		var notFoundErr *models.NotFoundError
+		if err != nil && !errors.As(err, &notFoundErr) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Anonymize re-keys an undeleted user, and all of the user's sessions, to anonymousID,
+// clears the user's email and name, and replaces the user's metadata.
+func (us *UserStore) Anonymize(
+	ctx context.Context,
+	userID string,
+	anonymousID string,
+	metadata map[string]interface{},
+) (*models.User, error) {
+	if _, err := us.unheldSessions(ctx, userID, true); err != nil {
+		return nil, err
+	}
+	user, err := us.UserStore.Anonymize(ctx, userID, anonymousID, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = us.memoryStore.Client.updateByQuery(
+		ctx,
+		sessionsIndex,
+		term("user_id", userID),
+		map[string]any{"user_id": anonymousID, "updated_at": now()},
+	)
+	if err != nil {
+		return nil, store.NewStorageError("failed to anonymize user sessions", err)
+	}
+	return user, nil
+}
+
+// GetSessions returns the user's undeleted sessions.
+func (us *UserStore) GetSessions(ctx context.Context, userID string) ([]*models.Session, error) {
+	records, err := us.memoryStore.userSessions(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*models.Session, len(records))
+	for i := range records {
+		sessions[i] = records[i].doc.session()
+	}
+	return sessions, nil
+}
+
+// unheldSessions returns the user's sessions, including deleted sessions if
+// includeDeleted is true, or a LegalHoldError if any of them is under legal hold.
+func (us *UserStore) unheldSessions(
+	ctx context.Context,
+	userID string,
+	includeDeleted bool,
+) ([]record[sessionDoc], error) {
+	holds, err := us.memoryStore.legalHolds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := us.memoryStore.userSessions(ctx, userID, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range sessions {
+		if holds[[2]string{models.LegalHoldSubjectSession, rec.doc.SessionID}] {
+			return nil, models.NewLegalHoldError(models.LegalHoldSubjectSession, rec.doc.SessionID)
+		}
+	}
+	return sessions, nil
+}
+
+// userSessions returns the user's sessions, oldest first, including deleted sessions if
+// includeDeleted is true.
+func (ms *MemoryStore) userSessions(
+	ctx context.Context,
+	userID string,
+	includeDeleted bool,
+) ([]record[sessionDoc], error) {
+	query := all(term("user_id", userID))
+	if !includeDeleted {
+		query = all(term("user_id", userID), undeletedSession)
+	}
+	hits, err := ms.Client.searchAll(ctx, sessionsIndex, query, byID)
+	if err != nil {
+		return nil, store.NewStorageError("failed to get user sessions", err)
+	}
+	return decode[sessionDoc](hits)
+}
@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.AsyncTaskStore = &AsyncTaskStoreDAO{}
+
+// finishedAsyncTaskStatuses are the statuses a task can't leave.
+var finishedAsyncTaskStatuses = []models.AsyncTaskStatus{
+	models.AsyncTaskSucceeded,
+	models.AsyncTaskFailed,
+	models.AsyncTaskCanceled,
+}
+
+type AsyncTaskStoreDAO struct {
+	db *bun.DB
+}
+
+func NewAsyncTaskStoreDAO(db *bun.DB) *AsyncTaskStoreDAO {
+	return &AsyncTaskStoreDAO{
+		db: db,
+	}
+}
+
+// Create stores a new pending task.
+func (dao *AsyncTaskStoreDAO) Create(
+	ctx context.Context,
+	taskType string,
+	params map[string]interface{},
+) (*models.AsyncTask, error) {
+	if taskType == "" {
+		return nil, models.NewValidationError("task type cannot be empty")
+	}
+
+	task := &AsyncTaskSchema{
+		Type:   taskType,
+		Status: string(models.AsyncTaskPending),
+		Params: params,
+	}
+	_, err := dao.db.NewInsert().Model(task).Returning("*").Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+	return asyncTaskSchemaToAsyncTask(task), nil
+}
+
+// Get returns a task.
+func (dao *AsyncTaskStoreDAO) Get(
+	ctx context.Context,
+	taskUUID uuid.UUID,
+) (*models.AsyncTask, error) {
+	task := new(AsyncTaskSchema)
+	err := dao.db.NewSelect().Model(task).Where("uuid = ?", taskUUID).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewNotFoundError("task " + taskUUID.String())
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	return asyncTaskSchemaToAsyncTask(task), nil
+}
+
+// List returns the tasks matching opts, newest first.
+func (dao *AsyncTaskStoreDAO) List(
+	ctx context.Context,
+	opts models.AsyncTaskListOptions,
+) ([]*models.AsyncTask, error) {
+	var tasks []AsyncTaskSchema
+	q := dao.db.NewSelect().Model(&tasks).Order("created_at DESC")
+	if opts.Type != "" {
+		q = q.Where("type = ?", opts.Type)
+	}
+	if opts.Status != "" {
+		q = q.Where("status = ?", opts.Status)
+	}
+	if opts.Limit > 0 {
+		q = q.Limit(opts.Limit)
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	result := make([]*models.AsyncTask, len(tasks))
+	for i := range tasks {
+		result[i] = asyncTaskSchemaToAsyncTask(&tasks[i])
+	}
+	return result, nil
+}
+
+// Update stores the task's status, progress, error, result, and start and finish times.
+// A finished task isn't updated.
+func (dao *AsyncTaskStoreDAO) Update(
+	ctx context.Context,
+	task *models.AsyncTask,
+) (*models.AsyncTask, error) {
+	return withRetry(ctx, "update task", func(ctx context.Context) (*models.AsyncTask, error) {
+		return dao.update(ctx, task)
+	})
+}
+
+func (dao *AsyncTaskStoreDAO) update(
+	ctx context.Context,
+	task *models.AsyncTask,
+) (*models.AsyncTask, error) {
+	record := &AsyncTaskSchema{
+		UUID:      task.UUID,
+		Status:    string(task.Status),
+		Completed: task.Completed,
+		Total:     task.Total,
+		Error:     task.Error,
+		Result:    task.Result,
+	}
+	if task.StartedAt != nil {
+		record.StartedAt = *task.StartedAt
+	}
+	if task.FinishedAt != nil {
+		record.FinishedAt = *task.FinishedAt
+	}
+
+	r, err := dao.db.NewUpdate().
+		Model(record).
+		Column(
+			"status",
+			"completed",
+			"total",
+			"error",
+			"result",
+			"started_at",
+			"finished_at",
+			"updated_at",
+		).
+		WherePK().
+		Where("status NOT IN (?)", bun.In(finishedAsyncTaskStatuses)).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update task: %w", err)
+	}
+
+	rowsUpdated, err := r.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsUpdated == 0 {
+		return nil, dao.unchangedError(ctx, task.UUID)
+	}
+	return asyncTaskSchemaToAsyncTask(record), nil
+}
+
+// Cancel marks an unfinished task as canceled.
+func (dao *AsyncTaskStoreDAO) Cancel(
+	ctx context.Context,
+	taskUUID uuid.UUID,
+) (*models.AsyncTask, error) {
+	task := new(AsyncTaskSchema)
+	r, err := dao.db.NewUpdate().
+		Model(task).
+		Set("status = ?", models.AsyncTaskCanceled).
+		Set("finished_at = current_timestamp").
+		Set("updated_at = current_timestamp").
+		Where("uuid = ?", taskUUID).
+		Where("status NOT IN (?)", bun.In(finishedAsyncTaskStatuses)).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel task: %w", err)
+	}
+
+	rowsUpdated, err := r.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsUpdated == 0 {
+		return nil, dao.unchangedError(ctx, taskUUID)
+	}
+	return asyncTaskSchemaToAsyncTask(task), nil
+}
+
+// FailAbandoned fails the unfinished tasks not updated since before.
+func (dao *AsyncTaskStoreDAO) FailAbandoned(ctx context.Context, before time.Time) (int, error) {
+	r, err := dao.db.NewUpdate().
+		Model((*AsyncTaskSchema)(nil)).
+		Set("status = ?", models.AsyncTaskFailed).
+		Set("error = ?", "task abandoned").
+		Set("finished_at = current_timestamp").
+		Set("updated_at = current_timestamp").
+		Where("status NOT IN (?)", bun.In(finishedAsyncTaskStatuses)).
+		Where("updated_at < ?", before).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail abandoned tasks: %w", err)
+	}
+
+	rowsUpdated, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return int(rowsUpdated), nil
+}
+
+// unchangedError explains why an update of a task changed no rows: the task either
+// doesn't exist or is finished.
+func (dao *AsyncTaskStoreDAO) unchangedError(ctx context.Context, taskUUID uuid.UUID) error {
+	task, err := dao.Get(ctx, taskUUID)
+	if err != nil {
+		return err
+	}
+	return models.NewConflictError("task is " + string(task.Status))
+}
+
+func asyncTaskSchemaToAsyncTask(task *AsyncTaskSchema) *models.AsyncTask {
+	t := &models.AsyncTask{
+		UUID:      task.UUID,
+		CreatedAt: task.CreatedAt,
+		UpdatedAt: task.UpdatedAt,
+		Type:      task.Type,
+		Status:    models.AsyncTaskStatus(task.Status),
+		Params:    task.Params,
+		Completed: task.Completed,
+		Total:     task.Total,
+		Error:     task.Error,
+		Result:    task.Result,
+	}
+	if !task.StartedAt.IsZero() {
+		startedAt := task.StartedAt
+		t.StartedAt = &startedAt
+	}
+	if !task.FinishedAt.IsZero() {
+		finishedAt := task.FinishedAt
+		t.FinishedAt = &finishedAt
+	}
+	return t
+}
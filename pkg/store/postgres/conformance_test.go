@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/storetest"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.RunMemoryStoreTests(t, func(t *testing.T) models.MemoryStore[any] {
+		memoryStore, err := NewPostgresMemoryStore(appState, testDB)
+		require.NoError(t, err)
+		return memoryStore
+	})
+}
+
+func TestDocumentStoreConformance(t *testing.T) {
+	storetest.RunDocumentStoreTests(t, func(t *testing.T) models.DocumentStore[any] {
+		documentStore, err := NewDocumentStore(testCtx, appState, testDB)
+		require.NoError(t, err)
+		return documentStore
+	})
+}
+
+func TestAsyncTaskStoreConformance(t *testing.T) {
+	storetest.RunAsyncTaskStoreTests(t, func(t *testing.T) models.AsyncTaskStore {
+		return NewAsyncTaskStoreDAO(testDB)
+	})
+}
+
+func TestMemorySnapshotStoreConformance(t *testing.T) {
+	storetest.RunMemorySnapshotStoreTests(
+		t,
+		func(t *testing.T) (models.MemoryStore[any], models.MemorySnapshotStore) {
+			memoryStore, err := NewPostgresMemoryStore(appState, testDB)
+			require.NoError(t, err)
+			return memoryStore, NewMemorySnapshotStoreDAO(testDB)
+		},
+	)
+}
+
+func TestProvenanceStoreConformance(t *testing.T) {
+	storetest.RunProvenanceStoreTests(t, func(t *testing.T) models.ProvenanceStore {
+		return NewProvenanceStoreDAO(testDB)
+	})
+}
+
+func TestQueryLogStoreConformance(t *testing.T) {
+	storetest.RunQueryLogStoreTests(t, func(t *testing.T) models.QueryLogStore {
+		return NewQueryLogStoreDAO(testDB)
+	})
+}
+
+func TestEvalSetStoreConformance(t *testing.T) {
+	storetest.RunEvalSetStoreTests(t, func(t *testing.T) models.EvalSetStore {
+		return NewEvalSetStoreDAO(testDB)
+	})
+}
+
+func TestLegalHoldStoreConformance(t *testing.T) {
+	storetest.RunLegalHoldStoreTests(
+		t,
+		func(t *testing.T) (models.MemoryStore[any], models.UserStore, models.LegalHoldStore) {
+			memoryStore, err := NewPostgresMemoryStore(appState, testDB)
+			require.NoError(t, err)
+			return memoryStore, NewUserStoreDAO(testDB), NewLegalHoldStoreDAO(testDB)
+		},
+	)
+}
+
+func TestProviderOverrideStoreConformance(t *testing.T) {
+	storetest.RunProviderOverrideStoreTests(t, func(t *testing.T) models.ProviderOverrideStore {
+		return NewProviderOverrideStoreDAO(testDB, newTestBox(t))
+	})
+}
@@ -0,0 +1,221 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// DefaultCopyThreshold is the minimum number of rows for which inserts use COPY rather than INSERT.
+const DefaultCopyThreshold = 500
+
+// copyThreshold returns the configured COPY threshold. A negative threshold disables COPY.
+func copyThreshold(appState *models.AppState) int {
+	threshold := appState.Config.Store.Postgres.Ingest.CopyThreshold
+	if threshold == 0 {
+		return DefaultCopyThreshold
+	}
+	return threshold
+}
+
+// useCopy returns true if a batch of rowCount rows should be inserted using COPY.
+func useCopy(appState *models.AppState, rowCount int) bool {
+	threshold := copyThreshold(appState)
+	return threshold > 0 && rowCount >= threshold
+}
+
+// csvRows encodes rows in the CSV format accepted by COPY. Values are always quoted so that
+// empty strings are distinguished from NULLs, which are written as unquoted empty fields.
+type csvRows struct {
+	buf bytes.Buffer
+}
+
+func (c *csvRows) writeRow(values ...*string) {
+	for i, v := range values {
+		if i > 0 {
+			c.buf.WriteByte(',')
+		}
+		if v == nil {
+			continue
+		}
+		c.buf.WriteByte('"')
+		c.buf.WriteString(strings.ReplaceAll(*v, `"`, `""`))
+		c.buf.WriteByte('"')
+	}
+	c.buf.WriteByte('\n')
+}
+
+// copyFrom streams rows into the given table and columns using COPY FROM STDIN.
+func copyFrom(
+	ctx context.Context,
+	db *bun.DB,
+	table string,
+	columns []string,
+	rows *csvRows,
+) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	idents := make([]string, len(columns))
+	for i, c := range columns {
+		idents[i] = string(db.Formatter().AppendIdent(nil, c))
+	}
+
+	_, err = pgdriver.CopyFrom(
+		ctx,
+		conn,
+		&rows.buf,
+		"COPY ? ("+strings.Join(idents, ", ")+") FROM STDIN WITH (FORMAT csv)",
+		bun.Ident(table),
+	)
+	return err
+}
+
+// copyDocuments inserts documents into the collection table using COPY. Document UUIDs are
+// generated client side, as COPY cannot return generated values. Zero values are written as
+// NULLs, matching the nullzero columns of DocumentBase.
+func (dc *DocumentCollectionDAO) copyDocuments(
+	ctx context.Context,
+	documents []models.Document,
+) error {
+	rows := &csvRows{}
+	for i := range documents {
+		if documents[i].UUID == uuid.Nil {
+			documents[i].UUID = uuid.New()
+		}
+		d := &documents[i]
+
+		id := d.UUID.String()
+		metadata, err := jsonValue(d.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal document metadata: %w", err)
+		}
+		var isEmbedded *string
+		if d.IsEmbedded {
+			isEmbedded = stringPtr("true")
+		}
+
+		rows.writeRow(
+			&id,
+			nullZero(d.DocumentID),
+			nullZero(d.Content),
+			metadata,
+			isEmbedded,
+			vectorValue(d.Embedding),
+			nullZero(d.ContentHash),
+		)
+	}
+
+	return copyFrom(
+		ctx,
+		dc.db,
+		dc.TableName,
+		[]string{
+			"uuid", "document_id", "content", "metadata", "is_embedded", "embedding", "content_hash",
+		},
+		rows,
+	)
+}
+
+// copyMessages inserts messages into the message table using COPY, returning the
+// stored messages in insertion order.
+func (dao *MessageDAO) copyMessages(
+	ctx context.Context,
+	messages []models.Message,
+) ([]MessageStoreSchema, error) {
+	uuids := make([]uuid.UUID, len(messages))
+	rows := &csvRows{}
+	for i := range messages {
+		m := &messages[i]
+		if m.UUID == uuid.Nil {
+			m.UUID = uuid.New()
+		}
+		uuids[i] = m.UUID
+
+		id := m.UUID.String()
+		tokenCount := strconv.Itoa(m.TokenCount)
+		metadata, err := jsonValue(m.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal message metadata: %w", err)
+		}
+
+		contentHash := models.ContentHash(m.Content)
+		rows.writeRow(&id, &dao.sessionID, &m.Role, &m.Content, &tokenCount, metadata, &contentHash)
+	}
+
+	err := copyFrom(
+		ctx,
+		dao.db,
+		"message",
+		[]string{
+			"uuid", "session_id", "role", "content", "token_count", "metadata", "content_hash",
+		},
+		rows,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var pgMessages []MessageStoreSchema
+	err = dao.db.NewSelect().
+		Model(&pgMessages).
+		Where("uuid IN (?)", bun.In(uuids)).
+		Order("id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get copied messages: %w", err)
+	}
+
+	return pgMessages, nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func nullZero(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func jsonValue(m map[string]interface{}) (*string, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return stringPtr(string(b)), nil
+}
+
+// vectorValue formats an embedding in the pgvector text format.
+func vectorValue(v []float32) *string {
+	if len(v) == 0 {
+		return nil
+	}
+	b := make([]byte, 0, len(v)*8)
+	b = append(b, '[')
+	for i, f := range v {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = strconv.AppendFloat(b, float64(f), 'f', -1, 32)
+	}
+	b = append(b, ']')
+	return stringPtr(string(b))
+}
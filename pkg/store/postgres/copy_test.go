@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+func TestCSVRows(t *testing.T) {
+	rows := &csvRows{}
+	rows.writeRow(stringPtr("a"), nil, stringPtr(""), stringPtr(`say "hi"`))
+	rows.writeRow(stringPtr("line\nbreak"))
+
+	assert.Equal(t, "\"a\",,\"\",\"say \"\"hi\"\"\"\n\"line\nbreak\"\n", rows.buf.String())
+	assert.Equal(t, "[0.1,-2,3.5]", *vectorValue([]float32{0.1, -2, 3.5}))
+	assert.Nil(t, vectorValue(nil))
+}
+
+func TestCopyDocumentsAndMessages(t *testing.T) {
+	ctx := context.Background()
+
+	ingestConfig := appState.Config.Store.Postgres.Ingest
+	appState.Config.Store.Postgres.Ingest.CopyThreshold = 2
+	defer func() { appState.Config.Store.Postgres.Ingest = ingestConfig }()
+
+	t.Run("documents", func(t *testing.T) {
+		collection := NewTestCollectionDAO(3)
+		collection.IsAutoEmbedded = false
+		err := collection.Create(ctx)
+		require.NoError(t, err)
+
+		documents := make([]models.Document, 3)
+		for i := range documents {
+			documents[i] = models.Document{
+				DocumentBase: models.DocumentBase{
+					DocumentID: testutils.GenerateRandomString(10),
+					Content:    `a "quoted", multi-line` + "\n" + testutils.GenerateRandomString(10),
+					Metadata:   map[string]interface{}{"key": "value"},
+				},
+				Embedding: []float32{0.1, 0.2, 0.3},
+			}
+		}
+
+		uuids, err := collection.CreateDocuments(ctx, documents)
+		require.NoError(t, err)
+		compareDocumentUUIDs(t, documents, uuids)
+
+		returned, err := collection.GetDocuments(ctx, 0, uuids, nil)
+		require.NoError(t, err)
+		require.Equal(t, len(documents), len(returned))
+		for _, d := range returned {
+			assert.True(t, d.IsEmbedded)
+			assert.Equal(t, []float32{0.1, 0.2, 0.3}, d.Embedding)
+			assert.Equal(t, "value", d.Metadata["key"])
+			assert.Contains(t, d.Content, `a "quoted", multi-line`)
+		}
+
+		// duplicate document_ids are reported as for INSERT
+		_, err = collection.CreateDocuments(ctx, documents[:2])
+		assert.ErrorAs(t, err, new(*models.ConflictError))
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		sessionID := createSession(t)
+		messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+		require.NoError(t, err)
+
+		messages := []models.Message{
+			{Role: "human", Content: "first", TokenCount: 1},
+			{UUID: uuid.New(), Role: "ai", Content: "", Metadata: map[string]interface{}{"k": "v"}},
+			{Role: "human", Content: "third", TokenCount: 3},
+		}
+
+		created, err := messageDAO.CreateMany(ctx, messages)
+		require.NoError(t, err)
+		require.Equal(t, len(messages), len(created))
+		for i := range messages {
+			assert.Equal(t, messages[i].UUID, created[i].UUID)
+			assert.Equal(t, messages[i].Content, created[i].Content)
+			assert.Equal(t, messages[i].TokenCount, created[i].TokenCount)
+			assert.False(t, created[i].CreatedAt.IsZero())
+		}
+		assert.Equal(t, "v", created[1].Metadata["k"])
+	})
+}
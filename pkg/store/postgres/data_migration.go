@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	// DataMigrationTaskType is the type of the async task running the data migrations.
+	DataMigrationTaskType     = "data_migration"
+	DefaultDataMigrationBatch = 1000
+)
+
+// DataMigration is a change to existing data too slow to run with the schema migrations,
+// such as a backfill of a new column across every message. It's run in batches, each in
+// its own transaction, so that it neither holds locks for long nor loses its progress
+// when interrupted.
+type DataMigration struct {
+	// Name identifies the migration's progress. It must not change once released.
+	Name        string
+	Description string
+	// Remaining returns the number of rows left to migrate, used to report progress.
+	Remaining func(ctx context.Context, appState *models.AppState, db bun.IDB) (int, error)
+	// Batch migrates up to limit rows after cursor, in the order of the cursor, returning
+	// the cursor of the last row migrated and the number of rows migrated. The migration
+	// is finished when a batch migrates no rows.
+	Batch func(
+		ctx context.Context,
+		appState *models.AppState,
+		tx bun.Tx,
+		cursor int64,
+		limit int,
+	) (int64, int, error)
+}
+
+// DataMigrationStatus is the progress of a data migration.
+type DataMigrationStatus struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Completed   int        `json:"completed"`
+	Total       int        `json:"total"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// dataMigrations are the data migrations, run in order. Migrations are only ever added.
+var dataMigrations = []DataMigration{
+	messageTokenCountMigration,
+}
+
+// RunDataMigrations runs the unfinished data migrations, reporting the number of rows
+// migrated of the total with progress after each batch. Migrations resume from the last
+// batch stored, and batches of concurrent runs, such as by the server and
+// `zep migrate-data`, are serialized.
+func RunDataMigrations(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	progress func(completed, total int),
+) ([]DataMigrationStatus, error) {
+	return runDataMigrations(ctx, appState, db, dataMigrations, progress)
+}
+
+func runDataMigrations(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	migrations []DataMigration,
+	progress func(completed, total int),
+) ([]DataMigrationStatus, error) {
+	batchSize := appState.Config.Store.Postgres.DataMigrations.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultDataMigrationBatch
+	}
+
+	statuses := make([]DataMigrationStatus, len(migrations))
+	for i, m := range migrations {
+		status, err := dataMigrationStatus(ctx, appState, db, m)
+		if err != nil {
+			return statuses, err
+		}
+		statuses[i] = *status
+	}
+	report := func() {
+		var completed, total int
+		for _, s := range statuses {
+			completed += s.Completed
+			total += s.Total
+		}
+		progress(completed, total)
+	}
+	report()
+
+	for i, m := range migrations {
+		status := &statuses[i]
+		for status.FinishedAt == nil {
+			if err := runDataMigrationBatch(ctx, appState, db, m, batchSize, status); err != nil {
+				return statuses, fmt.Errorf("data migration %s failed: %w", m.Name, err)
+			}
+			report()
+		}
+		log.Infof("data migration %s finished: %d rows migrated", m.Name, status.Completed)
+	}
+	return statuses, nil
+}
+
+// dataMigrationStatus stores the migration's progress, if this is its first run, and
+// returns it. The total is estimated from the rows migrated and those remaining.
+func dataMigrationStatus(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	m DataMigration,
+) (*DataMigrationStatus, error) {
+	record := &DataMigrationSchema{Name: m.Name}
+	_, err := db.NewInsert().Model(record).On("CONFLICT (name) DO NOTHING").Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store data migration %s: %w", m.Name, err)
+	}
+	if err := db.NewSelect().Model(record).WherePK().Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get data migration %s: %w", m.Name, err)
+	}
+
+	status := &DataMigrationStatus{
+		Name:        m.Name,
+		Description: m.Description,
+		Completed:   record.Completed,
+		Total:       record.Completed,
+	}
+	if !record.FinishedAt.IsZero() {
+		status.FinishedAt = &record.FinishedAt
+		return status, nil
+	}
+	remaining, err := m.Remaining(ctx, appState, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows of data migration %s: %w", m.Name, err)
+	}
+	status.Total += remaining
+	return status, nil
+}
+
+// runDataMigrationBatch migrates a batch of rows and stores the migration's progress in
+// the same transaction. The migration's row is locked first, so that a concurrent run
+// waits for the batch and then continues after it.
+func runDataMigrationBatch(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	m DataMigration,
+	batchSize int,
+	status *DataMigrationStatus,
+) error {
+	return db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		record := &DataMigrationSchema{Name: m.Name}
+		if err := tx.NewSelect().Model(record).WherePK().For("UPDATE").Scan(ctx); err != nil {
+			return fmt.Errorf("failed to lock data migration: %w", err)
+		}
+		if !record.FinishedAt.IsZero() {
+			// finished by a concurrent run
+			status.Completed = record.Completed
+			status.FinishedAt = &record.FinishedAt
+			return nil
+		}
+
+		cursor, n, err := m.Batch(ctx, appState, tx, record.Cursor, batchSize)
+		if err != nil {
+			return err
+		}
+		update := tx.NewUpdate().
+			Model(record).
+			Set("updated_at = current_timestamp").
+			WherePK().
+			Returning("*")
+		if n == 0 {
+			update = update.Set("finished_at = current_timestamp")
+		} else {
+			update = update.
+				Set("cursor = ?", cursor).
+				Set("completed = completed + ?", n)
+		}
+		if _, err := update.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to store data migration progress: %w", err)
+		}
+
+		status.Completed = record.Completed
+		status.Total = max(status.Total, status.Completed)
+		if !record.FinishedAt.IsZero() {
+			status.Total = status.Completed
+			status.FinishedAt = &record.FinishedAt
+		}
+		return nil
+	})
+}
+
+// messageTokenCountMigration counts the tokens of the messages stored without a token
+// count, as before the token counter ran on every message, so that memory retrieval
+// can be bounded by tokens. Messages of LLMs without a tokenizer keep a count of 0.
+var messageTokenCountMigration = DataMigration{
+	Name:        "message_token_count",
+	Description: "Counts the tokens of messages stored without a token count",
+	Remaining: func(ctx context.Context, _ *models.AppState, db bun.IDB) (int, error) {
+		return db.NewSelect().
+			Model((*MessageStoreSchema)(nil)).
+			Where("token_count = 0").
+			Count(ctx)
+	},
+	Batch: func(
+		ctx context.Context,
+		appState *models.AppState,
+		tx bun.Tx,
+		cursor int64,
+		limit int,
+	) (int64, int, error) {
+		if appState.LLMClient == nil {
+			return 0, 0, fmt.Errorf("an LLM client is required to count tokens")
+		}
+		var messages []MessageStoreSchema
+		err := tx.NewSelect().
+			Model(&messages).
+			Column("uuid", "id", "content").
+			Where("id > ?", cursor).
+			Where("token_count = 0").
+			Order("id").
+			Limit(limit).
+			Scan(ctx)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get messages: %w", err)
+		}
+		if len(messages) == 0 {
+			return cursor, 0, nil
+		}
+
+		for i := range messages {
+			count, err := appState.LLMClient.GetTokenCount(messages[i].Content)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to count tokens: %w", err)
+			}
+			messages[i].TokenCount = count
+		}
+		_, err = tx.NewUpdate().
+			With("_data", tx.NewValues(&messages)).
+			Model(&messages).
+			TableExpr("_data").
+			Set("token_count = _data.token_count").
+			Where("m.uuid = _data.uuid").
+			Exec(ctx)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to update token counts: %w", err)
+		}
+		return messages[len(messages)-1].ID, len(messages), nil
+	},
+}
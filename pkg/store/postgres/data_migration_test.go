@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+func TestDataMigrations(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	require.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, true,
+	)
+	require.NoError(t, err)
+
+	original := appState.Config.Store.Postgres.DataMigrations
+	t.Cleanup(func() { appState.Config.Store.Postgres.DataMigrations = original })
+	appState.Config.Store.Postgres.DataMigrations.BatchSize = 2
+
+	var migrated []int64
+	interrupt := true
+	m := DataMigration{
+		Name: "test_" + sessionID,
+		Remaining: func(ctx context.Context, _ *models.AppState, db bun.IDB) (int, error) {
+			return db.NewSelect().
+				Model((*MessageStoreSchema)(nil)).
+				Where("session_id = ?", sessionID).
+				Count(ctx)
+		},
+		Batch: func(
+			ctx context.Context,
+			_ *models.AppState,
+			tx bun.Tx,
+			cursor int64,
+			limit int,
+		) (int64, int, error) {
+			if interrupt && len(migrated) > 0 {
+				interrupt = false
+				return 0, 0, errors.New("interrupted")
+			}
+			var ids []int64
+			err := tx.NewSelect().
+				Model((*MessageStoreSchema)(nil)).
+				Column("id").
+				Where("session_id = ?", sessionID).
+				Where("id > ?", cursor).
+				Order("id").
+				Limit(limit).
+				Scan(ctx, &ids)
+			if err != nil || len(ids) == 0 {
+				return cursor, 0, err
+			}
+			migrated = append(migrated, ids...)
+			return ids[len(ids)-1], len(ids), nil
+		},
+	}
+	var progress [][2]int
+	report := func(completed, total int) {
+		progress = append(progress, [2]int{completed, total})
+	}
+
+	_, err = runDataMigrations(testCtx, appState, testDB, []DataMigration{m}, report)
+	assert.ErrorContains(t, err, "interrupted")
+	assert.Len(t, migrated, 2)
+
+	// the migration resumes after the last batch stored
+	n := len(testutils.TestMessages)
+	statuses, err := runDataMigrations(testCtx, appState, testDB, []DataMigration{m}, report)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.NotNil(t, statuses[0].FinishedAt)
+	assert.Equal(t, n, statuses[0].Completed)
+	assert.Equal(t, n, statuses[0].Total)
+	assert.Len(t, migrated, n)
+	assert.IsIncreasing(t, migrated)
+	assert.Equal(t, [2]int{n, n}, progress[len(progress)-1])
+
+	// finished migrations aren't run again
+	migrated = nil
+	statuses, err = runDataMigrations(testCtx, appState, testDB, []DataMigration{m}, report)
+	require.NoError(t, err)
+	assert.Empty(t, migrated)
+	assert.Equal(t, n, statuses[0].Completed)
+}
+
+func TestMessageTokenCountMigration(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	require.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, true,
+	)
+	require.NoError(t, err)
+	_, err = testDB.NewUpdate().
+		Model((*MessageStoreSchema)(nil)).
+		Set("token_count = 0").
+		Where("session_id = ?", sessionID).
+		Exec(testCtx)
+	require.NoError(t, err)
+
+	m := messageTokenCountMigration
+	m.Name = "test_token_count_" + sessionID
+	_, err = runDataMigrations(testCtx, appState, testDB, []DataMigration{m}, func(int, int) {})
+	require.NoError(t, err)
+
+	var counts []int
+	err = testDB.NewSelect().
+		Model((*MessageStoreSchema)(nil)).
+		Column("token_count").
+		Where("session_id = ?", sessionID).
+		Scan(testCtx, &counts)
+	require.NoError(t, err)
+	require.Len(t, counts, len(testutils.TestMessages))
+	for _, count := range counts {
+		assert.Positive(t, count)
+	}
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pgvector/pgvector-go"
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/search"
+)
+
+// dedupeGroupExpr returns the expression used to group message embeddings for deduplication.
+// Sessions without a user are deduplicated on their own when the scope is user.
+func dedupeGroupExpr(scope models.DeduplicationScope) (string, error) {
+	switch scope {
+	case models.DeduplicationScopeSession, "":
+		return "'session:' || s.session_id", nil
+	case models.DeduplicationScopeUser:
+		return "COALESCE('user:' || s.user_id, 'session:' || s.session_id)", nil
+	default:
+		return "", fmt.Errorf("invalid deduplication scope: %s", scope)
+	}
+}
+
+// deduplicateMessageEmbeddings finds clusters of near-duplicate message embeddings within
+// each session or user that has had messages embedded since the given time. The oldest message
+// in a cluster is kept as its representative and the rest are marked as duplicates of it.
+// Returns the number of embeddings marked as duplicates.
+func deduplicateMessageEmbeddings(
+	ctx context.Context,
+	db *bun.DB,
+	since time.Time,
+	threshold float32,
+	scope models.DeduplicationScope,
+) (int, error) {
+	groupExpr, err := dedupeGroupExpr(scope)
+	if err != nil {
+		return 0, err
+	}
+
+	var groups []string
+	err = db.NewSelect().
+		TableExpr("message_embedding AS me").
+		Join("JOIN session AS s").
+		JoinOn("s.session_id = me.session_id").
+		ColumnExpr("DISTINCT "+groupExpr).
+		Where("me.deleted_at IS NULL").
+		Where("me.is_embedded").
+		Where("me.created_at >= ?", since).
+		Scan(ctx, &groups)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sessions to deduplicate: %w", err)
+	}
+
+	var duplicateCount int
+	for _, group := range groups {
+		count, err := deduplicateGroup(ctx, db, groupExpr, group, threshold)
+		if err != nil {
+			return duplicateCount, err
+		}
+		duplicateCount += count
+	}
+
+	log.Infof("completed deduplicating message embeddings. %d duplicates found", duplicateCount)
+
+	return duplicateCount, nil
+}
+
+func deduplicateGroup(
+	ctx context.Context,
+	db *bun.DB,
+	groupExpr string,
+	group string,
+	threshold float32,
+) (int, error) {
+	var rows []struct {
+		MessageUUID uuid.UUID       `bun:"message_uuid"`
+		Embedding   pgvector.Vector `bun:"embedding"`
+	}
+	// Existing duplicates are excluded, so previously selected representatives
+	// remain representatives on subsequent runs.
+	err := db.NewSelect().
+		TableExpr("message_embedding AS me").
+		Join("JOIN message AS m").
+		JoinOn("m.uuid = me.message_uuid").
+		Join("JOIN session AS s").
+		JoinOn("s.session_id = me.session_id").
+		ColumnExpr("me.message_uuid, me.embedding").
+		Where("me.deleted_at IS NULL").
+		Where("m.deleted_at IS NULL").
+		Where("me.is_embedded").
+		Where("me.duplicate_of IS NULL").
+		Where("? = ?", bun.Safe(groupExpr), group).
+		Order("m.id ASC").
+		Scan(ctx, &rows)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get message embeddings for %s: %w", group, err)
+	}
+
+	embeddingList := make([][]float32, len(rows))
+	for i := range rows {
+		embeddingList[i] = rows[i].Embedding.Slice()
+	}
+
+	duplicateOf, err := search.NearDuplicates(embeddingList, threshold)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find near-duplicates for %s: %w", group, err)
+	}
+
+	clusters := make(map[uuid.UUID][]uuid.UUID)
+	for i, r := range duplicateOf {
+		if r == -1 {
+			continue
+		}
+		representative := rows[r].MessageUUID
+		clusters[representative] = append(clusters[representative], rows[i].MessageUUID)
+	}
+
+	var count int
+	for representative, duplicates := range clusters {
+		_, err := db.NewUpdate().
+			Model((*MessageVectorStoreSchema)(nil)).
+			Set("duplicate_of = ?", representative).
+			Where("message_uuid IN (?)", bun.In(duplicates)).
+			Exec(ctx)
+		if err != nil {
+			return count, fmt.Errorf("failed to mark duplicate message embeddings: %w", err)
+		}
+		count += len(duplicates)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+func TestDeduplicateMessageEmbeddings(t *testing.T) {
+	sessionID := createSession(t)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	require.NoError(t, err)
+
+	messages := make([]models.Message, 3)
+	for i := range messages {
+		messages[i] = models.Message{
+			Role:    "user",
+			Content: fmt.Sprintf("testContent%d", i),
+		}
+	}
+	messages, err = messageDAO.CreateMany(testCtx, messages)
+	require.NoError(t, err)
+
+	vector := genTestVector(t, 1536)
+	err = messageDAO.CreateEmbeddings(testCtx, []models.TextData{
+		{TextUUID: messages[0].UUID, Embedding: vector},
+		{TextUUID: messages[1].UUID, Embedding: genTestVector(t, 1536)},
+		{TextUUID: messages[2].UUID, Embedding: vector},
+	})
+	require.NoError(t, err)
+
+	count, err := deduplicateMessageEmbeddings(
+		testCtx,
+		testDB,
+		time.Now().Add(-time.Minute),
+		0.99,
+		models.DeduplicationScopeSession,
+	)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, count, 1)
+
+	var embedding MessageVectorStoreSchema
+	err = testDB.NewSelect().
+		Model(&embedding).
+		Where("message_uuid = ?", messages[2].UUID).
+		Scan(testCtx)
+	assert.NoError(t, err)
+	require.NotNil(t, embedding.DuplicateOf)
+	assert.Equal(t, messages[0].UUID, *embedding.DuplicateOf)
+
+	// Representatives are not marked as duplicates
+	var representative MessageVectorStoreSchema
+	err = testDB.NewSelect().
+		Model(&representative).
+		Where("message_uuid = ?", messages[0].UUID).
+		Scan(testCtx)
+	assert.NoError(t, err)
+	assert.Nil(t, representative.DuplicateOf)
+
+	_, err = deduplicateMessageEmbeddings(testCtx, testDB, time.Time{}, 0.99, "invalid")
+	assert.Error(t, err)
+}
+
+func TestDeduplicateMessageEmbeddingsUserScope(t *testing.T) {
+	userID := testutils.GenerateRandomString(16)
+	_, err := NewUserStoreDAO(testDB).Create(testCtx, &models.CreateUserRequest{UserID: userID})
+	require.NoError(t, err)
+
+	vector := genTestVector(t, 1536)
+	sessionIDs := make([]string, 2)
+	messageUUIDs := make([]uuid.UUID, 2)
+	for i := range sessionIDs {
+		sessionIDs[i], err = testutils.GenerateRandomSessionID(16)
+		require.NoError(t, err)
+		_, err = NewSessionDAO(testDB).Create(testCtx, &models.CreateSessionRequest{
+			SessionID: sessionIDs[i],
+			UserID:    &userID,
+		})
+		require.NoError(t, err)
+
+		messageDAO, err := NewMessageDAO(testDB, appState, sessionIDs[i])
+		require.NoError(t, err)
+		messages, err := messageDAO.CreateMany(testCtx, []models.Message{
+			{Role: "user", Content: "testContent"},
+		})
+		require.NoError(t, err)
+		messageUUIDs[i] = messages[0].UUID
+		err = messageDAO.CreateEmbeddings(testCtx, []models.TextData{
+			{TextUUID: messages[0].UUID, Embedding: vector},
+		})
+		require.NoError(t, err)
+	}
+
+	_, err = deduplicateMessageEmbeddings(
+		testCtx,
+		testDB,
+		time.Now().Add(-time.Minute),
+		0.99,
+		models.DeduplicationScopeUser,
+	)
+	require.NoError(t, err)
+
+	var embedding MessageVectorStoreSchema
+	err = testDB.NewSelect().
+		Model(&embedding).
+		Where("message_uuid = ?", messageUUIDs[1]).
+		Scan(testCtx)
+	require.NoError(t, err)
+	require.NotNil(t, embedding.DuplicateOf)
+	assert.Equal(t, messageUUIDs[0], *embedding.DuplicateOf)
+
+	// the duplicate's representative is in another session, so the duplicate is still
+	// searched in its own
+	search := &models.MemorySearchPayload{SearchScope: models.SearchScopeMessages}
+	var found []uuid.UUID
+	err = testDB.NewSelect().
+		TableExpr(
+			"(?) AS q",
+			buildMessageSearchQuery(testCtx, testDB, search, false).
+				Where("m.session_id = ?", sessionIDs[1]),
+		).
+		ColumnExpr("q.message__uuid").
+		Scan(testCtx, &found)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{messageUUIDs[1]}, found)
+}
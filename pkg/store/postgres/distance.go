@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+)
+
+var distanceFunctions = []models.DistanceFunction{
+	models.DistanceFunctionCosine,
+	models.DistanceFunctionDotProduct,
+	models.DistanceFunctionL2,
+}
+
+func validateDistanceFunction(df models.DistanceFunction) error {
+	for _, v := range distanceFunctions {
+		if df == v {
+			return nil
+		}
+	}
+	return models.NewValidationError("unknown distance function " + string(df))
+}
+
+// modelDistanceFunction returns the validated distance function of an embedding model,
+// cosine if none is configured.
+func modelDistanceFunction(model *models.EmbeddingModel) (models.DistanceFunction, error) {
+	if model.DistanceFunction == "" {
+		return models.DistanceFunctionCosine, nil
+	}
+	if err := validateDistanceFunction(model.DistanceFunction); err != nil {
+		return "", fmt.Errorf("invalid embedding distance function: %s", model.DistanceFunction)
+	}
+	return model.DistanceFunction, nil
+}
+
+// memoryDistanceFunction returns the distance function of the message or summary
+// embeddings. It's validated when the memory indexes are created.
+func memoryDistanceFunction(
+	appState *models.AppState,
+	documentType string,
+) (models.DistanceFunction, error) {
+	model, err := llms.GetEmbeddingModel(appState, documentType)
+	if err != nil {
+		return "", fmt.Errorf("error getting %s embedding model: %w", documentType, err)
+	}
+	return modelDistanceFunction(model)
+}
+
+// distanceOpsPrefix returns the prefix of the operator classes of a distance function, as
+// in vector_cosine_ops, which also suffixes the names of its indexes.
+func distanceOpsPrefix(df models.DistanceFunction) string {
+	switch df {
+	case models.DistanceFunctionDotProduct:
+		return "ip"
+	case models.DistanceFunctionL2:
+		return "l2"
+	default:
+		return "cosine"
+	}
+}
+
+// indexNameSuffix returns the suffix naming the indexes of a distance function, so that
+// indexes are recreated when it changes. Cosine indexes keep their original names.
+func indexNameSuffix(df models.DistanceFunction) string {
+	if df == models.DistanceFunctionCosine || df == "" {
+		return ""
+	}
+	return "_" + distanceOpsPrefix(df)
+}
+
+// similarityExpr returns the SQL expression of the similarity of the embedding column to
+// the query vector, its argument, by the pgvector operator of the distance function. Higher
+// is more similar: the cosine similarity, the inner product, or 1 / (1 + the Euclidean
+// distance).
+func similarityExpr(df models.DistanceFunction) string {
+	switch df {
+	case models.DistanceFunctionDotProduct:
+		return "((embedding <#> ?) * -1)"
+	case models.DistanceFunctionL2:
+		return "(1 / (1 + (embedding <-> ?)))"
+	default:
+		return "(1 - (embedding <=> ?))"
+	}
+}
+
+// similarityDistance returns the distance returned by the pgvector operator of the
+// distance function, of which similarity is the similarityExpr.
+func similarityDistance(df models.DistanceFunction, similarity float64) float64 {
+	switch df {
+	case models.DistanceFunctionDotProduct:
+		return -similarity
+	case models.DistanceFunctionL2:
+		return 1/similarity - 1
+	default:
+		return 1 - similarity
+	}
+}
+
+// vectorIndexNames returns the names of every vector index that may exist on a column, of
+// any type, quantization and distance function.
+func vectorIndexNames(table, column string) []string {
+	var names []string
+	for _, df := range distanceFunctions {
+		names = append(names, ivfflatIndexName(table, column, df))
+		for _, q := range quantizations {
+			names = append(names, hnswIndexName(table, column, q, df))
+		}
+	}
+	return names
+}
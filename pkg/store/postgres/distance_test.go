@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestDistanceFunctions(t *testing.T) {
+	tests := []struct {
+		df           models.DistanceFunction
+		wantExpr     string
+		wantHalfExpr string
+		wantHNSW     string
+		wantIVFFlat  string
+		similarity   float64
+		wantDistance float64
+	}{
+		{
+			df:           models.DistanceFunctionCosine,
+			wantExpr:     "embedding vector_cosine_ops",
+			wantHalfExpr: "embedding halfvec_cosine_ops",
+			wantHNSW:     "message_embedding_embedding_hnsw_idx",
+			wantIVFFlat:  "message_embedding_embedding_idx",
+			similarity:   0.75,
+			wantDistance: 0.25,
+		},
+		{
+			df:           models.DistanceFunctionDotProduct,
+			wantExpr:     "embedding vector_ip_ops",
+			wantHalfExpr: "embedding halfvec_ip_ops",
+			wantHNSW:     "message_embedding_embedding_ip_hnsw_idx",
+			wantIVFFlat:  "message_embedding_embedding_ip_idx",
+			similarity:   0.75,
+			wantDistance: -0.75,
+		},
+		{
+			df:           models.DistanceFunctionL2,
+			wantExpr:     "embedding vector_l2_ops",
+			wantHalfExpr: "embedding halfvec_l2_ops",
+			wantHNSW:     "message_embedding_embedding_l2_hnsw_idx",
+			wantIVFFlat:  "message_embedding_embedding_l2_idx",
+			similarity:   0.25,
+			wantDistance: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.df), func(t *testing.T) {
+			assert.NoError(t, validateDistanceFunction(tt.df))
+			assert.Equal(
+				t,
+				tt.wantExpr,
+				vectorIndexExpr("embedding", 384, models.QuantizationNone, tt.df),
+			)
+			assert.Equal(
+				t,
+				tt.wantHalfExpr,
+				vectorIndexExpr("embedding", 384, models.QuantizationHalfVec, tt.df),
+			)
+			assert.Equal(
+				t,
+				"(binary_quantize(embedding)::bit(384)) bit_hamming_ops",
+				vectorIndexExpr("embedding", 384, models.QuantizationBit, tt.df),
+			)
+			assert.Equal(
+				t,
+				tt.wantHNSW,
+				hnswIndexName("message_embedding", "embedding", models.QuantizationNone, tt.df),
+			)
+			assert.Equal(
+				t,
+				tt.wantIVFFlat,
+				ivfflatIndexName("message_embedding", "embedding", tt.df),
+			)
+			assert.InDelta(t, tt.wantDistance, similarityDistance(tt.df, tt.similarity), 1e-9)
+		})
+	}
+
+	assert.ErrorIs(t, validateDistanceFunction("hamming"), models.ErrValidation)
+
+	df, err := modelDistanceFunction(&models.EmbeddingModel{})
+	assert.NoError(t, err)
+	assert.Equal(t, models.DistanceFunctionCosine, df)
+	_, err = modelDistanceFunction(&models.EmbeddingModel{DistanceFunction: "hamming"})
+	assert.Error(t, err)
+
+	// every index is named distinctly, so that all can be dropped
+	names := vectorIndexNames("message_embedding", "embedding")
+	unique := make(map[string]bool)
+	for _, name := range names {
+		unique[name] = true
+	}
+	assert.Len(t, unique, len(distanceFunctions)*(len(quantizations)+1))
+
+	// documents keep cosine scores normalized to between 0 and 1
+	assert.Equal(
+		t,
+		"((1 - (embedding <=> ?))/2 + 0.5)",
+		documentScoreExpr(models.DistanceFunctionCosine),
+	)
+	assert.InDelta(t, 0.5, documentScoreDistance(models.DistanceFunctionCosine, 0.75), 1e-9)
+	assert.Equal(
+		t,
+		similarityExpr(models.DistanceFunctionL2),
+		documentScoreExpr(models.DistanceFunctionL2),
+	)
+}
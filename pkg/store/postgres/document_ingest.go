@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	DefaultIngestBatchSize = 500
+	DefaultIngestWorkers   = 4
+)
+
+// IngestDocuments creates documents in batches, inserted by a bounded pool of workers. A batch
+// that fails to insert is retried one document at a time, so that a single bad document is
+// reported in its result rather than failing the batch. Embedding tasks for auto-embedded
+// collections are published as each batch is inserted.
+func (ds *DocumentStore) IngestDocuments(
+	ctx context.Context,
+	collectionName string,
+	documents []models.Document,
+) (*models.DocumentIngestResponse, error) {
+	if collectionName == "" {
+		return nil, models.NewValidationError("collection name is empty")
+	}
+	start := time.Now()
+
+	collection := NewDocumentCollectionDAO(
+		ds.appState,
+		ds.Client,
+		models.DocumentCollection{Name: collectionName},
+	)
+	if err := collection.GetByName(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+
+	results := make([]models.DocumentIngestResult, len(documents))
+	pending := make([]int, 0, len(documents))
+	for i := range documents {
+		results[i] = models.DocumentIngestResult{
+			Index:      i,
+			DocumentID: documents[i].DocumentID,
+		}
+		hasEmbedding := len(documents[i].Embedding) != 0
+		switch {
+		case collection.IsAutoEmbedded && hasEmbedding:
+			results[i].Error = "cannot create documents with embeddings in an auto-embedded collection"
+		case !collection.IsAutoEmbedded && !hasEmbedding:
+			results[i].Error = "cannot create documents without embeddings in a non-auto-embedded collection"
+		default:
+			// documents in a collection that is not auto embedded must have been given embeddings
+			documents[i].IsEmbedded = !collection.IsAutoEmbedded
+			pending = append(pending, i)
+		}
+	}
+
+	batchSize := ds.appState.Config.Store.Postgres.Ingest.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultIngestBatchSize
+	}
+	workers := ds.appState.Config.Store.Postgres.Ingest.Workers
+	if workers <= 0 {
+		workers = DefaultIngestWorkers
+	}
+
+	batches := make(chan []int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				ds.ingestBatch(ctx, collection, documents, batch, results)
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < len(pending); i += batchSize {
+		end := i + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		select {
+		case batches <- pending[i:end]:
+		case <-ctx.Done():
+			for _, idx := range pending[i:] {
+				results[idx].Error = ctx.Err().Error()
+			}
+			break feed
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	response := &models.DocumentIngestResponse{Results: results}
+	for i := range results {
+		if results[i].Error == "" {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+
+	elapsed := time.Since(start)
+	response.DurationMs = elapsed.Milliseconds()
+	if elapsed > 0 {
+		response.DocumentsPerSecond = float64(response.Succeeded) / elapsed.Seconds()
+	}
+
+	log.Infof(
+		"ingested %d documents into collection %s in %s (%.1f docs/sec), %d failed",
+		response.Succeeded,
+		collectionName,
+		elapsed,
+		response.DocumentsPerSecond,
+		response.Failed,
+	)
+
+	return response, nil
+}
+
+// ingestBatch inserts the documents at the given indexes, recording the outcome of each in results.
+// Each batch writes to distinct indexes of documents and results, so workers need not synchronize.
+func (ds *DocumentStore) ingestBatch(
+	ctx context.Context,
+	collection *DocumentCollectionDAO,
+	documents []models.Document,
+	batch []int,
+	results []models.DocumentIngestResult,
+) {
+	docs := make([]models.Document, len(batch))
+	for i, idx := range batch {
+		docs[i] = documents[idx]
+	}
+
+	inserted := make([]models.Document, 0, len(docs))
+	err := collection.insertDocuments(ctx, docs)
+	if err == nil {
+		inserted = append(inserted, docs...)
+	} else {
+		log.Warnf("failed to insert document batch, retrying individually: %v", err)
+		for i := range docs {
+			single := docs[i : i+1]
+			if err := collection.insertDocuments(ctx, single); err != nil {
+				results[batch[i]].Error = err.Error()
+				continue
+			}
+			inserted = append(inserted, single[0])
+		}
+	}
+
+	for i, idx := range batch {
+		if results[idx].Error != "" {
+			continue
+		}
+		documents[idx].UUID = docs[i].UUID
+		id := docs[i].UUID
+		results[idx].UUID = &id
+	}
+
+	if collection.IsAutoEmbedded && len(inserted) > 0 {
+		ds.documentEmbeddingTasker(ctx, collection.DocumentCollection, inserted)
+	}
+	ds.documentSummaryTasker(ctx, collection.Name, inserted)
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+func TestIngestDocuments(t *testing.T) {
+	ctx := context.Background()
+
+	ingestConfig := appState.Config.Store.Postgres.Ingest
+	appState.Config.Store.Postgres.Ingest.BatchSize = 2
+	appState.Config.Store.Postgres.Ingest.Workers = 2
+	defer func() { appState.Config.Store.Postgres.Ingest = ingestConfig }()
+
+	ds, err := NewDocumentStore(ctx, appState, testDB)
+	require.NoError(t, err)
+
+	collection := NewTestCollectionDAO(5)
+	collection.IsAutoEmbedded = false
+	err = collection.Create(ctx)
+	require.NoError(t, err)
+
+	duplicateID := testutils.GenerateRandomString(10)
+	documents := make([]models.Document, 7)
+	for i := range documents {
+		documents[i] = models.Document{
+			DocumentBase: models.DocumentBase{
+				DocumentID: testutils.GenerateRandomString(10),
+				Content:    testutils.GenerateRandomString(10),
+			},
+			Embedding: []float32{0.1, 0.2, 0.3, 0.4, 0.5},
+		}
+	}
+	// missing embedding
+	documents[1].Embedding = nil
+	// duplicate document_id within a batch
+	documents[5].DocumentID = duplicateID
+	documents[6].DocumentID = duplicateID
+
+	response, err := ds.IngestDocuments(ctx, collection.Name, documents)
+	require.NoError(t, err)
+
+	assert.Equal(t, len(documents), len(response.Results))
+	assert.Equal(t, 5, response.Succeeded)
+	assert.Equal(t, 2, response.Failed)
+
+	for i, result := range response.Results {
+		assert.Equal(t, i, result.Index)
+		switch i {
+		case 1, 6:
+			assert.NotEmpty(t, result.Error)
+			assert.Nil(t, result.UUID)
+		default:
+			assert.Empty(t, result.Error)
+			require.NotNil(t, result.UUID)
+			assert.Equal(t, documents[i].UUID, *result.UUID)
+		}
+	}
+
+	returned, err := collection.GetDocuments(ctx, 0, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, len(returned))
+}
@@ -5,21 +5,30 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/getzep/zep/pkg/llms"
 	"github.com/getzep/zep/pkg/search"
 	"github.com/getzep/zep/pkg/store"
+	"github.com/google/uuid"
 	"github.com/pgvector/pgvector-go"
 	"github.com/uptrace/bun"
 
 	"github.com/getzep/zep/pkg/models"
 )
 
+// DefaultEFSearch is the hnsw.ef_search of searches when store.postgres.vector_index.ef_search
+// isn't set.
 const DefaultEFSearch = 100
 const DefaultDocumentSearchLimit = 20
 const MaxParallelWorkersPerGather = 4
 
+// VectorStoreFilterMultiplier is the number of embeddings searched per result by searches
+// of an external vector store with metadata filters.
+const VectorStoreFilterMultiplier = 5
+
 func newDocumentSearchOperation(
 	ctx context.Context,
 	appState *models.AppState,
@@ -58,27 +67,103 @@ func (dso *documentSearchOperation) Execute() (*models.DocumentSearchResultPage,
 	var count int
 	var err error
 
+	debug := models.SearchDebugFromContext(dso.ctx)
+	if dso.searchVectorStore() {
+		results, err = dso.execVectorStoreQuery()
+		if err != nil {
+			return nil, fmt.Errorf("error executing search: %w", err)
+		}
+		count = len(results)
+	} else {
+		results, count, err = dso.execPostgresQuery()
+		if err != nil {
+			return nil, fmt.Errorf("error executing search: %w", err)
+		}
+	}
+
+	if dso.searchPayload.SearchType == models.SearchTypeMMR {
+		started := time.Now()
+		results, err = dso.reRankMMR(results)
+		if err != nil {
+			return nil, fmt.Errorf("error reranking results: %w", err)
+		}
+		debug.AddRerank(time.Since(started))
+	}
+	if dso.queryVector != nil {
+		distances := make([]float64, len(results))
+		for i := range results {
+			distances[i] = documentScoreDistance(dso.collection.DistanceFunction, results[i].Score)
+		}
+		debug.SetDistances(distances)
+	}
+
+	resultPage := &models.DocumentSearchResultPage{
+		Results: searchResultsFromSearchQueries(
+			results,
+			dso.searchPayload.ReturnSummaries,
+		),
+		QueryVector: dso.queryVector,
+		ResultCount: count,
+	}
+	if dso.queryVector != nil && dso.searchPayload.SearchType != models.SearchTypeMMR &&
+		!dso.searchVectorStore() && len(results) == dso.limit {
+		last := results[len(results)-1]
+		resultPage.NextCursor = models.SearchCursor{Score: last.Score, UUID: last.UUID}.Encode()
+	}
+
+	return resultPage, nil
+}
+
+// execPostgresQuery searches the collection table, returning the results and their count.
+func (dso *documentSearchOperation) execPostgresQuery() (
+	[]models.SearchDocumentResult,
+	int,
+	error,
+) {
+	var results []models.SearchDocumentResult
+	var count int
+	var err error
+
 	// run in transaction to set LOCAL
+	debug := models.SearchDebugFromContext(dso.ctx)
 	err = dso.db.RunInTx(dso.ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		// exact searches scan the table in parallel, as unindexed collections are
+		indexed := dso.collection.IsIndexed && !dso.searchPayload.Exact
 		switch dso.collection.IndexType {
-		case "ivfflat":
-			if dso.collection.IsIndexed {
+		case models.IndexTypeIVFFlat:
+			if indexed {
 				_, err = tx.Exec("SET LOCAL ivfflat.probes = ?", dso.collection.ProbeCount)
+				debug.SetSetting("ivfflat.probes", strconv.Itoa(dso.collection.ProbeCount))
 			} else {
 				_, err = tx.Exec("SET LOCAL max_parallel_workers_per_gather = ?", MaxParallelWorkersPerGather)
+				debug.SetSetting(
+					"max_parallel_workers_per_gather",
+					strconv.Itoa(MaxParallelWorkersPerGather),
+				)
 			}
 			if err != nil {
 				return fmt.Errorf("error setting probes: %w", err)
 			}
-		case "hnsw":
-			if dso.collection.IsIndexed {
-				_, err = tx.Exec("SET LOCAL hnsw.ef_search = ?", DefaultEFSearch)
+		case models.IndexTypeHNSW:
+			if indexed {
+				ef := efSearch(dso.appState)
+				_, err = tx.Exec("SET LOCAL hnsw.ef_search = ?", ef)
+				debug.SetSetting("hnsw.ef_search", strconv.Itoa(ef))
 			} else {
 				_, err = tx.Exec("SET LOCAL max_parallel_workers_per_gather = ?", MaxParallelWorkersPerGather)
+				debug.SetSetting(
+					"max_parallel_workers_per_gather",
+					strconv.Itoa(MaxParallelWorkersPerGather),
+				)
 			}
 		default:
 			return fmt.Errorf("unknown index type %s", dso.collection.IndexType)
 		}
+		if dso.searchPayload.Exact {
+			if err := disableIndexScans(ctx, tx); err != nil {
+				return err
+			}
+		}
 
 		count, err = dso.execQuery(tx, &results)
 		if err != nil {
@@ -88,23 +173,10 @@ func (dso *documentSearchOperation) Execute() (*models.DocumentSearchResultPage,
 		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("error executing search: %w", err)
-	}
-
-	if dso.searchPayload.SearchType == models.SearchTypeMMR {
-		results, err = dso.reRankMMR(results)
-		if err != nil {
-			return nil, fmt.Errorf("error reranking results: %w", err)
-		}
+		return nil, 0, err
 	}
 
-	resultPage := &models.DocumentSearchResultPage{
-		Results:     searchResultsFromSearchQueries(results),
-		QueryVector: dso.queryVector,
-		ResultCount: count,
-	}
-
-	return resultPage, nil
+	return results, count, nil
 }
 
 // reRankMMR reranks the results using the MMR algorithm.
@@ -144,33 +216,76 @@ func (dso *documentSearchOperation) execQuery(
 	db bun.IDB,
 	results *[]models.SearchDocumentResult,
 ) (int, error) {
-	query, err := dso.buildQuery(db)
+	// Searches without metadata filters are run as prepared statements if enabled.
+	params := &queryParams{
+		enabled: dso.appState.Config.Store.Postgres.PreparedStatements &&
+			len(dso.searchPayload.Metadata) == 0,
+	}
+
+	query, limit, err := dso.buildQuery(db, params)
 	if err != nil {
 		return 0, fmt.Errorf("error building query %w", err)
 	}
 
-	err = query.Scan(dso.ctx, results)
+	started := time.Now()
+	var queryString string
+	if params.enabled {
+		var tx *bun.Tx
+		if t, ok := db.(bun.Tx); ok {
+			tx = &t
+		}
+		queryString = query.String() + " LIMIT " + params.placeholder(limit)
+		err = queryPrepared(dso.ctx, dso.db, tx, queryString, params.args, results)
+	} else {
+		query = query.Limit(limit)
+		queryString = query.String()
+		err = query.Scan(dso.ctx, results)
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "different vector dimensions") {
 			return 0, store.NewEmbeddingMismatchError(err)
 		}
 		return 0, fmt.Errorf("error scanning query %w", err)
 	}
+	models.SearchDebugFromContext(dso.ctx).AddQuery(queryString, time.Since(started))
 
 	count := len(*results)
 
 	return count, nil
 }
 
-func (dso *documentSearchOperation) buildQuery(db bun.IDB) (*bun.SelectQuery, error) {
+// buildQuery builds the search query, returning it with the number of rows to be retrieved.
+// The caller is responsible for applying the limit.
+func (dso *documentSearchOperation) buildQuery(
+	db bun.IDB,
+	params *queryParams,
+) (*bun.SelectQuery, int, error) {
 	m := &[]models.SearchDocumentResult{}
 	query := db.NewSelect().Model(m).
 		ModelTableExpr("?", bun.Ident(dso.collection.TableName)).
 		Column("*").
-		WhereAllWithDeleted().
-		Where("deleted_at IS NULL") // Manually add as ModelTableExpr confuses bun
+		WhereAllWithDeleted()
+
+	// filter applies the search's where clauses to a query of the collection's table. They're
+	// applied to both the search query and its bit quantized candidates.
+	var wheres []func(*bun.SelectQuery) *bun.SelectQuery
+	filter := func(q *bun.SelectQuery) (*bun.SelectQuery, error) {
+		q = q.Where("deleted_at IS NULL") // Manually add as ModelTableExpr confuses bun
+		for _, where := range wheres {
+			q = where(q)
+		}
+		if len(dso.searchPayload.Metadata) > 0 {
+			var err error
+			q, err = dso.applyDocsMetadataFilter(q, dso.searchPayload.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("error applying metadata filter: %w", err)
+			}
+		}
+		return q, nil
+	}
 
 	// Add the vector column if either text or embedding is set
+	var vectorArg interface{}
 	if dso.searchPayload.Text != "" || len(dso.searchPayload.Embedding) != 0 {
 		var v pgvector.Vector
 		var err error
@@ -179,26 +294,77 @@ func (dso *documentSearchOperation) buildQuery(db bun.IDB) (*bun.SelectQuery, er
 		} else {
 			v, err = dso.getDocQueryVector(dso.searchPayload.Text)
 			if err != nil {
-				return nil, fmt.Errorf("error getting query vector %w", err)
+				return nil, 0, fmt.Errorf("error getting query vector %w", err)
 			}
 		}
 		dso.queryVector = v.Slice()
 
-		// Score is cosine similarity normalized to 1
-		query = query.ColumnExpr("((1 - (embedding <=> ?))/2 + 0.5) AS score", v)
+		vectorArg = params.add(v)
+		scoreExpr := documentScoreExpr(dso.collection.DistanceFunction)
+		query = query.ColumnExpr(scoreExpr+" AS score", vectorArg)
+		if dso.searchPayload.MinScore != 0 {
+			minScoreArg := params.add(dso.searchPayload.MinScore)
+			wheres = append(wheres, func(q *bun.SelectQuery) *bun.SelectQuery {
+				return q.Where(scoreExpr+" >= ?", vectorArg, minScoreArg)
+			})
+		}
+		if dso.searchPayload.Cursor != "" {
+			cursor, err := models.DecodeSearchCursor(dso.searchPayload.Cursor)
+			if err != nil {
+				return nil, 0, err
+			}
+			scoreArg, uuidArg := params.add(cursor.Score), params.add(cursor.UUID)
+			wheres = append(wheres, func(q *bun.SelectQuery) *bun.SelectQuery {
+				return q.Where("("+scoreExpr+", uuid) < (?, ?)", vectorArg, scoreArg, uuidArg)
+			})
+		}
 	}
 
-	if len(dso.searchPayload.Metadata) > 0 {
-		var err error
-		query, err = dso.applyDocsMetadataFilter(query, dso.searchPayload.Metadata)
+	query, err := filter(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Bit quantized collections are searched using the hamming distance index, with
+	// candidates then scored using their full precision embeddings.
+	if vectorArg != nil {
+		model, err := llms.GetEmbeddingModel(dso.appState, "document")
 		if err != nil {
-			return nil, fmt.Errorf("error applying metadata filter: %w", err)
+			return nil, 0, fmt.Errorf("failed to get document embedding model %w", err)
+		}
+		if model.Quantization == models.QuantizationBit {
+			candidates, err := filter(
+				db.NewSelect().ModelTableExpr("?", bun.Ident(dso.collection.TableName)),
+			)
+			if err != nil {
+				return nil, 0, err
+			}
+			query = bitCandidatesFilter(
+				query,
+				candidates,
+				"uuid",
+				dso.collection.EmbeddingDimensions,
+				vectorArg,
+				params.add(dso.limit*BitCandidateMultiplier),
+			)
 		}
 	}
 
-	// Add LIMIT
-	// If we're using MMR, we need to add a limit of 2x the requested limit to allow for the MMR
-	// algorithm to rerank and filter out results.
+	limit := dso.candidateLimit()
+
+	// Order by dist - required for index to be used. Ties are ordered by UUID, so that
+	// results can be paged by keyset.
+	if dso.searchPayload.Text != "" || len(dso.searchPayload.Embedding) != 0 {
+		query.Order("score DESC", "uuid DESC")
+	}
+
+	return query, limit, nil
+}
+
+// candidateLimit returns the number of results to be retrieved. If we're using MMR, we need
+// to add a limit of 2x the requested limit to allow for the MMR algorithm to rerank and
+// filter out results.
+func (dso *documentSearchOperation) candidateLimit() int {
 	limit := dso.limit
 	if dso.searchPayload.SearchType == models.SearchTypeMMR {
 		limit *= DefaultMMRMultiplier
@@ -206,14 +372,136 @@ func (dso *documentSearchOperation) buildQuery(db bun.IDB) (*bun.SelectQuery, er
 			limit = 10
 		}
 	}
-	query = query.Limit(limit)
+	return limit
+}
 
-	// Order by dist - required for index to be used.
-	if dso.searchPayload.Text != "" || len(dso.searchPayload.Embedding) != 0 {
-		query.Order("score DESC")
+// searchVectorStore returns true if the search is of embeddings stored in an external
+// vector store.
+func (dso *documentSearchOperation) searchVectorStore() bool {
+	return dso.appState.VectorStore != nil &&
+		(dso.searchPayload.Text != "" || len(dso.searchPayload.Embedding) != 0)
+}
+
+// execVectorStoreQuery searches the embeddings of the vector store, then reads the matching
+// documents from the collection table. As metadata filters are applied to the documents,
+// VectorStoreFilterMultiplier times as many embeddings are searched for filtered searches.
+func (dso *documentSearchOperation) execVectorStoreQuery() ([]models.SearchDocumentResult, error) {
+	if dso.searchPayload.Cursor != "" {
+		return nil, models.NewValidationError(
+			"searches of collections in an external vector store can't be paged",
+		)
 	}
 
-	return query, nil
+	dso.queryVector = dso.searchPayload.Embedding
+	if len(dso.queryVector) == 0 {
+		v, err := dso.getDocQueryVector(dso.searchPayload.Text)
+		if err != nil {
+			return nil, fmt.Errorf("error getting query vector %w", err)
+		}
+		dso.queryVector = v.Slice()
+	}
+	if len(dso.queryVector) != dso.collection.EmbeddingDimensions {
+		return nil, store.NewEmbeddingMismatchError(fmt.Errorf(
+			"expected %d dimensions, not %d",
+			dso.collection.EmbeddingDimensions,
+			len(dso.queryVector),
+		))
+	}
+
+	limit := dso.candidateLimit()
+	vectorLimit := limit
+	if len(dso.searchPayload.Metadata) > 0 {
+		vectorLimit *= VectorStoreFilterMultiplier
+	}
+	started := time.Now()
+	vectors, err := dso.appState.VectorStore.Search(
+		dso.ctx,
+		dso.collection,
+		&models.VectorQuery{
+			Embedding:      dso.queryVector,
+			Limit:          vectorLimit,
+			Exact:          dso.searchPayload.Exact,
+			WithEmbeddings: dso.searchPayload.SearchType == models.SearchTypeMMR,
+		},
+	)
+	if err != nil {
+		return nil, models.NewDependencyFailureError("vector store", err)
+	}
+	debug := models.SearchDebugFromContext(dso.ctx)
+	debug.AddQuery("vector store search", time.Since(started))
+
+	minScore := dso.searchPayload.MinScore
+	uuids := make([]uuid.UUID, 0, len(vectors))
+	for i := range vectors {
+		if minScore == 0 || vectors[i].Score >= minScore {
+			uuids = append(uuids, vectors[i].UUID)
+		}
+	}
+	if len(uuids) == 0 {
+		return nil, nil
+	}
+
+	var documents []models.Document
+	query := dso.db.NewSelect().
+		Model(&documents).
+		ModelTableExpr("?", bun.Ident(dso.collection.TableName)).
+		Column("*").
+		WhereAllWithDeleted().
+		Where("deleted_at IS NULL").
+		Where("uuid IN (?)", bun.In(uuids))
+	if len(dso.searchPayload.Metadata) > 0 {
+		query, err = dso.applyDocsMetadataFilter(query, dso.searchPayload.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("error applying metadata filter: %w", err)
+		}
+	}
+	started = time.Now()
+	if err := query.Scan(dso.ctx); err != nil {
+		return nil, fmt.Errorf("error scanning query %w", err)
+	}
+	debug.AddQuery(query.String(), time.Since(started))
+
+	// the results are ordered as found by the vector store
+	byUUID := make(map[uuid.UUID]*models.Document, len(documents))
+	for i := range documents {
+		byUUID[documents[i].UUID] = &documents[i]
+	}
+	results := make([]models.SearchDocumentResult, 0, min(len(documents), limit))
+	for i := range vectors {
+		document, ok := byUUID[vectors[i].UUID]
+		if !ok || (minScore != 0 && vectors[i].Score < minScore) {
+			continue
+		}
+		document.Embedding = vectors[i].Embedding
+		results = append(results, models.SearchDocumentResult{
+			Document: document,
+			Score:    vectors[i].Score,
+		})
+		if len(results) == limit {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+// documentScoreExpr returns the expression of a document's score, its similarity to the
+// query vector by the collection's distance function. Cosine similarities are normalized to
+// between 0 and 1.
+func documentScoreExpr(df models.DistanceFunction) string {
+	if df == models.DistanceFunctionCosine || df == "" {
+		return "((1 - (embedding <=> ?))/2 + 0.5)"
+	}
+	return similarityExpr(df)
+}
+
+// documentScoreDistance returns the distance of a document scored by documentScoreExpr.
+func documentScoreDistance(df models.DistanceFunction, score float64) float64 {
+	if df == models.DistanceFunctionCosine || df == "" {
+		// from distances between 0 and 2
+		return 2 * (1 - score)
+	}
+	return similarityDistance(df, score)
 }
 
 // getDocQueryVector returns the vector for the query text.
@@ -228,7 +516,7 @@ func (dso *documentSearchOperation) getDocQueryVector(
 
 	e, err := llms.EmbedTexts(dso.ctx, dso.appState, model, documentType, []string{queryText})
 	if err != nil {
-		return pgvector.Vector{}, fmt.Errorf("failed to embed query %w", err)
+		return pgvector.Vector{}, models.NewDependencyFailureError("query embedding", err)
 	}
 
 	v := pgvector.NewVector(e[0])
@@ -261,7 +549,13 @@ func (dso *documentSearchOperation) applyDocsMetadataFilter(
 	return query, nil
 }
 
-func searchResultsFromSearchQueries(s []models.SearchDocumentResult) []models.DocumentSearchResult {
+// searchResultsFromSearchQueries converts search query results to DocumentSearchResults.
+// If returnSummaries is true, summarized documents are returned with their summary as
+// their content.
+func searchResultsFromSearchQueries(
+	s []models.SearchDocumentResult,
+	returnSummaries bool,
+) []models.DocumentSearchResult {
 	result := make([]models.DocumentSearchResult, len(s))
 
 	for i := range s {
@@ -275,9 +569,14 @@ func searchResultsFromSearchQueries(s []models.SearchDocumentResult) []models.Do
 				Metadata:   s[i].Metadata,
 				Embedding:  s[i].Embedding,
 				IsEmbedded: s[i].IsEmbedded,
+				Summary:    s[i].Summary,
 			},
 			Score: s[i].Score,
 		}
+		if returnSummaries && s[i].Summary != "" {
+			result[i].Content = s[i].Summary
+			result[i].Summary = ""
+		}
 	}
 
 	return result
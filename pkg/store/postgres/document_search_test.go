@@ -65,6 +65,36 @@ func TestDocumentSearchWithIndexEndToEnd(t *testing.T) {
 		assert.NotEmpty(t, searchResults.Results[i].DocumentID)
 	}
 
+	// the next page follows the last result of the page
+	assert.NotEmpty(t, searchResults.NextCursor)
+	lastScore := searchResults.Results[limit-1].Score
+	searchPayload.Cursor = searchResults.NextCursor
+	nextPage, err := documentStore.SearchCollection(ctx, searchPayload, limit, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, nextPage.Results, limit)
+	for i := range nextPage.Results {
+		assert.LessOrEqual(t, nextPage.Results[i].Score, lastScore)
+		for j := range searchResults.Results {
+			assert.NotEqual(t, searchResults.Results[j].UUID, nextPage.Results[i].UUID)
+		}
+	}
+	searchPayload.Cursor = ""
+
+	// documents scoring less than min_score are excluded
+	searchPayload.MinScore = searchResults.Results[1].Score
+	searchResults, err = documentStore.SearchCollection(
+		ctx,
+		searchPayload,
+		limit,
+		0,
+		0,
+	)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, searchResults.Results)
+	for i := range searchResults.Results {
+		assert.GreaterOrEqual(t, searchResults.Results[i].Score, searchPayload.MinScore)
+	}
+
 	err = documentStore.Shutdown(ctx)
 	assert.NoError(t, err)
 
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// mapVectorStore is a models.VectorStore scoring embeddings by their inner product.
+type mapVectorStore struct {
+	embeddings map[uuid.UUID][]float32
+}
+
+func (s *mapVectorStore) Put(
+	_ context.Context,
+	_ *models.DocumentCollection,
+	records []models.VectorRecord,
+) error {
+	for _, record := range records {
+		s.embeddings[record.UUID] = record.Embedding
+	}
+	return nil
+}
+
+func (s *mapVectorStore) Delete(
+	_ context.Context,
+	_ *models.DocumentCollection,
+	uuids []uuid.UUID,
+) error {
+	if uuids == nil {
+		s.embeddings = make(map[uuid.UUID][]float32)
+	}
+	for _, id := range uuids {
+		delete(s.embeddings, id)
+	}
+	return nil
+}
+
+func (s *mapVectorStore) Search(
+	_ context.Context,
+	_ *models.DocumentCollection,
+	query *models.VectorQuery,
+) ([]models.VectorResult, error) {
+	var results []models.VectorResult
+	for id, embedding := range s.embeddings {
+		var score float64
+		for i := range embedding {
+			score += float64(embedding[i] * query.Embedding[i])
+		}
+		results = append(results, models.VectorResult{UUID: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results[:min(query.Limit, len(results))], nil
+}
+
+func TestDocumentCollectionVectorStore(t *testing.T) {
+	ctx := context.Background()
+	CleanDB(t, testDB)
+	require.NoError(t, CreateSchema(ctx, appState, testDB))
+
+	vectors := &mapVectorStore{embeddings: make(map[uuid.UUID][]float32)}
+	state := *appState
+	state.VectorStore = vectors
+	collection := NewTestCollectionDAO(3)
+	collection.appState = &state
+	collection.IsAutoEmbedded = false
+	collection.DistanceFunction = models.DistanceFunctionDotProduct
+	require.NoError(t, collection.Create(ctx))
+
+	documents := []models.Document{
+		{
+			DocumentBase: models.DocumentBase{
+				Content:  "near",
+				Metadata: map[string]interface{}{"kind": "a"},
+			},
+			Embedding: []float32{1, 0, 0},
+		},
+		{
+			DocumentBase: models.DocumentBase{
+				Content:  "far",
+				Metadata: map[string]interface{}{"kind": "b"},
+			},
+			Embedding: []float32{0, 1, 0},
+		},
+	}
+	uuids, err := collection.CreateDocuments(ctx, documents)
+	require.NoError(t, err)
+	require.Len(t, vectors.embeddings, 2)
+	assert.Equal(t, []float32{1, 0, 0}, vectors.embeddings[uuids[0]])
+
+	// the embeddings aren't stored in the collection table
+	stored, err := collection.GetDocuments(ctx, 0, uuids, nil)
+	require.NoError(t, err)
+	for _, document := range stored {
+		assert.Empty(t, document.Embedding)
+		assert.True(t, document.IsEmbedded)
+	}
+
+	_, err = collection.CreateDocuments(ctx, []models.Document{{Embedding: []float32{1, 0}}})
+	assert.Error(t, err, "embedding dimensions are checked")
+
+	search := func(payload *models.DocumentSearchPayload) []models.DocumentSearchResult {
+		page, err := collection.SearchDocuments(ctx, payload, 10, 0, 0)
+		require.NoError(t, err)
+		return page.Results
+	}
+	results := search(&models.DocumentSearchPayload{Embedding: []float32{1, 0.5, 0}})
+	require.Len(t, results, 2)
+	assert.Equal(t, "near", results[0].Content)
+	assert.InDelta(t, 1, results[0].Score, 1e-6)
+	assert.Equal(t, "far", results[1].Content)
+
+	results = search(&models.DocumentSearchPayload{Embedding: []float32{1, 0.5, 0}, MinScore: 0.9})
+	require.Len(t, results, 1)
+	results = search(&models.DocumentSearchPayload{
+		Embedding: []float32{1, 0.5, 0},
+		Metadata: map[string]interface{}{
+			"where": map[string]interface{}{"jsonpath": `$[*] ? (@.kind == "b")`},
+		},
+	})
+	require.Len(t, results, 1)
+	assert.Equal(t, "far", results[0].Content)
+
+	// updated embeddings replace those of the vector store
+	err = collection.UpdateDocuments(ctx, []models.Document{
+		{DocumentBase: models.DocumentBase{UUID: uuids[1]}, Embedding: []float32{2, 0, 0}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []float32{2, 0, 0}, vectors.embeddings[uuids[1]])
+
+	require.NoError(t, collection.DeleteDocumentsByUUID(ctx, []uuid.UUID{uuids[0]}))
+	assert.NotContains(t, vectors.embeddings, uuids[0])
+
+	require.NoError(t, collection.Delete(ctx))
+	assert.Empty(t, vectors.embeddings)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/getzep/zep/pkg/llms"
 	"github.com/getzep/zep/pkg/store"
 
 	"github.com/getzep/zep/pkg/models"
@@ -51,15 +52,38 @@ func (dc *DocumentCollectionDAO) Create(
 
 	// Determine the index type to use. Default to HNSW if available, otherwise
 	// use IVFFLAT.
-	dc.IndexType = "ivfflat"
-	if dc.appState.Config.Store.Postgres.AvailableIndexes.HSNW {
-		dc.IndexType = "hnsw"
-		// We'll create the index when we create the document table.
-		dc.IsIndexed = true
+	hnswAvailable := dc.appState.Config.Store.Postgres.AvailableIndexes.HSNW
+	switch dc.IndexType {
+	case "":
+		dc.IndexType = models.IndexTypeIVFFlat
+		if hnswAvailable {
+			dc.IndexType = models.IndexTypeHNSW
+		}
+	case models.IndexTypeHNSW:
+		if !hnswAvailable {
+			return models.NewValidationError("hnsw indexes require pgvector 0.5.0 or later")
+		}
+	case models.IndexTypeIVFFlat:
+	default:
+		return models.NewValidationError("unknown index type " + string(dc.IndexType))
 	}
+	// We'll create an HNSW index when we create the document table.
+	dc.IsIndexed = dc.IndexType == models.IndexTypeHNSW
 
-	// We only support cosine distance function for now.
-	dc.DistanceFunction = "cosine"
+	// Collections default to the distance function of the document embedding model.
+	if dc.DistanceFunction == "" {
+		model, err := llms.GetEmbeddingModel(dc.appState, "document")
+		if err != nil {
+			return fmt.Errorf("failed to get document embedding model: %w", err)
+		}
+		dc.DistanceFunction, err = modelDistanceFunction(model)
+		if err != nil {
+			return err
+		}
+	}
+	if err := validateDistanceFunction(dc.DistanceFunction); err != nil {
+		return err
+	}
 
 	collectionRecord := DocumentCollectionSchema{DocumentCollection: dc.DocumentCollection}
 
@@ -69,14 +93,22 @@ func (dc *DocumentCollectionDAO) Create(
 		Exec(ctx)
 	if err != nil {
 		if err, ok := err.(pgdriver.Error); ok && err.IntegrityViolation() {
-			return models.NewBadRequestError("collection already exists: " + dc.getName())
+			return models.NewConflictError("collection already exists: " + dc.getName())
 		}
 		return fmt.Errorf("failed to insert collection: %w", err)
 	}
 
 	// Create the document table for the collection. It will only be created if
 	// it doesn't already exist.
-	err = createDocumentTable(ctx, dc.appState, dc.db, dc.TableName, dc.EmbeddingDimensions)
+	err = createDocumentTable(
+		ctx,
+		dc.appState,
+		dc.db,
+		dc.TableName,
+		dc.EmbeddingDimensions,
+		dc.IndexType,
+		dc.DistanceFunction,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to create document table: %w", err)
 	}
@@ -87,6 +119,14 @@ func (dc *DocumentCollectionDAO) Create(
 // Update updates a collection in the collections table.
 func (dc *DocumentCollectionDAO) Update(
 	ctx context.Context,
+) error {
+	return withRetryErr(ctx, "update collection", func(ctx context.Context) error {
+		return dc.update(ctx)
+	})
+}
+
+func (dc *DocumentCollectionDAO) update(
+	ctx context.Context,
 ) error {
 	if dc.getName() == "" {
 		return errors.New("collection Name is required")
@@ -240,6 +280,14 @@ func (dc *DocumentCollectionDAO) Delete(ctx context.Context) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// the collection is deleted even if its embeddings can't be
+	if dc.appState.VectorStore != nil {
+		err = dc.appState.VectorStore.Delete(ctx, &dc.DocumentCollection, nil)
+		if err != nil {
+			log.Errorf("failed to delete embeddings of collection %s: %v", dc.Name, err)
+		}
+	}
+
 	return nil
 }
 
@@ -265,31 +313,131 @@ func (dc *DocumentCollectionDAO) CreateDocuments(
 			documents[i].IsEmbedded = true
 		}
 	}
-	_, err := dc.db.NewInsert().
-		Model(&documents).
-		ModelTableExpr("?", bun.Ident(dc.TableName)).
-		Returning("uuid").
-		Exec(ctx)
+	if err := dc.insertDocuments(ctx, documents); err != nil {
+		return nil, err
+	}
+
+	uuids := make([]uuid.UUID, len(documents))
+	for i := range documents {
+		uuids[i] = documents[i].UUID
+	}
+
+	return uuids, nil
+}
+
+// insertDocuments inserts the given documents into the collection table, setting their UUIDs.
+// Large batches are inserted using COPY. The collection must have been loaded.
+func (dc *DocumentCollectionDAO) insertDocuments(
+	ctx context.Context,
+	documents []models.Document,
+) error {
+	for i := range documents {
+		documents[i].ContentHash = models.ContentHash(documents[i].Content)
+	}
+	// The embeddings of an external vector store are put once the documents are inserted.
+	// The documents are left as given if the insert fails, as they may be inserted again.
+	rows := documents
+	var records []models.VectorRecord
+	if dc.appState.VectorStore != nil {
+		rows = make([]models.Document, len(documents))
+		copy(rows, documents)
+		for i := range rows {
+			if rows[i].UUID == uuid.Nil {
+				rows[i].UUID = uuid.New()
+			}
+		}
+		var err error
+		records, err = dc.vectorRecords(rows)
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			rows[i].Embedding = nil
+		}
+	}
+	err := withConflictRetryErr(ctx, "insert documents", func(ctx context.Context) error {
+		if useCopy(dc.appState, len(rows)) {
+			return dc.copyDocuments(ctx, rows)
+		}
+		_, err := dc.db.NewInsert().
+			Model(&rows).
+			ModelTableExpr("?", bun.Ident(dc.TableName)).
+			Returning("uuid").
+			Exec(ctx)
+		return err
+	})
 	if err != nil {
 		if err, ok := err.(pgdriver.Error); ok && err.IntegrityViolation() {
-			return nil, models.NewBadRequestError("document_id already exists")
+			return models.NewConflictError("document_id already exists")
 		}
 		if strings.Contains(err.Error(), "different vector dimensions") {
-			return nil, store.NewEmbeddingMismatchError(err)
+			return store.NewEmbeddingMismatchError(err)
 		}
-		return nil, fmt.Errorf("failed to insert documents: %w", err)
+		return fmt.Errorf("failed to insert documents: %w", err)
+	}
+	if dc.appState.VectorStore == nil {
+		return nil
 	}
 
-	uuids := make([]uuid.UUID, len(documents))
-	for i := range documents {
-		uuids[i] = documents[i].UUID
+	err = dc.appState.VectorStore.Put(ctx, &dc.DocumentCollection, records)
+	if err != nil {
+		// the documents would otherwise be marked as embedded without embeddings
+		uuids := make([]uuid.UUID, len(rows))
+		for i := range rows {
+			uuids[i] = rows[i].UUID
+		}
+		_, deleteErr := dc.db.NewDelete().
+			Model((*models.Document)(nil)).
+			ModelTableExpr("?", bun.Ident(dc.TableName)).
+			Where("uuid IN (?)", bun.In(uuids)).
+			WhereAllWithDeleted().
+			ForceDelete().
+			Exec(ctx)
+		if deleteErr != nil {
+			return fmt.Errorf(
+				"failed to delete documents after failing to put embeddings (%v): %w",
+				err,
+				deleteErr,
+			)
+		}
+		return models.NewDependencyFailureError("vector store", err)
+	}
+	for i := range rows {
+		documents[i].UUID = rows[i].UUID
 	}
 
-	return uuids, nil
+	return nil
+}
+
+// vectorRecords returns the vector store records of the documents with embeddings, whose
+// dimensions are checked as pgvector would.
+func (dc *DocumentCollectionDAO) vectorRecords(
+	documents []models.Document,
+) ([]models.VectorRecord, error) {
+	var records []models.VectorRecord
+	for i := range documents {
+		if len(documents[i].Embedding) == 0 {
+			continue
+		}
+		if len(documents[i].Embedding) != dc.EmbeddingDimensions {
+			return nil, store.NewEmbeddingMismatchError(fmt.Errorf(
+				"expected %d dimensions, not %d",
+				dc.EmbeddingDimensions,
+				len(documents[i].Embedding),
+			))
+		}
+		records = append(records, models.VectorRecord{
+			UUID:      documents[i].UUID,
+			Embedding: documents[i].Embedding,
+		})
+	}
+	return records, nil
 }
 
-// UpdateDocuments updates the document_id, metadata, and embedding columns of the
-// given documents in the given collection. The documents must have non-nil uuids.
+// UpdateDocuments updates the document_id, metadata, content, and embedding columns of
+// the given documents in the given collection. The documents must have non-nil uuids.
+// Updating the content clears the document's summary, and marks it as unembedded unless
+// a new embedding is given.
 //
 // **IMPORTANT:** We determine which columns to update based on the fields that are
 // non-zero in the given documents. This means that all documents must have data
@@ -298,6 +446,15 @@ func (dc *DocumentCollectionDAO) CreateDocuments(
 func (dc *DocumentCollectionDAO) UpdateDocuments(
 	ctx context.Context,
 	documents []models.Document,
+) error {
+	return withRetryErr(ctx, "update documents", func(ctx context.Context) error {
+		return dc.updateDocuments(ctx, documents)
+	})
+}
+
+func (dc *DocumentCollectionDAO) updateDocuments(
+	ctx context.Context,
+	documents []models.Document,
 ) error {
 	if len(documents) == 0 {
 		return nil
@@ -309,6 +466,8 @@ func (dc *DocumentCollectionDAO) UpdateDocuments(
 	updateDocumentID := false
 	updateMetadata := false
 	updateEmbedding := false
+	updateSummary := false
+	contentCount := 0
 	for i := range documents {
 		document := &documents[i]
 		if document.UUID == uuid.Nil {
@@ -323,11 +482,58 @@ func (dc *DocumentCollectionDAO) UpdateDocuments(
 		if len(document.Embedding) > 0 {
 			updateEmbedding = true
 		}
+		if len(document.Summary) > 0 {
+			updateSummary = true
+		}
+		if len(document.Content) > 0 {
+			contentCount++
+		}
 	}
+	updateContent := contentCount > 0
 
-	if !updateDocumentID && !updateMetadata && !updateEmbedding {
+	if !updateDocumentID && !updateMetadata && !updateEmbedding && !updateSummary &&
+		!updateContent {
 		return errors.New("no fields to update")
 	}
+	// a document without content would have its content cleared
+	if updateContent && contentCount != len(documents) {
+		return models.NewValidationError(
+			"either all or none of the updated documents must include content",
+		)
+	}
+	for i := range documents {
+		if updateContent {
+			// the summary of the previous content is cleared
+			documents[i].Summary = ""
+			documents[i].ContentHash = models.ContentHash(documents[i].Content)
+		}
+		if updateContent || updateEmbedding {
+			documents[i].IsEmbedded = len(documents[i].Embedding) > 0
+		}
+	}
+
+	// The embeddings of an external vector store are put once the documents are updated,
+	// and the stale embeddings of documents whose content changed are deleted.
+	var records []models.VectorRecord
+	var staleVectors []uuid.UUID
+	rows := documents
+	if dc.appState.VectorStore != nil {
+		if updateEmbedding {
+			var err error
+			records, err = dc.vectorRecords(documents)
+			if err != nil {
+				return err
+			}
+		}
+		rows = make([]models.Document, len(documents))
+		copy(rows, documents)
+		for i := range rows {
+			if updateContent && len(rows[i].Embedding) == 0 {
+				staleVectors = append(staleVectors, rows[i].UUID)
+			}
+			rows[i].Embedding = nil
+		}
+	}
 
 	var columns []string
 	if updateDocumentID {
@@ -336,8 +542,17 @@ func (dc *DocumentCollectionDAO) UpdateDocuments(
 	if updateMetadata {
 		columns = append(columns, "metadata")
 	}
-	if updateEmbedding {
-		columns = append(columns, "embedding", "is_embedded")
+	if updateContent {
+		columns = append(columns, "content", "content_hash", "summary")
+	}
+	if updateEmbedding && dc.appState.VectorStore == nil {
+		columns = append(columns, "embedding")
+	}
+	if updateEmbedding || updateContent {
+		columns = append(columns, "is_embedded")
+	}
+	if updateSummary && !updateContent {
+		columns = append(columns, "summary")
 	}
 
 	err := dc.GetByName(ctx)
@@ -346,7 +561,7 @@ func (dc *DocumentCollectionDAO) UpdateDocuments(
 	}
 
 	r, err := dc.db.NewUpdate().
-		Model(&documents).
+		Model(&rows).
 		ModelTableExpr("? AS document", bun.Ident(dc.TableName)).
 		Column(columns...).
 		Bulk().
@@ -367,6 +582,16 @@ func (dc *DocumentCollectionDAO) UpdateDocuments(
 		return models.NewNotFoundError("documents")
 	}
 
+	if dc.appState.VectorStore != nil {
+		err := dc.appState.VectorStore.Put(ctx, &dc.DocumentCollection, records)
+		if err == nil && len(staleVectors) > 0 {
+			err = dc.appState.VectorStore.Delete(ctx, &dc.DocumentCollection, staleVectors)
+		}
+		if err != nil {
+			return models.NewDependencyFailureError("vector store", err)
+		}
+	}
+
 	return nil
 }
 
@@ -400,7 +625,17 @@ func (dc *DocumentCollectionDAO) GetDocuments(
 	query := dc.db.NewSelect().
 		Model(&documents).
 		ModelTableExpr("? AS document", bun.Ident(dc.TableName)).
-		Column("uuid", "created_at", "content", "metadata", "document_id", "embedding", "is_embedded")
+		Column(
+			"uuid",
+			"created_at",
+			"content",
+			"metadata",
+			"document_id",
+			"embedding",
+			"is_embedded",
+			"summary",
+			"content_hash",
+		)
 
 	if len(uuids) > 0 {
 		query = query.Where("uuid IN (?)", bun.In(uuids))
@@ -427,6 +662,15 @@ func (dc *DocumentCollectionDAO) GetDocuments(
 func (dc *DocumentCollectionDAO) DeleteDocumentsByUUID(
 	ctx context.Context,
 	documentUUIDs []uuid.UUID,
+) error {
+	return withRetryErr(ctx, "delete documents", func(ctx context.Context) error {
+		return dc.deleteDocumentsByUUID(ctx, documentUUIDs)
+	})
+}
+
+func (dc *DocumentCollectionDAO) deleteDocumentsByUUID(
+	ctx context.Context,
+	documentUUIDs []uuid.UUID,
 ) error {
 	if dc.getName() == "" {
 		return errors.New("collection name cannot be empty")
@@ -456,6 +700,13 @@ func (dc *DocumentCollectionDAO) DeleteDocumentsByUUID(
 		return models.NewNotFoundError("documents")
 	}
 
+	if dc.appState.VectorStore != nil && len(documentUUIDs) > 0 {
+		err := dc.appState.VectorStore.Delete(ctx, &dc.DocumentCollection, documentUUIDs)
+		if err != nil {
+			return models.NewDependencyFailureError("vector store", err)
+		}
+	}
+
 	return nil
 }
 
@@ -481,6 +732,17 @@ func (dc *DocumentCollectionDAO) SearchDocuments(ctx context.Context,
 		return nil, errors.New("cannot specify both text and embedding")
 	}
 
+	if query.MinScore != 0 && len(query.Text) == 0 && len(query.Embedding) == 0 {
+		return nil, models.NewValidationError("min_score requires text or an embedding")
+	}
+
+	if query.Cursor != "" && ((len(query.Text) == 0 && len(query.Embedding) == 0) ||
+		query.SearchType == models.SearchTypeMMR) {
+		return nil, models.NewValidationError(
+			"only similarity searches with text or an embedding can be paged",
+		)
+	}
+
 	if err := dc.GetByName(ctx); err != nil {
 		return nil, fmt.Errorf("failed to get collection: %w", err)
 	}
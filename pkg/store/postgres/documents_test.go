@@ -63,6 +63,64 @@ func TestCollectionCreate(t *testing.T) {
 	}
 }
 
+func TestCollectionCreateIndexType(t *testing.T) {
+	ctx := context.Background()
+
+	CleanDB(t, testDB)
+	err := CreateSchema(ctx, appState, testDB)
+	assert.NoError(t, err)
+
+	hnswAvailable := appState.Config.Store.Postgres.AvailableIndexes.HSNW
+
+	ivfflat := NewTestCollectionDAO(10)
+	ivfflat.IndexType = models.IndexTypeIVFFlat
+	err = ivfflat.Create(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, models.IndexTypeIVFFlat, ivfflat.IndexType)
+	assert.False(t, ivfflat.IsIndexed, "ivfflat indexes are built once populated")
+
+	hnsw := NewTestCollectionDAO(10)
+	hnsw.IndexType = models.IndexTypeHNSW
+	err = hnsw.Create(ctx)
+	if hnswAvailable {
+		assert.NoError(t, err)
+		assert.True(t, hnsw.IsIndexed)
+	} else {
+		assert.ErrorIs(t, err, models.ErrValidation)
+	}
+
+	unknown := NewTestCollectionDAO(10)
+	unknown.IndexType = "diskann"
+	err = unknown.Create(ctx)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func TestCollectionCreateDistanceFunction(t *testing.T) {
+	ctx := context.Background()
+
+	CleanDB(t, testDB)
+	err := CreateSchema(ctx, appState, testDB)
+	assert.NoError(t, err)
+
+	collection := NewTestCollectionDAO(10)
+	err = collection.Create(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DistanceFunctionCosine, collection.DistanceFunction)
+
+	l2 := NewTestCollectionDAO(10)
+	l2.DistanceFunction = models.DistanceFunctionL2
+	err = l2.Create(ctx)
+	assert.NoError(t, err)
+	err = l2.GetByName(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DistanceFunctionL2, l2.DistanceFunction)
+
+	unknown := NewTestCollectionDAO(10)
+	unknown.DistanceFunction = "hamming"
+	err = unknown.Create(ctx)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
 func TestCollectionUpdate(t *testing.T) {
 	ctx := context.Background()
 
@@ -390,6 +448,104 @@ func TestDocumentCollectionUpdateDocuments(t *testing.T) {
 	}
 }
 
+func TestDocumentCollectionUpdateDocumentSummaries(t *testing.T) {
+	ctx := context.Background()
+	CleanDB(t, testDB)
+	err := CreateSchema(ctx, appState, testDB)
+	assert.NoError(t, err)
+
+	collection := NewTestCollectionDAO(5)
+	err = collection.Create(ctx)
+	assert.NoError(t, err)
+
+	documents := []models.Document{
+		{DocumentBase: models.DocumentBase{Content: "first"}},
+		{DocumentBase: models.DocumentBase{Content: "second"}},
+	}
+	uuids, err := collection.CreateDocuments(ctx, documents)
+	assert.NoError(t, err)
+
+	// only the summary is updated
+	err = collection.UpdateDocuments(ctx, []models.Document{
+		{DocumentBase: models.DocumentBase{UUID: uuids[0], Summary: "summary"}},
+	})
+	assert.NoError(t, err)
+
+	returnedDocuments, err := collection.GetDocuments(ctx, 0, uuids, nil)
+	assert.NoError(t, err)
+	summaries := map[string]string{}
+	for _, doc := range returnedDocuments {
+		summaries[doc.Content] = doc.Summary
+	}
+	assert.Equal(t, map[string]string{"first": "summary", "second": ""}, summaries)
+
+	results := searchResultsFromSearchQueries([]models.SearchDocumentResult{
+		{Document: &returnedDocuments[0]},
+		{Document: &returnedDocuments[1]},
+	}, true)
+	for _, result := range results {
+		if result.UUID == uuids[0] {
+			assert.Equal(t, "summary", result.Content)
+			assert.Empty(t, result.Summary)
+		} else {
+			assert.Equal(t, "second", result.Content)
+		}
+	}
+}
+
+func TestDocumentCollectionUpdateDocumentContent(t *testing.T) {
+	ctx := context.Background()
+	CleanDB(t, testDB)
+	err := CreateSchema(ctx, appState, testDB)
+	assert.NoError(t, err)
+
+	collection := NewTestCollectionDAO(5)
+	err = collection.Create(ctx)
+	assert.NoError(t, err)
+
+	documents := []models.Document{
+		{
+			DocumentBase: models.DocumentBase{
+				Content:  "first",
+				Metadata: map[string]interface{}{"key": "value"},
+			},
+			Embedding: []float32{1, 0, 0, 0, 0},
+		},
+		{
+			DocumentBase: models.DocumentBase{Content: "second"},
+			Embedding:    []float32{0, 1, 0, 0, 0},
+		},
+	}
+	uuids, err := collection.CreateDocuments(ctx, documents)
+	assert.NoError(t, err)
+
+	err = collection.UpdateDocuments(ctx, []models.Document{
+		{DocumentBase: models.DocumentBase{UUID: uuids[0], Summary: "summary"}},
+	})
+	assert.NoError(t, err)
+
+	// new content without an embedding clears the summary and awaits embedding
+	err = collection.UpdateDocuments(ctx, []models.Document{
+		{DocumentBase: models.DocumentBase{UUID: uuids[0], Content: "updated"}},
+	})
+	assert.NoError(t, err)
+
+	returnedDocuments, err := collection.GetDocuments(ctx, 0, []uuid.UUID{uuids[0]}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, returnedDocuments, 1)
+	assert.Equal(t, "updated", returnedDocuments[0].Content)
+	assert.Empty(t, returnedDocuments[0].Summary)
+	assert.False(t, returnedDocuments[0].IsEmbedded)
+	assert.Equal(t, map[string]interface{}{"key": "value"}, returnedDocuments[0].Metadata)
+
+	// every document in a content update must have content
+	err = collection.UpdateDocuments(ctx, []models.Document{
+		{DocumentBase: models.DocumentBase{UUID: uuids[0], Content: "again"}},
+		{DocumentBase: models.DocumentBase{UUID: uuids[1], DocumentID: "second"}},
+	})
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
 func getDocumentIDs(docs []models.Document) ([]string, error) {
 	ids := make([]string, len(docs))
 	for i, doc := range docs {
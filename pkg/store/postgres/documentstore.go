@@ -10,12 +10,22 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/getzep/zep/pkg/llms"
 	"github.com/getzep/zep/pkg/models"
 	"github.com/uptrace/bun"
 )
 
 const DefaultDocEmbeddingChunkSize = 1000
 
+// DocumentSummaryChunkSize is the number of documents summarized in a single task. Each
+// document is summarized by a separate LLM call, so chunks are kept small.
+const DocumentSummaryChunkSize = 10
+
+// ContextualChunkHeaderChunkSize is the number of documents embedded in a single task when
+// the collection has contextual chunk headers, as a header is written for each document
+// by a separate LLM call.
+const ContextualChunkHeaderChunkSize = 10
+
 // NewDocumentStore returns a new DocumentStore. Use this to correctly initialize the store.
 func NewDocumentStore(
 	ctx context.Context,
@@ -47,8 +57,184 @@ type DocumentStore struct {
 }
 
 func (ds *DocumentStore) OnStart(
-	_ context.Context,
+	ctx context.Context,
 ) error {
+	if err := ds.addCollectionSummaryColumns(ctx); err != nil {
+		return err
+	}
+	if err := ds.addCollectionContentHashColumns(ctx); err != nil {
+		return err
+	}
+	if err := ds.shrinkCollectionDims(ctx); err != nil {
+		return err
+	}
+	return ds.migrateCollectionQuantization(ctx)
+}
+
+// addCollectionSummaryColumns adds the summary column to the tables of collections created
+// before documents were summarized. Tables created since include it.
+func (ds *DocumentStore) addCollectionSummaryColumns(ctx context.Context) error {
+	collections, err := ds.GetCollectionList(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range collections {
+		_, err := ds.Client.ExecContext(
+			ctx,
+			"ALTER TABLE ? ADD COLUMN IF NOT EXISTS summary text",
+			bun.Ident(collection.TableName),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add summary column to %s: %w", collection.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// addCollectionContentHashColumns adds the content_hash column to the tables of collections
+// created before document content was hashed, and hashes their documents' content. Tables
+// created since include it.
+func (ds *DocumentStore) addCollectionContentHashColumns(ctx context.Context) error {
+	collections, err := ds.GetCollectionList(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range collections {
+		exists, err := ds.Client.NewSelect().
+			Table("information_schema.columns").
+			Where("table_name = ?", collection.TableName).
+			Where("column_name = 'content_hash'").
+			Exists(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get columns of %s: %w", collection.Name, err)
+		}
+		if exists {
+			continue
+		}
+
+		log.Infof("hashing the content of documents in collection %s", collection.Name)
+		_, err = ds.Client.ExecContext(
+			ctx,
+			`ALTER TABLE ? ADD COLUMN IF NOT EXISTS content_hash text;
+			UPDATE ?
+				SET content_hash = encode(sha256(convert_to(coalesce(content, ''), 'UTF8')), 'hex');`,
+			bun.Ident(collection.TableName),
+			bun.Ident(collection.TableName),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add content_hash column to %s: %w", collection.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// shrinkCollectionDims truncates the embeddings of auto-embedded collections to the configured
+// dimensions when the document embedding model supports Matryoshka truncation. Collections
+// embedded by the client are left unchanged.
+func (ds *DocumentStore) shrinkCollectionDims(ctx context.Context) error {
+	model, err := llms.GetEmbeddingModel(ds.appState, "document")
+	if err != nil {
+		return fmt.Errorf("failed to get document embedding model: %w", err)
+	}
+	if !model.Matryoshka || model.Dimensions == 0 {
+		return nil
+	}
+
+	collections, err := ds.GetCollectionList(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range collections {
+		if !collection.IsAutoEmbedded || collection.EmbeddingDimensions <= model.Dimensions {
+			continue
+		}
+
+		log.Warnf(
+			"truncating embeddings in collection %s from %d to %d dimensions",
+			collection.Name,
+			collection.EmbeddingDimensions,
+			model.Dimensions,
+		)
+		err = truncateEmbeddingDims(ctx, ds.Client, collection.TableName, model.Dimensions)
+		if err != nil {
+			return fmt.Errorf("failed to shrink collection %s: %w", collection.Name, err)
+		}
+
+		// HNSW indexes are recreated by migrateCollectionQuantization
+		q := ds.Client.NewUpdate().
+			Model(&DocumentCollectionSchema{}).
+			Set("embedding_dimensions = ?", model.Dimensions).
+			Where("name = ?", collection.Name)
+		if collection.IndexType == models.IndexTypeIVFFlat {
+			q = q.Set("is_indexed = ?", false)
+		}
+		_, err = q.Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to update collection %s: %w", collection.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateCollectionQuantization converts the embedding columns of existing collections to the
+// quantization configured for the document embedding model. IVFFLAT indexes are dropped when a
+// column is converted, so the collection is marked as unindexed until the index is recreated.
+func (ds *DocumentStore) migrateCollectionQuantization(ctx context.Context) error {
+	model, err := llms.GetEmbeddingModel(ds.appState, "document")
+	if err != nil {
+		return fmt.Errorf("failed to get document embedding model: %w", err)
+	}
+
+	collections, err := ds.GetCollectionList(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range collections {
+		changed, err := migrateEmbeddingQuantization(
+			ctx,
+			ds.Client,
+			collection.TableName,
+			collection.EmbeddingDimensions,
+			model.Quantization,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to migrate collection %s: %w", collection.Name, err)
+		}
+
+		if changed && collection.IndexType == models.IndexTypeIVFFlat {
+			_, err = ds.Client.NewUpdate().
+				Model(&DocumentCollectionSchema{}).
+				Set("is_indexed = ?", false).
+				Where("name = ?", collection.Name).
+				Exec(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to update collection %s: %w", collection.Name, err)
+			}
+		}
+
+		if collection.IndexType == models.IndexTypeHNSW && ds.appState.Config.Store.Postgres.AvailableIndexes.HSNW {
+			err = createHNSWIndex(
+				ctx,
+				ds.Client,
+				collection.TableName,
+				EmbeddingColName,
+				collection.EmbeddingDimensions,
+				model.Quantization,
+				collection.DistanceFunction,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create hnsw index for %s: %w", collection.Name, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -79,7 +265,7 @@ func (ds *DocumentStore) UpdateCollection(
 	collection models.DocumentCollection,
 ) error {
 	if collection.Name == "" {
-		return errors.New("collection name is empty")
+		return models.NewValidationError("collection name is empty")
 	}
 	dbCollection := NewDocumentCollectionDAO(ds.appState, ds.Client, collection)
 	err := dbCollection.Update(ctx)
@@ -94,7 +280,7 @@ func (ds *DocumentStore) GetCollection(
 	collectionName string,
 ) (models.DocumentCollection, error) {
 	if collectionName == "" {
-		return models.DocumentCollection{}, errors.New("collection name is empty")
+		return models.DocumentCollection{}, models.NewValidationError("collection name is empty")
 	}
 	dbCollection := NewDocumentCollectionDAO(
 		ds.appState,
@@ -131,7 +317,7 @@ func (ds *DocumentStore) DeleteCollection(
 	collectionName string,
 ) error {
 	if collectionName == "" {
-		return errors.New("collection name is empty")
+		return models.NewValidationError("collection name is empty")
 	}
 	dbCollection := NewDocumentCollectionDAO(
 		ds.appState,
@@ -151,7 +337,7 @@ func (ds *DocumentStore) CreateDocuments(
 	documents []models.Document,
 ) ([]uuid.UUID, error) {
 	if collectionName == "" {
-		return nil, errors.New("collection name is empty")
+		return nil, models.NewValidationError("collection name is empty")
 	}
 	collection := NewDocumentCollectionDAO(
 		ds.appState,
@@ -181,12 +367,12 @@ func (ds *DocumentStore) CreateDocuments(
 		}
 	}
 	if collection.IsAutoEmbedded && someEmbeddings {
-		return nil, errors.New(
+		return nil, models.NewValidationError(
 			"cannot create documents with embeddings in an auto-embedded collection",
 		)
 	}
 	if !collection.IsAutoEmbedded && someEmpty {
-		return nil, models.NewBadRequestError(
+		return nil, models.NewValidationError(
 			"cannot create documents without embeddings in a non-auto-embedded collection",
 		)
 	}
@@ -199,8 +385,9 @@ func (ds *DocumentStore) CreateDocuments(
 	// if the collection is configured to auto-embed, send the documents
 	// to the document embedding tasker
 	if collection.IsAutoEmbedded {
-		ds.documentEmbeddingTasker(collectionName, documents)
+		ds.documentEmbeddingTasker(ctx, collection.DocumentCollection, documents)
 	}
+	ds.documentSummaryTasker(ctx, collectionName, documents)
 
 	return uuids, nil
 }
@@ -211,18 +398,54 @@ func (ds *DocumentStore) UpdateDocuments(
 	documents []models.Document,
 ) error {
 	if collectionName == "" {
-		return errors.New("collection name is empty")
+		return models.NewValidationError("collection name is empty")
 	}
 	dbCollection := NewDocumentCollectionDAO(
 		ds.appState,
 		ds.Client,
 		models.DocumentCollection{Name: collectionName},
 	)
+
+	// documents with new content are re-embedded and re-summarized. Metadata-only
+	// updates leave the embedding alone.
+	var changed []models.Document
+	for i := range documents {
+		if documents[i].Content != "" {
+			changed = append(changed, documents[i])
+		}
+	}
+	if len(changed) > 0 {
+		if err := dbCollection.GetByName(ctx); err != nil {
+			return fmt.Errorf("failed to get collection: %w", err)
+		}
+		for i := range changed {
+			hasEmbedding := len(changed[i].Embedding) > 0
+			if dbCollection.IsAutoEmbedded && hasEmbedding {
+				return models.NewValidationError(
+					"cannot update documents with embeddings in an auto-embedded collection",
+				)
+			}
+			if !dbCollection.IsAutoEmbedded && !hasEmbedding {
+				return models.NewValidationError(
+					"documents with new content must include embeddings in a " +
+						"non-auto-embedded collection",
+				)
+			}
+		}
+	}
+
 	err := dbCollection.UpdateDocuments(ctx, documents)
 	if err != nil {
 		return fmt.Errorf("failed to Update documents: %w", err)
 	}
 
+	if len(changed) > 0 {
+		if dbCollection.IsAutoEmbedded {
+			ds.documentEmbeddingTasker(ctx, dbCollection.DocumentCollection, changed)
+		}
+		ds.documentSummaryTasker(ctx, collectionName, changed)
+	}
+
 	return nil
 }
 
@@ -233,7 +456,7 @@ func (ds *DocumentStore) GetDocuments(
 	documentIDs []string,
 ) ([]models.Document, error) {
 	if collectionName == "" {
-		return nil, errors.New("collection name is empty")
+		return nil, models.NewValidationError("collection name is empty")
 	}
 	dbCollection := NewDocumentCollectionDAO(
 		ds.appState,
@@ -254,7 +477,7 @@ func (ds *DocumentStore) DeleteDocuments(
 	documentUUID []uuid.UUID,
 ) error {
 	if collectionName == "" {
-		return errors.New("collection name is empty")
+		return models.NewValidationError("collection name is empty")
 	}
 	dbCollection := NewDocumentCollectionDAO(
 		ds.appState,
@@ -306,7 +529,7 @@ func (ds *DocumentStore) CreateCollectionIndex(
 		return fmt.Errorf("failed to get collection: %w", err)
 	}
 
-	if collection.IndexType != "ivfflat" {
+	if collection.IndexType != models.IndexTypeIVFFlat {
 		log.Warningf(
 			"collection %s is of type %s, which is not supported for manual indexing",
 			collection.Name,
@@ -330,7 +553,8 @@ func (ds *DocumentStore) CreateCollectionIndex(
 }
 
 func (ds *DocumentStore) documentEmbeddingTasker(
-	collectionName string,
+	ctx context.Context,
+	collection models.DocumentCollection,
 	documents []models.Document,
 ) {
 	tasks := make([]models.DocEmbeddingTask, len(documents))
@@ -346,14 +570,17 @@ func (ds *DocumentStore) documentEmbeddingTasker(
 	if tmpChunkSize > 0 {
 		taskChunkSize = tmpChunkSize
 	}
+	if collection.ContextualChunkHeaders {
+		taskChunkSize = min(taskChunkSize, ContextualChunkHeaderChunkSize)
+	}
 	taskChunks := chunkTasks(tasks, taskChunkSize)
 
 	for _, taskChunk := range taskChunks {
 		err := ds.appState.TaskPublisher.Publish(
 			"document_embedder",
-			map[string]string{
-				"collection_name": collectionName,
-			},
+			models.WithTenantMetadata(ctx, map[string]string{
+				"collection_name": collection.Name,
+			}),
 			taskChunk,
 		)
 		if err != nil {
@@ -362,6 +589,38 @@ func (ds *DocumentStore) documentEmbeddingTasker(
 	}
 }
 
+// documentSummaryTasker publishes the documents with content to the document summarizer,
+// if it's enabled.
+func (ds *DocumentStore) documentSummaryTasker(
+	ctx context.Context,
+	collectionName string,
+	documents []models.Document,
+) {
+	if !ds.appState.Config.Extractors.Documents.Summarizer.Enabled {
+		return
+	}
+
+	var tasks []models.DocEmbeddingTask
+	for i := range documents {
+		if documents[i].Content != "" {
+			tasks = append(tasks, models.DocEmbeddingTask{UUID: documents[i].UUID})
+		}
+	}
+
+	for _, taskChunk := range chunkTasks(tasks, DocumentSummaryChunkSize) {
+		err := ds.appState.TaskPublisher.Publish(
+			models.DocumentSummarizerTopic,
+			models.WithTenantMetadata(ctx, map[string]string{
+				"collection_name": collectionName,
+			}),
+			taskChunk,
+		)
+		if err != nil {
+			log.Errorf("failed to publish document summary task: %v", err)
+		}
+	}
+}
+
 // chunkTasks splits the given tasks into chunks of the given size.
 func chunkTasks(tasks []models.DocEmbeddingTask, chunkSize int) [][]models.DocEmbeddingTask {
 	var chunks [][]models.DocEmbeddingTask
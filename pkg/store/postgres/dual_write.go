@@ -0,0 +1,367 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/uptrace/bun"
+)
+
+// dualWritePrefix prefixes the names of the triggers, and their functions, that mirror
+// writes into the target schema.
+const dualWritePrefix = "dual_write_"
+
+// dualWriteExcluded are tables derived by triggers of their own, which the target
+// schema's triggers maintain from the mirrored rows.
+var dualWriteExcluded = []string{"user_memory_index"}
+
+// dualWriteTable is a table mirrored into the target schema.
+type dualWriteTable struct {
+	name string
+	// columns are the columns of the table that the target's table also has, other than
+	// generated columns.
+	columns []string
+	// keys are the columns of the primary key of the target's table.
+	keys []string
+}
+
+// DualWriteReport compares a table with its copy in the dual write target schema.
+type DualWriteReport struct {
+	Table      string `json:"table"`
+	SourceRows int64  `json:"source_rows"`
+	TargetRows int64  `json:"target_rows"`
+	// Missing rows are in the source table but not the target's.
+	Missing int64 `json:"missing"`
+	// Extra rows are in the target table but not the source's.
+	Extra int64 `json:"extra"`
+	// Mismatched rows are in both tables, with different values in their common columns.
+	Mismatched int64 `json:"mismatched"`
+}
+
+// Consistent reports whether the target table holds the same rows as the source table.
+func (r DualWriteReport) Consistent() bool {
+	return r.Missing == 0 && r.Extra == 0 && r.Mismatched == 0
+}
+
+// setupDualWrite creates or replaces the triggers mirroring writes into the dual write
+// target schema, if dual writes are enabled, and drops those of tables no longer
+// mirrored. The rows of tables that weren't already mirrored are copied.
+func setupDualWrite(ctx context.Context, appState *models.AppState, db *bun.DB) error {
+	cfg := appState.Config.Store.Postgres.DualWrite
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	// instances starting together would otherwise race to create the triggers
+	if _, err := tx.ExecContext(
+		ctx,
+		"SELECT pg_advisory_xact_lock(hashtext('dual_write'))",
+	); err != nil {
+		return fmt.Errorf("failed to lock dual writes: %w", err)
+	}
+
+	var existing []string
+	err = tx.NewRaw(
+		`SELECT c.relname
+		FROM pg_trigger t
+		         JOIN pg_class c ON c.oid = t.tgrelid
+		         JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE t.tgname = ? || c.relname
+		  AND n.nspname = current_schema()`,
+		dualWritePrefix,
+	).Scan(ctx, &existing)
+	if err != nil {
+		return fmt.Errorf("failed to list dual write triggers: %w", err)
+	}
+
+	var tables []dualWriteTable
+	if cfg.Enabled {
+		tables, err = dualWriteTables(ctx, tx, cfg.TargetSchema, cfg.Tables)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range existing {
+		if slices.ContainsFunc(tables, func(t dualWriteTable) bool { return t.name == name }) {
+			continue
+		}
+		log.Infof("dropping dual write trigger of %s", name)
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+			"DROP TRIGGER IF EXISTS %s ON %s; DROP FUNCTION IF EXISTS %s();",
+			quoteIdent(dualWritePrefix+name),
+			quoteIdent(name),
+			quoteIdent(dualWritePrefix+name),
+		)); err != nil {
+			return fmt.Errorf("failed to drop dual write trigger of %s: %w", name, err)
+		}
+	}
+
+	// tables are copied before the tables referencing them
+	for _, table := range tables {
+		if _, err := tx.ExecContext(ctx, dualWriteFunction(cfg.TargetSchema, table)); err != nil {
+			return fmt.Errorf("failed to create dual write function of %s: %w", table.name, err)
+		}
+		if slices.Contains(existing, table.name) {
+			continue
+		}
+		log.Infof("creating dual write trigger of %s and copying its rows", table.name)
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`CREATE TRIGGER %s
+			    AFTER INSERT OR UPDATE OR DELETE
+			    ON %s
+			    FOR EACH ROW
+			EXECUTE FUNCTION %s();`,
+			quoteIdent(dualWritePrefix+table.name),
+			quoteIdent(table.name),
+			quoteIdent(dualWritePrefix+table.name),
+		)); err != nil {
+			return fmt.Errorf("failed to create dual write trigger of %s: %w", table.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, dualWriteBackfill(cfg.TargetSchema, table)); err != nil {
+			return fmt.Errorf("failed to copy rows of %s: %w", table.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// VerifyDualWrite compares each table mirrored by dual writes with its copy in the target
+// schema, by the values of their common columns.
+func VerifyDualWrite(
+	ctx context.Context,
+	appState *models.AppState,
+	db bun.IDB,
+) ([]DualWriteReport, error) {
+	cfg := appState.Config.Store.Postgres.DualWrite
+	if !cfg.Enabled {
+		return nil, errors.New("dual writes are not enabled")
+	}
+	tables, err := dualWriteTables(ctx, db, cfg.TargetSchema, cfg.Tables)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]DualWriteReport, len(tables))
+	for i, table := range tables {
+		keys := quoteIdents(table.keys)
+		rowHash := fmt.Sprintf("md5(ROW(%s)::text)", quoteIdents(table.columns))
+		report := DualWriteReport{Table: table.name}
+		err := db.NewRaw(fmt.Sprintf(
+			`WITH s AS (SELECT %[1]s, %[2]s AS h FROM %[3]s),
+			     t AS (SELECT %[1]s, %[2]s AS h FROM %[4]s)
+			SELECT count(s.h)                                  AS source_rows,
+			       count(t.h)                                  AS target_rows,
+			       count(*) FILTER (WHERE t.h IS NULL)         AS missing,
+			       count(*) FILTER (WHERE s.h IS NULL)         AS extra,
+			       count(*) FILTER (WHERE s.h <> t.h)          AS mismatched
+			FROM s
+			         FULL JOIN t USING (%[1]s)`,
+			keys,
+			rowHash,
+			quoteIdent(table.name),
+			quoteIdent(cfg.TargetSchema)+"."+quoteIdent(table.name),
+		)).Scan(
+			ctx,
+			&report.SourceRows,
+			&report.TargetRows,
+			&report.Missing,
+			&report.Extra,
+			&report.Mismatched,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare %s: %w", table.name, err)
+		}
+		reports[i] = report
+	}
+	return reports, nil
+}
+
+// dualWriteTables returns the tables mirrored into the target schema, before the tables
+// referencing them. Without tables configured, these are the tables of CreateSchema that
+// exist in the target schema.
+func dualWriteTables(
+	ctx context.Context,
+	db bun.IDB,
+	targetSchema string,
+	configured []string,
+) ([]dualWriteTable, error) {
+	if targetSchema == "" {
+		return nil, errors.New("store.postgres.dual_write.target_schema must be set")
+	}
+	var currentSchema string
+	if err := db.NewRaw("SELECT current_schema()").Scan(ctx, &currentSchema); err != nil {
+		return nil, fmt.Errorf("failed to get current schema: %w", err)
+	}
+	if targetSchema == currentSchema {
+		return nil, fmt.Errorf("dual write target schema %s is the current schema", targetSchema)
+	}
+
+	var names []string
+	schemaTables := schemaTableList()
+	for i := len(schemaTables) - 1; i >= 0; i-- {
+		name := db.Dialect().Tables().Get(reflect.TypeOf(schemaTables[i])).Name
+		if !slices.Contains(dualWriteExcluded, name) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range configured {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("%s is not a table that can be dual written", name)
+		}
+	}
+
+	var tables []dualWriteTable
+	for _, name := range names {
+		if len(configured) > 0 && !slices.Contains(configured, name) {
+			continue
+		}
+		target := quoteIdent(targetSchema) + "." + quoteIdent(name)
+		var exists bool
+		err := db.NewRaw("SELECT to_regclass(?) IS NOT NULL", target).Scan(ctx, &exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check target table %s: %w", target, err)
+		}
+		if !exists {
+			if len(configured) > 0 {
+				return nil, fmt.Errorf("target table %s does not exist", target)
+			}
+			log.Warnf("target table %s does not exist, %s is not dual written", target, name)
+			continue
+		}
+
+		table := dualWriteTable{name: name}
+		err = db.NewRaw(
+			`SELECT s.column_name
+			FROM information_schema.columns s
+			         JOIN information_schema.columns t
+			              ON t.table_schema = ?
+			                  AND t.table_name = s.table_name
+			                  AND t.column_name = s.column_name
+			WHERE s.table_schema = current_schema()
+			  AND s.table_name = ?
+			  AND s.is_generated = 'NEVER'
+			  AND t.is_generated = 'NEVER'
+			ORDER BY s.ordinal_position`,
+			targetSchema,
+			name,
+		).Scan(ctx, &table.columns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list columns of %s: %w", name, err)
+		}
+		err = db.NewRaw(
+			`SELECT a.attname
+			FROM pg_index i
+			         JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY (i.indkey)
+			WHERE i.indrelid = to_regclass(?)
+			  AND i.indisprimary
+			ORDER BY a.attnum`,
+			target,
+		).Scan(ctx, &table.keys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get primary key of %s: %w", target, err)
+		}
+		if len(table.keys) == 0 {
+			return nil, fmt.Errorf("target table %s has no primary key", target)
+		}
+		for _, key := range table.keys {
+			if !slices.Contains(table.columns, key) {
+				return nil, fmt.Errorf("%s has no column %s, the primary key of %s", name, key, target)
+			}
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// dualWriteFunction returns the statement creating or replacing the trigger function
+// mirroring the writes to table into the target schema.
+func dualWriteFunction(targetSchema string, table dualWriteTable) string {
+	target := quoteIdent(targetSchema) + "." + quoteIdent(table.name)
+	keys := quoteIdents(table.keys)
+	oldKeys := prefixIdents("OLD.", table.keys)
+	newKeys := prefixIdents("NEW.", table.keys)
+
+	var updates []string
+	for _, c := range table.columns {
+		if !slices.Contains(table.keys, c) {
+			updates = append(updates, fmt.Sprintf("%[1]s = EXCLUDED.%[1]s", quoteIdent(c)))
+		}
+	}
+	onConflict := "DO NOTHING"
+	if len(updates) > 0 {
+		onConflict = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	return fmt.Sprintf(`
+CREATE OR REPLACE FUNCTION %[1]s()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    IF TG_OP <> 'INSERT' AND (TG_OP = 'DELETE' OR ROW(%[4]s) IS DISTINCT FROM ROW(%[5]s)) THEN
+        DELETE FROM %[2]s WHERE ROW(%[3]s) = ROW(%[4]s);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        INSERT INTO %[2]s (%[6]s)
+        VALUES (%[7]s)
+        ON CONFLICT (%[3]s) %[8]s;
+    END IF;
+    RETURN NULL;
+END;
+$$;
+`,
+		quoteIdent(dualWritePrefix+table.name),
+		target,
+		keys,
+		oldKeys,
+		newKeys,
+		quoteIdents(table.columns),
+		prefixIdents("NEW.", table.columns),
+		onConflict,
+	)
+}
+
+// dualWriteBackfill returns the statement copying the rows of table missing from the
+// target schema.
+func dualWriteBackfill(targetSchema string, table dualWriteTable) string {
+	columns := quoteIdents(table.columns)
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) SELECT %s FROM %s ON CONFLICT (%s) DO NOTHING",
+		quoteIdent(targetSchema)+"."+quoteIdent(table.name),
+		columns,
+		columns,
+		quoteIdent(table.name),
+		quoteIdents(table.keys),
+	)
+}
+
+// quoteIdent quotes an identifier for use in statements that can't take arguments, such
+// as the body of a function.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteIdents(names []string) string {
+	return prefixIdents("", names)
+}
+
+func prefixIdents(prefix string, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = prefix + quoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+func TestDualWrite(t *testing.T) {
+	const targetSchema = "zep_dual_write_test"
+	_, err := testDB.ExecContext(testCtx, "CREATE SCHEMA "+targetSchema)
+	require.NoError(t, err)
+	_, err = testDB.ExecContext(
+		testCtx,
+		"CREATE TABLE "+targetSchema+".session (LIKE session INCLUDING ALL)",
+	)
+	require.NoError(t, err)
+
+	original := appState.Config.Store.Postgres.DualWrite
+	t.Cleanup(func() {
+		appState.Config.Store.Postgres.DualWrite = original
+		assert.NoError(t, setupDualWrite(testCtx, appState, testDB))
+		_, err := testDB.ExecContext(testCtx, "DROP SCHEMA "+targetSchema+" CASCADE")
+		assert.NoError(t, err)
+	})
+
+	// sessions created before dual writes are enabled are copied
+	existingID, err := testutils.GenerateRandomSessionID(16)
+	require.NoError(t, err)
+	_, err = appState.MemoryStore.CreateSession(testCtx, &models.CreateSessionRequest{
+		SessionID: existingID,
+	})
+	require.NoError(t, err)
+
+	appState.Config.Store.Postgres.DualWrite = config.DualWriteConfig{
+		Enabled:      true,
+		TargetSchema: targetSchema,
+		Tables:       []string{"session"},
+	}
+	require.NoError(t, setupDualWrite(testCtx, appState, testDB))
+	// setting up again leaves the triggers in place
+	require.NoError(t, setupDualWrite(testCtx, appState, testDB))
+
+	targetMetadata := func(sessionID string) (map[string]interface{}, bool) {
+		var sessions []SessionSchema
+		err := testDB.NewSelect().
+			Model(&sessions).
+			ModelTableExpr(targetSchema+".session AS s").
+			WhereAllWithDeleted().
+			Where("session_id = ?", sessionID).
+			Scan(testCtx)
+		require.NoError(t, err)
+		if len(sessions) == 0 {
+			return nil, false
+		}
+		return sessions[0].Metadata, true
+	}
+	_, ok := targetMetadata(existingID)
+	assert.True(t, ok)
+
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	require.NoError(t, err)
+	_, err = appState.MemoryStore.CreateSession(testCtx, &models.CreateSessionRequest{
+		SessionID: sessionID,
+	})
+	require.NoError(t, err)
+	_, err = appState.MemoryStore.UpdateSession(testCtx, &models.UpdateSessionRequest{
+		SessionID: sessionID,
+		Metadata:  map[string]interface{}{"foo": "bar"},
+	})
+	require.NoError(t, err)
+	metadata, ok := targetMetadata(sessionID)
+	require.True(t, ok)
+	assert.Equal(t, "bar", metadata["foo"])
+
+	verify := func() DualWriteReport {
+		reports, err := VerifyDualWrite(testCtx, appState, testDB)
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+		assert.Equal(t, "session", reports[0].Table)
+		return reports[0]
+	}
+	report := verify()
+	assert.True(t, report.Consistent())
+	assert.Equal(t, report.SourceRows, report.TargetRows)
+
+	// rows changed in only one schema are reported
+	_, err = testDB.ExecContext(
+		testCtx,
+		"UPDATE "+targetSchema+".session SET metadata = '{}' WHERE session_id = ?",
+		sessionID,
+	)
+	require.NoError(t, err)
+	report = verify()
+	assert.False(t, report.Consistent())
+	assert.Equal(t, int64(1), report.Mismatched)
+
+	// hard deletes are mirrored
+	_, err = testDB.NewDelete().
+		Model((*SessionSchema)(nil)).
+		Where("session_id = ?", sessionID).
+		ForceDelete().
+		Exec(testCtx)
+	require.NoError(t, err)
+	_, ok = targetMetadata(sessionID)
+	assert.False(t, ok)
+	assert.True(t, verify().Consistent())
+
+	// disabling dual writes drops the triggers
+	appState.Config.Store.Postgres.DualWrite.Enabled = false
+	require.NoError(t, setupDualWrite(testCtx, appState, testDB))
+	var triggers int
+	err = testDB.NewRaw(
+		"SELECT count(*) FROM pg_trigger WHERE tgname LIKE ?",
+		dualWritePrefix+"%",
+	).Scan(testCtx, &triggers)
+	require.NoError(t, err)
+	assert.Equal(t, 0, triggers)
+}
+
+func TestDualWriteTablesValidation(t *testing.T) {
+	_, err := dualWriteTables(testCtx, testDB, "", nil)
+	assert.ErrorContains(t, err, "target_schema must be set")
+
+	var currentSchema string
+	require.NoError(t, testDB.NewRaw("SELECT current_schema()").Scan(testCtx, &currentSchema))
+	_, err = dualWriteTables(testCtx, testDB, currentSchema, nil)
+	assert.ErrorContains(t, err, "is the current schema")
+
+	_, err = dualWriteTables(testCtx, testDB, "zep_next", []string{"user_memory_index"})
+	assert.ErrorContains(t, err, "is not a table that can be dual written")
+}
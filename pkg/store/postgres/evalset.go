@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.EvalSetStore = &EvalSetStoreDAO{}
+
+type EvalSetStoreDAO struct {
+	db *bun.DB
+}
+
+func NewEvalSetStoreDAO(db *bun.DB) *EvalSetStoreDAO {
+	return &EvalSetStoreDAO{
+		db: db,
+	}
+}
+
+// Put creates an eval set, or replaces the description and cases of an existing one.
+func (dao *EvalSetStoreDAO) Put(ctx context.Context, set *models.EvalSet) (*models.EvalSet, error) {
+	now := time.Now()
+	record := &EvalSetSchema{
+		Name:        set.Name,
+		Description: set.Description,
+		Cases:       set.Cases,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	_, err := dao.db.NewInsert().
+		Model(record).
+		On("CONFLICT (name) DO UPDATE").
+		Set("description = EXCLUDED.description").
+		Set("cases = EXCLUDED.cases").
+		Set("updated_at = EXCLUDED.updated_at").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put eval set: %w", err)
+	}
+	return evalSetSchemaToEvalSet(record), nil
+}
+
+func (dao *EvalSetStoreDAO) Get(ctx context.Context, name string) (*models.EvalSet, error) {
+	record := new(EvalSetSchema)
+	err := dao.db.NewSelect().Model(record).Where("name = ?", name).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewNotFoundError("eval set " + name)
+		}
+		return nil, fmt.Errorf("failed to get eval set: %w", err)
+	}
+	return evalSetSchemaToEvalSet(record), nil
+}
+
+func (dao *EvalSetStoreDAO) List(ctx context.Context) ([]*models.EvalSet, error) {
+	var records []EvalSetSchema
+	if err := dao.db.NewSelect().Model(&records).Order("name ASC").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list eval sets: %w", err)
+	}
+
+	sets := make([]*models.EvalSet, len(records))
+	for i := range records {
+		sets[i] = evalSetSchemaToEvalSet(&records[i])
+	}
+	return sets, nil
+}
+
+func (dao *EvalSetStoreDAO) Delete(ctx context.Context, name string) error {
+	r, err := dao.db.NewDelete().
+		Model((*EvalSetSchema)(nil)).
+		Where("name = ?", name).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete eval set: %w", err)
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete eval set: %w", err)
+	}
+	if n == 0 {
+		return models.NewNotFoundError("eval set " + name)
+	}
+	return nil
+}
+
+func evalSetSchemaToEvalSet(record *EvalSetSchema) *models.EvalSet {
+	return &models.EvalSet{
+		Name:        record.Name,
+		Description: record.Description,
+		Cases:       record.Cases,
+		CreatedAt:   record.CreatedAt,
+		UpdatedAt:   record.UpdatedAt,
+	}
+}
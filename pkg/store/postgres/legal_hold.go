@@ -0,0 +1,281 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// sessionHeldCondition selects the sessions, aliased s, under legal hold directly or
+// through their user. Its arguments are the session and user subject types.
+const sessionHeldCondition = `EXISTS (SELECT 1 FROM legal_hold AS lh
+	WHERE (lh.subject_type = ? AND lh.subject_id = s.session_id)
+	OR (lh.subject_type = ? AND lh.subject_id = s.user_id))`
+
+var _ models.LegalHoldStore = &LegalHoldStoreDAO{}
+
+type LegalHoldStoreDAO struct {
+	db *bun.DB
+}
+
+func NewLegalHoldStoreDAO(db *bun.DB) *LegalHoldStoreDAO {
+	return &LegalHoldStoreDAO{
+		db: db,
+	}
+}
+
+// Place places a hold and records the event in the same transaction.
+func (dao *LegalHoldStoreDAO) Place(
+	ctx context.Context,
+	hold *models.LegalHold,
+) (*models.LegalHold, error) {
+	record := &LegalHoldSchema{
+		SubjectType: hold.SubjectType,
+		SubjectID:   hold.SubjectID,
+		Reason:      hold.Reason,
+		PlacedBy:    hold.PlacedBy,
+		PlacedAt:    time.Now(),
+	}
+
+	tx, err := dao.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	_, err = tx.NewInsert().
+		Model(record).
+		On("CONFLICT (subject_type, subject_id) DO UPDATE").
+		Set("reason = EXCLUDED.reason").
+		Set("placed_by = EXCLUDED.placed_by").
+		Set("placed_at = EXCLUDED.placed_at").
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place legal hold: %w", err)
+	}
+	err = insertLegalHoldEvent(
+		ctx,
+		tx,
+		hold.SubjectType,
+		hold.SubjectID,
+		models.LegalHoldPlaced,
+		hold.Reason,
+		hold.PlacedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return legalHoldFromSchema(record), nil
+}
+
+// Lift lifts a hold and records the event in the same transaction.
+func (dao *LegalHoldStoreDAO) Lift(
+	ctx context.Context,
+	subjectType, subjectID, actor, reason string,
+) error {
+	tx, err := dao.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	r, err := tx.NewDelete().
+		Model((*LegalHoldSchema)(nil)).
+		Where("subject_type = ?", subjectType).
+		Where("subject_id = ?", subjectID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to lift legal hold: %w", err)
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.NewNotFoundError("legal hold on " + subjectType + " " + subjectID)
+	}
+	err = insertLegalHoldEvent(
+		ctx,
+		tx,
+		subjectType,
+		subjectID,
+		models.LegalHoldLifted,
+		reason,
+		actor,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the hold on a subject.
+func (dao *LegalHoldStoreDAO) Get(
+	ctx context.Context,
+	subjectType, subjectID string,
+) (*models.LegalHold, error) {
+	record := new(LegalHoldSchema)
+	err := dao.db.NewSelect().
+		Model(record).
+		Where("subject_type = ?", subjectType).
+		Where("subject_id = ?", subjectID).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewNotFoundError("legal hold on " + subjectType + " " + subjectID)
+		}
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+	return legalHoldFromSchema(record), nil
+}
+
+// List returns the holds in place, most recently placed first.
+func (dao *LegalHoldStoreDAO) List(ctx context.Context) ([]*models.LegalHold, error) {
+	var records []LegalHoldSchema
+	err := dao.db.NewSelect().
+		Model(&records).
+		Order("placed_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+
+	holds := make([]*models.LegalHold, len(records))
+	for i := range records {
+		holds[i] = legalHoldFromSchema(&records[i])
+	}
+	return holds, nil
+}
+
+// ListEvents returns the events of a subject, or of all subjects if subjectType is empty,
+// oldest first.
+func (dao *LegalHoldStoreDAO) ListEvents(
+	ctx context.Context,
+	subjectType, subjectID string,
+) ([]*models.LegalHoldEvent, error) {
+	var records []LegalHoldEventSchema
+	q := dao.db.NewSelect().Model(&records)
+	if subjectType != "" {
+		q = q.Where("subject_type = ?", subjectType).Where("subject_id = ?", subjectID)
+	}
+	if err := q.Order("created_at ASC").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list legal hold events: %w", err)
+	}
+
+	events := make([]*models.LegalHoldEvent, len(records))
+	for i, r := range records {
+		events[i] = &models.LegalHoldEvent{
+			UUID:        r.UUID,
+			CreatedAt:   r.CreatedAt,
+			SubjectType: r.SubjectType,
+			SubjectID:   r.SubjectID,
+			Action:      r.Action,
+			Reason:      r.Reason,
+			Actor:       r.Actor,
+		}
+	}
+	return events, nil
+}
+
+func insertLegalHoldEvent(
+	ctx context.Context,
+	tx bun.Tx,
+	subjectType, subjectID, action, reason, actor string,
+) error {
+	_, err := tx.NewInsert().
+		Model(&LegalHoldEventSchema{
+			SubjectType: subjectType,
+			SubjectID:   subjectID,
+			Action:      action,
+			Reason:      reason,
+			Actor:       actor,
+		}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to record legal hold event: %w", err)
+	}
+	return nil
+}
+
+// checkSessionHold returns a ConflictError if the undeleted session is under legal hold,
+// directly or through its user.
+func checkSessionHold(ctx context.Context, db bun.IDB, sessionID string) error {
+	held, err := db.NewSelect().
+		Model((*SessionSchema)(nil)).
+		Where("s.session_id = ?", sessionID).
+		Where(
+			sessionHeldCondition,
+			models.LegalHoldSubjectSession,
+			models.LegalHoldSubjectUser,
+		).
+		Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check legal hold: %w", err)
+	}
+	if held {
+		return models.NewLegalHoldError(models.LegalHoldSubjectSession, sessionID)
+	}
+	return nil
+}
+
+// checkUserHold returns a ConflictError if the user, or any of the user's undeleted
+// sessions, is under legal hold.
+func checkUserHold(ctx context.Context, db bun.IDB, userID string) error {
+	held, err := db.NewSelect().
+		Model((*LegalHoldSchema)(nil)).
+		Where("subject_type = ?", models.LegalHoldSubjectUser).
+		Where("subject_id = ?", userID).
+		Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check legal hold: %w", err)
+	}
+	if held {
+		return models.NewLegalHoldError(models.LegalHoldSubjectUser, userID)
+	}
+
+	var sessionIDs []string
+	err = db.NewSelect().
+		Model((*SessionSchema)(nil)).
+		Column("session_id").
+		Where("s.user_id = ?", userID).
+		Where(
+			sessionHeldCondition,
+			models.LegalHoldSubjectSession,
+			models.LegalHoldSubjectUser,
+		).
+		Limit(1).
+		Scan(ctx, &sessionIDs)
+	if err != nil {
+		return fmt.Errorf("failed to check legal hold: %w", err)
+	}
+	if len(sessionIDs) > 0 {
+		return models.NewLegalHoldError(models.LegalHoldSubjectSession, sessionIDs[0])
+	}
+	return nil
+}
+
+func legalHoldFromSchema(record *LegalHoldSchema) *models.LegalHold {
+	return &models.LegalHold{
+		SubjectType: record.SubjectType,
+		SubjectID:   record.SubjectID,
+		Reason:      record.Reason,
+		PlacedBy:    record.PlacedBy,
+		PlacedAt:    record.PlacedAt,
+	}
+}
@@ -12,7 +12,7 @@ import (
 // NewMemoryDAO creates a new MemoryDAO.
 func NewMemoryDAO(db *bun.DB, appState *models.AppState, sessionID string) (*MemoryDAO, error) {
 	if sessionID == "" {
-		return nil, errors.New("sessionID cannot be empty")
+		return nil, models.NewValidationError("sessionID cannot be empty")
 	}
 	return &MemoryDAO{
 		db:        db,
@@ -42,7 +42,7 @@ func (m *MemoryDAO) Get(
 	lastNMessages int,
 ) (*models.Memory, error) {
 	if lastNMessages < 0 {
-		return nil, errors.New("cannot specify negative lastNMessages")
+		return nil, models.NewValidationError("cannot specify negative lastNMessages")
 	}
 
 	summaryDAO, err := NewSummaryDAO(m.db, m.appState, m.sessionID)
@@ -124,14 +124,20 @@ func (m *MemoryDAO) Create(
 	}
 
 	// Send new messages to the message router
-	err = m.appState.TaskPublisher.PublishMessage(
-		map[string]string{"session_id": m.sessionID},
-		mt,
-	)
+	metadata := models.WithTenantMetadata(ctx, map[string]string{"session_id": m.sessionID})
+	err = m.appState.TaskPublisher.PublishMessage(metadata, mt)
 	if err != nil {
 		return fmt.Errorf("failed to publish new messages %w", err)
 	}
 
+	// Compaction is opt-in, so only publish to the compactor if a session limit is set
+	if m.appState.Config.Memory.MaxSessionMessages > 0 {
+		err = m.appState.TaskPublisher.Publish(models.MessageCompactorTopic, metadata, mt)
+		if err != nil {
+			return fmt.Errorf("failed to publish message compaction task %w", err)
+		}
+	}
+
 	return nil
 }
 
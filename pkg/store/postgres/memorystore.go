@@ -2,11 +2,10 @@ package postgres
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
-	"encoding/binary"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/getzep/zep/pkg/store"
 	"github.com/google/uuid"
@@ -81,6 +80,14 @@ func (pms *PostgresMemoryStore) CreateSession(
 	return pms.SessionStore.Create(ctx, session)
 }
 
+// CreateSessions creates a batch of Sessions in a single transaction.
+func (pms *PostgresMemoryStore) CreateSessions(
+	ctx context.Context,
+	sessions []models.CreateSessionRequest,
+) (*models.CreateSessionsResponse, error) {
+	return pms.SessionStore.CreateBatch(ctx, sessions)
+}
+
 // UpdateSession creates or updates a Session for a given sessionID.
 func (pms *PostgresMemoryStore) UpdateSession(
 	ctx context.Context,
@@ -89,6 +96,15 @@ func (pms *PostgresMemoryStore) UpdateSession(
 	return pms.SessionStore.Update(ctx, session, false)
 }
 
+// PatchSessionMetadata applies a JSON Patch or JSON Merge Patch to a Session's metadata.
+func (pms *PostgresMemoryStore) PatchSessionMetadata(
+	ctx context.Context,
+	sessionID string,
+	patch models.MetadataPatch,
+) (*models.Session, error) {
+	return pms.SessionStore.PatchMetadata(ctx, sessionID, patch, false)
+}
+
 // DeleteSession deletes a session from the memory store. This is a soft Delete.
 func (pms *PostgresMemoryStore) DeleteSession(ctx context.Context, sessionID string) error {
 	return pms.SessionStore.Delete(ctx, sessionID)
@@ -115,6 +131,56 @@ func (pms *PostgresMemoryStore) ListSessionsOrdered(
 	return pms.SessionStore.ListAllOrdered(ctx, pageNumber, pageSize, orderedBy, asc)
 }
 
+// ListExpiringSessions returns sessions expiring before the given time that have not been warned.
+func (pms *PostgresMemoryStore) ListExpiringSessions(
+	ctx context.Context,
+	before time.Time,
+) ([]*models.Session, error) {
+	return pms.SessionStore.ListExpiring(ctx, before)
+}
+
+// MarkSessionsExpiryWarned records that an expiry warning has been sent for the given sessionIDs.
+func (pms *PostgresMemoryStore) MarkSessionsExpiryWarned(
+	ctx context.Context,
+	sessionIDs []string,
+) error {
+	return pms.SessionStore.MarkExpiryWarned(ctx, sessionIDs)
+}
+
+// DeleteExpiredSessions soft deletes all sessions whose expiry has passed.
+func (pms *PostgresMemoryStore) DeleteExpiredSessions(
+	ctx context.Context,
+) ([]*models.Session, error) {
+	return pms.SessionStore.DeleteExpired(ctx)
+}
+
+// UpdateSessionTitle sets the title of a session.
+func (pms *PostgresMemoryStore) UpdateSessionTitle(
+	ctx context.Context,
+	sessionID string,
+	title string,
+) error {
+	return pms.SessionStore.UpdateTitle(ctx, sessionID, title)
+}
+
+// UpdateSessionTaskState replaces the task state of a session.
+func (pms *PostgresMemoryStore) UpdateSessionTaskState(
+	ctx context.Context,
+	sessionID string,
+	state *models.TaskState,
+) error {
+	return pms.SessionStore.UpdateTaskState(ctx, sessionID, state)
+}
+
+// UpdateSessionEpisodes replaces the episodes of a session.
+func (pms *PostgresMemoryStore) UpdateSessionEpisodes(
+	ctx context.Context,
+	sessionID string,
+	episodes []models.Episode,
+) error {
+	return pms.SessionStore.UpdateEpisodes(ctx, sessionID, episodes)
+}
+
 // GetMemory returns the most recent Summary and a list of messages for a given sessionID.
 // GetMemory returns:
 //   - the most recent Summary, if one exists
@@ -128,7 +194,7 @@ func (pms *PostgresMemoryStore) GetMemory(
 	lastNMessages int,
 ) (*models.Memory, error) {
 	if lastNMessages < 0 {
-		return nil, errors.New("cannot specify negative lastNMessages")
+		return nil, models.NewValidationError("cannot specify negative lastNMessages")
 	}
 
 	memoryDAO, err := NewMemoryDAO(pms.Client, pms.appState, sessionID)
@@ -168,6 +234,80 @@ func (pms *PostgresMemoryStore) GetMessageList(
 	return messageDAO.GetListBySession(ctx, pageNumber, pageSize)
 }
 
+// GetMatchingMessageList retrieves a page of the messages of a session matching the filter.
+func (pms *PostgresMemoryStore) GetMatchingMessageList(
+	ctx context.Context,
+	sessionID string,
+	filter *models.MessageFilter,
+	pageNumber int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	messageDAO, err := NewMessageDAO(pms.Client, pms.appState, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create messageDAO: %w", err)
+	}
+
+	return messageDAO.GetMatchingList(ctx, filter, pageNumber, pageSize)
+}
+
+// DeleteMatchingMessages deletes the messages of a session matching the filter.
+func (pms *PostgresMemoryStore) DeleteMatchingMessages(
+	ctx context.Context,
+	sessionID string,
+	filter *models.MessageFilter,
+) (int, error) {
+	messageDAO, err := NewMessageDAO(pms.Client, pms.appState, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create messageDAO: %w", err)
+	}
+
+	return messageDAO.DeleteMatching(ctx, filter)
+}
+
+// GetCompactionCandidates returns the oldest uncompacted messages for a given sessionID in excess of maxMessages.
+func (pms *PostgresMemoryStore) GetCompactionCandidates(
+	ctx context.Context,
+	sessionID string,
+	maxMessages int,
+) (*models.CompactionCandidates, error) {
+	messageDAO, err := NewMessageDAO(pms.Client, pms.appState, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create messageDAO: %w", err)
+	}
+
+	return messageDAO.GetCompactionCandidates(ctx, maxMessages)
+}
+
+// CompactMessages prunes the content of all messages up to and including throughUUID.
+func (pms *PostgresMemoryStore) CompactMessages(
+	ctx context.Context,
+	sessionID string,
+	throughUUID uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	messageDAO, err := NewMessageDAO(pms.Client, pms.appState, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to create messageDAO: %w", err)
+	}
+
+	return messageDAO.Compact(ctx, throughUUID, retainEmbeddings)
+}
+
+// PruneMessages prunes the content of the given messages.
+func (pms *PostgresMemoryStore) PruneMessages(
+	ctx context.Context,
+	sessionID string,
+	messageUUIDs []uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	messageDAO, err := NewMessageDAO(pms.Client, pms.appState, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to create messageDAO: %w", err)
+	}
+
+	return messageDAO.Prune(ctx, messageUUIDs, retainEmbeddings)
+}
+
 func (pms *PostgresMemoryStore) GetMessagesByUUID(
 	ctx context.Context,
 	sessionID string,
@@ -240,9 +380,9 @@ func (pms *PostgresMemoryStore) CreateSummary(
 	}
 	err = pms.appState.TaskPublisher.Publish(
 		models.MessageSummaryEmbedderTopic,
-		map[string]string{
+		models.WithTenantMetadata(ctx, map[string]string{
 			"session_id": sessionID,
-		},
+		}),
 		task,
 	)
 	if err != nil {
@@ -251,9 +391,9 @@ func (pms *PostgresMemoryStore) CreateSummary(
 
 	err = pms.appState.TaskPublisher.Publish(
 		models.MessageSummaryNERTopic,
-		map[string]string{
+		models.WithTenantMetadata(ctx, map[string]string{
 			"session_id": sessionID,
-		},
+		}),
 		task,
 	)
 	if err != nil {
@@ -322,8 +462,19 @@ func (pms *PostgresMemoryStore) SearchMemory(
 	return memoryDAO.Search(ctx, query, limit)
 }
 
+func (pms *PostgresMemoryStore) SearchUserMemory(
+	ctx context.Context,
+	userID string,
+	query *models.MemorySearchPayload,
+	sessions int,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	return searchUserMemory(ctx, pms.appState, pms.Client, userID, query, sessions, limit)
+}
+
 func (pms *PostgresMemoryStore) Close() error {
 	if pms.Client != nil {
+		preparedStatements.closeDB(pms.Client)
 		return pms.Client.Close()
 	}
 	return nil
@@ -361,49 +512,19 @@ func (pms *PostgresMemoryStore) PurgeDeleted(ctx context.Context) error {
 	return nil
 }
 
-func generateLockID(key string) uint64 {
-	hasher := sha256.New()
-	hasher.Write([]byte(key))
-	hash := hasher.Sum(nil)
-	return binary.BigEndian.Uint64(hash[:8])
-}
-
-// tryAcquireAdvisoryLock attempts to acquire a PostgreSQL advisory lock using pg_try_advisory_lock.
-// This function will fail if it's unable to immediately acquire a lock.
-// Accepts a bun.IDB, which can be either a *bun.DB or *bun.Tx.
-// Returns the lock ID and a boolean indicating if the lock was successfully acquired.
-func tryAcquireAdvisoryLock(ctx context.Context, db bun.IDB, key string) (uint64, error) {
-	lockID := generateLockID(key)
-
-	var acquired bool
-	if err := db.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(?)", lockID).Scan(&acquired); err != nil {
-		return 0, fmt.Errorf("tryAcquireAdvisoryLock: %w", err)
-	}
-	if !acquired {
-		return 0, models.NewAdvisoryLockError(fmt.Errorf("failed to acquire advisory lock for %s", key))
-	}
-	return lockID, nil
-}
-
-// acquireAdvisoryLock acquires a PostgreSQL advisory lock for the given key.
-func acquireAdvisoryLock(ctx context.Context, db bun.IDB, key string) (uint64, error) {
-	lockID := generateLockID(key)
-
-	if _, err := db.ExecContext(ctx, "SELECT pg_advisory_lock(?)", lockID); err != nil {
-		return 0, store.NewStorageError("failed to acquire advisory lock", err)
-	}
-
-	return lockID, nil
-}
-
-// releaseAdvisoryLock releases a PostgreSQL advisory lock for the given key.
-// Accepts a bun.IDB, which can be either a *bun.DB or *bun.Tx.
-func releaseAdvisoryLock(ctx context.Context, db bun.IDB, lockID uint64) error {
-	if _, err := db.ExecContext(ctx, "SELECT pg_advisory_unlock(?)", lockID); err != nil {
-		return store.NewStorageError("failed to release advisory lock", err)
+// DeduplicateMessageEmbeddings marks near-duplicate message embeddings, excluding them from search.
+func (pms *PostgresMemoryStore) DeduplicateMessageEmbeddings(
+	ctx context.Context,
+	since time.Time,
+	threshold float32,
+	scope models.DeduplicationScope,
+) (int, error) {
+	count, err := deduplicateMessageEmbeddings(ctx, pms.Client, since, threshold, scope)
+	if err != nil {
+		return count, store.NewStorageError("failed to deduplicate message embeddings", err)
 	}
 
-	return nil
+	return count, nil
 }
 
 // rollbackOnError rolls back the transaction if an error is encountered.
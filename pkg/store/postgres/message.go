@@ -14,8 +14,15 @@ import (
 	"github.com/getzep/zep/pkg/models"
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
 )
 
+var _ models.MessageDAO = &MessageDAO{}
+
+// DefaultMessageRegexTimeout is the timeout of a query filtering messages by a regular
+// expression, in milliseconds, if memory.message_regex.timeout isn't set.
+const DefaultMessageRegexTimeout = 5000
+
 type MessageDAO struct {
 	db        *bun.DB
 	appState  *models.AppState
@@ -30,7 +37,7 @@ func NewMessageDAO(db *bun.DB, appState *models.AppState, sessionID string) (*Me
 		return nil, errors.New("appState cannot be nil")
 	}
 	if sessionID == "" {
-		return nil, errors.New("sessionID cannot be empty")
+		return nil, models.NewValidationError("sessionID cannot be empty")
 	}
 	return &MessageDAO{
 		db:        db,
@@ -43,15 +50,25 @@ func NewMessageDAO(db *bun.DB, appState *models.AppState, sessionID string) (*Me
 func (dao *MessageDAO) Create(
 	ctx context.Context,
 	message *models.Message,
+) (*models.Message, error) {
+	return withConflictRetry(ctx, "create message", func(ctx context.Context) (*models.Message, error) {
+		return dao.create(ctx, message)
+	})
+}
+
+func (dao *MessageDAO) create(
+	ctx context.Context,
+	message *models.Message,
 ) (*models.Message, error) {
 	// Create a new MessageStoreSchema from the provided message
 	pgMessage := MessageStoreSchema{
-		UUID:       message.UUID,
-		SessionID:  dao.sessionID,
-		Role:       message.Role,
-		Content:    message.Content,
-		TokenCount: message.TokenCount,
-		Metadata:   message.Metadata,
+		UUID:        message.UUID,
+		SessionID:   dao.sessionID,
+		Role:        message.Role,
+		Content:     message.Content,
+		TokenCount:  message.TokenCount,
+		Metadata:    message.Metadata,
+		ContentHash: models.ContentHash(message.Content),
 	}
 
 	// Insert the new message into the database
@@ -65,13 +82,14 @@ func (dao *MessageDAO) Create(
 	}
 
 	return &models.Message{
-		UUID:       pgMessage.UUID,
-		CreatedAt:  pgMessage.CreatedAt,
-		UpdatedAt:  pgMessage.UpdatedAt,
-		Role:       pgMessage.Role,
-		Content:    pgMessage.Content,
-		TokenCount: pgMessage.TokenCount,
-		Metadata:   pgMessage.Metadata,
+		UUID:        pgMessage.UUID,
+		CreatedAt:   pgMessage.CreatedAt,
+		UpdatedAt:   pgMessage.UpdatedAt,
+		Role:        pgMessage.Role,
+		Content:     pgMessage.Content,
+		TokenCount:  pgMessage.TokenCount,
+		Metadata:    pgMessage.Metadata,
+		ContentHash: pgMessage.ContentHash,
 	}, nil
 }
 
@@ -79,20 +97,42 @@ func (dao *MessageDAO) Create(
 func (dao *MessageDAO) CreateMany(
 	ctx context.Context,
 	messages []models.Message,
+) ([]models.Message, error) {
+	return withConflictRetry(
+		ctx,
+		"create messages",
+		func(ctx context.Context) ([]models.Message, error) {
+			return dao.createMany(ctx, messages)
+		},
+	)
+}
+
+func (dao *MessageDAO) createMany(
+	ctx context.Context,
+	messages []models.Message,
 ) ([]models.Message, error) {
 	if len(messages) == 0 {
 		return nil, nil
 	}
 
+	if useCopy(dao.appState, len(messages)) {
+		pgMessages, err := dao.copyMessages(ctx, messages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy messages %w", err)
+		}
+		return messagesFromStoreSchema(pgMessages), nil
+	}
+
 	pgMessages := make([]MessageStoreSchema, len(messages))
 	for i, msg := range messages {
 		pgMessages[i] = MessageStoreSchema{
-			UUID:       msg.UUID,
-			SessionID:  dao.sessionID,
-			Role:       msg.Role,
-			Content:    msg.Content,
-			TokenCount: msg.TokenCount,
-			Metadata:   msg.Metadata,
+			UUID:        msg.UUID,
+			SessionID:   dao.sessionID,
+			Role:        msg.Role,
+			Content:     msg.Content,
+			TokenCount:  msg.TokenCount,
+			Metadata:    msg.Metadata,
+			ContentHash: models.ContentHash(msg.Content),
 		}
 	}
 
@@ -125,11 +165,12 @@ func (dao *MessageDAO) Get(ctx context.Context, messageUUID uuid.UUID) (*models.
 	}
 
 	return &models.Message{
-		UUID:       messages.UUID,
-		Role:       messages.Role,
-		Content:    messages.Content,
-		TokenCount: messages.TokenCount,
-		Metadata:   messages.Metadata,
+		UUID:        messages.UUID,
+		Role:        messages.Role,
+		Content:     messages.Content,
+		TokenCount:  messages.TokenCount,
+		Metadata:    messages.Metadata,
+		ContentHash: messages.ContentHash,
 	}, nil
 }
 
@@ -291,12 +332,13 @@ func (dao *MessageDAO) GetListBySession(
 	messageList := make([]models.Message, len(messages))
 	for i, msg := range messages {
 		messageList[i] = models.Message{
-			UUID:       msg.UUID,
-			CreatedAt:  msg.CreatedAt,
-			Role:       msg.Role,
-			Content:    msg.Content,
-			TokenCount: msg.TokenCount,
-			Metadata:   msg.Metadata,
+			UUID:        msg.UUID,
+			CreatedAt:   msg.CreatedAt,
+			Role:        msg.Role,
+			Content:     msg.Content,
+			TokenCount:  msg.TokenCount,
+			Metadata:    msg.Metadata,
+			ContentHash: msg.ContentHash,
 		}
 	}
 
@@ -314,7 +356,18 @@ func (dao *MessageDAO) GetListBySession(
 
 // Update updates a message by its UUID. Metadata is updated via a merge.
 // If includeContent is true, the content and role fields are updated, too.
-func (dao *MessageDAO) Update(ctx context.Context,
+func (dao *MessageDAO) Update(
+	ctx context.Context,
+	message *models.Message,
+	includeContent bool,
+	isPrivileged bool,
+) error {
+	return withRetryErr(ctx, "update message", func(ctx context.Context) error {
+		return dao.update(ctx, message, includeContent, isPrivileged)
+	})
+}
+
+func (dao *MessageDAO) update(ctx context.Context,
 	message *models.Message,
 	includeContent bool,
 	isPrivileged bool) error {
@@ -329,14 +382,15 @@ func (dao *MessageDAO) Update(ctx context.Context,
 
 	// Don't update the Metadata field here. We do this via a merge below.
 	messageDB := MessageStoreSchema{
-		Role:       message.Role,
-		Content:    message.Content,
-		TokenCount: message.TokenCount,
+		Role:        message.Role,
+		Content:     message.Content,
+		TokenCount:  message.TokenCount,
+		ContentHash: models.ContentHash(message.Content),
 	}
 
 	columns := []string{"token_count"}
 	if includeContent {
-		columns = append(columns, "role", "content")
+		columns = append(columns, "role", "content", "content_hash")
 	}
 
 	r, err := tx.NewUpdate().
@@ -374,7 +428,18 @@ func (dao *MessageDAO) Update(ctx context.Context,
 }
 
 // UpdateMany updates a batch of messages by their UUIDs. Metadata is updated via a merge.
-func (dao *MessageDAO) UpdateMany(ctx context.Context,
+func (dao *MessageDAO) UpdateMany(
+	ctx context.Context,
+	messages []models.Message,
+	includeContent bool,
+	isPrivileged bool,
+) error {
+	return withRetryErr(ctx, "update messages", func(ctx context.Context) error {
+		return dao.updateMany(ctx, messages, includeContent, isPrivileged)
+	})
+}
+
+func (dao *MessageDAO) updateMany(ctx context.Context,
 	messages []models.Message,
 	includeContent bool,
 	isPrivileged bool) error {
@@ -390,10 +455,11 @@ func (dao *MessageDAO) UpdateMany(ctx context.Context,
 			return fmt.Errorf("message UUID cannot be nil")
 		}
 		messagesDB[i] = MessageStoreSchema{
-			UUID:       msg.UUID,
-			Role:       msg.Role,
-			Content:    msg.Content,
-			TokenCount: msg.TokenCount,
+			UUID:        msg.UUID,
+			Role:        msg.Role,
+			Content:     msg.Content,
+			TokenCount:  msg.TokenCount,
+			ContentHash: models.ContentHash(msg.Content),
 		}
 	}
 
@@ -407,7 +473,8 @@ func (dao *MessageDAO) UpdateMany(ctx context.Context,
 
 	if includeContent {
 		query = query.Set("role = _data.role").
-			Set("content = _data.content")
+			Set("content = _data.content").
+			Set("content_hash = _data.content_hash")
 	}
 
 	_, err = query.
@@ -436,8 +503,8 @@ func (dao *MessageDAO) UpdateMany(ctx context.Context,
 	return nil
 }
 
-// updateMetadata updates the metadata for a message by its UUID. Metadata is updated via a merge.
-// An advisory lock is acquired on the message UUID to prevent concurrent updates to the metadata.
+// updateMetadata updates the metadata for a message by its UUID. Metadata is deep merged
+// into the existing metadata in a single UPDATE. See mergeMetadata.
 func (dao *MessageDAO) updateMetadata(
 	ctx context.Context,
 	tx bun.IDB, // use bun.IDB interface to make it easier to test
@@ -445,47 +512,45 @@ func (dao *MessageDAO) updateMetadata(
 	metadata map[string]interface{},
 	isPrivileged bool,
 ) error {
-	// Acquire a lock for this Message UUID. This is to prevent concurrent updates
-	// to the message metadata.
-	lockID, err := acquireAdvisoryLock(ctx, tx, messageUUID.String())
-	if err != nil {
-		return fmt.Errorf("failed to acquire advisory lock: %w", err)
+	// remove the top-level `system` key from the metadata if the caller is not privileged
+	if !isPrivileged {
+		delete(metadata, "system")
 	}
-	defer func(ctx context.Context, db bun.IDB, lockID uint64) {
-		err := releaseAdvisoryLock(ctx, db, lockID)
-		if err != nil {
-			log.Errorf("failed to release advisory lock: %v", err)
-		}
-	}(ctx, tx, lockID)
-
-	mergedMetadata, err := mergeMetadata(
-		ctx,
-		tx,
-		"uuid",
-		messageUUID.String(),
-		"message",
-		metadata,
-		isPrivileged,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to merge message metadata: %w", err)
+	if len(metadata) == 0 {
+		return nil
 	}
 
-	_, err = tx.NewUpdate().
+	r, err := tx.NewUpdate().
 		Model(&MessageStoreSchema{}).
-		Column("metadata").
+		Set(metadataMergeSet, metadata).
 		Where("session_id = ?", dao.sessionID).
 		Where("uuid = ?", messageUUID).
-		Set("metadata = ?", mergedMetadata).
 		Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to update message metadata: %w", err)
 	}
 
+	rows, err := r.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return models.NewNotFoundError(fmt.Sprintf("message %s not found", messageUUID))
+	}
+
 	return nil
 }
 
-func (dao *MessageDAO) Delete(ctx context.Context, messageUUID uuid.UUID) error {
+func (dao *MessageDAO) Delete(
+	ctx context.Context,
+	messageUUID uuid.UUID,
+) error {
+	return withRetryErr(ctx, "delete message", func(ctx context.Context) error {
+		return dao.delete(ctx, messageUUID)
+	})
+}
+
+func (dao *MessageDAO) delete(ctx context.Context, messageUUID uuid.UUID) error {
 	if messageUUID == uuid.Nil {
 		return fmt.Errorf("message UUID cannot be nil")
 	}
@@ -525,6 +590,10 @@ func (dao *MessageDAO) Delete(ctx context.Context, messageUUID uuid.UUID) error
 		return models.NewNotFoundError(fmt.Sprintf("message %s not found", messageUUID))
 	}
 
+	if err := updateSessionEmbedding(ctx, tx, dao.sessionID); err != nil {
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -532,10 +601,326 @@ func (dao *MessageDAO) Delete(ctx context.Context, messageUUID uuid.UUID) error
 	return nil
 }
 
+// GetCompactionCandidates returns the oldest uncompacted messages in excess of maxMessages,
+// along with the subset of these that are more recent than the current SummaryPoint.
+// Compacted messages have had their content pruned and are not counted towards maxMessages.
+func (dao *MessageDAO) GetCompactionCandidates(
+	ctx context.Context,
+	maxMessages int,
+) (*models.CompactionCandidates, error) {
+	count, err := dao.db.NewSelect().
+		Model((*MessageStoreSchema)(nil)).
+		Where("session_id = ?", dao.sessionID).
+		Where("content <> ''").
+		Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	if count <= maxMessages {
+		return &models.CompactionCandidates{}, nil
+	}
+
+	var messagesDB []MessageStoreSchema
+	err = dao.db.NewSelect().
+		Model(&messagesDB).
+		Where("session_id = ?", dao.sessionID).
+		Where("content <> ''").
+		Order("id ASC").
+		Limit(count - maxMessages).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get compaction candidates: %w", err)
+	}
+
+	summaryDAO, err := NewSummaryDAO(dao.db, dao.appState, dao.sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create summaryDAO: %w", err)
+	}
+	summary, err := summaryDAO.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+
+	var summaryPointIndex int64
+	if summary.SummaryPointUUID != uuid.Nil {
+		summaryPointIndex, err = getMessageIndex(ctx, dao.db, dao.sessionID, summary.SummaryPointUUID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve summary point index %w", err)
+		}
+	}
+
+	var unsummarizedDB []MessageStoreSchema
+	for i := range messagesDB {
+		if messagesDB[i].ID > summaryPointIndex {
+			unsummarizedDB = messagesDB[i:]
+			break
+		}
+	}
+
+	return &models.CompactionCandidates{
+		Messages:     messagesFromStoreSchema(messagesDB),
+		Unsummarized: messagesFromStoreSchema(unsummarizedDB),
+	}, nil
+}
+
+// Compact prunes the content of all messages up to and including throughUUID. If retainEmbeddings
+// is true, the messages and their embeddings are kept, so that compacted messages continue to be
+// returned by search. Otherwise, the messages and their embeddings are deleted.
+func (dao *MessageDAO) Compact(
+	ctx context.Context,
+	throughUUID uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	return withRetryErr(ctx, "compact messages", func(ctx context.Context) error {
+		return dao.compact(ctx, throughUUID, retainEmbeddings)
+	})
+}
+
+func (dao *MessageDAO) compact(
+	ctx context.Context,
+	throughUUID uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	if throughUUID == uuid.Nil {
+		return fmt.Errorf("message UUID cannot be nil")
+	}
+
+	index, err := getMessageIndex(ctx, dao.db, dao.sessionID, throughUUID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve compaction point index %w", err)
+	}
+	if index == 0 {
+		return models.NewNotFoundError("message " + throughUUID.String())
+	}
+
+	return dao.prune(ctx, retainEmbeddings, "id <= ?", index)
+}
+
+// Prune prunes the content of the given messages, as Compact does.
+func (dao *MessageDAO) Prune(
+	ctx context.Context,
+	messageUUIDs []uuid.UUID,
+	retainEmbeddings bool,
+) error {
+	if len(messageUUIDs) == 0 {
+		return nil
+	}
+	return dao.prune(ctx, retainEmbeddings, "uuid IN (?)", bun.In(messageUUIDs))
+}
+
+// GetMatchingList retrieves a page of the session's messages whose content matches the
+// filter's regular expression, oldest first.
+func (dao *MessageDAO) GetMatchingList(
+	ctx context.Context,
+	filter *models.MessageFilter,
+	currentPage int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	if err := filter.Validate(dao.appState.Config.Memory.MessageRegex.MaxComplexity); err != nil {
+		return nil, err
+	}
+
+	var count int
+	var messages []MessageStoreSchema
+	err := dao.runRegexQuery(ctx, func(ctx context.Context, tx bun.Tx) error {
+		var err error
+		count, err = tx.NewSelect().
+			Model((*MessageStoreSchema)(nil)).
+			Where("session_id = ?", dao.sessionID).
+			Where("content ~ ?", filter.ContentRegex).
+			Count(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get message count %w", err)
+		}
+		err = tx.NewSelect().
+			Model(&messages).
+			Where("session_id = ?", dao.sessionID).
+			Where("content ~ ?", filter.ContentRegex).
+			OrderExpr("id ASC").
+			Limit(pageSize).
+			Offset((currentPage - 1) * pageSize).
+			Scan(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get messages %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MessageListResponse{
+		Messages:   messagesFromStoreSchema(messages),
+		TotalCount: count,
+		RowCount:   len(messages),
+	}, nil
+}
+
+// DeleteMatching deletes the session's messages whose content matches the filter's
+// regular expression, and their embeddings, returning the number of messages deleted.
+func (dao *MessageDAO) DeleteMatching(
+	ctx context.Context,
+	filter *models.MessageFilter,
+) (int, error) {
+	if err := filter.Validate(dao.appState.Config.Memory.MessageRegex.MaxComplexity); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	err := dao.runRegexQuery(ctx, func(ctx context.Context, tx bun.Tx) error {
+		if err := checkSessionHold(ctx, tx, dao.sessionID); err != nil {
+			return err
+		}
+		matching := tx.NewSelect().
+			Model((*MessageStoreSchema)(nil)).
+			Column("uuid").
+			Where("session_id = ?", dao.sessionID).
+			Where("content ~ ?", filter.ContentRegex)
+		_, err := tx.NewDelete().
+			Model((*MessageVectorStoreSchema)(nil)).
+			Where("session_id = ?", dao.sessionID).
+			Where("message_uuid IN (?)", matching).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to delete message embeddings: %w", err)
+		}
+
+		r, err := tx.NewDelete().
+			Model((*MessageStoreSchema)(nil)).
+			Where("session_id = ?", dao.sessionID).
+			Where("content ~ ?", filter.ContentRegex).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to delete messages: %w", err)
+		}
+		deleted, err = r.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		return updateSessionEmbedding(ctx, tx, dao.sessionID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
+
+// runRegexQuery runs fn in a transaction whose statements are canceled once they run
+// longer than memory.message_regex.timeout. A canceled statement or an expression
+// Postgres can't compile is a ValidationError, as the fault is the expression's.
+func (dao *MessageDAO) runRegexQuery(
+	ctx context.Context,
+	fn func(ctx context.Context, tx bun.Tx) error,
+) error {
+	timeout := dao.appState.Config.Memory.MessageRegex.Timeout
+	if timeout <= 0 {
+		timeout = DefaultMessageRegexTimeout
+	}
+	err := dao.db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SET LOCAL statement_timeout = ?", timeout); err != nil {
+			return fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+		return fn(ctx, tx)
+	})
+	var pgErr pgdriver.Error
+	if errors.As(err, &pgErr) {
+		switch pgErr.Field('C') {
+		case "57014": // query_canceled
+			return models.NewValidationError(fmt.Sprintf(
+				"content_regex took longer than %dms to match", timeout,
+			))
+		case "2201B": // invalid_regular_expression
+			return models.NewValidationError("invalid content_regex: " + pgErr.Field('M'))
+		}
+	}
+	return err
+}
+
+// prune prunes the content of the session's messages matching the where clause, marking
+// them as compacted. If retainEmbeddings is false, the messages and their embeddings are
+// deleted. A session under legal hold isn't pruned, and a ConflictError is returned.
+func (dao *MessageDAO) prune(
+	ctx context.Context,
+	retainEmbeddings bool,
+	where string,
+	args ...interface{},
+) error {
+	tx, err := dao.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	if err := checkSessionHold(ctx, tx, dao.sessionID); err != nil {
+		return err
+	}
+
+	if retainEmbeddings {
+		_, err = tx.NewUpdate().
+			Model((*MessageStoreSchema)(nil)).
+			Set("content = ''").
+			Set("content_hash = ?", models.ContentHash("")).
+			Set("token_count = 0").
+			Set(metadataMergeSet, models.CompactedMessageMetadata()).
+			Where("session_id = ?", dao.sessionID).
+			Where(where, args...).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to compact messages: %w", err)
+		}
+	} else {
+		_, err = tx.NewDelete().
+			Model((*MessageVectorStoreSchema)(nil)).
+			Where("session_id = ?", dao.sessionID).
+			Where(
+				"message_uuid IN (?)",
+				tx.NewSelect().
+					Model((*MessageStoreSchema)(nil)).
+					Column("uuid").
+					Where("session_id = ?", dao.sessionID).
+					Where(where, args...),
+			).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to delete compacted message embeddings: %w", err)
+		}
+
+		_, err = tx.NewDelete().
+			Model((*MessageStoreSchema)(nil)).
+			Where("session_id = ?", dao.sessionID).
+			Where(where, args...).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to delete compacted messages: %w", err)
+		}
+
+		if err := updateSessionEmbedding(ctx, tx, dao.sessionID); err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // CreateEmbeddings saves message embeddings for a set of given messages
 func (dao *MessageDAO) CreateEmbeddings(
 	ctx context.Context,
 	embeddings []models.TextData,
+) error {
+	return withConflictRetryErr(ctx, "create message embeddings", func(ctx context.Context) error {
+		return dao.createEmbeddings(ctx, embeddings)
+	})
+}
+
+func (dao *MessageDAO) createEmbeddings(
+	ctx context.Context,
+	embeddings []models.TextData,
 ) error {
 	if len(embeddings) == 0 {
 		return errors.New("no embeddings received")
@@ -548,17 +933,75 @@ func (dao *MessageDAO) CreateEmbeddings(
 			Embedding:   pgvector.NewVector(e.Embedding),
 			MessageUUID: e.TextUUID,
 			IsEmbedded:  true,
+			WindowUUIDs: e.Window,
 		}
 	}
 
-	_, err := dao.db.NewInsert().
+	tx, err := dao.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	// A message is re-embedded when its turn pair is completed.
+	_, err = tx.NewInsert().
 		Model(&embeddingVectors).
+		On("CONFLICT (message_uuid) DO UPDATE").
+		Set("embedding = EXCLUDED.embedding").
+		Set("window_uuids = EXCLUDED.window_uuids").
+		Set("duplicate_of = NULL").
+		Set("updated_at = current_timestamp").
 		Exec(ctx)
-
 	if err != nil {
 		return fmt.Errorf("failed to insert message vectors %w", err)
 	}
 
+	if err := updateSessionEmbedding(ctx, tx, dao.sessionID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// updateSessionEmbedding recomputes the session's embedding as the sum of the embeddings
+// of its undeleted messages, so that re-embedded messages aren't counted twice and deleted
+// messages are subtracted. The session's embedding is deleted once it has no embedded
+// messages.
+func updateSessionEmbedding(ctx context.Context, db bun.IDB, sessionID string) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO session_embedding (session_id, embedding, message_count)
+SELECT ?0, sum(me.embedding), count(*)
+FROM message_embedding AS me
+JOIN message AS m ON m.uuid = me.message_uuid
+WHERE me.session_id = ?0
+AND me.is_embedded
+AND me.deleted_at IS NULL
+AND m.deleted_at IS NULL
+HAVING count(*) > 0
+ON CONFLICT (session_id) DO UPDATE
+SET embedding = EXCLUDED.embedding,
+	message_count = EXCLUDED.message_count,
+	updated_at = current_timestamp`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to update session embedding: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, `DELETE FROM session_embedding AS sem
+WHERE sem.session_id = ?0
+AND NOT EXISTS (
+	SELECT 1 FROM message_embedding AS me
+	JOIN message AS m ON m.uuid = me.message_uuid
+	WHERE me.session_id = ?0
+	AND me.is_embedded
+	AND me.deleted_at IS NULL
+	AND m.deleted_at IS NULL
+)`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session embedding: %w", err)
+	}
 	return nil
 }
 
@@ -588,6 +1031,7 @@ func (dao *MessageDAO) GetEmbedding(ctx context.Context, messageUUID uuid.UUID)
 		Embedding: result.Embedding.Slice(),
 		TextUUID:  result.MessageUUID,
 		Text:      result.Content,
+		Window:    result.WindowUUIDs,
 	}, nil
 }
 
@@ -616,6 +1060,7 @@ func (dao *MessageDAO) GetEmbeddingListBySession(ctx context.Context) ([]models.
 			Embedding: vectorStoreRecord.Embedding.Slice(),
 			TextUUID:  vectorStoreRecord.MessageUUID,
 			Text:      vectorStoreRecord.Content,
+			Window:    vectorStoreRecord.WindowUUIDs,
 		}
 	}
 
@@ -633,9 +1078,12 @@ func getMessageIndex(
 ) (int64, error) {
 	var message MessageStoreSchema
 
+	// Include deleted messages, as the summary point may have been deleted or compacted.
+	// Its position in the session remains valid.
 	err := db.NewSelect().
 		Model(&message).
 		Column("id").
+		WhereAllWithDeleted().
 		Where("session_id = ? AND uuid = ?", sessionID, summaryPointUUID).
 		Scan(ctx)
 
@@ -661,12 +1109,13 @@ func messagesFromStoreSchema(messages []MessageStoreSchema) []models.Message {
 	messageList := make([]models.Message, len(messages))
 	for i, msg := range messages {
 		messageList[i] = models.Message{
-			UUID:       msg.UUID,
-			CreatedAt:  msg.CreatedAt,
-			Role:       msg.Role,
-			Content:    msg.Content,
-			TokenCount: msg.TokenCount,
-			Metadata:   msg.Metadata,
+			UUID:        msg.UUID,
+			CreatedAt:   msg.CreatedAt,
+			Role:        msg.Role,
+			Content:     msg.Content,
+			TokenCount:  msg.TokenCount,
+			Metadata:    msg.Metadata,
+			ContentHash: msg.ContentHash,
 		}
 	}
 	return messageList
@@ -6,6 +6,7 @@ import (
 	"github.com/getzep/zep/pkg/testutils"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"math/rand"
 	"testing"
 )
@@ -580,6 +581,123 @@ func TestDelete(t *testing.T) {
 	assert.ErrorIs(t, err, models.ErrNotFound)
 }
 
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		name             string
+		retainEmbeddings bool
+	}{
+		{name: "Retain embeddings", retainEmbeddings: true},
+		{name: "Delete embeddings", retainEmbeddings: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionID := createSession(t)
+
+			messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+			assert.NoError(t, err)
+
+			messages := make([]models.Message, 10)
+			for i := range messages {
+				messages[i] = models.Message{
+					Role:    "user",
+					Content: fmt.Sprintf("testContent%d", i),
+				}
+			}
+			messages, err = messageDAO.CreateMany(testCtx, messages)
+			assert.NoError(t, err)
+
+			err = messageDAO.CreateEmbeddings(testCtx, []models.TextData{
+				{TextUUID: messages[0].UUID, Embedding: genTestVector(t, 1536)},
+			})
+			assert.NoError(t, err)
+
+			// Summarize the first 2 messages
+			summaryDAO, err := NewSummaryDAO(testDB, appState, sessionID)
+			assert.NoError(t, err)
+			_, err = summaryDAO.Create(testCtx, &models.Summary{
+				Content:          "summary",
+				SummaryPointUUID: messages[1].UUID,
+			})
+			assert.NoError(t, err)
+
+			candidates, err := messageDAO.GetCompactionCandidates(testCtx, 10)
+			assert.NoError(t, err)
+			assert.Empty(t, candidates.Messages)
+
+			candidates, err = messageDAO.GetCompactionCandidates(testCtx, 6)
+			assert.NoError(t, err)
+			assert.Len(t, candidates.Messages, 4)
+			assert.Equal(t, messages[0].UUID, candidates.Messages[0].UUID)
+			assert.Len(t, candidates.Unsummarized, 2)
+			assert.Equal(t, messages[2].UUID, candidates.Unsummarized[0].UUID)
+
+			err = messageDAO.Compact(testCtx, messages[3].UUID, tt.retainEmbeddings)
+			assert.NoError(t, err)
+
+			candidates, err = messageDAO.GetCompactionCandidates(testCtx, 6)
+			assert.NoError(t, err)
+			assert.Empty(t, candidates.Messages)
+
+			_, err = messageDAO.GetEmbedding(testCtx, messages[0].UUID)
+			if tt.retainEmbeddings {
+				assert.NoError(t, err)
+				m, err := messageDAO.Get(testCtx, messages[0].UUID)
+				assert.NoError(t, err)
+				assert.Empty(t, m.Content)
+				assert.True(t, models.MessageCompacted(m))
+			} else {
+				assert.ErrorIs(t, err, models.ErrNotFound)
+				_, err = messageDAO.Get(testCtx, messages[0].UUID)
+				assert.ErrorIs(t, err, models.ErrNotFound)
+			}
+
+			m, err := messageDAO.Get(testCtx, messages[4].UUID)
+			assert.NoError(t, err)
+			assert.Equal(t, messages[4].Content, m.Content)
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	sessionID := createSession(t)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	assert.NoError(t, err)
+
+	messages := make([]models.Message, 4)
+	for i := range messages {
+		messages[i] = models.Message{
+			Role:    "user",
+			Content: fmt.Sprintf("testContent%d", i),
+		}
+	}
+	messages, err = messageDAO.CreateMany(testCtx, messages)
+	assert.NoError(t, err)
+
+	err = messageDAO.Prune(testCtx, []uuid.UUID{messages[0].UUID, messages[2].UUID}, true)
+	assert.NoError(t, err)
+
+	for i, m := range messages {
+		got, err := messageDAO.Get(testCtx, m.UUID)
+		assert.NoError(t, err)
+		if i == 0 || i == 2 {
+			assert.Empty(t, got.Content)
+			assert.True(t, models.MessageCompacted(got))
+		} else {
+			assert.Equal(t, m.Content, got.Content)
+			assert.False(t, models.MessageCompacted(got))
+		}
+	}
+
+	err = messageDAO.Prune(testCtx, []uuid.UUID{messages[1].UUID}, false)
+	assert.NoError(t, err)
+	_, err = messageDAO.Get(testCtx, messages[1].UUID)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+	_, err = messageDAO.Get(testCtx, messages[3].UUID)
+	assert.NoError(t, err)
+}
+
 func genTestVector(t *testing.T, width int) []float32 {
 	t.Helper()
 	vector := make([]float32, width)
@@ -712,3 +830,62 @@ func TestGetEmbeddingListBySession(t *testing.T) {
 	assert.Equal(t, embeddings[0].Embedding, textDataList[0].Embedding)
 	assert.Equal(t, embeddings[1].Embedding, textDataList[1].Embedding)
 }
+
+func TestSessionEmbedding(t *testing.T) {
+	sessionID := createSession(t)
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	require.NoError(t, err)
+	messages, err := messageDAO.CreateMany(testCtx, []models.Message{
+		{Role: "user", Content: "testContent0"},
+		{Role: "user", Content: "testContent1"},
+	})
+	require.NoError(t, err)
+
+	sessionEmbedding := func() SessionEmbeddingSchema {
+		var sem SessionEmbeddingSchema
+		err := testDB.NewSelect().Model(&sem).Where("session_id = ?", sessionID).Scan(testCtx)
+		require.NoError(t, err)
+		return sem
+	}
+	sum := func(vectors ...[]float32) []float32 {
+		s := make([]float32, len(vectors[0]))
+		for _, v := range vectors {
+			for i := range v {
+				s[i] += v[i]
+			}
+		}
+		return s
+	}
+
+	vectors := [][]float32{genTestVector(t, 1536), genTestVector(t, 1536)}
+	require.NoError(t, messageDAO.CreateEmbeddings(testCtx, []models.TextData{
+		{TextUUID: messages[0].UUID, Embedding: vectors[0]},
+		{TextUUID: messages[1].UUID, Embedding: vectors[1]},
+	}))
+	sem := sessionEmbedding()
+	assert.Equal(t, 2, sem.MessageCount)
+	assert.InDeltaSlice(t, sum(vectors...), sem.Embedding.Slice(), 1e-4)
+
+	// a re-embedded message replaces its previous embedding
+	vectors[1] = genTestVector(t, 1536)
+	require.NoError(t, messageDAO.CreateEmbeddings(testCtx, []models.TextData{
+		{TextUUID: messages[1].UUID, Embedding: vectors[1]},
+	}))
+	sem = sessionEmbedding()
+	assert.Equal(t, 2, sem.MessageCount)
+	assert.InDeltaSlice(t, sum(vectors...), sem.Embedding.Slice(), 1e-4)
+
+	// deleted messages are subtracted
+	require.NoError(t, messageDAO.Delete(testCtx, messages[0].UUID))
+	sem = sessionEmbedding()
+	assert.Equal(t, 1, sem.MessageCount)
+	assert.InDeltaSlice(t, vectors[1], sem.Embedding.Slice(), 1e-4)
+
+	require.NoError(t, messageDAO.Prune(testCtx, []uuid.UUID{messages[1].UUID}, false))
+	exists, err := testDB.NewSelect().
+		Model((*SessionEmbeddingSchema)(nil)).
+		Where("session_id = ?", sessionID).
+		Exists(testCtx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -6,13 +6,17 @@ import (
 	"errors"
 	"fmt"
 
-	"dario.cat/mergo"
 	"github.com/getzep/zep/pkg/models"
 	"github.com/uptrace/bun"
 )
 
-// mergeMetadata merges the received metadata map with the existing metadata map in DB,
-// creating keys and values if they don't exist, and overwriting others.
+// metadataMergeSet is a SET clause deep merging the metadata argument into the metadata column.
+const metadataMergeSet = "metadata = jsonb_deep_merge(coalesce(metadata, '{}'::jsonb), ?::jsonb)"
+
+// mergeMetadata deep merges the received metadata map into the entity's metadata in the DB,
+// creating keys and values if they don't exist, and overwriting others. The merge is a
+// single UPDATE, so concurrent merges into the same entity are serialized by the row lock.
+// Returns the merged metadata.
 func mergeMetadata(ctx context.Context,
 	db bun.IDB,
 	entityField string,
@@ -37,24 +41,20 @@ func mergeMetadata(ctx context.Context,
 		delete(metadata, "system")
 	}
 
-	// this should include selection of soft-deleted entities
-	dbMetadata := new(map[string]interface{})
-	err := db.NewSelect().
+	// this should include soft-deleted entities
+	var mergedMetadata map[string]interface{}
+	err := db.NewUpdate().
 		Table(table).
-		Column("metadata").
+		Set(metadataMergeSet, metadata).
 		Where("? = ?", bun.Ident(entityField), entityID).
-		Scan(ctx, &dbMetadata)
+		Returning("metadata").
+		Scan(ctx, &mergedMetadata)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, models.NewNotFoundError(fmt.Sprintf("%s %s", entityField, entityID))
 		}
-		return nil, fmt.Errorf("failed to get %s: %w", entityField, err)
-	}
-
-	// merge the existing metadata with the new metadata
-	if err := mergo.Merge(dbMetadata, metadata, mergo.WithOverride); err != nil {
-		return nil, fmt.Errorf("failed to merge metadata: %w", err)
+		return nil, fmt.Errorf("failed to merge %s metadata: %w", entityField, err)
 	}
 
-	return *dbMetadata, nil
+	return mergedMetadata, nil
 }
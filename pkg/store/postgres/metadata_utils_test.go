@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/getzep/zep/pkg/models"
@@ -53,35 +54,37 @@ func Test_mergeMetadata(t *testing.T) {
 	// Initialize SessionDAO
 	dao := NewSessionDAO(testDB)
 
-	// Create a test session
-	sessionID, err := testutils.GenerateRandomSessionID(16)
-	assert.NoError(t, err, "GenerateRandomSessionID should not return an error")
-
-	session := &models.CreateSessionRequest{
-		SessionID: sessionID,
-		Metadata: map[string]interface{}{
-			"A": 1,
-			"B": map[string]interface{}{
-				"C": 2,
+	// Merges are stored, so each test case merges into a new session
+	createTestSession := func(t *testing.T) string {
+		sessionID, err := testutils.GenerateRandomSessionID(16)
+		assert.NoError(t, err, "GenerateRandomSessionID should not return an error")
+
+		session := &models.CreateSessionRequest{
+			SessionID: sessionID,
+			Metadata: map[string]interface{}{
+				"A": 1,
+				"B": map[string]interface{}{
+					"C": 2,
+				},
+				"Z":  3,
+				"YY": "this should be removed",
 			},
-			"Z":  3,
-			"YY": "this should be removed",
-		},
+		}
+		_, err = dao.Create(testCtx, session)
+		assert.NoError(t, err)
+
+		return sessionID
 	}
-	_, err = dao.Create(testCtx, session)
-	assert.NoError(t, err)
 
 	tests := []struct {
 		name             string
-		sessionID        string
 		metadata         map[string]interface{}
 		privileged       bool
 		expectedError    error
 		expectedMetadata map[string]interface{}
 	}{
 		{
-			name:      "Update metadata",
-			sessionID: sessionID,
+			name: "Update metadata",
 			metadata: map[string]interface{}{
 				"A": 3, // Should override initial value of "A"
 				"B": map[string]interface{}{
@@ -107,8 +110,7 @@ func Test_mergeMetadata(t *testing.T) {
 			},
 		},
 		{
-			name:      "Unprivileged update with system metadata",
-			sessionID: sessionID,
+			name: "Unprivileged update with system metadata",
 			metadata: map[string]interface{}{
 				"A": 1,
 				"B": map[string]interface{}{
@@ -130,8 +132,7 @@ func Test_mergeMetadata(t *testing.T) {
 			},
 		},
 		{
-			name:      "Privileged update with system metadata",
-			sessionID: sessionID,
+			name: "Privileged update with system metadata",
 			metadata: map[string]interface{}{
 				"A": 1,
 				"B": map[string]interface{}{
@@ -159,11 +160,12 @@ func Test_mergeMetadata(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			sessionID := createTestSession(t)
 			mergedMetadata, err := mergeMetadata(
 				testCtx,
 				testDB,
 				"session_id",
-				tt.sessionID,
+				sessionID,
 				"session",
 				tt.metadata,
 				tt.privileged,
@@ -177,6 +179,12 @@ func Test_mergeMetadata(t *testing.T) {
 
 				// Compare the expected metadata and merged metadata
 				assertEqualMaps(t, tt.expectedMetadata, mergedMetadata)
+
+				// The merged metadata is stored. Numbers are scanned as json.Number.
+				session, err := dao.Get(testCtx, sessionID)
+				assert.NoError(t, err)
+				assert.Equal(t, len(tt.expectedMetadata), len(session.Metadata))
+				assert.Equal(t, fmt.Sprint(tt.expectedMetadata["A"]), fmt.Sprint(session.Metadata["A"]))
 			}
 		})
 	}
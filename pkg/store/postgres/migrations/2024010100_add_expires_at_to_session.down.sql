@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS session_expires_at_idx;
+
+ALTER TABLE session
+    DROP COLUMN IF EXISTS expiry_warned_at;
+ALTER TABLE session
+    DROP COLUMN IF EXISTS expires_at;
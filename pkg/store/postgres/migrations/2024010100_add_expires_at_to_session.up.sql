@@ -0,0 +1,8 @@
+ALTER TABLE session
+    ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+ALTER TABLE session
+    ADD COLUMN IF NOT EXISTS expiry_warned_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS session_expires_at_idx
+    ON session (expires_at)
+    WHERE expires_at IS NOT NULL;
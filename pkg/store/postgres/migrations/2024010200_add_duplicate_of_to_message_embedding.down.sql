@@ -0,0 +1,2 @@
+ALTER TABLE message_embedding
+    DROP COLUMN IF EXISTS duplicate_of;
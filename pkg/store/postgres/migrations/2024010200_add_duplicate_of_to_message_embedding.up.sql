@@ -0,0 +1,2 @@
+ALTER TABLE message_embedding
+    ADD COLUMN IF NOT EXISTS duplicate_of uuid;
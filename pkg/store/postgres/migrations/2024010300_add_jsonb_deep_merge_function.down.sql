@@ -0,0 +1 @@
+DROP FUNCTION IF EXISTS jsonb_deep_merge(jsonb, jsonb);
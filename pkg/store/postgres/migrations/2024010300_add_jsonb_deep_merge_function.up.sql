@@ -0,0 +1,32 @@
+-- jsonb_deep_merge recursively merges patch into current_value. Objects are merged key by
+-- key, and any other value in patch, including null, replaces the current value.
+CREATE OR REPLACE FUNCTION jsonb_deep_merge(current_value jsonb, patch jsonb)
+    RETURNS jsonb
+    LANGUAGE plpgsql
+    IMMUTABLE
+AS
+$$
+BEGIN
+    IF patch IS NULL THEN
+        RETURN current_value;
+    END IF;
+    IF current_value IS NULL
+        OR jsonb_typeof(current_value) <> 'object'
+        OR jsonb_typeof(patch) <> 'object' THEN
+        RETURN patch;
+    END IF;
+    RETURN (SELECT coalesce(
+                           jsonb_object_agg(
+                                   coalesce(c.key, p.key),
+                                   CASE
+                                       WHEN p.key IS NULL THEN c.value
+                                       WHEN c.key IS NULL THEN p.value
+                                       ELSE jsonb_deep_merge(c.value, p.value)
+                                       END
+                           ),
+                           '{}'::jsonb
+                   )
+            FROM jsonb_each(current_value) c
+                     FULL OUTER JOIN jsonb_each(patch) p ON c.key = p.key);
+END;
+$$;
@@ -0,0 +1,2 @@
+ALTER TABLE session
+    ADD COLUMN IF NOT EXISTS task_state jsonb;
@@ -0,0 +1,2 @@
+ALTER TABLE document_collection
+    DROP COLUMN IF EXISTS contextual_chunk_headers;
@@ -0,0 +1,2 @@
+ALTER TABLE document_collection
+    ADD COLUMN IF NOT EXISTS contextual_chunk_headers boolean NOT NULL DEFAULT false;
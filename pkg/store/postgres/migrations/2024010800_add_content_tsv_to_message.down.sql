@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS message_content_tsv_idx;
+
+--bun:split
+ALTER TABLE message
+    DROP COLUMN IF EXISTS content_tsv;
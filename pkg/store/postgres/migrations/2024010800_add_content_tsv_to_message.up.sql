@@ -0,0 +1,6 @@
+ALTER TABLE message
+    ADD COLUMN IF NOT EXISTS content_tsv tsvector
+        GENERATED ALWAYS AS (to_tsvector('english', content)) STORED;
+
+--bun:split
+CREATE INDEX IF NOT EXISTS message_content_tsv_idx ON message USING GIN (content_tsv);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS message_content_trgm_idx;
+
+--bun:split
+DROP INDEX IF EXISTS summary_content_trgm_idx;
@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+--bun:split
+CREATE INDEX IF NOT EXISTS message_content_trgm_idx ON message USING GIN (content gin_trgm_ops);
+
+--bun:split
+CREATE INDEX IF NOT EXISTS summary_content_trgm_idx ON summary USING GIN (content gin_trgm_ops);
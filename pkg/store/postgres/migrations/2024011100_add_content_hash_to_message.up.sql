@@ -0,0 +1,7 @@
+ALTER TABLE message
+    ADD COLUMN IF NOT EXISTS content_hash text;
+
+--bun:split
+UPDATE message
+    SET content_hash = encode(sha256(convert_to(content, 'UTF8')), 'hex')
+    WHERE content_hash IS NULL;
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.ProvenanceStore = &ProvenanceStoreDAO{}
+
+type ProvenanceStoreDAO struct {
+	db *bun.DB
+}
+
+func NewProvenanceStoreDAO(db *bun.DB) *ProvenanceStoreDAO {
+	return &ProvenanceStoreDAO{
+		db: db,
+	}
+}
+
+// Put stores the provenance of a response.
+func (dao *ProvenanceStoreDAO) Put(
+	ctx context.Context,
+	provenance *models.ContextProvenance,
+) error {
+	record := &ContextProvenanceSchema{
+		ResponseID:     provenance.ResponseID,
+		CreatedAt:      provenance.CreatedAt,
+		Kind:           provenance.Kind,
+		SessionID:      provenance.SessionID,
+		CollectionName: provenance.CollectionName,
+		Messages:       nonNilUUIDs(provenance.Messages),
+		Summaries:      nonNilUUIDs(provenance.Summaries),
+		Documents:      nonNilUUIDs(provenance.Documents),
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	_, err := dao.db.NewInsert().Model(record).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to put context provenance: %w", err)
+	}
+	return nil
+}
+
+// Get returns the provenance of a response.
+func (dao *ProvenanceStoreDAO) Get(
+	ctx context.Context,
+	responseID uuid.UUID,
+) (*models.ContextProvenance, error) {
+	record := new(ContextProvenanceSchema)
+	err := dao.db.NewSelect().Model(record).Where("response_id = ?", responseID).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewNotFoundError("provenance of response " + responseID.String())
+		}
+		return nil, fmt.Errorf("failed to get context provenance: %w", err)
+	}
+	return &models.ContextProvenance{
+		ResponseID:     record.ResponseID,
+		CreatedAt:      record.CreatedAt,
+		Kind:           record.Kind,
+		SessionID:      record.SessionID,
+		CollectionName: record.CollectionName,
+		Messages:       record.Messages,
+		Summaries:      record.Summaries,
+		Documents:      record.Documents,
+	}, nil
+}
+
+// DeleteBefore deletes the records created before the given time.
+func (dao *ProvenanceStoreDAO) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	r, err := dao.db.NewDelete().
+		Model((*ContextProvenanceSchema)(nil)).
+		Where("created_at < ?", before).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete context provenance: %w", err)
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete context provenance: %w", err)
+	}
+	return int(n), nil
+}
+
+// nonNilUUIDs returns uuids, or an empty slice if it's nil, so that it's stored as an
+// empty JSON array.
+func nonNilUUIDs(uuids []uuid.UUID) []uuid.UUID {
+	if uuids == nil {
+		return []uuid.UUID{}
+	}
+	return uuids
+}
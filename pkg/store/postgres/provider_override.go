@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/secrets"
+)
+
+var _ models.ProviderOverrideStore = &ProviderOverrideStoreDAO{}
+
+// ProviderOverrideStoreDAO stores provider overrides with their providers encrypted by
+// box, bound to their tenant.
+type ProviderOverrideStoreDAO struct {
+	db  *bun.DB
+	box *secrets.Box
+}
+
+func NewProviderOverrideStoreDAO(db *bun.DB, box *secrets.Box) *ProviderOverrideStoreDAO {
+	return &ProviderOverrideStoreDAO{
+		db:  db,
+		box: box,
+	}
+}
+
+// overrideProviders is the encrypted part of a provider override.
+type overrideProviders struct {
+	LLM        *models.LLMProvider       `json:"llm,omitempty"`
+	Embeddings *models.EmbeddingProvider `json:"embeddings,omitempty"`
+}
+
+// Put creates a tenant's provider override, or replaces its providers.
+func (dao *ProviderOverrideStoreDAO) Put(
+	ctx context.Context,
+	override *models.ProviderOverride,
+) (*models.ProviderOverride, error) {
+	providers, err := json.Marshal(overrideProviders{
+		LLM:        override.LLM,
+		Embeddings: override.Embeddings,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal providers: %w", err)
+	}
+	sealed, err := dao.box.Seal(providers, []byte(override.Tenant))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt providers: %w", err)
+	}
+
+	now := time.Now()
+	record := &ProviderOverrideSchema{
+		Tenant:    override.Tenant,
+		Providers: sealed,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err = dao.db.NewInsert().
+		Model(record).
+		On("CONFLICT (tenant) DO UPDATE").
+		Set("providers = EXCLUDED.providers").
+		Set("updated_at = EXCLUDED.updated_at").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put provider override: %w", err)
+	}
+	return dao.toProviderOverride(record)
+}
+
+func (dao *ProviderOverrideStoreDAO) Get(
+	ctx context.Context,
+	tenant string,
+) (*models.ProviderOverride, error) {
+	record := new(ProviderOverrideSchema)
+	err := dao.db.NewSelect().Model(record).Where("tenant = ?", tenant).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewNotFoundError("provider override of tenant " + tenant)
+		}
+		return nil, fmt.Errorf("failed to get provider override: %w", err)
+	}
+	return dao.toProviderOverride(record)
+}
+
+func (dao *ProviderOverrideStoreDAO) List(
+	ctx context.Context,
+) ([]*models.ProviderOverride, error) {
+	var records []ProviderOverrideSchema
+	if err := dao.db.NewSelect().Model(&records).Order("tenant ASC").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list provider overrides: %w", err)
+	}
+
+	overrides := make([]*models.ProviderOverride, len(records))
+	for i := range records {
+		override, err := dao.toProviderOverride(&records[i])
+		if err != nil {
+			return nil, err
+		}
+		overrides[i] = override
+	}
+	return overrides, nil
+}
+
+func (dao *ProviderOverrideStoreDAO) Delete(ctx context.Context, tenant string) error {
+	r, err := dao.db.NewDelete().
+		Model((*ProviderOverrideSchema)(nil)).
+		Where("tenant = ?", tenant).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete provider override: %w", err)
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete provider override: %w", err)
+	}
+	if n == 0 {
+		return models.NewNotFoundError("provider override of tenant " + tenant)
+	}
+	return nil
+}
+
+// toProviderOverride decrypts the providers of a record.
+func (dao *ProviderOverrideStoreDAO) toProviderOverride(
+	record *ProviderOverrideSchema,
+) (*models.ProviderOverride, error) {
+	opened, err := dao.box.Open(record.Providers, []byte(record.Tenant))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to decrypt provider override of tenant %s: %w",
+			record.Tenant,
+			err,
+		)
+	}
+	var providers overrideProviders
+	if err := json.Unmarshal(opened, &providers); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal providers: %w", err)
+	}
+	return &models.ProviderOverride{
+		Tenant:     record.Tenant,
+		LLM:        providers.LLM,
+		Embeddings: providers.Embeddings,
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/secrets"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+func newTestBox(t *testing.T) *secrets.Box {
+	box, err := secrets.NewBox(bytes.Repeat([]byte{1}, secrets.KeySize))
+	require.NoError(t, err)
+	return box
+}
+
+func TestProviderOverrideEncrypted(t *testing.T) {
+	ctx := context.Background()
+	dao := NewProviderOverrideStoreDAO(testDB, newTestBox(t))
+	tenant := "tenant" + testutils.GenerateRandomString(8)
+	_, err := dao.Put(ctx, &models.ProviderOverride{
+		Tenant: tenant,
+		LLM: &models.LLMProvider{
+			Service: models.ProviderServiceOpenAI,
+			Model:   "gpt-4o-mini",
+			APIKey:  "sk-plaintext",
+		},
+	})
+	require.NoError(t, err)
+
+	// the providers aren't stored in plaintext
+	record := new(ProviderOverrideSchema)
+	require.NoError(t, testDB.NewSelect().Model(record).Where("tenant = ?", tenant).Scan(ctx))
+	assert.NotContains(t, string(record.Providers), "sk-plaintext")
+	assert.NotContains(t, string(record.Providers), "gpt-4o-mini")
+
+	// and can't be read with another key
+	otherBox, err := secrets.NewBox(bytes.Repeat([]byte{2}, secrets.KeySize))
+	require.NoError(t, err)
+	_, err = NewProviderOverrideStoreDAO(testDB, otherBox).Get(ctx, tenant)
+	assert.ErrorIs(t, err, secrets.ErrDecrypt)
+}
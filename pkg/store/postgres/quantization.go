@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// BitCandidateMultiplier is the number of search candidates retrieved using a bit quantized
+// index, as a multiple of the search limit. Candidates are reranked using full precision vectors.
+const BitCandidateMultiplier = 10
+
+var quantizations = []models.Quantization{
+	models.QuantizationNone,
+	models.QuantizationHalfVec,
+	models.QuantizationBit,
+}
+
+func validateQuantization(q models.Quantization) error {
+	for _, v := range quantizations {
+		if q == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid embedding quantization: %s", q)
+}
+
+// validateMemoryQuantization validates the quantization of the message or summary embeddings.
+// Memory search doesn't retrieve bit quantized candidates, so bit quantization is only
+// supported for documents.
+func validateMemoryQuantization(documentType string, q models.Quantization) error {
+	if err := validateQuantization(q); err != nil {
+		return err
+	}
+	if q == models.QuantizationBit {
+		return fmt.Errorf("%s embeddings don't support %s quantization", documentType, q)
+	}
+	return nil
+}
+
+// vectorType returns the column type used to store embeddings with the given quantization.
+// Bit quantized embeddings are stored as full precision vectors. Only the index is quantized.
+func vectorType(q models.Quantization) string {
+	if q == models.QuantizationHalfVec {
+		return "halfvec"
+	}
+	return "vector"
+}
+
+// vectorIndexExpr returns the indexed expression and operator class for an embedding column,
+// compared by the given distance function. Bit quantized embeddings are indexed by hamming
+// distance whatever the distance function, which then only scores the candidates.
+func vectorIndexExpr(
+	column string,
+	dimensions int,
+	q models.Quantization,
+	df models.DistanceFunction,
+) string {
+	switch q {
+	case models.QuantizationHalfVec:
+		return fmt.Sprintf("%s halfvec_%s_ops", column, distanceOpsPrefix(df))
+	case models.QuantizationBit:
+		return fmt.Sprintf("(binary_quantize(%s)::bit(%d)) bit_hamming_ops", column, dimensions)
+	default:
+		return fmt.Sprintf("%s vector_%s_ops", column, distanceOpsPrefix(df))
+	}
+}
+
+// hnswIndexName returns the name of the HNSW index on a column. Names differ by quantization
+// and distance function so that indexes are recreated when either changes.
+func hnswIndexName(table, column string, q models.Quantization, df models.DistanceFunction) string {
+	if q == models.QuantizationNone {
+		return table + "_" + column + indexNameSuffix(df) + "_hnsw_idx"
+	}
+	return table + "_" + column + "_" + string(q) + indexNameSuffix(df) + "_hnsw_idx"
+}
+
+// bitCandidatesFilter restricts a search query to the rows selected by candidates nearest to
+// the query vector by hamming distance over the bit quantized embeddings. This allows the bit
+// quantized HNSW index to be used, with exact distances then calculated only for the
+// candidates. candidates must apply the same where clauses as the search query, so that
+// filtered and paged searches aren't left with fewer than limit candidates.
+func bitCandidatesFilter(
+	q *bun.SelectQuery,
+	candidates *bun.SelectQuery,
+	keyColumn string,
+	dimensions int,
+	vector interface{},
+	limit interface{},
+) *bun.SelectQuery {
+	candidates = candidates.
+		ColumnExpr("?", bun.Ident(keyColumn)).
+		OrderExpr("binary_quantize(embedding)::bit(?) <~> binary_quantize(?::vector)", dimensions, vector)
+	return q.Where("? IN (? LIMIT ?)", bun.Ident(keyColumn), candidates, limit)
+}
+
+// getEmbeddingColumnType returns the type of the embedding column in the provided table,
+// either vector or halfvec.
+func getEmbeddingColumnType(ctx context.Context, db bun.IDB, tableName string) (string, error) {
+	var columnType string
+	err := db.NewSelect().
+		Table("pg_attribute").
+		ColumnExpr("format_type(atttypid, NULL)").
+		Where("attrelid = ?::regclass", tableName).
+		Where("attname = 'embedding'").
+		Scan(ctx, &columnType)
+	if err != nil {
+		return "", fmt.Errorf("error getting embedding column type for %s: %w", tableName, err)
+	}
+	return columnType, nil
+}
+
+// migrateEmbeddingQuantization converts the embedding column in the provided table to the
+// given quantization. Existing vectors are cast to the new type and are not lost. HNSW indexes
+// for other quantizations are dropped, as are the table's IVFFLAT indexes if the column type
+// changes. Returns true if the column type was changed.
+func migrateEmbeddingQuantization(
+	ctx context.Context,
+	db *bun.DB,
+	tableName string,
+	dimensions int,
+	q models.Quantization,
+) (bool, error) {
+	if err := validateQuantization(q); err != nil {
+		return false, err
+	}
+	// we may be missing a config key, so use the default dimensions if none are provided
+	if dimensions == 0 {
+		dimensions = defaultEmbeddingDims
+	}
+
+	for _, other := range quantizations {
+		if other == q {
+			continue
+		}
+		for _, df := range distanceFunctions {
+			_, err := db.ExecContext(
+				ctx,
+				"DROP INDEX IF EXISTS ?",
+				bun.Ident(hnswIndexName(tableName, EmbeddingColName, other, df)),
+			)
+			if err != nil {
+				return false, fmt.Errorf("error dropping hnsw index: %w", err)
+			}
+		}
+	}
+
+	columnType, err := getEmbeddingColumnType(ctx, db, tableName)
+	if err != nil {
+		return false, err
+	}
+	if columnType == vectorType(q) {
+		return false, nil
+	}
+
+	log.Warnf(
+		"migrating %s embedding column from %s to %s. this may take some time for large tables",
+		tableName,
+		columnType,
+		vectorType(q),
+	)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	for _, df := range distanceFunctions {
+		_, err = tx.ExecContext(
+			ctx,
+			"DROP INDEX IF EXISTS ?",
+			bun.Ident(ivfflatIndexName(tableName, EmbeddingColName, df)),
+		)
+		if err != nil {
+			return false, fmt.Errorf("error dropping ivfflat index: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"ALTER TABLE ? ALTER COLUMN embedding TYPE ?(?) USING embedding::?(?)",
+		bun.Ident(tableName),
+		bun.Safe(vectorType(q)),
+		dimensions,
+		bun.Safe(vectorType(q)),
+		dimensions,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error altering embedding column type: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// checkEmbeddingQuantization migrates the embedding column of a message or summary embedding
+// table to the quantization configured for its embedding model.
+func checkEmbeddingQuantization(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	documentType string,
+	tableName string,
+) error {
+	model, err := llms.GetEmbeddingModel(appState, documentType)
+	if err != nil {
+		return fmt.Errorf("error getting %s embedding model: %w", documentType, err)
+	}
+
+	if err := validateMemoryQuantization(documentType, model.Quantization); err != nil {
+		return err
+	}
+
+	_, err = migrateEmbeddingQuantization(ctx, db, tableName, model.Dimensions, model.Quantization)
+	if err != nil {
+		return fmt.Errorf("error migrating %s embedding quantization: %w", documentType, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestVectorIndexExpr(t *testing.T) {
+	tests := []struct {
+		name         string
+		quantization models.Quantization
+		wantType     string
+		wantExpr     string
+		wantIdx      string
+	}{
+		{
+			name:         "none",
+			quantization: models.QuantizationNone,
+			wantType:     "vector",
+			wantExpr:     "embedding vector_cosine_ops",
+			wantIdx:      "message_embedding_embedding_hnsw_idx",
+		},
+		{
+			name:         "halfvec",
+			quantization: models.QuantizationHalfVec,
+			wantType:     "halfvec",
+			wantExpr:     "embedding halfvec_cosine_ops",
+			wantIdx:      "message_embedding_embedding_halfvec_hnsw_idx",
+		},
+		{
+			name:         "bit",
+			quantization: models.QuantizationBit,
+			wantType:     "vector",
+			wantExpr:     "(binary_quantize(embedding)::bit(384)) bit_hamming_ops",
+			wantIdx:      "message_embedding_embedding_bit_hnsw_idx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, validateQuantization(tt.quantization))
+			assert.Equal(t, tt.wantType, vectorType(tt.quantization))
+			assert.Equal(t, tt.wantExpr, vectorIndexExpr(
+				"embedding",
+				384,
+				tt.quantization,
+				models.DistanceFunctionCosine,
+			))
+			assert.Equal(
+				t,
+				tt.wantIdx,
+				hnswIndexName(
+					"message_embedding",
+					"embedding",
+					tt.quantization,
+					models.DistanceFunctionCosine,
+				),
+			)
+		})
+	}
+
+	assert.Error(t, validateQuantization("int8"))
+}
+
+func TestValidateMemoryQuantization(t *testing.T) {
+	assert.NoError(t, validateMemoryQuantization("message", models.QuantizationNone))
+	assert.NoError(t, validateMemoryQuantization("summary", models.QuantizationHalfVec))
+	assert.Error(t, validateMemoryQuantization("message", models.QuantizationBit))
+	assert.Error(t, validateMemoryQuantization("summary", "int8"))
+}
+
+func TestBitCandidatesFilter(t *testing.T) {
+	cfg := *appState.Config
+	cfg.Extractors.Documents.Embeddings.Quantization = string(models.QuantizationBit)
+	state := *appState
+	state.Config = &cfg
+
+	dso := newDocumentSearchOperation(
+		testCtx,
+		&state,
+		testDB,
+		&models.DocumentSearchPayload{
+			Embedding: []float32{0.1, 0.2, 0.3},
+			Metadata: map[string]interface{}{
+				"where": map[string]interface{}{"jsonpath": "$[*] ? (@.topic == \"bit\")"},
+			},
+			Cursor: models.SearchCursor{Score: 0.5, UUID: uuid.New()}.Encode(),
+		},
+		&models.DocumentCollection{TableName: "docstore_bit", EmbeddingDimensions: 3},
+		5,
+	)
+	query, _, err := dso.buildQuery(testDB, &queryParams{})
+	require.NoError(t, err)
+
+	// the candidates are selected with the search's where clauses, so that filtered and
+	// paged searches retrieve as many candidates as unfiltered ones
+	sql := query.String()
+	i := strings.Index(sql, "IN (SELECT")
+	require.NotEqual(t, -1, i, sql)
+	candidates := sql[i:]
+	assert.Contains(t, candidates, "deleted_at IS NULL")
+	assert.Contains(t, candidates, "jsonb_path_exists(metadata")
+	assert.Contains(t, candidates, ", uuid) < (")
+	assert.Contains(t, candidates, "<~> binary_quantize(")
+	assert.Contains(t, candidates, "LIMIT 50)")
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// defaultTopQueries is the number of top queries returned if the filter has no limit.
+const defaultTopQueries = 10
+
+var _ models.QueryLogStore = &QueryLogStoreDAO{}
+
+type QueryLogStoreDAO struct {
+	db *bun.DB
+}
+
+func NewQueryLogStoreDAO(db *bun.DB) *QueryLogStoreDAO {
+	return &QueryLogStoreDAO{
+		db: db,
+	}
+}
+
+// Put logs a search.
+func (dao *QueryLogStoreDAO) Put(ctx context.Context, search *models.LoggedSearch) error {
+	record := &SearchQuerySchema{
+		ID:             search.ID,
+		CreatedAt:      search.CreatedAt,
+		Kind:           search.Kind,
+		SessionID:      search.SessionID,
+		CollectionName: search.CollectionName,
+		Text:           search.Text,
+		SearchType:     string(search.SearchType),
+		LatencyMS:      search.LatencyMS,
+		ResultCount:    search.ResultCount,
+		ZeroResults:    search.ZeroResults,
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	_, err := dao.db.NewInsert().Model(record).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to log search: %w", err)
+	}
+	return nil
+}
+
+// Analytics aggregates the logged searches selected by filter.
+func (dao *QueryLogStoreDAO) Analytics(
+	ctx context.Context,
+	filter *models.SearchAnalyticsFilter,
+) (*models.SearchAnalytics, error) {
+	var totals struct {
+		Searches     int
+		ZeroResults  int
+		AvgLatencyMS float64
+	}
+	err := dao.filteredQuery(filter).
+		ColumnExpr("count(*) AS searches").
+		ColumnExpr("count(*) FILTER (WHERE sq.zero_results) AS zero_results").
+		ColumnExpr("COALESCE(avg(sq.latency_ms), 0) AS avg_latency_ms").
+		Scan(ctx, &totals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate searches: %w", err)
+	}
+
+	analytics := &models.SearchAnalytics{
+		Searches:     totals.Searches,
+		ZeroResults:  totals.ZeroResults,
+		AvgLatencyMS: totals.AvgLatencyMS,
+	}
+	if totals.Searches > 0 {
+		analytics.ZeroResultRate = float64(totals.ZeroResults) / float64(totals.Searches)
+	}
+
+	analytics.TopQueries, err = dao.topQueries(ctx, filter, false)
+	if err != nil {
+		return nil, err
+	}
+	analytics.TopZeroResultQueries, err = dao.topQueries(ctx, filter, true)
+	if err != nil {
+		return nil, err
+	}
+	return analytics, nil
+}
+
+// topQueries returns the most frequent query texts selected by filter, lowercased, and
+// only those of searches returning no results if zeroResults is set.
+func (dao *QueryLogStoreDAO) topQueries(
+	ctx context.Context,
+	filter *models.SearchAnalyticsFilter,
+	zeroResults bool,
+) ([]models.QueryCount, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultTopQueries
+	}
+	q := dao.filteredQuery(filter).
+		ColumnExpr("lower(btrim(sq.text)) AS text").
+		ColumnExpr("count(*) AS searches").
+		ColumnExpr("count(*) FILTER (WHERE sq.zero_results) AS zero_results").
+		ColumnExpr("avg(sq.result_count) AS avg_results").
+		Where("btrim(sq.text) <> ''").
+		GroupExpr("lower(btrim(sq.text))").
+		OrderExpr("searches DESC, text").
+		Limit(limit)
+	if zeroResults {
+		q = q.Where("sq.zero_results")
+	}
+
+	queries := []models.QueryCount{}
+	if err := q.Scan(ctx, &queries); err != nil {
+		return nil, fmt.Errorf("failed to get top queries: %w", err)
+	}
+	return queries, nil
+}
+
+func (dao *QueryLogStoreDAO) filteredQuery(filter *models.SearchAnalyticsFilter) *bun.SelectQuery {
+	q := dao.db.NewSelect().Model((*SearchQuerySchema)(nil))
+	if filter.Kind != "" {
+		q = q.Where("sq.kind = ?", filter.Kind)
+	}
+	if filter.CollectionName != "" {
+		q = q.Where("sq.collection_name = ?", filter.CollectionName)
+	}
+	if !filter.Since.IsZero() {
+		q = q.Where("sq.created_at >= ?", filter.Since)
+	}
+	return q
+}
+
+// DeleteBefore deletes the searches logged before the given time.
+func (dao *QueryLogStoreDAO) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	r, err := dao.db.NewDelete().
+		Model((*SearchQuerySchema)(nil)).
+		Where("created_at < ?", before).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete logged searches: %w", err)
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete logged searches: %w", err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,231 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/getzep/zep/config"
+)
+
+const (
+	DefaultRetryMaxAttempts     = 3
+	DefaultRetryInitialInterval = 100 * time.Millisecond
+	DefaultRetryMaxInterval     = 2 * time.Second
+	DefaultRetryJitter          = 0.5
+)
+
+// retryPolicy controls how DAO writes are retried after transient Postgres errors.
+// The delay before attempt n+1 is InitialInterval * 2^(n-1), capped at MaxInterval, of which
+// a random fraction of up to Jitter is subtracted.
+type retryPolicy struct {
+	MaxAttempts     int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Jitter          float64
+}
+
+var defaultRetryPolicy = retryPolicy{
+	MaxAttempts:     DefaultRetryMaxAttempts,
+	InitialInterval: DefaultRetryInitialInterval,
+	MaxInterval:     DefaultRetryMaxInterval,
+	Jitter:          DefaultRetryJitter,
+}
+
+var writeRetryPolicy atomic.Pointer[retryPolicy]
+
+// newRetryPolicy creates a retryPolicy from config, using defaults for zero values.
+func newRetryPolicy(cfg config.RetryConfig) retryPolicy {
+	policy := defaultRetryPolicy
+	if cfg.MaxAttempts > 0 {
+		policy.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.InitialInterval > 0 {
+		policy.InitialInterval = time.Duration(cfg.InitialInterval) * time.Millisecond
+	}
+	if cfg.MaxInterval > 0 {
+		policy.MaxInterval = time.Duration(cfg.MaxInterval) * time.Millisecond
+	}
+	if cfg.Jitter > 0 && cfg.Jitter <= 1 {
+		policy.Jitter = cfg.Jitter
+	}
+	return policy
+}
+
+// setRetryPolicy sets the policy used by withRetry.
+func setRetryPolicy(cfg config.RetryConfig) {
+	policy := newRetryPolicy(cfg)
+	writeRetryPolicy.Store(&policy)
+}
+
+func currentRetryPolicy() retryPolicy {
+	if policy := writeRetryPolicy.Load(); policy != nil {
+		return *policy
+	}
+	return defaultRetryPolicy
+}
+
+// backoff returns the delay before the attempt following the given attempt, starting at 1.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialInterval
+	for i := 1; i < attempt && delay < p.MaxInterval; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxInterval {
+		delay = p.MaxInterval
+	}
+	if p.Jitter > 0 {
+		delay -= time.Duration(p.Jitter * rand.Float64() * float64(delay)) //nolint:gosec
+	}
+	return delay
+}
+
+// withRetry calls fn, retrying it with backoff while it fails with a retryable error.
+// fn must be safe to retry: either a single statement or a transaction that is rolled back
+// on error, whose effect is the same if it's repeated after committing.
+func withRetry[T any](
+	ctx context.Context,
+	operation string,
+	fn func(ctx context.Context) (T, error),
+) (T, error) {
+	return retry(ctx, operation, isRetryableError, fn)
+}
+
+// withConflictRetry is withRetry for writes that aren't idempotent, such as inserts of
+// rows whose UUIDs are generated by the database. After a connection error the write may
+// have committed, so they're only retried after serialization failures and deadlocks,
+// which are reported before the transaction commits.
+func withConflictRetry[T any](
+	ctx context.Context,
+	operation string,
+	fn func(ctx context.Context) (T, error),
+) (T, error) {
+	return retry(ctx, operation, isConflictError, fn)
+}
+
+func retry[T any](
+	ctx context.Context,
+	operation string,
+	retryable func(err error) bool,
+	fn func(ctx context.Context) (T, error),
+) (T, error) {
+	policy := currentRetryPolicy()
+
+	var result T
+	var err error
+	for attempt := 1; ; attempt++ {
+		result, err = fn(ctx)
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) {
+			return result, err
+		}
+
+		delay := policy.backoff(attempt)
+		log.Warnf(
+			"%s failed with a transient error, retrying in %s (attempt %d of %d): %s",
+			operation, delay, attempt, policy.MaxAttempts, err,
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+// withRetryErr is withRetry for operations that only return an error.
+func withRetryErr(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	_, err := withRetry(ctx, operation, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// withConflictRetryErr is withConflictRetry for operations that only return an error.
+func withConflictRetryErr(
+	ctx context.Context,
+	operation string,
+	fn func(ctx context.Context) error,
+) error {
+	_, err := withConflictRetry(ctx, operation, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// conflictSQLStates are the SQLSTATE codes of transactions rolled back because of
+// concurrent transactions.
+var conflictSQLStates = []string{
+	"40001", // serialization_failure
+	"40P01", // deadlock_detected
+}
+
+// retryableSQLStates are SQLSTATE codes and classes for errors caused by concurrent
+// transactions or by the server being unavailable, such as during a failover.
+var retryableSQLStates = []string{
+	"40001", // serialization_failure
+	"40P01", // deadlock_detected
+	"08",    // connection_exception class
+	"53300", // too_many_connections
+	"57P01", // admin_shutdown
+	"57P02", // crash_shutdown
+	"57P03", // cannot_connect_now
+}
+
+// isRetryableError returns true if err is a transient Postgres or network error. Errors such
+// as constraint violations or syntax errors are fatal and never retried.
+func isRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if code, ok := sqlState(err); ok {
+		return hasSQLState(code, retryableSQLStates)
+	}
+
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// isConflictError returns true if err is a serialization failure or deadlock.
+func isConflictError(err error) bool {
+	code, ok := sqlState(err)
+	return ok && hasSQLState(code, conflictSQLStates)
+}
+
+// sqlState returns the SQLSTATE code of a pgdriver.Error.
+func sqlState(err error) (string, bool) {
+	var pgErr interface{ Field(k byte) string }
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+	return pgErr.Field('C'), true
+}
+
+func hasSQLState(code string, states []string) bool {
+	for _, state := range states {
+		if strings.HasPrefix(code, state) {
+			return true
+		}
+	}
+	return false
+}
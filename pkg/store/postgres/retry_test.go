@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// sqlStateError mimics pgdriver.Error, which can't be constructed outside of the driver.
+type sqlStateError string
+
+func (e sqlStateError) Field(k byte) string {
+	if k == 'C' {
+		return string(e)
+	}
+	return ""
+}
+
+func (e sqlStateError) Error() string {
+	return fmt.Sprintf("SQLSTATE=%s", string(e))
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", sqlStateError("40001"), true},
+		{"deadlock", sqlStateError("40P01"), true},
+		{"connection failure", sqlStateError("08006"), true},
+		{"admin shutdown", sqlStateError("57P01"), true},
+		{"cannot connect now", sqlStateError("57P03"), true},
+		{"wrapped", fmt.Errorf("failed to create message: %w", sqlStateError("40001")), true},
+		{"unique violation", sqlStateError("23505"), false},
+		{"syntax error", sqlStateError("42601"), false},
+		{"statement timeout", sqlStateError("57014"), false},
+		{"connection reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{"unexpected eof", fmt.Errorf("failed: %w", io.ErrUnexpectedEOF), true},
+		{"no rows", sql.ErrNoRows, false},
+		{"not found", models.NewNotFoundError("session"), false},
+		{"context canceled", context.Canceled, false},
+		{"other", errors.New("failed"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isRetryableError(tt.err))
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := newRetryPolicy(config.RetryConfig{
+		InitialInterval: 10,
+		MaxInterval:     50,
+		Jitter:          0.5,
+	})
+	assert.Equal(t, DefaultRetryMaxAttempts, policy.MaxAttempts)
+
+	for attempt, want := range []time.Duration{10, 20, 40, 50, 50} {
+		want *= time.Millisecond
+		delay := policy.backoff(attempt + 1)
+		assert.LessOrEqual(t, delay, want)
+		assert.GreaterOrEqual(t, delay, want/2)
+	}
+
+	policy.Jitter = 0
+	assert.Equal(t, 50*time.Millisecond, policy.backoff(100))
+}
+
+func TestWithRetry(t *testing.T) {
+	setRetryPolicy(config.RetryConfig{MaxAttempts: 3, InitialInterval: 1, MaxInterval: 1})
+	defer setRetryPolicy(config.RetryConfig{})
+
+	t.Run("retries transient errors", func(t *testing.T) {
+		attempts := 0
+		result, err := withRetry(testCtx, "test", func(ctx context.Context) (int, error) {
+			attempts++
+			if attempts < 3 {
+				return 0, sqlStateError("40001")
+			}
+			return attempts, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, result)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		attempts := 0
+		err := withRetryErr(testCtx, "test", func(ctx context.Context) error {
+			attempts++
+			return sqlStateError("40P01")
+		})
+		assert.ErrorIs(t, err, sqlStateError("40P01"))
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("does not retry fatal errors", func(t *testing.T) {
+		attempts := 0
+		err := withRetryErr(testCtx, "test", func(ctx context.Context) error {
+			attempts++
+			return sqlStateError("23505")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("retries inserts only after conflicts", func(t *testing.T) {
+		for _, tt := range []struct {
+			err      error
+			attempts int
+		}{
+			{sqlStateError("40001"), 3},
+			{sqlStateError("40P01"), 3},
+			{sqlStateError("08006"), 1},
+			{sqlStateError("57P01"), 1},
+			{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, 1},
+			{io.EOF, 1},
+		} {
+			attempts := 0
+			err := withConflictRetryErr(testCtx, "test", func(ctx context.Context) error {
+				attempts++
+				return tt.err
+			})
+			assert.Error(t, err)
+			assert.Equal(t, tt.attempts, attempts, tt.err.Error())
+		}
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		setRetryPolicy(config.RetryConfig{MaxAttempts: 3, InitialInterval: 1000})
+		ctx, cancel := context.WithCancel(testCtx)
+		attempts := 0
+		err := withRetryErr(ctx, "test", func(ctx context.Context) error {
+			attempts++
+			cancel()
+			return sqlStateError("40001")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
@@ -44,6 +44,12 @@ type SessionSchema struct {
 	// UserUUID must be pointer type in order to be nullable
 	UserID *string     `bun:","                                                           yaml:"user_id,omitempty"`
 	User   *UserSchema `bun:"rel:belongs-to,join:user_id=user_id,on_delete:cascade"       yaml:"-"`
+	// ExpiresAt and ExpiryWarnedAt must be pointer types in order to be nullable
+	ExpiresAt      *time.Time        `bun:"type:timestamptz"                                            yaml:"expires_at,omitempty"`
+	ExpiryWarnedAt *time.Time        `bun:"type:timestamptz"                                            yaml:"expiry_warned_at,omitempty"`
+	Title          string            `bun:",notnull,default:''"                                         yaml:"title,omitempty"`
+	TaskState      *models.TaskState `bun:"type:jsonb"                                                  yaml:"task_state,omitempty"`
+	Episodes       []models.Episode  `bun:"type:jsonb"                                                  yaml:"episodes,omitempty"`
 }
 
 var _ bun.BeforeAppendModelHook = (*SessionSchema)(nil)
@@ -69,7 +75,12 @@ type MessageStoreSchema struct {
 	Content    string                 `bun:",notnull"                                                    yaml:"content,omitempty"`
 	TokenCount int                    `bun:",notnull"                                                    yaml:"token_count,omitempty"`
 	Metadata   map[string]interface{} `bun:"type:jsonb,nullzero,json_use_number"                         yaml:"metadata,omitempty"`
-	Session    *SessionSchema         `bun:"rel:belongs-to,join:session_id=session_id,on_delete:cascade" yaml:"-"`
+	// ContentHash is the models.ContentHash of Content, set whenever Content is written.
+	ContentHash string `bun:",nullzero"                                                          yaml:"content_hash,omitempty"`
+	// ContentTSV is the full-text search vector of Content, generated by Postgres and used by
+	// hybrid searches. It's added by a migration, and is only read back by RETURNING *.
+	ContentTSV string         `bun:"content_tsv,scanonly"                                        yaml:"-"`
+	Session    *SessionSchema `bun:"rel:belongs-to,join:session_id=session_id,on_delete:cascade" yaml:"-"`
 }
 
 var _ bun.BeforeAppendModelHook = (*MessageStoreSchema)(nil)
@@ -93,6 +104,8 @@ type MessageVectorStoreSchema struct {
 	MessageUUID uuid.UUID           `bun:"type:uuid,notnull,unique"`
 	Embedding   pgvector.Vector     `bun:"type:vector(1536)"`
 	IsEmbedded  bool                `bun:"type:bool,notnull,default:false"`
+	DuplicateOf *uuid.UUID          `bun:"type:uuid"`  // near-duplicate messages are excluded from search
+	WindowUUIDs []uuid.UUID         `bun:"type:jsonb"` // messages embedded together, with a sliding window
 	Session     *SessionSchema      `bun:"rel:belongs-to,join:session_id=session_id,on_delete:cascade"`
 	Message     *MessageStoreSchema `bun:"rel:belongs-to,join:message_uuid=uuid,on_delete:cascade"`
 }
@@ -106,6 +119,36 @@ func (s *MessageVectorStoreSchema) BeforeAppendModel(_ context.Context, query bu
 	return nil
 }
 
+// SessionEmbeddingSchema stores the sum of a session's message embeddings, which has the
+// same cosine similarity to a query as their centroid. It's recomputed from the session's
+// undeleted message embeddings as messages are embedded, deleted or pruned, and deleted
+// once the session has none.
+type SessionEmbeddingSchema struct {
+	bun.BaseModel `bun:"table:session_embedding,alias:sem" yaml:"-"`
+
+	UUID         uuid.UUID       `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	CreatedAt    time.Time       `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	UpdatedAt    time.Time       `bun:"type:timestamptz,nullzero,default:current_timestamp"`
+	DeletedAt    time.Time       `bun:"type:timestamptz,soft_delete,nullzero"`
+	SessionID    string          `bun:",notnull,unique"`
+	Embedding    pgvector.Vector `bun:"type:vector(1536)"`
+	MessageCount int             `bun:",notnull"`
+	Session      *SessionSchema  `bun:"rel:belongs-to,join:session_id=session_id,on_delete:cascade"`
+}
+
+// UserMemoryIndexSchema indexes the message embeddings of users' sessions by user, so that
+// a user's messages can be searched without joining their sessions. It's kept in sync by
+// triggers on message_embedding and session. See createUserMemoryIndexTriggers.
+type UserMemoryIndexSchema struct {
+	bun.BaseModel `bun:"table:user_memory_index,alias:umi" yaml:"-"`
+
+	MessageUUID uuid.UUID       `bun:",pk,type:uuid"`
+	UserID      string          `bun:",notnull"`
+	SessionID   string          `bun:",notnull"`
+	Embedding   pgvector.Vector `bun:"type:vector(1536)"`
+	WindowUUIDs []uuid.UUID     `bun:"type:jsonb"`
+}
+
 type SummaryStoreSchema struct {
 	bun.BaseModel `bun:"table:summary,alias:su" ,yaml:"-"`
 
@@ -155,6 +198,113 @@ func (s *SummaryVectorStoreSchema) BeforeAppendModel(_ context.Context, query bu
 	return nil
 }
 
+// MemorySnapshotSchema stores the memory returned for a turn of a session.
+type MemorySnapshotSchema struct {
+	bun.BaseModel `bun:"table:memory_snapshot,alias:ms" yaml:"-"`
+
+	UUID      uuid.UUID      `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	CreatedAt time.Time      `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	DeletedAt time.Time      `bun:"type:timestamptz,soft_delete,nullzero"`
+	SessionID string         `bun:",notnull"`
+	TurnUUID  uuid.UUID      `bun:"type:uuid,notnull"`
+	Memory    *models.Memory `bun:"type:jsonb,notnull"`
+	Session   *SessionSchema `bun:"rel:belongs-to,join:session_id=session_id,on_delete:cascade"`
+}
+
+// ContextProvenanceSchema stores the context returned in a response. It has no foreign
+// keys, as records outlive their session.
+type ContextProvenanceSchema struct {
+	bun.BaseModel `bun:"table:context_provenance,alias:cp" yaml:"-"`
+
+	ResponseID     uuid.UUID   `bun:",pk,type:uuid"`
+	CreatedAt      time.Time   `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	Kind           string      `bun:",notnull"`
+	SessionID      string      `bun:",nullzero"`
+	CollectionName string      `bun:",nullzero"`
+	Messages       []uuid.UUID `bun:"type:jsonb,notnull"`
+	Summaries      []uuid.UUID `bun:"type:jsonb,notnull"`
+	Documents      []uuid.UUID `bun:"type:jsonb,notnull"`
+}
+
+// SearchQuerySchema stores a logged search. Like context provenance, it has no foreign
+// keys.
+type SearchQuerySchema struct {
+	bun.BaseModel `bun:"table:search_query,alias:sq" yaml:"-"`
+
+	ID             uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	CreatedAt      time.Time `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	Kind           string    `bun:",notnull"`
+	SessionID      string    `bun:",nullzero"`
+	CollectionName string    `bun:",nullzero"`
+	Text           string    `bun:",notnull"`
+	SearchType     string    `bun:",nullzero"`
+	LatencyMS      int64     `bun:",notnull"`
+	ResultCount    int       `bun:",notnull"`
+	ZeroResults    bool      `bun:",notnull"`
+}
+
+// EvalSetSchema stores the labeled queries of an eval set.
+type EvalSetSchema struct {
+	bun.BaseModel `bun:"table:eval_set,alias:es" yaml:"-"`
+
+	Name        string            `bun:",pk"`
+	Description string            `bun:",notnull,default:''"`
+	Cases       []models.EvalCase `bun:"type:jsonb,notnull"`
+	CreatedAt   time.Time         `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	UpdatedAt   time.Time         `bun:"type:timestamptz,notnull,default:current_timestamp"`
+}
+
+// ProviderOverrideSchema stores a tenant's provider override. Its providers, with their
+// API keys, are encrypted.
+type ProviderOverrideSchema struct {
+	bun.BaseModel `bun:"table:provider_override,alias:po" yaml:"-"`
+
+	Tenant    string    `bun:",pk"`
+	Providers []byte    `bun:"type:bytea,notnull"`
+	CreatedAt time.Time `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"type:timestamptz,notnull,default:current_timestamp"`
+}
+
+// LegalHoldSchema stores a legal hold on a user or session. It has no foreign key, as its
+// subject may be either.
+type LegalHoldSchema struct {
+	bun.BaseModel `bun:"table:legal_hold,alias:lh" yaml:"-"`
+
+	SubjectType string    `bun:",pk"`
+	SubjectID   string    `bun:",pk"`
+	Reason      string    `bun:",notnull"`
+	PlacedBy    string    `bun:",notnull"`
+	PlacedAt    time.Time `bun:"type:timestamptz,notnull,default:current_timestamp"`
+}
+
+// LegalHoldEventSchema records a legal hold being placed or lifted. Events are kept after
+// their hold is lifted and after their subject is deleted.
+type LegalHoldEventSchema struct {
+	bun.BaseModel `bun:"table:legal_hold_event,alias:lhe" yaml:"-"`
+
+	UUID        uuid.UUID `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	CreatedAt   time.Time `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	SubjectType string    `bun:",notnull"`
+	SubjectID   string    `bun:",notnull"`
+	Action      string    `bun:",notnull"`
+	Reason      string    `bun:",nullzero"`
+	Actor       string    `bun:",notnull"`
+}
+
+// DataMigrationSchema stores the progress of a data migration. Cursor is the position
+// after which the next batch starts, stored with each batch, so that an interrupted
+// migration resumes where it stopped.
+type DataMigrationSchema struct {
+	bun.BaseModel `bun:"table:data_migration,alias:dmg" yaml:"-"`
+
+	Name       string    `bun:",pk"`
+	Cursor     int64     `bun:",notnull"`
+	Completed  int       `bun:",notnull"`
+	CreatedAt  time.Time `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	UpdatedAt  time.Time `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	FinishedAt time.Time `bun:"type:timestamptz,nullzero"`
+}
+
 // DocumentCollectionSchema represents the schema for the DocumentCollectionDAO table.
 type DocumentCollectionSchema struct {
 	bun.BaseModel             `bun:"table:document_collection,alias:dc" yaml:"-"`
@@ -170,6 +320,33 @@ func (s *DocumentCollectionSchema) BeforeAppendModel(_ context.Context, query bu
 	return nil
 }
 
+// AsyncTaskSchema represents the schema for the AsyncTaskStoreDAO table.
+type AsyncTaskSchema struct {
+	bun.BaseModel `bun:"table:async_task,alias:at" yaml:"-"`
+
+	UUID       uuid.UUID              `bun:",pk,type:uuid,default:gen_random_uuid()"`
+	CreatedAt  time.Time              `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	UpdatedAt  time.Time              `bun:"type:timestamptz,notnull,default:current_timestamp"`
+	Type       string                 `bun:",notnull"`
+	Status     string                 `bun:",notnull"`
+	Params     map[string]interface{} `bun:"type:jsonb,nullzero,json_use_number"`
+	Completed  int                    `bun:",notnull"`
+	Total      int                    `bun:",notnull"`
+	Error      string                 `bun:",nullzero"`
+	Result     map[string]interface{} `bun:"type:jsonb,nullzero,json_use_number"`
+	StartedAt  time.Time              `bun:"type:timestamptz,nullzero"`
+	FinishedAt time.Time              `bun:"type:timestamptz,nullzero"`
+}
+
+var _ bun.BeforeAppendModelHook = (*AsyncTaskSchema)(nil)
+
+func (s *AsyncTaskSchema) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	if _, ok := query.(*bun.UpdateQuery); ok {
+		s.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
 // DocumentSchemaTemplate represents the schema template for Document tables.
 // TextData is manually added when createDocumentTable is run in order to set the correct dimensions.
 // This means the embedding is not returned when querying using bun.
@@ -208,7 +385,16 @@ var _ bun.AfterCreateTableHook = (*MessageStoreSchema)(nil)
 var _ bun.AfterCreateTableHook = (*MessageVectorStoreSchema)(nil)
 var _ bun.AfterCreateTableHook = (*SummaryStoreSchema)(nil)
 var _ bun.AfterCreateTableHook = (*SummaryVectorStoreSchema)(nil)
+var _ bun.AfterCreateTableHook = (*MemorySnapshotSchema)(nil)
 var _ bun.AfterCreateTableHook = (*UserSchema)(nil)
+var _ bun.AfterCreateTableHook = (*AsyncTaskSchema)(nil)
+var _ bun.AfterCreateTableHook = (*ContextProvenanceSchema)(nil)
+var _ bun.AfterCreateTableHook = (*SearchQuerySchema)(nil)
+var _ bun.AfterCreateTableHook = (*EvalSetSchema)(nil)
+var _ bun.AfterCreateTableHook = (*ProviderOverrideSchema)(nil)
+var _ bun.AfterCreateTableHook = (*DataMigrationSchema)(nil)
+var _ bun.AfterCreateTableHook = (*LegalHoldSchema)(nil)
+var _ bun.AfterCreateTableHook = (*LegalHoldEventSchema)(nil)
 
 // Create Collection Name index after table creation
 var _ bun.AfterCreateTableHook = (*DocumentCollectionSchema)(nil)
@@ -302,6 +488,41 @@ func (*SummaryVectorStoreSchema) AfterCreateTable(
 	return err
 }
 
+// AfterCreateTable creates no indexes, as the unique constraint on session_id indexes it.
+func (*SessionEmbeddingSchema) AfterCreateTable(context.Context, *bun.CreateTableQuery) error {
+	return nil
+}
+
+// AfterCreateTable creates the index on the user and session that searches filter by.
+func (*UserMemoryIndexSchema) AfterCreateTable(
+	ctx context.Context,
+	query *bun.CreateTableQuery,
+) error {
+	_, err := query.DB().NewCreateIndex().
+		Model((*UserMemoryIndexSchema)(nil)).
+		Index("user_memory_index_user_id_session_id_idx").
+		Column("user_id", "session_id").
+		IfNotExists().
+		Exec(ctx)
+	return err
+}
+
+// AfterCreateTable creates the unique index on the session and turn that snapshots are
+// upserted on.
+func (*MemorySnapshotSchema) AfterCreateTable(
+	ctx context.Context,
+	query *bun.CreateTableQuery,
+) error {
+	_, err := query.DB().NewCreateIndex().
+		Model((*MemorySnapshotSchema)(nil)).
+		Index("memory_snapshot_session_id_turn_uuid_idx").
+		Unique().
+		Column("session_id", "turn_uuid").
+		IfNotExists().
+		Exec(ctx)
+	return err
+}
+
 func (*DocumentCollectionSchema) AfterCreateTable(
 	ctx context.Context,
 	query *bun.CreateTableQuery,
@@ -343,7 +564,81 @@ func (*UserSchema) AfterCreateTable(
 	return nil
 }
 
+func (*AsyncTaskSchema) AfterCreateTable(
+	ctx context.Context,
+	query *bun.CreateTableQuery,
+) error {
+	_, err := query.DB().NewCreateIndex().
+		Model((*AsyncTaskSchema)(nil)).
+		Index("async_task_created_at_idx").
+		Column("created_at").
+		IfNotExists().
+		Exec(ctx)
+	return err
+}
+
+func (*ContextProvenanceSchema) AfterCreateTable(
+	ctx context.Context,
+	query *bun.CreateTableQuery,
+) error {
+	_, err := query.DB().NewCreateIndex().
+		Model((*ContextProvenanceSchema)(nil)).
+		Index("context_provenance_created_at_idx").
+		Column("created_at").
+		IfNotExists().
+		Exec(ctx)
+	return err
+}
+
+func (*SearchQuerySchema) AfterCreateTable(
+	ctx context.Context,
+	query *bun.CreateTableQuery,
+) error {
+	_, err := query.DB().NewCreateIndex().
+		Model((*SearchQuerySchema)(nil)).
+		Index("search_query_created_at_idx").
+		Column("created_at").
+		IfNotExists().
+		Exec(ctx)
+	return err
+}
+
+// AfterCreateTable creates no indexes, as eval sets are only looked up by name.
+func (*EvalSetSchema) AfterCreateTable(context.Context, *bun.CreateTableQuery) error {
+	return nil
+}
+
+// AfterCreateTable creates no indexes, as provider overrides are only looked up by tenant.
+func (*ProviderOverrideSchema) AfterCreateTable(context.Context, *bun.CreateTableQuery) error {
+	return nil
+}
+
+// AfterCreateTable creates no indexes, as data migrations are only looked up by name.
+func (*DataMigrationSchema) AfterCreateTable(context.Context, *bun.CreateTableQuery) error {
+	return nil
+}
+
+// AfterCreateTable creates no indexes, as legal holds are looked up by their primary key.
+func (*LegalHoldSchema) AfterCreateTable(context.Context, *bun.CreateTableQuery) error {
+	return nil
+}
+
+func (*LegalHoldEventSchema) AfterCreateTable(
+	ctx context.Context,
+	query *bun.CreateTableQuery,
+) error {
+	_, err := query.DB().NewCreateIndex().
+		Model((*LegalHoldEventSchema)(nil)).
+		Index("legal_hold_event_subject_idx").
+		Column("subject_type", "subject_id").
+		IfNotExists().
+		Exec(ctx)
+	return err
+}
+
 var messageTableList = []bun.AfterCreateTableHook{
+	&MemorySnapshotSchema{},
+	&SessionEmbeddingSchema{},
 	&MessageVectorStoreSchema{},
 	&SummaryVectorStoreSchema{},
 	&SummaryStoreSchema{},
@@ -359,14 +654,24 @@ func createDocumentTable(
 	db *bun.DB,
 	tableName string,
 	embeddingDimensions int,
+	indexType models.IndexType,
+	distanceFunction models.DistanceFunction,
 ) error {
+	model, err := llms.GetEmbeddingModel(appState, "document")
+	if err != nil {
+		return fmt.Errorf("error getting document embedding model: %w", err)
+	}
+	if err := validateQuantization(model.Quantization); err != nil {
+		return err
+	}
+
 	schema := &DocumentSchemaTemplate{}
-	_, err := db.NewCreateTable().
+	_, err = db.NewCreateTable().
 		Model(schema).
 		// override default table name
 		ModelTableExpr("?", bun.Ident(tableName)).
 		// create the embedding column using the provided dimensions
-		ColumnExpr("embedding vector(?)", embeddingDimensions).
+		ColumnExpr("embedding ?(?)", bun.Safe(vectorType(model.Quantization)), embeddingDimensions).
 		IfNotExists().
 		Exec(ctx)
 	if err != nil {
@@ -385,9 +690,18 @@ func createDocumentTable(
 		return fmt.Errorf("error creating session_session_id_idx: %w", err)
 	}
 
-	// If HNSW indexes are available, create an HNSW index on the embedding column
-	if appState.Config.Store.Postgres.AvailableIndexes.HSNW {
-		err = createHNSWIndex(ctx, db, tableName, "embedding")
+	// If the collection uses an HNSW index and HNSW indexes are available, create it on the
+	// embedding column. IVFFlat indexes are created once the collection has enough rows.
+	if indexType == models.IndexTypeHNSW && appState.Config.Store.Postgres.AvailableIndexes.HSNW {
+		err = createHNSWIndex(
+			ctx,
+			db,
+			tableName,
+			"embedding",
+			embeddingDimensions,
+			model.Quantization,
+			distanceFunction,
+		)
 		if err != nil {
 			return fmt.Errorf("error creating hnsw index: %w", err)
 		}
@@ -421,18 +735,32 @@ func enablePgVectorExtension(_ context.Context, db *bun.DB) error {
 }
 
 // CreateSchema creates the db schema if it does not exist.
+// schemaTableList returns the tables created by CreateSchema. Tables are listed before
+// the tables they reference.
+func schemaTableList() []bun.AfterCreateTableHook {
+	return append( //nolint:gocritic
+		messageTableList,
+		&UserSchema{},
+		&DocumentCollectionSchema{},
+		&AsyncTaskSchema{},
+		&ContextProvenanceSchema{},
+		&SearchQuerySchema{},
+		&EvalSetSchema{},
+		&ProviderOverrideSchema{},
+		&UserMemoryIndexSchema{},
+		&DataMigrationSchema{},
+		&LegalHoldSchema{},
+		&LegalHoldEventSchema{},
+	)
+}
+
 func CreateSchema(
 	ctx context.Context,
 	appState *models.AppState,
 	db *bun.DB,
 ) error {
-	// Create new tableList slice and append DocumentCollectionSchema to it
-	tableList := append( //nolint:gocritic
-		messageTableList,
-		&UserSchema{},
-		&DocumentCollectionSchema{},
-	)
-	// iterate through messageTableList in reverse order to create tables with foreign keys first
+	tableList := schemaTableList()
+	// iterate through tableList in reverse order to create tables with foreign keys first
 	for i := len(tableList) - 1; i >= 0; i-- {
 		schema := tableList[i]
 		_, err := db.NewCreateTable().
@@ -461,40 +789,66 @@ func CreateSchema(
 	if err := checkEmbeddingDims(ctx, appState, db, "summary", "summary_embedding"); err != nil {
 		return fmt.Errorf("error checking summary embedding dimensions: %w", err)
 	}
+	if err := checkEmbeddingDims(ctx, appState, db, "message", "session_embedding"); err != nil {
+		return fmt.Errorf("error checking session embedding dimensions: %w", err)
+	}
+	if err := checkEmbeddingDims(ctx, appState, db, "message", "user_memory_index"); err != nil {
+		return fmt.Errorf("error checking user memory index dimensions: %w", err)
+	}
 
-	// Create HNSW index on message and summary embeddings if available
-	if appState.Config.Store.Postgres.AvailableIndexes.HSNW {
-		c := "embedding"
-		if err := createHNSWIndex(ctx, db, "message_embedding", c); err != nil {
-			return fmt.Errorf("error creating hnsw index: %w", err)
-		}
+	// migrate the message and summary embedding columns to the configured quantization
+	if err := checkEmbeddingQuantization(ctx, appState, db, "message", "message_embedding"); err != nil {
+		return fmt.Errorf("error checking message embedding quantization: %w", err)
+	}
+	if err := checkEmbeddingQuantization(ctx, appState, db, "summary", "summary_embedding"); err != nil {
+		return fmt.Errorf("error checking summary embedding quantization: %w", err)
+	}
 
-		if err := createHNSWIndex(ctx, db, "summary_embedding", c); err != nil {
-			return fmt.Errorf("error creating hnsw index: %w", err)
-		}
+	if err := createUserMemoryIndexTriggers(ctx, db); err != nil {
+		return fmt.Errorf("error creating user memory index triggers: %w", err)
+	}
+
+	if err := setupDualWrite(ctx, appState, db); err != nil {
+		return fmt.Errorf("error setting up dual writes: %w", err)
+	}
+
+	if err := createMemoryIndexes(ctx, appState, db); err != nil {
+		return fmt.Errorf("error creating memory vector indexes: %w", err)
 	}
 
 	return nil
 }
 
 // createHNSWIndex creates an HNSW index on the given table and column if it does not exist.
-// The index is created with the default M and efConstruction values. Only vector_cosine_ops is supported.
-func createHNSWIndex(ctx context.Context, db *bun.DB, table, column string) error {
+// The index is created with the default M and efConstruction values, for the given distance
+// function, or for hamming distance for bit quantized embeddings.
+func createHNSWIndex(
+	ctx context.Context,
+	db *bun.DB,
+	table, column string,
+	dimensions int,
+	quantization models.Quantization,
+	distanceFunction models.DistanceFunction,
+) error {
 	const (
 		m              = 16
 		efConstruction = 64
 	)
 
-	idx := table + "_" + column + "_hnsw_idx"
+	if dimensions == 0 {
+		dimensions = defaultEmbeddingDims
+	}
+
+	idx := hnswIndexName(table, column, quantization, distanceFunction)
 
 	log.Infof("creating hnsw index on %s.%s if it does not exist", table, column)
 
 	_, err := db.ExecContext(
 		ctx,
-		"CREATE INDEX CONCURRENTLY IF NOT EXISTS ? ON ? USING hnsw (? vector_cosine_ops) WITH (M = ?, ef_construction = ?);",
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS ? ON ? USING hnsw (?) WITH (M = ?, ef_construction = ?);",
 		bun.Safe(idx),
 		bun.Ident(table),
-		bun.Ident(column),
+		bun.Safe(vectorIndexExpr(column, dimensions, quantization, distanceFunction)),
 		m,
 		efConstruction,
 	)
@@ -526,6 +880,23 @@ func checkEmbeddingDims(
 		return fmt.Errorf("error getting embedding column width: %w", err)
 	}
 
+	// Matryoshka embeddings can be shrunk in place, retaining existing vectors
+	if model.Matryoshka && model.Dimensions > 0 && width > model.Dimensions {
+		log.Warnf(
+			"%s embedding dimensions are %d, expected %d.\n truncating %s embeddings to %d dimensions",
+			documentType,
+			width,
+			model.Dimensions,
+			documentType,
+			model.Dimensions,
+		)
+		err := truncateEmbeddingDims(ctx, db, tableName, model.Dimensions)
+		if err != nil {
+			return fmt.Errorf("error truncating %s embedding dimensions: %w", documentType, err)
+		}
+		return nil
+	}
+
 	if width != model.Dimensions {
 		log.Warnf(
 			"%s embedding dimensions are %d, expected %d.\n migrating %s embedding column width to %d. this may result in loss of existing embedding vectors",
@@ -661,6 +1032,8 @@ func NewPostgresConn(appState *models.AppState) (*bun.DB, error) {
 	db := bun.NewDB(sqldb, pgdialect.New())
 	db.AddQueryHook(bunotel.NewQueryHook(bunotel.WithDBName("zep")))
 
+	setRetryPolicy(appState.Config.Store.Postgres.Retry)
+
 	// Enable pgvector extension
 	err := enablePgVectorExtension(ctx, db)
 	if err != nil {
@@ -741,3 +1114,52 @@ type IndexStatus struct {
 	TuplesTotal int    `bun:"tuples_total"`
 	TuplesDone  int    `bun:"tuples_done"`
 }
+
+// truncateEmbeddingDims shrinks the embedding column in the provided table to the given number
+// of dimensions, truncating and re-normalizing existing Matryoshka embeddings. Vector indexes
+// on the column are dropped and must be recreated.
+func truncateEmbeddingDims(
+	ctx context.Context,
+	db *bun.DB,
+	tableName string,
+	dimensions int,
+) error {
+	columnType, err := getEmbeddingColumnType(ctx, db, tableName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("truncateEmbeddingDims error starting transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	for _, idx := range vectorIndexNames(tableName, EmbeddingColName) {
+		_, err = tx.ExecContext(ctx, "DROP INDEX IF EXISTS ?", bun.Ident(idx))
+		if err != nil {
+			return fmt.Errorf("truncateEmbeddingDims error dropping index %s: %w", idx, err)
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"ALTER TABLE ? ALTER COLUMN embedding TYPE ?(?) USING l2_normalize(subvector(embedding, 1, ?))::?(?)",
+		bun.Ident(tableName),
+		bun.Safe(columnType),
+		dimensions,
+		dimensions,
+		bun.Safe(columnType),
+		dimensions,
+	)
+	if err != nil {
+		return fmt.Errorf("truncateEmbeddingDims error altering column embedding: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("truncateEmbeddingDims error committing transaction: %w", err)
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/uptrace/bun"
 )
@@ -34,7 +35,15 @@ func TestCreateDocumentTable(t *testing.T) {
 	tableName, err := generateDocumentTableName(&collection)
 	assert.NoError(t, err)
 
-	err = createDocumentTable(testCtx, appState, testDB, tableName, collection.EmbeddingDimensions)
+	err = createDocumentTable(
+		testCtx,
+		appState,
+		testDB,
+		tableName,
+		collection.EmbeddingDimensions,
+		models.IndexTypeHNSW,
+		models.DistanceFunctionCosine,
+	)
 	assert.NoError(t, err)
 }
 
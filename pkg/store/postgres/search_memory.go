@@ -1,26 +1,39 @@
 package postgres
 
 import (
+	"cmp"
 	"context"
+	"database/sql"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/getzep/zep/pkg/llms"
 	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
 	"github.com/getzep/zep/pkg/search"
 	"github.com/getzep/zep/pkg/store"
+	"github.com/google/uuid"
 	"github.com/pgvector/pgvector-go"
 	"github.com/uptrace/bun"
 )
 
 const DefaultMemorySearchLimit = 10
 
+// DefaultUserSearchSessions is the number of sessions a user's memory search searches, if
+// unset.
+const DefaultUserSearchSessions = 5
+
 type JSONQuery struct {
 	JSONPath string       `json:"jsonpath"`
 	And      []*JSONQuery `json:"and,omitempty"`
 	Or       []*JSONQuery `json:"or,omitempty"`
+	// Not excludes the rows matching its query.
+	Not *JSONQuery `json:"not,omitempty"`
 }
 
 func searchMemory(
@@ -36,89 +49,477 @@ func searchMemory(
 	}
 
 	if query.Text == "" && len(query.Metadata) == 0 {
-		return nil, errors.New("empty query")
+		return nil, models.NewValidationError("empty query")
 	}
-
-	var dbQuery *bun.SelectQuery
-	var tablePrefix string
-
-	switch query.SearchScope {
-	case models.SearchScopeMessages, "":
-		dbQuery = buildMessageSearchQuery(ctx, db, query)
-		tablePrefix = "m"
-	case models.SearchScopeSummary:
-		dbQuery = buildSummarySearchQuery(ctx, db, query)
-		tablePrefix = "s"
-	default:
-		return nil, errors.New("invalid search scope")
+	if err := query.ValidateSearchType(); err != nil {
+		return nil, err
 	}
-
-	var err error
-	var queryEmbedding []float32
-	if query.Text != "" {
-		dbQuery, queryEmbedding, err = addMemoryVectorColumn(ctx, appState, dbQuery, query.Text)
-		if err != nil {
-			return nil, store.NewStorageError("error adding vector column", err)
+	if err := query.ValidateRecency(); err != nil {
+		return nil, err
+	}
+	if query.MinScore != 0 && query.Text == "" {
+		return nil, models.NewValidationError("min_score requires text to score results by")
+	}
+	var cursor *models.SearchCursor
+	if query.Cursor != "" {
+		if query.Text == "" ||
+			(query.SearchType != "" && query.SearchType != models.SearchTypeSimilarity) {
+			return nil, models.NewValidationError("only similarity searches with text can be paged")
+		}
+		var err error
+		if cursor, err = models.DecodeSearchCursor(query.Cursor); err != nil {
+			return nil, err
 		}
 	}
-	if len(query.Metadata) > 0 {
+
+	// literal searches match the text itself, so it isn't embedded
+	var queryEmbedding []float32
+	if query.Text != "" && query.SearchType != models.SearchTypeLiteral {
 		var err error
-		dbQuery, err = applyMemoryMetadataFilter(dbQuery, query.Metadata, tablePrefix)
+		queryEmbedding, err = embedMemoryQuery(ctx, appState, query.Text)
 		if err != nil {
-			return nil, store.NewStorageError("error applying metadata filter", err)
+			return nil, store.NewStorageError("error embedding query", err)
 		}
 	}
+	return searchSessionMemory(ctx, appState, db, sessionID, query, queryEmbedding, cursor, limit)
+}
 
-	dbQuery = dbQuery.Where("?.session_id = ?", bun.Safe(tablePrefix), sessionID)
+// searchUserMemory searches the messages of the sessions of a user whose session embedding
+// is most similar to the query's, by the user memory index.
+func searchUserMemory(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	userID string,
+	query *models.MemorySearchPayload,
+	sessions int,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	if query == nil || appState == nil {
+		return nil, store.NewStorageError("nil query or appState received", nil)
+	}
+	if err := query.ValidateUserSearch(); err != nil {
+		return nil, err
+	}
+	if err := query.ValidateRecency(); err != nil {
+		return nil, err
+	}
+	if sessions == 0 {
+		sessions = DefaultUserSearchSessions
+	}
+	if limit == 0 {
+		limit = DefaultMemorySearchLimit
+	}
 
-	// Ensure we don't return deleted records.
-	dbQuery = dbQuery.Where("?.deleted_at IS NULL", bun.Safe(tablePrefix))
+	queryEmbedding, err := embedMemoryQuery(ctx, appState, query.Text)
+	if err != nil {
+		return nil, store.NewStorageError("error embedding query", err)
+	}
 
-	// Add sort and limit.
-	addMessagesSortQuery(query.Text, dbQuery, tablePrefix)
+	var sessionIDs []string
+	sessionsQuery := db.NewSelect().
+		Model((*SessionEmbeddingSchema)(nil)).
+		Column("sem.session_id").
+		Join("JOIN session AS s ON s.session_id = sem.session_id").
+		Where("s.user_id = ?", userID).
+		Where("s.deleted_at IS NULL").
+		Where("sem.embedding IS NOT NULL").
+		OrderExpr("sem.embedding <=> ?", pgvector.NewVector(queryEmbedding)).
+		Limit(sessions)
+	started := time.Now()
+	if err := sessionsQuery.Scan(ctx, &sessionIDs); err != nil {
+		return nil, store.NewStorageError("error finding user sessions", err)
+	}
+	models.SearchDebugFromContext(ctx).AddQuery(sessionsQuery.String(), time.Since(started))
+
+	results, err := searchUserMemoryIndex(
+		ctx,
+		appState,
+		db,
+		userID,
+		sessionIDs,
+		query,
+		queryEmbedding,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []models.MemorySearchResult{}
+	}
+	return results, nil
+}
+
+// searchSessionMemory runs a validated memory search of a session, with the embedding of
+// the query's text, if any.
+func searchSessionMemory(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	sessionID string,
+	query *models.MemorySearchPayload,
+	queryEmbedding []float32,
+	cursor *models.SearchCursor,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	// Queries without metadata or role filters have a fixed shape per scope and search type,
+	// and are run as prepared statements if enabled. Hybrid searches run two queries.
+	params := &queryParams{
+		enabled: appState.Config.Store.Postgres.PreparedStatements && len(query.Metadata) == 0 &&
+			len(query.Roles) == 0 && query.SearchType != models.SearchTypeHybrid,
+	}
+
+	dbQuery, tablePrefix, vectorArg, err := buildMemorySearchQuery(
+		ctx,
+		appState,
+		db,
+		sessionID,
+		query,
+		queryEmbedding,
+		false,
+		params,
+	)
+	if err != nil {
+		return nil, err
+	}
+	documentType := memoryDocumentType(tablePrefix)
+	df, err := memoryDistanceFunction(appState, documentType)
+	if err != nil {
+		return nil, store.NewStorageError("error getting distance function", err)
+	}
+
+	// Add sort and limit. Messages are boosted by their importance and recency, if
+	// weighted. Ties are ordered by UUID, so that results can be paged by keyset.
+	weight := appState.Config.Memory.Importance.SearchWeight
+	switch {
+	case query.SearchType == models.SearchTypeLiteral:
+		// literal matches are equally relevant, so the most recent come first
+		dbQuery.OrderExpr(
+			"?.created_at DESC, ?.uuid DESC",
+			bun.Safe(tablePrefix),
+			bun.Safe(tablePrefix),
+		)
+	case query.Text != "":
+		rankExpr, rankArgs := memoryRankExpr(query, tablePrefix, df, vectorArg, weight, params)
+		dbQuery.ColumnExpr(rankExpr+" AS rank_score", rankArgs...)
+		if cursor != nil {
+			// an output column can't be used in a WHERE clause, so the rank is repeated
+			cursorArgs := make([]interface{}, 0, len(rankArgs)+3)
+			cursorArgs = append(cursorArgs, rankArgs...)
+			cursorArgs = append(
+				cursorArgs,
+				bun.Safe(tablePrefix),
+				params.add(cursor.Score),
+				params.add(cursor.UUID),
+			)
+			dbQuery.Where("("+rankExpr+", ?.uuid) < (?, ?)", cursorArgs...)
+		}
+		dbQuery.OrderExpr("rank_score DESC, ?.uuid DESC", bun.Safe(tablePrefix))
+	default:
+		addMessagesSortQuery(query.Text, dbQuery, tablePrefix)
+	}
 
 	if limit == 0 {
 		limit = DefaultMemorySearchLimit
 	}
 
 	// If we're using MMR, we need to return more results than the limit so we can
-	// rerank them.
+	// rerank them. Hybrid searches fuse more results than the limit from each ranking.
+	queryLimit := limit
+	if query.SearchType == models.SearchTypeHybrid {
+		queryLimit = max(limit*DefaultHybridMultiplier, 10)
+	}
 	if query.SearchType == models.SearchTypeMMR {
 		if query.MMRLambda == 0 {
+			query.MMRLambda = appState.Config.Memory.MMRLambda
+		}
+		if query.MMRLambda <= 0 || query.MMRLambda > 1 {
 			query.MMRLambda = DefaultMMRLambda
 		}
-		tmpLimit := limit * DefaultMMRMultiplier
-		if tmpLimit < 10 {
-			tmpLimit = 10
+		queryLimit = limit * DefaultMMRMultiplier
+		if queryLimit < 10 {
+			queryLimit = 10
 		}
-		dbQuery = dbQuery.Limit(tmpLimit)
-	} else {
-		dbQuery = dbQuery.Limit(limit)
 	}
 
-	results, err := executeMessagesSearchScan(ctx, dbQuery)
+	// Vector searches run in a transaction setting the search settings of the embedding
+	// table's index.
+	var results []models.MemorySearchResult
+	execute := func(ctx context.Context, tx *bun.Tx) error {
+		var err error
+		if params.enabled {
+			results, err = executeMessagesSearchPrepared(ctx, db, tx, dbQuery, params, queryLimit)
+		} else {
+			if tx != nil {
+				dbQuery.Conn(tx)
+			}
+			results, err = executeMessagesSearchScan(ctx, dbQuery.Limit(queryLimit))
+		}
+		return err
+	}
+	if vectorArg == nil {
+		err = execute(ctx, nil)
+	} else {
+		table := memoryEmbeddingTables[documentType]
+		err = db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+			if err := setMemoryIndexSettings(ctx, tx, appState, table, query.Exact); err != nil {
+				return err
+			}
+			return execute(ctx, &tx)
+		})
+	}
 	if err != nil {
 		return nil, store.NewStorageError("memory searchMemory failed", err)
 	}
 
+	// Hybrid searches also rank messages by full text, which finds messages that aren't
+	// embedded yet.
+	var textResults []models.MemorySearchResult
+	if query.SearchType == models.SearchTypeHybrid {
+		textResults, err = searchMessagesText(
+			ctx,
+			appState,
+			db,
+			sessionID,
+			query,
+			queryEmbedding,
+			queryLimit,
+		)
+		if err != nil {
+			return nil, store.NewStorageError("memory full-text search failed", err)
+		}
+	}
+
 	// If we didn't find any results, return early.
-	if len(results) == 0 {
+	if len(results) == 0 && len(textResults) == 0 {
 		return []models.MemorySearchResult{}, nil
 	}
 
 	filteredResults := filterValidMessageSearchResults(results, query.Metadata)
 
+	var fusedScores []float64
+	if query.SearchType == models.SearchTypeHybrid {
+		filteredResults, fusedScores = fuseRankings(limit, filteredResults, textResults)
+	}
+
+	if query.Explain {
+		err = explainMemoryResults(ctx, db, query, tablePrefix, weight, filteredResults)
+		if err != nil {
+			return nil, store.NewStorageError("error explaining results", err)
+		}
+		for i, score := range fusedScores {
+			filteredResults[i].Explanation.Score = score
+		}
+	}
+
 	// If we're using MMR, rerank the results.
+	debug := models.SearchDebugFromContext(ctx)
 	if query.SearchType == models.SearchTypeMMR {
+		started := time.Now()
 		filteredResults, err = rerankMMR(filteredResults, queryEmbedding, query.MMRLambda, limit)
 		if err != nil {
 			return nil, store.NewStorageError("error applying mmr", err)
 		}
+		debug.AddRerank(time.Since(started))
+	}
+	debug.SetDistances(memoryResultDistances(filteredResults, df))
+
+	if err := loadResultWindows(ctx, db, sessionID, filteredResults); err != nil {
+		return nil, store.NewStorageError("error loading result windows", err)
+	}
+
+	if query.Text != "" &&
+		(query.SearchType == "" || query.SearchType == models.SearchTypeSimilarity) {
+		setResultCursors(filteredResults)
 	}
 
 	return filteredResults, nil
 }
 
+// memoryResultDistances returns the distances of the results' embeddings to the query's, by
+// the distance function their dist is the similarity of.
+func memoryResultDistances(
+	results []models.MemorySearchResult,
+	df models.DistanceFunction,
+) []float64 {
+	distances := make([]float64, len(results))
+	for i, r := range results {
+		distances[i] = similarityDistance(df, r.Dist)
+	}
+	return distances
+}
+
+// memoryDocumentType returns the type of the documents embedded in the table of a memory
+// search's results, by the table's prefix in the search query.
+func memoryDocumentType(tablePrefix string) string {
+	if tablePrefix == "s" {
+		return "summary"
+	}
+	return "message"
+}
+
+// memoryRankExpr returns the expression results of a search with text are ranked by, and
+// its arguments: their similarity to the query by the distance function, boosted by the
+// importance and recency of messages, if weighted.
+func memoryRankExpr(
+	query *models.MemorySearchPayload,
+	tablePrefix string,
+	df models.DistanceFunction,
+	vectorArg interface{},
+	weight float64,
+	params *queryParams,
+) (string, []interface{}) {
+	rankExpr, rankArgs := similarityExpr(df), []interface{}{vectorArg}
+	if tablePrefix == "m" && weight > 0 {
+		rankExpr += " + ? * COALESCE((m.metadata->'system'->>?)::float8, 0)"
+		rankArgs = append(rankArgs, weight, models.ImportanceMetadataKey)
+	}
+	if tablePrefix == "m" && query.RecencyWeight > 0 {
+		// the boost halves every half-life, in hours, of the message's age
+		rankExpr += " + ? * power(0.5, " +
+			"GREATEST(extract(epoch FROM now() - m.created_at)::float8, 0) / 3600 / ?)"
+		rankArgs = append(
+			rankArgs,
+			params.add(query.RecencyWeight),
+			params.add(query.RecencyHalfLife()),
+		)
+	}
+	return rankExpr, rankArgs
+}
+
+// setResultCursors sets the cursor of each result, from the score it's ranked by.
+func setResultCursors(results []models.MemorySearchResult) {
+	for i := range results {
+		r := &results[i]
+		cursor := models.SearchCursor{Score: r.RankScore}
+		if r.Message != nil {
+			cursor.UUID = r.Message.UUID
+		} else if r.Summary != nil {
+			cursor.UUID = r.Summary.UUID
+		}
+		r.Cursor = cursor.Encode()
+	}
+}
+
+// loadResultWindows sets the Window of each message result embedded with other messages.
+// Deleted messages are left out of windows.
+func loadResultWindows(
+	ctx context.Context,
+	db *bun.DB,
+	sessionID string,
+	results []models.MemorySearchResult,
+) error {
+	var uuids []uuid.UUID
+	for _, r := range results {
+		uuids = append(uuids, r.WindowUUIDs...)
+	}
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	var messages []MessageStoreSchema
+	err := db.NewSelect().
+		Model(&messages).
+		Where("session_id = ?", sessionID).
+		Where("uuid IN (?)", bun.In(uuids)).
+		Scan(ctx)
+	if err != nil {
+		return err
+	}
+	byUUID := make(map[uuid.UUID]models.Message, len(messages))
+	for _, m := range messagesFromStoreSchema(messages) {
+		byUUID[m.UUID] = m
+	}
+
+	for i := range results {
+		for _, u := range results[i].WindowUUIDs {
+			if m, ok := byUUID[u]; ok {
+				results[i].Window = append(results[i].Window, m)
+			}
+		}
+	}
+	return nil
+}
+
+// searchMessagesText ranks the messages matching the query text by full-text search, with
+// the same filters as the similarity ranking. Messages are ranked whether or not they're
+// embedded.
+func searchMessagesText(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	sessionID string,
+	query *models.MemorySearchPayload,
+	queryEmbedding []float32,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	dbQuery, _, _, err := buildMemorySearchQuery(
+		ctx,
+		appState,
+		db,
+		sessionID,
+		query,
+		queryEmbedding,
+		true,
+		&queryParams{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	// the text search configuration must match the one content_tsv is generated with
+	dbQuery = dbQuery.
+		Where("m.content_tsv @@ websearch_to_tsquery('english', ?)", query.Text).
+		OrderExpr("ts_rank(m.content_tsv, websearch_to_tsquery('english', ?)) DESC", query.Text).
+		Limit(limit)
+	return executeMessagesSearchScan(ctx, dbQuery)
+}
+
+// fuseRankings fuses rankings of message search results by Reciprocal Rank Fusion. Each
+// message scores the sum of 1 / (RRFRankConstant + rank) over the rankings it's in, ranked
+// from 1. The top limit messages are returned with their scores, in order.
+func fuseRankings(
+	limit int,
+	rankings ...[]models.MemorySearchResult,
+) ([]models.MemorySearchResult, []float64) {
+	var fused []models.MemorySearchResult
+	var scores []float64
+	index := make(map[uuid.UUID]int)
+	for _, ranking := range rankings {
+		for rank, result := range ranking {
+			score := 1 / float64(RRFRankConstant+rank+1)
+			i, ok := index[result.Message.UUID]
+			if !ok {
+				i = len(fused)
+				index[result.Message.UUID] = i
+				fused = append(fused, result)
+				scores = append(scores, 0)
+			}
+			scores[i] += score
+		}
+	}
+
+	order := make([]int, len(fused))
+	for i := range order {
+		order[i] = i
+	}
+	// ties keep the order of the earlier rankings
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+	if len(order) > limit {
+		order = order[:limit]
+	}
+
+	results := make([]models.MemorySearchResult, len(order))
+	resultScores := make([]float64, len(order))
+	for i, idx := range order {
+		results[i] = fused[idx]
+		resultScores[i] = scores[idx]
+	}
+	return results, resultScores
+}
+
 // rerankMMR reranks the results using the Maximal Marginal Relevance algorithm
 func rerankMMR(
 	results []models.MemorySearchResult,
@@ -130,7 +531,7 @@ func rerankMMR(
 	for i, result := range results {
 		embeddingList[i] = result.Embedding
 	}
-	rerankedIdxs, err := search.MaximalMarginalRelevance(
+	rerankedIdxs, scores, err := search.MaximalMarginalRelevanceScores(
 		queryEmbedding,
 		embeddingList,
 		lambda,
@@ -142,24 +543,161 @@ func rerankMMR(
 	rerankedResults := make([]models.MemorySearchResult, len(rerankedIdxs))
 	for i, idx := range rerankedIdxs {
 		rerankedResults[i] = results[idx]
+		if explanation := rerankedResults[i].Explanation; explanation != nil {
+			score := float64(scores[i])
+			explanation.MMRScore = &score
+		}
 	}
 	return rerankedResults, nil
 }
 
+// explainMemoryResults sets the explanation of each result, in their ranked order. The
+// metadata filter hits are found by testing each JSONPath expression of the filter
+// against the results.
+func explainMemoryResults(
+	ctx context.Context,
+	db *bun.DB,
+	query *models.MemorySearchPayload,
+	tablePrefix string,
+	weight float64,
+	results []models.MemorySearchResult,
+) error {
+	uuids := make([]uuid.UUID, 0, len(results))
+	now := time.Now()
+	for i := range results {
+		r := &results[i]
+		explanation := &models.SearchExplanation{Rank: i + 1}
+		if query.Text != "" && !math.IsNaN(r.Dist) {
+			explanation.VectorScore = r.Dist
+		}
+		if r.Message != nil {
+			uuids = append(uuids, r.Message.UUID)
+			if importance, ok := models.MessageImportance(r.Message); ok &&
+				query.Text != "" && weight > 0 {
+				explanation.ImportanceBoost = weight * importance
+			}
+			explanation.RecencyBoost = query.RecencyBoost(r.Message.CreatedAt, now)
+		} else if r.Summary != nil {
+			uuids = append(uuids, r.Summary.UUID)
+		}
+		explanation.Score = explanation.VectorScore + explanation.ImportanceBoost +
+			explanation.RecencyBoost
+		r.Explanation = explanation
+	}
+
+	var paths []string
+	if where, ok := query.Metadata["where"]; ok {
+		j, err := json.Marshal(where)
+		if err != nil {
+			return fmt.Errorf("error marshalling metadata: %w", err)
+		}
+		var jq JSONQuery
+		if err := json.Unmarshal(j, &jq); err != nil {
+			return fmt.Errorf("error unmarshalling metadata: %w", err)
+		}
+		paths = jsonQueryPaths(&jq)
+	}
+	table := "message"
+	if tablePrefix == "s" {
+		table = "summary"
+	}
+	hits := make(map[uuid.UUID][]string, len(results))
+	for _, path := range paths {
+		if len(uuids) == 0 {
+			break
+		}
+		var matched []uuid.UUID
+		err := db.NewSelect().
+			Table(table).
+			Column("uuid").
+			Where("uuid IN (?)", bun.In(uuids)).
+			Where("jsonb_path_exists(metadata, ?)", strings.ReplaceAll(path, "'", "\"")).
+			Scan(ctx, &matched)
+		if err != nil {
+			return fmt.Errorf("error matching %q: %w", path, err)
+		}
+		for _, u := range matched {
+			hits[u] = append(hits[u], path)
+		}
+	}
+	for _, filter := range []string{"start_date", "end_date"} {
+		if _, ok := query.Metadata[filter]; ok {
+			for _, u := range uuids {
+				hits[u] = append(hits[u], filter)
+			}
+		}
+	}
+	for i := range results {
+		r := &results[i]
+		if r.Message != nil {
+			r.Explanation.MetadataHits = hits[r.Message.UUID]
+		} else if r.Summary != nil {
+			r.Explanation.MetadataHits = hits[r.Summary.UUID]
+		}
+	}
+	return nil
+}
+
+// jsonQueryPaths returns the JSONPath expressions of a metadata filter, in order. Those
+// of Not branches are omitted, as results can't match them.
+func jsonQueryPaths(jq *JSONQuery) []string {
+	var paths []string
+	if jq.JSONPath != "" {
+		paths = append(paths, jq.JSONPath)
+	}
+	for _, q := range append(slices.Clone(jq.And), jq.Or...) {
+		paths = append(paths, jsonQueryPaths(q)...)
+	}
+	return paths
+}
+
+// buildMessageSearchQuery returns the query of the messages searched. If unembedded is true,
+// messages are searched whether or not they're embedded, with the embeddings of those that are.
 func buildMessageSearchQuery(
 	_ context.Context,
 	db *bun.DB,
 	query *models.MemorySearchPayload,
+	unembedded bool,
 ) *bun.SelectQuery {
+	// literal searches match the content of messages whether or not they're embedded
+	if query.SearchType == models.SearchTypeLiteral {
+		return db.NewSelect().TableExpr("message AS m").
+			ColumnExpr("m.uuid AS message__uuid").
+			ColumnExpr("m.created_at AS message__created_at").
+			ColumnExpr("m.role AS message__role").
+			ColumnExpr("m.content AS message__content").
+			ColumnExpr("m.metadata AS message__metadata").
+			ColumnExpr("m.token_count AS message__token_count")
+	}
+
 	dbQuery := db.NewSelect().TableExpr("message_embedding AS me").
 		Join("JOIN message AS m").
-		JoinOn("me.message_uuid = m.uuid").
+		JoinOn("me.message_uuid = m.uuid")
+	if unembedded {
+		dbQuery = db.NewSelect().TableExpr("message AS m").
+			Join("LEFT JOIN message_embedding AS me").
+			JoinOn("me.message_uuid = m.uuid")
+	}
+	dbQuery = dbQuery.
 		ColumnExpr("m.uuid AS message__uuid").
 		ColumnExpr("m.created_at AS message__created_at").
 		ColumnExpr("m.role AS message__role").
 		ColumnExpr("m.content AS message__content").
 		ColumnExpr("m.metadata AS message__metadata").
-		ColumnExpr("m.token_count AS message__token_count")
+		ColumnExpr("m.token_count AS message__token_count").
+		ColumnExpr("me.window_uuids AS window_uuids").
+		// Exclude near-duplicates of the session's other messages. With the user
+		// deduplication scope, a message's representative may be in another of the user's
+		// sessions, so the message is kept. See deduplicateMessageEmbeddings.
+		Where(
+			"me.duplicate_of IS NULL OR NOT EXISTS (?)",
+			db.NewSelect().
+				TableExpr("message_embedding AS rep").
+				ColumnExpr("1").
+				Where("rep.message_uuid = me.duplicate_of").
+				Where("rep.session_id = me.session_id").
+				Where("rep.deleted_at IS NULL"),
+		)
 
 	if query.SearchType == models.SearchTypeMMR {
 		dbQuery = dbQuery.ColumnExpr("me.embedding AS embedding")
@@ -173,9 +711,15 @@ func buildSummarySearchQuery(
 	db *bun.DB,
 	query *models.MemorySearchPayload,
 ) *bun.SelectQuery {
-	dbQuery := db.NewSelect().TableExpr("summary_embedding AS se").
-		Join("JOIN summary AS s").
-		JoinOn("se.summary_uuid = s.uuid").
+	var dbQuery *bun.SelectQuery
+	if query.SearchType == models.SearchTypeLiteral {
+		dbQuery = db.NewSelect().TableExpr("summary AS s")
+	} else {
+		dbQuery = db.NewSelect().TableExpr("summary_embedding AS se").
+			Join("JOIN summary AS s").
+			JoinOn("se.summary_uuid = s.uuid")
+	}
+	dbQuery = dbQuery.
 		ColumnExpr("s.uuid AS summary__uuid").
 		ColumnExpr("s.created_at AS summary__created_at").
 		ColumnExpr("s.content AS summary__content").
@@ -230,9 +774,35 @@ func executeMessagesSearchScan(
 	dbQuery *bun.SelectQuery,
 ) ([]models.MemorySearchResult, error) {
 	var results []models.MemorySearchResult
+	started := time.Now()
 	if err := dbQuery.Scan(ctx, &results); err != nil {
 		return nil, fmt.Errorf("error scanning: %w", err)
 	}
+	models.SearchDebugFromContext(ctx).AddQuery(dbQuery.String(), time.Since(started))
+	if len(results) == 0 {
+		return []models.MemorySearchResult{}, nil
+	}
+	return results, nil
+}
+
+// executeMessagesSearchPrepared runs the query as a prepared statement, with the limit passed
+// as a parameter so that requests with different limits share a statement.
+func executeMessagesSearchPrepared(
+	ctx context.Context,
+	db *bun.DB,
+	tx *bun.Tx,
+	dbQuery *bun.SelectQuery,
+	params *queryParams,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	query := dbQuery.String() + " LIMIT " + params.placeholder(limit)
+
+	var results []models.MemorySearchResult
+	started := time.Now()
+	if err := queryPrepared(ctx, db, tx, query, params.args, &results); err != nil {
+		return nil, fmt.Errorf("error scanning: %w", err)
+	}
+	models.SearchDebugFromContext(ctx).AddQuery(query, time.Since(started))
 	if len(results) == 0 {
 		return []models.MemorySearchResult{}, nil
 	}
@@ -262,24 +832,119 @@ func addMessageDateFilters(qb *bun.QueryBuilder, m map[string]any, tablePrefix s
 	}
 }
 
-// addMemoryVectorColumn adds a column to the query that calculates the distance between the query text and the message embedding
-func addMemoryVectorColumn(
+// buildMemorySearchQuery returns the unordered query of the memory search results in the
+// scope of query that match its filters. If queryEmbedding is set, each result's
+// similarity to it is selected as dist, and the embedding's argument is returned so that it
+// can be reused elsewhere in the query. Literal searches match the results containing the
+// query's text, whose dist is 1. If unembedded is true, messages are searched whether or not
+// they're embedded, and the dist of those that aren't is 0.
+func buildMemorySearchQuery(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	sessionID string,
+	query *models.MemorySearchPayload,
+	queryEmbedding []float32,
+	unembedded bool,
+	params *queryParams,
+) (*bun.SelectQuery, string, interface{}, error) {
+	var dbQuery *bun.SelectQuery
+	var tablePrefix string
+
+	switch query.SearchScope {
+	case models.SearchScopeMessages, "":
+		dbQuery = buildMessageSearchQuery(ctx, db, query, unembedded)
+		tablePrefix = "m"
+		// messages may have been embedded before their role was excluded from search
+		registry := roles.FromConfig(appState.Config)
+		if unsearchable := registry.Unsearchable(); len(unsearchable) > 0 {
+			dbQuery = dbQuery.Where("lower(m.role) NOT IN (?)", bun.In(unsearchable))
+		}
+		if len(query.Roles) > 0 {
+			dbQuery = dbQuery.Where("lower(m.role) IN (?)", bun.In(registry.Matching(query.Roles)))
+		}
+	case models.SearchScopeSummary:
+		if query.Episode != 0 {
+			return nil, "", nil, models.NewValidationError("episode can only filter message searches")
+		}
+		if len(query.Roles) > 0 {
+			return nil, "", nil, models.NewValidationError("roles can only filter message searches")
+		}
+		dbQuery = buildSummarySearchQuery(ctx, db, query)
+		tablePrefix = "s"
+	default:
+		return nil, "", nil, models.NewValidationError("invalid search scope")
+	}
+
+	var vectorArg interface{}
+	if queryEmbedding != nil {
+		df, err := memoryDistanceFunction(appState, memoryDocumentType(tablePrefix))
+		if err != nil {
+			return nil, "", nil, store.NewStorageError("error getting distance function", err)
+		}
+		vectorArg = params.add(pgvector.NewVector(queryEmbedding))
+		if unembedded {
+			dbQuery = dbQuery.ColumnExpr("COALESCE("+similarityExpr(df)+", 0) AS dist", vectorArg)
+		} else {
+			dbQuery = dbQuery.ColumnExpr(similarityExpr(df)+" AS dist", vectorArg)
+		}
+		// dist can't be referenced in the WHERE clause, so it's repeated
+		if query.MinScore != 0 {
+			dbQuery = dbQuery.Where(
+				similarityExpr(df)+" >= ?",
+				vectorArg,
+				params.add(query.MinScore),
+			)
+		}
+	}
+	if query.SearchType == models.SearchTypeLiteral {
+		// every match is scored alike. The trigram indexes on content serve the ILIKE.
+		dbQuery = dbQuery.
+			ColumnExpr("1.0 AS dist").
+			Where(
+				"?.content ILIKE ? ESCAPE '\\'",
+				bun.Safe(tablePrefix),
+				params.add(likePattern(query.Text)),
+			)
+	}
+	if len(query.Metadata) > 0 {
+		var err error
+		dbQuery, err = applyMemoryMetadataFilter(dbQuery, query.Metadata, tablePrefix)
+		if err != nil {
+			return nil, "", nil, store.NewStorageError("error applying metadata filter", err)
+		}
+	}
+
+	dbQuery = dbQuery.Where("?.session_id = ?", bun.Safe(tablePrefix), params.add(sessionID))
+	if query.Episode != 0 {
+		dbQuery = dbQuery.Where(
+			"m.metadata->'system'->>? = ?",
+			models.EpisodeMetadataKey,
+			params.add(strconv.Itoa(query.Episode)),
+		)
+	}
+
+	// Ensure we don't return deleted records.
+	dbQuery = dbQuery.Where("?.deleted_at IS NULL", bun.Safe(tablePrefix))
+
+	return dbQuery, tablePrefix, vectorArg, nil
+}
+
+// embedMemoryQuery embeds the text of a memory search with the message embedding model.
+func embedMemoryQuery(
 	ctx context.Context,
 	appState *models.AppState,
-	q *bun.SelectQuery,
 	queryText string,
-) (*bun.SelectQuery, []float32, error) {
+) ([]float32, error) {
 	documentType := "message"
 	model, err := llms.GetEmbeddingModel(appState, documentType)
 	if err != nil {
-		return nil, nil, store.NewStorageError("failed to get message embedding model", err)
+		return nil, store.NewStorageError("failed to get message embedding model", err)
 	}
 
 	e, err := llms.EmbedTexts(ctx, appState, model, documentType, []string{queryText})
 	if err != nil {
-		return nil, nil, store.NewStorageError("failed to embed query", err)
+		return nil, models.NewDependencyFailureError("query embedding", err)
 	}
-
-	vector := pgvector.NewVector(e[0])
-	return q.ColumnExpr("(embedding <#> ?) * -1 AS dist", vector), e[0], nil
+	return e[0], nil
 }
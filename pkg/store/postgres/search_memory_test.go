@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 
 	"github.com/getzep/zep/pkg/models"
@@ -186,3 +187,410 @@ func TestAddDateFilters(t *testing.T) {
 		})
 	}
 }
+
+func TestMemorySearchExplain(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	assert.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, false,
+	)
+	assert.NoError(t, err)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		me, err := messageDAO.GetEmbeddingListBySession(testCtx)
+		return err == nil && len(me) == len(testutils.TestMessages)
+	}, 10*time.Second, 500*time.Millisecond)
+
+	q := models.MemorySearchPayload{
+		Text:       "travel",
+		SearchType: models.SearchTypeMMR,
+		Explain:    true,
+		Metadata:   map[string]interface{}{"start_date": "2000-01-01"},
+	}
+	results, err := searchMemory(testCtx, appState, testDB, sessionID, &q, 3)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	for _, r := range results {
+		explanation := r.Explanation
+		if assert.NotNil(t, explanation) {
+			assert.Equal(t, r.Dist, explanation.VectorScore)
+			assert.Equal(t, explanation.VectorScore+explanation.ImportanceBoost, explanation.Score)
+			assert.Positive(t, explanation.Rank)
+			assert.NotNil(t, explanation.MMRScore)
+			assert.Contains(t, explanation.MetadataHits, "start_date")
+		}
+	}
+}
+
+func TestMemorySearchDebug(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	assert.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, false,
+	)
+	assert.NoError(t, err)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		me, err := messageDAO.GetEmbeddingListBySession(testCtx)
+		return err == nil && len(me) == len(testutils.TestMessages)
+	}, 10*time.Second, 500*time.Millisecond)
+
+	debug := &models.SearchDebug{}
+	ctx := models.WithSearchDebug(testCtx, debug)
+	q := models.MemorySearchPayload{Text: "travel", SearchType: models.SearchTypeMMR}
+	results, err := searchMemory(ctx, appState, testDB, sessionID, &q, 3)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	assert.NotEmpty(t, debug.Queries)
+	assert.NotNil(t, debug.EmbeddingModel)
+	assert.Len(t, debug.Distances, len(results))
+	for i, r := range results {
+		// the cosine distance
+		assert.InDelta(t, 1-r.Dist, debug.Distances[i], 1e-9)
+	}
+	assert.Positive(t, debug.Timings.DatabaseMS)
+	assert.Positive(t, debug.Timings.EmbeddingMS)
+}
+
+func TestMemorySearchExact(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	assert.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, false,
+	)
+	assert.NoError(t, err)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		me, err := messageDAO.GetEmbeddingListBySession(testCtx)
+		return err == nil && len(me) == len(testutils.TestMessages)
+	}, 10*time.Second, 500*time.Millisecond)
+
+	q := models.MemorySearchPayload{Text: "travel"}
+	approximate, err := searchMemory(testCtx, appState, testDB, sessionID, &q, 3)
+	assert.NoError(t, err)
+
+	debug := &models.SearchDebug{}
+	ctx := models.WithSearchDebug(testCtx, debug)
+	q.Exact = true
+	exact, err := searchMemory(ctx, appState, testDB, sessionID, &q, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "off", debug.Settings["enable_indexscan"])
+	assert.NotContains(t, debug.Settings, "hnsw.ef_search")
+
+	// a session's few messages are all found by the index, too
+	assert.Equal(t, len(approximate), len(exact))
+	for i := range exact {
+		assert.Equal(t, approximate[i].Message.UUID, exact[i].Message.UUID)
+	}
+}
+
+func TestJSONQueryPaths(t *testing.T) {
+	jq := &JSONQuery{
+		JSONPath: "$.a",
+		And: []*JSONQuery{
+			{JSONPath: "$.b"},
+			{Or: []*JSONQuery{{JSONPath: "$.c"}, {JSONPath: "$.d"}}},
+		},
+		Or:  []*JSONQuery{{JSONPath: "$.e"}},
+		Not: &JSONQuery{JSONPath: "$.f"},
+	}
+	assert.Equal(t, []string{"$.a", "$.b", "$.c", "$.d", "$.e"}, jsonQueryPaths(jq))
+}
+
+func TestMemorySearchHybrid(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	assert.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, false,
+	)
+	assert.NoError(t, err)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		me, err := messageDAO.GetEmbeddingListBySession(testCtx)
+		return err == nil && len(me) == len(testutils.TestMessages)
+	}, 10*time.Second, 500*time.Millisecond)
+
+	q := models.MemorySearchPayload{
+		Text:       "travel",
+		SearchType: models.SearchTypeHybrid,
+		Explain:    true,
+	}
+	results, err := searchMemory(testCtx, appState, testDB, sessionID, &q, 3)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	for i, r := range results {
+		if assert.NotNil(t, r.Explanation) {
+			assert.Equal(t, i+1, r.Explanation.Rank)
+			assert.Positive(t, r.Explanation.Score)
+		}
+	}
+
+	q.SearchScope = models.SearchScopeSummary
+	_, err = searchMemory(testCtx, appState, testDB, sessionID, &q, 3)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func TestMemorySearchMinScore(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	assert.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, false,
+	)
+	assert.NoError(t, err)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		me, err := messageDAO.GetEmbeddingListBySession(testCtx)
+		return err == nil && len(me) == len(testutils.TestMessages)
+	}, 10*time.Second, 500*time.Millisecond)
+
+	q := models.MemorySearchPayload{Text: "travel"}
+	all, err := searchMemory(testCtx, appState, testDB, sessionID, &q, 5)
+	assert.NoError(t, err)
+	if !assert.Len(t, all, 5) {
+		return
+	}
+
+	// the threshold is applied before the limit
+	q.MinScore = all[2].Dist
+	results, err := searchMemory(testCtx, appState, testDB, sessionID, &q, 5)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, results)
+	assert.Less(t, len(results), len(all))
+	for _, r := range results {
+		assert.GreaterOrEqual(t, r.Dist, q.MinScore)
+	}
+
+	q = models.MemorySearchPayload{
+		Metadata: map[string]interface{}{"start_date": "2020-01-01"},
+		MinScore: 0.5,
+	}
+	_, err = searchMemory(testCtx, appState, testDB, sessionID, &q, 5)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func TestMemorySearchRoles(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	assert.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, false,
+	)
+	assert.NoError(t, err)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		me, err := messageDAO.GetEmbeddingListBySession(testCtx)
+		return err == nil && len(me) == len(testutils.TestMessages)
+	}, 10*time.Second, 500*time.Millisecond)
+
+	for _, role := range []string{"user", "Assistant"} {
+		q := models.MemorySearchPayload{Text: "travel", Roles: []string{role}}
+		results, err := searchMemory(testCtx, appState, testDB, sessionID, &q, 20)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, results)
+		for _, r := range results {
+			assert.True(t, strings.EqualFold(role, r.Message.Role), r.Message.Role)
+		}
+	}
+
+	q := models.MemorySearchPayload{
+		Text:        "travel",
+		SearchScope: models.SearchScopeSummary,
+		Roles:       []string{"user"},
+	}
+	_, err = searchMemory(testCtx, appState, testDB, sessionID, &q, 5)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func TestMemorySearchCursor(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	assert.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, false,
+	)
+	assert.NoError(t, err)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		me, err := messageDAO.GetEmbeddingListBySession(testCtx)
+		return err == nil && len(me) == len(testutils.TestMessages)
+	}, 10*time.Second, 500*time.Millisecond)
+
+	q := models.MemorySearchPayload{Text: "travel"}
+	all, err := searchMemory(testCtx, appState, testDB, sessionID, &q, 6)
+	assert.NoError(t, err)
+	assert.Len(t, all, 6)
+
+	var paged []models.MemorySearchResult
+	for i := 0; i < 3; i++ {
+		page, err := searchMemory(testCtx, appState, testDB, sessionID, &q, 2)
+		assert.NoError(t, err)
+		if !assert.Len(t, page, 2) {
+			return
+		}
+		paged = append(paged, page...)
+		q.Cursor = page[len(page)-1].Cursor
+	}
+	for i := range all {
+		assert.Equal(t, all[i].Message.UUID, paged[i].Message.UUID)
+		assert.Equal(t, all[i].Cursor, paged[i].Cursor)
+	}
+
+	for _, q := range []models.MemorySearchPayload{
+		{Text: "travel", Cursor: "not a cursor"},
+		{Text: "travel", SearchType: models.SearchTypeMMR, Cursor: all[0].Cursor},
+		{Metadata: map[string]interface{}{"start_date": "2020-01-01"}, Cursor: all[0].Cursor},
+	} {
+		_, err = searchMemory(testCtx, appState, testDB, sessionID, &q, 2)
+		assert.ErrorIs(t, err, models.ErrValidation)
+	}
+}
+
+func TestMemorySearchRecency(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	assert.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, false,
+	)
+	assert.NoError(t, err)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		me, err := messageDAO.GetEmbeddingListBySession(testCtx)
+		return err == nil && len(me) == len(testutils.TestMessages)
+	}, 10*time.Second, 500*time.Millisecond)
+
+	q := models.MemorySearchPayload{Text: "travel"}
+	unweighted, err := searchMemory(testCtx, appState, testDB, sessionID, &q, 3)
+	assert.NoError(t, err)
+	assert.Len(t, unweighted, 3)
+
+	// every message but the least similar was sent two days ago
+	_, err = testDB.NewUpdate().
+		Model((*MessageStoreSchema)(nil)).
+		Set("created_at = created_at - interval '48 hours'").
+		Where("session_id = ?", sessionID).
+		Where("uuid != ?", unweighted[2].Message.UUID).
+		Exec(testCtx)
+	assert.NoError(t, err)
+
+	q = models.MemorySearchPayload{Text: "travel", RecencyWeight: 10, Explain: true}
+	weighted, err := searchMemory(testCtx, appState, testDB, sessionID, &q, 3)
+	assert.NoError(t, err)
+	if assert.Len(t, weighted, 3) {
+		assert.Equal(t, unweighted[2].Message.UUID, weighted[0].Message.UUID)
+		assert.InDelta(t, 10, weighted[0].Explanation.RecencyBoost, 0.01)
+		// two half-lives of the default 24 hours have passed
+		assert.InDelta(t, 2.5, weighted[1].Explanation.RecencyBoost, 0.01)
+		for _, r := range weighted {
+			assert.InDelta(t, r.RankScore, r.Explanation.Score, 0.01)
+		}
+	}
+
+	for _, q := range []models.MemorySearchPayload{
+		{Text: "travel", RecencyWeight: -1},
+		{Text: "travel", RecencyWeight: 1, SearchScope: models.SearchScopeSummary},
+		{Text: "travel", RecencyWeight: 1, Cursor: unweighted[0].Cursor},
+		{Metadata: map[string]interface{}{"start_date": "2020-01-01"}, RecencyWeight: 1},
+	} {
+		_, err = searchMemory(testCtx, appState, testDB, sessionID, &q, 3)
+		assert.ErrorIs(t, err, models.ErrValidation)
+	}
+}
+
+func TestSearchUserMemory(t *testing.T) {
+	userID := testutils.GenerateRandomString(16)
+	_, err := NewUserStoreDAO(testDB).Create(testCtx, &models.CreateUserRequest{UserID: userID})
+	assert.NoError(t, err)
+
+	var sessionIDs []string
+	for i := 0; i < 3; i++ {
+		sessionID, err := testutils.GenerateRandomSessionID(16)
+		assert.NoError(t, err)
+		request := &models.CreateSessionRequest{SessionID: sessionID}
+		// the last session belongs to another user
+		if i < 2 {
+			request.UserID = &userID
+		}
+		_, err = appState.MemoryStore.CreateSession(testCtx, request)
+		assert.NoError(t, err)
+		err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+			&models.Memory{Messages: testutils.TestMessages}, false,
+		)
+		assert.NoError(t, err)
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+
+	for _, sessionID := range sessionIDs {
+		assert.Eventually(t, func() bool {
+			var sessionEmbedding SessionEmbeddingSchema
+			err := testDB.NewSelect().
+				Model(&sessionEmbedding).
+				Where("session_id = ?", sessionID).
+				Scan(testCtx)
+			return err == nil && sessionEmbedding.MessageCount >= len(testutils.TestMessages)
+		}, 10*time.Second, 500*time.Millisecond)
+	}
+
+	q := models.MemorySearchPayload{Text: "travel"}
+	results, err := searchUserMemory(testCtx, appState, testDB, userID, &q, 0, 4)
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+	for i, r := range results {
+		assert.Contains(t, sessionIDs[:2], r.SessionID)
+		assert.Empty(t, r.Cursor)
+		if i > 0 {
+			assert.GreaterOrEqual(t, results[i-1].RankScore, r.RankScore)
+		}
+	}
+
+	// only the nearest session is searched
+	results, err = searchUserMemory(testCtx, appState, testDB, userID, &q, 1, 10)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, results) {
+		for _, r := range results {
+			assert.Equal(t, results[0].SessionID, r.SessionID)
+		}
+	}
+
+	for _, q := range []models.MemorySearchPayload{
+		{},
+		{Text: "travel", SearchType: models.SearchTypeMMR},
+		{Text: "travel", Cursor: "cursor"},
+	} {
+		_, err = searchUserMemory(testCtx, appState, testDB, userID, &q, 0, 0)
+		assert.ErrorIs(t, err, models.ErrValidation)
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	result := func(u uuid.UUID) models.MemorySearchResult {
+		return models.MemorySearchResult{Message: &models.Message{UUID: u}}
+	}
+
+	// b is ranked by both, and c only by the text ranking
+	results, scores := fuseRankings(
+		2,
+		[]models.MemorySearchResult{result(a), result(b)},
+		[]models.MemorySearchResult{result(b), result(c)},
+	)
+	assert.Len(t, results, 2)
+	assert.Equal(t, b, results[0].Message.UUID)
+	assert.Equal(t, a, results[1].Message.UUID)
+	assert.InDelta(t, 1.0/62+1.0/61, scores[0], 1e-9)
+	assert.InDelta(t, 1.0/61, scores[1], 1e-9)
+}
@@ -10,6 +10,11 @@ import (
 const DefaultMMRMultiplier = 2
 const DefaultMMRLambda = 0.5
 
+// DefaultHybridMultiplier is the multiple of the limit a hybrid search fuses from each
+// ranking, and RRFRankConstant dampens the weight of the top ranks when they're fused.
+const DefaultHybridMultiplier = 2
+const RRFRankConstant = 60
+
 // parseJSONQuery recursively parses a JSONQuery and returns a bun.QueryBuilder.
 // TODO: fix the addition of extraneous parentheses in the query
 func parseJSONQuery(
@@ -55,9 +60,65 @@ func parseJSONQuery(
 		})
 	}
 
+	if jq.Not != nil {
+		if cond, args := jsonQueryCondition(jq.Not, tp); cond != "" {
+			// bun drops the separator of a leading WhereGroup, so the NOT is added as
+			// a single condition rather than as a group
+			cond = "NOT (" + cond + ")"
+			if isOr {
+				qb = qb.WhereOr(cond, args...)
+			} else {
+				qb = qb.Where(cond, args...)
+			}
+		}
+	}
+
 	return qb
 }
 
+// jsonQueryCondition returns the condition of a JSONQuery, with its arguments, as
+// parseJSONQuery would add it to a query. tp is the table prefix, with its trailing dot.
+func jsonQueryCondition(jq *JSONQuery, tp string) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if jq.JSONPath != "" {
+		conds = append(conds, fmt.Sprintf("jsonb_path_exists(%smetadata, ?)", tp))
+		args = append(args, strings.ReplaceAll(jq.JSONPath, "'", "\""))
+	}
+	group := func(queries []*JSONQuery, sep string) {
+		var groupConds []string
+		for _, q := range queries {
+			cond, groupArgs := jsonQueryCondition(q, tp)
+			if cond == "" {
+				continue
+			}
+			groupConds = append(groupConds, "("+cond+")")
+			args = append(args, groupArgs...)
+		}
+		if len(groupConds) > 0 {
+			conds = append(conds, "("+strings.Join(groupConds, sep)+")")
+		}
+	}
+	group(jq.And, " AND ")
+	group(jq.Or, " OR ")
+	if jq.Not != nil {
+		if cond, notArgs := jsonQueryCondition(jq.Not, tp); cond != "" {
+			conds = append(conds, "NOT ("+cond+")")
+			args = append(args, notArgs...)
+		}
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, and its escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern returns a LIKE pattern, escaped by backslashes, matching strings that
+// contain text.
+func likePattern(text string) string {
+	return "%" + likeEscaper.Replace(text) + "%"
+}
+
 func getAscDesc(asc bool) string {
 	if asc {
 		return "ASC"
@@ -35,6 +35,18 @@ func TestParseJSONQuery(t *testing.T) {
 			expectedCond: `WHERE ((jsonb_path_exists(m.metadata, '$.system.entities[*] ? (@.Label == "DATE")')) AND (jsonb_path_exists(m.metadata, '$.system.entities[*] ? (@.Label == "ORG")')) AND ((jsonb_path_exists(m.metadata, '$.system.entities[*] ? (@.Name == "Iceland")')) OR (jsonb_path_exists(m.metadata, '$.system.entities[*] ? (@.Name == "Canada")'))))`,
 			tablePrefix:  "m",
 		},
+		{
+			name:         "Not",
+			jsonQuery:    `{"where": {"and": [{"jsonpath": "$.system.entities[*] ? (@.Label == \"DATE\")"},{"not": {"jsonpath": "$.system.entities[*] ? (@.Name == \"Iceland\")"}}]}}`,
+			expectedCond: `WHERE ((jsonb_path_exists(m.metadata, '$.system.entities[*] ? (@.Label == "DATE")')) AND (NOT (jsonb_path_exists(m.metadata, '$.system.entities[*] ? (@.Name == "Iceland")'))))`,
+			tablePrefix:  "m",
+		},
+		{
+			name:         "Not Or",
+			jsonQuery:    `{"where": {"not": {"or": [{"jsonpath": "$.system.entities[*] ? (@.Name == \"Iceland\")"},{"jsonpath": "$.system.entities[*] ? (@.Name == \"Canada\")"}]}}}`,
+			expectedCond: `WHERE (NOT (((jsonb_path_exists(metadata, '$.system.entities[*] ? (@.Name == "Iceland")')) OR (jsonb_path_exists(metadata, '$.system.entities[*] ? (@.Name == "Canada")')))))`,
+			tablePrefix:  "",
+		},
 	}
 
 	for _, tt := range tests {
@@ -69,3 +81,8 @@ func TestParseJSONQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestLikePattern(t *testing.T) {
+	assert.Equal(t, "%order #12345%", likePattern("order #12345"))
+	assert.Equal(t, `%50\% off\_now\\%`, likePattern(`50% off_now\`))
+}
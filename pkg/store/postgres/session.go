@@ -14,9 +14,9 @@ import (
 	"github.com/uptrace/bun/driver/pgdriver"
 )
 
-var _ models.SessionManager = &SessionDAO{}
+var _ models.SessionDAO = &SessionDAO{}
 
-// SessionDAO implements the SessionManager interface.
+// SessionDAO implements the models.SessionDAO interface.
 type SessionDAO struct {
 	db *bun.DB
 }
@@ -35,27 +35,100 @@ func NewSessionDAO(db *bun.DB) *SessionDAO {
 func (dao *SessionDAO) Create(
 	ctx context.Context,
 	session *models.CreateSessionRequest,
+) (*models.Session, error) {
+	return withConflictRetry(ctx, "create session", func(ctx context.Context) (*models.Session, error) {
+		return dao.create(ctx, dao.db, session)
+	})
+}
+
+// CreateBatch creates sessions in a single transaction. Each session is inserted under a
+// savepoint, so that a session failing validation or conflicting with an existing session
+// is rolled back and reported in its result while the others are created.
+func (dao *SessionDAO) CreateBatch(
+	ctx context.Context,
+	sessions []models.CreateSessionRequest,
+) (*models.CreateSessionsResponse, error) {
+	return withConflictRetry(
+		ctx,
+		"create sessions",
+		func(ctx context.Context) (*models.CreateSessionsResponse, error) {
+			return dao.createBatch(ctx, sessions)
+		},
+	)
+}
+
+func (dao *SessionDAO) createBatch(
+	ctx context.Context,
+	sessions []models.CreateSessionRequest,
+) (*models.CreateSessionsResponse, error) {
+	tx, err := dao.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	response := &models.CreateSessionsResponse{
+		Results: make([]models.CreateSessionsResult, len(sessions)),
+	}
+	for i := range sessions {
+		result := &response.Results[i]
+		result.Index = i
+		result.SessionID = sessions[i].SessionID
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT create_session"); err != nil {
+			return nil, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		session, err := dao.create(ctx, tx, &sessions[i])
+		if err != nil {
+			if !errors.Is(err, models.ErrValidation) && !errors.Is(err, models.ErrConflict) {
+				return nil, err
+			}
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT create_session"); err != nil {
+				return nil, fmt.Errorf("failed to roll back to savepoint: %w", err)
+			}
+			result.Error = err.Error()
+			response.Failed++
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT create_session"); err != nil {
+			return nil, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		result.Session = session
+		response.Succeeded++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return response, nil
+}
+
+func (dao *SessionDAO) create(
+	ctx context.Context,
+	db bun.IDB,
+	session *models.CreateSessionRequest,
 ) (*models.Session, error) {
 	if session.SessionID == "" {
-		return nil, errors.New("sessionID cannot be empty")
+		return nil, models.NewValidationError("sessionID cannot be empty")
 	}
 	sessionDB := SessionSchema{
 		SessionID: session.SessionID,
 		UserID:    session.UserID,
 		Metadata:  session.Metadata,
+		ExpiresAt: session.ExpiresAt,
 	}
-	_, err := dao.db.NewInsert().
+	_, err := db.NewInsert().
 		Model(&sessionDB).
 		Returning("*").
 		Exec(ctx)
 	if err != nil {
 		if err, ok := err.(pgdriver.Error); ok && err.IntegrityViolation() {
 			if strings.Contains(err.Error(), "user") {
-				return nil, models.NewBadRequestError(
+				return nil, models.NewValidationError(
 					"user does not exist with user_id: " + *session.UserID,
 				)
 			}
-			return nil, models.NewBadRequestError(
+			return nil, models.NewConflictError(
 				"session already exists with session_id: " + session.SessionID,
 			)
 		}
@@ -70,6 +143,10 @@ func (dao *SessionDAO) Create(
 		SessionID: sessionDB.SessionID,
 		Metadata:  sessionDB.Metadata,
 		UserID:    sessionDB.UserID,
+		ExpiresAt: sessionDB.ExpiresAt,
+		Title:     sessionDB.Title,
+		TaskState: sessionDB.TaskState,
+		Episodes:  sessionDB.Episodes,
 	}, nil
 }
 
@@ -97,6 +174,10 @@ func (dao *SessionDAO) Get(ctx context.Context, sessionID string) (*models.Sessi
 		SessionID: session.SessionID,
 		Metadata:  session.Metadata,
 		UserID:    session.UserID,
+		ExpiresAt: session.ExpiresAt,
+		Title:     session.Title,
+		TaskState: session.TaskState,
+		Episodes:  session.Episodes,
 	}
 	return &retSession, nil
 }
@@ -109,32 +190,40 @@ func (dao *SessionDAO) Update(
 	ctx context.Context,
 	session *models.UpdateSessionRequest,
 	isPrivileged bool,
+) (*models.Session, error) {
+	return withRetry(ctx, "update session", func(ctx context.Context) (*models.Session, error) {
+		return dao.update(ctx, session, isPrivileged)
+	})
+}
+
+func (dao *SessionDAO) update(
+	ctx context.Context,
+	session *models.UpdateSessionRequest,
+	isPrivileged bool,
 ) (*models.Session, error) {
 	if session.SessionID == "" {
-		return nil, errors.New("sessionID cannot be empty")
+		return nil, models.NewValidationError("sessionID cannot be empty")
 	}
 
 	// if metadata is null, we can keep this a cheap operation
 	if session.Metadata == nil {
-		return dao.updateSession(ctx, session)
+		return dao.updateSession(ctx, dao.db, session)
 	}
 
-	// Acquire a lock for this SessionID. This is to prevent concurrent updates
-	// to the session metadata.
-	lockID, err := acquireAdvisoryLock(ctx, dao.db, session.SessionID)
+	tx, err := dao.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to acquire advisory lock: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	updatedSession, err := dao.updateSession(ctx, tx, session)
+	if err != nil {
+		return nil, err
 	}
-	defer func(ctx context.Context, db bun.IDB, lockID uint64) {
-		err := releaseAdvisoryLock(ctx, db, lockID)
-		if err != nil {
-			log.Errorf("failed to release advisory lock: %v", err)
-		}
-	}(ctx, dao.db, lockID)
 
-	mergedMetadata, err := mergeMetadata(
+	updatedSession.Metadata, err = mergeMetadata(
 		ctx,
-		dao.db,
+		tx,
 		"session_id",
 		session.SessionID,
 		"session",
@@ -145,28 +234,92 @@ func (dao *SessionDAO) Update(
 		return nil, fmt.Errorf("failed to merge session metadata: %w", err)
 	}
 
-	session = &models.UpdateSessionRequest{
-		SessionID: session.SessionID,
-		Metadata:  mergedMetadata,
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return updatedSession, nil
+}
+
+// PatchMetadata applies patch to the metadata of an undeleted session. The session's row is
+// locked while the patch is applied, so that concurrent patches aren't lost.
+func (dao *SessionDAO) PatchMetadata(
+	ctx context.Context,
+	sessionID string,
+	patch models.MetadataPatch,
+	isPrivileged bool,
+) (*models.Session, error) {
+	return withRetry(
+		ctx,
+		"patch session metadata",
+		func(ctx context.Context) (*models.Session, error) {
+			return dao.patchMetadata(ctx, sessionID, patch, isPrivileged)
+		},
+	)
+}
+
+func (dao *SessionDAO) patchMetadata(
+	ctx context.Context,
+	sessionID string,
+	patch models.MetadataPatch,
+	isPrivileged bool,
+) (*models.Session, error) {
+	tx, err := dao.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	session := SessionSchema{}
+	err = tx.NewSelect().
+		Model(&session).
+		Where("session_id = ?", sessionID).
+		For("UPDATE").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewNotFoundError("session " + sessionID)
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
-	return dao.updateSession(ctx, session)
+
+	session.Metadata, err = models.PatchMetadata(session.Metadata, patch, isPrivileged)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.NewUpdate().
+		Model(&session).
+		Column("metadata", "updated_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session metadata: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return sessionSchemaToSession([]SessionSchema{session})[0], nil
 }
 
-// updateSession updates a session in the database. It expects the metadata to be merged.
+// updateSession updates a session in the database, undeleting it if it was soft-deleted.
+// The session's metadata is not updated. See mergeMetadata.
 func (dao *SessionDAO) updateSession(
 	ctx context.Context,
+	db bun.IDB,
 	session *models.UpdateSessionRequest,
 ) (*models.Session, error) {
 	sessionDB := SessionSchema{
 		SessionID: session.SessionID,
-		Metadata:  session.Metadata,
 		DeletedAt: time.Time{}, // Intentionally overwrite soft-delete with zero value
+		ExpiresAt: session.ExpiresAt,
 	}
 	var columns = []string{"deleted_at", "updated_at"}
-	if session.Metadata != nil {
-		columns = append(columns, "metadata")
+	if session.ExpiresAt != nil {
+		// a new expiry resets the warning so that the session.expiring webhook is sent again
+		columns = append(columns, "expires_at", "expiry_warned_at")
 	}
-	r, err := dao.db.NewUpdate().
+	r, err := db.NewUpdate().
 		Model(&sessionDB).
 		// intentionally overwrite the deleted_at field, undeleting the session
 		// if the session exists and is deleted
@@ -195,6 +348,10 @@ func (dao *SessionDAO) updateSession(
 		SessionID: sessionDB.SessionID,
 		Metadata:  sessionDB.Metadata,
 		UserID:    sessionDB.UserID,
+		ExpiresAt: sessionDB.ExpiresAt,
+		Title:     sessionDB.Title,
+		TaskState: sessionDB.TaskState,
+		Episodes:  sessionDB.Episodes,
 	}
 
 	return &returnedSession, nil
@@ -202,7 +359,17 @@ func (dao *SessionDAO) updateSession(
 
 // Delete soft-deletes a session from the database by its sessionID.
 // It also soft-deletes all messages, message embeddings, and summaries associated with the session.
-func (dao *SessionDAO) Delete(ctx context.Context, sessionID string) error {
+// Returns a ConflictError if the session or its user is under legal hold.
+func (dao *SessionDAO) Delete(
+	ctx context.Context,
+	sessionID string,
+) error {
+	return withRetryErr(ctx, "delete session", func(ctx context.Context) error {
+		return dao.delete(ctx, sessionID)
+	})
+}
+
+func (dao *SessionDAO) delete(ctx context.Context, sessionID string) error {
 	dbSession := &SessionSchema{}
 
 	tx, err := dao.db.BeginTx(ctx, nil)
@@ -211,6 +378,10 @@ func (dao *SessionDAO) Delete(ctx context.Context, sessionID string) error {
 	}
 	defer rollbackOnError(tx)
 
+	if err := checkSessionHold(ctx, tx, sessionID); err != nil {
+		return err
+	}
+
 	r, err := tx.NewDelete().
 		Model(dbSession).
 		Where("session_id = ?", sessionID).
@@ -228,27 +399,216 @@ func (dao *SessionDAO) Delete(ctx context.Context, sessionID string) error {
 	}
 
 	// delete all messages, message embeddings, and summaries associated with the session
+	if err := deleteSessionRecords(ctx, tx, []string{sessionID}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// deleteSessionRecords soft-deletes all messages, message embeddings, summaries, and
+// memory snapshots associated with the given sessionIDs. The sessions themselves are not deleted.
+func deleteSessionRecords(ctx context.Context, tx bun.Tx, sessionIDs []string) error {
 	for _, schema := range messageTableList {
 		if _, ok := schema.(*SessionSchema); ok {
 			continue
 		}
-		log.Debugf("deleting session %s from schema %T", sessionID, schema)
+		log.Debugf("deleting sessions %v from schema %T", sessionIDs, schema)
 		_, err := tx.NewDelete().
 			Model(schema).
-			Where("session_id = ?", sessionID).
+			Where("session_id IN (?)", bun.In(sessionIDs)).
 			Exec(ctx)
 		if err != nil {
 			return fmt.Errorf("error deleting rows from %T: %w", schema, err)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	return nil
+}
+
+// ListExpiring returns undeleted sessions expiring before the given time that have not
+// yet had an expiry warning sent, excluding sessions under legal hold.
+func (dao *SessionDAO) ListExpiring(
+	ctx context.Context,
+	before time.Time,
+) ([]*models.Session, error) {
+	var sessions []SessionSchema
+	err := dao.db.NewSelect().
+		Model(&sessions).
+		Where("expires_at IS NOT NULL").
+		Where("expires_at <= ?", before).
+		Where("expiry_warned_at IS NULL").
+		Where(
+			"NOT "+sessionHeldCondition,
+			models.LegalHoldSubjectSession,
+			models.LegalHoldSubjectUser,
+		).
+		Order("expires_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiring sessions: %w", err)
+	}
+
+	return sessionSchemaToSession(sessions), nil
+}
+
+// UpdateTitle sets the title of an undeleted session. updated_at is left unchanged, as
+// the title is derived from the session's messages rather than set by the client.
+func (dao *SessionDAO) UpdateTitle(ctx context.Context, sessionID string, title string) error {
+	r, err := dao.db.NewUpdate().
+		Model((*SessionSchema)(nil)).
+		Set("title = ?", title).
+		Where("session_id = ?", sessionID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update session title: %w", err)
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.NewNotFoundError("session " + sessionID)
+	}
+	return nil
+}
+
+// UpdateTaskState replaces the task state of an undeleted session. As with the title,
+// updated_at is left unchanged.
+func (dao *SessionDAO) UpdateTaskState(
+	ctx context.Context,
+	sessionID string,
+	state *models.TaskState,
+) error {
+	r, err := dao.db.NewUpdate().
+		Model((*SessionSchema)(nil)).
+		Set("task_state = ?", state).
+		Where("session_id = ?", sessionID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update session task state: %w", err)
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.NewNotFoundError("session " + sessionID)
+	}
+	return nil
+}
+
+// UpdateEpisodes replaces the episodes of an undeleted session, leaving updated_at
+// unchanged.
+func (dao *SessionDAO) UpdateEpisodes(
+	ctx context.Context,
+	sessionID string,
+	episodes []models.Episode,
+) error {
+	r, err := dao.db.NewUpdate().
+		Model((*SessionSchema)(nil)).
+		Set("episodes = ?", episodes).
+		Where("session_id = ?", sessionID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update session episodes: %w", err)
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.NewNotFoundError("session " + sessionID)
+	}
+	return nil
+}
+
+// MarkExpiryWarned sets expiry_warned_at for the given sessionIDs.
+func (dao *SessionDAO) MarkExpiryWarned(
+	ctx context.Context,
+	sessionIDs []string,
+) error {
+	return withRetryErr(ctx, "mark sessions expiry warned", func(ctx context.Context) error {
+		return dao.markExpiryWarned(ctx, sessionIDs)
+	})
+}
+
+func (dao *SessionDAO) markExpiryWarned(ctx context.Context, sessionIDs []string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+
+	_, err := dao.db.NewUpdate().
+		Model((*SessionSchema)(nil)).
+		Set("expiry_warned_at = current_timestamp").
+		Where("session_id IN (?)", bun.In(sessionIDs)).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to mark sessions expiry warned: %w", err)
 	}
 
 	return nil
 }
 
+// DeleteExpired soft-deletes all sessions whose expires_at has passed, along with
+// their messages, message embeddings, and summaries. Sessions under legal hold are skipped.
+// It returns the deleted sessions.
+func (dao *SessionDAO) DeleteExpired(
+	ctx context.Context,
+) ([]*models.Session, error) {
+	return withRetry(ctx, "delete expired sessions", func(ctx context.Context) ([]*models.Session, error) {
+		return dao.deleteExpired(ctx)
+	})
+}
+
+func (dao *SessionDAO) deleteExpired(ctx context.Context) ([]*models.Session, error) {
+	var sessions []SessionSchema
+
+	tx, err := dao.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	_, err = tx.NewDelete().
+		Model(&sessions).
+		Where("expires_at IS NOT NULL").
+		Where("expires_at <= current_timestamp").
+		Where(
+			"NOT "+sessionHeldCondition,
+			models.LegalHoldSubjectSession,
+			models.LegalHoldSubjectUser,
+		).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+
+	sessionIDs := make([]string, len(sessions))
+	for i := range sessions {
+		sessionIDs[i] = sessions[i].SessionID
+	}
+
+	if err := deleteSessionRecords(ctx, tx, sessionIDs); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return sessionSchemaToSession(sessions), nil
+}
+
 // ListAll retrieves all sessions from the database.
 // It takes a context, a cursor int64, and a limit int.
 // It returns a slice of pointers to Session structs or an error if the retrieval fails.
@@ -348,6 +708,10 @@ func sessionSchemaToSession(sessions []SessionSchema) []*models.Session {
 			SessionID: sessions[i].SessionID,
 			Metadata:  sessions[i].Metadata,
 			UserID:    sessions[i].UserID,
+			ExpiresAt: sessions[i].ExpiresAt,
+			Title:     sessions[i].Title,
+			TaskState: sessions[i].TaskState,
+			Episodes:  sessions[i].Episodes,
 		}
 	}
 	return retSessions
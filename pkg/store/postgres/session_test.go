@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"testing"
+	"time"
 
 	"github.com/getzep/zep/pkg/models"
 	"github.com/getzep/zep/pkg/testutils"
@@ -41,7 +42,7 @@ func TestSessionDAO_Create(t *testing.T) {
 					"key": "value",
 				}},
 			wantErr:    true,
-			errMessage: "sessionID cannot be empty",
+			errMessage: "bad request: sessionID cannot be empty",
 		},
 		// Add more test cases as needed
 	}
@@ -51,7 +52,7 @@ func TestSessionDAO_Create(t *testing.T) {
 			result, err := dao.Create(testCtx, tt.session)
 
 			if tt.wantErr {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, models.ErrValidation)
 				assert.Equal(t, tt.errMessage, err.Error())
 			} else {
 				assert.NoError(t, err)
@@ -520,6 +521,147 @@ func TestSessionDAO_ListAllOrdered(t *testing.T) {
 	}
 }
 
+func TestSessionDAO_UpdateTitle(t *testing.T) {
+	dao := NewSessionDAO(testDB)
+	sessionID := createSession(t)
+
+	err := dao.UpdateTitle(testCtx, sessionID, "Planning a trip")
+	assert.NoError(t, err)
+
+	session, err := dao.Get(testCtx, sessionID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Planning a trip", session.Title)
+
+	// client updates leave the title alone
+	updated, err := dao.Update(testCtx, &models.UpdateSessionRequest{
+		SessionID: sessionID,
+		Metadata:  map[string]interface{}{"key": "value"},
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Planning a trip", updated.Title)
+
+	err = dao.UpdateTitle(testCtx, "missing", "title")
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestSessionDAO_UpdateTaskState(t *testing.T) {
+	dao := NewSessionDAO(testDB)
+	sessionID := createSession(t)
+
+	session, err := dao.Get(testCtx, sessionID)
+	assert.NoError(t, err)
+	assert.Nil(t, session.TaskState)
+
+	state := &models.TaskState{
+		Stage:     "executing",
+		Goal:      "Book a flight",
+		StepsDone: []string{"chose dates"},
+		Blockers:  []string{},
+	}
+	err = dao.UpdateTaskState(testCtx, sessionID, state)
+	assert.NoError(t, err)
+
+	session, err = dao.Get(testCtx, sessionID)
+	assert.NoError(t, err)
+	assert.Equal(t, state, session.TaskState)
+
+	err = dao.UpdateTaskState(testCtx, "missing", state)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestSessionDAO_UpdateEpisodes(t *testing.T) {
+	dao := NewSessionDAO(testDB)
+	sessionID := createSession(t)
+
+	session, err := dao.Get(testCtx, sessionID)
+	assert.NoError(t, err)
+	assert.Empty(t, session.Episodes)
+
+	episodes := []models.Episode{
+		{ID: 1, Topic: "flights", StartMessageUUID: uuid.New(), MessageCount: 2},
+	}
+	err = dao.UpdateEpisodes(testCtx, sessionID, episodes)
+	assert.NoError(t, err)
+
+	session, err = dao.Get(testCtx, sessionID)
+	assert.NoError(t, err)
+	if assert.Len(t, session.Episodes, 1) {
+		assert.Equal(t, episodes[0].Topic, session.Episodes[0].Topic)
+		assert.Equal(t, episodes[0].StartMessageUUID, session.Episodes[0].StartMessageUUID)
+	}
+
+	err = dao.UpdateEpisodes(testCtx, "missing", episodes)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestSessionDAO_Expiry(t *testing.T) {
+	dao := NewSessionDAO(testDB)
+
+	expiredAt := time.Now().Add(-time.Minute)
+	expiringAt := time.Now().Add(30 * time.Minute)
+
+	expiredSessionID := createSession(t)
+	_, err := dao.Update(testCtx, &models.UpdateSessionRequest{
+		SessionID: expiredSessionID,
+		ExpiresAt: &expiredAt,
+	}, false)
+	assert.NoError(t, err)
+
+	expiringSessionID, err := testutils.GenerateRandomSessionID(16)
+	assert.NoError(t, err)
+	expiringSession, err := dao.Create(testCtx, &models.CreateSessionRequest{
+		SessionID: expiringSessionID,
+		ExpiresAt: &expiringAt,
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, expiringSession.ExpiresAt)
+
+	messageDAO, err := NewMessageDAO(testDB, appState, expiredSessionID)
+	assert.NoError(t, err)
+	_, err = messageDAO.CreateMany(testCtx, []models.Message{{Role: "user", Content: "hello"}})
+	assert.NoError(t, err)
+
+	t.Run("ListExpiring", func(t *testing.T) {
+		sessions, err := dao.ListExpiring(testCtx, time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, containsSession(sessions, expiringSessionID))
+		assert.True(t, containsSession(sessions, expiredSessionID))
+
+		err = dao.MarkExpiryWarned(testCtx, []string{expiringSessionID})
+		assert.NoError(t, err)
+
+		sessions, err = dao.ListExpiring(testCtx, time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.False(t, containsSession(sessions, expiringSessionID))
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		sessions, err := dao.DeleteExpired(testCtx)
+		assert.NoError(t, err)
+		assert.True(t, containsSession(sessions, expiredSessionID))
+		assert.False(t, containsSession(sessions, expiringSessionID))
+
+		_, err = dao.Get(testCtx, expiredSessionID)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+
+		messages, err := messageDAO.GetLastN(testCtx, 10, uuid.Nil)
+		assert.NoError(t, err)
+		assert.Empty(t, messages)
+
+		_, err = dao.Get(testCtx, expiringSessionID)
+		assert.NoError(t, err)
+	})
+}
+
+func containsSession(sessions []*models.Session, sessionID string) bool {
+	for _, session := range sessions {
+		if session.SessionID == sessionID {
+			return true
+		}
+	}
+	return false
+}
+
 // Helper function to reverse a slice of sessions
 func reverse(sessions []*models.Session) []*models.Session {
 	reversed := make([]*models.Session, len(sessions))
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.MemorySnapshotStore = &MemorySnapshotStoreDAO{}
+
+type MemorySnapshotStoreDAO struct {
+	db *bun.DB
+}
+
+func NewMemorySnapshotStoreDAO(db *bun.DB) *MemorySnapshotStoreDAO {
+	return &MemorySnapshotStoreDAO{
+		db: db,
+	}
+}
+
+// Put stores a snapshot, replacing any snapshot of the same turn, including one deleted
+// with an earlier session of the same ID.
+func (dao *MemorySnapshotStoreDAO) Put(
+	ctx context.Context,
+	snapshot *models.MemorySnapshot,
+) (*models.MemorySnapshot, error) {
+	if snapshot.TurnUUID == uuid.Nil {
+		return nil, models.NewValidationError("turn UUID cannot be empty")
+	}
+
+	exists, err := dao.db.NewSelect().
+		Model((*SessionSchema)(nil)).
+		Where("session_id = ?", snapshot.SessionID).
+		Exists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check session: %w", err)
+	}
+	if !exists {
+		return nil, models.NewNotFoundError("session " + snapshot.SessionID)
+	}
+
+	record := &MemorySnapshotSchema{
+		CreatedAt: time.Now(),
+		SessionID: snapshot.SessionID,
+		TurnUUID:  snapshot.TurnUUID,
+		Memory:    snapshot.Memory,
+	}
+	_, err = dao.db.NewInsert().
+		Model(record).
+		On("CONFLICT (session_id, turn_uuid) DO UPDATE").
+		Set("created_at = EXCLUDED.created_at").
+		Set("memory = EXCLUDED.memory").
+		Set("deleted_at = NULL").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put memory snapshot: %w", err)
+	}
+	return memorySnapshotSchemaToMemorySnapshot(record), nil
+}
+
+// Get returns the snapshot of a session's turn.
+func (dao *MemorySnapshotStoreDAO) Get(
+	ctx context.Context,
+	sessionID string,
+	turnUUID uuid.UUID,
+) (*models.MemorySnapshot, error) {
+	record := new(MemorySnapshotSchema)
+	err := dao.db.NewSelect().
+		Model(record).
+		Where("session_id = ?", sessionID).
+		Where("turn_uuid = ?", turnUUID).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewNotFoundError("snapshot of turn " + turnUUID.String())
+		}
+		return nil, fmt.Errorf("failed to get memory snapshot: %w", err)
+	}
+	return memorySnapshotSchemaToMemorySnapshot(record), nil
+}
+
+// List returns a session's snapshots, oldest first.
+func (dao *MemorySnapshotStoreDAO) List(
+	ctx context.Context,
+	sessionID string,
+	limit int,
+) ([]*models.MemorySnapshot, error) {
+	var records []MemorySnapshotSchema
+	q := dao.db.NewSelect().
+		Model(&records).
+		Where("session_id = ?", sessionID).
+		Order("created_at ASC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list memory snapshots: %w", err)
+	}
+
+	snapshots := make([]*models.MemorySnapshot, len(records))
+	for i := range records {
+		snapshots[i] = memorySnapshotSchemaToMemorySnapshot(&records[i])
+	}
+	return snapshots, nil
+}
+
+func memorySnapshotSchemaToMemorySnapshot(record *MemorySnapshotSchema) *models.MemorySnapshot {
+	return &models.MemorySnapshot{
+		UUID:      record.UUID,
+		CreatedAt: record.CreatedAt,
+		SessionID: record.SessionID,
+		TurnUUID:  record.TurnUUID,
+		Memory:    record.Memory,
+	}
+}
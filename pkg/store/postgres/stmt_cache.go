@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/uptrace/bun"
+)
+
+// MaxPreparedStatements is the maximum number of prepared statements cached across all
+// connections. The oldest statement is evicted when the cache is full, and closed once it's
+// no longer in use.
+const MaxPreparedStatements = 256
+
+// preparedStatements caches prepared statements for the hot search paths, so that Postgres
+// plans each query shape once rather than on every request.
+var preparedStatements = newStmtCache(MaxPreparedStatements)
+
+type stmtKey struct {
+	db    *bun.DB
+	query string
+}
+
+// cachedStmt is a prepared statement and the number of its callers yet to release it. An
+// evicted statement is closed when the last of them does.
+type cachedStmt struct {
+	stmt    *sql.Stmt
+	users   int
+	evicted bool
+}
+
+type stmtCache struct {
+	mu    sync.Mutex
+	size  int
+	keys  []stmtKey
+	stmts map[stmtKey]*cachedStmt
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size:  size,
+		stmts: make(map[stmtKey]*cachedStmt),
+	}
+}
+
+// prepare returns a cached prepared statement for the query, preparing it if necessary,
+// and a func the caller must call once done with the statement. Statements are safe for
+// concurrent use and are re-prepared by database/sql on each connection as required.
+//
+// The statement is prepared without holding the cache's lock, so that a slow prepare
+// doesn't block other queries. Concurrent callers may then both prepare the query, in
+// which case the first statement cached is used.
+func (c *stmtCache) prepare(
+	ctx context.Context,
+	db *bun.DB,
+	query string,
+) (*sql.Stmt, func(), error) {
+	key := stmtKey{db: db, query: query}
+
+	c.mu.Lock()
+	if cached, ok := c.stmts[key]; ok {
+		cached.users++
+		c.mu.Unlock()
+		return cached.stmt, c.releaser(cached), nil
+	}
+	c.mu.Unlock()
+
+	stmt, err := db.DB.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+
+	var unused []*sql.Stmt
+	c.mu.Lock()
+	cached, ok := c.stmts[key]
+	if ok {
+		unused = append(unused, stmt)
+	} else {
+		if len(c.keys) >= c.size {
+			oldest := c.keys[0]
+			c.keys = c.keys[1:]
+			if evicted := c.evict(oldest); evicted != nil {
+				unused = append(unused, evicted)
+			}
+		}
+		cached = &cachedStmt{stmt: stmt}
+		c.keys = append(c.keys, key)
+		c.stmts[key] = cached
+	}
+	cached.users++
+	c.mu.Unlock()
+
+	closeStmts(unused)
+	return cached.stmt, c.releaser(cached), nil
+}
+
+// evict removes the statement of key from the cache, returning it if it's not in use
+// and should be closed. The caller must hold the lock.
+func (c *stmtCache) evict(key stmtKey) *sql.Stmt {
+	cached := c.stmts[key]
+	delete(c.stmts, key)
+	cached.evicted = true
+	if cached.users > 0 {
+		return nil
+	}
+	return cached.stmt
+}
+
+// releaser returns the func releasing a caller's use of cached.
+func (c *stmtCache) releaser(cached *cachedStmt) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			cached.users--
+			closeEvicted := cached.evicted && cached.users == 0
+			c.mu.Unlock()
+
+			if closeEvicted {
+				closeStmts([]*sql.Stmt{cached.stmt})
+			}
+		})
+	}
+}
+
+// closeDB removes all statements prepared on the given db, closing them once they're no
+// longer in use.
+func (c *stmtCache) closeDB(db *bun.DB) {
+	var unused []*sql.Stmt
+	c.mu.Lock()
+	keys := c.keys[:0]
+	for _, key := range c.keys {
+		if key.db != db {
+			keys = append(keys, key)
+			continue
+		}
+		if evicted := c.evict(key); evicted != nil {
+			unused = append(unused, evicted)
+		}
+	}
+	c.keys = keys
+	c.mu.Unlock()
+
+	closeStmts(unused)
+}
+
+func closeStmts(stmts []*sql.Stmt) {
+	for _, stmt := range stmts {
+		if err := stmt.Close(); err != nil {
+			log.Warnf("failed to close prepared statement: %v", err)
+		}
+	}
+}
+
+// queryParams collects the values of a query built for a prepared statement. Values are
+// replaced in the query with positional placeholders. When disabled, values are returned
+// unchanged and bun formats them into the query.
+type queryParams struct {
+	enabled bool
+	args    []interface{}
+}
+
+func (p *queryParams) add(v interface{}) interface{} {
+	if !p.enabled {
+		return v
+	}
+	return bun.Safe(p.placeholder(v))
+}
+
+func (p *queryParams) placeholder(v interface{}) string {
+	p.args = append(p.args, v)
+	return "$" + strconv.Itoa(len(p.args))
+}
+
+// queryPrepared runs the query using a cached prepared statement and scans the rows into dest.
+// If tx is non-nil, the statement is run in the transaction.
+func queryPrepared(
+	ctx context.Context,
+	db *bun.DB,
+	tx *bun.Tx,
+	query string,
+	args []interface{},
+	dest interface{},
+) error {
+	stmt, release, err := preparedStatements.prepare(ctx, db, query)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if tx != nil {
+		stmt = tx.StmtContext(ctx, stmt)
+		defer stmt.Close()
+	}
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return db.ScanRows(ctx, rows, dest)
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestQueryParams(t *testing.T) {
+	params := &queryParams{enabled: true}
+	assert.Equal(t, bun.Safe("$1"), params.add("a"))
+	assert.Equal(t, "$2", params.placeholder(10))
+	assert.Equal(t, []interface{}{"a", 10}, params.args)
+
+	disabled := &queryParams{}
+	assert.Equal(t, "a", disabled.add("a"))
+	assert.Empty(t, disabled.args)
+}
+
+func TestStmtCache(t *testing.T) {
+	cache := newStmtCache(2)
+
+	first, release, err := cache.prepare(testCtx, testDB, "SELECT $1::int")
+	require.NoError(t, err)
+	release()
+	again, release, err := cache.prepare(testCtx, testDB, "SELECT $1::int")
+	require.NoError(t, err)
+	release()
+	assert.Same(t, first, again)
+
+	// the oldest statement is evicted, and closed once released
+	inUse, releaseInUse, err := cache.prepare(testCtx, testDB, "SELECT $1::int")
+	require.NoError(t, err)
+	for _, query := range []string{"SELECT $1::text", "SELECT $1::bigint"} {
+		_, release, err := cache.prepare(testCtx, testDB, query)
+		require.NoError(t, err)
+		release()
+	}
+	assert.Equal(t, 2, len(cache.stmts))
+	assert.NotContains(t, cache.stmts, stmtKey{db: testDB, query: "SELECT $1::int"})
+
+	var n int
+	require.NoError(t, inUse.QueryRowContext(testCtx, 1).Scan(&n))
+	assert.Equal(t, 1, n)
+	releaseInUse()
+	releaseInUse()
+	assert.Error(t, inUse.QueryRowContext(testCtx, 1).Scan(&n))
+
+	cache.closeDB(testDB)
+	assert.Empty(t, cache.stmts)
+	assert.Empty(t, cache.keys)
+}
+
+// BenchmarkMessageSearchQuery compares building and planning the message search query on
+// each request with running it as a cached prepared statement.
+func BenchmarkMessageSearchQuery(b *testing.B) {
+	sessionID := uuid.NewString()
+	_, err := NewSessionDAO(testDB).Create(
+		testCtx,
+		&models.CreateSessionRequest{SessionID: sessionID},
+	)
+	require.NoError(b, err)
+
+	model, err := llms.GetEmbeddingModel(appState, "message")
+	require.NoError(b, err)
+
+	randomVector := func() []float32 {
+		v := make([]float32, model.Dimensions)
+		for i := range v {
+			v[i] = rand.Float32() //nolint:gosec
+		}
+		return v
+	}
+
+	messageDAO, err := NewMessageDAO(testDB, appState, sessionID)
+	require.NoError(b, err)
+	messages := make([]models.Message, 200)
+	for i := range messages {
+		messages[i] = models.Message{Role: "human", Content: "message"}
+	}
+	messages, err = messageDAO.CreateMany(testCtx, messages)
+	require.NoError(b, err)
+	embeddings := make([]models.TextData, len(messages))
+	for i := range messages {
+		embeddings[i] = models.TextData{TextUUID: messages[i].UUID, Embedding: randomVector()}
+	}
+	require.NoError(b, messageDAO.CreateEmbeddings(testCtx, embeddings))
+
+	vector := pgvector.NewVector(randomVector())
+	search := &models.MemorySearchPayload{SearchScope: models.SearchScopeMessages}
+	build := func(params *queryParams) *bun.SelectQuery {
+		return buildMessageSearchQuery(testCtx, testDB, search, false).
+			ColumnExpr("(embedding <#> ?) * -1 AS dist", params.add(vector)).
+			Where("m.session_id = ?", params.add(sessionID)).
+			Where("m.deleted_at IS NULL").
+			Order("dist DESC")
+	}
+
+	b.Run("dynamic", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := executeMessagesSearchScan(testCtx, build(&queryParams{}).Limit(10))
+			require.NoError(b, err)
+		}
+	})
+
+	b.Run("prepared", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			params := &queryParams{enabled: true}
+			_, err := executeMessagesSearchPrepared(testCtx, testDB, nil, build(params), params, 10)
+			require.NoError(b, err)
+		}
+	})
+}
@@ -16,7 +16,7 @@ import (
 // NewSummaryDAO creates a new SummaryDAO.
 func NewSummaryDAO(db *bun.DB, appState *models.AppState, sessionID string) (*SummaryDAO, error) {
 	if sessionID == "" {
-		return nil, errors.New("sessionID cannot be empty")
+		return nil, models.NewValidationError("sessionID cannot be empty")
 	}
 	return &SummaryDAO{
 		db:        db,
@@ -25,6 +25,8 @@ func NewSummaryDAO(db *bun.DB, appState *models.AppState, sessionID string) (*Su
 	}, nil
 }
 
+var _ models.SummaryDAO = &SummaryDAO{}
+
 type SummaryDAO struct {
 	db        *bun.DB
 	appState  *models.AppState
@@ -36,6 +38,15 @@ type SummaryDAO struct {
 func (s *SummaryDAO) Create(
 	ctx context.Context,
 	summary *models.Summary,
+) (*models.Summary, error) {
+	return withConflictRetry(ctx, "create summary", func(ctx context.Context) (*models.Summary, error) {
+		return s.create(ctx, summary)
+	})
+}
+
+func (s *SummaryDAO) create(
+	ctx context.Context,
+	summary *models.Summary,
 ) (*models.Summary, error) {
 	pgSummary := &SummaryStoreSchema{
 		SessionID:        s.sessionID,
@@ -64,6 +75,16 @@ func (s *SummaryDAO) Update(
 	ctx context.Context,
 	summary *models.Summary,
 	includeContent bool,
+) (*models.Summary, error) {
+	return withRetry(ctx, "update summary", func(ctx context.Context) (*models.Summary, error) {
+		return s.update(ctx, summary, includeContent)
+	})
+}
+
+func (s *SummaryDAO) update(
+	ctx context.Context,
+	summary *models.Summary,
+	includeContent bool,
 ) (*models.Summary, error) {
 	if summary.UUID == uuid.Nil {
 		return nil, errors.New("summary UUID cannot be empty")
@@ -75,7 +96,7 @@ func (s *SummaryDAO) Update(
 	}
 	defer rollbackOnError(tx)
 
-	metadata, err := mergeMetadata(
+	_, err = mergeMetadata(
 		ctx,
 		tx,
 		"uuid",
@@ -91,11 +112,10 @@ func (s *SummaryDAO) Update(
 	pgSummary := &SummaryStoreSchema{
 		UUID:       summary.UUID,
 		Content:    summary.Content,
-		Metadata:   metadata,
 		TokenCount: summary.TokenCount,
 	}
 
-	columns := []string{"metadata", "token_count"}
+	columns := []string{"token_count"}
 	if includeContent {
 		columns = append(columns, "content")
 	}
@@ -175,6 +195,15 @@ func (s *SummaryDAO) GetByUUID(
 func (s *SummaryDAO) PutEmbedding(
 	ctx context.Context,
 	embedding *models.TextData,
+) error {
+	return withRetryErr(ctx, "create summary embedding", func(ctx context.Context) error {
+		return s.putEmbedding(ctx, embedding)
+	})
+}
+
+func (s *SummaryDAO) putEmbedding(
+	ctx context.Context,
+	embedding *models.TextData,
 ) error {
 	record := SummaryVectorStoreSchema{
 		SessionID:   s.sessionID,
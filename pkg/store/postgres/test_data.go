@@ -106,9 +106,9 @@ func GenerateFixtureData(fixtureCount int, outputDir string) {
 		tableName := generateTestTableName(collectionName, embeddingDimensions[0])
 		var indexType models.IndexType
 		if i%2 == 0 {
-			indexType = "ivfflat"
+			indexType = models.IndexTypeIVFFlat
 		} else {
-			indexType = "hnsw"
+			indexType = models.IndexTypeHNSW
 		}
 
 		collections[i] = DocumentCollectionSchema{
@@ -248,8 +248,10 @@ func writeFixtureToYAML[T Row](fixtures Fixtures[T], outputDir, filename string)
 
 func createTestDocumentTables(ctx context.Context, appState *models.AppState, db *bun.DB) error {
 	type Result struct {
-		TableName           string `bun:"table_name"`
-		EmbeddingDimensions int    `bun:"embedding_dimensions"`
+		TableName           string                  `bun:"table_name"`
+		EmbeddingDimensions int                     `bun:"embedding_dimensions"`
+		IndexType           models.IndexType        `bun:"index_type"`
+		DistanceFunction    models.DistanceFunction `bun:"distance_function"`
 	}
 
 	var results []Result
@@ -257,7 +259,7 @@ func createTestDocumentTables(ctx context.Context, appState *models.AppState, db
 	// Query DocumentCollections to get all table names and embedding dimensions
 	err := db.NewSelect().
 		Model((*DocumentCollectionSchema)(nil)).
-		Column("table_name", "embedding_dimensions").
+		Column("table_name", "embedding_dimensions", "index_type", "distance_function").
 		Scan(ctx, &results)
 	if err != nil {
 		return fmt.Errorf("failed to query DocumentCollections: %w", err)
@@ -265,7 +267,15 @@ func createTestDocumentTables(ctx context.Context, appState *models.AppState, db
 
 	// Create tables for each DocumentCollection
 	for _, table := range results {
-		err = createDocumentTable(ctx, appState, db, table.TableName, table.EmbeddingDimensions)
+		err = createDocumentTable(
+			ctx,
+			appState,
+			db,
+			table.TableName,
+			table.EmbeddingDimensions,
+			table.IndexType,
+			table.DistanceFunction,
+		)
 		if err != nil {
 			return fmt.Errorf("failed to create table %s: %w", table.TableName, err)
 		}
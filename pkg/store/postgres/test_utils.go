@@ -23,6 +23,13 @@ func CleanDB(t *testing.T, db *bun.DB) {
 		Exec(context.Background())
 	require.NoError(t, err)
 
+	_, err = db.NewDropTable().
+		Model(&AsyncTaskSchema{}).
+		Cascade().
+		IfExists().
+		Exec(context.Background())
+	require.NoError(t, err)
+
 	_, err = db.NewDropTable().
 		Model(&MessageStoreSchema{}).
 		Cascade().
@@ -35,6 +42,18 @@ func CleanDB(t *testing.T, db *bun.DB) {
 		Cascade().
 		Exec(context.Background())
 	require.NoError(t, err)
+	_, err = db.NewDropTable().
+		Model(&SessionEmbeddingSchema{}).
+		IfExists().
+		Cascade().
+		Exec(context.Background())
+	require.NoError(t, err)
+	_, err = db.NewDropTable().
+		Model(&UserMemoryIndexSchema{}).
+		IfExists().
+		Cascade().
+		Exec(context.Background())
+	require.NoError(t, err)
 	_, err = db.NewDropTable().
 		Model(&SummaryStoreSchema{}).
 		Cascade().
@@ -47,6 +66,36 @@ func CleanDB(t *testing.T, db *bun.DB) {
 		Cascade().
 		Exec(context.Background())
 	require.NoError(t, err)
+	_, err = db.NewDropTable().
+		Model(&MemorySnapshotSchema{}).
+		Cascade().
+		IfExists().
+		Exec(context.Background())
+	require.NoError(t, err)
+	_, err = db.NewDropTable().
+		Model(&ContextProvenanceSchema{}).
+		Cascade().
+		IfExists().
+		Exec(context.Background())
+	require.NoError(t, err)
+	_, err = db.NewDropTable().
+		Model(&SearchQuerySchema{}).
+		Cascade().
+		IfExists().
+		Exec(context.Background())
+	require.NoError(t, err)
+	_, err = db.NewDropTable().
+		Model(&EvalSetSchema{}).
+		Cascade().
+		IfExists().
+		Exec(context.Background())
+	require.NoError(t, err)
+	_, err = db.NewDropTable().
+		Model(&ProviderOverrideSchema{}).
+		Cascade().
+		IfExists().
+		Exec(context.Background())
+	require.NoError(t, err)
 	_, err = db.NewDropTable().
 		Model(&DocumentCollectionSchema{}).
 		Cascade().
@@ -0,0 +1,244 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
+	"github.com/getzep/zep/pkg/store"
+	"github.com/pgvector/pgvector-go"
+	"github.com/uptrace/bun"
+)
+
+// userMemoryIndexFunctions keep user_memory_index in sync with the message embeddings of
+// users' sessions. An embedding is indexed while it's undeleted, not a near-duplicate, and
+// its session is undeleted and has a user. Triggers are used rather than writes by the
+// store, so that embeddings written or deleted by any query, including cascading deletes,
+// are kept in sync.
+const userMemoryIndexFunctions = `
+CREATE OR REPLACE FUNCTION user_memory_index_sync_embedding()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        DELETE FROM user_memory_index WHERE message_uuid = OLD.message_uuid;
+    END IF;
+    IF TG_OP <> 'DELETE'
+        AND NEW.deleted_at IS NULL
+        AND NEW.duplicate_of IS NULL
+        AND NEW.embedding IS NOT NULL THEN
+        INSERT INTO user_memory_index (message_uuid, user_id, session_id, embedding, window_uuids)
+        SELECT NEW.message_uuid, s.user_id, s.session_id, NEW.embedding::vector, NEW.window_uuids
+        FROM session s
+        WHERE s.session_id = NEW.session_id
+          AND s.user_id IS NOT NULL
+          AND s.deleted_at IS NULL
+        ON CONFLICT (message_uuid) DO UPDATE
+            SET user_id      = EXCLUDED.user_id,
+                session_id   = EXCLUDED.session_id,
+                embedding    = EXCLUDED.embedding,
+                window_uuids = EXCLUDED.window_uuids;
+    END IF;
+    RETURN NULL;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION user_memory_index_sync_session()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND NEW.user_id IS NOT DISTINCT FROM OLD.user_id
+        AND NEW.deleted_at IS NOT DISTINCT FROM OLD.deleted_at THEN
+        RETURN NULL;
+    END IF;
+    DELETE FROM user_memory_index WHERE session_id = OLD.session_id;
+    IF TG_OP = 'UPDATE' AND NEW.user_id IS NOT NULL AND NEW.deleted_at IS NULL THEN
+        INSERT INTO user_memory_index (message_uuid, user_id, session_id, embedding, window_uuids)
+        SELECT me.message_uuid, NEW.user_id, NEW.session_id, me.embedding::vector, me.window_uuids
+        FROM message_embedding me
+        WHERE me.session_id = NEW.session_id
+          AND me.deleted_at IS NULL
+          AND me.duplicate_of IS NULL
+          AND me.embedding IS NOT NULL
+        ON CONFLICT (message_uuid) DO NOTHING;
+    END IF;
+    RETURN NULL;
+END;
+$$;
+`
+
+// userMemoryIndexTriggers fire on any change to a row, rather than to the columns read,
+// as the embedding column is dropped when its dimensions are migrated.
+const userMemoryIndexTriggers = `
+CREATE TRIGGER user_memory_index_sync_embedding
+    AFTER INSERT OR UPDATE OR DELETE
+    ON message_embedding
+    FOR EACH ROW
+EXECUTE FUNCTION user_memory_index_sync_embedding();
+
+CREATE TRIGGER user_memory_index_sync_session
+    AFTER UPDATE OR DELETE
+    ON session
+    FOR EACH ROW
+EXECUTE FUNCTION user_memory_index_sync_session();
+`
+
+// userMemoryIndexBackfill indexes the message embeddings written before the triggers were
+// created.
+const userMemoryIndexBackfill = `
+INSERT INTO user_memory_index (message_uuid, user_id, session_id, embedding, window_uuids)
+SELECT me.message_uuid, s.user_id, s.session_id, me.embedding::vector, me.window_uuids
+FROM message_embedding me
+         JOIN session s ON s.session_id = me.session_id
+WHERE s.user_id IS NOT NULL
+  AND s.deleted_at IS NULL
+  AND me.deleted_at IS NULL
+  AND me.duplicate_of IS NULL
+  AND me.embedding IS NOT NULL
+ON CONFLICT (message_uuid) DO NOTHING;
+`
+
+// createUserMemoryIndexTriggers creates or replaces the functions keeping user_memory_index
+// in sync. If the triggers don't exist, such as when the index is first created, they're
+// created and the index is backfilled.
+func createUserMemoryIndexTriggers(ctx context.Context, db *bun.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	// instances starting together would otherwise race to create the triggers
+	if _, err := tx.ExecContext(
+		ctx,
+		"SELECT pg_advisory_xact_lock(hashtext('user_memory_index'))",
+	); err != nil {
+		return fmt.Errorf("failed to lock user memory index: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, userMemoryIndexFunctions); err != nil {
+		return fmt.Errorf("failed to create user memory index functions: %w", err)
+	}
+
+	var exists bool
+	err = tx.NewRaw(
+		"SELECT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'user_memory_index_sync_embedding')",
+	).Scan(ctx, &exists)
+	if err != nil {
+		return fmt.Errorf("failed to check user memory index triggers: %w", err)
+	}
+	if !exists {
+		log.Info("creating user memory index triggers and backfilling the index")
+		if _, err := tx.ExecContext(ctx, userMemoryIndexTriggers); err != nil {
+			return fmt.Errorf("failed to create user memory index triggers: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, userMemoryIndexBackfill); err != nil {
+			return fmt.Errorf("failed to backfill user memory index: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// searchUserMemoryIndex searches the messages of a user's given sessions by the user memory
+// index, in a single query rather than one per session.
+func searchUserMemoryIndex(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	userID string,
+	sessionIDs []string,
+	query *models.MemorySearchPayload,
+	queryEmbedding []float32,
+	limit int,
+) ([]models.MemorySearchResult, error) {
+	if len(sessionIDs) == 0 {
+		return []models.MemorySearchResult{}, nil
+	}
+
+	// the index holds message embeddings
+	df, err := memoryDistanceFunction(appState, "message")
+	if err != nil {
+		return nil, store.NewStorageError("error getting distance function", err)
+	}
+
+	params := &queryParams{}
+	vectorArg := pgvector.NewVector(queryEmbedding)
+	dbQuery := db.NewSelect().TableExpr("user_memory_index AS umi").
+		Join("JOIN message AS m").
+		JoinOn("umi.message_uuid = m.uuid").
+		ColumnExpr("umi.session_id AS session_id").
+		ColumnExpr("m.uuid AS message__uuid").
+		ColumnExpr("m.created_at AS message__created_at").
+		ColumnExpr("m.role AS message__role").
+		ColumnExpr("m.content AS message__content").
+		ColumnExpr("m.metadata AS message__metadata").
+		ColumnExpr("m.token_count AS message__token_count").
+		ColumnExpr("umi.window_uuids AS window_uuids").
+		ColumnExpr(similarityExpr(df)+" AS dist", vectorArg).
+		Where("umi.user_id = ?", userID).
+		Where("umi.session_id IN (?)", bun.In(sessionIDs)).
+		Where("m.deleted_at IS NULL")
+	registry := roles.FromConfig(appState.Config)
+	if unsearchable := registry.Unsearchable(); len(unsearchable) > 0 {
+		dbQuery = dbQuery.Where("lower(m.role) NOT IN (?)", bun.In(unsearchable))
+	}
+	if len(query.Roles) > 0 {
+		dbQuery = dbQuery.Where("lower(m.role) IN (?)", bun.In(registry.Matching(query.Roles)))
+	}
+	if query.MinScore != 0 {
+		dbQuery = dbQuery.Where(similarityExpr(df)+" >= ?", vectorArg, query.MinScore)
+	}
+	if query.Episode != 0 {
+		dbQuery = dbQuery.Where(
+			"m.metadata->'system'->>? = ?",
+			models.EpisodeMetadataKey,
+			strconv.Itoa(query.Episode),
+		)
+	}
+	if len(query.Metadata) > 0 {
+		var err error
+		dbQuery, err = applyMemoryMetadataFilter(dbQuery, query.Metadata, "m")
+		if err != nil {
+			return nil, store.NewStorageError("error applying metadata filter", err)
+		}
+	}
+
+	weight := appState.Config.Memory.Importance.SearchWeight
+	rankExpr, rankArgs := memoryRankExpr(query, "m", df, vectorArg, weight, params)
+	results, err := executeMessagesSearchScan(
+		ctx,
+		dbQuery.
+			ColumnExpr(rankExpr+" AS rank_score", rankArgs...).
+			OrderExpr("rank_score DESC, m.uuid DESC").
+			Limit(limit),
+	)
+	if err != nil {
+		return nil, store.NewStorageError("user memory search failed", err)
+	}
+	results = filterValidMessageSearchResults(results, query.Metadata)
+	models.SearchDebugFromContext(ctx).SetDistances(memoryResultDistances(results, df))
+
+	if query.Explain {
+		if err := explainMemoryResults(ctx, db, query, "m", weight, results); err != nil {
+			return nil, store.NewStorageError("error explaining results", err)
+		}
+	}
+	// windows are loaded by session
+	for i := range results {
+		err := loadResultWindows(ctx, db, results[i].SessionID, results[i:i+1])
+		if err != nil {
+			return nil, store.NewStorageError("error loading result windows", err)
+		}
+	}
+	return results, nil
+}
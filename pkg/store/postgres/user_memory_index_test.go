@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+func TestUserMemoryIndex(t *testing.T) {
+	userID := testutils.GenerateRandomString(16)
+	_, err := NewUserStoreDAO(testDB).Create(testCtx, &models.CreateUserRequest{UserID: userID})
+	require.NoError(t, err)
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	require.NoError(t, err)
+	_, err = appState.MemoryStore.CreateSession(testCtx, &models.CreateSessionRequest{
+		SessionID: sessionID,
+		UserID:    &userID,
+	})
+	require.NoError(t, err)
+
+	indexed := func() int {
+		count, err := testDB.NewSelect().
+			Model((*UserMemoryIndexSchema)(nil)).
+			Where("user_id = ?", userID).
+			Where("session_id = ?", sessionID).
+			Count(testCtx)
+		require.NoError(t, err)
+		return count
+	}
+
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, false,
+	)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return indexed() == len(testutils.TestMessages)
+	}, 10*time.Second, 500*time.Millisecond)
+
+	// messages of sessions without a user aren't indexed
+	_, err = testDB.NewUpdate().
+		Model((*SessionSchema)(nil)).
+		Set("user_id = NULL").
+		Where("session_id = ?", sessionID).
+		Exec(testCtx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, indexed())
+
+	_, err = testDB.NewUpdate().
+		Model((*SessionSchema)(nil)).
+		Set("user_id = ?", userID).
+		Where("session_id = ?", sessionID).
+		Exec(testCtx)
+	require.NoError(t, err)
+	assert.Equal(t, len(testutils.TestMessages), indexed())
+
+	require.NoError(t, appState.MemoryStore.DeleteSession(testCtx, sessionID))
+	assert.Equal(t, 0, indexed())
+}
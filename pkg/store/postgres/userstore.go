@@ -6,10 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
-	"time"
 
-	"github.com/failsafe-go/failsafe-go"
-	"github.com/failsafe-go/failsafe-go/retrypolicy"
 	"github.com/getzep/zep/pkg/models"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/driver/pgdriver"
@@ -31,9 +28,18 @@ func NewUserStoreDAO(db *bun.DB) *UserStoreDAO {
 func (dao *UserStoreDAO) Create(
 	ctx context.Context,
 	user *models.CreateUserRequest,
+) (*models.User, error) {
+	return withConflictRetry(ctx, "create user", func(ctx context.Context) (*models.User, error) {
+		return dao.create(ctx, user)
+	})
+}
+
+func (dao *UserStoreDAO) create(
+	ctx context.Context,
+	user *models.CreateUserRequest,
 ) (*models.User, error) {
 	if user.UserID == "" {
-		return nil, models.NewBadRequestError("UserID cannot be empty")
+		return nil, models.NewValidationError("UserID cannot be empty")
 	}
 	userDB := &UserSchema{
 		UserID:    user.UserID,
@@ -45,7 +51,7 @@ func (dao *UserStoreDAO) Create(
 	_, err := dao.db.NewInsert().Model(userDB).Returning("*").Exec(ctx)
 	if err != nil {
 		if err, ok := err.(pgdriver.Error); ok && err.IntegrityViolation() {
-			return nil, models.NewBadRequestError(
+			return nil, models.NewConflictError(
 				"user already exists with user_id: " + user.UserID,
 			)
 		}
@@ -85,46 +91,42 @@ func (dao *UserStoreDAO) Update(
 	ctx context.Context,
 	user *models.UpdateUserRequest,
 	isPrivileged bool,
+) (*models.User, error) {
+	return withRetry(ctx, "update user", func(ctx context.Context) (*models.User, error) {
+		return dao.update(ctx, user, isPrivileged)
+	})
+}
+
+func (dao *UserStoreDAO) update(
+	ctx context.Context,
+	user *models.UpdateUserRequest,
+	isPrivileged bool,
 ) (*models.User, error) {
 	if user.UserID == "" {
-		return nil, errors.New("UserID cannot be empty")
+		return nil, models.NewValidationError("UserID cannot be empty")
 	}
 
 	// if metadata is null, we can keep this a cheap operation
 	if user.Metadata == nil {
-		return dao.updateUser(ctx, user)
+		if err := updateUser(ctx, dao.db, user); err != nil {
+			return nil, err
+		}
+		return dao.getUpdated(ctx, user.UserID)
 	}
 
-	// Acquire a lock for this UserID. This is to prevent concurrent updates
-	// to the session metadata.
-	lockRetryPolicy := retrypolicy.Builder[any]().
-		HandleErrors(models.ErrLockAcquisitionFailed).
-		WithBackoff(200*time.Millisecond, 10*time.Second).
-		WithMaxRetries(7).
-		Build()
-
-	lockIDVal, err := failsafe.Get(func() (any, error) {
-		return tryAcquireAdvisoryLock(ctx, dao.db, user.UserID)
-	}, lockRetryPolicy)
+	tx, err := dao.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to acquire advisory lock: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer rollbackOnError(tx)
 
-	lockID, ok := lockIDVal.(uint64)
-	if !ok {
-		return nil, fmt.Errorf("failed to acquire advisory lock: %w", models.ErrLockAcquisitionFailed)
+	if err := updateUser(ctx, tx, user); err != nil {
+		return nil, err
 	}
 
-	defer func(ctx context.Context, db bun.IDB, lockID uint64) {
-		err := releaseAdvisoryLock(ctx, db, lockID)
-		if err != nil {
-			log.Errorf("failed to release advisory lock: %v", err)
-		}
-	}(ctx, dao.db, lockID)
-
-	mergedMetadata, err := mergeMetadata(
+	_, err = mergeMetadata(
 		ctx,
-		dao.db,
+		tx,
 		"user_id",
 		user.UserID,
 		"users",
@@ -135,40 +137,98 @@ func (dao *UserStoreDAO) Update(
 		return nil, fmt.Errorf("failed to merge metadata: %w", err)
 	}
 
-	user.Metadata = mergedMetadata
-	return dao.updateUser(ctx, user)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return dao.getUpdated(ctx, user.UserID)
 }
 
-func (dao *UserStoreDAO) updateUser(
+// PatchMetadata applies patch to the metadata of an undeleted user, locking the user's row
+// while the patch is applied.
+func (dao *UserStoreDAO) PatchMetadata(
 	ctx context.Context,
-	user *models.UpdateUserRequest,
+	userID string,
+	patch models.MetadataPatch,
+	isPrivileged bool,
 ) (*models.User, error) {
+	return withRetry(ctx, "patch user metadata", func(ctx context.Context) (*models.User, error) {
+		return dao.patchMetadata(ctx, userID, patch, isPrivileged)
+	})
+}
+
+func (dao *UserStoreDAO) patchMetadata(
+	ctx context.Context,
+	userID string,
+	patch models.MetadataPatch,
+	isPrivileged bool,
+) (*models.User, error) {
+	tx, err := dao.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	user := new(UserSchema)
+	err = tx.NewSelect().Model(user).Where("user_id = ?", userID).For("UPDATE").Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewNotFoundError("user " + userID)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user.Metadata, err = models.PatchMetadata(user.Metadata, patch, isPrivileged)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.NewUpdate().Model(user).Column("metadata", "updated_at").WherePK().Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user metadata: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return userSchemaToUser(user), nil
+}
+
+// updateUser updates a user's email and name. The user's metadata is not updated.
+// See mergeMetadata.
+func updateUser(
+	ctx context.Context,
+	db bun.IDB,
+	user *models.UpdateUserRequest,
+) error {
 	userDB := UserSchema{
 		Email:     user.Email,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
-		Metadata:  user.Metadata,
 	}
-	r, err := dao.db.NewUpdate().
+	r, err := db.NewUpdate().
 		Model(&userDB).
-		Column("email", "first_name", "last_name", "metadata", "updated_at").
+		Column("email", "first_name", "last_name", "updated_at").
 		OmitZero().
 		Where("user_id = ?", user.UserID).
 		Exec(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	rowsAffected, err := r.RowsAffected()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if rowsAffected == 0 {
-		return nil, models.NewNotFoundError("user " + user.UserID)
+		return models.NewNotFoundError("user " + user.UserID)
 	}
 
-	// We're can't return the updated User above as we're using OmitZero,
-	// so we need to get the updated user from the DB
-	updatedUserDB, err := dao.Get(ctx, user.UserID)
+	return nil
+}
+
+// getUpdated returns a user after an update. We can't return the updated User from the
+// update as we're using OmitZero, so we need to get the updated user from the DB.
+func (dao *UserStoreDAO) getUpdated(ctx context.Context, userID string) (*models.User, error) {
+	updatedUserDB, err := dao.Get(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -188,7 +248,8 @@ func (dao *UserStoreDAO) updateUser(
 	return updatedUser, nil
 }
 
-// Delete deletes a user.
+// Delete deletes a user and the user's sessions. Returns a ConflictError if the user or
+// any of the sessions is under legal hold.
 func (dao *UserStoreDAO) Delete(ctx context.Context, userID string) error {
 	// Start a new transaction
 	tx, err := dao.db.Begin()
@@ -197,6 +258,11 @@ func (dao *UserStoreDAO) Delete(ctx context.Context, userID string) error {
 	}
 	defer rollbackOnError(tx)
 
+	// Refuse to delete anything if the user or any of the user's sessions is held
+	if err := checkUserHold(ctx, tx, userID); err != nil {
+		return err
+	}
+
 	// Delete all related sessions
 	sessions, err := dao.GetSessions(ctx, userID)
 	if err != nil {
@@ -233,6 +299,104 @@ func (dao *UserStoreDAO) Delete(ctx context.Context, userID string) error {
 	return nil
 }
 
+// Anonymize re-keys an undeleted user, and all of the user's sessions, to anonymousID,
+// clears the user's email and name, and replaces the user's metadata.
+func (dao *UserStoreDAO) Anonymize(
+	ctx context.Context,
+	userID string,
+	anonymousID string,
+	metadata map[string]interface{},
+) (*models.User, error) {
+	if anonymousID == "" {
+		return nil, models.NewValidationError("anonymous ID cannot be empty")
+	}
+	err := withRetryErr(ctx, "anonymize user", func(ctx context.Context) error {
+		return dao.anonymize(ctx, userID, anonymousID, metadata)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dao.Get(ctx, anonymousID)
+}
+
+func (dao *UserStoreDAO) anonymize(
+	ctx context.Context,
+	userID string,
+	anonymousID string,
+	metadata map[string]interface{},
+) error {
+	tx, err := dao.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(tx)
+
+	if err := checkUserHold(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	// The sessions' user_id references the user's, so the sessions are detached from the
+	// user while it's re-keyed.
+	var sessionIDs []string
+	err = tx.NewUpdate().
+		Model((*SessionSchema)(nil)).
+		Set("user_id = NULL").
+		Set("updated_at = current_timestamp").
+		Where("user_id = ?", userID).
+		WhereAllWithDeleted().
+		Returning("session_id").
+		Scan(ctx, &sessionIDs)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to detach sessions: %w", err)
+	}
+
+	q := tx.NewUpdate().
+		Model((*UserSchema)(nil)).
+		Set("user_id = ?", anonymousID).
+		Set("email = ''").
+		Set("first_name = ''").
+		Set("last_name = ''").
+		Set("updated_at = current_timestamp").
+		Where("user_id = ?", userID)
+	if len(metadata) == 0 {
+		q = q.Set("metadata = NULL")
+	} else {
+		q = q.Set("metadata = ?::jsonb", metadata)
+	}
+	r, err := q.Exec(ctx)
+	if err != nil {
+		if err, ok := err.(pgdriver.Error); ok && err.IntegrityViolation() {
+			return models.NewConflictError("user already exists with user_id: " + anonymousID)
+		}
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.NewNotFoundError("user " + userID)
+	}
+
+	if len(sessionIDs) > 0 {
+		_, err = tx.NewUpdate().
+			Model((*SessionSchema)(nil)).
+			Set("user_id = ?", anonymousID).
+			Where("session_id IN (?)", bun.In(sessionIDs)).
+			WhereAllWithDeleted().
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to re-key sessions: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // ListAll lists all users. The cursor is used to paginate results.
 func (dao *UserStoreDAO) ListAll(
 	ctx context.Context,
@@ -349,6 +513,7 @@ func (dao *UserStoreDAO) GetSessions(
 			SessionID: sessionsDB[i].SessionID,
 			Metadata:  sessionsDB[i].Metadata,
 			UserID:    sessionsDB[i].UserID,
+			ExpiresAt: sessionsDB[i].ExpiresAt,
 		}
 	}
 	return sessions, nil
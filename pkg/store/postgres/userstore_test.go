@@ -194,6 +194,58 @@ func TestUserStoreDAO(t *testing.T) {
 
 }
 
+func TestUserStoreDAO_Anonymize(t *testing.T) {
+	ctx := context.Background()
+	userStore := NewUserStoreDAO(testDB)
+	sessionStore := NewSessionDAO(testDB)
+
+	userID := testutils.GenerateRandomString(16)
+	_, err := userStore.Create(ctx, &models.CreateUserRequest{
+		UserID:    userID,
+		Email:     "jane@example.com",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Metadata:  map[string]interface{}{"plan": "pro", "phone": "555-0100"},
+	})
+	assert.NoError(t, err)
+	sessionID := testutils.GenerateRandomString(16)
+	_, err = sessionStore.Create(ctx, &models.CreateSessionRequest{
+		SessionID: sessionID,
+		UserID:    &userID,
+	})
+	assert.NoError(t, err)
+
+	anonymousID := testutils.GenerateRandomString(16)
+	user, err := userStore.Anonymize(
+		ctx,
+		userID,
+		anonymousID,
+		map[string]interface{}{"plan": "pro"},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, anonymousID, user.UserID)
+	assert.Empty(t, user.Email)
+	assert.Empty(t, user.FirstName)
+	assert.Empty(t, user.LastName)
+	assert.Equal(t, map[string]interface{}{"plan": "pro"}, user.Metadata)
+
+	_, err = userStore.Get(ctx, userID)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+	session, err := sessionStore.Get(ctx, sessionID)
+	assert.NoError(t, err)
+	assert.Equal(t, anonymousID, *session.UserID)
+
+	// the anonymous ID of another user can't be reused
+	otherID := testutils.GenerateRandomString(16)
+	_, err = userStore.Create(ctx, &models.CreateUserRequest{UserID: otherID})
+	assert.NoError(t, err)
+	_, err = userStore.Anonymize(ctx, otherID, anonymousID, nil)
+	assert.ErrorIs(t, err, models.ErrConflict)
+
+	_, err = userStore.Anonymize(ctx, userID, testutils.GenerateRandomString(16), nil)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
 func TestUserStoreDAO_ListAll(t *testing.T) {
 	CleanDB(t, testDB)
 	err := CreateSchema(testCtx, appState, testDB)
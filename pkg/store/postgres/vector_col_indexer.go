@@ -4,10 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math"
 	"sync"
 	"time"
 
+	"github.com/getzep/zep/pkg/llms"
 	"github.com/getzep/zep/pkg/models"
 
 	"github.com/uptrace/bun"
@@ -56,14 +56,7 @@ func (vci *VectorColIndex) CalculateListCount() error {
 		return fmt.Errorf("rows must be greater than 0")
 	}
 
-	switch {
-	case vci.RowCount <= 1000:
-		vci.ListCount = 1
-	case vci.RowCount <= 1_000_000:
-		vci.ListCount = vci.RowCount / 1000
-	default:
-		vci.ListCount = int(math.Sqrt(float64(vci.RowCount)))
-	}
+	vci.ListCount = ivfflatListCount(vci.RowCount)
 
 	return nil
 }
@@ -73,7 +66,7 @@ func (vci *VectorColIndex) CalculateProbes() error {
 	if vci.ListCount <= 0 {
 		return errors.New("lists must be greater than 0")
 	}
-	vci.ProbeCount = int(math.Sqrt(float64(vci.ListCount)))
+	vci.ProbeCount = ivfflatProbeCount(vci.ListCount)
 
 	return nil
 }
@@ -86,8 +79,8 @@ func (vci *VectorColIndex) CreateIndex(_ context.Context, force bool) error {
 	// Lock the mutex for this collection.
 	IndexMutexMap[vci.Collection.Name].Lock()
 
-	if vci.Collection.DistanceFunction != "cosine" {
-		return fmt.Errorf("only cosine distance function is currently supported")
+	if err := validateDistanceFunction(vci.Collection.DistanceFunction); err != nil {
+		return err
 	}
 
 	// If this is not a forced index creation, check if there are enough rows to create an index.
@@ -100,7 +93,11 @@ func (vci *VectorColIndex) CreateIndex(_ context.Context, force bool) error {
 		return fmt.Errorf("failed to get bun.DB db")
 	}
 
-	indexName := fmt.Sprintf("%s_%s_idx", vci.Collection.TableName, vci.ColName)
+	indexName := ivfflatIndexName(
+		vci.Collection.TableName,
+		vci.ColName,
+		vci.Collection.DistanceFunction,
+	)
 
 	// run index creation in a goroutine with IndexTimeout
 	go func() {
@@ -121,12 +118,24 @@ func (vci *VectorColIndex) CreateIndex(_ context.Context, force bool) error {
 			return
 		}
 
-		// currently only supports cosine distance ops
+		model, err := llms.GetEmbeddingModel(vci.appState, "document")
+		if err != nil {
+			log.Error("error getting document embedding model: ", err)
+			return
+		}
+
 		log.Infof("Starting index creation on %s", vci.Collection.Name)
 		_, err = db.ExecContext(
 			ctx,
-			"CREATE INDEX CONCURRENTLY ON ? USING ivfflat (embedding vector_cosine_ops) WITH (lists = ?)",
+			"CREATE INDEX CONCURRENTLY ? ON ? USING ivfflat (?) WITH (lists = ?)",
+			bun.Ident(indexName),
 			bun.Ident(vci.Collection.TableName),
+			bun.Safe(vectorIndexExpr(
+				vci.ColName,
+				vci.Collection.EmbeddingDimensions,
+				model.Quantization,
+				vci.Collection.DistanceFunction,
+			)),
 			vci.ListCount,
 		)
 		if err != nil {
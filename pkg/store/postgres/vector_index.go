@@ -0,0 +1,279 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/uptrace/bun"
+
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// memoryEmbeddingTables are the memory embedding tables indexed by createMemoryIndexes,
+// by the type of document embedded in them.
+var memoryEmbeddingTables = map[string]string{
+	"message": "message_embedding",
+	"summary": "summary_embedding",
+}
+
+// memoryIndex is the vector index of a memory embedding table.
+type memoryIndex struct {
+	indexType models.IndexType
+	// probeCount is the ivfflat.probes of searches of an IVFFlat index, or 0 if the table
+	// isn't indexed.
+	probeCount int
+}
+
+// memoryIndexes records the vector indexes of the memory embedding tables, as created by
+// createMemoryIndexes when the server starts.
+var memoryIndexes = struct {
+	sync.RWMutex
+	tables map[string]memoryIndex
+}{tables: make(map[string]memoryIndex)}
+
+func getMemoryIndex(table string) memoryIndex {
+	memoryIndexes.RLock()
+	defer memoryIndexes.RUnlock()
+	return memoryIndexes.tables[table]
+}
+
+func setMemoryIndex(table string, index memoryIndex) {
+	memoryIndexes.Lock()
+	defer memoryIndexes.Unlock()
+	memoryIndexes.tables[table] = index
+}
+
+// ivfflatIndexName returns the name of the IVFFlat index on a column, by the distance
+// function it's built for.
+func ivfflatIndexName(table, column string, df models.DistanceFunction) string {
+	return table + "_" + column + indexNameSuffix(df) + "_idx"
+}
+
+// ivfflatListCount returns the number of lists of an IVFFlat index of the given number of
+// rows, as recommended by the pgvector docs.
+func ivfflatListCount(rows int) int {
+	switch {
+	case rows <= 1000:
+		return 1
+	case rows <= 1_000_000:
+		return rows / 1000
+	default:
+		return int(math.Sqrt(float64(rows)))
+	}
+}
+
+// ivfflatProbeCount returns the ivfflat.probes of searches of an index with the given
+// number of lists.
+func ivfflatProbeCount(lists int) int {
+	return int(math.Sqrt(float64(lists)))
+}
+
+// efSearch returns the configured hnsw.ef_search.
+func efSearch(appState *models.AppState) int {
+	if ef := appState.Config.Store.Postgres.VectorIndex.EFSearch; ef > 0 {
+		return ef
+	}
+	return DefaultEFSearch
+}
+
+// memoryIndexType returns the configured index type of the memory embedding tables.
+func memoryIndexType(appState *models.AppState) (models.IndexType, error) {
+	hnswAvailable := appState.Config.Store.Postgres.AvailableIndexes.HSNW
+	switch indexType := models.IndexType(appState.Config.Store.Postgres.VectorIndex.Type); indexType {
+	case "":
+		if hnswAvailable {
+			return models.IndexTypeHNSW, nil
+		}
+		return models.IndexTypeIVFFlat, nil
+	case models.IndexTypeHNSW:
+		if !hnswAvailable {
+			return "", fmt.Errorf("hnsw indexes require pgvector 0.5.0 or later")
+		}
+		return indexType, nil
+	case models.IndexTypeIVFFlat:
+		return indexType, nil
+	default:
+		return "", fmt.Errorf(
+			"store.postgres.vector_index.type must be hnsw or ivfflat, not %s",
+			indexType,
+		)
+	}
+}
+
+// createMemoryIndexes creates the configured type of vector index on the message and
+// summary embeddings, for their distance functions, dropping any other vector index on
+// them. IVFFlat indexes are only created once a table has MinRowsForIndex rows, as their
+// lists are sized for the rows indexed.
+func createMemoryIndexes(ctx context.Context, appState *models.AppState, db *bun.DB) error {
+	indexType, err := memoryIndexType(appState)
+	if err != nil {
+		return err
+	}
+
+	for documentType, table := range memoryEmbeddingTables {
+		model, err := llms.GetEmbeddingModel(appState, documentType)
+		if err != nil {
+			return fmt.Errorf("error getting %s embedding model: %w", documentType, err)
+		}
+		df, err := modelDistanceFunction(model)
+		if err != nil {
+			return err
+		}
+		if err := validateMemoryQuantization(documentType, model.Quantization); err != nil {
+			return err
+		}
+
+		keep := ivfflatIndexName(table, EmbeddingColName, df)
+		if indexType == models.IndexTypeHNSW {
+			keep = hnswIndexName(table, EmbeddingColName, model.Quantization, df)
+		}
+		for _, name := range vectorIndexNames(table, EmbeddingColName) {
+			if name == keep {
+				continue
+			}
+			if err := dropIndex(ctx, db, name); err != nil {
+				return err
+			}
+		}
+
+		index := memoryIndex{indexType: indexType}
+		if indexType == models.IndexTypeHNSW {
+			err = createHNSWIndex(
+				ctx,
+				db,
+				table,
+				EmbeddingColName,
+				model.Dimensions,
+				model.Quantization,
+				df,
+			)
+			if err != nil {
+				return fmt.Errorf("error creating hnsw index: %w", err)
+			}
+		} else {
+			index.probeCount, err = createIVFFlatIndex(ctx, db, table, model, df)
+			if err != nil {
+				return fmt.Errorf("error creating ivfflat index: %w", err)
+			}
+		}
+		setMemoryIndex(table, index)
+	}
+
+	return nil
+}
+
+// createIVFFlatIndex creates an IVFFlat index for the distance function on the table's
+// embeddings if it doesn't exist and the table has enough rows, returning the
+// ivfflat.probes of searches of the index, or 0 if the table isn't indexed.
+func createIVFFlatIndex(
+	ctx context.Context,
+	db *bun.DB,
+	table string,
+	model *models.EmbeddingModel,
+	df models.DistanceFunction,
+) (int, error) {
+	indexName := ivfflatIndexName(table, EmbeddingColName, df)
+
+	var lists int
+	err := db.NewRaw(
+		`SELECT coalesce((
+			SELECT option_value::int FROM pg_class, pg_options_to_table(reloptions)
+			WHERE pg_class.oid = to_regclass(?) AND option_name = 'lists'
+		), 0)`,
+		indexName,
+	).Scan(ctx, &lists)
+	if err != nil {
+		return 0, fmt.Errorf("error getting lists of %s: %w", indexName, err)
+	}
+	if lists > 0 {
+		return ivfflatProbeCount(lists), nil
+	}
+
+	rows, err := db.NewSelect().Table(table).Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error counting rows of %s: %w", table, err)
+	}
+	if rows < MinRowsForIndex {
+		log.Infof(
+			"%s has %d rows. not creating an ivfflat index until it has %d",
+			table,
+			rows,
+			MinRowsForIndex,
+		)
+		return 0, nil
+	}
+
+	dimensions := model.Dimensions
+	if dimensions == 0 {
+		dimensions = defaultEmbeddingDims
+	}
+	lists = ivfflatListCount(rows)
+	log.Infof("creating ivfflat index on %s with %d lists", table, lists)
+	_, err = db.ExecContext(
+		ctx,
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS ? ON ? USING ivfflat (?) WITH (lists = ?)",
+		bun.Ident(indexName),
+		bun.Ident(table),
+		bun.Safe(vectorIndexExpr(EmbeddingColName, dimensions, model.Quantization, df)),
+		lists,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return ivfflatProbeCount(lists), nil
+}
+
+func dropIndex(ctx context.Context, db *bun.DB, indexName string) error {
+	_, err := db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS ?", bun.Ident(indexName))
+	if err != nil {
+		return fmt.Errorf("error dropping index %s: %w", indexName, err)
+	}
+	return nil
+}
+
+// setMemoryIndexSettings sets the search settings of the table's vector index for the
+// transaction: hnsw.ef_search for an HNSW index, or ivfflat.probes for an IVFFlat index.
+// Exact searches don't use the index.
+func setMemoryIndexSettings(
+	ctx context.Context,
+	tx bun.Tx,
+	appState *models.AppState,
+	table string,
+	exact bool,
+) error {
+	debug := models.SearchDebugFromContext(ctx)
+	index := getMemoryIndex(table)
+	switch {
+	case exact:
+		return disableIndexScans(ctx, tx)
+	case index.indexType == models.IndexTypeHNSW:
+		ef := efSearch(appState)
+		if _, err := tx.ExecContext(ctx, "SET LOCAL hnsw.ef_search = ?", ef); err != nil {
+			return fmt.Errorf("error setting ef_search: %w", err)
+		}
+		debug.SetSetting("hnsw.ef_search", strconv.Itoa(ef))
+	case index.probeCount > 0:
+		_, err := tx.ExecContext(ctx, "SET LOCAL ivfflat.probes = ?", index.probeCount)
+		if err != nil {
+			return fmt.Errorf("error setting probes: %w", err)
+		}
+		debug.SetSetting("ivfflat.probes", strconv.Itoa(index.probeCount))
+	}
+	return nil
+}
+
+// disableIndexScans disables index scans for the transaction, so that a vector search
+// compares the query with every embedding rather than searching an approximate index.
+// Filters are then applied by bitmap scans, which vector indexes don't support.
+func disableIndexScans(ctx context.Context, tx bun.Tx) error {
+	if _, err := tx.ExecContext(ctx, "SET LOCAL enable_indexscan = off"); err != nil {
+		return fmt.Errorf("error disabling index scans: %w", err)
+	}
+	models.SearchDebugFromContext(ctx).SetSetting("enable_indexscan", "off")
+	return nil
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestMemoryIndexType(t *testing.T) {
+	tests := []struct {
+		name          string
+		indexType     string
+		hnsw          bool
+		want          models.IndexType
+		expectedError string
+	}{
+		{name: "default with hnsw", hnsw: true, want: models.IndexTypeHNSW},
+		{name: "default without hnsw", want: models.IndexTypeIVFFlat},
+		{name: "ivfflat", indexType: "ivfflat", hnsw: true, want: models.IndexTypeIVFFlat},
+		{name: "hnsw", indexType: "hnsw", hnsw: true, want: models.IndexTypeHNSW},
+		{name: "hnsw unavailable", indexType: "hnsw", expectedError: "pgvector 0.5.0"},
+		{name: "unknown", indexType: "diskann", hnsw: true, expectedError: "must be hnsw or ivfflat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Store.Postgres.VectorIndex.Type = tt.indexType
+			cfg.Store.Postgres.AvailableIndexes.HSNW = tt.hnsw
+
+			got, err := memoryIndexType(&models.AppState{Config: cfg})
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIVFFlatCounts(t *testing.T) {
+	assert.Equal(t, 1, ivfflatListCount(500))
+	assert.Equal(t, 10, ivfflatListCount(10_000))
+	assert.Equal(t, 2000, ivfflatListCount(4_000_000))
+	assert.Equal(t, 1, ivfflatProbeCount(1))
+	assert.Equal(t, 3, ivfflatProbeCount(10))
+}
+
+func TestEFSearch(t *testing.T) {
+	cfg := &config.Config{}
+	assert.Equal(t, DefaultEFSearch, efSearch(&models.AppState{Config: cfg}))
+	cfg.Store.Postgres.VectorIndex.EFSearch = 200
+	assert.Equal(t, 200, efSearch(&models.AppState{Config: cfg}))
+}
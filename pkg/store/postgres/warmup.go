@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/uptrace/bun"
+)
+
+const (
+	DefaultWarmupSessions = 100
+	DefaultWarmupTimeout  = 60 * time.Second
+)
+
+// WarmupResult counts what a warm-up loaded.
+type WarmupResult struct {
+	// Indexes is the number of vector indexes prewarmed. It's 0 if pg_prewarm isn't
+	// available.
+	Indexes int
+	// Sessions is the number of sessions whose memory was read.
+	Sessions int
+}
+
+// vectorIndexesQuery lists the HNSW and IVFFlat indexes of the current schema, including
+// those of document collections.
+const vectorIndexesQuery = `
+SELECT c.oid::regclass::text
+FROM pg_class c
+         JOIN pg_am am ON am.oid = c.relam
+         JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind = 'i'
+  AND am.amname IN ('hnsw', 'ivfflat')
+  AND n.nspname = current_schema()
+ORDER BY 1
+`
+
+// Warmup loads the vector indexes into the buffer cache, and reads the memory of the most
+// recently updated sessions, so that the first requests after startup don't read them
+// from disk. It's bounded by the configured timeout, after which what was loaded is
+// returned along with the context's error.
+func Warmup(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+) (*WarmupResult, error) {
+	cfg := appState.Config.Store.Postgres.Warmup
+	timeout := DefaultWarmupTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	sessions := cfg.Sessions
+	if sessions <= 0 {
+		sessions = DefaultWarmupSessions
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := &WarmupResult{}
+	indexes, err := prewarmVectorIndexes(ctx, db)
+	result.Indexes = indexes
+	if err != nil {
+		return result, err
+	}
+	result.Sessions, err = warmRecentSessions(ctx, appState, db, sessions)
+	return result, err
+}
+
+// prewarmVectorIndexes loads the vector indexes using pg_prewarm, creating the extension
+// if needed. Managed databases may not allow it to be created, in which case no indexes
+// are loaded and no error is returned.
+func prewarmVectorIndexes(ctx context.Context, db *bun.DB) (int, error) {
+	if _, err := db.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS pg_prewarm"); err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		log.Warnf("pg_prewarm is unavailable, vector indexes won't be prewarmed: %v", err)
+		return 0, nil
+	}
+
+	var indexes []string
+	if err := db.NewRaw(vectorIndexesQuery).Scan(ctx, &indexes); err != nil {
+		return 0, fmt.Errorf("failed to list vector indexes: %w", err)
+	}
+	for i, index := range indexes {
+		var blocks int64
+		if err := db.NewRaw("SELECT pg_prewarm(?::regclass)", index).Scan(ctx, &blocks); err != nil {
+			return i, fmt.Errorf("failed to prewarm index %s: %w", index, err)
+		}
+		log.Debugf("prewarmed index %s: %d blocks", index, blocks)
+	}
+	return len(indexes), nil
+}
+
+// warmRecentSessions reads the memory of up to limit of the most recently updated
+// sessions, as a GetMemory request would.
+func warmRecentSessions(
+	ctx context.Context,
+	appState *models.AppState,
+	db *bun.DB,
+	limit int,
+) (int, error) {
+	var sessionIDs []string
+	err := db.NewSelect().
+		Model((*SessionSchema)(nil)).
+		Column("session_id").
+		OrderExpr("updated_at DESC").
+		Limit(limit).
+		Scan(ctx, &sessionIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list recent sessions: %w", err)
+	}
+
+	for i, sessionID := range sessionIDs {
+		memoryDAO, err := NewMemoryDAO(db, appState, sessionID)
+		if err != nil {
+			return i, fmt.Errorf("failed to create memoryDAO: %w", err)
+		}
+		if _, err := memoryDAO.Get(ctx, 0); err != nil {
+			return i, fmt.Errorf("failed to read memory of session %s: %w", sessionID, err)
+		}
+	}
+	return len(sessionIDs), nil
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+func TestWarmup(t *testing.T) {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	require.NoError(t, err)
+	_, err = appState.MemoryStore.CreateSession(testCtx, &models.CreateSessionRequest{
+		SessionID: sessionID,
+	})
+	require.NoError(t, err)
+	err = appState.MemoryStore.PutMemory(testCtx, sessionID,
+		&models.Memory{Messages: testutils.TestMessages}, true,
+	)
+	require.NoError(t, err)
+
+	original := appState.Config.Store.Postgres.Warmup
+	t.Cleanup(func() { appState.Config.Store.Postgres.Warmup = original })
+	appState.Config.Store.Postgres.Warmup.Sessions = 1
+
+	result, err := Warmup(testCtx, appState, testDB)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Sessions)
+	assert.GreaterOrEqual(t, result.Indexes, 0)
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(testCtx)
+		cancel()
+		result, err := Warmup(ctx, appState, testDB)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0, result.Sessions)
+	})
+}
@@ -0,0 +1,317 @@
+// Package qdrant stores the embeddings of document collections in Qdrant, using its REST
+// API. Each document collection has a Qdrant collection, created when embeddings are first
+// put, whose points are the collection's documents, identified by their UUIDs.
+package qdrant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	RetryMax       = 3
+	// APIKeyHeader authenticates requests if an API key is configured.
+	APIKeyHeader = "api-key"
+)
+
+var _ models.VectorStore = &VectorStore{}
+
+// VectorStore is a models.VectorStore backed by Qdrant.
+type VectorStore struct {
+	url    string
+	apiKey string
+	prefix string
+	client *http.Client
+
+	mu sync.Mutex
+	// collections are the names of the Qdrant collections known to exist.
+	collections map[string]bool
+}
+
+// NewVectorStore returns a VectorStore using the Qdrant of cfg.
+func NewVectorStore(cfg *config.QdrantConfig) (*VectorStore, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("store.vector_store.qdrant.url is required")
+	}
+	timeout := DefaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	return &VectorStore{
+		url:         strings.TrimSuffix(cfg.URL, "/"),
+		apiKey:      cfg.APIKey,
+		prefix:      cfg.CollectionPrefix,
+		client:      llms.NewRetryableHTTPClient(RetryMax, timeout),
+		collections: make(map[string]bool),
+	}, nil
+}
+
+type point struct {
+	ID     uuid.UUID `json:"id"`
+	Vector []float32 `json:"vector"`
+}
+
+func (s *VectorStore) Put(
+	ctx context.Context,
+	collection *models.DocumentCollection,
+	records []models.VectorRecord,
+) error {
+	if len(records) == 0 {
+		return nil
+	}
+	name := s.collectionName(collection)
+	if err := s.ensureCollection(ctx, name, collection); err != nil {
+		return err
+	}
+
+	points := make([]point, len(records))
+	for i := range records {
+		points[i] = point{ID: records[i].UUID, Vector: records[i].Embedding}
+	}
+	err := s.do(
+		ctx,
+		http.MethodPut,
+		"/collections/"+name+"/points?wait=true",
+		map[string]any{"points": points},
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to put embeddings: %w", err)
+	}
+	return nil
+}
+
+func (s *VectorStore) Delete(
+	ctx context.Context,
+	collection *models.DocumentCollection,
+	uuids []uuid.UUID,
+) error {
+	name := s.collectionName(collection)
+	if uuids == nil {
+		err := s.do(ctx, http.MethodDelete, "/collections/"+name, nil, nil)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to delete collection %s: %w", name, err)
+		}
+		s.mu.Lock()
+		delete(s.collections, name)
+		s.mu.Unlock()
+		return nil
+	}
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	err := s.do(
+		ctx,
+		http.MethodPost,
+		"/collections/"+name+"/points/delete?wait=true",
+		map[string]any{"points": uuids},
+		nil,
+	)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete embeddings: %w", err)
+	}
+	return nil
+}
+
+type searchResult struct {
+	ID     uuid.UUID `json:"id"`
+	Score  float64   `json:"score"`
+	Vector []float32 `json:"vector"`
+}
+
+func (s *VectorStore) Search(
+	ctx context.Context,
+	collection *models.DocumentCollection,
+	query *models.VectorQuery,
+) ([]models.VectorResult, error) {
+	request := map[string]any{
+		"vector":       query.Embedding,
+		"limit":        query.Limit,
+		"with_payload": false,
+		"with_vector":  query.WithEmbeddings,
+	}
+	if query.Exact {
+		request["params"] = map[string]any{"exact": true}
+	}
+
+	var found []searchResult
+	err := s.do(
+		ctx,
+		http.MethodPost,
+		"/collections/"+s.collectionName(collection)+"/points/search",
+		request,
+		&found,
+	)
+	if err != nil {
+		// nothing has been embedded in the collection yet
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+
+	results := make([]models.VectorResult, len(found))
+	for i := range found {
+		results[i] = models.VectorResult{
+			UUID:      found[i].ID,
+			Score:     score(collection.DistanceFunction, found[i].Score),
+			Embedding: found[i].Vector,
+		}
+	}
+	return results, nil
+}
+
+// ensureCollection creates the Qdrant collection name for collection, unless it exists.
+func (s *VectorStore) ensureCollection(
+	ctx context.Context,
+	name string,
+	collection *models.DocumentCollection,
+) error {
+	s.mu.Lock()
+	exists := s.collections[name]
+	s.mu.Unlock()
+	if exists {
+		return nil
+	}
+
+	err := s.do(ctx, http.MethodGet, "/collections/"+name, nil, nil)
+	if isNotFound(err) {
+		err = s.do(ctx, http.MethodPut, "/collections/"+name, map[string]any{
+			"vectors": map[string]any{
+				"size":     collection.EmbeddingDimensions,
+				"distance": distance(collection.DistanceFunction),
+			},
+		}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create collection %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	s.collections[name] = true
+	s.mu.Unlock()
+	return nil
+}
+
+// collectionName returns the name of the Qdrant collection of collection. Table names are
+// unique, and, unlike collection names, include the embedding dimensions.
+func (s *VectorStore) collectionName(collection *models.DocumentCollection) string {
+	return s.prefix + collection.TableName
+}
+
+// statusError is returned for unsuccessful responses.
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("qdrant returned status %d: %s", e.status, e.message)
+}
+
+func isNotFound(err error) bool {
+	var e *statusError
+	return errors.As(err, &e) && e.status == http.StatusNotFound
+}
+
+// do sends a request to the Qdrant API, decoding the result of the response into result,
+// if not nil.
+func (s *VectorStore) do(
+	ctx context.Context,
+	method, path string,
+	body any,
+	result any,
+) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.url+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.apiKey != "" {
+		req.Header.Set(APIKeyHeader, s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to qdrant: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Status json.RawMessage `json:"status"`
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		// errors are reported as {"status": {"error": "..."}}
+		var status struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&response) == nil {
+			_ = json.Unmarshal(response.Status, &status)
+		}
+		return &statusError{status: resp.StatusCode, message: status.Error}
+	}
+	if result == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode qdrant response: %w", err)
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("failed to decode qdrant result: %w", err)
+	}
+	return nil
+}
+
+// distance returns the Qdrant distance of a distance function.
+func distance(df models.DistanceFunction) string {
+	switch df {
+	case models.DistanceFunctionDotProduct:
+		return "Dot"
+	case models.DistanceFunctionL2:
+		return "Euclid"
+	default:
+		return "Cosine"
+	}
+}
+
+// score converts a Qdrant score to the score of document search results: the cosine
+// similarity normalized to between 0 and 1, the inner product, or 1 / (1 + the Euclidean
+// distance), of which Qdrant returns the distance.
+func score(df models.DistanceFunction, qdrantScore float64) float64 {
+	switch df {
+	case models.DistanceFunctionDotProduct:
+		return qdrantScore
+	case models.DistanceFunctionL2:
+		return 1 / (1 + qdrantScore)
+	default:
+		return (qdrantScore + 1) / 2
+	}
+}
@@ -0,0 +1,213 @@
+package qdrant
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// fakeQdrant implements the parts of the Qdrant REST API used by VectorStore, scoring
+// points by cosine similarity.
+type fakeQdrant struct {
+	mu          sync.Mutex
+	collections map[string]map[string][]float32
+	sizes       map[string]int
+	apiKeys     []string
+}
+
+func newFakeQdrant(t *testing.T) (*fakeQdrant, *httptest.Server) {
+	f := &fakeQdrant{
+		collections: make(map[string]map[string][]float32),
+		sizes:       make(map[string]int),
+	}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeQdrant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.apiKeys = append(f.apiKeys, r.Header.Get(APIKeyHeader))
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/collections/"), "/")
+	name := parts[0]
+	points, exists := f.collections[name]
+	reply := func(status int, result any) {
+		w.WriteHeader(status)
+		if status == http.StatusNotFound {
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": map[string]any{
+				"error": "Not found: Collection `" + name + "` doesn't exist!",
+			}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": "ok", "result": result})
+	}
+	if !exists && !(len(parts) == 1 && r.Method == http.MethodPut) {
+		reply(http.StatusNotFound, nil)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		reply(http.StatusOK, map[string]any{"status": "green"})
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var req struct {
+			Vectors struct {
+				Size int `json:"size"`
+			} `json:"vectors"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.collections[name] = make(map[string][]float32)
+		f.sizes[name] = req.Vectors.Size
+		reply(http.StatusOK, true)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		delete(f.collections, name)
+		reply(http.StatusOK, true)
+	case parts[1] == "points" && len(parts) == 2:
+		var req struct {
+			Points []point `json:"points"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for _, p := range req.Points {
+			points[p.ID.String()] = p.Vector
+		}
+		reply(http.StatusOK, map[string]any{"status": "completed"})
+	case parts[2] == "delete":
+		var req struct {
+			Points []string `json:"points"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for _, id := range req.Points {
+			delete(points, id)
+		}
+		reply(http.StatusOK, map[string]any{"status": "completed"})
+	case parts[2] == "search":
+		var req struct {
+			Vector     []float32 `json:"vector"`
+			Limit      int       `json:"limit"`
+			WithVector bool      `json:"with_vector"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var results []map[string]any
+		for id, v := range points {
+			result := map[string]any{"id": id, "score": cosine(req.Vector, v)}
+			if req.WithVector {
+				result["vector"] = v
+			}
+			results = append(results, result)
+		}
+		sort.Slice(results, func(i, j int) bool {
+			return results[i]["score"].(float64) > results[j]["score"].(float64)
+		})
+		reply(http.StatusOK, results[:min(req.Limit, len(results))])
+	default:
+		reply(http.StatusNotFound, nil)
+	}
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i] * b[i])
+		na += float64(a[i] * a[i])
+		nb += float64(b[i] * b[i])
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func TestVectorStore(t *testing.T) {
+	ctx := context.Background()
+	fake, server := newFakeQdrant(t)
+	store, err := NewVectorStore(&config.QdrantConfig{
+		URL:              server.URL + "/",
+		APIKey:           "secret",
+		CollectionPrefix: "zep_",
+	})
+	require.NoError(t, err)
+	collection := &models.DocumentCollection{
+		TableName:           "docstore_test_3",
+		EmbeddingDimensions: 3,
+		DistanceFunction:    models.DistanceFunctionCosine,
+	}
+
+	// searches of collections without embeddings are empty
+	results, err := store.Search(
+		ctx,
+		collection,
+		&models.VectorQuery{Embedding: []float32{1, 0, 0}, Limit: 2},
+	)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	same, orthogonal, opposite := uuid.New(), uuid.New(), uuid.New()
+	err = store.Put(ctx, collection, []models.VectorRecord{
+		{UUID: same, Embedding: []float32{1, 0, 0}},
+		{UUID: orthogonal, Embedding: []float32{0, 1, 0}},
+		{UUID: opposite, Embedding: []float32{-1, 0, 0}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, fake.sizes["zep_docstore_test_3"])
+
+	results, err = store.Search(ctx, collection, &models.VectorQuery{
+		Embedding:      []float32{1, 0, 0},
+		Limit:          2,
+		WithEmbeddings: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, same, results[0].UUID)
+	// cosine similarities are normalized to between 0 and 1
+	assert.InDelta(t, 1, results[0].Score, 1e-6)
+	assert.Equal(t, []float32{1, 0, 0}, results[0].Embedding)
+	assert.Equal(t, orthogonal, results[1].UUID)
+	assert.InDelta(t, 0.5, results[1].Score, 1e-6)
+
+	require.NoError(t, store.Delete(ctx, collection, []uuid.UUID{same}))
+	results, err = store.Search(
+		ctx,
+		collection,
+		&models.VectorQuery{Embedding: []float32{1, 0, 0}, Limit: 3},
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Nil(t, results[0].Embedding)
+
+	// deleting every embedding drops the Qdrant collection, which is recreated by the
+	// next put
+	require.NoError(t, store.Delete(ctx, collection, nil))
+	assert.NotContains(t, fake.collections, "zep_docstore_test_3")
+	require.NoError(t, store.Delete(ctx, collection, nil))
+	require.NoError(t, store.Put(ctx, collection, []models.VectorRecord{
+		{UUID: same, Embedding: []float32{1, 0, 0}},
+	}))
+	assert.Len(t, fake.collections["zep_docstore_test_3"], 1)
+
+	for _, key := range fake.apiKeys {
+		assert.Equal(t, "secret", key)
+	}
+}
+
+func TestScore(t *testing.T) {
+	assert.InDelta(t, 0.75, score(models.DistanceFunctionCosine, 0.5), 1e-9)
+	assert.InDelta(t, 3.5, score(models.DistanceFunctionDotProduct, 3.5), 1e-9)
+	assert.InDelta(t, 0.25, score(models.DistanceFunctionL2, 3), 1e-9)
+}
+
+func TestNewVectorStoreRequiresURL(t *testing.T) {
+	_, err := NewVectorStore(&config.QdrantConfig{})
+	assert.Error(t, err)
+}
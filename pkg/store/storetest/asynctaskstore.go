@@ -0,0 +1,197 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+// AsyncTaskStoreFactory returns the AsyncTaskStore under test.
+type AsyncTaskStoreFactory func(t *testing.T) models.AsyncTaskStore
+
+// RunAsyncTaskStoreTests runs the AsyncTaskStore conformance suite. newStore is called
+// once per subtest. Each subtest uses its own task type, so the store may hold other
+// tasks.
+func RunAsyncTaskStoreTests(t *testing.T, newStore AsyncTaskStoreFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s models.AsyncTaskStore)
+	}{
+		{"create and get", testAsyncTaskCreateGet},
+		{"list", testAsyncTaskList},
+		{"update", testAsyncTaskUpdate},
+		{"cancel", testAsyncTaskCancel},
+		{"fail abandoned", testAsyncTaskFailAbandoned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newTaskType() string {
+	return "storetest_" + testutils.GenerateRandomString(16)
+}
+
+func testAsyncTaskCreateGet(t *testing.T, s models.AsyncTaskStore) {
+	ctx := context.Background()
+	taskType := newTaskType()
+
+	task, err := s.Create(ctx, taskType, map[string]interface{}{"collection": "c"})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, task.UUID)
+	assert.Equal(t, taskType, task.Type)
+	assert.Equal(t, models.AsyncTaskPending, task.Status)
+	assert.False(t, task.CreatedAt.IsZero())
+	assert.Nil(t, task.StartedAt)
+	assert.Nil(t, task.FinishedAt)
+
+	got, err := s.Get(ctx, task.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, task.UUID, got.UUID)
+	assert.Equal(t, "c", got.Params["collection"])
+
+	_, err = s.Get(ctx, uuid.New())
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+
+	_, err = s.Create(ctx, "", nil)
+	assert.True(t, errors.Is(err, models.ErrBadRequest))
+}
+
+func testAsyncTaskList(t *testing.T, s models.AsyncTaskStore) {
+	ctx := context.Background()
+	taskType := newTaskType()
+
+	var created []*models.AsyncTask
+	for i := 0; i < 3; i++ {
+		task, err := s.Create(ctx, taskType, nil)
+		require.NoError(t, err)
+		created = append(created, task)
+	}
+	_, err := s.Cancel(ctx, created[0].UUID)
+	require.NoError(t, err)
+
+	tasks, err := s.List(ctx, models.AsyncTaskListOptions{Type: taskType})
+	require.NoError(t, err)
+	require.Len(t, tasks, 3)
+	assert.Equal(t, created[2].UUID, tasks[0].UUID, "newest first")
+	assert.Equal(t, created[0].UUID, tasks[2].UUID)
+
+	tasks, err = s.List(ctx, models.AsyncTaskListOptions{
+		Type:   taskType,
+		Status: models.AsyncTaskCanceled,
+	})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, created[0].UUID, tasks[0].UUID)
+
+	tasks, err = s.List(ctx, models.AsyncTaskListOptions{Type: taskType, Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+}
+
+func testAsyncTaskUpdate(t *testing.T, s models.AsyncTaskStore) {
+	ctx := context.Background()
+
+	task, err := s.Create(ctx, newTaskType(), nil)
+	require.NoError(t, err)
+
+	startedAt := time.Now().UTC()
+	task.Status = models.AsyncTaskRunning
+	task.StartedAt = &startedAt
+	task.Completed = 2
+	task.Total = 10
+	updated, err := s.Update(ctx, task)
+	require.NoError(t, err)
+	assert.Equal(t, models.AsyncTaskRunning, updated.Status)
+	assert.Equal(t, 2, updated.Completed)
+	assert.Equal(t, 10, updated.Total)
+	require.NotNil(t, updated.StartedAt)
+	assert.WithinDuration(t, startedAt, *updated.StartedAt, time.Second)
+
+	finishedAt := time.Now().UTC()
+	task.Status = models.AsyncTaskSucceeded
+	task.FinishedAt = &finishedAt
+	task.Completed = 10
+	task.Result = map[string]interface{}{"documents": float64(10)}
+	_, err = s.Update(ctx, task)
+	require.NoError(t, err)
+
+	got, err := s.Get(ctx, task.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AsyncTaskSucceeded, got.Status)
+	assert.Equal(t, float64(10), got.Result["documents"])
+	assert.NotNil(t, got.FinishedAt)
+
+	task.Status = models.AsyncTaskRunning
+	_, err = s.Update(ctx, task)
+	assert.True(t, errors.Is(err, models.ErrConflict), "a finished task can't be updated")
+
+	task.UUID = uuid.New()
+	_, err = s.Update(ctx, task)
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+}
+
+func testAsyncTaskCancel(t *testing.T, s models.AsyncTaskStore) {
+	ctx := context.Background()
+
+	task, err := s.Create(ctx, newTaskType(), nil)
+	require.NoError(t, err)
+
+	canceled, err := s.Cancel(ctx, task.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AsyncTaskCanceled, canceled.Status)
+	assert.NotNil(t, canceled.FinishedAt)
+
+	_, err = s.Cancel(ctx, task.UUID)
+	assert.True(t, errors.Is(err, models.ErrConflict))
+
+	task.Status = models.AsyncTaskRunning
+	_, err = s.Update(ctx, task)
+	assert.True(t, errors.Is(err, models.ErrConflict), "a canceled task can't be updated")
+
+	_, err = s.Cancel(ctx, uuid.New())
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+}
+
+func testAsyncTaskFailAbandoned(t *testing.T, s models.AsyncTaskStore) {
+	ctx := context.Background()
+	taskType := newTaskType()
+
+	abandoned, err := s.Create(ctx, taskType, nil)
+	require.NoError(t, err)
+	finished, err := s.Create(ctx, taskType, nil)
+	require.NoError(t, err)
+	_, err = s.Cancel(ctx, finished.UUID)
+	require.NoError(t, err)
+
+	// tasks updated before the cutoff are abandoned
+	n, err := s.FailAbandoned(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, n, 0)
+	got, err := s.Get(ctx, abandoned.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AsyncTaskPending, got.Status)
+
+	n, err = s.FailAbandoned(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, n, 1)
+
+	got, err = s.Get(ctx, abandoned.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AsyncTaskFailed, got.Status)
+	assert.NotEmpty(t, got.Error)
+
+	got, err = s.Get(ctx, finished.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AsyncTaskCanceled, got.Status)
+}
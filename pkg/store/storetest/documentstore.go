@@ -0,0 +1,291 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+// testEmbeddingDimensions is the embedding dimensions of the collections created by the
+// DocumentStore suite. Documents are created with their embeddings, so the suite doesn't
+// depend on an embedding service.
+const testEmbeddingDimensions = 8
+
+// DocumentStoreFactory returns the DocumentStore under test.
+type DocumentStoreFactory func(t *testing.T) models.DocumentStore[any]
+
+// RunDocumentStoreTests runs the DocumentStore conformance suite. newStore is called once
+// per subtest.
+func RunDocumentStoreTests(t *testing.T, newStore DocumentStoreFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ds models.DocumentStore[any])
+	}{
+		{"collections", testCollections},
+		{"collection not found", testCollectionNotFound},
+		{"documents", testDocuments},
+		{"document validation", testDocumentValidation},
+		{"search", testSearchCollection},
+		{"delete collection", testDeleteCollection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newCollectionName() string {
+	return "storetest" + testutils.GenerateRandomString(16)
+}
+
+// createCollection creates a collection of manually embedded documents and returns its
+// name.
+func createCollection(t *testing.T, ds models.DocumentStore[any]) string {
+	name := newCollectionName()
+	err := ds.CreateCollection(context.Background(), models.DocumentCollection{
+		Name:                name,
+		Description:         "conformance test collection",
+		EmbeddingDimensions: testEmbeddingDimensions,
+		IsAutoEmbedded:      false,
+	})
+	require.NoError(t, err)
+	return name
+}
+
+// unitVector returns an embedding with 1 at index i and 0 elsewhere.
+func unitVector(i int) []float32 {
+	v := make([]float32, testEmbeddingDimensions)
+	v[i] = 1
+	return v
+}
+
+func testCollections(t *testing.T, ds models.DocumentStore[any]) {
+	ctx := context.Background()
+	name := createCollection(t, ds)
+
+	err := ds.CreateCollection(ctx, models.DocumentCollection{
+		Name:                name,
+		EmbeddingDimensions: testEmbeddingDimensions,
+	})
+	assert.ErrorIs(t, err, models.ErrConflict)
+
+	collection, err := ds.GetCollection(ctx, name)
+	require.NoError(t, err)
+	assert.Equal(t, name, collection.Name)
+	assert.Equal(t, testEmbeddingDimensions, collection.EmbeddingDimensions)
+	assert.False(t, collection.IsAutoEmbedded)
+
+	collection.Description = "updated"
+	collection.Metadata = map[string]interface{}{"a": "1"}
+	err = ds.UpdateCollection(ctx, collection)
+	require.NoError(t, err)
+
+	collection, err = ds.GetCollection(ctx, name)
+	require.NoError(t, err)
+	assert.Equal(t, "updated", collection.Description)
+	assert.Equal(t, map[string]interface{}{"a": "1"}, collection.Metadata)
+
+	collections, err := ds.GetCollectionList(ctx)
+	require.NoError(t, err)
+	names := make([]string, len(collections))
+	for i, c := range collections {
+		names[i] = c.Name
+	}
+	assert.Contains(t, names, name)
+}
+
+func testCollectionNotFound(t *testing.T, ds models.DocumentStore[any]) {
+	ctx := context.Background()
+	name := newCollectionName()
+
+	_, err := ds.GetCollection(ctx, name)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	err = ds.DeleteCollection(ctx, name)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	_, err = ds.GetCollection(ctx, "")
+	assert.ErrorIs(t, err, models.ErrValidation)
+
+	_, err = ds.CreateDocuments(ctx, "", []models.Document{{Embedding: unitVector(0)}})
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func testDocuments(t *testing.T, ds models.DocumentStore[any]) {
+	ctx := context.Background()
+	name := createCollection(t, ds)
+
+	documents := []models.Document{
+		{
+			DocumentBase: models.DocumentBase{DocumentID: "doc1", Content: "one"},
+			Embedding:    unitVector(0),
+		},
+		{
+			DocumentBase: models.DocumentBase{DocumentID: "doc2", Content: "two"},
+			Embedding:    unitVector(1),
+		},
+	}
+	uuids, err := ds.CreateDocuments(ctx, name, documents)
+	require.NoError(t, err)
+	require.Len(t, uuids, 2)
+
+	got, err := ds.GetDocuments(ctx, name, uuids, nil)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	contents := make([]string, len(got))
+	for i, d := range got {
+		contents[i] = d.Content
+		assert.Len(t, d.Embedding, testEmbeddingDimensions)
+	}
+	assert.ElementsMatch(t, []string{"one", "two"}, contents)
+
+	got, err = ds.GetDocuments(ctx, name, nil, []string{"doc2"})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, uuids[1], got[0].UUID)
+
+	// document IDs are unique within a collection
+	_, err = ds.CreateDocuments(ctx, name, []models.Document{{
+		DocumentBase: models.DocumentBase{DocumentID: "doc1", Content: "duplicate"},
+		Embedding:    unitVector(2),
+	}})
+	assert.ErrorIs(t, err, models.ErrConflict)
+
+	err = ds.UpdateDocuments(ctx, name, []models.Document{{
+		DocumentBase: models.DocumentBase{
+			UUID:     uuids[0],
+			Metadata: map[string]interface{}{"a": "1"},
+		},
+	}})
+	require.NoError(t, err)
+
+	got, err = ds.GetDocuments(ctx, name, []uuid.UUID{uuids[0]}, nil)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, map[string]interface{}{"a": "1"}, got[0].Metadata)
+	assert.Equal(t, "one", got[0].Content)
+
+	// updated content is stored with its new embedding, leaving the metadata unchanged
+	err = ds.UpdateDocuments(ctx, name, []models.Document{{
+		DocumentBase: models.DocumentBase{UUID: uuids[0], Content: "uno"},
+		Embedding:    unitVector(3),
+	}})
+	require.NoError(t, err)
+
+	got, err = ds.GetDocuments(ctx, name, []uuid.UUID{uuids[0]}, nil)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "uno", got[0].Content)
+	assert.Equal(t, unitVector(3), got[0].Embedding)
+	assert.True(t, got[0].IsEmbedded)
+	assert.Equal(t, map[string]interface{}{"a": "1"}, got[0].Metadata)
+
+	err = ds.UpdateDocuments(ctx, name, []models.Document{{
+		DocumentBase: models.DocumentBase{UUID: uuid.New(), DocumentID: "missing"},
+	}})
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	err = ds.DeleteDocuments(ctx, name, []uuid.UUID{uuids[0]})
+	require.NoError(t, err)
+
+	_, err = ds.GetDocuments(ctx, name, []uuid.UUID{uuids[0]}, nil)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	err = ds.DeleteDocuments(ctx, name, []uuid.UUID{uuid.New()})
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func testDocumentValidation(t *testing.T, ds models.DocumentStore[any]) {
+	ctx := context.Background()
+	name := createCollection(t, ds)
+
+	// documents in a manually embedded collection must have embeddings
+	_, err := ds.CreateDocuments(ctx, name, []models.Document{{
+		DocumentBase: models.DocumentBase{Content: "no embedding"},
+	}})
+	assert.ErrorIs(t, err, models.ErrValidation)
+
+	autoEmbedded := newCollectionName()
+	err = ds.CreateCollection(ctx, models.DocumentCollection{
+		Name:                autoEmbedded,
+		EmbeddingDimensions: testEmbeddingDimensions,
+		IsAutoEmbedded:      true,
+	})
+	require.NoError(t, err)
+
+	// documents in an auto-embedded collection can't have embeddings
+	_, err = ds.CreateDocuments(ctx, autoEmbedded, []models.Document{{
+		DocumentBase: models.DocumentBase{Content: "embedded"},
+		Embedding:    unitVector(0),
+	}})
+	assert.ErrorIs(t, err, models.ErrValidation)
+
+	// new content in a manually embedded collection needs a new embedding
+	uuids, err := ds.CreateDocuments(ctx, name, []models.Document{{
+		DocumentBase: models.DocumentBase{Content: "before"},
+		Embedding:    unitVector(0),
+	}})
+	require.NoError(t, err)
+	err = ds.UpdateDocuments(ctx, name, []models.Document{{
+		DocumentBase: models.DocumentBase{UUID: uuids[0], Content: "after"},
+	}})
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func testSearchCollection(t *testing.T, ds models.DocumentStore[any]) {
+	ctx := context.Background()
+	name := createCollection(t, ds)
+
+	documents := make([]models.Document, 3)
+	for i := range documents {
+		documents[i] = models.Document{
+			DocumentBase: models.DocumentBase{Content: []string{"zero", "one", "two"}[i]},
+			Embedding:    unitVector(i),
+		}
+	}
+	_, err := ds.CreateDocuments(ctx, name, documents)
+	require.NoError(t, err)
+
+	query := unitVector(1)
+	query[2] = 0.5
+	page, err := ds.SearchCollection(ctx, &models.DocumentSearchPayload{
+		CollectionName: name,
+		Embedding:      query,
+	}, 2, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Results, 2)
+	assert.Equal(t, "one", page.Results[0].Content)
+	assert.Equal(t, "two", page.Results[1].Content)
+	assert.GreaterOrEqual(t, page.Results[0].Score, page.Results[1].Score)
+}
+
+func testDeleteCollection(t *testing.T, ds models.DocumentStore[any]) {
+	ctx := context.Background()
+	name := createCollection(t, ds)
+
+	_, err := ds.CreateDocuments(ctx, name, []models.Document{{
+		DocumentBase: models.DocumentBase{Content: "one"},
+		Embedding:    unitVector(0),
+	}})
+	require.NoError(t, err)
+
+	err = ds.DeleteCollection(ctx, name)
+	require.NoError(t, err)
+
+	_, err = ds.GetCollection(ctx, name)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	// the name can be reused once the collection is deleted
+	err = ds.CreateCollection(ctx, models.DocumentCollection{
+		Name:                name,
+		EmbeddingDimensions: testEmbeddingDimensions,
+	})
+	require.NoError(t, err)
+}
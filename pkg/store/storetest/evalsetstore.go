@@ -0,0 +1,97 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+// EvalSetStoreFactory returns the EvalSetStore under test.
+type EvalSetStoreFactory func(t *testing.T) models.EvalSetStore
+
+// RunEvalSetStoreTests runs the EvalSetStore conformance suite. newStore is called once
+// per subtest. Each subtest uses its own eval set names, so the store may hold other sets.
+func RunEvalSetStoreTests(t *testing.T, newStore EvalSetStoreFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s models.EvalSetStore)
+	}{
+		{"put and get", testEvalSetPutGet},
+		{"list and delete", testEvalSetListDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newEvalSet() *models.EvalSet {
+	return &models.EvalSet{
+		Name:        "storetest" + testutils.GenerateRandomString(16),
+		Description: "labeled queries",
+		Cases: []models.EvalCase{
+			{Query: "pizza", SessionID: "s1", Relevant: []string{"a", "b"}},
+			{Query: "pasta", CollectionName: "c1", Relevant: []string{"c"}},
+		},
+	}
+}
+
+func testEvalSetPutGet(t *testing.T, s models.EvalSetStore) {
+	ctx := context.Background()
+	set := newEvalSet()
+
+	created, err := s.Put(ctx, set)
+	require.NoError(t, err)
+	assert.Equal(t, set.Name, created.Name)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	got, err := s.Get(ctx, set.Name)
+	require.NoError(t, err)
+	assert.Equal(t, set.Description, got.Description)
+	assert.Equal(t, set.Cases, got.Cases)
+
+	// putting a set of the same name replaces its cases
+	set.Cases = set.Cases[:1]
+	set.Description = ""
+	replaced, err := s.Put(ctx, set)
+	require.NoError(t, err)
+	assert.Equal(t, created.CreatedAt.Unix(), replaced.CreatedAt.Unix())
+	got, err = s.Get(ctx, set.Name)
+	require.NoError(t, err)
+	assert.Empty(t, got.Description)
+	assert.Len(t, got.Cases, 1)
+
+	_, err = s.Get(ctx, "storetestmissing")
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func testEvalSetListDelete(t *testing.T, s models.EvalSetStore) {
+	ctx := context.Background()
+	first, second := newEvalSet(), newEvalSet()
+	_, err := s.Put(ctx, first)
+	require.NoError(t, err)
+	_, err = s.Put(ctx, second)
+	require.NoError(t, err)
+
+	names := func() []string {
+		sets, err := s.List(ctx)
+		require.NoError(t, err)
+		var names []string
+		for _, set := range sets {
+			names = append(names, set.Name)
+		}
+		return names
+	}
+	assert.Subset(t, names(), []string{first.Name, second.Name})
+	assert.IsNonDecreasing(t, names())
+
+	require.NoError(t, s.Delete(ctx, first.Name))
+	assert.NotContains(t, names(), first.Name)
+	assert.ErrorIs(t, s.Delete(ctx, first.Name), models.ErrNotFound)
+}
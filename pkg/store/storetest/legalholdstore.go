@@ -0,0 +1,219 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// LegalHoldStoreFactory returns the LegalHoldStore under test, and a MemoryStore and
+// UserStore sharing its database.
+type LegalHoldStoreFactory func(
+	t *testing.T,
+) (models.MemoryStore[any], models.UserStore, models.LegalHoldStore)
+
+// RunLegalHoldStoreTests runs the LegalHoldStore conformance suite. newStores is called
+// once per subtest.
+func RunLegalHoldStoreTests(t *testing.T, newStores LegalHoldStoreFactory) {
+	tests := []struct {
+		name string
+		fn   func(
+			t *testing.T,
+			ms models.MemoryStore[any],
+			us models.UserStore,
+			hs models.LegalHoldStore,
+		)
+	}{
+		{"place and lift", testLegalHoldPlaceLift},
+		{"held session", testLegalHoldSession},
+		{"held user", testLegalHoldUser},
+		{"expired sessions", testLegalHoldExpiredSessions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms, us, hs := newStores(t)
+			tt.fn(t, ms, us, hs)
+		})
+	}
+}
+
+func placeHold(
+	t *testing.T,
+	hs models.LegalHoldStore,
+	subjectType, subjectID string,
+) *models.LegalHold {
+	hold, err := hs.Place(context.Background(), &models.LegalHold{
+		SubjectType: subjectType,
+		SubjectID:   subjectID,
+		Reason:      "litigation",
+		PlacedBy:    "counsel",
+	})
+	require.NoError(t, err)
+	return hold
+}
+
+func testLegalHoldPlaceLift(
+	t *testing.T,
+	ms models.MemoryStore[any],
+	_ models.UserStore,
+	hs models.LegalHoldStore,
+) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+	putMessages(t, ms, sessionID, "one")
+
+	hold := placeHold(t, hs, models.LegalHoldSubjectSession, sessionID)
+	assert.Equal(t, "litigation", hold.Reason)
+	assert.False(t, hold.PlacedAt.IsZero())
+
+	got, err := hs.Get(ctx, models.LegalHoldSubjectSession, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "counsel", got.PlacedBy)
+
+	holds, err := hs.List(ctx)
+	require.NoError(t, err)
+	var listed bool
+	for _, h := range holds {
+		listed = listed || h.SubjectID == sessionID
+	}
+	assert.True(t, listed)
+
+	err = hs.Lift(ctx, models.LegalHoldSubjectSession, sessionID, "admin", "settled")
+	require.NoError(t, err)
+	_, err = hs.Get(ctx, models.LegalHoldSubjectSession, sessionID)
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+	err = hs.Lift(ctx, models.LegalHoldSubjectSession, sessionID, "admin", "settled")
+	assert.True(t, errors.Is(err, models.ErrNotFound), "a lifted hold can't be lifted again")
+
+	events, err := hs.ListEvents(ctx, models.LegalHoldSubjectSession, sessionID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.LegalHoldPlaced, events[0].Action)
+	assert.Equal(t, "counsel", events[0].Actor)
+	assert.Equal(t, "litigation", events[0].Reason)
+	assert.Equal(t, models.LegalHoldLifted, events[1].Action)
+	assert.Equal(t, "admin", events[1].Actor)
+	assert.Equal(t, "settled", events[1].Reason)
+}
+
+func testLegalHoldSession(
+	t *testing.T,
+	ms models.MemoryStore[any],
+	_ models.UserStore,
+	hs models.LegalHoldStore,
+) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+	messages := putMessages(t, ms, sessionID, "one", "two")
+	placeHold(t, hs, models.LegalHoldSubjectSession, sessionID)
+
+	err := ms.DeleteSession(ctx, sessionID)
+	assert.True(t, errors.Is(err, models.ErrConflict))
+	_, err = ms.DeleteMatchingMessages(ctx, sessionID, &models.MessageFilter{ContentRegex: "one"})
+	assert.True(t, errors.Is(err, models.ErrConflict))
+	for _, retainEmbeddings := range []bool{true, false} {
+		err = ms.CompactMessages(ctx, sessionID, messages[1].UUID, retainEmbeddings)
+		assert.True(t, errors.Is(err, models.ErrConflict))
+		err = ms.PruneMessages(ctx, sessionID, []uuid.UUID{messages[0].UUID}, retainEmbeddings)
+		assert.True(t, errors.Is(err, models.ErrConflict))
+	}
+
+	list, err := ms.GetMessageList(ctx, sessionID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, list.Messages, 2)
+	assert.Equal(t, "one", list.Messages[0].Content)
+	assert.Equal(t, "two", list.Messages[1].Content)
+
+	err = hs.Lift(ctx, models.LegalHoldSubjectSession, sessionID, "admin", "")
+	require.NoError(t, err)
+	require.NoError(t, ms.DeleteSession(ctx, sessionID))
+}
+
+func testLegalHoldUser(
+	t *testing.T,
+	ms models.MemoryStore[any],
+	us models.UserStore,
+	hs models.LegalHoldStore,
+) {
+	ctx := context.Background()
+	userID := newSessionID(t)
+	_, err := us.Create(ctx, &models.CreateUserRequest{UserID: userID})
+	require.NoError(t, err)
+	sessionID := newSessionID(t)
+	_, err = ms.CreateSession(ctx, &models.CreateSessionRequest{
+		SessionID: sessionID,
+		UserID:    &userID,
+	})
+	require.NoError(t, err)
+
+	// a hold on a user holds the user's sessions
+	placeHold(t, hs, models.LegalHoldSubjectUser, userID)
+	err = us.Delete(ctx, userID)
+	assert.True(t, errors.Is(err, models.ErrConflict))
+	err = ms.DeleteSession(ctx, sessionID)
+	assert.True(t, errors.Is(err, models.ErrConflict))
+	_, err = us.Anonymize(ctx, userID, newSessionID(t), nil)
+	assert.True(t, errors.Is(err, models.ErrConflict), "a held user can't be anonymized")
+	_, err = ms.GetSession(ctx, sessionID)
+	require.NoError(t, err)
+
+	// a hold on one of the user's sessions prevents deleting the user
+	require.NoError(t, hs.Lift(ctx, models.LegalHoldSubjectUser, userID, "admin", ""))
+	placeHold(t, hs, models.LegalHoldSubjectSession, sessionID)
+	err = us.Delete(ctx, userID)
+	assert.True(t, errors.Is(err, models.ErrConflict))
+	_, err = us.Get(ctx, userID)
+	require.NoError(t, err, "nothing is deleted if a session is held")
+
+	require.NoError(t, hs.Lift(ctx, models.LegalHoldSubjectSession, sessionID, "admin", ""))
+	require.NoError(t, us.Delete(ctx, userID))
+	_, err = ms.GetSession(ctx, sessionID)
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+}
+
+func testLegalHoldExpiredSessions(
+	t *testing.T,
+	ms models.MemoryStore[any],
+	_ models.UserStore,
+	hs models.LegalHoldStore,
+) {
+	ctx := context.Background()
+	expired := time.Now().Add(-time.Minute)
+	held, unheld := newSessionID(t), newSessionID(t)
+	for _, sessionID := range []string{held, unheld} {
+		_, err := ms.CreateSession(ctx, &models.CreateSessionRequest{
+			SessionID: sessionID,
+			ExpiresAt: &expired,
+		})
+		require.NoError(t, err)
+	}
+	placeHold(t, hs, models.LegalHoldSubjectSession, held)
+
+	expiring, err := ms.ListExpiringSessions(ctx, time.Now())
+	require.NoError(t, err)
+	assert.NotContains(t, sessionIDs(expiring), held)
+	assert.Contains(t, sessionIDs(expiring), unheld)
+
+	deleted, err := ms.DeleteExpiredSessions(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, sessionIDs(deleted), held)
+	assert.Contains(t, sessionIDs(deleted), unheld)
+
+	_, err = ms.GetSession(ctx, held)
+	require.NoError(t, err)
+}
+
+func sessionIDs(sessions []*models.Session) []string {
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.SessionID
+	}
+	return ids
+}
@@ -0,0 +1,595 @@
+// Package storetest is a conformance test suite for MemoryStore and DocumentStore
+// implementations. A store backend runs the suite from its own tests to verify that it
+// behaves like the Postgres store:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.RunMemoryStoreTests(t, func(t *testing.T) models.MemoryStore[any] {
+//			return NewMemoryStore(appState, NewDB())
+//		})
+//	}
+//
+// The suite only relies on behavior common to all backends. It doesn't assume the store is
+// empty, so it may run against a shared database, and it doesn't check search relevance,
+// which is backend specific.
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/jsonpatch"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+// MemoryStoreFactory returns the MemoryStore under test. If the store publishes tasks when
+// summaries are created, its AppState must have a TaskPublisher.
+type MemoryStoreFactory func(t *testing.T) models.MemoryStore[any]
+
+// RunMemoryStoreTests runs the MemoryStore conformance suite. newStore is called once per
+// subtest.
+func RunMemoryStoreTests(t *testing.T, newStore MemoryStoreFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ms models.MemoryStore[any])
+	}{
+		{"sessions", testSessions},
+		{"create sessions", testCreateSessions},
+		{"patch session metadata", testPatchSessionMetadata},
+		{"session not found", testSessionNotFound},
+		{"list sessions", testListSessions},
+		{"put and get memory", testPutAndGetMemory},
+		{"message list", testMessageList},
+		{"update messages", testUpdateMessages},
+		{"summaries", testSummaries},
+		{"search validation", testSearchValidation},
+		{"literal search", testLiteralSearch},
+		{"hybrid search of unembedded messages", testHybridSearchUnembedded},
+		{"matching messages", testMatchingMessages},
+		{"delete session", testDeleteSession},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newSessionID(t *testing.T) string {
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	require.NoError(t, err)
+	return sessionID
+}
+
+// putMessages stores the given contents as alternating human and ai messages and returns
+// the stored messages, oldest first.
+func putMessages(
+	t *testing.T,
+	ms models.MemoryStore[any],
+	sessionID string,
+	contents ...string,
+) []models.Message {
+	ctx := context.Background()
+
+	messages := make([]models.Message, len(contents))
+	for i, content := range contents {
+		role := "human"
+		if i%2 == 1 {
+			role = "ai"
+		}
+		messages[i] = models.Message{Role: role, Content: content}
+	}
+	err := ms.PutMemory(ctx, sessionID, &models.Memory{Messages: messages}, true)
+	require.NoError(t, err)
+
+	list, err := ms.GetMessageList(ctx, sessionID, 1, len(contents)+100)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(list.Messages), len(contents))
+	return list.Messages[len(list.Messages)-len(contents):]
+}
+
+func testSessions(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	session, err := ms.CreateSession(ctx, &models.CreateSessionRequest{
+		SessionID: sessionID,
+		Metadata:  map[string]interface{}{"a": "1", "nested": map[string]interface{}{"b": "2"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, sessionID, session.SessionID)
+	assert.NotEqual(t, uuid.Nil, session.UUID)
+	assert.False(t, session.CreatedAt.IsZero())
+
+	_, err = ms.CreateSession(ctx, &models.CreateSessionRequest{SessionID: sessionID})
+	assert.ErrorIs(t, err, models.ErrConflict)
+
+	_, err = ms.CreateSession(ctx, &models.CreateSessionRequest{})
+	assert.ErrorIs(t, err, models.ErrValidation)
+
+	// metadata is deep merged, and the system key can't be set by an unprivileged update
+	updated, err := ms.UpdateSession(ctx, &models.UpdateSessionRequest{
+		SessionID: sessionID,
+		Metadata: map[string]interface{}{
+			"nested": map[string]interface{}{"c": "3"},
+			"system": map[string]interface{}{"d": "4"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a":      "1",
+		"nested": map[string]interface{}{"b": "2", "c": "3"},
+	}, updated.Metadata)
+
+	got, err := ms.GetSession(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, session.UUID, got.UUID)
+	assert.Equal(t, updated.Metadata, got.Metadata)
+}
+
+func testCreateSessions(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	existing := newSessionID(t)
+	_, err := ms.CreateSession(ctx, &models.CreateSessionRequest{SessionID: existing})
+	require.NoError(t, err)
+
+	unknownUser := newSessionID(t)
+	first, second := newSessionID(t), newSessionID(t)
+	response, err := ms.CreateSessions(ctx, []models.CreateSessionRequest{
+		{SessionID: first, Metadata: map[string]interface{}{"a": "1"}},
+		{SessionID: existing},
+		{SessionID: newSessionID(t), UserID: &unknownUser},
+		{},
+		{SessionID: second},
+		{SessionID: second},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, 4, response.Failed)
+	require.Len(t, response.Results, 6)
+	for i, result := range response.Results {
+		assert.Equal(t, i, result.Index)
+		if i == 0 || i == 4 {
+			assert.Empty(t, result.Error)
+			require.NotNil(t, result.Session)
+		} else {
+			assert.NotEmpty(t, result.Error)
+			assert.Nil(t, result.Session)
+		}
+	}
+	assert.Equal(t, first, response.Results[0].Session.SessionID)
+
+	// sessions created after a failed one are kept
+	got, err := ms.GetSession(ctx, second)
+	require.NoError(t, err)
+	assert.Equal(t, response.Results[4].Session.UUID, got.UUID)
+	got, err = ms.GetSession(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": "1"}, got.Metadata)
+}
+
+func testPatchSessionMetadata(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+	_, err := ms.CreateSession(ctx, &models.CreateSessionRequest{
+		SessionID: sessionID,
+		Metadata: map[string]interface{}{
+			"a":      "1",
+			"nested": map[string]interface{}{"b": "2", "c": "3"},
+		},
+	})
+	require.NoError(t, err)
+
+	patched, err := ms.PatchSessionMetadata(ctx, sessionID, jsonpatch.Patch{
+		{Op: "remove", Path: "/nested/b"},
+		{Op: "replace", Path: "/a", Value: []byte(`"4"`)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a":      "4",
+		"nested": map[string]interface{}{"c": "3"},
+	}, patched.Metadata)
+
+	patched, err = ms.PatchSessionMetadata(ctx, sessionID, jsonpatch.MergePatch{"nested": nil})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": "4"}, patched.Metadata)
+
+	got, err := ms.GetSession(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, patched.Metadata, got.Metadata)
+
+	// a failed patch makes no change
+	_, err = ms.PatchSessionMetadata(ctx, sessionID, jsonpatch.Patch{
+		{Op: "remove", Path: "/a"},
+		{Op: "test", Path: "/a", Value: []byte(`"4"`)},
+	})
+	assert.ErrorIs(t, err, models.ErrValidation)
+	_, err = ms.PatchSessionMetadata(ctx, sessionID, jsonpatch.Patch{
+		{Op: "test", Path: "/a", Value: []byte(`"5"`)},
+	})
+	assert.ErrorIs(t, err, models.ErrConflict)
+	_, err = ms.PatchSessionMetadata(ctx, sessionID, jsonpatch.MergePatch{
+		"system": map[string]interface{}{"d": "4"},
+	})
+	assert.ErrorIs(t, err, models.ErrValidation)
+	got, err = ms.GetSession(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": "4"}, got.Metadata)
+
+	_, err = ms.PatchSessionMetadata(ctx, newSessionID(t), jsonpatch.MergePatch{"a": "1"})
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func testSessionNotFound(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	_, err := ms.GetSession(ctx, sessionID)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	_, err = ms.UpdateSession(ctx, &models.UpdateSessionRequest{SessionID: sessionID})
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	err = ms.DeleteSession(ctx, sessionID)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	_, err = ms.GetMemory(ctx, "", 0)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func testListSessions(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+
+	ids := make([]int64, 3)
+	for i := range ids {
+		session, err := ms.CreateSession(ctx, &models.CreateSessionRequest{
+			SessionID: newSessionID(t),
+		})
+		require.NoError(t, err)
+		ids[i] = session.ID
+	}
+
+	sessions, err := ms.ListSessions(ctx, ids[0], 2)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, ids[1], sessions[0].ID)
+	assert.Equal(t, ids[2], sessions[1].ID)
+
+	ordered, err := ms.ListSessionsOrdered(ctx, 1, 2, "id", false)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, ordered.TotalCount, 3)
+	assert.Equal(t, 2, ordered.RowCount)
+	require.Len(t, ordered.Sessions, 2)
+	assert.Greater(t, ordered.Sessions[0].ID, ordered.Sessions[1].ID)
+}
+
+func testPutAndGetMemory(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	messages := putMessages(t, ms, sessionID, "one", "two", "three")
+
+	// PutMemory creates the session
+	_, err := ms.GetSession(ctx, sessionID)
+	require.NoError(t, err)
+
+	for _, m := range messages {
+		assert.NotEqual(t, uuid.Nil, m.UUID)
+	}
+
+	memory, err := ms.GetMemory(ctx, sessionID, 2)
+	require.NoError(t, err)
+	require.Len(t, memory.Messages, 2)
+	assert.Equal(t, "two", memory.Messages[0].Content)
+	assert.Equal(t, "three", memory.Messages[1].Content)
+	assert.Equal(t, "ai", memory.Messages[0].Role)
+
+	memory, err = ms.GetMemory(ctx, sessionID, 0)
+	require.NoError(t, err)
+	assert.Len(t, memory.Messages, 3)
+	assert.Empty(t, memory.Summary.Content)
+
+	_, err = ms.GetMemory(ctx, sessionID, -1)
+	assert.ErrorIs(t, err, models.ErrValidation)
+
+	// a second PutMemory appends to the existing session
+	putMessages(t, ms, sessionID, "four")
+	memory, err = ms.GetMemory(ctx, sessionID, 10)
+	require.NoError(t, err)
+	require.Len(t, memory.Messages, 4)
+	assert.Equal(t, "four", memory.Messages[3].Content)
+}
+
+func testMessageList(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	messages := putMessages(t, ms, sessionID, "one", "two", "three")
+
+	list, err := ms.GetMessageList(ctx, sessionID, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, list.TotalCount)
+	assert.Equal(t, 1, list.RowCount)
+	require.Len(t, list.Messages, 1)
+	assert.Equal(t, "three", list.Messages[0].Content)
+
+	byUUID, err := ms.GetMessagesByUUID(
+		ctx,
+		sessionID,
+		[]uuid.UUID{messages[0].UUID, messages[2].UUID},
+	)
+	require.NoError(t, err)
+	contents := make([]string, len(byUUID))
+	for i, m := range byUUID {
+		contents[i] = m.Content
+	}
+	assert.ElementsMatch(t, []string{"one", "three"}, contents)
+
+	// messages of other sessions are not returned
+	byUUID, err = ms.GetMessagesByUUID(ctx, newSessionID(t), []uuid.UUID{messages[0].UUID})
+	require.NoError(t, err)
+	assert.Empty(t, byUUID)
+}
+
+func testUpdateMessages(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	messages := putMessages(t, ms, sessionID, "one", "two")
+
+	err := ms.UpdateMessages(ctx, sessionID, []models.Message{{
+		UUID:     messages[0].UUID,
+		Role:     "human",
+		Content:  "one, edited",
+		Metadata: map[string]interface{}{"system": map[string]interface{}{"intent": "none"}},
+	}}, true, true)
+	require.NoError(t, err)
+
+	// without includeContent, only the metadata is updated
+	err = ms.UpdateMessages(ctx, sessionID, []models.Message{{
+		UUID:     messages[1].UUID,
+		Content:  "ignored",
+		Metadata: map[string]interface{}{"a": "1"},
+	}}, false, false)
+	require.NoError(t, err)
+
+	updated, err := ms.GetMessagesByUUID(
+		ctx,
+		sessionID,
+		[]uuid.UUID{messages[0].UUID, messages[1].UUID},
+	)
+	require.NoError(t, err)
+	require.Len(t, updated, 2)
+	for _, m := range updated {
+		// the content hash follows the content
+		assert.Equal(t, models.ContentHash(m.Content), m.ContentHash)
+		switch m.UUID {
+		case messages[0].UUID:
+			assert.Equal(t, "one, edited", m.Content)
+			assert.Equal(t, map[string]interface{}{
+				"system": map[string]interface{}{"intent": "none"},
+			}, m.Metadata)
+		case messages[1].UUID:
+			assert.Equal(t, "two", m.Content)
+			assert.Equal(t, map[string]interface{}{"a": "1"}, m.Metadata)
+		}
+	}
+}
+
+func testSummaries(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	messages := putMessages(t, ms, sessionID, "one", "two", "three")
+
+	summary, err := ms.GetSummary(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Empty(t, summary.Content)
+
+	err = ms.CreateSummary(ctx, sessionID, &models.Summary{
+		Content:          "a summary of one and two",
+		SummaryPointUUID: messages[1].UUID,
+	})
+	require.NoError(t, err)
+
+	summary, err = ms.GetSummary(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "a summary of one and two", summary.Content)
+	assert.Equal(t, messages[1].UUID, summary.SummaryPointUUID)
+	require.NotEqual(t, uuid.Nil, summary.UUID)
+
+	// only the messages after the summary point are returned with the summary
+	memory, err := ms.GetMemory(ctx, sessionID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, summary.UUID, memory.Summary.UUID)
+	require.Len(t, memory.Messages, 1)
+	assert.Equal(t, "three", memory.Messages[0].Content)
+
+	summary.Metadata = map[string]interface{}{"a": "1"}
+	err = ms.UpdateSummary(ctx, sessionID, summary, false)
+	require.NoError(t, err)
+
+	got, err := ms.GetSummaryByUUID(ctx, sessionID, summary.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, "a summary of one and two", got.Content)
+	assert.Equal(t, map[string]interface{}{"a": "1"}, got.Metadata)
+
+	err = ms.CreateSummary(ctx, sessionID, &models.Summary{
+		Content:          "a summary of one, two, and three",
+		SummaryPointUUID: messages[2].UUID,
+	})
+	require.NoError(t, err)
+
+	list, err := ms.GetSummaryList(ctx, sessionID, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.RowCount)
+
+	summary, err = ms.GetSummary(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "a summary of one, two, and three", summary.Content)
+}
+
+func testSearchValidation(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	putMessages(t, ms, sessionID, "one")
+
+	_, err := ms.SearchMemory(ctx, sessionID, &models.MemorySearchPayload{}, 10)
+	assert.ErrorIs(t, err, models.ErrValidation)
+
+	_, err = ms.SearchMemory(ctx, sessionID, &models.MemorySearchPayload{
+		Text:        "one",
+		SearchScope: "invalid",
+	}, 10)
+	assert.ErrorIs(t, err, models.ErrValidation)
+
+	_, err = ms.SearchUserMemory(ctx, newSessionID(t), &models.MemorySearchPayload{}, 0, 10)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func testLiteralSearch(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	putMessages(t, ms, sessionID,
+		"Where is ORDER #12345?",
+		"Order #12345 shipped yesterday.",
+		"Order 12345 is another order.",
+		"It's 50% off.",
+	)
+
+	search := func(text string) []string {
+		results, err := ms.SearchMemory(ctx, sessionID, &models.MemorySearchPayload{
+			Text:       text,
+			SearchType: models.SearchTypeLiteral,
+		}, 10)
+		require.NoError(t, err)
+		contents := make([]string, len(results))
+		for i, r := range results {
+			require.NotNil(t, r.Message)
+			contents[i] = r.Message.Content
+		}
+		return contents
+	}
+
+	assert.ElementsMatch(t, []string{
+		"Where is ORDER #12345?",
+		"Order #12345 shipped yesterday.",
+	}, search("order #12345"))
+	// wildcards are matched literally
+	assert.Equal(t, []string{"It's 50% off."}, search("0% o"))
+	assert.Empty(t, search("5_%"))
+
+	_, err := ms.SearchMemory(ctx, sessionID, &models.MemorySearchPayload{
+		SearchType: models.SearchTypeLiteral,
+		Metadata:   map[string]interface{}{"where": map[string]interface{}{"jsonpath": "$.a"}},
+	}, 10)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+// testHybridSearchUnembedded searches messages that haven't been embedded, which only the
+// full-text ranking of hybrid searches finds.
+func testHybridSearchUnembedded(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	putMessages(t, ms, sessionID,
+		"The flight to Paris leaves at noon.",
+		"I prefer window seats.",
+	)
+
+	results, err := ms.SearchMemory(ctx, sessionID, &models.MemorySearchPayload{
+		Text:       "paris",
+		SearchType: models.SearchTypeHybrid,
+	}, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	require.NotNil(t, results[0].Message)
+	assert.Equal(t, "The flight to Paris leaves at noon.", results[0].Message.Content)
+
+	results, err = ms.SearchMemory(ctx, sessionID, &models.MemorySearchPayload{
+		Text:       "zurich",
+		SearchType: models.SearchTypeHybrid,
+	}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func testMatchingMessages(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	putMessages(t, ms, sessionID,
+		"my key is sk-abcdefghijklmnopqrstu",
+		"thanks",
+		"and SK-ABCDEFGHIJKLMNOPQRSTU too",
+		"sk-short",
+	)
+	filter := &models.MessageFilter{ContentRegex: `sk-[A-Za-z0-9]{20,}`}
+
+	list, err := ms.GetMatchingMessageList(ctx, sessionID, filter, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.TotalCount)
+	require.Len(t, list.Messages, 1)
+	assert.Equal(t, "my key is sk-abcdefghijklmnopqrstu", list.Messages[0].Content)
+
+	insensitive := &models.MessageFilter{ContentRegex: `(?i)sk-[a-z0-9]{20,}`}
+	list, err = ms.GetMatchingMessageList(ctx, sessionID, insensitive, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.TotalCount)
+
+	for _, invalid := range []string{"", "(a", `(a)\1`, "a{1000}"} {
+		_, err = ms.GetMatchingMessageList(
+			ctx,
+			sessionID,
+			&models.MessageFilter{ContentRegex: invalid},
+			1,
+			10,
+		)
+		assert.ErrorIs(t, err, models.ErrValidation, invalid)
+	}
+
+	deleted, err := ms.DeleteMatchingMessages(ctx, sessionID, insensitive)
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	list, err = ms.GetMessageList(ctx, sessionID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, list.Messages, 2)
+	assert.Equal(t, "thanks", list.Messages[0].Content)
+	assert.Equal(t, "sk-short", list.Messages[1].Content)
+
+	deleted, err = ms.DeleteMatchingMessages(ctx, sessionID, insensitive)
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+}
+
+func testDeleteSession(t *testing.T, ms models.MemoryStore[any]) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	putMessages(t, ms, sessionID, "one", "two")
+
+	err := ms.DeleteSession(ctx, sessionID)
+	require.NoError(t, err)
+
+	_, err = ms.GetSession(ctx, sessionID)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	// messages of a deleted session are deleted, too
+	memory, err := ms.GetMemory(ctx, sessionID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, memory.Messages)
+
+	err = ms.PurgeDeleted(ctx)
+	require.NoError(t, err)
+
+	// a purged session can be recreated
+	_, err = ms.CreateSession(ctx, &models.CreateSessionRequest{SessionID: sessionID})
+	require.NoError(t, err)
+}
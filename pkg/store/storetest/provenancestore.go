@@ -0,0 +1,88 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// ProvenanceStoreFactory returns the ProvenanceStore under test.
+type ProvenanceStoreFactory func(t *testing.T) models.ProvenanceStore
+
+// RunProvenanceStoreTests runs the ProvenanceStore conformance suite. newStore is called
+// once per subtest.
+func RunProvenanceStoreTests(t *testing.T, newStore ProvenanceStoreFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s models.ProvenanceStore)
+	}{
+		{"put and get", testProvenancePutGet},
+		{"delete before", testProvenanceDeleteBefore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func testProvenancePutGet(t *testing.T, s models.ProvenanceStore) {
+	ctx := context.Background()
+
+	provenance := &models.ContextProvenance{
+		ResponseID: uuid.New(),
+		Kind:       models.ProvenanceMemory,
+		SessionID:  newSessionID(t),
+		Messages:   []uuid.UUID{uuid.New(), uuid.New()},
+		Summaries:  []uuid.UUID{uuid.New()},
+	}
+	require.NoError(t, s.Put(ctx, provenance))
+
+	got, err := s.Get(ctx, provenance.ResponseID)
+	require.NoError(t, err)
+	assert.Equal(t, provenance.Kind, got.Kind)
+	assert.Equal(t, provenance.SessionID, got.SessionID)
+	assert.Equal(t, provenance.Messages, got.Messages)
+	assert.Equal(t, provenance.Summaries, got.Summaries)
+	assert.Empty(t, got.CollectionName)
+	assert.NotNil(t, got.Documents)
+	assert.Empty(t, got.Documents)
+	assert.False(t, got.CreatedAt.IsZero())
+
+	_, err = s.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func testProvenanceDeleteBefore(t *testing.T, s models.ProvenanceStore) {
+	ctx := context.Background()
+
+	old := &models.ContextProvenance{
+		ResponseID:     uuid.New(),
+		CreatedAt:      time.Now().Add(-48 * time.Hour),
+		Kind:           models.ProvenanceDocumentSearch,
+		CollectionName: "storetest",
+		Documents:      []uuid.UUID{uuid.New()},
+	}
+	recent := &models.ContextProvenance{
+		ResponseID:     uuid.New(),
+		Kind:           models.ProvenanceDocumentSearch,
+		CollectionName: "storetest",
+	}
+	require.NoError(t, s.Put(ctx, old))
+	require.NoError(t, s.Put(ctx, recent))
+
+	n, err := s.DeleteBefore(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, n, 1)
+
+	_, err = s.Get(ctx, old.ResponseID)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+	_, err = s.Get(ctx, recent.ResponseID)
+	assert.NoError(t, err)
+}
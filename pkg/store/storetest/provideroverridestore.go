@@ -0,0 +1,105 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+// ProviderOverrideStoreFactory returns the ProviderOverrideStore under test.
+type ProviderOverrideStoreFactory func(t *testing.T) models.ProviderOverrideStore
+
+// RunProviderOverrideStoreTests runs the ProviderOverrideStore conformance suite. newStore
+// is called once per subtest. Each subtest uses its own tenants, so the store may hold
+// other overrides.
+func RunProviderOverrideStoreTests(t *testing.T, newStore ProviderOverrideStoreFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s models.ProviderOverrideStore)
+	}{
+		{"put and get", testProviderOverridePutGet},
+		{"list and delete", testProviderOverrideListDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newProviderOverride() *models.ProviderOverride {
+	return &models.ProviderOverride{
+		Tenant: "storetest" + testutils.GenerateRandomString(16),
+		LLM: &models.LLMProvider{
+			Service: models.ProviderServiceAnthropic,
+			Model:   "claude-3-haiku-20240307",
+			APIKey:  "sk-ant-secret",
+		},
+		Embeddings: &models.EmbeddingProvider{
+			Service:  models.ProviderServiceOpenAI,
+			Model:    "text-embedding-3-small",
+			APIKey:   "sk-secret",
+			Endpoint: "https://eu.example.com/v1",
+		},
+	}
+}
+
+func testProviderOverridePutGet(t *testing.T, s models.ProviderOverrideStore) {
+	ctx := context.Background()
+	override := newProviderOverride()
+
+	created, err := s.Put(ctx, override)
+	require.NoError(t, err)
+	assert.Equal(t, override.Tenant, created.Tenant)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	// the API keys are stored
+	got, err := s.Get(ctx, override.Tenant)
+	require.NoError(t, err)
+	assert.Equal(t, override.LLM, got.LLM)
+	assert.Equal(t, override.Embeddings, got.Embeddings)
+
+	// putting an override of the same tenant replaces its providers
+	override.LLM = nil
+	override.Embeddings.APIKey = "sk-rotated"
+	replaced, err := s.Put(ctx, override)
+	require.NoError(t, err)
+	assert.Equal(t, created.CreatedAt.Unix(), replaced.CreatedAt.Unix())
+	got, err = s.Get(ctx, override.Tenant)
+	require.NoError(t, err)
+	assert.Nil(t, got.LLM)
+	assert.Equal(t, "sk-rotated", got.Embeddings.APIKey)
+
+	_, err = s.Get(ctx, "storetestmissing")
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func testProviderOverrideListDelete(t *testing.T, s models.ProviderOverrideStore) {
+	ctx := context.Background()
+	first, second := newProviderOverride(), newProviderOverride()
+	_, err := s.Put(ctx, first)
+	require.NoError(t, err)
+	_, err = s.Put(ctx, second)
+	require.NoError(t, err)
+
+	tenants := func() []string {
+		overrides, err := s.List(ctx)
+		require.NoError(t, err)
+		var tenants []string
+		for _, override := range overrides {
+			tenants = append(tenants, override.Tenant)
+		}
+		return tenants
+	}
+	assert.Subset(t, tenants(), []string{first.Tenant, second.Tenant})
+	assert.IsNonDecreasing(t, tenants())
+
+	require.NoError(t, s.Delete(ctx, first.Tenant))
+	assert.NotContains(t, tenants(), first.Tenant)
+	assert.ErrorIs(t, s.Delete(ctx, first.Tenant), models.ErrNotFound)
+}
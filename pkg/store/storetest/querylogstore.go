@@ -0,0 +1,117 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// QueryLogStoreFactory returns the QueryLogStore under test.
+type QueryLogStoreFactory func(t *testing.T) models.QueryLogStore
+
+// RunQueryLogStoreTests runs the QueryLogStore conformance suite. newStore is called once
+// per subtest.
+func RunQueryLogStoreTests(t *testing.T, newStore QueryLogStoreFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s models.QueryLogStore)
+	}{
+		{"analytics", testQueryLogAnalytics},
+		{"delete before", testQueryLogDeleteBefore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func testQueryLogAnalytics(t *testing.T, s models.QueryLogStore) {
+	ctx := context.Background()
+
+	// searches are isolated from other tests by their collection
+	collection := newSessionID(t)
+	for _, search := range []models.LoggedSearch{
+		{Text: "refund policy", LatencyMS: 10, ResultCount: 3},
+		{Text: "Refund Policy ", LatencyMS: 20},
+		{Text: "refund policy", LatencyMS: 30, ResultCount: 3},
+		{Text: "shipping to mars", LatencyMS: 40},
+		{Text: "opening hours", LatencyMS: 50, ResultCount: 1},
+		// metadata searches are counted, but have no query
+		{LatencyMS: 60, ResultCount: 2},
+	} {
+		search.Kind = models.SearchKindDocument
+		search.CollectionName = collection
+		search.ZeroResults = search.ResultCount == 0
+		require.NoError(t, s.Put(ctx, &search))
+	}
+	require.NoError(t, s.Put(ctx, &models.LoggedSearch{
+		Kind:           models.SearchKindDocument,
+		CollectionName: collection,
+		CreatedAt:      time.Now().Add(-48 * time.Hour),
+		Text:           "old",
+		ZeroResults:    true,
+	}))
+
+	analytics, err := s.Analytics(ctx, &models.SearchAnalyticsFilter{
+		Kind:           models.SearchKindDocument,
+		CollectionName: collection,
+		Since:          time.Now().Add(-24 * time.Hour),
+		Limit:          2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 6, analytics.Searches)
+	assert.Equal(t, 2, analytics.ZeroResults)
+	assert.InDelta(t, 2.0/6, analytics.ZeroResultRate, 1e-9)
+	assert.InDelta(t, 35, analytics.AvgLatencyMS, 1e-9)
+	assert.Equal(t, []models.QueryCount{
+		{Text: "refund policy", Searches: 3, ZeroResults: 1, AvgResults: 2},
+		{Text: "opening hours", Searches: 1, AvgResults: 1},
+	}, analytics.TopQueries)
+	assert.Equal(t, []models.QueryCount{
+		{Text: "refund policy", Searches: 1, ZeroResults: 1},
+		{Text: "shipping to mars", Searches: 1, ZeroResults: 1},
+	}, analytics.TopZeroResultQueries)
+
+	analytics, err = s.Analytics(ctx, &models.SearchAnalyticsFilter{
+		Kind:           models.SearchKindMemory,
+		CollectionName: collection,
+	})
+	require.NoError(t, err)
+	assert.Zero(t, analytics.Searches)
+	assert.Zero(t, analytics.ZeroResultRate)
+	assert.NotNil(t, analytics.TopQueries)
+	assert.Empty(t, analytics.TopQueries)
+}
+
+func testQueryLogDeleteBefore(t *testing.T, s models.QueryLogStore) {
+	ctx := context.Background()
+
+	collection := newSessionID(t)
+	require.NoError(t, s.Put(ctx, &models.LoggedSearch{
+		CreatedAt:      time.Now().Add(-48 * time.Hour),
+		Kind:           models.SearchKindDocument,
+		CollectionName: collection,
+		Text:           "old",
+	}))
+	require.NoError(t, s.Put(ctx, &models.LoggedSearch{
+		Kind:           models.SearchKindDocument,
+		CollectionName: collection,
+		Text:           "recent",
+	}))
+
+	n, err := s.DeleteBefore(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, n, 1)
+
+	analytics, err := s.Analytics(ctx, &models.SearchAnalyticsFilter{CollectionName: collection})
+	require.NoError(t, err)
+	assert.Equal(t, 1, analytics.Searches)
+	require.Len(t, analytics.TopQueries, 1)
+	assert.Equal(t, "recent", analytics.TopQueries[0].Text)
+}
@@ -0,0 +1,157 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// MemorySnapshotStoreFactory returns the MemorySnapshotStore under test, and a MemoryStore
+// sharing its sessions.
+type MemorySnapshotStoreFactory func(
+	t *testing.T,
+) (models.MemoryStore[any], models.MemorySnapshotStore)
+
+// RunMemorySnapshotStoreTests runs the MemorySnapshotStore conformance suite. newStores is
+// called once per subtest.
+func RunMemorySnapshotStoreTests(t *testing.T, newStores MemorySnapshotStoreFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ms models.MemoryStore[any], ss models.MemorySnapshotStore)
+	}{
+		{"put and get", testSnapshotPutGet},
+		{"list", testSnapshotList},
+		{"delete session", testSnapshotDeleteSession},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms, ss := newStores(t)
+			tt.fn(t, ms, ss)
+		})
+	}
+}
+
+// putSnapshot snapshots the current memory of a session at its last message.
+func putSnapshot(
+	t *testing.T,
+	ms models.MemoryStore[any],
+	ss models.MemorySnapshotStore,
+	sessionID string,
+) *models.MemorySnapshot {
+	ctx := context.Background()
+
+	memory, err := ms.GetMemory(ctx, sessionID, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, memory.Messages)
+	snapshot, err := ss.Put(ctx, &models.MemorySnapshot{
+		SessionID: sessionID,
+		TurnUUID:  memory.Messages[len(memory.Messages)-1].UUID,
+		Memory:    memory,
+	})
+	require.NoError(t, err)
+	return snapshot
+}
+
+func testSnapshotPutGet(t *testing.T, ms models.MemoryStore[any], ss models.MemorySnapshotStore) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	messages := putMessages(t, ms, sessionID, "one", "two")
+	snapshot := putSnapshot(t, ms, ss, sessionID)
+	assert.NotEqual(t, uuid.Nil, snapshot.UUID)
+	assert.False(t, snapshot.CreatedAt.IsZero())
+	assert.Equal(t, messages[1].UUID, snapshot.TurnUUID)
+
+	got, err := ss.Get(ctx, sessionID, snapshot.TurnUUID)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.UUID, got.UUID)
+	require.Len(t, got.Memory.Messages, 2)
+	assert.Equal(t, "one", got.Memory.Messages[0].Content)
+	assert.Equal(t, messages[0].UUID, got.Memory.Messages[0].UUID)
+
+	// later messages don't change the snapshot
+	putMessages(t, ms, sessionID, "three")
+	got, err = ss.Get(ctx, sessionID, snapshot.TurnUUID)
+	require.NoError(t, err)
+	assert.Len(t, got.Memory.Messages, 2)
+
+	// a snapshot of the same turn replaces it
+	replaced, err := ss.Put(ctx, &models.MemorySnapshot{
+		SessionID: sessionID,
+		TurnUUID:  snapshot.TurnUUID,
+		Memory:    &models.Memory{Summary: &models.Summary{Content: "summary"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.UUID, replaced.UUID)
+	got, err = ss.Get(ctx, sessionID, snapshot.TurnUUID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Memory.Messages)
+	assert.Equal(t, "summary", got.Memory.Summary.Content)
+
+	_, err = ss.Get(ctx, sessionID, uuid.New())
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	_, err = ss.Put(ctx, &models.MemorySnapshot{
+		SessionID: newSessionID(t),
+		TurnUUID:  uuid.New(),
+		Memory:    &models.Memory{},
+	})
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	_, err = ss.Put(ctx, &models.MemorySnapshot{SessionID: sessionID, Memory: &models.Memory{}})
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
+
+func testSnapshotList(t *testing.T, ms models.MemoryStore[any], ss models.MemorySnapshotStore) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	var turns []uuid.UUID
+	for _, content := range []string{"one", "two", "three"} {
+		putMessages(t, ms, sessionID, content)
+		turns = append(turns, putSnapshot(t, ms, ss, sessionID).TurnUUID)
+	}
+
+	snapshots, err := ss.List(ctx, sessionID, 0)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+	for i, snapshot := range snapshots {
+		assert.Equal(t, turns[i], snapshot.TurnUUID)
+		assert.Len(t, snapshot.Memory.Messages, i+1)
+	}
+
+	snapshots, err = ss.List(ctx, sessionID, 2)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, turns[0], snapshots[0].TurnUUID)
+
+	snapshots, err = ss.List(ctx, newSessionID(t), 0)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func testSnapshotDeleteSession(
+	t *testing.T,
+	ms models.MemoryStore[any],
+	ss models.MemorySnapshotStore,
+) {
+	ctx := context.Background()
+	sessionID := newSessionID(t)
+
+	putMessages(t, ms, sessionID, "one")
+	snapshot := putSnapshot(t, ms, ss, sessionID)
+
+	require.NoError(t, ms.DeleteSession(ctx, sessionID))
+	_, err := ss.Get(ctx, sessionID, snapshot.TurnUUID)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+	snapshots, err := ss.List(ctx, sessionID, 0)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+
+	require.NoError(t, ms.PurgeDeleted(ctx))
+}
@@ -0,0 +1,154 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// AsyncTaskAbandonedAfter is how long an unfinished AsyncTask may go without a heartbeat
+// before it is considered abandoned, as when the server running it stopped.
+const AsyncTaskAbandonedAfter = time.Minute
+
+// asyncTaskHeartbeatInterval is how often a running AsyncTask's progress is stored. Each
+// heartbeat also checks whether the task has been canceled.
+var asyncTaskHeartbeatInterval = 5 * time.Second
+
+// AsyncTaskFunc performs the work of an AsyncTask, reporting progress with p. ctx is
+// canceled if the task is canceled. The returned result is stored with the task.
+type AsyncTaskFunc func(ctx context.Context, p *AsyncTaskProgress) (map[string]interface{}, error)
+
+// AsyncTaskProgress records the progress of a running AsyncTask. It's safe for
+// concurrent use.
+type AsyncTaskProgress struct {
+	mu        sync.Mutex
+	completed int
+	total     int
+}
+
+// Set records that completed of total units of work are done. total may be 0 if unknown.
+func (p *AsyncTaskProgress) Set(completed, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed = completed
+	p.total = total
+}
+
+func (p *AsyncTaskProgress) get() (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.completed, p.total
+}
+
+// StartAsyncTask creates an AsyncTask and runs fn in the background, returning the
+// pending task. The task outlives ctx, so that it may outlive the request starting it.
+func StartAsyncTask(
+	ctx context.Context,
+	store models.AsyncTaskStore,
+	taskType string,
+	params map[string]interface{},
+	fn AsyncTaskFunc,
+) (*models.AsyncTask, error) {
+	task, err := store.Create(ctx, taskType, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+
+	runCtx := context.WithoutCancel(ctx)
+	running := *task
+	go runAsyncTask(runCtx, store, &running, fn)
+
+	return task, nil
+}
+
+// RunAsyncTask creates an AsyncTask and runs fn, returning the task once finished. It's
+// used by commands, whose tasks are tracked like those started by the server.
+func RunAsyncTask(
+	ctx context.Context,
+	store models.AsyncTaskStore,
+	taskType string,
+	params map[string]interface{},
+	fn AsyncTaskFunc,
+) (*models.AsyncTask, error) {
+	task, err := store.Create(ctx, taskType, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+	runAsyncTask(ctx, store, task, fn)
+	return store.Get(context.WithoutCancel(ctx), task.UUID)
+}
+
+// runAsyncTask runs fn, storing the task's progress with each heartbeat and its outcome
+// when fn returns. fn's context is canceled once a heartbeat finds the task canceled.
+func runAsyncTask(
+	ctx context.Context,
+	store models.AsyncTaskStore,
+	task *models.AsyncTask,
+	fn AsyncTaskFunc,
+) {
+	startedAt := time.Now().UTC()
+	task.Status = models.AsyncTaskRunning
+	task.StartedAt = &startedAt
+	if _, err := store.Update(ctx, task); err != nil {
+		if !errors.Is(err, models.ErrConflict) {
+			log.Errorf("failed to start task %s: %v", task.UUID, err)
+		}
+		return
+	}
+
+	fnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	progress := &AsyncTaskProgress{}
+	done := make(chan struct{})
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(asyncTaskHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				task.Completed, task.Total = progress.get()
+				_, err := store.Update(ctx, task)
+				if errors.Is(err, models.ErrConflict) {
+					// the task has been canceled
+					cancel()
+					return
+				}
+				if err != nil {
+					log.Errorf("failed to update task %s: %v", task.UUID, err)
+				}
+			}
+		}
+	}()
+
+	result, err := fn(fnCtx, progress)
+	close(done)
+	<-heartbeatDone
+
+	finishedAt := time.Now().UTC()
+	task.Completed, task.Total = progress.get()
+	task.FinishedAt = &finishedAt
+	task.Result = result
+	task.Status = models.AsyncTaskSucceeded
+	if err != nil {
+		task.Status = models.AsyncTaskFailed
+		task.Error = err.Error()
+	}
+
+	_, err = store.Update(ctx, task)
+	if errors.Is(err, models.ErrConflict) {
+		log.Infof("task %s was canceled", task.UUID)
+		return
+	}
+	if err != nil {
+		log.Errorf("failed to finish task %s: %v", task.UUID, err)
+	}
+}
@@ -0,0 +1,132 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+// waitForAsyncTask returns the task once it's finished.
+func waitForAsyncTask(
+	t *testing.T,
+	store models.AsyncTaskStore,
+	task *models.AsyncTask,
+) *models.AsyncTask {
+	var got *models.AsyncTask
+	require.Eventually(t, func() bool {
+		var err error
+		got, err = store.Get(testCtx, task.UUID)
+		require.NoError(t, err)
+		return got.Status.IsFinished()
+	}, 5*time.Second, 10*time.Millisecond)
+	return got
+}
+
+func TestStartAsyncTask(t *testing.T) {
+	store := inmemory.NewAsyncTaskStore(inmemory.NewDB())
+
+	task, err := StartAsyncTask(
+		testCtx,
+		store,
+		"test",
+		map[string]interface{}{"n": 3},
+		func(ctx context.Context, p *AsyncTaskProgress) (map[string]interface{}, error) {
+			p.Set(3, 3)
+			return map[string]interface{}{"done": true}, nil
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, models.AsyncTaskPending, task.Status)
+
+	got := waitForAsyncTask(t, store, task)
+	assert.Equal(t, models.AsyncTaskSucceeded, got.Status)
+	assert.Equal(t, 3, got.Completed)
+	assert.Equal(t, 3, got.Total)
+	assert.Equal(t, true, got.Result["done"])
+	assert.NotNil(t, got.StartedAt)
+	assert.NotNil(t, got.FinishedAt)
+}
+
+func TestStartAsyncTaskFailed(t *testing.T) {
+	store := inmemory.NewAsyncTaskStore(inmemory.NewDB())
+
+	task, err := StartAsyncTask(
+		testCtx,
+		store,
+		"test",
+		nil,
+		func(ctx context.Context, p *AsyncTaskProgress) (map[string]interface{}, error) {
+			return nil, errors.New("boom")
+		},
+	)
+	require.NoError(t, err)
+
+	got := waitForAsyncTask(t, store, task)
+	assert.Equal(t, models.AsyncTaskFailed, got.Status)
+	assert.Equal(t, "boom", got.Error)
+}
+
+func TestRunAsyncTask(t *testing.T) {
+	store := inmemory.NewAsyncTaskStore(inmemory.NewDB())
+
+	got, err := RunAsyncTask(
+		testCtx,
+		store,
+		"test",
+		nil,
+		func(ctx context.Context, p *AsyncTaskProgress) (map[string]interface{}, error) {
+			p.Set(2, 2)
+			return nil, errors.New("boom")
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, models.AsyncTaskFailed, got.Status)
+	assert.Equal(t, "boom", got.Error)
+	assert.Equal(t, 2, got.Completed)
+}
+
+func TestStartAsyncTaskCanceled(t *testing.T) {
+	interval := asyncTaskHeartbeatInterval
+	asyncTaskHeartbeatInterval = 10 * time.Millisecond
+	defer func() { asyncTaskHeartbeatInterval = interval }()
+
+	store := inmemory.NewAsyncTaskStore(inmemory.NewDB())
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	task, err := StartAsyncTask(
+		testCtx,
+		store,
+		"test",
+		nil,
+		func(ctx context.Context, p *AsyncTaskProgress) (map[string]interface{}, error) {
+			defer close(stopped)
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	)
+	require.NoError(t, err)
+
+	<-started
+	_, err = store.Cancel(testCtx, task.UUID)
+	require.NoError(t, err)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task wasn't stopped after it was canceled")
+	}
+
+	got, err := store.Get(testCtx, task.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AsyncTaskCanceled, got.Status)
+	assert.Empty(t, got.Error)
+}
@@ -0,0 +1,38 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// MaintainCollectionIndexes recreates the IVFFLAT index of each collection, so that the
+// index's lists are sized for the documents added since it was built. Collections
+// without an index are indexed once they have enough documents. A failure for one
+// collection is logged, and the remaining collections are still indexed.
+func MaintainCollectionIndexes(ctx context.Context, appState *models.AppState) error {
+	collections, err := appState.DocumentStore.GetCollectionList(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	failed := 0
+	for _, collection := range collections {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if collection.IndexType != "ivfflat" {
+			continue
+		}
+		err := appState.DocumentStore.CreateCollectionIndex(ctx, collection.Name, false)
+		if err != nil {
+			log.Errorf("failed to index collection %s: %v", collection.Name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to index %d of %d collections", failed, len(collections))
+	}
+	return nil
+}
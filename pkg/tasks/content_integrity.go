@@ -0,0 +1,170 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// ContentIntegrityTaskType is the type of the async tasks verifying content hashes.
+const ContentIntegrityTaskType = "content_integrity"
+
+const (
+	integritySessionPageSize = 100
+	integrityMessagePageSize = 1000
+	// maxReportedMismatches bounds the mismatches listed by an IntegrityReport
+	maxReportedMismatches = 100
+)
+
+// VerifyContentHashes hashes the content of every stored message and document, and
+// compares it with the hash stored when the content was written, to detect content
+// corrupted or modified outside of Zep. progress, if not nil, is called with the sessions
+// and collections verified, of their total, as they are. If any content doesn't match its
+// hash, the mismatches are logged and an error is returned, so that the job's status shows
+// them.
+func VerifyContentHashes(
+	ctx context.Context,
+	appState *models.AppState,
+	progress func(completed, total int),
+) (*models.IntegrityReport, error) {
+	if progress == nil {
+		progress = func(int, int) {}
+	}
+	report := &models.IntegrityReport{CheckedAt: time.Now().UTC()}
+
+	counted, err := appState.MemoryStore.ListSessionsOrdered(ctx, 1, 1, "id", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	var collections []models.DocumentCollection
+	if appState.DocumentStore != nil {
+		collections, err = appState.DocumentStore.GetCollectionList(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list collections: %w", err)
+		}
+	}
+	total := counted.TotalCount + len(collections)
+
+	completed := 0
+	var cursor int64
+	for {
+		sessions, err := appState.MemoryStore.ListSessions(ctx, cursor, integritySessionPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		for _, session := range sessions {
+			if err := verifySessionMessages(ctx, appState, session.SessionID, report); err != nil {
+				return nil, err
+			}
+			cursor = session.ID
+			completed++
+			progress(completed, max(total, completed))
+		}
+		if len(sessions) < integritySessionPageSize {
+			break
+		}
+	}
+
+	for _, collection := range collections {
+		documents, err := appState.DocumentStore.GetDocuments(ctx, collection.Name, nil, nil)
+		if err != nil && !errors.Is(err, models.ErrNotFound) {
+			return nil, fmt.Errorf(
+				"failed to get documents of collection %s: %w",
+				collection.Name,
+				err,
+			)
+		}
+		for i := range documents {
+			report.Documents++
+			verifyContentHash(report, models.IntegrityMismatch{
+				Kind:           "document",
+				CollectionName: collection.Name,
+				UUID:           documents[i].UUID,
+				StoredHash:     documents[i].ContentHash,
+			}, documents[i].Content)
+		}
+		completed++
+		progress(completed, max(total, completed))
+	}
+
+	if report.MismatchCount == 0 {
+		log.Infof(
+			"verified the content hashes of %d messages and %d documents, %d unhashed",
+			report.Messages,
+			report.Documents,
+			report.Unhashed,
+		)
+		return report, nil
+	}
+
+	for _, m := range report.Mismatches {
+		if m.Kind == "message" {
+			log.Errorf("content of message %s of session %s doesn't match its hash", m.UUID, m.SessionID)
+		} else {
+			log.Errorf(
+				"content of document %s of collection %s doesn't match its hash",
+				m.UUID,
+				m.CollectionName,
+			)
+		}
+	}
+	return report, fmt.Errorf(
+		"the content of %d of %d messages and documents doesn't match its hash",
+		report.MismatchCount,
+		report.Messages+report.Documents,
+	)
+}
+
+func verifySessionMessages(
+	ctx context.Context,
+	appState *models.AppState,
+	sessionID string,
+	report *models.IntegrityReport,
+) error {
+	for page := 1; ; page++ {
+		list, err := appState.MemoryStore.GetMessageList(
+			ctx,
+			sessionID,
+			page,
+			integrityMessagePageSize,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get messages of session %s: %w", sessionID, err)
+		}
+		for _, message := range list.Messages {
+			report.Messages++
+			verifyContentHash(report, models.IntegrityMismatch{
+				Kind:       "message",
+				SessionID:  sessionID,
+				UUID:       message.UUID,
+				StoredHash: message.ContentHash,
+			}, message.Content)
+		}
+		if len(list.Messages) < integrityMessagePageSize {
+			return nil
+		}
+	}
+}
+
+// verifyContentHash adds mismatch to the report if content doesn't match its stored hash.
+func verifyContentHash(
+	report *models.IntegrityReport,
+	mismatch models.IntegrityMismatch,
+	content string,
+) {
+	if mismatch.StoredHash == "" {
+		report.Unhashed++
+		return
+	}
+	mismatch.ContentHash = models.ContentHash(content)
+	if mismatch.ContentHash == mismatch.StoredHash {
+		return
+	}
+	report.MismatchCount++
+	if len(report.Mismatches) < maxReportedMismatches {
+		report.Mismatches = append(report.Mismatches, mismatch)
+	}
+}
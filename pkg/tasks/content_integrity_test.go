@@ -0,0 +1,85 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+// corruptedMemoryStore lists the content of a message as modified, without updating its
+// stored hash, as if it had been modified in the database.
+type corruptedMemoryStore struct {
+	models.MemoryStore[any]
+	corrupted uuid.UUID
+}
+
+func (s *corruptedMemoryStore) GetMessageList(
+	ctx context.Context,
+	sessionID string,
+	pageNumber int,
+	pageSize int,
+) (*models.MessageListResponse, error) {
+	list, err := s.MemoryStore.GetMessageList(ctx, sessionID, pageNumber, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Messages {
+		if list.Messages[i].UUID == s.corrupted {
+			list.Messages[i].Content += " (modified)"
+		}
+	}
+	return list, nil
+}
+
+func TestVerifyContentHashes(t *testing.T) {
+	ctx := context.Background()
+	integrityState := &models.AppState{Config: &config.Config{}}
+	store := &corruptedMemoryStore{
+		MemoryStore: inmemory.NewMemoryStore(integrityState, inmemory.NewDB()),
+	}
+	integrityState.MemoryStore = store
+
+	for _, sessionID := range []string{"integrity-1", "integrity-2"} {
+		messages := make([]models.Message, 3)
+		for i := range messages {
+			messages[i] = models.Message{
+				UUID:    uuid.New(),
+				Role:    "user",
+				Content: fmt.Sprintf("message %d of %s", i, sessionID),
+			}
+		}
+		err := store.PutMemory(ctx, sessionID, &models.Memory{Messages: messages}, true)
+		require.NoError(t, err)
+		store.corrupted = messages[1].UUID
+	}
+
+	var completed, total int
+	report, err := VerifyContentHashes(ctx, integrityState, func(c, t int) {
+		completed, total = c, t
+	})
+	assert.Error(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, 6, report.Messages)
+	assert.Equal(t, 1, report.MismatchCount)
+	require.Len(t, report.Mismatches, 1)
+	mismatch := report.Mismatches[0]
+	assert.Equal(t, "integrity-2", mismatch.SessionID)
+	assert.Equal(t, store.corrupted, mismatch.UUID)
+	assert.Equal(t, models.ContentHash("message 1 of integrity-2"), mismatch.StoredHash)
+	assert.Equal(t, 2, completed)
+	assert.Equal(t, 2, total)
+
+	store.corrupted = uuid.Nil
+	report, err = VerifyContentHashes(ctx, integrityState, nil)
+	require.NoError(t, err)
+	assert.Zero(t, report.MismatchCount)
+	assert.Zero(t, report.Unhashed)
+}
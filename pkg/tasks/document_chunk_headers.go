@@ -0,0 +1,92 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	chunkHeaderMaxTokens      = 64
+	chunkHeaderNeighborLength = 500
+)
+
+// contextualChunkTexts returns the text to embed for each document, prefixed with a line
+// written by the LLM that places the document within its parent. A document's parent is
+// named by its "title" metadata, or failing that its DocumentID, and the adjacent
+// documents with the same parent are given to the LLM as context. If a header can't be
+// written, the document's content is embedded alone, so that a failing LLM doesn't stop
+// documents from being embedded.
+func contextualChunkTexts(
+	ctx context.Context,
+	appState *models.AppState,
+	docs []models.Document,
+) []string {
+	texts := make([]string, len(docs))
+	for i := range docs {
+		texts[i] = docs[i].Content
+
+		title := documentTitle(&docs[i])
+		data := ChunkHeaderPromptTemplateData{Title: title, Content: docs[i].Content}
+		if i > 0 && title != "" && documentTitle(&docs[i-1]) == title {
+			data.Previous = truncateText(docs[i-1].Content, chunkHeaderNeighborLength)
+		}
+		if i < len(docs)-1 && title != "" && documentTitle(&docs[i+1]) == title {
+			data.Next = truncateText(docs[i+1].Content, chunkHeaderNeighborLength)
+		}
+
+		header, err := chunkHeader(ctx, appState, data)
+		if err != nil {
+			log.Warnf("failed to write chunk header for document %s: %v", docs[i].UUID, err)
+			continue
+		}
+		if header != "" {
+			texts[i] = header + "\n\n" + docs[i].Content
+		}
+	}
+	return texts
+}
+
+func chunkHeader(
+	ctx context.Context,
+	appState *models.AppState,
+	data ChunkHeaderPromptTemplateData,
+) (string, error) {
+	prompt, err := internal.ParsePrompt(chunkHeaderPromptTemplate, data)
+	if err != nil {
+		return "", err
+	}
+
+	header, err := appState.LLMClient.Call(ctx, prompt, llms.WithMaxTokens(chunkHeaderMaxTokens))
+	if err != nil {
+		return "", fmt.Errorf("LLM call failed: %w", err)
+	}
+	return strings.TrimSpace(header), nil
+}
+
+// documentTitle returns the document's "title" metadata, or its DocumentID if it has no
+// title.
+func documentTitle(doc *models.Document) string {
+	if title, ok := doc.Metadata["title"].(string); ok && strings.TrimSpace(title) != "" {
+		return strings.TrimSpace(title)
+	}
+	return doc.DocumentID
+}
+
+// truncateText returns at most maxLength bytes of text, cut at a word boundary where
+// possible.
+func truncateText(text string, maxLength int) string {
+	if len(text) <= maxLength {
+		return text
+	}
+	text = strings.ToValidUTF8(text[:maxLength], "")
+	if i := strings.LastIndexAny(text, " \n\t"); i > 0 {
+		text = text[:i]
+	}
+	return text
+}
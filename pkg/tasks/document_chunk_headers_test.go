@@ -0,0 +1,71 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// chunkHeaderLLM records its prompts, and fails for chunks containing "fail".
+type chunkHeaderLLM struct {
+	models.ZepLLM
+	prompts []string
+}
+
+func (l *chunkHeaderLLM) Call(
+	_ context.Context,
+	prompt string,
+	_ ...llms.CallOption,
+) (string, error) {
+	l.prompts = append(l.prompts, prompt)
+	if strings.Contains(prompt, "fail") {
+		return "", errors.New("LLM unavailable")
+	}
+	return " This chunk is from the handbook. \n", nil
+}
+
+func TestContextualChunkTexts(t *testing.T) {
+	newDoc := func(content string, metadata map[string]interface{}) models.Document {
+		return models.Document{
+			DocumentBase: models.DocumentBase{
+				UUID:     uuid.New(),
+				Content:  content,
+				Metadata: metadata,
+			},
+		}
+	}
+	handbook := map[string]interface{}{"title": "Handbook"}
+	docs := []models.Document{
+		newDoc("first chunk", handbook),
+		newDoc("second chunk", handbook),
+		newDoc("please fail", map[string]interface{}{"title": "Other"}),
+	}
+
+	llm := &chunkHeaderLLM{}
+	texts := contextualChunkTexts(testCtx, &models.AppState{LLMClient: llm}, docs)
+
+	assert.Equal(t, []string{
+		"This chunk is from the handbook.\n\nfirst chunk",
+		"This chunk is from the handbook.\n\nsecond chunk",
+		"please fail",
+	}, texts)
+
+	assert.Len(t, llm.prompts, 3)
+	assert.Contains(t, llm.prompts[0], `titled "Handbook"`)
+	assert.Contains(t, llm.prompts[0], "<next_chunk>\nsecond chunk\n</next_chunk>")
+	assert.Contains(t, llm.prompts[1], "<previous_chunk>\nfirst chunk\n</previous_chunk>")
+	// the neighboring chunk belongs to another document
+	assert.Contains(t, llm.prompts[1], "<next_chunk>\n\n</next_chunk>")
+}
+
+func TestTruncateText(t *testing.T) {
+	assert.Equal(t, "short", truncateText("short", 10))
+	assert.Equal(t, "a few", truncateText("a few words here", 8))
+}
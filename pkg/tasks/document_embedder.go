@@ -87,9 +87,24 @@ func (dt *DocumentEmbedderTask) Process(
 		return fmt.Errorf("DocumentEmbedderTask no documents found for given uuids")
 	}
 
-	texts := make([]string, len(docs))
-	for i, r := range docs {
-		texts[i] = r.Content
+	collection, err := dt.appState.DocumentStore.GetCollection(ctx, collectionName)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf("DocumentEmbedderTask collection not found. Was it deleted? %v", err)
+			// Don't error out
+			return nil
+		}
+		return fmt.Errorf("DocumentEmbedderTask get collection failed: %w", err)
+	}
+
+	var texts []string
+	if collection.ContextualChunkHeaders {
+		texts = contextualChunkTexts(ctx, dt.appState, docs)
+	} else {
+		texts = make([]string, len(docs))
+		for i, r := range docs {
+			texts[i] = r.Content
+		}
 	}
 
 	model, err := llms.GetEmbeddingModel(dt.appState, docType)
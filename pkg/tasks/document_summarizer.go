@@ -0,0 +1,167 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	documentSummaryMaxTokens        = 256
+	defaultDocumentSummaryMinLength = 500
+)
+
+var _ models.Task = &DocumentSummarizerTask{}
+
+func NewDocumentSummarizerTask(appState *models.AppState) *DocumentSummarizerTask {
+	return &DocumentSummarizerTask{
+		BaseTask: BaseTask{
+			appState: appState,
+		},
+	}
+}
+
+// DocumentSummarizerTask summarizes newly created documents and stores the summary with
+// each document, so that searches can return summaries in place of the full content.
+type DocumentSummarizerTask struct {
+	BaseTask
+}
+
+func (dt *DocumentSummarizerTask) Execute(
+	ctx context.Context,
+	msg *message.Message,
+) error {
+	ctx, done := context.WithTimeout(ctx, TaskTimeout*time.Second)
+	defer done()
+
+	collectionName := msg.Metadata.Get("collection_name")
+	if collectionName == "" {
+		return errors.New("DocumentSummarizerTask collection_name is empty")
+	}
+	log.Debugf("DocumentSummarizerTask called for collection %s", collectionName)
+
+	var tasks []models.DocEmbeddingTask
+	if err := json.Unmarshal(msg.Payload, &tasks); err != nil {
+		return err
+	}
+
+	if err := dt.Process(ctx, collectionName, tasks); err != nil {
+		return err
+	}
+
+	msg.Ack()
+
+	return nil
+}
+
+func (dt *DocumentSummarizerTask) Process(
+	ctx context.Context,
+	collectionName string,
+	docTasks []models.DocEmbeddingTask,
+) error {
+	promptTemplate, err := dt.promptTemplate()
+	if err != nil {
+		return err
+	}
+
+	uuids := make([]uuid.UUID, len(docTasks))
+	for i, r := range docTasks {
+		uuids[i] = r.UUID
+	}
+
+	docs, err := dt.appState.DocumentStore.GetDocuments(ctx, collectionName, uuids, nil)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf(
+				"DocumentSummarizerTask GetDocuments not found. Were the records deleted? %v",
+				err,
+			)
+			// Don't error out
+			return nil
+		}
+		return fmt.Errorf("DocumentSummarizerTask retrieve documents failed: %w", err)
+	}
+
+	minLength := dt.appState.Config.Extractors.Documents.Summarizer.MinLength
+	if minLength <= 0 {
+		minLength = defaultDocumentSummaryMinLength
+	}
+
+	var summarized []models.Document
+	for _, doc := range docs {
+		if len(doc.Content) < minLength || doc.Summary != "" {
+			continue
+		}
+
+		prompt, err := internal.ParsePrompt(
+			promptTemplate,
+			DocumentSummaryPromptTemplateData{Content: doc.Content},
+		)
+		if err != nil {
+			return fmt.Errorf("DocumentSummarizerTask: %w", err)
+		}
+
+		summary, err := dt.appState.LLMClient.Call(
+			ctx,
+			prompt,
+			llms.WithMaxTokens(documentSummaryMaxTokens),
+		)
+		if err != nil {
+			return fmt.Errorf("DocumentSummarizerTask summarize failed: %w", err)
+		}
+
+		summary = strings.TrimSpace(summary)
+		if summary == "" {
+			continue
+		}
+		summarized = append(summarized, models.Document{
+			DocumentBase: models.DocumentBase{UUID: doc.UUID, Summary: summary},
+		})
+	}
+	if len(summarized) == 0 {
+		return nil
+	}
+
+	err = dt.appState.DocumentStore.UpdateDocuments(ctx, collectionName, summarized)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf(
+				"DocumentSummarizerTask UpdateDocuments not found. Were the records deleted? %v",
+				err,
+			)
+			// Don't error out
+			return nil
+		}
+		return fmt.Errorf("DocumentSummarizerTask save summaries failed: %w", err)
+	}
+	return nil
+}
+
+// promptTemplate returns the configured custom prompt, if any, or the default prompt.
+func (dt *DocumentSummarizerTask) promptTemplate() (string, error) {
+	custom := strings.TrimSpace(dt.appState.Config.CustomPrompts.DocumentSummaryPrompt)
+	if custom == "" {
+		return defaultDocumentSummaryPromptTemplate, nil
+	}
+	if !strings.Contains(custom, "{{.Content}}") {
+		return "", errors.New(
+			"wrong document summary prompt format. please make sure it contains the " +
+				"identifier {{.Content}}",
+		)
+	}
+	return custom, nil
+}
+
+func (dt *DocumentSummarizerTask) HandleError(err error) {
+	log.Errorf("DocumentSummarizerTask failed: %v", err)
+}
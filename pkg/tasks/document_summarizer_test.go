@@ -0,0 +1,80 @@
+package tasks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// summaryDocumentStore implements the document methods of DocumentStore used by the
+// summarizer.
+type summaryDocumentStore struct {
+	models.DocumentStore[any]
+	documents []models.Document
+	updated   []models.Document
+}
+
+func (s *summaryDocumentStore) GetDocuments(
+	_ context.Context,
+	_ string,
+	_ []uuid.UUID,
+	_ []string,
+) ([]models.Document, error) {
+	return s.documents, nil
+}
+
+func (s *summaryDocumentStore) UpdateDocuments(
+	_ context.Context,
+	_ string,
+	documents []models.Document,
+) error {
+	s.updated = append(s.updated, documents...)
+	return nil
+}
+
+func TestDocumentSummarizerTask(t *testing.T) {
+	long := models.Document{
+		DocumentBase: models.DocumentBase{UUID: uuid.New(), Content: strings.Repeat("a", 20)},
+	}
+	short := models.Document{
+		DocumentBase: models.DocumentBase{UUID: uuid.New(), Content: "short"},
+	}
+	summarized := models.Document{
+		DocumentBase: models.DocumentBase{
+			UUID:    uuid.New(),
+			Content: strings.Repeat("b", 20),
+			Summary: "existing",
+		},
+	}
+	documentStore := &summaryDocumentStore{
+		documents: []models.Document{long, short, summarized},
+	}
+
+	cfg := &config.Config{}
+	cfg.Extractors.Documents.Summarizer.MinLength = 10
+	summaryAppState := &models.AppState{
+		Config:        cfg,
+		LLMClient:     &cannedLLM{response: "  A summary.\n"},
+		DocumentStore: documentStore,
+	}
+
+	task := NewDocumentSummarizerTask(summaryAppState)
+	err := task.Process(testCtx, "collection", []models.DocEmbeddingTask{
+		{UUID: long.UUID}, {UUID: short.UUID}, {UUID: summarized.UUID},
+	})
+	require.NoError(t, err)
+	require.Len(t, documentStore.updated, 1)
+	assert.Equal(t, long.UUID, documentStore.updated[0].UUID)
+	assert.Equal(t, "A summary.", documentStore.updated[0].Summary)
+
+	cfg.CustomPrompts.DocumentSummaryPrompt = "Summarize this."
+	err = task.Process(testCtx, "collection", []models.DocEmbeddingTask{{UUID: long.UUID}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/viterin/vek/vek32"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/webhooks"
+)
+
+const (
+	defaultDriftSampleSize    = 20
+	defaultDriftMinSimilarity = 0.95
+	// driftSessionAttempts bounds the sessions read per sampled embedding, as sessions
+	// may have no embedded messages
+	driftSessionAttempts = 3
+)
+
+// CheckEmbeddingDrift embeds the text of stored message embeddings, sampled from random
+// sessions, again with the configured embedding model, and compares the fresh embeddings
+// with those stored. If the mean cosine similarity is below MinSimilarity, or any
+// dimensions differ, the drift is logged, an embeddings.drift_detected webhook is sent, and
+// an error is returned, so that the job's status shows the drift.
+func CheckEmbeddingDrift(
+	ctx context.Context,
+	appState *models.AppState,
+	sender *webhooks.Sender,
+	cfg config.EmbeddingDriftConfig,
+) (*models.EmbeddingDriftReport, error) {
+	sampleSize := cfg.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultDriftSampleSize
+	}
+	minSimilarity := cfg.MinSimilarity
+	if minSimilarity <= 0 {
+		minSimilarity = defaultDriftMinSimilarity
+	}
+
+	sample, err := sampleMessageEmbeddings(ctx, appState, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.EmbeddingDriftReport{Sampled: len(sample), CheckedAt: time.Now().UTC()}
+	if len(sample) == 0 {
+		return report, nil
+	}
+
+	texts := make([]string, len(sample))
+	for i, s := range sample {
+		texts[i] = s.Text
+	}
+	model, err := llms.GetEmbeddingModel(appState, "message")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message embedding model: %w", err)
+	}
+	fresh, err := llms.EmbedTexts(ctx, appState, model, "message", texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed sampled messages: %w", err)
+	}
+	if len(fresh) != len(sample) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(sample), len(fresh))
+	}
+
+	var total float64
+	report.MinSimilarity = 1
+	for i, s := range sample {
+		var similarity float64
+		if len(s.Embedding) == len(fresh[i]) {
+			similarity = float64(vek32.CosineSimilarity(s.Embedding, fresh[i]))
+		} else {
+			report.DimensionMismatches++
+		}
+		total += similarity
+		report.MinSimilarity = math.Min(report.MinSimilarity, similarity)
+	}
+	report.MeanSimilarity = total / float64(len(sample))
+	report.Drifted = report.MeanSimilarity < minSimilarity || report.DimensionMismatches > 0
+	if !report.Drifted {
+		log.Debugf("no embedding drift: mean similarity %.4f of %d embeddings",
+			report.MeanSimilarity, report.Sampled)
+		return report, nil
+	}
+
+	log.Errorf(
+		"embedding drift detected: mean similarity %.4f, min similarity %.4f, "+
+			"%d of %d embeddings with other dimensions. Has the embedding model changed?",
+		report.MeanSimilarity,
+		report.MinSimilarity,
+		report.DimensionMismatches,
+		report.Sampled,
+	)
+	if err := sender.Send(ctx, webhooks.NewEvent(webhooks.EventEmbeddingDrift, report)); err != nil {
+		log.Errorf("failed to send embedding drift webhook: %v", err)
+	}
+	return report, fmt.Errorf(
+		"embedding drift detected: mean similarity %.4f is below %.4f or dimensions differ",
+		report.MeanSimilarity,
+		minSimilarity,
+	)
+}
+
+// sampleMessageEmbeddings returns up to n stored message embeddings with text, each from a
+// different random session.
+func sampleMessageEmbeddings(
+	ctx context.Context,
+	appState *models.AppState,
+	n int,
+) ([]models.TextData, error) {
+	list, err := appState.MemoryStore.ListSessionsOrdered(ctx, 1, 1, "id", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sessions: %w", err)
+	}
+
+	var sample []models.TextData
+	pages := rand.Perm(list.TotalCount) //nolint:gosec
+	for _, page := range pages[:min(len(pages), n*driftSessionAttempts)] {
+		if len(sample) == n {
+			break
+		}
+		sessions, err := appState.MemoryStore.ListSessionsOrdered(ctx, page+1, 1, "id", true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		if len(sessions.Sessions) == 0 {
+			continue
+		}
+		embeddings, err := appState.MemoryStore.GetMessageEmbeddings(
+			ctx,
+			sessions.Sessions[0].SessionID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get message embeddings: %w", err)
+		}
+		// pruned messages keep their embeddings but have no text to embed again
+		var candidates []models.TextData
+		for _, e := range embeddings {
+			if e.Text != "" && len(e.Embedding) > 0 {
+				candidates = append(candidates, e)
+			}
+		}
+		if len(candidates) > 0 {
+			sample = append(sample, candidates[rand.Intn(len(candidates))]) //nolint:gosec
+		}
+	}
+	return sample, nil
+}
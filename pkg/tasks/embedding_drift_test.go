@@ -0,0 +1,135 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+	"github.com/getzep/zep/pkg/webhooks"
+)
+
+func TestCheckEmbeddingDrift(t *testing.T) {
+	var events []webhooks.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event webhooks.Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+	}))
+	defer server.Close()
+	sender := webhooks.NewSender(&config.WebhooksConfig{URL: server.URL})
+
+	db := inmemory.NewDB()
+	driftState := &models.AppState{Config: &config.Config{}, LLMClient: &scriptedLLM{}}
+	driftState.Config.Extractors.Messages.Embeddings = config.EmbeddingsConfig{
+		Service:    llms.FakeLLMService,
+		Dimensions: 8,
+	}
+	driftState.MemoryStore = inmemory.NewMemoryStore(driftState, db)
+
+	cfg := config.EmbeddingDriftConfig{SampleSize: 2}
+	report, err := CheckEmbeddingDrift(testCtx, driftState, sender, cfg)
+	require.NoError(t, err)
+	assert.Zero(t, report.Sampled)
+
+	// the second session's messages aren't embedded, and the third's were embedded as
+	// the text of another message
+	embedded := map[string]func(text string) string{
+		"drift-1": func(text string) string { return text },
+		"drift-2": nil,
+		"drift-3": func(text string) string { return "something else entirely" },
+	}
+	for _, sessionID := range []string{"drift-1", "drift-2", "drift-3"} {
+		messages := make([]models.Message, 3)
+		for i := range messages {
+			messages[i] = models.Message{
+				UUID:    uuid.New(),
+				Role:    "user",
+				Content: fmt.Sprintf("message %d of %s", i, sessionID),
+			}
+		}
+		err := driftState.MemoryStore.PutMemory(
+			testCtx,
+			sessionID,
+			&models.Memory{Messages: messages},
+			true,
+		)
+		require.NoError(t, err)
+		textOf := embedded[sessionID]
+		if textOf == nil {
+			continue
+		}
+		embeddings := make([]models.TextData, len(messages))
+		for i, m := range messages {
+			embeddings[i] = models.TextData{
+				TextUUID:  m.UUID,
+				Embedding: llms.FakeEmbeddings([]string{textOf(m.Content)}, 8)[0],
+			}
+		}
+		err = driftState.MemoryStore.CreateMessageEmbeddings(testCtx, sessionID, embeddings)
+		require.NoError(t, err)
+	}
+
+	report, err = CheckEmbeddingDrift(testCtx, driftState, sender, cfg)
+	assert.ErrorContains(t, err, "embedding drift detected")
+	assert.Equal(t, 2, report.Sampled)
+	assert.True(t, report.Drifted)
+	assert.Less(t, report.MinSimilarity, 0.95)
+	// the embedding sampled from the first session matches its fresh embedding
+	assert.InDelta(t, 1, report.MeanSimilarity*2-report.MinSimilarity, 1e-4)
+	require.Len(t, events, 1)
+	assert.Equal(t, webhooks.EventEmbeddingDrift, events[0].Type)
+
+	// a change of dimensions is drift, whatever the similarity threshold
+	driftState.Config.Extractors.Messages.Embeddings.Dimensions = 4
+	report, err = CheckEmbeddingDrift(testCtx, driftState, sender, config.EmbeddingDriftConfig{
+		SampleSize:    1,
+		MinSimilarity: -1,
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, report.DimensionMismatches)
+	assert.Len(t, events, 2)
+}
+
+func TestCheckEmbeddingDriftUnchanged(t *testing.T) {
+	db := inmemory.NewDB()
+	driftState := &models.AppState{Config: &config.Config{}, LLMClient: &scriptedLLM{}}
+	driftState.Config.Extractors.Messages.Embeddings = config.EmbeddingsConfig{
+		Service:    llms.FakeLLMService,
+		Dimensions: 8,
+	}
+	driftState.MemoryStore = inmemory.NewMemoryStore(driftState, db)
+
+	m := models.Message{UUID: uuid.New(), Role: "user", Content: "unchanged"}
+	err := driftState.MemoryStore.PutMemory(
+		testCtx,
+		"unchanged",
+		&models.Memory{Messages: []models.Message{m}},
+		true,
+	)
+	require.NoError(t, err)
+	err = driftState.MemoryStore.CreateMessageEmbeddings(testCtx, "unchanged", []models.TextData{
+		{TextUUID: m.UUID, Embedding: llms.FakeEmbeddings([]string{m.Content}, 8)[0]},
+	})
+	require.NoError(t, err)
+
+	report, err := CheckEmbeddingDrift(
+		testCtx,
+		driftState,
+		webhooks.NewSender(&config.WebhooksConfig{}),
+		config.EmbeddingDriftConfig{},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Sampled)
+	assert.False(t, report.Drifted)
+	assert.InDelta(t, 1, report.MeanSimilarity, 1e-4)
+}
@@ -2,6 +2,7 @@ package tasks
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
 	"net/http/httptrace"
 	"time"
@@ -10,17 +11,31 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/getzep/zep/pkg/egress"
 )
 
 // NewRetryableHTTPClient returns a new retryable HTTP client with the given retryMax and timeout.
-// The retryable HTTP transport is wrapped in an OpenTelemetry transport.
+// The retryable HTTP transport is wrapped in an OpenTelemetry transport, and its requests
+// follow the egress policy.
 func NewRetryableHTTPClient(retryMax int, timeout time.Duration) *http.Client {
+	return NewRetryableHTTPClientWithTLS(retryMax, timeout, nil)
+}
+
+// NewRetryableHTTPClientWithTLS returns a retryable HTTP client like NewRetryableHTTPClient,
+// whose TLS connections use tlsConfig.
+func NewRetryableHTTPClientWithTLS(
+	retryMax int,
+	timeout time.Duration,
+	tlsConfig *tls.Config,
+) *http.Client {
 	retryableHTTPClient := retryablehttp.NewClient()
 	retryableHTTPClient.RetryMax = retryMax
 	retryableHTTPClient.HTTPClient.Timeout = timeout
 	retryableHTTPClient.Logger = log
 	retryableHTTPClient.Backoff = retryablehttp.DefaultBackoff
 	retryableHTTPClient.CheckRetry = retryablehttp.DefaultRetryPolicy
+	egress.ConfigureClient(retryableHTTPClient, tlsConfig)
 
 	httpClient := &http.Client{
 		Transport: otelhttp.NewTransport(
@@ -0,0 +1,184 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.Task = &MessageCompactorTask{}
+
+// MessageCompactorTask keeps the number of messages in a session under the configured
+// memory.max_session_messages soft limit. Once a session exceeds the limit, the oldest
+// messages are compacted:
+// - messages not yet captured by the running summary are summarized into it
+// - the raw message content is pruned. Embeddings are optionally retained so that
+// compacted messages remain searchable. Messages scoring at least
+// memory.importance.retain_threshold for importance are kept.
+type MessageCompactorTask struct {
+	BaseTask
+}
+
+func NewMessageCompactorTask(appState *models.AppState) *MessageCompactorTask {
+	return &MessageCompactorTask{
+		BaseTask: BaseTask{
+			appState: appState,
+		},
+	}
+}
+
+func (t *MessageCompactorTask) Execute(
+	ctx context.Context,
+	msg *message.Message,
+) error {
+	ctx, done := context.WithTimeout(ctx, TaskTimeout*time.Second)
+	defer done()
+
+	sessionID := msg.Metadata.Get("session_id")
+	if sessionID == "" {
+		return errors.New("MessageCompactorTask session_id is empty")
+	}
+
+	log.Debugf("MessageCompactorTask called for session %s", sessionID)
+
+	maxMessages := t.appState.Config.Memory.MaxSessionMessages
+	if maxMessages == 0 {
+		msg.Ack()
+		return nil
+	}
+
+	candidates, err := t.appState.MemoryStore.GetCompactionCandidates(ctx, sessionID, maxMessages)
+	if err != nil {
+		return fmt.Errorf("MessageCompactorTask get compaction candidates failed: %w", err)
+	}
+
+	if len(candidates.Messages) == 0 {
+		msg.Ack()
+		return nil
+	}
+
+	// held sessions are neither summarized nor pruned until the hold is released
+	if err := checkSessionHold(ctx, t.appState, sessionID); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			log.Debugf("MessageCompactorTask skipped session %s under legal hold", sessionID)
+			msg.Ack()
+			return nil
+		}
+		return fmt.Errorf("MessageCompactorTask check legal hold failed: %w", err)
+	}
+
+	if len(candidates.Unsummarized) > 0 {
+		err = t.summarize(ctx, sessionID, candidates.Unsummarized)
+		if err != nil {
+			return fmt.Errorf("MessageCompactorTask summarize failed: %w", err)
+		}
+	}
+
+	pruned := prunedCandidates(candidates.Messages, t.appState.Config.Memory.Importance.RetainThreshold)
+	if len(pruned) == len(candidates.Messages) {
+		compactionPoint := candidates.Messages[len(candidates.Messages)-1].UUID
+		err = t.appState.MemoryStore.CompactMessages(
+			ctx,
+			sessionID,
+			compactionPoint,
+			t.appState.Config.Memory.RetainCompactedEmbeddings,
+		)
+	} else {
+		err = t.appState.MemoryStore.PruneMessages(
+			ctx,
+			sessionID,
+			pruned,
+			t.appState.Config.Memory.RetainCompactedEmbeddings,
+		)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf("MessageCompactorTask CompactMessages not found. Were the records deleted?")
+			// Don't error out
+			msg.Ack()
+			return nil
+		}
+		if errors.Is(err, models.ErrConflict) {
+			// the session was placed under legal hold since it was checked
+			log.Debugf("MessageCompactorTask skipped session %s under legal hold", sessionID)
+			msg.Ack()
+			return nil
+		}
+		return fmt.Errorf("MessageCompactorTask compact messages failed: %w", err)
+	}
+
+	log.Debugf(
+		"MessageCompactorTask compacted %d messages for session %s",
+		len(pruned),
+		sessionID,
+	)
+
+	msg.Ack()
+
+	return nil
+}
+
+// summarize folds the given messages into the session's running summary. The new
+// SummaryPoint is the most recent of the given messages.
+func (t *MessageCompactorTask) summarize(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+) error {
+	summary, err := t.appState.MemoryStore.GetSummary(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("get summary failed: %w", err)
+	}
+
+	var currentSummaryContent string
+	if summary != nil {
+		currentSummaryContent = summary.Content
+	}
+
+	messages = dropEmptyMessages(messages)
+	if len(messages) == 0 {
+		return nil
+	}
+
+	summarizer := NewMessageSummaryTask(t.appState)
+	maxInputTokens, err := summarizer.summarizerMaxInputTokens(0)
+	if err != nil {
+		return err
+	}
+
+	newSummary, err := summarizer.processOverLimitMessages(
+		ctx,
+		messages,
+		maxInputTokens,
+		currentSummaryContent,
+	)
+	if err != nil {
+		return err
+	}
+
+	return t.appState.MemoryStore.CreateSummary(ctx, sessionID, newSummary)
+}
+
+// prunedCandidates returns the UUIDs of the compaction candidates to prune: those scoring
+// less than retainThreshold for importance, or that haven't been scored. If
+// retainThreshold is 0, all candidates are pruned.
+func prunedCandidates(candidates []models.Message, retainThreshold float64) []uuid.UUID {
+	pruned := make([]uuid.UUID, 0, len(candidates))
+	for i := range candidates {
+		importance, ok := models.MessageImportance(&candidates[i])
+		if retainThreshold <= 0 || !ok || importance < retainThreshold {
+			pruned = append(pruned, candidates[i].UUID)
+		}
+	}
+	return pruned
+}
+
+func (t *MessageCompactorTask) HandleError(err error) {
+	log.Errorf("MessageCompactorTask failed: %v", err)
+}
@@ -0,0 +1,221 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
+)
+
+const contradictionMaxTokens = 512
+
+var _ models.Task = &MessageContradictionTask{}
+
+func NewMessageContradictionTask(appState *models.AppState) *MessageContradictionTask {
+	return &MessageContradictionTask{
+		BaseTask: BaseTask{
+			appState: appState,
+		},
+		resolve: appState.Config.Extractors.Messages.Contradictions.Resolve,
+		roles:   roles.FromConfig(appState.Config),
+	}
+}
+
+// MessageContradictionTask checks new messages against the facts synthesized about the
+// session's user, and records each contradiction found as a conflict on the fact. If
+// resolve is set, the LLM also resolves the conflict, so that the user's facts don't go
+// stale between reflections.
+type MessageContradictionTask struct {
+	BaseTask
+	resolve bool
+	roles   *roles.Registry
+}
+
+// contradictions are the LLM's conflicts between facts and messages, both numbered from 1.
+// Resolution and Revised are only set when conflicts are resolved.
+type contradictions struct {
+	Conflicts []struct {
+		Fact        int    `json:"fact"`
+		Line        int    `json:"line"`
+		Explanation string `json:"explanation"`
+		Resolution  string `json:"resolution"`
+		Revised     string `json:"revised"`
+	} `json:"conflicts"`
+}
+
+func (t *MessageContradictionTask) Execute(
+	ctx context.Context,
+	msg *message.Message,
+) error {
+	ctx, done := context.WithTimeout(ctx, TaskTimeout*time.Second)
+	defer done()
+
+	sessionID := msg.Metadata.Get("session_id")
+	if sessionID == "" {
+		return errors.New("MessageContradictionTask session_id is empty")
+	}
+
+	log.Debugf("MessageContradictionTask called for session %s", sessionID)
+
+	messages, err := messageTaskPayloadToMessages(ctx, t.appState, msg)
+	if err != nil {
+		return fmt.Errorf(
+			"MessageContradictionTask messageTaskPayloadToMessages failed: %w",
+			err,
+		)
+	}
+
+	err = t.process(ctx, sessionID, messages)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf("MessageContradictionTask session %s not found. Was it deleted?", sessionID)
+			msg.Ack()
+			return nil
+		}
+		return fmt.Errorf("MessageContradictionTask failed: %w", err)
+	}
+
+	msg.Ack()
+
+	return nil
+}
+
+func (t *MessageContradictionTask) process(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+) error {
+	// only the user's own messages speak to facts about them
+	messages = slices.DeleteFunc(dropEmptyMessages(messages), func(m models.Message) bool {
+		kind := t.roles.Kind(m.Role)
+		return kind != "" && kind != roles.User
+	})
+	if len(messages) == 0 {
+		return nil
+	}
+
+	session, err := t.appState.MemoryStore.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID == nil || *session.UserID == "" {
+		return nil
+	}
+	user, err := t.appState.UserStore.Get(ctx, *session.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	facts, err := GetUserFacts(user)
+	if err != nil {
+		return err
+	}
+	if len(facts.Facts) == 0 {
+		return nil
+	}
+
+	factLines := make([]string, len(facts.Facts))
+	for i, fact := range facts.Facts {
+		factLines[i] = fmt.Sprintf("%d. %s", i+1, fact.Fact)
+	}
+	lines := make([]string, len(messages))
+	for i, m := range messages {
+		lines[i] = fmt.Sprintf("%d. %s: %s", i+1, t.roles.Label(m.Role), m.Content)
+	}
+	prompt, err := internal.ParsePrompt(contradictionPromptTemplate, ContradictionPromptTemplateData{
+		Resolve:        t.resolve,
+		FactsJoined:    strings.Join(factLines, "\n"),
+		MessagesJoined: strings.Join(lines, "\n"),
+	})
+	if err != nil {
+		return err
+	}
+	response, err := t.appState.LLMClient.Call(ctx, prompt, llms.WithMaxTokens(contradictionMaxTokens))
+	if err != nil {
+		return fmt.Errorf("failed to detect contradictions: %w", err)
+	}
+	parsed, err := parseContradictions(response)
+	if err != nil {
+		return err
+	}
+
+	detectedAt := time.Now().UTC()
+	retracted := make(map[int]bool)
+	var found bool
+	for _, c := range parsed.Conflicts {
+		if c.Fact < 1 || c.Fact > len(facts.Facts) || c.Line < 1 || c.Line > len(messages) ||
+			retracted[c.Fact-1] {
+			continue
+		}
+		found = true
+		fact := &facts.Facts[c.Fact-1]
+		m := messages[c.Line-1]
+		conflict := models.FactConflict{
+			MessageUUID: m.UUID,
+			SessionID:   sessionID,
+			Explanation: strings.TrimSpace(c.Explanation),
+			DetectedAt:  detectedAt,
+		}
+		if t.resolve {
+			switch revised := strings.TrimSpace(c.Revised); {
+			case c.Resolution == "revise" && revised != "":
+				conflict.Resolution = models.FactRevised
+				conflict.PreviousFact = fact.Fact
+				fact.Fact = revised
+				if !slices.Contains(fact.MessageUUIDs, m.UUID) {
+					fact.MessageUUIDs = append(fact.MessageUUIDs, m.UUID)
+				}
+			case c.Resolution == "retract":
+				conflict.Resolution = models.FactRetracted
+				retracted[c.Fact-1] = true
+			case c.Resolution == "keep":
+				conflict.Resolution = models.FactKept
+			}
+		}
+		fact.Conflicts = append(fact.Conflicts, conflict)
+	}
+	if !found {
+		return nil
+	}
+
+	kept := make([]models.UserFact, 0, len(facts.Facts))
+	for i, fact := range facts.Facts {
+		if retracted[i] {
+			facts.Retracted = append(facts.Retracted, fact)
+		} else {
+			kept = append(kept, fact)
+		}
+	}
+	facts.Facts = kept
+
+	return storeUserFacts(ctx, t.appState, user.UserID, facts)
+}
+
+func (t *MessageContradictionTask) HandleError(err error) {
+	log.Errorf("MessageContradictionTask failed: %v", err)
+}
+
+// parseContradictions reads the JSON object in an LLM response, ignoring any text or code
+// fence around it.
+func parseContradictions(response string) (*contradictions, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no contradictions found in LLM response: %q", response)
+	}
+
+	var parsed contradictions
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse contradictions: %w", err)
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,128 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func newContradictionState(t *testing.T, llm *scriptedLLM, resolve bool) *models.AppState {
+	db := inmemory.NewDB()
+	appState := &models.AppState{Config: &config.Config{}, LLMClient: llm}
+	appState.Config.Extractors.Messages.Contradictions = config.ContradictionExtractorConfig{
+		Enabled: true,
+		Resolve: resolve,
+	}
+	appState.MemoryStore = inmemory.NewMemoryStore(appState, db)
+	appState.UserStore = inmemory.NewUserStore(db)
+
+	userID := "contradiction-user"
+	_, err := appState.UserStore.Create(testCtx, &models.CreateUserRequest{UserID: userID})
+	require.NoError(t, err)
+	_, err = appState.MemoryStore.CreateSession(testCtx, &models.CreateSessionRequest{
+		SessionID: "contradiction-session",
+		UserID:    &userID,
+	})
+	require.NoError(t, err)
+	require.NoError(t, storeUserFacts(testCtx, appState, userID, &models.UserFacts{
+		Facts: []models.UserFact{
+			{Fact: "Prefers concise answers", MessageUUIDs: []uuid.UUID{}},
+			{Fact: "Has a project deadline in March", MessageUUIDs: []uuid.UUID{}},
+			{Fact: "Lives in Lisbon", MessageUUIDs: []uuid.UUID{}},
+		},
+	}))
+	return appState
+}
+
+func contradictionFacts(t *testing.T, appState *models.AppState) *models.UserFacts {
+	user, err := appState.UserStore.Get(testCtx, "contradiction-user")
+	require.NoError(t, err)
+	facts, err := GetUserFacts(user)
+	require.NoError(t, err)
+	return facts
+}
+
+func TestMessageContradictionTask(t *testing.T) {
+	llm := &scriptedLLM{responses: []string{"```json\n" + `{"conflicts": [` +
+		`{"fact": 2, "line": 1, "explanation": "The deadline moved", "resolution": "revise", ` +
+		`"revised": "Has a project deadline in April"}, ` +
+		`{"fact": 3, "line": 2, "explanation": "Moved away", "resolution": "retract"}, ` +
+		`{"fact": 3, "line": 1, "explanation": "Already retracted", "resolution": "keep"}, ` +
+		`{"fact": 9, "line": 1, "explanation": "No such fact"}]}` + "\n```"}}
+	appState := newContradictionState(t, llm, true)
+
+	messages := []models.Message{
+		{UUID: uuid.New(), Role: "user", Content: "The deadline slipped to April."},
+		{UUID: uuid.New(), Role: "user", Content: "I moved to Porto last week."},
+		{UUID: uuid.New(), Role: "assistant", Content: "You live in Lisbon, right?"},
+	}
+	task := NewMessageContradictionTask(appState)
+	require.NoError(t, task.process(testCtx, "contradiction-session", messages))
+
+	require.Len(t, llm.prompts, 1)
+	assert.Contains(t, llm.prompts[0], "2. Has a project deadline in March")
+	assert.Contains(t, llm.prompts[0], "2. user: I moved to Porto last week.")
+	assert.NotContains(t, llm.prompts[0], "You live in Lisbon")
+	assert.Contains(t, llm.prompts[0], `"resolution": "revise"`)
+
+	facts := contradictionFacts(t, appState)
+	require.Len(t, facts.Facts, 2)
+	assert.Empty(t, facts.Facts[0].Conflicts)
+	revised := facts.Facts[1]
+	assert.Equal(t, "Has a project deadline in April", revised.Fact)
+	assert.Equal(t, []uuid.UUID{messages[0].UUID}, revised.MessageUUIDs)
+	require.Len(t, revised.Conflicts, 1)
+	assert.Equal(t, models.FactRevised, revised.Conflicts[0].Resolution)
+	assert.Equal(t, "Has a project deadline in March", revised.Conflicts[0].PreviousFact)
+	assert.Equal(t, "contradiction-session", revised.Conflicts[0].SessionID)
+	assert.False(t, revised.Disputed())
+
+	require.Len(t, facts.Retracted, 1)
+	assert.Equal(t, "Lives in Lisbon", facts.Retracted[0].Fact)
+	require.Len(t, facts.Retracted[0].Conflicts, 1)
+	assert.Equal(t, models.FactRetracted, facts.Retracted[0].Conflicts[0].Resolution)
+	assert.Equal(t, messages[1].UUID, facts.Retracted[0].Conflicts[0].MessageUUID)
+}
+
+func TestMessageContradictionTaskUnresolved(t *testing.T) {
+	llm := &scriptedLLM{responses: []string{
+		`{"conflicts": [{"fact": 1, "line": 1, "explanation": "Asked for detail", ` +
+			`"resolution": "retract"}]}`,
+		`{"conflicts": []}`,
+	}}
+	appState := newContradictionState(t, llm, false)
+
+	messages := []models.Message{
+		{UUID: uuid.New(), Role: "user", Content: "Please explain in detail from now on."},
+	}
+	task := NewMessageContradictionTask(appState)
+	require.NoError(t, task.process(testCtx, "contradiction-session", messages))
+	assert.NotContains(t, llm.prompts[0], `"resolution"`)
+
+	// without resolve, the conflict is only flagged
+	facts := contradictionFacts(t, appState)
+	require.Len(t, facts.Facts, 3)
+	assert.Empty(t, facts.Retracted)
+	assert.Equal(t, "Prefers concise answers", facts.Facts[0].Fact)
+	require.Len(t, facts.Facts[0].Conflicts, 1)
+	assert.Empty(t, facts.Facts[0].Conflicts[0].Resolution)
+	assert.True(t, facts.Facts[0].Disputed())
+
+	require.NoError(t, task.process(testCtx, "contradiction-session", messages))
+	assert.Len(t, contradictionFacts(t, appState).Facts[0].Conflicts, 1)
+}
+
+func TestParseContradictions(t *testing.T) {
+	_, err := parseContradictions("No contradictions.")
+	assert.Error(t, err)
+
+	parsed, err := parseContradictions(`{"conflicts": []}`)
+	require.NoError(t, err)
+	assert.Empty(t, parsed.Conflicts)
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/getzep/zep/pkg/llms"
 	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
+	"github.com/google/uuid"
 )
 
 var _ models.Task = &MessageEmbedderTask{}
@@ -19,11 +22,13 @@ func NewMessageEmbedderTask(appState *models.AppState) *MessageEmbedderTask {
 		BaseTask: BaseTask{
 			appState: appState,
 		},
+		roles: roles.FromConfig(appState.Config),
 	}
 }
 
 type MessageEmbedderTask struct {
 	BaseTask
+	roles *roles.Registry
 }
 
 func (t *MessageEmbedderTask) Execute(
@@ -63,25 +68,53 @@ func (t *MessageEmbedderTask) Process(
 	sessionID string,
 	msgs []models.Message,
 ) error {
+	// messages of roles that aren't searchable aren't embedded
+	msgs = slices.DeleteFunc(slices.Clone(msgs), func(m models.Message) bool {
+		return !t.roles.Searchable(m.Role)
+	})
+	if len(msgs) == 0 {
+		return nil
+	}
+
 	messageType := "message"
-	texts := messageToStringSlice(msgs, false)
+	embeddingRecords := make([]models.TextData, len(msgs))
+	for i, text := range messageToStringSlice(msgs, false) {
+		embeddingRecords[i] = models.TextData{TextUUID: msgs[i].UUID, Text: text}
+	}
+
+	var err error
+	switch cfg := t.appState.Config.Extractors.Messages.Embeddings; {
+	case cfg.TurnPairs:
+		embeddingRecords, err = t.turnPairs(ctx, sessionID, msgs)
+		if err != nil {
+			return fmt.Errorf("MessageEmbedderTask get turn pairs failed: %w", err)
+		}
+	case cfg.Window > 1:
+		embeddingRecords, err = t.messageWindows(ctx, sessionID, msgs, cfg.Window)
+		if err != nil {
+			return fmt.Errorf("MessageEmbedderTask get message windows failed: %w", err)
+		}
+	}
+	if len(embeddingRecords) == 0 {
+		return nil
+	}
 
 	model, err := llms.GetEmbeddingModel(t.appState, messageType)
 	if err != nil {
 		return fmt.Errorf("MessageEmbedderTask get message embedding model failed: %w", err)
 	}
 
+	texts := make([]string, len(embeddingRecords))
+	for i, r := range embeddingRecords {
+		texts[i] = r.Text
+	}
 	embeddings, err := llms.EmbedTexts(ctx, t.appState, model, messageType, texts)
 	if err != nil {
 		return fmt.Errorf("MessageEmbedderTask embed messages failed: %w", err)
 	}
 
-	embeddingRecords := make([]models.TextData, len(msgs))
-	for i, r := range msgs {
-		embeddingRecords[i] = models.TextData{
-			TextUUID:  r.UUID,
-			Embedding: embeddings[i],
-		}
+	for i := range embeddingRecords {
+		embeddingRecords[i].Embedding = embeddings[i]
 	}
 	err = t.appState.MemoryStore.CreateMessageEmbeddings(
 		ctx,
@@ -102,6 +135,107 @@ func (t *MessageEmbedderTask) Process(
 	return nil
 }
 
+// messageWindows returns the text to embed for each message, with the UUIDs of the
+// messages in its window: the message and up to size-1 searchable messages preceding it in
+// the session. Messages no longer among the session's most recent messages are windowed
+// with the messages preceding them in msgs.
+func (t *MessageEmbedderTask) messageWindows(
+	ctx context.Context,
+	sessionID string,
+	msgs []models.Message,
+	size int,
+) ([]models.TextData, error) {
+	history, err := t.searchableHistory(ctx, sessionID, len(msgs)+size-1)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]models.TextData, len(msgs))
+	for i, m := range msgs {
+		seq, j := history.locate(msgs, i)
+		records[i] = windowRecord(m.UUID, seq[max(0, j-size+1):j+1])
+	}
+	return records, nil
+}
+
+// turnPairs returns the texts to embed for msgs when user and assistant turns are
+// embedded as pairs. A pair is embedded under its user message, with its assistant reply
+// in the window, and replaces any embedding of the user message alone. Messages that
+// aren't part of a pair, including user messages yet to be replied to, are embedded
+// alone.
+func (t *MessageEmbedderTask) turnPairs(
+	ctx context.Context,
+	sessionID string,
+	msgs []models.Message,
+) ([]models.TextData, error) {
+	history, err := t.searchableHistory(ctx, sessionID, len(msgs)+1)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []models.TextData
+	for i, m := range msgs {
+		seq, j := history.locate(msgs, i)
+		switch {
+		case t.roles.Kind(m.Role) == roles.Assistant && j > 0 &&
+			t.roles.Kind(seq[j-1].Role) == roles.User:
+			records = append(records, windowRecord(seq[j-1].UUID, seq[j-1:j+1]))
+		case t.roles.Kind(m.Role) == roles.User && j+1 < len(seq) &&
+			t.roles.Kind(seq[j+1].Role) == roles.Assistant:
+			// embedded with its reply
+		default:
+			records = append(records, models.TextData{TextUUID: m.UUID, Text: m.Content})
+		}
+	}
+	return records, nil
+}
+
+// windowRecord returns the record of an embedding of the messages of window, stored under
+// the message with the UUID key.
+func windowRecord(key uuid.UUID, window []models.Message) models.TextData {
+	contents := make([]string, len(window))
+	uuids := make([]uuid.UUID, len(window))
+	for k, w := range window {
+		contents[k] = w.Content
+		uuids[k] = w.UUID
+	}
+	return models.TextData{TextUUID: key, Text: strings.Join(contents, "\n"), Window: uuids}
+}
+
+// messageHistory is a session's most recent searchable messages, in order.
+type messageHistory struct {
+	messages []models.Message
+	index    map[uuid.UUID]int
+}
+
+func (t *MessageEmbedderTask) searchableHistory(
+	ctx context.Context,
+	sessionID string,
+	lastN int,
+) (*messageHistory, error) {
+	recent, err := t.appState.MemoryStore.GetMemory(ctx, sessionID, lastN)
+	if err != nil {
+		return nil, err
+	}
+	messages := slices.DeleteFunc(recent.Messages, func(m models.Message) bool {
+		return !t.roles.Searchable(m.Role)
+	})
+	index := make(map[uuid.UUID]int, len(messages))
+	for i, m := range messages {
+		index[m.UUID] = i
+	}
+	return &messageHistory{messages: messages, index: index}, nil
+}
+
+// locate returns the sequence of messages msgs[i] is found in, and its index in it. Messages
+// no longer in the history are located in msgs.
+func (h *messageHistory) locate(msgs []models.Message, i int) ([]models.Message, int) {
+	if j, ok := h.index[msgs[i].UUID]; ok {
+		return h.messages, j
+	}
+	return msgs, i
+}
+
 // messageToStringSlice converts a slice of TextData to a slice of strings.
 // If enrich is true, the text slice will include the prior and subsequent
 // messages text to the slice item.
@@ -1,13 +1,19 @@
 package tasks
 
 import (
+	"fmt"
 	"math"
+	"slices"
 	"testing"
 
+	"github.com/getzep/zep/config"
 	"github.com/getzep/zep/pkg/llms"
 	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
 	"github.com/getzep/zep/pkg/testutils"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEmbeddingExtractor_Extract_OpenAI(t *testing.T) {
@@ -95,6 +101,121 @@ func TestEmbeddingExtractor_Extract_OpenAI(t *testing.T) {
 	}
 }
 
+func TestMessageEmbedderTask_Window(t *testing.T) {
+	windowState := &models.AppState{Config: &config.Config{}, LLMClient: &scriptedLLM{}}
+	windowState.Config.Extractors.Messages.Embeddings = config.EmbeddingsConfig{
+		Service:    llms.FakeLLMService,
+		Dimensions: 8,
+		Window:     2,
+	}
+	windowState.MemoryStore = inmemory.NewMemoryStore(windowState, inmemory.NewDB())
+
+	sessionID := "window"
+	messages := make([]models.Message, 6)
+	for i := range messages {
+		messages[i] = models.Message{
+			UUID:    uuid.New(),
+			Role:    "user",
+			Content: fmt.Sprintf("message %d", i),
+		}
+	}
+	err := windowState.MemoryStore.PutMemory(
+		testCtx,
+		sessionID,
+		&models.Memory{Messages: messages},
+		true,
+	)
+	require.NoError(t, err)
+
+	task := NewMessageEmbedderTask(windowState)
+	// the first message of the batch is windowed with the message preceding it
+	require.NoError(t, task.Process(testCtx, sessionID, messages[4:]))
+	// older messages are windowed with the messages preceding them in the batch
+	require.NoError(t, task.Process(testCtx, sessionID, messages[:2]))
+
+	embeddings, err := windowState.MemoryStore.GetMessageEmbeddings(testCtx, sessionID)
+	require.NoError(t, err)
+	byUUID := make(map[uuid.UUID]models.TextData, len(embeddings))
+	for _, e := range embeddings {
+		byUUID[e.TextUUID] = e
+	}
+	assert.Len(t, byUUID, 4)
+
+	for i, window := range map[int][]int{0: {0}, 1: {0, 1}, 4: {3, 4}, 5: {4, 5}} {
+		e := byUUID[messages[i].UUID]
+		wantUUIDs := make([]uuid.UUID, len(window))
+		text := ""
+		for k, j := range window {
+			wantUUIDs[k] = messages[j].UUID
+			if k > 0 {
+				text += "\n"
+			}
+			text += messages[j].Content
+		}
+		assert.Equal(t, wantUUIDs, e.Window, "message %d", i)
+		assert.Equal(t, llms.FakeEmbeddings([]string{text}, 8)[0], e.Embedding, "message %d", i)
+	}
+}
+
+func TestMessageEmbedderTask_TurnPairs(t *testing.T) {
+	pairState := &models.AppState{Config: &config.Config{}, LLMClient: &scriptedLLM{}}
+	pairState.Config.Extractors.Messages.Embeddings = config.EmbeddingsConfig{
+		Service:    llms.FakeLLMService,
+		Dimensions: 8,
+		TurnPairs:  true,
+	}
+	pairState.MemoryStore = inmemory.NewMemoryStore(pairState, inmemory.NewDB())
+
+	sessionID := "pairs"
+	messages := []models.Message{
+		{UUID: uuid.New(), Role: "user", Content: "which plan includes SSO?"},
+		{UUID: uuid.New(), Role: "assistant", Content: "the enterprise plan"},
+		{UUID: uuid.New(), Role: "user", Content: "how much is it?"},
+		{UUID: uuid.New(), Role: "assistant", Content: "contact sales for pricing"},
+	}
+	put := func(msgs []models.Message) {
+		err := pairState.MemoryStore.PutMemory(
+			testCtx,
+			sessionID,
+			&models.Memory{Messages: msgs},
+			true,
+		)
+		require.NoError(t, err)
+	}
+
+	task := NewMessageEmbedderTask(pairState)
+	put(messages[:3])
+	require.NoError(t, task.Process(testCtx, sessionID, messages[:3]))
+	embeddings, err := pairState.MemoryStore.GetMessageEmbeddings(testCtx, sessionID)
+	require.NoError(t, err)
+	// the unanswered user message is embedded alone
+	assert.Len(t, embeddings, 2)
+
+	// the reply re-embeds the user message as a pair
+	put(messages[3:])
+	require.NoError(t, task.Process(testCtx, sessionID, messages[3:]))
+	embeddings, err = pairState.MemoryStore.GetMessageEmbeddings(testCtx, sessionID)
+	require.NoError(t, err)
+	require.Len(t, embeddings, 2)
+	for _, e := range embeddings {
+		user := slices.IndexFunc(messages, func(m models.Message) bool { return m.UUID == e.TextUUID })
+		require.Contains(t, []int{0, 2}, user)
+		reply := messages[user+1]
+		assert.Equal(t, []uuid.UUID{e.TextUUID, reply.UUID}, e.Window)
+		text := messages[user].Content + "\n" + reply.Content
+		assert.Equal(t, llms.FakeEmbeddings([]string{text}, 8)[0], e.Embedding)
+	}
+
+	// search results return the pair
+	results, err := pairState.MemoryStore.SearchMemory(testCtx, sessionID,
+		&models.MemorySearchPayload{Text: "how much"}, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	if assert.Len(t, results[0].Window, 2) {
+		assert.Equal(t, messages[3].Content, results[0].Window[1].Content)
+	}
+}
+
 // compareFloat32Vectors compares two float32 vectors, asserting that their values are within the given variance.
 func compareFloat32Vectors(t *testing.T, a, b []float32, variance float32) {
 	t.Helper()
@@ -0,0 +1,212 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/webhooks"
+)
+
+const (
+	// EnrichmentMetadataKey is the key, under system, of the metadata returned by the
+	// enrichment service.
+	EnrichmentMetadataKey = "enrichment"
+
+	enrichmentRetryMax       = 3
+	defaultEnrichmentTimeout = 10 * time.Second
+)
+
+var _ models.Task = &MessageEnrichmentTask{}
+
+// EnrichmentRequest is the payload POSTed to the enrichment service.
+type EnrichmentRequest struct {
+	SessionID string              `json:"session_id"`
+	Messages  []EnrichmentMessage `json:"messages"`
+}
+
+// EnrichmentMessage is a message sent to the enrichment service.
+type EnrichmentMessage struct {
+	UUID     uuid.UUID              `json:"uuid"`
+	Role     string                 `json:"role"`
+	Content  string                 `json:"content"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// EnrichmentResponse is the response of the enrichment service. Each result's metadata
+// is stored with the message of the same UUID.
+type EnrichmentResponse struct {
+	Messages []EnrichmentResult `json:"messages"`
+}
+
+// EnrichmentResult is the enrichment of a message.
+type EnrichmentResult struct {
+	UUID     uuid.UUID              `json:"uuid"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+func NewMessageEnrichmentTask(appState *models.AppState) *MessageEnrichmentTask {
+	cfg := appState.Config.Extractors.Messages.Enrichment
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultEnrichmentTimeout
+	}
+	return &MessageEnrichmentTask{
+		BaseTask: BaseTask{
+			appState: appState,
+		},
+		url:    cfg.URL,
+		secret: []byte(cfg.Secret),
+		client: NewRetryableHTTPClient(enrichmentRetryMax, timeout),
+	}
+}
+
+// MessageEnrichmentTask POSTs new messages to an external enrichment service, such as a
+// proprietary classifier, and stores the metadata it returns for each message under
+// system.enrichment. Requests are signed like webhook events.
+type MessageEnrichmentTask struct {
+	BaseTask
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func (t *MessageEnrichmentTask) Execute(
+	ctx context.Context,
+	msg *message.Message,
+) error {
+	ctx, done := context.WithTimeout(ctx, TaskTimeout*time.Second)
+	defer done()
+
+	sessionID := msg.Metadata.Get("session_id")
+	if sessionID == "" {
+		return errors.New("MessageEnrichmentTask session_id is empty")
+	}
+
+	log.Debugf("MessageEnrichmentTask called for session %s", sessionID)
+
+	messages, err := messageTaskPayloadToMessages(ctx, t.appState, msg)
+	if err != nil {
+		return fmt.Errorf("MessageEnrichmentTask messageTaskPayloadToMessages failed: %w", err)
+	}
+
+	err = t.process(ctx, sessionID, messages)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf("MessageEnrichmentTask messages not found. Were the records deleted?")
+			msg.Ack()
+			return nil
+		}
+		return fmt.Errorf("MessageEnrichmentTask failed: %w", err)
+	}
+
+	msg.Ack()
+
+	return nil
+}
+
+func (t *MessageEnrichmentTask) process(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	request := EnrichmentRequest{
+		SessionID: sessionID,
+		Messages:  make([]EnrichmentMessage, len(messages)),
+	}
+	byUUID := make(map[uuid.UUID]models.Message, len(messages))
+	for i, m := range messages {
+		request.Messages[i] = EnrichmentMessage{
+			UUID:     m.UUID,
+			Role:     m.Role,
+			Content:  m.Content,
+			Metadata: m.Metadata,
+		}
+		byUUID[m.UUID] = m
+	}
+
+	response, err := t.enrich(ctx, &request)
+	if err != nil {
+		return err
+	}
+
+	var updates []models.Message
+	for _, result := range response.Messages {
+		m, ok := byUUID[result.UUID]
+		if !ok || len(result.Metadata) == 0 {
+			continue
+		}
+		updates = append(updates, models.Message{
+			UUID:       m.UUID,
+			TokenCount: m.TokenCount,
+			Metadata: map[string]interface{}{"system": map[string]interface{}{
+				EnrichmentMetadataKey: result.Metadata,
+			}},
+		})
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	return t.appState.MemoryStore.UpdateMessages(ctx, sessionID, updates, true, false)
+}
+
+// enrich POSTs request to the enrichment service. A response without a body enriches no
+// messages.
+func (t *MessageEnrichmentTask) enrich(
+	ctx context.Context,
+	request *EnrichmentRequest,
+) (*EnrichmentResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal enrichment request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create enrichment request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(t.secret) > 0 {
+		req.Header.Set(webhooks.SignatureHeader, webhooks.Sign(t.secret, body))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call enrichment service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read enrichment response: %w", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("enrichment service returned status %d", resp.StatusCode)
+	}
+
+	var response EnrichmentResponse
+	if len(bytes.TrimSpace(b)) == 0 {
+		return &response, nil
+	}
+	if err := json.Unmarshal(b, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode enrichment response: %w", err)
+	}
+	return &response, nil
+}
+
+func (t *MessageEnrichmentTask) HandleError(err error) {
+	log.Errorf("MessageEnrichmentTask failed: %v", err)
+}
@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+	"github.com/getzep/zep/pkg/webhooks"
+)
+
+func TestMessageEnrichmentTask(t *testing.T) {
+	var requests []EnrichmentRequest
+	var signature string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhooks.SignatureHeader)
+		var req EnrichmentRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		// classify the first message only
+		_ = json.NewEncoder(w).Encode(EnrichmentResponse{Messages: []EnrichmentResult{
+			{UUID: req.Messages[0].UUID, Metadata: map[string]interface{}{"label": "billing"}},
+			{UUID: uuid.New(), Metadata: map[string]interface{}{"label": "unknown"}},
+		}})
+	}))
+	defer server.Close()
+
+	db := inmemory.NewDB()
+	enrichmentAppState := &models.AppState{Config: &config.Config{}}
+	enrichmentAppState.Config.Extractors.Messages.Enrichment = config.EnrichmentExtractorConfig{
+		Enabled: true,
+		URL:     server.URL,
+		Secret:  "secret",
+	}
+	enrichmentAppState.MemoryStore = inmemory.NewMemoryStore(enrichmentAppState, db)
+
+	m1, m2 := uuid.New(), uuid.New()
+	err := enrichmentAppState.MemoryStore.PutMemory(testCtx, "enrichment", &models.Memory{
+		Messages: []models.Message{
+			{UUID: m1, Role: "user", Content: "Why was I charged twice?"},
+			{UUID: m2, Role: "assistant", Content: "Let me check."},
+		},
+	}, true)
+	require.NoError(t, err)
+	messages, err := enrichmentAppState.MemoryStore.GetMessagesByUUID(
+		testCtx,
+		"enrichment",
+		[]uuid.UUID{m1, m2},
+	)
+	require.NoError(t, err)
+
+	task := NewMessageEnrichmentTask(enrichmentAppState)
+	require.NoError(t, task.process(testCtx, "enrichment", messages))
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, "enrichment", requests[0].SessionID)
+	assert.Len(t, requests[0].Messages, 2)
+	assert.NotEmpty(t, signature)
+
+	messages, err = enrichmentAppState.MemoryStore.GetMessagesByUUID(
+		testCtx,
+		"enrichment",
+		[]uuid.UUID{m1, m2},
+	)
+	require.NoError(t, err)
+	for _, m := range messages {
+		if m.UUID == m1 {
+			assert.Equal(t, map[string]interface{}{"label": "billing"},
+				m.Metadata["system"].(map[string]interface{})[EnrichmentMetadataKey])
+		} else {
+			assert.Nil(t, m.Metadata["system"])
+		}
+	}
+
+	status = http.StatusBadRequest
+	assert.Error(t, task.process(testCtx, "enrichment", messages))
+}
@@ -0,0 +1,303 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
+)
+
+const (
+	episodeMaxTokens        = 256
+	episodeSummaryMaxTokens = 256
+	// episodeContextMessages is the number of recent messages read for the current
+	// episode's context, and for the summaries of ended episodes.
+	episodeContextMessages = 30
+)
+
+var _ models.Task = &MessageEpisodeTask{}
+
+func NewMessageEpisodeTask(appState *models.AppState) *MessageEpisodeTask {
+	return &MessageEpisodeTask{
+		BaseTask: BaseTask{
+			appState: appState,
+		},
+		roles: roles.FromConfig(appState.Config),
+	}
+}
+
+// MessageEpisodeTask segments a session into episodes, starting a new episode at each
+// message the LLM finds shifts the conversation to a new topic. Each message is tagged
+// with its episode under system.episode, and an episode is summarized once it ends.
+type MessageEpisodeTask struct {
+	BaseTask
+	roles *roles.Registry
+}
+
+// episodeSegmentation is the LLM's segmentation of a batch of messages. Lines are
+// numbered from 1.
+type episodeSegmentation struct {
+	Topic  string `json:"topic"`
+	Shifts []struct {
+		Line  int    `json:"line"`
+		Topic string `json:"topic"`
+	} `json:"shifts"`
+}
+
+func (t *MessageEpisodeTask) Execute(
+	ctx context.Context,
+	msg *message.Message,
+) error {
+	ctx, done := context.WithTimeout(ctx, TaskTimeout*time.Second)
+	defer done()
+
+	sessionID := msg.Metadata.Get("session_id")
+	if sessionID == "" {
+		return errors.New("MessageEpisodeTask session_id is empty")
+	}
+
+	log.Debugf("MessageEpisodeTask called for session %s", sessionID)
+
+	messages, err := messageTaskPayloadToMessages(ctx, t.appState, msg)
+	if err != nil {
+		return fmt.Errorf("MessageEpisodeTask messageTaskPayloadToMessages failed: %w", err)
+	}
+
+	err = t.process(ctx, sessionID, messages)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf("MessageEpisodeTask session %s not found. Was it deleted?", sessionID)
+			msg.Ack()
+			return nil
+		}
+		return fmt.Errorf("MessageEpisodeTask failed: %w", err)
+	}
+
+	msg.Ack()
+
+	return nil
+}
+
+func (t *MessageEpisodeTask) process(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+) error {
+	// messages already in an episode aren't segmented again
+	messages = slices.DeleteFunc(dropEmptyMessages(messages), func(m models.Message) bool {
+		return models.MessageEpisode(&m) != 0
+	})
+	if len(messages) == 0 {
+		return nil
+	}
+
+	session, err := t.appState.MemoryStore.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	memory, err := t.appState.MemoryStore.GetMemory(ctx, sessionID, episodeContextMessages)
+	if err != nil {
+		return fmt.Errorf("failed to get memory: %w", err)
+	}
+
+	episodes := session.Episodes
+	var current *models.Episode
+	if n := len(episodes); n > 0 && episodes[n-1].EndedAt == nil {
+		current = &episodes[n-1]
+	}
+
+	episodeOf := make(map[uuid.UUID]int, len(memory.Messages)+len(messages))
+	for i := range memory.Messages {
+		episodeOf[memory.Messages[i].UUID] = models.MessageEpisode(&memory.Messages[i])
+	}
+	for _, m := range messages {
+		episodeOf[m.UUID] = 0
+	}
+
+	segmentation, err := t.segment(ctx, current, memory.Messages, episodeOf, messages)
+	if err != nil {
+		return err
+	}
+	shifts := make(map[int]string, len(segmentation.Shifts))
+	for _, shift := range segmentation.Shifts {
+		shifts[shift.Line] = shift.Topic
+	}
+
+	if current != nil && segmentation.Topic != "" {
+		current.Topic = segmentation.Topic
+	}
+	var ended []int
+	updates := make([]models.Message, len(messages))
+	for i, m := range messages {
+		topic, shift := shifts[i+1]
+		if current == nil || shift {
+			startedAt := m.CreatedAt
+			if startedAt.IsZero() {
+				startedAt = time.Now().UTC()
+			}
+			if current != nil {
+				current.EndedAt = &startedAt
+				ended = append(ended, current.ID)
+			}
+			if !shift {
+				topic = segmentation.Topic
+			}
+			episodes = append(episodes, models.Episode{
+				ID:               len(episodes) + 1,
+				Topic:            topic,
+				StartMessageUUID: m.UUID,
+				StartedAt:        startedAt,
+			})
+			current = &episodes[len(episodes)-1]
+		}
+		current.EndMessageUUID = m.UUID
+		current.MessageCount++
+		episodeOf[m.UUID] = current.ID
+
+		updates[i] = models.Message{
+			UUID:       m.UUID,
+			TokenCount: m.TokenCount,
+			Metadata: map[string]interface{}{"system": map[string]interface{}{
+				models.EpisodeMetadataKey: current.ID,
+			}},
+		}
+	}
+
+	// a failed summary leaves the episode unsummarized, rather than segmenting the
+	// messages again on retry
+	for _, id := range ended {
+		episode := &episodes[id-1]
+		summary, err := t.summarize(ctx, episode, memory.Messages, messages, episodeOf)
+		if err != nil {
+			log.Warnf("MessageEpisodeTask failed to summarize episode %d of %s: %s",
+				id, sessionID, err)
+			continue
+		}
+		episode.Summary = summary
+	}
+
+	if err := t.appState.MemoryStore.UpdateMessages(ctx, sessionID, updates, true, false); err != nil {
+		return fmt.Errorf("failed to tag messages with their episode: %w", err)
+	}
+	return t.appState.MemoryStore.UpdateSessionEpisodes(ctx, sessionID, episodes)
+}
+
+// segment asks the LLM where messages shift to a new topic, given the recent messages of
+// the current episode, if any.
+func (t *MessageEpisodeTask) segment(
+	ctx context.Context,
+	current *models.Episode,
+	recent []models.Message,
+	episodeOf map[uuid.UUID]int,
+	messages []models.Message,
+) (*episodeSegmentation, error) {
+	currentTopic := "None"
+	var contextLines []string
+	if current != nil {
+		currentTopic = current.Topic
+		for _, m := range recent {
+			if episodeOf[m.UUID] == current.ID && m.Content != "" {
+				contextLines = append(contextLines,
+					fmt.Sprintf("%s: %s", t.roles.Label(m.Role), m.Content))
+			}
+		}
+	}
+	if len(contextLines) == 0 {
+		contextLines = []string{"None"}
+	}
+
+	lines := make([]string, len(messages))
+	for i, m := range messages {
+		lines[i] = fmt.Sprintf("%d. %s: %s", i+1, t.roles.Label(m.Role), m.Content)
+	}
+
+	prompt, err := internal.ParsePrompt(
+		episodeSegmentationPromptTemplate,
+		EpisodeSegmentationPromptTemplateData{
+			CurrentTopic:   currentTopic,
+			ContextJoined:  strings.Join(contextLines, "\n"),
+			MessagesJoined: strings.Join(lines, "\n"),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := t.appState.LLMClient.Call(ctx, prompt, llms.WithMaxTokens(episodeMaxTokens))
+	if err != nil {
+		return nil, fmt.Errorf("failed to segment episodes: %w", err)
+	}
+	return parseEpisodeSegmentation(response)
+}
+
+// summarize summarizes the messages of an ended episode that are among the recent
+// messages.
+func (t *MessageEpisodeTask) summarize(
+	ctx context.Context,
+	episode *models.Episode,
+	recent []models.Message,
+	messages []models.Message,
+	episodeOf map[uuid.UUID]int,
+) (string, error) {
+	seen := make(map[uuid.UUID]bool, len(recent))
+	var lines []string
+	for _, m := range append(slices.Clone(recent), messages...) {
+		if seen[m.UUID] || episodeOf[m.UUID] != episode.ID || m.Content == "" {
+			continue
+		}
+		seen[m.UUID] = true
+		lines = append(lines, fmt.Sprintf("%s: %s", t.roles.Label(m.Role), m.Content))
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	prompt, err := internal.ParsePrompt(episodeSummaryPromptTemplate, EpisodeSummaryPromptTemplateData{
+		Topic:          episode.Topic,
+		MessagesJoined: strings.Join(lines, "\n"),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	response, err := t.appState.LLMClient.Call(
+		ctx,
+		prompt,
+		llms.WithMaxTokens(episodeSummaryMaxTokens),
+	)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response), nil
+}
+
+func (t *MessageEpisodeTask) HandleError(err error) {
+	log.Errorf("MessageEpisodeTask failed: %v", err)
+}
+
+// parseEpisodeSegmentation reads the JSON object in an LLM response, ignoring any text or
+// code fence around it.
+func parseEpisodeSegmentation(response string) (*episodeSegmentation, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no episode segmentation found in LLM response: %q", response)
+	}
+
+	var segmentation episodeSegmentation
+	if err := json.Unmarshal([]byte(response[start:end+1]), &segmentation); err != nil {
+		return nil, fmt.Errorf("failed to parse episode segmentation: %w", err)
+	}
+	return &segmentation, nil
+}
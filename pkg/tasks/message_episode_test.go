@@ -0,0 +1,122 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+// scriptedLLM responds to each call with the next of responses, recording the prompts.
+type scriptedLLM struct {
+	models.ZepLLM
+	responses []string
+	prompts   []string
+}
+
+func (l *scriptedLLM) Call(_ context.Context, prompt string, _ ...llms.CallOption) (string, error) {
+	l.prompts = append(l.prompts, prompt)
+	response := l.responses[0]
+	l.responses = l.responses[1:]
+	return response, nil
+}
+
+func TestParseEpisodeSegmentation(t *testing.T) {
+	segmentation, err := parseEpisodeSegmentation("```json\n" +
+		`{"topic": "flights", "shifts": [{"line": 2, "topic": "hotels"}]}` + "\n```")
+	require.NoError(t, err)
+	assert.Equal(t, "flights", segmentation.Topic)
+	require.Len(t, segmentation.Shifts, 1)
+	assert.Equal(t, 2, segmentation.Shifts[0].Line)
+	assert.Equal(t, "hotels", segmentation.Shifts[0].Topic)
+
+	_, err = parseEpisodeSegmentation("The topic doesn't change.")
+	assert.Error(t, err)
+}
+
+func TestMessageEpisodeTask(t *testing.T) {
+	db := inmemory.NewDB()
+	llm := &scriptedLLM{}
+	episodeAppState := &models.AppState{Config: &config.Config{}, LLMClient: llm}
+	episodeAppState.MemoryStore = inmemory.NewMemoryStore(episodeAppState, db)
+	task := NewMessageEpisodeTask(episodeAppState)
+
+	put := func(contents ...string) []models.Message {
+		memory := &models.Memory{}
+		var uuids []uuid.UUID
+		for i, content := range contents {
+			role := "user"
+			if i%2 == 1 {
+				role = "assistant"
+			}
+			id := uuid.New()
+			uuids = append(uuids, id)
+			memory.Messages = append(memory.Messages,
+				models.Message{UUID: id, Role: role, Content: content})
+		}
+		err := episodeAppState.MemoryStore.PutMemory(testCtx, "episodes", memory, true)
+		require.NoError(t, err)
+		messages, err := episodeAppState.MemoryStore.GetMessagesByUUID(testCtx, "episodes", uuids)
+		require.NoError(t, err)
+		return messages
+	}
+
+	first := put("I need a flight to Lisbon", "Which dates?")
+	llm.responses = []string{`{"topic": "booking a flight to Lisbon", "shifts": []}`}
+	require.NoError(t, task.process(testCtx, "episodes", first))
+
+	session, err := episodeAppState.MemoryStore.GetSession(testCtx, "episodes")
+	require.NoError(t, err)
+	require.Len(t, session.Episodes, 1)
+	assert.Equal(t, "booking a flight to Lisbon", session.Episodes[0].Topic)
+	assert.Equal(t, 2, session.Episodes[0].MessageCount)
+	assert.Nil(t, session.Episodes[0].EndedAt)
+
+	second := put("May 3rd. Any hotel tips?", "Try Alfama.")
+	llm.responses = []string{
+		`{"topic": "booking a flight to Lisbon on May 3rd", ` +
+			`"shifts": [{"line": 1, "topic": "hotels in Lisbon"}]}`,
+		"The user asked for a flight to Lisbon.",
+	}
+	require.NoError(t, task.process(testCtx, "episodes", second))
+	// the current episode's messages are given as context
+	assert.Contains(t, llm.prompts[1], "user: I need a flight to Lisbon")
+
+	session, err = episodeAppState.MemoryStore.GetSession(testCtx, "episodes")
+	require.NoError(t, err)
+	require.Len(t, session.Episodes, 2)
+	ended, open := session.Episodes[0], session.Episodes[1]
+	assert.Equal(t, "booking a flight to Lisbon on May 3rd", ended.Topic)
+	assert.Equal(t, first[1].UUID, ended.EndMessageUUID)
+	assert.NotNil(t, ended.EndedAt)
+	assert.Equal(t, "The user asked for a flight to Lisbon.", ended.Summary)
+	assert.Equal(t, 2, open.ID)
+	assert.Equal(t, "hotels in Lisbon", open.Topic)
+	assert.Equal(t, second[0].UUID, open.StartMessageUUID)
+	assert.Equal(t, 2, open.MessageCount)
+
+	messages, err := episodeAppState.MemoryStore.GetMessagesByUUID(
+		testCtx,
+		"episodes",
+		[]uuid.UUID{first[0].UUID, second[1].UUID},
+	)
+	require.NoError(t, err)
+	for _, m := range messages {
+		want := 1
+		if m.UUID == second[1].UUID {
+			want = 2
+		}
+		assert.Equal(t, want, models.MessageEpisode(&m))
+	}
+
+	// messages already in an episode are skipped
+	require.NoError(t, task.process(testCtx, "episodes", messages))
+	assert.Len(t, llm.prompts, 3)
+}
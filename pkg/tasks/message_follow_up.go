@@ -0,0 +1,205 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
+)
+
+const (
+	followUpMaxTokens       = 256
+	followUpContextMessages = 6
+	followUpMaxQuestions    = 3
+)
+
+// followUpListMarkerRegex matches the bullet or number of a list item.
+var followUpListMarkerRegex = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+
+var _ models.Task = &MessageFollowUpTask{}
+
+func NewMessageFollowUpTask(appState *models.AppState) *MessageFollowUpTask {
+	return &MessageFollowUpTask{
+		BaseTask: BaseTask{
+			appState: appState,
+		},
+		roles: roles.FromConfig(appState.Config),
+	}
+}
+
+// MessageFollowUpTask suggests questions the user might ask after an assistant message,
+// and stores them in the message's metadata under system.follow_up_questions. Only the
+// latest assistant message of each batch is considered, as earlier suggestions would
+// already have been overtaken by the conversation.
+type MessageFollowUpTask struct {
+	BaseTask
+	roles *roles.Registry
+}
+
+func (t *MessageFollowUpTask) Execute(
+	ctx context.Context,
+	msg *message.Message,
+) error {
+	ctx, done := context.WithTimeout(ctx, TaskTimeout*time.Second)
+	defer done()
+
+	sessionID := msg.Metadata.Get("session_id")
+	if sessionID == "" {
+		return errors.New("MessageFollowUpTask session_id is empty")
+	}
+
+	log.Debugf("MessageFollowUpTask called for session %s", sessionID)
+
+	messages, err := messageTaskPayloadToMessages(ctx, t.appState, msg)
+	if err != nil {
+		return fmt.Errorf("MessageFollowUpTask messageTaskPayloadToMessages failed: %w", err)
+	}
+
+	var latest *models.Message
+	for i := range messages {
+		if t.roles.Kind(messages[i].Role) == roles.Assistant && messages[i].Content != "" {
+			latest = &messages[i]
+		}
+	}
+	if latest == nil {
+		msg.Ack()
+		return nil
+	}
+
+	err = t.process(ctx, sessionID, latest)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf("MessageFollowUpTask message not found. Were the records deleted?")
+			msg.Ack()
+			return nil
+		}
+		return fmt.Errorf("MessageFollowUpTask failed: %w", err)
+	}
+
+	msg.Ack()
+
+	return nil
+}
+
+func (t *MessageFollowUpTask) process(
+	ctx context.Context,
+	sessionID string,
+	assistantMessage *models.Message,
+) error {
+	promptTemplate, err := t.promptTemplate()
+	if err != nil {
+		return err
+	}
+
+	memory, err := t.appState.MemoryStore.GetMemory(ctx, sessionID, followUpContextMessages)
+	if err != nil {
+		return fmt.Errorf("failed to get memory: %w", err)
+	}
+
+	prompt, err := internal.ParsePrompt(promptTemplate, FollowUpPromptTemplateData{
+		MessagesJoined: followUpContext(memory.Messages, assistantMessage, t.roles),
+	})
+	if err != nil {
+		return err
+	}
+
+	response, err := t.appState.LLMClient.Call(ctx, prompt, llms.WithMaxTokens(followUpMaxTokens))
+	if err != nil {
+		return fmt.Errorf("failed to generate follow-up questions: %w", err)
+	}
+
+	questions := parseFollowUpQuestions(response)
+	if len(questions) == 0 {
+		return nil
+	}
+
+	return t.appState.MemoryStore.UpdateMessages(
+		ctx,
+		sessionID,
+		[]models.Message{
+			{
+				UUID: assistantMessage.UUID,
+				Metadata: map[string]interface{}{"system": map[string]interface{}{
+					"follow_up_questions": questions,
+				}},
+			},
+		},
+		true,
+		false,
+	)
+}
+
+// promptTemplate returns the configured custom prompt, if any, or the default prompt.
+func (t *MessageFollowUpTask) promptTemplate() (string, error) {
+	custom := strings.TrimSpace(t.appState.Config.CustomPrompts.FollowUpPrompt)
+	if custom == "" {
+		return defaultFollowUpPromptTemplate, nil
+	}
+	if !strings.Contains(custom, "{{.MessagesJoined}}") {
+		return "", errors.New(
+			"wrong follow-up prompt format. please make sure it contains the identifier " +
+				"{{.MessagesJoined}}",
+		)
+	}
+	return custom, nil
+}
+
+func (t *MessageFollowUpTask) HandleError(err error) {
+	log.Errorf("MessageFollowUpTask failed: %v", err)
+}
+
+// followUpContext formats the recent messages up to and including the assistant message.
+// Messages added after it are left out, so that the questions follow on from it. If the
+// assistant message is no longer among the recent messages, it is used alone.
+func followUpContext(
+	recent []models.Message,
+	assistantMessage *models.Message,
+	registry *roles.Registry,
+) string {
+	end := -1
+	for i := range recent {
+		if recent[i].UUID == assistantMessage.UUID {
+			end = i
+		}
+	}
+	window := []models.Message{*assistantMessage}
+	if end >= 0 {
+		window = recent[:end+1]
+	}
+
+	lines := make([]string, 0, len(window))
+	for _, m := range window {
+		if m.Content == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", registry.Label(m.Role), m.Content))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseFollowUpQuestions reads up to followUpMaxQuestions questions from an LLM response,
+// one per line, stripping any list numbering or bullets.
+func parseFollowUpQuestions(response string) []string {
+	var questions []string
+	for _, line := range strings.Split(response, "\n") {
+		line = followUpListMarkerRegex.ReplaceAllString(line, "")
+		line = strings.Trim(strings.TrimSpace(line), "\"")
+		if !strings.HasSuffix(line, "?") {
+			continue
+		}
+		questions = append(questions, line)
+		if len(questions) == followUpMaxQuestions {
+			break
+		}
+	}
+	return questions
+}
@@ -0,0 +1,38 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestParseFollowUpQuestions(t *testing.T) {
+	response := `Here are some questions:
+1. What are the opening hours?
+2) "Is there parking nearby?"
+- 3D printing services available?
+* How much does it cost?`
+
+	assert.Equal(t, []string{
+		"What are the opening hours?",
+		"Is there parking nearby?",
+		"3D printing services available?",
+	}, parseFollowUpQuestions(response))
+	assert.Empty(t, parseFollowUpQuestions("No questions."))
+}
+
+func TestFollowUpContext(t *testing.T) {
+	recent := []models.Message{
+		{UUID: uuid.New(), Role: "user", Content: "Hi"},
+		{UUID: uuid.New(), Role: "assistant", Content: "Hello"},
+		{UUID: uuid.New(), Role: "user", Content: "Bye"},
+	}
+
+	assert.Equal(t, "user: Hi\nassistant: Hello", followUpContext(recent, &recent[1], nil))
+
+	older := &models.Message{UUID: uuid.New(), Role: "ai", Content: "Earlier"}
+	assert.Equal(t, "ai: Earlier", followUpContext(recent, older, nil))
+}
@@ -0,0 +1,287 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
+)
+
+const (
+	// ImportanceScorerHeuristic and ImportanceScorerLLM are the importance scorers.
+	ImportanceScorerHeuristic = "heuristic"
+	ImportanceScorerLLM       = "llm"
+
+	importanceMaxTokens       = 256
+	importanceContextMessages = 20
+)
+
+// importanceDirectiveRegex matches phrases asking for something to be remembered or
+// followed, and statements of lasting facts or preferences.
+var importanceDirectiveRegex = regexp.MustCompile(`(?i)\b(remember|don'?t forget|` +
+	`from now on|always|never|make sure|my name is|call me|i prefer|i'?m allergic|` +
+	`i am allergic|i (?:really )?(?:like|love|hate|need)|important)\b`)
+
+// importanceEmotionWords are words carrying emotional weight.
+var importanceEmotionWords = map[string]bool{
+	"love": true, "hate": true, "angry": true, "upset": true, "afraid": true,
+	"scared": true, "excited": true, "sad": true, "happy": true, "worried": true,
+	"frustrated": true, "thrilled": true, "grateful": true, "anxious": true,
+	"devastated": true, "furious": true, "disappointed": true, "proud": true,
+}
+
+var _ models.Task = &MessageImportanceTask{}
+
+func NewMessageImportanceTask(appState *models.AppState) *MessageImportanceTask {
+	return &MessageImportanceTask{
+		BaseTask: BaseTask{
+			appState: appState,
+		},
+		useLLM: appState.Config.Extractors.Messages.Importance.Scorer == ImportanceScorerLLM,
+		roles:  roles.FromConfig(appState.Config),
+	}
+}
+
+// MessageImportanceTask scores the long-term importance of new messages, from 0 to 1, and
+// stores the score under system.importance. Scores are used to keep important messages
+// when sessions are compacted, and to rank message search results.
+type MessageImportanceTask struct {
+	BaseTask
+	useLLM bool
+	roles  *roles.Registry
+}
+
+func (t *MessageImportanceTask) Execute(
+	ctx context.Context,
+	msg *message.Message,
+) error {
+	ctx, done := context.WithTimeout(ctx, TaskTimeout*time.Second)
+	defer done()
+
+	sessionID := msg.Metadata.Get("session_id")
+	if sessionID == "" {
+		return errors.New("MessageImportanceTask session_id is empty")
+	}
+
+	log.Debugf("MessageImportanceTask called for session %s", sessionID)
+
+	messages, err := messageTaskPayloadToMessages(ctx, t.appState, msg)
+	if err != nil {
+		return fmt.Errorf("MessageImportanceTask messageTaskPayloadToMessages failed: %w", err)
+	}
+
+	err = t.process(ctx, sessionID, messages)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf("MessageImportanceTask messages not found. Were the records deleted?")
+			msg.Ack()
+			return nil
+		}
+		return fmt.Errorf("MessageImportanceTask failed: %w", err)
+	}
+
+	msg.Ack()
+
+	return nil
+}
+
+func (t *MessageImportanceTask) process(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+) error {
+	messages = dropEmptyMessages(messages)
+	if len(messages) == 0 {
+		return nil
+	}
+
+	memory, err := t.appState.MemoryStore.GetMemory(ctx, sessionID, importanceContextMessages)
+	if err != nil {
+		return fmt.Errorf("failed to get memory: %w", err)
+	}
+	batch := make(map[uuid.UUID]bool, len(messages))
+	for _, m := range messages {
+		batch[m.UUID] = true
+	}
+	var recent []models.Message
+	for _, m := range memory.Messages {
+		if !batch[m.UUID] && m.Content != "" {
+			recent = append(recent, m)
+		}
+	}
+
+	var scores []float64
+	if t.useLLM {
+		scores, err = t.scoreLLM(ctx, recent, messages)
+		if err != nil {
+			return err
+		}
+	} else {
+		scores = scoreImportanceHeuristic(recent, messages, t.roles)
+	}
+
+	updates := make([]models.Message, len(messages))
+	for i, m := range messages {
+		updates[i] = models.Message{
+			UUID:       m.UUID,
+			TokenCount: m.TokenCount,
+			Metadata: map[string]interface{}{"system": map[string]interface{}{
+				models.ImportanceMetadataKey: math.Round(scores[i]*100) / 100,
+			}},
+		}
+	}
+
+	return t.appState.MemoryStore.UpdateMessages(ctx, sessionID, updates, true, false)
+}
+
+// scoreLLM asks the LLM to rate each message from 1 to 10, given the recent messages, and
+// scales the ratings to 0 to 1. Messages the LLM doesn't rate score 0.
+func (t *MessageImportanceTask) scoreLLM(
+	ctx context.Context,
+	recent []models.Message,
+	messages []models.Message,
+) ([]float64, error) {
+	contextLines := []string{"None"}
+	if len(recent) > 0 {
+		contextLines = make([]string, len(recent))
+		for i, m := range recent {
+			contextLines[i] = fmt.Sprintf("%s: %s", t.roles.Label(m.Role), m.Content)
+		}
+	}
+	lines := make([]string, len(messages))
+	for i, m := range messages {
+		lines[i] = fmt.Sprintf("%d. %s: %s", i+1, t.roles.Label(m.Role), m.Content)
+	}
+
+	prompt, err := internal.ParsePrompt(importancePromptTemplate, ImportancePromptTemplateData{
+		ContextJoined:  strings.Join(contextLines, "\n"),
+		MessagesJoined: strings.Join(lines, "\n"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := t.appState.LLMClient.Call(ctx, prompt, llms.WithMaxTokens(importanceMaxTokens))
+	if err != nil {
+		return nil, fmt.Errorf("failed to score importance: %w", err)
+	}
+	ratings, err := parseImportanceRatings(response)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]float64, len(messages))
+	for line, rating := range ratings {
+		if line >= 1 && line <= len(messages) {
+			scores[line-1] = math.Max(0, math.Min(1, (rating-1)/9))
+		}
+	}
+	return scores, nil
+}
+
+func (t *MessageImportanceTask) HandleError(err error) {
+	log.Errorf("MessageImportanceTask failed: %v", err)
+}
+
+// parseImportanceRatings reads the ratings, by line number, of the JSON object in an LLM
+// response, ignoring any text or code fence around it.
+func parseImportanceRatings(response string) (map[int]float64, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no importance ratings found in LLM response: %q", response)
+	}
+
+	var parsed struct {
+		Ratings []struct {
+			Line   int     `json:"line"`
+			Rating float64 `json:"rating"`
+		} `json:"ratings"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse importance ratings: %w", err)
+	}
+	ratings := make(map[int]float64, len(parsed.Ratings))
+	for _, r := range parsed.Ratings {
+		ratings[r.Line] = r.Rating
+	}
+	return ratings, nil
+}
+
+// scoreImportanceHeuristic scores messages without the LLM. Directives weigh the most,
+// followed by novelty, the share of a message's words not used in the recent messages or
+// earlier in the batch, discounted for short messages, and emotional language. Messages that aren't from the user are
+// discounted, as they rarely hold facts about the user.
+func scoreImportanceHeuristic(
+	recent []models.Message,
+	messages []models.Message,
+	registry *roles.Registry,
+) []float64 {
+	seen := make(map[string]bool)
+	for _, m := range recent {
+		for _, word := range importanceWords(m.Content) {
+			seen[word] = true
+		}
+	}
+
+	scores := make([]float64, len(messages))
+	for i, m := range messages {
+		var score float64
+		if importanceDirectiveRegex.MatchString(m.Content) {
+			score += 0.45
+		}
+
+		var words []string
+		var emotional int
+		for _, word := range importanceWords(m.Content) {
+			if importanceEmotionWords[word] {
+				emotional++
+			}
+			// short words are mostly function words, and not counted towards novelty
+			if len([]rune(word)) > 3 {
+				words = append(words, word)
+			}
+		}
+		var novel int
+		for _, word := range words {
+			if !seen[word] {
+				novel++
+			}
+		}
+		if len(words) > 0 {
+			// short messages, such as acknowledgements, carry little that's new
+			length := math.Min(1, float64(len(words))/4)
+			score += 0.35 * length * float64(novel) / float64(len(words))
+		}
+		score += math.Min(0.2, 0.1*float64(emotional)+0.05*float64(strings.Count(m.Content, "!")))
+
+		if kind := registry.Kind(m.Role); kind != "" && kind != roles.User {
+			score *= 0.6
+		}
+		scores[i] = math.Min(1, score)
+
+		for _, word := range words {
+			seen[word] = true
+		}
+	}
+	return scores
+}
+
+// importanceWords returns the lowercased words of text.
+func importanceWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '\'' || r > 127)
+	})
+}
@@ -0,0 +1,97 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestScoreImportanceHeuristic(t *testing.T) {
+	recent := []models.Message{
+		{Role: "user", Content: "What's the weather like in Lisbon?"},
+		{Role: "assistant", Content: "Sunny and warm in Lisbon today."},
+	}
+	messages := []models.Message{
+		{Role: "user", Content: "ok thanks"},
+		{Role: "user", Content: "Remember that I'm allergic to peanuts!"},
+		{Role: "assistant", Content: "Remember that I'm allergic to peanuts!"},
+		{Role: "user", Content: "What's the weather like in Lisbon?"},
+	}
+	scores := scoreImportanceHeuristic(recent, messages, nil)
+
+	// directives outrank small talk, and are discounted if not from the user
+	assert.Greater(t, scores[1], 0.7)
+	assert.Less(t, scores[2], scores[1])
+	assert.Less(t, scores[0], scores[2])
+	// a repeated question isn't novel
+	assert.Equal(t, 0.0, scores[3])
+}
+
+func TestParseImportanceRatings(t *testing.T) {
+	ratings, err := parseImportanceRatings(
+		"```json\n" + `{"ratings": [{"line": 1, "rating": 2}, {"line": 2, "rating": 9}]}` + "\n```",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]float64{1: 2, 2: 9}, ratings)
+
+	_, err = parseImportanceRatings("All of them are important.")
+	assert.Error(t, err)
+}
+
+func TestMessageImportanceTask(t *testing.T) {
+	db := inmemory.NewDB()
+	llm := &cannedLLM{response: `{"ratings": [{"line": 1, "rating": 10}, {"line": 3, "rating": 1}]}`}
+	importanceAppState := &models.AppState{Config: &config.Config{}, LLMClient: llm}
+	importanceAppState.Config.Extractors.Messages.Importance.Scorer = ImportanceScorerLLM
+	importanceAppState.MemoryStore = inmemory.NewMemoryStore(importanceAppState, db)
+
+	uuids := []uuid.UUID{uuid.New(), uuid.New()}
+	err := importanceAppState.MemoryStore.PutMemory(testCtx, "importance", &models.Memory{
+		Messages: []models.Message{
+			{UUID: uuids[0], Role: "user", Content: "My daughter's name is Ana."},
+			{UUID: uuids[1], Role: "assistant", Content: "What a lovely name."},
+		},
+	}, true)
+	require.NoError(t, err)
+	messages, err := importanceAppState.MemoryStore.GetMessagesByUUID(testCtx, "importance", uuids)
+	require.NoError(t, err)
+
+	task := NewMessageImportanceTask(importanceAppState)
+	require.NoError(t, task.process(testCtx, "importance", messages))
+
+	messages, err = importanceAppState.MemoryStore.GetMessagesByUUID(testCtx, "importance", uuids)
+	require.NoError(t, err)
+	for _, m := range messages {
+		importance, ok := models.MessageImportance(&m)
+		assert.True(t, ok)
+		if m.UUID == uuids[0] {
+			assert.Equal(t, 1.0, importance)
+		} else {
+			// lines the LLM doesn't rate score 0
+			assert.Equal(t, 0.0, importance)
+		}
+	}
+}
+
+func TestPrunedCandidates(t *testing.T) {
+	important, trivial, unscored := uuid.New(), uuid.New(), uuid.New()
+	scored := func(id uuid.UUID, importance float64) models.Message {
+		return models.Message{UUID: id, Metadata: map[string]interface{}{
+			"system": map[string]interface{}{models.ImportanceMetadataKey: importance},
+		}}
+	}
+	candidates := []models.Message{
+		scored(important, 0.9),
+		scored(trivial, 0.1),
+		{UUID: unscored},
+	}
+
+	assert.Equal(t, []uuid.UUID{trivial, unscored}, prunedCandidates(candidates, 0.8))
+	assert.Equal(t, []uuid.UUID{important, trivial, unscored}, prunedCandidates(candidates, 0))
+}
@@ -0,0 +1,63 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+var _ models.Task = &routedTask{}
+
+// routedTask runs a message task on only the messages that message rules route to its
+// topic. See the rules package.
+type routedTask struct {
+	models.Task
+	appState *models.AppState
+	topic    models.TaskTopic
+}
+
+func newRoutedTask(
+	appState *models.AppState,
+	topic models.TaskTopic,
+	task models.Task,
+) *routedTask {
+	return &routedTask{Task: task, appState: appState, topic: topic}
+}
+
+func (t *routedTask) Execute(ctx context.Context, msg *message.Message) error {
+	messages, err := messageTaskPayloadToMessages(ctx, t.appState, msg)
+	if err != nil {
+		return fmt.Errorf("routed %s task failed: %w", t.topic, err)
+	}
+
+	var routed []models.MessageTask
+	for i := range messages {
+		rules := models.MessageRulesOf(&messages[i])
+		if slices.Contains(rules.Extractors, string(t.topic)) {
+			routed = append(routed, models.MessageTask{UUID: messages[i].UUID})
+		}
+	}
+	if len(routed) == 0 {
+		msg.Ack()
+		return nil
+	}
+
+	payload, err := json.Marshal(routed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal routed messages: %w", err)
+	}
+	msg.Payload = payload
+	return t.Task.Execute(ctx, msg)
+}
+
+// dropSkippedMessages drops the messages that message rules leave out of summaries.
+func dropSkippedMessages(messages []models.Message) []models.Message {
+	return slices.DeleteFunc(messages, func(m models.Message) bool {
+		return models.MessageRulesOf(&m).SkipSummarization
+	})
+}
@@ -0,0 +1,90 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+// recordingTask records the payloads it's executed with.
+type recordingTask struct {
+	BaseTask
+	payloads [][]models.MessageTask
+}
+
+func (t *recordingTask) Execute(_ context.Context, msg *message.Message) error {
+	var payload []models.MessageTask
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return err
+	}
+	t.payloads = append(t.payloads, payload)
+	return nil
+}
+
+func routedMetadata(rules models.MessageRules) map[string]interface{} {
+	return map[string]interface{}{"system": map[string]interface{}{
+		models.MessageRulesKey: map[string]interface{}{
+			"matched":            rules.Matched,
+			"extractors":         rules.Extractors,
+			"skip_summarization": rules.SkipSummarization,
+		},
+	}}
+}
+
+func TestRoutedTask(t *testing.T) {
+	db := inmemory.NewDB()
+	routedAppState := &models.AppState{Config: &config.Config{}}
+	routedAppState.MemoryStore = inmemory.NewMemoryStore(routedAppState, db)
+
+	routed, other := uuid.New(), uuid.New()
+	err := routedAppState.MemoryStore.PutMemory(testCtx, "routed", &models.Memory{
+		Messages: []models.Message{
+			{UUID: routed, Role: "user", Content: "invoice", Metadata: routedMetadata(
+				models.MessageRules{Matched: []string{"billing"}, Extractors: []string{"message_intent"}},
+			)},
+			{UUID: other, Role: "user", Content: "hello"},
+		},
+	}, true)
+	require.NoError(t, err)
+
+	inner := &recordingTask{}
+	task := newRoutedTask(routedAppState, models.MessageIntentTopic, inner)
+	execute := func(uuids ...uuid.UUID) {
+		t.Helper()
+		payload := make([]models.MessageTask, len(uuids))
+		for i, id := range uuids {
+			payload[i] = models.MessageTask{UUID: id}
+		}
+		b, err := json.Marshal(payload)
+		require.NoError(t, err)
+		msg := message.NewMessage(uuid.NewString(), b)
+		msg.Metadata.Set("session_id", "routed")
+		require.NoError(t, task.Execute(testCtx, msg))
+	}
+
+	execute(routed, other)
+	execute(other)
+	require.Len(t, inner.payloads, 1)
+	assert.Equal(t, []models.MessageTask{{UUID: routed}}, inner.payloads[0])
+}
+
+func TestDropSkippedMessages(t *testing.T) {
+	messages := []models.Message{
+		{Content: "a"},
+		{Content: "b", Metadata: routedMetadata(models.MessageRules{SkipSummarization: true})},
+		{Content: "c", Metadata: routedMetadata(models.MessageRules{Matched: []string{"r"}})},
+	}
+	messages = dropSkippedMessages(messages)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "a", messages[0].Content)
+	assert.Equal(t, "c", messages[1].Content)
+}
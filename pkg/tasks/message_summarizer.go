@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/getzep/zep/internal"
 	"github.com/getzep/zep/pkg/llms"
 	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
 )
 
 const MaxTokensFallback = 2048
@@ -32,6 +34,7 @@ var _ models.Task = &MessageSummaryTask{}
 // if there is one.
 type MessageSummaryTask struct {
 	BaseTask
+	roles *roles.Registry
 }
 
 func NewMessageSummaryTask(appState *models.AppState) *MessageSummaryTask {
@@ -39,6 +42,7 @@ func NewMessageSummaryTask(appState *models.AppState) *MessageSummaryTask {
 		BaseTask: BaseTask{
 			appState: appState,
 		},
+		roles: roles.FromConfig(appState.Config),
 	}
 }
 
@@ -77,8 +81,11 @@ func (t *MessageSummaryTask) Execute(
 		return nil
 	}
 
-	// drop empty messages
-	messages = dropEmptyMessages(messages)
+	// drop empty messages, and those that rules or role policies leave out of summaries
+	messages = dropSkippedMessages(dropEmptyMessages(messages))
+	messages = slices.DeleteFunc(messages, func(m models.Message) bool {
+		return !t.roles.Summarized(m.Role)
+	})
 
 	// If we're still under the message window, we don't need to summarize.
 	if len(messages) < t.appState.Config.Memory.MessageWindow {
@@ -139,23 +146,10 @@ func (t *MessageSummaryTask) summarize(
 	// Oldest messages that are over the newMessageCount
 	messagesToSummarize := messages[:len(messages)-newMessageCount]
 
-	modelName, err := llms.GetLLMModelName(t.appState.Config)
+	summarizerMaxInputTokens, err := t.summarizerMaxInputTokens(promptTokens)
 	if err != nil {
 		return &models.Summary{}, err
 	}
-	maxTokens, ok := llms.MaxLLMTokensMap[modelName]
-	if !ok {
-		maxTokens = MaxTokensFallback
-	}
-
-	if promptTokens == 0 {
-		// rough calculation of tokes for current prompt, plus some headroom
-		promptTokens = 250
-	}
-
-	// We use this to determine how many tokens we can use for the incremental summarization
-	// loop. We add more messages to a summarization loop until we hit this.
-	summarizerMaxInputTokens := maxTokens - SummaryMaxOutputTokens - promptTokens
 
 	// Take the oldest messages that are over newMessageCount and summarize them.
 	newSummary, err := t.processOverLimitMessages(
@@ -177,6 +171,26 @@ func (t *MessageSummaryTask) summarize(
 	return newSummary, nil
 }
 
+// summarizerMaxInputTokens returns the number of tokens we can use for the incremental summarization
+// loop. We add more messages to a summarization loop until we hit this.
+func (t *MessageSummaryTask) summarizerMaxInputTokens(promptTokens int) (int, error) {
+	modelName, err := llms.GetLLMModelName(t.appState.Config)
+	if err != nil {
+		return 0, err
+	}
+	maxTokens, ok := llms.MaxLLMTokensMap[modelName]
+	if !ok {
+		maxTokens = MaxTokensFallback
+	}
+
+	if promptTokens == 0 {
+		// rough calculation of tokes for current prompt, plus some headroom
+		promptTokens = 250
+	}
+
+	return maxTokens - SummaryMaxOutputTokens - promptTokens, nil
+}
+
 // processOverLimitMessages takes a slice of messages and a summary and enriches
 // the summary with the messages content. Summary can an empty string. Returns a
 // Summary model with enriched summary and the number of tokens in the summary.
@@ -215,7 +229,7 @@ func (t *MessageSummaryTask) processOverLimitMessages(
 	}
 
 	for _, m := range messages {
-		messageText := fmt.Sprintf("%s: %s", m.Role, m.Content)
+		messageText := fmt.Sprintf("%s: %s", t.roles.Label(m.Role), m.Content)
 		messageTokens, err := t.appState.LLMClient.GetTokenCount(messageText)
 		if err != nil {
 			return nil, err
@@ -319,7 +333,7 @@ func (t *MessageSummaryTask) generateProgressiveSummarizerPrompt(
 
 	var summaryPromptTemplate string
 	switch t.appState.Config.LLM.Service {
-	case "openai":
+	case "openai", llms.FakeLLMService:
 		if customSummaryPromptTemplateOpenAI != "" {
 			summaryPromptTemplate = customSummaryPromptTemplateOpenAI
 		} else {
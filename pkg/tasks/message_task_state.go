@@ -0,0 +1,146 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const taskStateMaxTokens = 512
+
+var _ models.Task = &MessageTaskStateTask{}
+
+func NewMessageTaskStateTask(appState *models.AppState) *MessageTaskStateTask {
+	return &MessageTaskStateTask{
+		BaseTask{
+			appState: appState,
+		},
+	}
+}
+
+// MessageTaskStateTask updates a session's task state with each batch of new messages,
+// asking the LLM to revise the current state rather than derive it from the whole
+// conversation.
+type MessageTaskStateTask struct {
+	BaseTask
+}
+
+func (t *MessageTaskStateTask) Execute(
+	ctx context.Context,
+	msg *message.Message,
+) error {
+	ctx, done := context.WithTimeout(ctx, TaskTimeout*time.Second)
+	defer done()
+
+	sessionID := msg.Metadata.Get("session_id")
+	if sessionID == "" {
+		return errors.New("MessageTaskStateTask session_id is empty")
+	}
+
+	log.Debugf("MessageTaskStateTask called for session %s", sessionID)
+
+	messages, err := messageTaskPayloadToMessages(ctx, t.appState, msg)
+	if err != nil {
+		return fmt.Errorf("MessageTaskStateTask messageTaskPayloadToMessages failed: %w", err)
+	}
+
+	err = t.process(ctx, sessionID, messages)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf("MessageTaskStateTask session %s not found. Was it deleted?", sessionID)
+			msg.Ack()
+			return nil
+		}
+		return fmt.Errorf("MessageTaskStateTask failed: %w", err)
+	}
+
+	msg.Ack()
+
+	return nil
+}
+
+func (t *MessageTaskStateTask) process(
+	ctx context.Context,
+	sessionID string,
+	messages []models.Message,
+) error {
+	var lines []string
+	for _, m := range messages {
+		if m.Content != "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", m.Role, m.Content))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	session, err := t.appState.MemoryStore.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	current := "None"
+	if session.TaskState != nil {
+		b, err := json.Marshal(session.TaskState)
+		if err != nil {
+			return fmt.Errorf("failed to marshal task state: %w", err)
+		}
+		current = string(b)
+	}
+
+	prompt, err := internal.ParsePrompt(taskStatePromptTemplate, TaskStatePromptTemplateData{
+		CurrentState:   current,
+		MessagesJoined: strings.Join(lines, "\n"),
+	})
+	if err != nil {
+		return err
+	}
+
+	response, err := t.appState.LLMClient.Call(ctx, prompt, llms.WithMaxTokens(taskStateMaxTokens))
+	if err != nil {
+		return fmt.Errorf("failed to update task state: %w", err)
+	}
+
+	state, err := parseTaskState(response)
+	if err != nil {
+		return err
+	}
+	state.UpdatedAt = time.Now().UTC()
+
+	return t.appState.MemoryStore.UpdateSessionTaskState(ctx, sessionID, state)
+}
+
+func (t *MessageTaskStateTask) HandleError(err error) {
+	log.Errorf("MessageTaskStateTask failed: %v", err)
+}
+
+// parseTaskState reads the JSON object in an LLM response, ignoring any text or code
+// fence around it.
+func parseTaskState(response string) (*models.TaskState, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no task state found in LLM response: %q", response)
+	}
+
+	var state models.TaskState
+	if err := json.Unmarshal([]byte(response[start:end+1]), &state); err != nil {
+		return nil, fmt.Errorf("failed to parse task state: %w", err)
+	}
+	if state.StepsDone == nil {
+		state.StepsDone = []string{}
+	}
+	if state.Blockers == nil {
+		state.Blockers = []string{}
+	}
+	return &state, nil
+}
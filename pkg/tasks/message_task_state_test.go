@@ -0,0 +1,71 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+// cannedLLM responds to every call with response.
+type cannedLLM struct {
+	models.ZepLLM
+	response string
+}
+
+func (l *cannedLLM) Call(_ context.Context, _ string, _ ...llms.CallOption) (string, error) {
+	return l.response, nil
+}
+
+func TestParseTaskState(t *testing.T) {
+	state, err := parseTaskState("```json\n" +
+		`{"stage": "executing", "goal": "Book a flight", "steps_done": ["chose dates"]}` +
+		"\n```")
+	require.NoError(t, err)
+	assert.Equal(t, "executing", state.Stage)
+	assert.Equal(t, "Book a flight", state.Goal)
+	assert.Equal(t, []string{"chose dates"}, state.StepsDone)
+	assert.Equal(t, []string{}, state.Blockers)
+
+	_, err = parseTaskState("There is no task.")
+	assert.Error(t, err)
+}
+
+func TestMessageTaskStateTask(t *testing.T) {
+	db := inmemory.NewDB()
+	llm := &cannedLLM{
+		response: `{"stage": "gathering requirements", "goal": "Book a flight", ` +
+			`"steps_done": [], "blockers": ["travel dates unknown"]}`,
+	}
+	taskStateAppState := &models.AppState{Config: &config.Config{}, LLMClient: llm}
+	taskStateAppState.MemoryStore = inmemory.NewMemoryStore(taskStateAppState, db)
+
+	_, err := taskStateAppState.MemoryStore.CreateSession(
+		testCtx,
+		&models.CreateSessionRequest{SessionID: "task-state"},
+	)
+	require.NoError(t, err)
+
+	task := NewMessageTaskStateTask(taskStateAppState)
+	err = task.process(testCtx, "task-state", []models.Message{
+		{UUID: uuid.New(), Role: "user", Content: "I need a flight to Lisbon"},
+	})
+	require.NoError(t, err)
+
+	session, err := taskStateAppState.MemoryStore.GetSession(testCtx, "task-state")
+	require.NoError(t, err)
+	require.NotNil(t, session.TaskState)
+	assert.Equal(t, "Book a flight", session.TaskState.Goal)
+	assert.Equal(t, []string{"travel dates unknown"}, session.TaskState.Blockers)
+	assert.False(t, session.TaskState.UpdatedAt.IsZero())
+
+	err = task.process(testCtx, "missing", []models.Message{{Role: "user", Content: "hi"}})
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
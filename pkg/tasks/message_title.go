@@ -0,0 +1,170 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	titleMaxTokens          = 32
+	titleMaxLength          = 100
+	titleContextMessages    = 12
+	defaultTitleMinMessages = 4
+)
+
+var _ models.Task = &MessageTitleTask{}
+
+func NewMessageTitleTask(appState *models.AppState) *MessageTitleTask {
+	return &MessageTitleTask{
+		BaseTask{
+			appState: appState,
+		},
+	}
+}
+
+// MessageTitleTask generates a short title for a session once it has enough messages to
+// describe, and refreshes it periodically as the session grows. The title is stored on
+// the session.
+type MessageTitleTask struct {
+	BaseTask
+}
+
+func (t *MessageTitleTask) Execute(
+	ctx context.Context,
+	msg *message.Message,
+) error {
+	ctx, done := context.WithTimeout(ctx, TaskTimeout*time.Second)
+	defer done()
+
+	sessionID := msg.Metadata.Get("session_id")
+	if sessionID == "" {
+		return errors.New("MessageTitleTask session_id is empty")
+	}
+
+	log.Debugf("MessageTitleTask called for session %s", sessionID)
+
+	var messageTasks []models.MessageTask
+	if err := json.Unmarshal(msg.Payload, &messageTasks); err != nil {
+		return fmt.Errorf("MessageTitleTask failed to unmarshal payload: %w", err)
+	}
+
+	err := t.process(ctx, sessionID, len(messageTasks))
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Warnf("MessageTitleTask session %s not found. Was it deleted?", sessionID)
+			msg.Ack()
+			return nil
+		}
+		return fmt.Errorf("MessageTitleTask failed: %w", err)
+	}
+
+	msg.Ack()
+
+	return nil
+}
+
+func (t *MessageTitleTask) process(ctx context.Context, sessionID string, added int) error {
+	session, err := t.appState.MemoryStore.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	list, err := t.appState.MemoryStore.GetMessageList(ctx, sessionID, 1, 1)
+	if err != nil {
+		return fmt.Errorf("failed to get message count: %w", err)
+	}
+
+	cfg := t.appState.Config.Extractors.Messages.Title
+	if !titleDue(session.Title, list.TotalCount-added, list.TotalCount,
+		cfg.MinMessages, cfg.RefreshEvery) {
+		return nil
+	}
+
+	memory, err := t.appState.MemoryStore.GetMemory(ctx, sessionID, titleContextMessages)
+	if err != nil {
+		return fmt.Errorf("failed to get memory: %w", err)
+	}
+
+	var lines []string
+	for _, m := range memory.Messages {
+		if m.Content == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", m.Role, m.Content))
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	prompt, err := internal.ParsePrompt(titlePromptTemplate, TitlePromptTemplateData{
+		CurrentTitle:   session.Title,
+		MessagesJoined: strings.Join(lines, "\n"),
+	})
+	if err != nil {
+		return err
+	}
+
+	response, err := t.appState.LLMClient.Call(ctx, prompt, llms.WithMaxTokens(titleMaxTokens))
+	if err != nil {
+		return fmt.Errorf("failed to generate title: %w", err)
+	}
+
+	title := cleanTitle(response)
+	if title == "" || title == session.Title {
+		return nil
+	}
+
+	return t.appState.MemoryStore.UpdateSessionTitle(ctx, sessionID, title)
+}
+
+func (t *MessageTitleTask) HandleError(err error) {
+	log.Errorf("MessageTitleTask failed: %v", err)
+}
+
+// titleDue reports whether a session's title should be generated or refreshed, given the
+// number of messages in the session before and after the latest messages were added.
+// An untitled session is titled once it reaches minMessages; a titled one is refreshed
+// each time its message count crosses a multiple of refreshEvery.
+func titleDue(title string, previous, total, minMessages, refreshEvery int) bool {
+	if minMessages <= 0 {
+		minMessages = defaultTitleMinMessages
+	}
+	if title == "" {
+		return total >= minMessages
+	}
+	if refreshEvery <= 0 {
+		return false
+	}
+	return total/refreshEvery > previous/refreshEvery
+}
+
+// cleanTitle strips the decoration models tend to add to a title, such as a "Title:"
+// prefix, quotes, and trailing punctuation, and limits its length.
+func cleanTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if line, _, found := strings.Cut(title, "\n"); found {
+		title = strings.TrimSpace(line)
+	}
+	if prefix, rest, found := strings.Cut(title, ":"); found &&
+		strings.EqualFold(strings.TrimSpace(prefix), "title") {
+		title = rest
+	}
+	title = strings.Trim(title, " \t\"'`*")
+	title = strings.TrimRight(title, ".!")
+	title = strings.TrimSpace(title)
+
+	if runes := []rune(title); len(runes) > titleMaxLength {
+		title = strings.TrimSpace(string(runes[:titleMaxLength]))
+	}
+	return title
+}
@@ -0,0 +1,40 @@
+package tasks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTitleDue(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		previous int
+		total    int
+		refresh  int
+		expected bool
+	}{
+		{"too few messages", "", 0, 3, 0, false},
+		{"reaches minimum", "", 2, 4, 0, true},
+		{"untitled past minimum", "", 9, 10, 0, true},
+		{"titled without refresh", "Trip", 40, 42, 0, false},
+		{"titled before refresh", "Trip", 16, 18, 20, false},
+		{"crosses refresh", "Trip", 18, 21, 20, true},
+		{"crosses second refresh", "Trip", 39, 40, 20, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, titleDue(tt.title, tt.previous, tt.total, 0, tt.refresh))
+		})
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	assert.Equal(t, "Planning a trip to Lisbon", cleanTitle(` "Planning a trip to Lisbon." `))
+	assert.Equal(t, "Planning a trip", cleanTitle("Title: **Planning a trip**"))
+	assert.Equal(t, "Planning a trip", cleanTitle("Planning a trip\n\nThe conversation is about"))
+	assert.Equal(t, "Recipe: Pasta", cleanTitle("Recipe: Pasta"))
+	assert.Len(t, []rune(cleanTitle(strings.Repeat("é", 200))), titleMaxLength)
+}
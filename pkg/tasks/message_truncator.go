@@ -0,0 +1,209 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// truncatePageSize is the page size used to read a session's messages when truncating.
+const truncatePageSize = 1000
+
+// TruncateSession prunes the content of a session's oldest messages, keeping those chosen
+// by the request's strategy and any pinned messages. Unlike the MessageCompactorTask, it
+// runs synchronously, so that a client near its model's context limit can truncate and
+// then immediately retrieve the shorter memory.
+//
+// If the summarizer is enabled, truncated messages not yet captured by the running
+// summary are first summarized into it, as compaction does. The summary point then moves
+// past any pinned messages among them, so these are no longer returned by GetMemory, but
+// remain in the session's message list.
+//
+// A session under legal hold isn't truncated, and a ConflictError is returned.
+func TruncateSession(
+	ctx context.Context,
+	appState *models.AppState,
+	sessionID string,
+	request *models.TruncateSessionRequest,
+) (*models.TruncateSessionResponse, error) {
+	if err := checkSessionHold(ctx, appState, sessionID); err != nil {
+		return nil, err
+	}
+
+	messages, summaryPoint, err := truncationMessages(ctx, appState, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Strategy == models.TruncateKeepLastNTokens {
+		for i := range messages {
+			if messages[i].TokenCount > 0 {
+				continue
+			}
+			messages[i].TokenCount, err = appState.LLMClient.GetTokenCount(messages[i].Content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get token count: %w", err)
+			}
+		}
+	}
+
+	truncated, retained := selectTruncated(messages, request)
+	response := &models.TruncateSessionResponse{
+		TruncatedCount: len(truncated),
+		RetainedCount:  len(retained),
+	}
+	if len(truncated) == 0 {
+		response.Summary, err = currentSummary(ctx, appState, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	uuids := make([]uuid.UUID, len(truncated))
+	isTruncated := make(map[uuid.UUID]bool, len(truncated))
+	for i := range truncated {
+		uuids[i] = truncated[i].UUID
+		isTruncated[truncated[i].UUID] = true
+	}
+
+	// truncated messages after the summary point haven't been summarized yet
+	var unsummarized []models.Message
+	for _, m := range messages[summaryPoint:] {
+		if isTruncated[m.UUID] {
+			unsummarized = append(unsummarized, m)
+		}
+	}
+	if len(unsummarized) > 0 && appState.Config.Extractors.Messages.Summarizer.Enabled {
+		err = NewMessageCompactorTask(appState).summarize(ctx, sessionID, unsummarized)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize truncated messages: %w", err)
+		}
+	}
+
+	err = appState.MemoryStore.PruneMessages(
+		ctx,
+		sessionID,
+		uuids,
+		appState.Config.Memory.RetainCompactedEmbeddings,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune truncated messages: %w", err)
+	}
+
+	response.Summary, err = currentSummary(ctx, appState, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// currentSummary returns the session's summary, or nil if it has none.
+func currentSummary(
+	ctx context.Context,
+	appState *models.AppState,
+	sessionID string,
+) (*models.Summary, error) {
+	summary, err := appState.MemoryStore.GetSummary(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+	if summary == nil || summary.UUID == uuid.Nil {
+		return nil, nil
+	}
+	return summary, nil
+}
+
+// truncationMessages returns the session's messages that still hold content, oldest
+// first, and the number of these at or before the current summary point.
+func truncationMessages(
+	ctx context.Context,
+	appState *models.AppState,
+	sessionID string,
+) ([]models.Message, int, error) {
+	if _, err := appState.MemoryStore.GetSession(ctx, sessionID); err != nil {
+		return nil, 0, err
+	}
+
+	summary, err := appState.MemoryStore.GetSummary(ctx, sessionID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get summary: %w", err)
+	}
+	summaryPointUUID := uuid.Nil
+	if summary != nil {
+		summaryPointUUID = summary.SummaryPointUUID
+	}
+
+	var messages []models.Message
+	summaryPoint := 0
+	for page := 1; ; page++ {
+		list, err := appState.MemoryStore.GetMessageList(ctx, sessionID, page, truncatePageSize)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get messages: %w", err)
+		}
+		for _, m := range list.Messages {
+			if m.Content != "" {
+				messages = append(messages, m)
+			}
+			if m.UUID == summaryPointUUID {
+				summaryPoint = len(messages)
+			}
+		}
+		if len(list.Messages) < truncatePageSize {
+			break
+		}
+	}
+
+	return messages, summaryPoint, nil
+}
+
+// selectTruncated splits messages, oldest first, into those to truncate and those to
+// keep. Pinned messages are always kept and don't count towards the request's N. The
+// truncated messages are returned in order; the kept messages include pinned messages
+// older than the truncation point.
+func selectTruncated(
+	messages []models.Message,
+	request *models.TruncateSessionRequest,
+) (truncated, retained []models.Message) {
+	keep := make([]bool, len(messages))
+	kept := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if isPinned(&messages[i]) {
+			keep[i] = true
+			continue
+		}
+		switch request.Strategy {
+		case models.TruncateKeepLastN:
+			keep[i] = kept < request.N
+			kept++
+		case models.TruncateKeepLastNTokens:
+			kept += messages[i].TokenCount
+			keep[i] = kept <= request.N
+		}
+		if !keep[i] {
+			// everything older is truncated, too
+			for j := i - 1; j >= 0; j-- {
+				keep[j] = isPinned(&messages[j])
+			}
+			break
+		}
+	}
+
+	for i := range messages {
+		if keep[i] {
+			retained = append(retained, messages[i])
+		} else {
+			truncated = append(truncated, messages[i])
+		}
+	}
+	return truncated, retained
+}
+
+func isPinned(message *models.Message) bool {
+	pinned, ok := message.Metadata[models.PinnedMetadataKey].(bool)
+	return ok && pinned
+}
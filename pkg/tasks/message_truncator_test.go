@@ -0,0 +1,127 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+	"github.com/getzep/zep/pkg/testutils"
+)
+
+func TestSelectTruncated(t *testing.T) {
+	pinned := map[string]interface{}{models.PinnedMetadataKey: true}
+	messages := []models.Message{
+		{Content: "0", TokenCount: 10},
+		{Content: "1", TokenCount: 10, Metadata: pinned},
+		{Content: "2", TokenCount: 10},
+		{Content: "3", TokenCount: 10},
+		{Content: "4", TokenCount: 10},
+	}
+
+	tests := []struct {
+		name      string
+		request   models.TruncateSessionRequest
+		truncated []string
+	}{
+		{
+			"keep last n",
+			models.TruncateSessionRequest{Strategy: models.TruncateKeepLastN, N: 2},
+			[]string{"0", "2"},
+		},
+		{
+			"keep more than exist",
+			models.TruncateSessionRequest{Strategy: models.TruncateKeepLastN, N: 10},
+			nil,
+		},
+		{
+			"keep last n tokens",
+			models.TruncateSessionRequest{Strategy: models.TruncateKeepLastNTokens, N: 25},
+			[]string{"0", "2"},
+		},
+		{
+			"keep last n tokens skips pinned",
+			models.TruncateSessionRequest{Strategy: models.TruncateKeepLastNTokens, N: 30},
+			[]string{"0"},
+		},
+		{
+			"summary only",
+			models.TruncateSessionRequest{Strategy: models.TruncateSummaryOnly},
+			[]string{"0", "2", "3", "4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncated, retained := selectTruncated(messages, &tt.request)
+			var contents []string
+			for _, m := range truncated {
+				contents = append(contents, m.Content)
+			}
+			assert.Equal(t, tt.truncated, contents)
+			assert.Len(t, retained, len(messages)-len(truncated))
+		})
+	}
+}
+
+func TestTruncateSession(t *testing.T) {
+	db := inmemory.NewDB()
+	cfg := &config.Config{}
+	cfg.Memory.RetainCompactedEmbeddings = true
+	truncateState := &models.AppState{Config: cfg}
+	truncateState.MemoryStore = inmemory.NewMemoryStore(truncateState, db)
+
+	sessionID, err := testutils.GenerateRandomSessionID(16)
+	require.NoError(t, err)
+	_, err = truncateState.MemoryStore.CreateSession(
+		testCtx,
+		&models.CreateSessionRequest{SessionID: sessionID},
+	)
+	require.NoError(t, err)
+
+	messages := make([]models.Message, 4)
+	for i := range messages {
+		messages[i] = models.Message{UUID: uuid.New(), Role: "user", Content: "content"}
+	}
+	messages[0].Metadata = map[string]interface{}{models.PinnedMetadataKey: true}
+	err = truncateState.MemoryStore.PutMemory(
+		testCtx,
+		sessionID,
+		&models.Memory{Messages: messages},
+		true,
+	)
+	require.NoError(t, err)
+
+	response, err := TruncateSession(
+		testCtx,
+		truncateState,
+		sessionID,
+		&models.TruncateSessionRequest{Strategy: models.TruncateKeepLastN, N: 1},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.TruncatedCount)
+	assert.Equal(t, 2, response.RetainedCount)
+	assert.Nil(t, response.Summary)
+
+	list, err := truncateState.MemoryStore.GetMessageList(testCtx, sessionID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, list.Messages, 4)
+	for i, m := range list.Messages {
+		if i == 1 || i == 2 {
+			assert.Empty(t, m.Content)
+		} else {
+			assert.Equal(t, "content", m.Content)
+		}
+	}
+
+	_, err = TruncateSession(
+		testCtx,
+		truncateState,
+		"missing",
+		&models.TruncateSessionRequest{Strategy: models.TruncateSummaryOnly},
+	)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
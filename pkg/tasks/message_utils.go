@@ -3,6 +3,7 @@ package tasks
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -11,6 +12,45 @@ import (
 	"github.com/google/uuid"
 )
 
+// checkSessionHold returns a ConflictError if the session, or the session's user, is
+// under legal hold, as held messages mustn't be pruned or summarized away. A session that
+// doesn't exist isn't held.
+func checkSessionHold(ctx context.Context, appState *models.AppState, sessionID string) error {
+	if appState.LegalHoldStore == nil {
+		return nil
+	}
+	session, err := appState.MemoryStore.GetSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get session %s: %w", sessionID, err)
+	}
+	if err := checkLegalHold(ctx, appState, models.LegalHoldSubjectSession, sessionID); err != nil {
+		return err
+	}
+	if session.UserID == nil {
+		return nil
+	}
+	return checkLegalHold(ctx, appState, models.LegalHoldSubjectUser, *session.UserID)
+}
+
+// checkLegalHold returns a ConflictError if the subject is under legal hold.
+func checkLegalHold(
+	ctx context.Context,
+	appState *models.AppState,
+	subjectType, subjectID string,
+) error {
+	_, err := appState.LegalHoldStore.Get(ctx, subjectType, subjectID)
+	if err == nil {
+		return models.NewLegalHoldError(subjectType, subjectID)
+	}
+	if !errors.Is(err, models.ErrNotFound) {
+		return fmt.Errorf("failed to check legal hold: %w", err)
+	}
+	return nil
+}
+
 func dropEmptyMessages(messages []models.Message) []models.Message {
 	for i := len(messages) - 1; i >= 0; i-- {
 		if strings.TrimSpace(messages[i].Content) == "" {
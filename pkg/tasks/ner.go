@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/egress"
 	"github.com/getzep/zep/pkg/models"
 )
 
@@ -43,7 +44,11 @@ func callNERTask(
 	var bodyBytes []byte
 	var response models.EntityResponse
 
-	client := NewRetryableHTTPClient(NerRetryMax, NerTimeout)
+	tlsConfig, err := egress.LoadTLSConfig(appState.Config.NLP.TLS)
+	if err != nil {
+		return models.EntityResponse{}, fmt.Errorf("invalid nlp.tls: %w", err)
+	}
+	client := NewRetryableHTTPClientWithTLS(NerRetryMax, NerTimeout, tlsConfig)
 
 	req, err := http.NewRequestWithContext(
 		ctx,
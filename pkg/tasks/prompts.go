@@ -16,6 +16,239 @@ type IntentPromptTemplateData struct {
 	Input string
 }
 
+const titlePromptTemplate = `
+Write a short title, of no more than eight words, for the conversation below. The title
+should describe what the conversation is about, not who is taking part in it.
+{{if .CurrentTitle}}
+The conversation currently has the title "{{.CurrentTitle}}". If this still describes
+the conversation well, respond with it unchanged.
+{{end}}
+Respond with the title only, without quotes or punctuation at the end.
+
+{{.MessagesJoined}}
+`
+
+type TitlePromptTemplateData struct {
+	CurrentTitle   string
+	MessagesJoined string
+}
+
+const defaultFollowUpPromptTemplate = `
+Suggest three short questions the human might ask next in the conversation below. Each
+question should follow on from the assistant's last message and be written from the
+human's point of view.
+
+Respond with one question per line and nothing else.
+
+{{.MessagesJoined}}
+`
+
+type FollowUpPromptTemplateData struct {
+	MessagesJoined string
+}
+
+const topicLabelPromptTemplate = `
+The messages below are from conversations with the same person and share a common topic.
+Name the topic in one to four words, such as "Marathon training" or "Home renovation".
+
+Respond with the topic name only.
+
+{{.MessagesJoined}}
+`
+
+type TopicLabelPromptTemplateData struct {
+	MessagesJoined string
+}
+
+const reflectionPromptTemplate = `
+Below are facts known about a person, and important messages from their recent
+conversations. Update the facts using the messages. A fact is a lasting insight about the
+person, such as a preference, a goal, a relationship, or a deadline, written as a short
+sentence such as "Prefers concise answers" or "Has a project deadline in March".
+
+Keep facts that still hold, revise facts the messages change or contradict, and add new
+facts. A fact marked as disputed was contradicted by a later message, as explained; revise
+or drop it unless the contradiction doesn't hold. Combine facts that overlap, and don't record what was only relevant to a single
+conversation. Keep at most {{.MaxFacts}} facts, the most useful first.
+
+Respond with the updated facts as a JSON object only, where "fact" is the number of the
+known fact that a fact keeps or revises, or 0 for a new fact, and "lines" are the numbers of
+the messages it was drawn from, for example:
+{"facts": [{"text": "Prefers concise answers", "fact": 1, "lines": [3]}]}
+
+Known facts:
+{{.FactsJoined}}
+
+Messages:
+{{.MessagesJoined}}
+`
+
+type ReflectionPromptTemplateData struct {
+	MaxFacts       int
+	FactsJoined    string
+	MessagesJoined string
+}
+
+const contradictionPromptTemplate = `
+Below are facts known about a person, and new messages from a conversation with them. Find
+the messages that contradict a fact, such as by stating a changed preference or a moved
+deadline. A message that only adds detail to a fact doesn't contradict it.
+{{if .Resolve}}
+Resolve each contradiction with "revise" and the revised fact, if the message updates the
+fact, "retract" if the fact no longer holds and has nothing to replace it, or "keep" if the
+fact should stand, such as when the message is hypothetical or about someone else.
+{{end}}
+Respond with a JSON object only, where "fact" is the number of the fact and "line" is the
+number of the message, for example:
+{{if .Resolve -}}
+{"conflicts": [{"fact": 2, "line": 1, "explanation": "The deadline moved to April", "resolution": "revise", "revised": "Has a project deadline in April"}]}
+{{- else -}}
+{"conflicts": [{"fact": 2, "line": 1, "explanation": "The deadline moved to April"}]}
+{{- end}}
+
+If no message contradicts a fact, respond with {"conflicts": []}
+
+Facts:
+{{.FactsJoined}}
+
+Messages:
+{{.MessagesJoined}}
+`
+
+type ContradictionPromptTemplateData struct {
+	Resolve        bool
+	FactsJoined    string
+	MessagesJoined string
+}
+
+const taskStatePromptTemplate = `
+You are tracking the state of a task that an assistant is helping a human with. Update
+the current task state using the new lines of the conversation below.
+
+The task state has these fields:
+- stage: a few words describing the current stage of the conversation, such as
+  "gathering requirements", "executing", or "awaiting confirmation"
+- goal: the human's overall goal, in one sentence
+- steps_done: the steps that have been completed so far, each in a few words
+- blockers: anything preventing progress, such as missing information
+
+Keep completed steps from the current state, and remove blockers that have been resolved.
+If the conversation has no task, leave the fields empty.
+
+Respond with the updated task state as a JSON object only, for example:
+{"stage": "executing", "goal": "Book a flight to Lisbon", "steps_done": ["chose dates"], "blockers": []}
+
+Current task state:
+{{.CurrentState}}
+
+New lines:
+{{.MessagesJoined}}
+`
+
+type TaskStatePromptTemplateData struct {
+	CurrentState   string
+	MessagesJoined string
+}
+
+const episodeSegmentationPromptTemplate = `
+You are dividing a conversation into episodes, each about a single topic. Decide whether
+any of the new lines below starts a new topic, rather than continuing or following on
+from the current one.
+
+Respond with a JSON object only, with these fields:
+- topic: the current topic in a few words, updated to include the new lines that continue it
+- shifts: the new lines that start a new topic, each with the number of the line and the
+  new topic in a few words. Leave it empty if the topic doesn't change.
+
+For example:
+{"topic": "booking a flight to Lisbon", "shifts": [{"line": 3, "topic": "hotel recommendations"}]}
+
+Current topic:
+{{.CurrentTopic}}
+
+Recent lines about the current topic:
+{{.ContextJoined}}
+
+New lines:
+{{.MessagesJoined}}
+`
+
+type EpisodeSegmentationPromptTemplateData struct {
+	CurrentTopic   string
+	ContextJoined  string
+	MessagesJoined string
+}
+
+const episodeSummaryPromptTemplate = `
+Summarize the part of a conversation below, about {{.Topic}}, in no more than three
+sentences. Keep any names, figures, and decisions. Respond with the summary only.
+
+{{.MessagesJoined}}
+`
+
+type EpisodeSummaryPromptTemplateData struct {
+	Topic          string
+	MessagesJoined string
+}
+
+const importancePromptTemplate = `
+Rate how important each of the new lines of the conversation below is to remember in the
+long term, from 1 to 10. Small talk and acknowledgements, such as "thanks" or "ok", rate
+1. Lasting facts about the human, their preferences, instructions to remember or follow,
+decisions, and emotionally significant events rate highly. Lines repeating what's already
+been said rate lower.
+
+Respond with a JSON object only, for example:
+{"ratings": [{"line": 1, "rating": 2}, {"line": 2, "rating": 8}]}
+
+Recent lines:
+{{.ContextJoined}}
+
+New lines:
+{{.MessagesJoined}}
+`
+
+type ImportancePromptTemplateData struct {
+	ContextJoined  string
+	MessagesJoined string
+}
+
+const defaultDocumentSummaryPromptTemplate = `
+Summarize the document below in no more than three sentences. Keep any names, figures,
+and terms a reader might search for. Respond with the summary only.
+
+{{.Content}}
+`
+
+type DocumentSummaryPromptTemplateData struct {
+	Content string
+}
+
+const chunkHeaderPromptTemplate = `
+The chunk below is taken from
+{{- if .Title}} the document titled "{{.Title}}".{{else}} a longer document.{{end}}
+Where available, the chunks before and after it are included. Write one short sentence
+naming the document and describing where the chunk fits within it, so that the chunk can
+be found in a search. Respond with the sentence only.
+
+<previous_chunk>
+{{.Previous}}
+</previous_chunk>
+<chunk>
+{{.Content}}
+</chunk>
+<next_chunk>
+{{.Next}}
+</next_chunk>
+`
+
+type ChunkHeaderPromptTemplateData struct {
+	Title    string
+	Previous string
+	Content  string
+	Next     string
+}
+
 const defaultSummaryPromptTemplateAnthropic = `
 Review the Current Summary inside <current_summary></current_summary> XML tags, 
 and the New Lines of the provided conversation inside the <new_lines></new_lines> XML tags. Create a concise summary 
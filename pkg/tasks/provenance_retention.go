@@ -0,0 +1,28 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// DeleteExpiredProvenance deletes the context provenance records older than the
+// configured retention. If the retention is 0, records are kept.
+func DeleteExpiredProvenance(ctx context.Context, appState *models.AppState) error {
+	retention := appState.Config.Provenance.Retention
+	if retention <= 0 || appState.ProvenanceStore == nil {
+		return nil
+	}
+
+	before := time.Now().AddDate(0, 0, -retention)
+	n, err := appState.ProvenanceStore.DeleteBefore(ctx, before)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired provenance: %w", err)
+	}
+	if n > 0 {
+		log.Infof("deleted %d context provenance records older than %d days", n, retention)
+	}
+	return nil
+}
@@ -56,15 +56,7 @@ func (t *TaskPublisher) PublishMessage(
 	metadata map[string]string,
 	payload []models.MessageTask,
 ) error {
-	var messageTopics = []models.TaskTopic{
-		models.MessageSummarizerTopic,
-		models.MessageEmbedderTopic,
-		models.MessageNerTopic,
-		models.MessageIntentTopic,
-		models.MessageTokenCountTopic,
-	}
-
-	for _, topic := range messageTopics {
+	for _, topic := range models.MessageTopics {
 		err := t.Publish(topic, metadata, payload)
 		if err != nil {
 			return fmt.Errorf("failed to publish message: %w", err)
@@ -0,0 +1,28 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// DeleteExpiredSearches deletes the logged searches older than the configured retention.
+// If the retention is 0, searches are kept.
+func DeleteExpiredSearches(ctx context.Context, appState *models.AppState) error {
+	retention := appState.Config.QueryLog.Retention
+	if retention <= 0 || appState.QueryLogStore == nil {
+		return nil
+	}
+
+	before := time.Now().AddDate(0, 0, -retention)
+	n, err := appState.QueryLogStore.DeleteBefore(ctx, before)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired searches: %w", err)
+	}
+	if n > 0 {
+		log.Infof("deleted %d logged searches older than %d days", n, retention)
+	}
+	return nil
+}
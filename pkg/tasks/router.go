@@ -11,6 +11,7 @@ import (
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/getzep/zep/config"
 	"github.com/getzep/zep/pkg/models"
 	wla "github.com/ma-hartma/watermill-logrus-adapter"
 )
@@ -31,7 +32,11 @@ type TaskRouter struct {
 	appState    *models.AppState
 	db          *sql.DB
 	logger      watermill.LoggerAdapter
+	retry       message.HandlerMiddleware
 	Subscribers map[string]message.Subscriber
+
+	poolsMu sync.Mutex
+	pools   []*workerPool
 }
 
 // NewTaskRouter creates a new TaskRouter. Note that db should not be a bun.DB instance
@@ -56,6 +61,17 @@ func NewTaskRouter(appState *models.AppState, db *sql.DB) (*TaskRouter, error) {
 		return nil, err
 	}
 
+	// The handler function is retried if it returns an error.
+	// After MaxRetries, the message is Nacked and it's up to the PubSub to resend it.
+	retry := middleware.Retry{
+		MaxRetries:          MaxQueueRetries,
+		InitialInterval:     1 * time.Second,
+		MaxInterval:         5 * time.Second,
+		Multiplier:          1.5,
+		RandomizationFactor: 0.5,
+		Logger:              wlog,
+	}.Middleware
+
 	router.AddMiddleware(
 		// Watermill opentelemetry middleware
 		wotel.Trace(),
@@ -70,16 +86,7 @@ func NewTaskRouter(appState *models.AppState, db *sql.DB) (*TaskRouter, error) {
 		// PoisonQueue will publish messages that failed to process after MaxRetries to the poison queue.
 		poisonQueueHandler,
 
-		// The handler function is retried if it returns an error.
-		// After MaxRetries, the message is Nacked and it's up to the PubSub to resend it.
-		middleware.Retry{
-			MaxRetries:          MaxQueueRetries,
-			InitialInterval:     1 * time.Second,
-			MaxInterval:         5 * time.Second,
-			Multiplier:          1.5,
-			RandomizationFactor: 0.5,
-			Logger:              wlog,
-		}.Middleware,
+		retry,
 	)
 
 	return &TaskRouter{
@@ -87,26 +94,51 @@ func NewTaskRouter(appState *models.AppState, db *sql.DB) (*TaskRouter, error) {
 		appState: appState,
 		db:       db,
 		logger:   wlog,
+		retry:    retry,
 	}, nil
 }
 
-// AddTask adds a task handler to the router.
+// AddTask adds a task handler to the router, processing messages using a worker pool
+// sized by workers. Workers run until ctx is done.
 func (tr *TaskRouter) AddTask(
-	_ context.Context,
+	ctx context.Context,
 	name string,
 	taskType models.TaskTopic,
 	task models.Task,
+	workers config.WorkerPoolConfig,
 ) {
-	subscriber, err := NewSQLQueueSubscriber(tr.db, tr.logger)
+	pool := newWorkerPool(name, task, workers, tr.retry)
+
+	subscriber, err := NewSQLQueueSubscriber(tr.db, tr.logger, pool.batchSize)
 	if err != nil {
 		log.Fatalf("Failed to create subscriber for task %s: %v", taskType, err)
 	}
-	tr.AddNoPublisherHandler(
+	handler := tr.AddNoPublisherHandler(
 		name,
 		string(taskType),
 		subscriber,
-		TaskHandler(task),
+		pool.Handler(),
 	)
+	if pool.rateLimit > 0 {
+		handler.AddMiddleware(middleware.NewThrottle(int64(pool.rateLimit), time.Second).Middleware)
+	}
+	pool.Start(ctx)
+
+	tr.poolsMu.Lock()
+	tr.pools = append(tr.pools, pool)
+	tr.poolsMu.Unlock()
+}
+
+// WorkerStats returns a snapshot of each task's worker pool.
+func (tr *TaskRouter) WorkerStats() []models.TaskWorkerStats {
+	tr.poolsMu.Lock()
+	defer tr.poolsMu.Unlock()
+
+	stats := make([]models.TaskWorkerStats, len(tr.pools))
+	for i, pool := range tr.pools {
+		stats[i] = pool.Stats()
+	}
+	return stats
 }
 
 func (tr *TaskRouter) Close() (err error) {
@@ -128,11 +160,13 @@ func (tr *TaskRouter) Close() (err error) {
 }
 
 // TaskHandler returns a message handler function for the given task.
-// Handlers are NoPublishHandlerFuncs i.e. do not publish messages.
+// Handlers are NoPublishHandlerFuncs i.e. do not publish messages. Tasks run on behalf of
+// the tenant in the message's metadata, if any.
 func TaskHandler(task models.Task) message.NoPublishHandlerFunc {
 	return func(msg *message.Message) error {
 		log.Debugf("Handling task: %s", msg.UUID)
-		err := task.Execute(msg.Context(), msg)
+		ctx := models.WithTenant(msg.Context(), msg.Metadata.Get(models.TenantMetadataKey))
+		err := task.Execute(ctx, msg)
 		if err != nil {
 			task.HandleError(err)
 			return err
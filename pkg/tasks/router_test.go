@@ -5,6 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ThreeDotsLabs/watermill/message"
+
+	"github.com/getzep/zep/pkg/models"
 	"github.com/getzep/zep/pkg/store/postgres"
 
 	"github.com/stretchr/testify/assert"
@@ -42,3 +45,29 @@ RouterStarted:
 	err = appState.TaskRouter.Close()
 	assert.NoError(t, err, "failed to close task router")
 }
+
+// tenantTask records the tenant it's executed on behalf of.
+type tenantTask struct {
+	BaseTask
+	tenant string
+}
+
+func (t *tenantTask) Execute(ctx context.Context, _ *message.Message) error {
+	t.tenant = models.TenantFromContext(ctx)
+	return nil
+}
+
+func TestTaskHandlerTenant(t *testing.T) {
+	task := &tenantTask{}
+	msg := message.NewMessage("1", nil)
+	msg.Metadata = models.WithTenantMetadata(
+		models.WithTenant(context.Background(), "acme"),
+		map[string]string{"session_id": "s1"},
+	)
+	assert.NoError(t, TaskHandler(task)(msg))
+	assert.Equal(t, "acme", task.tenant)
+
+	msg = message.NewMessage("2", nil)
+	assert.NoError(t, TaskHandler(task)(msg))
+	assert.Empty(t, task.tenant)
+}
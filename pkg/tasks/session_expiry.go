@@ -0,0 +1,60 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/webhooks"
+)
+
+// ExpireSessions sends a session.expiring webhook for each session expiring within
+// warnBefore, and then deletes all sessions whose expiry has passed, sending a
+// session.expired webhook for each. Warnings that fail to send are retried on the
+// next run. If warnBefore is 0 or webhooks are disabled, no warnings are sent.
+func ExpireSessions(
+	ctx context.Context,
+	appState *models.AppState,
+	sender *webhooks.Sender,
+	warnBefore time.Duration,
+) error {
+	if warnBefore > 0 && sender.Enabled() {
+		sessions, err := appState.MemoryStore.ListExpiringSessions(ctx, time.Now().Add(warnBefore))
+		if err != nil {
+			return fmt.Errorf("failed to list expiring sessions: %w", err)
+		}
+
+		warned := make([]string, 0, len(sessions))
+		for _, session := range sessions {
+			err := sender.Send(ctx, webhooks.NewEvent(webhooks.EventSessionExpiring, session))
+			if err != nil {
+				log.Errorf("failed to send expiry warning for session %s: %v", session.SessionID, err)
+				continue
+			}
+			warned = append(warned, session.SessionID)
+		}
+
+		err = appState.MemoryStore.MarkSessionsExpiryWarned(ctx, warned)
+		if err != nil {
+			return fmt.Errorf("failed to mark sessions expiry warned: %w", err)
+		}
+	}
+
+	expired, err := appState.MemoryStore.DeleteExpiredSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	if len(expired) > 0 {
+		log.Infof("deleted %d expired sessions", len(expired))
+	}
+
+	for _, session := range expired {
+		err := sender.Send(ctx, webhooks.NewEvent(webhooks.EventSessionExpired, session))
+		if err != nil {
+			log.Errorf("failed to send expired webhook for session %s: %v", session.SessionID, err)
+		}
+	}
+
+	return nil
+}
@@ -38,11 +38,21 @@ func NewSQLQueuePublisher(db *sql.DB, logger watermill.LoggerAdapter) (message.P
 	return wotel.NewPublisherDecorator(p), nil
 }
 
-func NewSQLQueueSubscriber(db *sql.DB, logger watermill.LoggerAdapter) (message.Subscriber, error) {
+// NewSQLQueueSubscriber creates a subscriber that fetches up to batchSize messages per query.
+// If batchSize is 0, watermill's default is used.
+func NewSQLQueueSubscriber(
+	db *sql.DB,
+	logger watermill.LoggerAdapter,
+	batchSize int,
+) (message.Subscriber, error) {
 	return wsql.NewSubscriber(
 		db,
 		wsql.SubscriberConfig{
-			SchemaAdapter:    SQLSchema{},
+			SchemaAdapter: SQLSchema{
+				DefaultPostgreSQLSchema: wsql.DefaultPostgreSQLSchema{
+					SubscribeBatchSize: batchSize,
+				},
+			},
 			OffsetsAdapter:   &wsql.DefaultPostgreSQLOffsetsAdapter{},
 			InitializeSchema: true,
 			PollInterval:     SQLSubscriberPollInterval,
@@ -2,8 +2,10 @@ package tasks
 
 import (
 	"context"
+	"slices"
 
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/getzep/zep/config"
 	"github.com/getzep/zep/internal"
 	"github.com/getzep/zep/pkg/models"
 )
@@ -28,10 +30,21 @@ func (b *BaseTask) HandleError(err error) {
 func Initialize(ctx context.Context, appState *models.AppState, router models.TaskRouter) {
 	log.Info("Initializing tasks")
 
-	addTask := func(ctx context.Context, name string, taskType models.TaskTopic, enabled bool, newTask func() models.Task) {
+	addTask := func(
+		ctx context.Context,
+		name string,
+		taskType models.TaskTopic,
+		enabled bool,
+		workers config.WorkerPoolConfig,
+		newTask func() models.Task,
+	) {
 		if enabled {
 			task := newTask()
-			router.AddTask(ctx, name, taskType, task)
+			if slices.Contains(appState.Config.Rules.RoutedExtractors, name) {
+				task = newRoutedTask(appState, taskType, task)
+				log.Infof("%s task only processes messages routed to it by rules", name)
+			}
+			router.AddTask(ctx, name, taskType, task, workers)
 			log.Infof("%s task added to task router", name)
 		}
 	}
@@ -41,6 +54,7 @@ func Initialize(ctx context.Context, appState *models.AppState, router models.Ta
 		string(models.MessageSummarizerTopic),
 		models.MessageSummarizerTopic,
 		appState.Config.Extractors.Messages.Summarizer.Enabled,
+		appState.Config.Extractors.Messages.Summarizer.Workers,
 		func() models.Task { return NewMessageSummaryTask(appState) },
 	)
 
@@ -49,6 +63,7 @@ func Initialize(ctx context.Context, appState *models.AppState, router models.Ta
 		string(models.MessageEmbedderTopic),
 		models.MessageEmbedderTopic,
 		appState.Config.Extractors.Messages.Embeddings.Enabled,
+		appState.Config.Extractors.Messages.Embeddings.Workers,
 		func() models.Task { return NewMessageEmbedderTask(appState) },
 	)
 
@@ -57,6 +72,7 @@ func Initialize(ctx context.Context, appState *models.AppState, router models.Ta
 		string(models.MessageNerTopic),
 		models.MessageNerTopic,
 		appState.Config.Extractors.Messages.Entities.Enabled,
+		appState.Config.Extractors.Messages.Entities.Workers,
 		func() models.Task { return NewMessageNERTask(appState) },
 	)
 
@@ -65,14 +81,92 @@ func Initialize(ctx context.Context, appState *models.AppState, router models.Ta
 		string(models.MessageIntentTopic),
 		models.MessageIntentTopic,
 		appState.Config.Extractors.Messages.Intent.Enabled,
+		appState.Config.Extractors.Messages.Intent.Workers,
 		func() models.Task { return NewMessageIntentTask(appState) },
 	)
 
+	addTask(
+		ctx,
+		string(models.MessageTitleTopic),
+		models.MessageTitleTopic,
+		appState.Config.Extractors.Messages.Title.Enabled,
+		appState.Config.Extractors.Messages.Title.Workers,
+		func() models.Task { return NewMessageTitleTask(appState) },
+	)
+
+	addTask(
+		ctx,
+		string(models.MessageFollowUpTopic),
+		models.MessageFollowUpTopic,
+		appState.Config.Extractors.Messages.FollowUp.Enabled,
+		appState.Config.Extractors.Messages.FollowUp.Workers,
+		func() models.Task { return NewMessageFollowUpTask(appState) },
+	)
+
+	addTask(
+		ctx,
+		string(models.MessageTaskStateTopic),
+		models.MessageTaskStateTopic,
+		appState.Config.Extractors.Messages.TaskState.Enabled,
+		appState.Config.Extractors.Messages.TaskState.Workers,
+		func() models.Task { return NewMessageTaskStateTask(appState) },
+	)
+
+	addTask(
+		ctx,
+		string(models.MessageEpisodeTopic),
+		models.MessageEpisodeTopic,
+		appState.Config.Extractors.Messages.Episodes.Enabled,
+		appState.Config.Extractors.Messages.Episodes.Workers,
+		func() models.Task { return NewMessageEpisodeTask(appState) },
+	)
+
+	importance := appState.Config.Extractors.Messages.Importance
+	switch importance.Scorer {
+	case "", ImportanceScorerHeuristic, ImportanceScorerLLM:
+	default:
+		log.Warnf(
+			"unknown extractors.messages.importance.scorer %q. using the heuristic scorer",
+			importance.Scorer,
+		)
+	}
+	addTask(
+		ctx,
+		string(models.MessageImportanceTopic),
+		models.MessageImportanceTopic,
+		importance.Enabled,
+		importance.Workers,
+		func() models.Task { return NewMessageImportanceTask(appState) },
+	)
+
+	addTask(
+		ctx,
+		string(models.MessageContradictionTopic),
+		models.MessageContradictionTopic,
+		appState.Config.Extractors.Messages.Contradictions.Enabled,
+		appState.Config.Extractors.Messages.Contradictions.Workers,
+		func() models.Task { return NewMessageContradictionTask(appState) },
+	)
+
+	enrichment := appState.Config.Extractors.Messages.Enrichment
+	if enrichment.Enabled && enrichment.URL == "" {
+		log.Warn("extractors.messages.enrichment.url is not set. enrichment is disabled")
+	}
+	addTask(
+		ctx,
+		string(models.MessageEnrichmentTopic),
+		models.MessageEnrichmentTopic,
+		enrichment.Enabled && enrichment.URL != "",
+		enrichment.Workers,
+		func() models.Task { return NewMessageEnrichmentTask(appState) },
+	)
+
 	addTask(
 		ctx,
 		string(models.MessageTokenCountTopic),
 		models.MessageTokenCountTopic,
 		true, // Always enabled
+		config.WorkerPoolConfig{},
 		func() models.Task { return NewMessageTokenCountTask(appState) },
 	)
 
@@ -81,14 +175,25 @@ func Initialize(ctx context.Context, appState *models.AppState, router models.Ta
 		string(models.DocumentEmbedderTopic),
 		models.DocumentEmbedderTopic,
 		appState.Config.Extractors.Documents.Embeddings.Enabled,
+		appState.Config.Extractors.Documents.Embeddings.Workers,
 		func() models.Task { return NewDocumentEmbedderTask(appState) },
 	)
 
+	addTask(
+		ctx,
+		string(models.DocumentSummarizerTopic),
+		models.DocumentSummarizerTopic,
+		appState.Config.Extractors.Documents.Summarizer.Enabled,
+		appState.Config.Extractors.Documents.Summarizer.Workers,
+		func() models.Task { return NewDocumentSummarizerTask(appState) },
+	)
+
 	addTask(
 		ctx,
 		string(models.MessageSummaryEmbedderTopic),
 		models.MessageSummaryEmbedderTopic,
 		appState.Config.Extractors.Messages.Summarizer.Embeddings.Enabled,
+		appState.Config.Extractors.Messages.Summarizer.Embeddings.Workers,
 		func() models.Task { return NewMessageSummaryEmbedderTask(appState) },
 	)
 
@@ -97,7 +202,16 @@ func Initialize(ctx context.Context, appState *models.AppState, router models.Ta
 		string(models.MessageSummaryNERTopic),
 		models.MessageSummaryNERTopic,
 		appState.Config.Extractors.Messages.Summarizer.Entities.Enabled,
+		appState.Config.Extractors.Messages.Summarizer.Entities.Workers,
 		func() models.Task { return NewMessageSummaryNERTask(appState) },
 	)
 
+	addTask(
+		ctx,
+		string(models.MessageCompactorTopic),
+		models.MessageCompactorTopic,
+		appState.Config.Memory.MaxSessionMessages > 0,
+		config.WorkerPoolConfig{},
+		func() models.Task { return NewMessageCompactorTask(appState) },
+	)
 }
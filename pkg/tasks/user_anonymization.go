@@ -0,0 +1,332 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// UserAnonymizationTaskType is the type of the async tasks anonymizing users.
+const UserAnonymizationTaskType = "user_anonymization"
+
+const (
+	anonymizationMessagePageSize = 1000
+	// anonymousUserIDPrefix prefixes the random IDs anonymized users are re-keyed to.
+	anonymousUserIDPrefix = "anon-"
+	// minIdentifierLength is the length below which a user's name or ID isn't scrubbed,
+	// as it would match too much unrelated text.
+	minIdentifierLength = 3
+)
+
+// piiEntityLabels are the labels of the named entities scrubbed from messages.
+var piiEntityLabels = map[string]string{
+	"PERSON": "[NAME]",
+}
+
+// piiPatterns detect PII by its form. Card numbers are matched before phone numbers,
+// which would otherwise match part of them.
+var piiPatterns = []struct {
+	placeholder string
+	pattern     *regexp.Regexp
+}{
+	{"[EMAIL]", regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)},
+	{"[SSN]", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{"[CARD]", regexp.MustCompile(`\b(?:\d[ -]?){12,15}\d\b`)},
+	{
+		"[PHONE]",
+		regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)\s?|\b\d{3}[\s.-])\d{3}[\s.-]\d{4}\b`),
+	},
+	{"[IP]", regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)},
+}
+
+// AnonymizeUser anonymizes a user, short of deleting the user: PII detected in the
+// content of the user's messages is replaced with placeholders, and the user is re-keyed to
+// a random ID, with the user's email and name cleared and PII scrubbed from the user's
+// metadata. Message embeddings, summaries and other derived data are kept, so that the
+// user's sessions still count towards aggregate analytics.
+//
+// PII is detected by form, such as email addresses and phone numbers, by the user's known
+// name, email and ID, and by the PERSON entities extracted from each message. Messages
+// are scrubbed before the user is re-keyed, so that a failed anonymization can be retried
+// with the user's original ID. progress, if not nil, is called with the sessions
+// scrubbed, of their total, as they are.
+func AnonymizeUser(
+	ctx context.Context,
+	appState *models.AppState,
+	userID string,
+	progress func(completed, total int),
+) (*models.AnonymizationReport, error) {
+	if progress == nil {
+		progress = func(int, int) {}
+	}
+
+	// the user store refuses to re-key a held user, but only after messages are scrubbed
+	if err := CheckUserAnonymizable(ctx, appState, userID); err != nil {
+		return nil, err
+	}
+	user, err := appState.UserStore.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := appState.UserStore.GetSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions of user %s: %w", userID, err)
+	}
+
+	scrubber := newPIIScrubber(user)
+	report := &models.AnonymizationReport{Sessions: len(sessions)}
+	for i, session := range sessions {
+		if err := scrubSessionMessages(ctx, appState, session.SessionID, scrubber, report); err != nil {
+			return nil, err
+		}
+		progress(i+1, len(sessions))
+	}
+
+	anonymousID := anonymousUserIDPrefix + uuid.NewString()
+	metadata, _ := scrubber.scrubValue(user.Metadata).(map[string]interface{})
+	anonymized, err := appState.UserStore.Anonymize(ctx, userID, anonymousID, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	report.UserID = anonymized.UserID
+	report.AnonymizedAt = time.Now().UTC()
+	log.Infof(
+		"anonymized user as %s, scrubbing %d of %d messages",
+		report.UserID,
+		report.MessagesScrubbed,
+		report.Messages,
+	)
+	return report, nil
+}
+
+// CheckUserAnonymizable returns a NotFoundError if the user doesn't exist, or a
+// ConflictError if the user or any of the user's sessions is under legal hold, as held
+// data mustn't be modified.
+func CheckUserAnonymizable(ctx context.Context, appState *models.AppState, userID string) error {
+	if _, err := appState.UserStore.Get(ctx, userID); err != nil {
+		return err
+	}
+	if appState.LegalHoldStore == nil {
+		return nil
+	}
+	sessions, err := appState.UserStore.GetSessions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get sessions of user %s: %w", userID, err)
+	}
+
+	if err := checkLegalHold(ctx, appState, models.LegalHoldSubjectUser, userID); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		err := checkLegalHold(ctx, appState, models.LegalHoldSubjectSession, session.SessionID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scrubSessionMessages(
+	ctx context.Context,
+	appState *models.AppState,
+	sessionID string,
+	scrubber *piiScrubber,
+	report *models.AnonymizationReport,
+) error {
+	for page := 1; ; page++ {
+		list, err := appState.MemoryStore.GetMessageList(
+			ctx,
+			sessionID,
+			page,
+			anonymizationMessagePageSize,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get messages of session %s: %w", sessionID, err)
+		}
+
+		var scrubbed []models.Message
+		for _, message := range list.Messages {
+			report.Messages++
+			if scrubMessage(scrubber, &message) {
+				scrubbed = append(scrubbed, message)
+			}
+		}
+		if len(scrubbed) > 0 {
+			err = appState.MemoryStore.UpdateMessages(ctx, sessionID, scrubbed, true, true)
+			if err != nil {
+				return fmt.Errorf("failed to update messages of session %s: %w", sessionID, err)
+			}
+			report.MessagesScrubbed += len(scrubbed)
+		}
+
+		if len(list.Messages) < anonymizationMessagePageSize {
+			return nil
+		}
+	}
+}
+
+// scrubMessage replaces the PII in a message's content, and in the PII entities extracted
+// from it, returning true if the message changed. The message's metadata is left holding
+// only its entities, if changed, as the update merges it into the stored metadata.
+func scrubMessage(scrubber *piiScrubber, message *models.Message) bool {
+	entities := messageEntities(message.Metadata)
+	var names []string
+	for _, entity := range entities {
+		if _, ok := piiEntityLabels[entityString(entity, "label")]; ok {
+			names = append(names, entityString(entity, "name"))
+		}
+	}
+
+	content, contentChanged := scrubber.with(names, "[NAME]").scrub(message.Content)
+	message.Content = content
+	message.Metadata = nil
+
+	var entitiesChanged bool
+	for _, entity := range entities {
+		placeholder, ok := piiEntityLabels[entityString(entity, "label")]
+		if !ok || entityString(entity, "name") == placeholder {
+			continue
+		}
+		// the offsets of the matches are of the original content
+		entity["name"] = placeholder
+		entity["matches"] = []interface{}{}
+		entitiesChanged = true
+	}
+	if entitiesChanged {
+		entityList := make([]interface{}, len(entities))
+		for i, entity := range entities {
+			entityList[i] = entity
+		}
+		message.Metadata = map[string]interface{}{
+			"system": map[string]interface{}{"entities": entityList},
+		}
+	}
+
+	return contentChanged || entitiesChanged
+}
+
+// messageEntities returns the named entities extracted from a message, stored in its
+// system metadata by the NER task.
+func messageEntities(metadata map[string]interface{}) []map[string]interface{} {
+	system, _ := metadata["system"].(map[string]interface{})
+	list, _ := system["entities"].([]interface{})
+	entities := make([]map[string]interface{}, 0, len(list))
+	for _, e := range list {
+		if entity, ok := e.(map[string]interface{}); ok {
+			entities = append(entities, entity)
+		}
+	}
+	return entities
+}
+
+func entityString(entity map[string]interface{}, key string) string {
+	s, _ := entity[key].(string)
+	return s
+}
+
+type piiReplacement struct {
+	placeholder string
+	pattern     *regexp.Regexp
+}
+
+// piiScrubber replaces the PII in text with placeholders naming the kind of PII removed.
+type piiScrubber struct {
+	// identifiers match the user's known email, name and ID, and are replaced before the
+	// PII patterns.
+	identifiers []piiReplacement
+}
+
+func newPIIScrubber(user *models.User) *piiScrubber {
+	s := &piiScrubber{}
+	s.identifiers = s.literals([]string{user.Email}, "[EMAIL]")
+	// the full name is replaced before its parts
+	fullName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	s.identifiers = append(
+		s.identifiers,
+		s.literals([]string{fullName, user.FirstName, user.LastName}, "[NAME]")...,
+	)
+	s.identifiers = append(s.identifiers, s.literals([]string{user.UserID}, "[USER_ID]")...)
+	return s
+}
+
+// with returns a scrubber also replacing the given literals with placeholder.
+func (s *piiScrubber) with(literals []string, placeholder string) *piiScrubber {
+	if len(literals) == 0 {
+		return s
+	}
+	return &piiScrubber{
+		identifiers: append(s.literals(literals, placeholder), s.identifiers...),
+	}
+}
+
+// literals returns case-insensitive replacements of the given literals as whole words.
+func (s *piiScrubber) literals(literals []string, placeholder string) []piiReplacement {
+	var replacements []piiReplacement
+	for _, literal := range literals {
+		literal = strings.TrimSpace(literal)
+		if len(literal) < minIdentifierLength {
+			continue
+		}
+		expr := regexp.QuoteMeta(literal)
+		if isWordChar(literal[0]) {
+			expr = `\b` + expr
+		}
+		if isWordChar(literal[len(literal)-1]) {
+			expr += `\b`
+		}
+		replacements = append(replacements, piiReplacement{
+			placeholder: placeholder,
+			pattern:     regexp.MustCompile("(?i)" + expr),
+		})
+	}
+	return replacements
+}
+
+// scrub returns text with its PII replaced, and true if any was found.
+func (s *piiScrubber) scrub(text string) (string, bool) {
+	scrubbed := text
+	for _, r := range s.identifiers {
+		scrubbed = r.pattern.ReplaceAllLiteralString(scrubbed, r.placeholder)
+	}
+	for _, p := range piiPatterns {
+		scrubbed = p.pattern.ReplaceAllLiteralString(scrubbed, p.placeholder)
+	}
+	return scrubbed, scrubbed != text
+}
+
+// scrubValue returns a copy of a metadata value with the PII in its strings replaced.
+func (s *piiScrubber) scrubValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		scrubbed, _ := s.scrub(v)
+		return scrubbed
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = s.scrubValue(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = s.scrubValue(e)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,136 @@
+package tasks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func TestPIIScrubber(t *testing.T) {
+	scrubber := newPIIScrubber(&models.User{
+		UserID:    "jdoe42",
+		Email:     "jane.doe@example.com",
+		FirstName: "Jane",
+		LastName:  "Doe",
+	})
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"I'm Jane Doe, call me Jane", "I'm [NAME], call me [NAME]"},
+		{"mail JANE.DOE@example.com or j@work.org", "mail [EMAIL] or [EMAIL]"},
+		{"my account is jdoe42", "my account is [USER_ID]"},
+		{"call (555) 123-4567 or +1 555.123.4567", "call [PHONE] or [PHONE]"},
+		{"card 4111 1111 1111 1111, ssn 123-45-6789", "card [CARD], ssn [SSN]"},
+		{"from 192.168.0.1", "from [IP]"},
+		{"Janet ordered 3 items in 2023", "Janet ordered 3 items in 2023"},
+	}
+	for _, tt := range tests {
+		got, changed := scrubber.scrub(tt.text)
+		assert.Equal(t, tt.want, got)
+		assert.Equal(t, tt.want != tt.text, changed)
+	}
+}
+
+func TestAnonymizeUser(t *testing.T) {
+	ctx := context.Background()
+	db := inmemory.NewDB()
+	anonState := &models.AppState{
+		Config:         &config.Config{},
+		UserStore:      inmemory.NewUserStore(db),
+		LegalHoldStore: inmemory.NewLegalHoldStore(db),
+	}
+	anonState.MemoryStore = inmemory.NewMemoryStore(anonState, db)
+
+	userID := "jane-" + uuid.NewString()
+	_, err := anonState.UserStore.Create(ctx, &models.CreateUserRequest{
+		UserID:    userID,
+		Email:     "jane@example.com",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Metadata:  map[string]interface{}{"plan": "pro", "note": "reach Jane at 555-123-4567"},
+	})
+	require.NoError(t, err)
+	sessionID := "anonymized-" + uuid.NewString()
+	_, err = anonState.MemoryStore.CreateSession(ctx, &models.CreateSessionRequest{
+		SessionID: sessionID,
+		UserID:    &userID,
+	})
+	require.NoError(t, err)
+	messages := []models.Message{
+		{UUID: uuid.New(), Role: "user", Content: "Hi, I'm Jane. My friend Bob says hello"},
+		{UUID: uuid.New(), Role: "assistant", Content: "How can I help?"},
+	}
+	err = anonState.MemoryStore.PutMemory(ctx, sessionID, &models.Memory{Messages: messages}, true)
+	require.NoError(t, err)
+	err = anonState.MemoryStore.UpdateMessages(ctx, sessionID, []models.Message{{
+		UUID: messages[0].UUID,
+		Metadata: map[string]interface{}{"system": map[string]interface{}{"entities": []interface{}{
+			map[string]interface{}{"name": "Bob", "label": "PERSON", "matches": []interface{}{}},
+			map[string]interface{}{"name": "hello", "label": "MISC", "matches": []interface{}{}},
+		}}},
+	}}, true, false)
+	require.NoError(t, err)
+
+	// held users aren't anonymized
+	_, err = anonState.LegalHoldStore.Place(ctx, &models.LegalHold{
+		SubjectType: models.LegalHoldSubjectSession,
+		SubjectID:   sessionID,
+	})
+	require.NoError(t, err)
+	_, err = AnonymizeUser(ctx, anonState, userID, nil)
+	assert.ErrorIs(t, err, models.ErrConflict)
+	require.NoError(t, anonState.LegalHoldStore.Lift(
+		ctx,
+		models.LegalHoldSubjectSession,
+		sessionID,
+		"admin",
+		"",
+	))
+
+	report, err := AnonymizeUser(ctx, anonState, userID, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(report.UserID, anonymousUserIDPrefix))
+	assert.Equal(t, 1, report.Sessions)
+	assert.Equal(t, 2, report.Messages)
+	assert.Equal(t, 1, report.MessagesScrubbed)
+
+	_, err = anonState.UserStore.Get(ctx, userID)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+	user, err := anonState.UserStore.Get(ctx, report.UserID)
+	require.NoError(t, err)
+	assert.Empty(t, user.Email)
+	assert.Empty(t, user.FirstName)
+	assert.Equal(t, "pro", user.Metadata["plan"])
+	assert.Equal(t, "reach [NAME] at [PHONE]", user.Metadata["note"])
+
+	session, err := anonState.MemoryStore.GetSession(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, report.UserID, *session.UserID)
+
+	scrubbed, err := anonState.MemoryStore.GetMessagesByUUID(
+		ctx,
+		sessionID,
+		[]uuid.UUID{messages[0].UUID},
+	)
+	require.NoError(t, err)
+	require.Len(t, scrubbed, 1)
+	assert.Equal(t, "Hi, I'm [NAME]. My friend [NAME] says hello", scrubbed[0].Content)
+	assert.Equal(t, models.ContentHash(scrubbed[0].Content), scrubbed[0].ContentHash)
+	entities := messageEntities(scrubbed[0].Metadata)
+	require.Len(t, entities, 2)
+	assert.Equal(t, "[NAME]", entities[0]["name"])
+	assert.Equal(t, "hello", entities[1]["name"])
+
+	_, err = AnonymizeUser(ctx, anonState, userID, nil)
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
@@ -0,0 +1,302 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/roles"
+)
+
+const (
+	defaultReflectionMinImportance = 0.6
+	defaultReflectionMaxMessages   = 200
+	defaultMaxUserFacts            = 20
+	reflectionMaxTokens            = 1024
+)
+
+// reflection is the LLM's updated facts about a user. Fact is the number of the known fact
+// a fact keeps or revises, and Lines are the numbers of the messages it was drawn from,
+// both numbered from 1.
+type reflection struct {
+	Facts []struct {
+		Text  string `json:"text"`
+		Fact  int    `json:"fact"`
+		Lines []int  `json:"lines"`
+	} `json:"facts"`
+}
+
+// ReflectUsers reviews the important messages each user sent since they were last
+// reflected on, and has the LLM synthesize them, with the facts already known about the
+// user, into updated facts. Conflicts left unresolved by the contradiction extractor are
+// resolved by the revision. A failure for one user is logged, and the remaining users are
+// still processed.
+func ReflectUsers(
+	ctx context.Context,
+	appState *models.AppState,
+	cfg config.ReflectionConfig,
+) error {
+	registry := roles.FromConfig(appState.Config)
+	var cursor int64
+	for {
+		users, err := appState.UserStore.ListAll(ctx, cursor, topicsPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list users: %w", err)
+		}
+		for _, user := range users {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			reflected, err := reflectUser(ctx, appState, registry, user, cfg)
+			if err != nil {
+				log.Errorf("failed to reflect on user %s: %v", user.UserID, err)
+				continue
+			}
+			if reflected {
+				log.Debugf("reflected on user %s", user.UserID)
+			}
+		}
+		if len(users) < topicsPageSize {
+			return nil
+		}
+		cursor = users[len(users)-1].ID
+	}
+}
+
+// GetUserFacts returns the facts synthesized about a user, or empty UserFacts if the user
+// hasn't been reflected on.
+func GetUserFacts(user *models.User) (*models.UserFacts, error) {
+	facts := &models.UserFacts{Facts: []models.UserFact{}}
+	system, ok := user.Metadata["system"].(map[string]interface{})
+	if !ok || system[models.UserFactsMetadataKey] == nil {
+		return facts, nil
+	}
+
+	b, err := json.Marshal(system[models.UserFactsMetadataKey])
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal user facts: %w", err)
+	}
+	if err := json.Unmarshal(b, facts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user facts: %w", err)
+	}
+	return facts, nil
+}
+
+// reflectUser updates the facts about the user from the messages created since the user
+// was last reflected on that score at least MinImportance, and stores the facts in the
+// user's system metadata. The user is skipped if they have no such messages and no
+// disputed facts. Returns true if the user was reflected on.
+func reflectUser(
+	ctx context.Context,
+	appState *models.AppState,
+	registry *roles.Registry,
+	user *models.User,
+	cfg config.ReflectionConfig,
+) (bool, error) {
+	previous, err := GetUserFacts(user)
+	if err != nil {
+		return false, err
+	}
+
+	minImportance := cfg.MinImportance
+	if minImportance <= 0 {
+		minImportance = defaultReflectionMinImportance
+	}
+	maxMessages := cfg.MaxMessages
+	if maxMessages <= 0 {
+		maxMessages = defaultReflectionMaxMessages
+	}
+	maxFacts := cfg.MaxFacts
+	if maxFacts <= 0 {
+		maxFacts = defaultMaxUserFacts
+	}
+
+	// messages created during the run are left to the next reflection
+	reflectedAt := time.Now().UTC()
+	sessions, err := appState.UserStore.GetSessions(ctx, user.UserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	var messages []models.Message
+	for _, session := range sessions {
+		memory, err := appState.MemoryStore.GetMemory(ctx, session.SessionID, maxMessages)
+		if err != nil {
+			return false, fmt.Errorf("failed to get memory: %w", err)
+		}
+		for _, m := range memory.Messages {
+			importance, ok := models.MessageImportance(&m)
+			if !ok || importance < minImportance || m.Content == "" ||
+				!m.CreatedAt.After(previous.ReflectedAt) || m.CreatedAt.After(reflectedAt) {
+				continue
+			}
+			messages = append(messages, m)
+		}
+	}
+	// disputed facts are reviewed even without new messages, so that they don't stay
+	// disputed
+	if len(messages) == 0 && !slices.ContainsFunc(previous.Facts, func(f models.UserFact) bool {
+		return f.Disputed()
+	}) {
+		return false, nil
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+
+	factLines := []string{"None"}
+	if len(previous.Facts) > 0 {
+		factLines = make([]string, len(previous.Facts))
+		for i, fact := range previous.Facts {
+			factLines[i] = fmt.Sprintf("%d. %s", i+1, fact.Fact)
+			var disputes []string
+			for _, c := range fact.Conflicts {
+				if c.Resolution == "" {
+					disputes = append(disputes, c.Explanation)
+				}
+			}
+			if len(disputes) > 0 {
+				factLines[i] += fmt.Sprintf(" (disputed: %s)", strings.Join(disputes, "; "))
+			}
+		}
+	}
+	lines := []string{"None"}
+	if len(messages) > 0 {
+		lines = make([]string, len(messages))
+		for i, m := range messages {
+			lines[i] = fmt.Sprintf("%d. %s: %s", i+1, registry.Label(m.Role), m.Content)
+		}
+	}
+	prompt, err := internal.ParsePrompt(reflectionPromptTemplate, ReflectionPromptTemplateData{
+		MaxFacts:       maxFacts,
+		FactsJoined:    strings.Join(factLines, "\n"),
+		MessagesJoined: strings.Join(lines, "\n"),
+	})
+	if err != nil {
+		return false, err
+	}
+	response, err := appState.LLMClient.Call(ctx, prompt, llms.WithMaxTokens(reflectionMaxTokens))
+	if err != nil {
+		return false, fmt.Errorf("failed to reflect: %w", err)
+	}
+	parsed, err := parseReflection(response)
+	if err != nil {
+		return false, err
+	}
+
+	facts := &models.UserFacts{
+		Facts:       []models.UserFact{},
+		Retracted:   previous.Retracted,
+		ReflectedAt: reflectedAt,
+	}
+	kept := make(map[int]bool, len(previous.Facts))
+	for _, f := range parsed.Facts {
+		text := strings.TrimSpace(f.Text)
+		if text == "" {
+			continue
+		}
+		fact := models.UserFact{Fact: text, MessageUUIDs: []uuid.UUID{}}
+		if f.Fact >= 1 && f.Fact <= len(previous.Facts) {
+			known := previous.Facts[f.Fact-1]
+			kept[f.Fact-1] = true
+			fact.MessageUUIDs = append(fact.MessageUUIDs, known.MessageUUIDs...)
+			resolution := models.FactKept
+			if text != known.Fact {
+				resolution = models.FactRevised
+			}
+			fact.Conflicts = resolveConflicts(known, resolution)
+		}
+		for _, line := range f.Lines {
+			if line >= 1 && line <= len(messages) &&
+				!slices.Contains(fact.MessageUUIDs, messages[line-1].UUID) {
+				fact.MessageUUIDs = append(fact.MessageUUIDs, messages[line-1].UUID)
+			}
+		}
+		facts.Facts = append(facts.Facts, fact)
+		if len(facts.Facts) == maxFacts {
+			break
+		}
+	}
+	// disputed facts the reflection dropped are retracted, rather than forgotten
+	for i, known := range previous.Facts {
+		if !kept[i] && known.Disputed() {
+			known.Conflicts = resolveConflicts(known, models.FactRetracted)
+			facts.Retracted = append(facts.Retracted, known)
+		}
+	}
+
+	if err := storeUserFacts(ctx, appState, user.UserID, facts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// resolveConflicts returns the conflicts of fact, with those that are unresolved resolved
+// by resolution.
+func resolveConflicts(fact models.UserFact, resolution models.FactResolution) []models.FactConflict {
+	conflicts := slices.Clone(fact.Conflicts)
+	for i := range conflicts {
+		if conflicts[i].Resolution != "" {
+			continue
+		}
+		conflicts[i].Resolution = resolution
+		if resolution == models.FactRevised {
+			conflicts[i].PreviousFact = fact.Fact
+		}
+	}
+	return conflicts
+}
+
+// storeUserFacts stores facts in the user's system metadata.
+func storeUserFacts(
+	ctx context.Context,
+	appState *models.AppState,
+	userID string,
+	facts *models.UserFacts,
+) error {
+	// round trip through JSON so that the metadata holds only JSON types
+	b, err := json.Marshal(facts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user facts: %w", err)
+	}
+	var factsMetadata map[string]interface{}
+	if err := json.Unmarshal(b, &factsMetadata); err != nil {
+		return fmt.Errorf("failed to unmarshal user facts: %w", err)
+	}
+
+	_, err = appState.UserStore.Update(ctx, &models.UpdateUserRequest{
+		UserID: userID,
+		Metadata: map[string]interface{}{
+			"system": map[string]interface{}{models.UserFactsMetadataKey: factsMetadata},
+		},
+	}, true)
+	if err != nil {
+		return fmt.Errorf("failed to store user facts: %w", err)
+	}
+	return nil
+}
+
+// parseReflection reads the JSON object in an LLM response, ignoring any text or code
+// fence around it.
+func parseReflection(response string) (*reflection, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no facts found in LLM response: %q", response)
+	}
+
+	var parsed reflection
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse facts: %w", err)
+	}
+	return &parsed, nil
+}
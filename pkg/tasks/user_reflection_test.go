@@ -0,0 +1,139 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+func importantMessage(content string, importance float64) models.Message {
+	return models.Message{
+		UUID:    uuid.New(),
+		Role:    "user",
+		Content: content,
+		Metadata: map[string]interface{}{"system": map[string]interface{}{
+			models.ImportanceMetadataKey: importance,
+		}},
+	}
+}
+
+func TestReflectUsers(t *testing.T) {
+	db := inmemory.NewDB()
+	llm := &scriptedLLM{responses: []string{
+		`{"facts": [{"text": "Prefers concise answers", "fact": 0, "lines": [1]}, ` +
+			`{"text": "", "fact": 0, "lines": [2]}]}`,
+		"```json\n" + `{"facts": [` +
+			`{"text": "Prefers concise answers", "fact": 1, "lines": []}, ` +
+			`{"text": "Has a project deadline in March", "fact": 0, "lines": [1, 7]}]}` + "\n```",
+	}}
+	reflectionState := &models.AppState{Config: &config.Config{}, LLMClient: llm}
+	reflectionState.MemoryStore = inmemory.NewMemoryStore(reflectionState, db)
+	reflectionState.UserStore = inmemory.NewUserStore(db)
+
+	userID := "reflection-user"
+	_, err := reflectionState.UserStore.Create(testCtx, &models.CreateUserRequest{UserID: userID})
+	require.NoError(t, err)
+	_, err = reflectionState.MemoryStore.CreateSession(testCtx, &models.CreateSessionRequest{
+		SessionID: "reflection-session",
+		UserID:    &userID,
+	})
+	require.NoError(t, err)
+
+	concise := importantMessage("Please keep your answers short.", 0.9)
+	err = reflectionState.MemoryStore.PutMemory(testCtx, "reflection-session", &models.Memory{
+		Messages: []models.Message{
+			concise,
+			importantMessage("ok", 0.1),
+			{UUID: uuid.New(), Role: "user", Content: "Unscored"},
+		},
+	}, true)
+	require.NoError(t, err)
+
+	cfg := config.ReflectionConfig{MinImportance: 0.5}
+	require.NoError(t, ReflectUsers(testCtx, reflectionState, cfg))
+	require.Len(t, llm.prompts, 1)
+	assert.Contains(t, llm.prompts[0], "1. user: Please keep your answers short.")
+	assert.NotContains(t, llm.prompts[0], "ok\n")
+	assert.NotContains(t, llm.prompts[0], "Unscored")
+
+	user, err := reflectionState.UserStore.Get(testCtx, userID)
+	require.NoError(t, err)
+	facts, err := GetUserFacts(user)
+	require.NoError(t, err)
+	assert.False(t, facts.ReflectedAt.IsZero())
+	assert.Equal(t, []models.UserFact{
+		{Fact: "Prefers concise answers", MessageUUIDs: []uuid.UUID{concise.UUID}},
+	}, facts.Facts)
+
+	// without new important messages, the user is skipped
+	require.NoError(t, ReflectUsers(testCtx, reflectionState, cfg))
+	assert.Len(t, llm.prompts, 1)
+
+	// the known facts are revised with the messages since the last reflection only
+	deadline := importantMessage("The project is due in March.", 0.8)
+	err = reflectionState.MemoryStore.PutMemory(testCtx, "reflection-session", &models.Memory{
+		Messages: []models.Message{deadline},
+	}, true)
+	require.NoError(t, err)
+	require.NoError(t, ReflectUsers(testCtx, reflectionState, cfg))
+	require.Len(t, llm.prompts, 2)
+	assert.Contains(t, llm.prompts[1], "1. Prefers concise answers")
+	assert.Contains(t, llm.prompts[1], "1. user: The project is due in March.")
+	assert.NotContains(t, llm.prompts[1], "keep your answers short")
+
+	user, err = reflectionState.UserStore.Get(testCtx, userID)
+	require.NoError(t, err)
+	facts, err = GetUserFacts(user)
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserFact{
+		{Fact: "Prefers concise answers", MessageUUIDs: []uuid.UUID{concise.UUID}},
+		{Fact: "Has a project deadline in March", MessageUUIDs: []uuid.UUID{deadline.UUID}},
+	}, facts.Facts)
+}
+
+func TestReflectUsersResolvesDisputes(t *testing.T) {
+	llm := &scriptedLLM{responses: []string{
+		`{"facts": [{"text": "Prefers detailed answers", "fact": 1, "lines": []}]}`,
+	}}
+	// the disputes are flagged without resolve
+	appState := newContradictionState(t, llm, false)
+	conflict := models.FactConflict{MessageUUID: uuid.New(), Explanation: "Asked for detail"}
+	require.NoError(t, storeUserFacts(testCtx, appState, "contradiction-user", &models.UserFacts{
+		Facts: []models.UserFact{
+			{Fact: "Prefers concise answers", Conflicts: []models.FactConflict{conflict}},
+			{Fact: "Lives in Lisbon", Conflicts: []models.FactConflict{
+				{MessageUUID: uuid.New(), Explanation: "Moved to Porto"},
+			}},
+			{Fact: "Has a dog"},
+		},
+	}))
+
+	// disputed facts are reviewed without new messages
+	require.NoError(t, ReflectUsers(testCtx, appState, config.ReflectionConfig{}))
+	require.Len(t, llm.prompts, 1)
+	assert.Contains(t, llm.prompts[0], "1. Prefers concise answers (disputed: Asked for detail)")
+
+	facts := contradictionFacts(t, appState)
+	require.Len(t, facts.Facts, 1)
+	require.Len(t, facts.Facts[0].Conflicts, 1)
+	assert.Equal(t, models.FactRevised, facts.Facts[0].Conflicts[0].Resolution)
+	assert.Equal(t, "Prefers concise answers", facts.Facts[0].Conflicts[0].PreviousFact)
+
+	// the dropped disputed fact is retracted, and the undisputed one forgotten
+	require.Len(t, facts.Retracted, 1)
+	assert.Equal(t, "Lives in Lisbon", facts.Retracted[0].Fact)
+	assert.Equal(t, models.FactRetracted, facts.Retracted[0].Conflicts[0].Resolution)
+}
+
+func TestGetUserFactsUnreflected(t *testing.T) {
+	facts, err := GetUserFacts(&models.User{UserID: "user"})
+	require.NoError(t, err)
+	assert.Empty(t, facts.Facts)
+	assert.NotNil(t, facts.Facts)
+}
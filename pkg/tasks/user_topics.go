@@ -0,0 +1,294 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/viterin/vek/vek32"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/search"
+)
+
+const (
+	defaultMaxTopics        = 8
+	defaultMinTopicSize     = 3
+	defaultMaxTopicMessages = 2000
+	topicsPageSize          = 100
+	topicsKMeansIterations  = 20
+	topicLabelMessages      = 5
+	topicLabelMaxTokens     = 16
+)
+
+// ModelUserTopics models the topics of each user with messages added since their topics
+// were last modeled. A failure for one user is logged, and the remaining users are still
+// processed.
+func ModelUserTopics(
+	ctx context.Context,
+	appState *models.AppState,
+	cfg config.TopicsConfig,
+) error {
+	var cursor int64
+	for {
+		users, err := appState.UserStore.ListAll(ctx, cursor, topicsPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list users: %w", err)
+		}
+		for _, user := range users {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			modeled, err := modelUserTopics(ctx, appState, user, cfg)
+			if err != nil {
+				log.Errorf("failed to model topics for user %s: %v", user.UserID, err)
+				continue
+			}
+			if modeled {
+				log.Debugf("modeled topics for user %s", user.UserID)
+			}
+		}
+		if len(users) < topicsPageSize {
+			return nil
+		}
+		cursor = users[len(users)-1].ID
+	}
+}
+
+// GetUserTopics returns the topics last modeled for a user, or empty UserTopics if they
+// haven't been modeled.
+func GetUserTopics(user *models.User) (*models.UserTopics, error) {
+	topics := &models.UserTopics{Topics: []models.UserTopic{}}
+	system, ok := user.Metadata["system"].(map[string]interface{})
+	if !ok || system[models.UserTopicsMetadataKey] == nil {
+		return topics, nil
+	}
+
+	b, err := json.Marshal(system[models.UserTopicsMetadataKey])
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal user topics: %w", err)
+	}
+	if err := json.Unmarshal(b, topics); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user topics: %w", err)
+	}
+	return topics, nil
+}
+
+// modelUserTopics clusters the user's message embeddings, labels each cluster, and
+// stores the topics in the user's system metadata. The user is skipped if their message
+// count hasn't changed since their topics were last modeled. Returns true if the topics
+// were modeled.
+func modelUserTopics(
+	ctx context.Context,
+	appState *models.AppState,
+	user *models.User,
+	cfg config.TopicsConfig,
+) (bool, error) {
+	previous, err := GetUserTopics(user)
+	if err != nil {
+		return false, err
+	}
+
+	sessions, err := appState.UserStore.GetSessions(ctx, user.UserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	// cluster the most recent sessions first, should the user have more than MaxMessages
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+
+	messageCount := 0
+	for _, session := range sessions {
+		list, err := appState.MemoryStore.GetMessageList(ctx, session.SessionID, 1, 1)
+		if err != nil {
+			return false, fmt.Errorf("failed to get message count: %w", err)
+		}
+		messageCount += list.TotalCount
+	}
+	if messageCount == previous.MessageCount && !previous.ModeledAt.IsZero() {
+		return false, nil
+	}
+
+	maxMessages := cfg.MaxMessages
+	if maxMessages <= 0 {
+		maxMessages = defaultMaxTopicMessages
+	}
+	var embeddings []models.TextData
+	for _, session := range sessions {
+		if len(embeddings) >= maxMessages {
+			break
+		}
+		sessionEmbeddings, err := appState.MemoryStore.GetMessageEmbeddings(ctx, session.SessionID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get message embeddings: %w", err)
+		}
+		embeddings = append(embeddings, sessionEmbeddings...)
+	}
+	if len(embeddings) > maxMessages {
+		embeddings = embeddings[:maxMessages]
+	}
+
+	clusters, err := clusterTopics(embeddings, cfg)
+	if err != nil {
+		return false, err
+	}
+
+	topics := &models.UserTopics{
+		Topics:       make([]models.UserTopic, 0, len(clusters)),
+		MessageCount: messageCount,
+		ModeledAt:    time.Now().UTC(),
+	}
+	for _, cluster := range clusters {
+		label, err := labelTopic(ctx, appState, cluster.representatives)
+		if err != nil {
+			return false, err
+		}
+		if label == "" {
+			continue
+		}
+		topics.Topics = append(topics.Topics, models.UserTopic{
+			Label:        label,
+			MessageCount: len(cluster.messageUUIDs),
+			MessageUUIDs: cluster.messageUUIDs,
+		})
+	}
+
+	// round trip through JSON so that the metadata holds only JSON types
+	b, err := json.Marshal(topics)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal user topics: %w", err)
+	}
+	var topicsMetadata map[string]interface{}
+	if err := json.Unmarshal(b, &topicsMetadata); err != nil {
+		return false, fmt.Errorf("failed to unmarshal user topics: %w", err)
+	}
+
+	_, err = appState.UserStore.Update(ctx, &models.UpdateUserRequest{
+		UserID: user.UserID,
+		Metadata: map[string]interface{}{
+			"system": map[string]interface{}{models.UserTopicsMetadataKey: topicsMetadata},
+		},
+	}, true)
+	if err != nil {
+		return false, fmt.Errorf("failed to store user topics: %w", err)
+	}
+
+	return true, nil
+}
+
+// topicCluster is a cluster of messages, with the texts of the messages nearest its
+// centroid.
+type topicCluster struct {
+	messageUUIDs    []uuid.UUID
+	representatives []string
+}
+
+// clusterTopics clusters the embeddings with k-means, where k allows for clusters of
+// about MinTopicSize, up to MaxTopics. Clusters smaller than MinTopicSize are dropped,
+// and the rest are returned largest first.
+func clusterTopics(embeddings []models.TextData, cfg config.TopicsConfig) ([]topicCluster, error) {
+	maxTopics := cfg.MaxTopics
+	if maxTopics <= 0 {
+		maxTopics = defaultMaxTopics
+	}
+	minTopicSize := cfg.MinTopicSize
+	if minTopicSize <= 0 {
+		minTopicSize = defaultMinTopicSize
+	}
+
+	var vectors [][]float32
+	var data []models.TextData
+	for _, e := range embeddings {
+		if len(e.Embedding) > 0 {
+			vectors = append(vectors, e.Embedding)
+			data = append(data, e)
+		}
+	}
+	k := min(maxTopics, len(vectors)/minTopicSize)
+	if k == 0 {
+		return []topicCluster{}, nil
+	}
+
+	assignments, centroids, err := search.KMeans(vectors, k, topicsKMeansIterations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cluster message embeddings: %w", err)
+	}
+
+	members := make([][]int, len(centroids))
+	for i, cluster := range assignments {
+		members[cluster] = append(members[cluster], i)
+	}
+
+	var clusters []topicCluster
+	for c, indices := range members {
+		if len(indices) < minTopicSize {
+			continue
+		}
+		cluster := topicCluster{messageUUIDs: make([]uuid.UUID, len(indices))}
+		for i, index := range indices {
+			cluster.messageUUIDs[i] = data[index].TextUUID
+		}
+
+		// pruned messages keep their embeddings but have no text to label
+		nearest := make([]int, 0, len(indices))
+		for _, index := range indices {
+			if strings.TrimSpace(data[index].Text) != "" {
+				nearest = append(nearest, index)
+			}
+		}
+		if len(nearest) == 0 {
+			continue
+		}
+		similarity := make(map[int]float32, len(nearest))
+		for _, index := range nearest {
+			similarity[index] = vek32.CosineSimilarity(vectors[index], centroids[c])
+		}
+		sort.SliceStable(nearest, func(i, j int) bool {
+			return similarity[nearest[i]] > similarity[nearest[j]]
+		})
+		for _, index := range nearest[:min(len(nearest), topicLabelMessages)] {
+			cluster.representatives = append(cluster.representatives, data[index].Text)
+		}
+
+		clusters = append(clusters, cluster)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i].messageUUIDs) > len(clusters[j].messageUUIDs)
+	})
+	return clusters, nil
+}
+
+// labelTopic asks the LLM to name the topic of the given messages.
+func labelTopic(ctx context.Context, appState *models.AppState, messages []string) (string, error) {
+	prompt, err := internal.ParsePrompt(topicLabelPromptTemplate, TopicLabelPromptTemplateData{
+		MessagesJoined: strings.Join(messages, "\n"),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	label, err := appState.LLMClient.Call(ctx, prompt, llms.WithMaxTokens(topicLabelMaxTokens))
+	if err != nil {
+		return "", fmt.Errorf("failed to label topic: %w", err)
+	}
+	return cleanTopicLabel(label), nil
+}
+
+// cleanTopicLabel removes a "Topic:" prefix, and otherwise cleans the label as a title.
+func cleanTopicLabel(label string) string {
+	label = strings.TrimSpace(label)
+	if prefix, rest, found := strings.Cut(label, ":"); found &&
+		strings.EqualFold(strings.TrimSpace(prefix), "topic") {
+		label = rest
+	}
+	return cleanTitle(label)
+}
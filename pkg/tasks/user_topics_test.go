@@ -0,0 +1,104 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/store/inmemory"
+)
+
+// topicLabeler labels topics "Topic: label <n>", numbered in the order they are labeled.
+type topicLabeler struct {
+	models.ZepLLM
+	calls int
+}
+
+func (l *topicLabeler) Call(
+	_ context.Context,
+	_ string,
+	_ ...llms.CallOption,
+) (string, error) {
+	l.calls++
+	return fmt.Sprintf("Topic: label %d", l.calls), nil
+}
+
+func TestModelUserTopics(t *testing.T) {
+	db := inmemory.NewDB()
+	labeler := &topicLabeler{}
+	topicsState := &models.AppState{Config: &config.Config{}, LLMClient: labeler}
+	topicsState.MemoryStore = inmemory.NewMemoryStore(topicsState, db)
+	topicsState.UserStore = inmemory.NewUserStore(db)
+
+	userID := "topics-user"
+	_, err := topicsState.UserStore.Create(testCtx, &models.CreateUserRequest{UserID: userID})
+	require.NoError(t, err)
+	_, err = topicsState.MemoryStore.CreateSession(testCtx, &models.CreateSessionRequest{
+		SessionID: "topics-session",
+		UserID:    &userID,
+	})
+	require.NoError(t, err)
+
+	// two topics of four messages each, and one outlier
+	vectors := [][]float32{
+		{1, 0, 0}, {0.9, 0.1, 0}, {1, 0.1, 0}, {0.9, 0, 0.1},
+		{0, 1, 0}, {0.1, 0.9, 0}, {0, 1, 0.1}, {0.1, 1, 0},
+		{0, 0, 1},
+	}
+	messages := make([]models.Message, len(vectors))
+	embeddings := make([]models.TextData, len(vectors))
+	for i := range vectors {
+		messages[i] = models.Message{UUID: uuid.New(), Role: "user", Content: "content"}
+		embeddings[i] = models.TextData{TextUUID: messages[i].UUID, Embedding: vectors[i]}
+	}
+	err = topicsState.MemoryStore.PutMemory(
+		testCtx,
+		"topics-session",
+		&models.Memory{Messages: messages},
+		true,
+	)
+	require.NoError(t, err)
+	err = topicsState.MemoryStore.CreateMessageEmbeddings(testCtx, "topics-session", embeddings)
+	require.NoError(t, err)
+
+	cfg := config.TopicsConfig{MaxTopics: 3, MinTopicSize: 2}
+	require.NoError(t, ModelUserTopics(testCtx, topicsState, cfg))
+
+	user, err := topicsState.UserStore.Get(testCtx, userID)
+	require.NoError(t, err)
+	topics, err := GetUserTopics(user)
+	require.NoError(t, err)
+	assert.Equal(t, len(messages), topics.MessageCount)
+	require.Len(t, topics.Topics, 2)
+	assert.ElementsMatch(t,
+		[]string{"label 1", "label 2"},
+		[]string{topics.Topics[0].Label, topics.Topics[1].Label},
+	)
+	for _, topic := range topics.Topics {
+		assert.Equal(t, 4, topic.MessageCount)
+		assert.Len(t, topic.MessageUUIDs, 4)
+	}
+	assert.ElementsMatch(
+		t,
+		[]uuid.UUID{messages[0].UUID, messages[1].UUID, messages[2].UUID, messages[3].UUID},
+		topics.Topics[0].MessageUUIDs,
+	)
+
+	// without new messages, the user is skipped
+	require.NoError(t, ModelUserTopics(testCtx, topicsState, cfg))
+	assert.Equal(t, 2, labeler.calls)
+}
+
+func TestGetUserTopicsUnmodeled(t *testing.T) {
+	topics, err := GetUserTopics(&models.User{UserID: "user"})
+	require.NoError(t, err)
+	assert.Empty(t, topics.Topics)
+	assert.NotNil(t, topics.Topics)
+}
@@ -0,0 +1,150 @@
+package tasks
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const DefaultWorkerConcurrency = 1
+const DefaultSubscribeBatchSize = 100 // watermill-sql's default for Postgres
+
+// workerPool executes a task's messages. With a concurrency of 1, messages are executed
+// in the handler and only acknowledged once complete. With a higher concurrency, the
+// handler acknowledges a message once it has been queued for one of the pool's workers,
+// trading redelivery of queued messages on shutdown for throughput. Errors are
+// retried by the workers, but failed messages are not sent to the poison queue.
+type workerPool struct {
+	name        string
+	task        models.Task
+	concurrency int
+	batchSize   int
+	queueLength int
+	rateLimit   int
+	queue       chan *message.Message
+	retry       message.HandlerMiddleware
+
+	inFlight  atomic.Int64
+	processed atomic.Int64
+	failed    atomic.Int64
+}
+
+func newWorkerPool(
+	name string,
+	task models.Task,
+	cfg config.WorkerPoolConfig,
+	retry message.HandlerMiddleware,
+) *workerPool {
+	p := &workerPool{
+		name:        name,
+		task:        task,
+		concurrency: cfg.Concurrency,
+		batchSize:   cfg.BatchSize,
+		queueLength: cfg.QueueLength,
+		rateLimit:   cfg.RateLimit,
+		retry:       retry,
+	}
+	if p.concurrency <= 0 {
+		p.concurrency = DefaultWorkerConcurrency
+	}
+	if p.batchSize <= 0 {
+		p.batchSize = DefaultSubscribeBatchSize
+	}
+	if p.concurrency == 1 {
+		p.queueLength = 0
+	} else if p.queueLength <= 0 {
+		p.queueLength = p.concurrency
+	}
+	if p.rateLimit < 0 {
+		p.rateLimit = 0
+	}
+	if p.concurrency > 1 {
+		p.queue = make(chan *message.Message, p.queueLength)
+	}
+
+	return p
+}
+
+// Start starts the pool's workers, which run until ctx is done. It is a no-op if
+// the pool executes messages synchronously.
+func (p *workerPool) Start(ctx context.Context) {
+	for i := 0; i < p.concurrency && p.queue != nil; i++ {
+		go p.work(ctx)
+	}
+}
+
+// Handler returns the message handler for the pool's task.
+func (p *workerPool) Handler() message.NoPublishHandlerFunc {
+	if p.queue == nil {
+		return p.execute
+	}
+
+	return func(msg *message.Message) error {
+		// The copy is acked as soon as this handler returns, so it must outlive
+		// the subscriber's context.
+		queued := msg.Copy()
+		queued.SetContext(context.WithoutCancel(msg.Context()))
+
+		select {
+		case p.queue <- queued:
+			return nil
+		case <-msg.Context().Done():
+			return msg.Context().Err()
+		}
+	}
+}
+
+// Stats returns a snapshot of the pool's size and counters.
+func (p *workerPool) Stats() models.TaskWorkerStats {
+	return models.TaskWorkerStats{
+		Task:        p.name,
+		Concurrency: p.concurrency,
+		BatchSize:   p.batchSize,
+		QueueLength: p.queueLength,
+		RateLimit:   p.rateLimit,
+		Queued:      len(p.queue),
+		InFlight:    p.inFlight.Load(),
+		Processed:   p.processed.Load(),
+		Failed:      p.failed.Load(),
+	}
+}
+
+func (p *workerPool) work(ctx context.Context) {
+	var handler message.HandlerFunc = func(msg *message.Message) ([]*message.Message, error) {
+		return nil, p.execute(msg)
+	}
+	if p.retry != nil {
+		handler = p.retry(handler)
+	}
+	handler = middleware.Recoverer(handler)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-p.queue:
+			if _, err := handler(msg); err != nil {
+				log.Errorf("task %s failed for message %s: %s", p.name, msg.UUID, err)
+			}
+		}
+	}
+}
+
+func (p *workerPool) execute(msg *message.Message) error {
+	p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+
+	err := TaskHandler(p.task)(msg)
+	if err != nil {
+		p.failed.Add(1)
+		return err
+	}
+	p.processed.Add(1)
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/config"
+)
+
+type blockingTask struct {
+	BaseTask
+	started chan struct{}
+	release chan struct{}
+	calls   atomic.Int64
+	fail    bool
+}
+
+func (b *blockingTask) Execute(_ context.Context, _ *message.Message) error {
+	b.calls.Add(1)
+	if b.started != nil {
+		b.started <- struct{}{}
+	}
+	if b.release != nil {
+		<-b.release
+	}
+	if b.fail {
+		return errors.New("task failed")
+	}
+	return nil
+}
+
+func newTestMessage(ctx context.Context) *message.Message {
+	msg := message.NewMessage(watermill.NewUUID(), nil)
+	msg.SetContext(ctx)
+	return msg
+}
+
+func TestWorkerPoolDefaults(t *testing.T) {
+	pool := newWorkerPool("test", &blockingTask{}, config.WorkerPoolConfig{}, nil)
+	stats := pool.Stats()
+	assert.Equal(t, DefaultWorkerConcurrency, stats.Concurrency)
+	assert.Equal(t, DefaultSubscribeBatchSize, stats.BatchSize)
+	assert.Equal(t, 0, stats.QueueLength)
+
+	pool = newWorkerPool("test", &blockingTask{}, config.WorkerPoolConfig{Concurrency: 4}, nil)
+	assert.Equal(t, 4, pool.Stats().QueueLength)
+}
+
+func TestWorkerPoolSynchronous(t *testing.T) {
+	task := &blockingTask{}
+	pool := newWorkerPool("test", task, config.WorkerPoolConfig{}, nil)
+
+	err := pool.Handler()(newTestMessage(testCtx))
+	assert.NoError(t, err)
+
+	task.fail = true
+	err = pool.Handler()(newTestMessage(testCtx))
+	assert.Error(t, err)
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(1), stats.Processed)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Equal(t, int64(0), stats.InFlight)
+}
+
+func TestWorkerPoolConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(testCtx)
+	defer cancel()
+
+	task := &blockingTask{
+		started: make(chan struct{}, 5),
+		release: make(chan struct{}),
+	}
+	pool := newWorkerPool(
+		"test",
+		task,
+		config.WorkerPoolConfig{Concurrency: 3, QueueLength: 2},
+		nil,
+	)
+	pool.Start(ctx)
+
+	handler := pool.Handler()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, handler(newTestMessage(ctx)))
+	}
+
+	// All workers are busy and the queue is full
+	for i := 0; i < 3; i++ {
+		<-task.started
+	}
+	stats := pool.Stats()
+	assert.Equal(t, int64(3), stats.InFlight)
+	assert.Equal(t, 2, stats.Queued)
+
+	// A full queue blocks the handler until the message's context is done
+	msgCtx, msgCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer msgCancel()
+	assert.ErrorIs(t, handler(newTestMessage(msgCtx)), context.DeadlineExceeded)
+
+	close(task.release)
+
+	assert.Eventually(t, func() bool {
+		return pool.Stats().Processed == 5
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(0), pool.Stats().InFlight)
+}
@@ -0,0 +1,118 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/llms"
+)
+
+const (
+	// SignatureHeader carries the hex-encoded HMAC-SHA256 of the request body,
+	// keyed with webhooks.secret. It is only set if a secret is configured.
+	SignatureHeader = "X-Zep-Signature"
+	EventTypeHeader = "X-Zep-Event"
+
+	WebhookRetryMax = 3
+	WebhookTimeout  = 10 * time.Second
+)
+
+type EventType string
+
+const (
+	EventSessionExpiring EventType = "session.expiring"
+	EventSessionExpired  EventType = "session.expired"
+	// EventEmbeddingDrift is sent when stored embeddings no longer match fresh
+	// embeddings of the same text.
+	EventEmbeddingDrift EventType = "embeddings.drift_detected"
+)
+
+// Event is the payload POSTed to the webhook URL.
+type Event struct {
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// NewEvent returns an Event of the given type, timestamped now.
+func NewEvent(eventType EventType, data any) *Event {
+	return &Event{
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// Sender delivers webhook events to the configured URL.
+type Sender struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewSender returns a Sender for the given config. If no URL is configured, the
+// returned Sender is disabled and Send is a no-op.
+func NewSender(cfg *config.WebhooksConfig) *Sender {
+	return &Sender{
+		url:    cfg.URL,
+		secret: []byte(cfg.Secret),
+		client: llms.NewRetryableHTTPClient(WebhookRetryMax, WebhookTimeout),
+	}
+}
+
+// Enabled returns true if a webhook URL is configured.
+func (s *Sender) Enabled() bool {
+	return s.url != ""
+}
+
+// Send POSTs the event to the webhook URL. Non-2xx responses are returned as errors.
+func (s *Sender) Send(ctx context.Context, event *Event) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, string(event.Type))
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook %s: %w", event.Type, err)
+	}
+	defer resp.Body.Close()
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook %s returned status %d", event.Type, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body using secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+)
+
+func TestSender_Send(t *testing.T) {
+	secret := "test-secret"
+
+	var gotBody []byte
+	var gotHeader http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	sender := NewSender(&config.WebhooksConfig{URL: ts.URL, Secret: secret})
+	assert.True(t, sender.Enabled())
+
+	event := NewEvent(EventSessionExpiring, map[string]string{"session_id": "abc"})
+	err := sender.Send(context.Background(), event)
+	require.NoError(t, err)
+
+	assert.Equal(t, string(EventSessionExpiring), gotHeader.Get(EventTypeHeader))
+	assert.Equal(t, Sign([]byte(secret), gotBody), gotHeader.Get(SignatureHeader))
+
+	var got Event
+	require.NoError(t, json.Unmarshal(gotBody, &got))
+	assert.Equal(t, EventSessionExpiring, got.Type)
+	assert.Equal(t, map[string]interface{}{"session_id": "abc"}, got.Data)
+}
+
+func TestSender_SendErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	sender := NewSender(&config.WebhooksConfig{URL: ts.URL})
+	err := sender.Send(context.Background(), NewEvent(EventSessionExpired, nil))
+	assert.ErrorContains(t, err, "returned status 400")
+}
+
+func TestSender_Disabled(t *testing.T) {
+	sender := NewSender(&config.WebhooksConfig{})
+	assert.False(t, sender.Enabled())
+	assert.NoError(t, sender.Send(context.Background(), NewEvent(EventSessionExpired, nil)))
+}
@@ -0,0 +1,274 @@
+// Package writebuffer buffers memory writes in process, coalescing bursts of small message
+// writes to a session, as made by webhook-driven ingestion, into batched inserts.
+package writebuffer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	// DurabilitySync acknowledges writes once inserted.
+	DurabilitySync = "sync"
+	// DurabilityAsync acknowledges writes once buffered.
+	DurabilityAsync = "async"
+
+	DefaultFlushInterval = 5 * time.Millisecond
+	DefaultMaxMessages   = 100
+
+	// flushTimeout bounds the insert of a batch, which isn't canceled with the requests
+	// of its writes.
+	flushTimeout = 30 * time.Second
+)
+
+var log = internal.GetLogger()
+
+var _ models.MemoryStore[any] = &MemoryStore{}
+
+// MemoryStore buffers the messages written by PutMemory, inserting the messages written
+// to a session within the flush interval with a single PutMemory of the wrapped store.
+// The messages of a session are inserted in the order they were written. A failed insert
+// fails every write of its batch. Other calls aren't buffered.
+type MemoryStore struct {
+	models.MemoryStore[any]
+	flushInterval time.Duration
+	maxMessages   int
+	async         bool
+
+	mu      sync.Mutex
+	pending map[string]*batch
+	// inserting holds, for each session with a batch being inserted, a channel closed
+	// once its last batch is inserted. A batch waits for the session's previous batch.
+	inserting map[string]chan struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+type batch struct {
+	sessionID  string
+	skipNotify bool
+	// tenant is the tenant the batch is inserted on behalf of, so that its extractors use
+	// the tenant's providers.
+	tenant   string
+	writes   []*write
+	messages int
+	timer    *time.Timer
+}
+
+type write struct {
+	messages []models.Message
+	// done receives the outcome of the write's insert. It's nil for async writes.
+	done chan error
+}
+
+// NewMemoryStore wraps memoryStore with a MemoryStore buffering writes for
+// flushInterval, or until a session has maxMessages buffered messages. If async is true,
+// writes return once buffered. Zero values use the defaults.
+func NewMemoryStore(
+	memoryStore models.MemoryStore[any],
+	flushInterval time.Duration,
+	maxMessages int,
+	async bool,
+) *MemoryStore {
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+	if maxMessages <= 0 {
+		maxMessages = DefaultMaxMessages
+	}
+	return &MemoryStore{
+		MemoryStore:   memoryStore,
+		flushInterval: flushInterval,
+		maxMessages:   maxMessages,
+		async:         async,
+		pending:       make(map[string]*batch),
+		inserting:     make(map[string]chan struct{}),
+	}
+}
+
+// Wrap replaces the memory store of appState with a MemoryStore buffering writes, if the
+// write buffer is enabled.
+func Wrap(appState *models.AppState) error {
+	cfg := appState.Config.Memory.WriteBuffer
+	if !cfg.Enabled || appState.MemoryStore == nil {
+		return nil
+	}
+	var async bool
+	switch cfg.Durability {
+	case "", DurabilitySync:
+	case DurabilityAsync:
+		async = true
+	default:
+		return fmt.Errorf(
+			"invalid memory.write_buffer.durability %q: must be %q or %q",
+			cfg.Durability,
+			DurabilitySync,
+			DurabilityAsync,
+		)
+	}
+
+	s := NewMemoryStore(
+		appState.MemoryStore,
+		time.Duration(cfg.FlushInterval)*time.Millisecond,
+		cfg.MaxMessages,
+		async,
+	)
+	log.Infof(
+		"memory write buffer enabled: flush_interval=%s max_messages=%d async=%t",
+		s.flushInterval,
+		s.maxMessages,
+		s.async,
+	)
+	if async {
+		log.Warn("buffered memory writes are acknowledged before they are inserted, " +
+			"and will be lost if the server crashes")
+	}
+	appState.MemoryStore = s
+	return nil
+}
+
+// PutMemory buffers the messages of memoryMessages. The summary and metadata of
+// memoryMessages are ignored, as by the stores. Writes without messages, which only
+// create the session, aren't buffered.
+func (s *MemoryStore) PutMemory(
+	ctx context.Context,
+	sessionID string,
+	memoryMessages *models.Memory,
+	skipNotify bool,
+) error {
+	if len(memoryMessages.Messages) == 0 {
+		return s.MemoryStore.PutMemory(ctx, sessionID, memoryMessages, skipNotify)
+	}
+
+	w := &write{messages: memoryMessages.Messages}
+	if !s.async {
+		w.done = make(chan error, 1)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return s.MemoryStore.PutMemory(ctx, sessionID, memoryMessages, skipNotify)
+	}
+	b := s.pending[sessionID]
+	tenant := models.TenantFromContext(ctx)
+	// writes notifying the extractors and writes that don't can't share an insert, nor
+	// can writes of different tenants
+	if b != nil && (b.skipNotify != skipNotify || b.tenant != tenant) {
+		s.flushLocked(b)
+		b = nil
+	}
+	if b == nil {
+		b = &batch{sessionID: sessionID, skipNotify: skipNotify, tenant: tenant}
+		s.pending[sessionID] = b
+		b.timer = time.AfterFunc(s.flushInterval, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.flushLocked(b)
+		})
+	}
+	b.writes = append(b.writes, w)
+	b.messages += len(w.messages)
+	if b.messages >= s.maxMessages {
+		s.flushLocked(b)
+	}
+	s.mu.Unlock()
+
+	if s.async {
+		return nil
+	}
+	select {
+	case err := <-w.done:
+		return err
+	case <-ctx.Done():
+		// the messages may still be inserted
+		return ctx.Err()
+	}
+}
+
+// Flush inserts the buffered messages and waits for all inserts to finish.
+func (s *MemoryStore) Flush() {
+	s.mu.Lock()
+	for _, b := range s.pending {
+		s.flushLocked(b)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Close inserts the buffered messages before closing the wrapped store. Later writes
+// aren't buffered.
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.Flush()
+	return s.MemoryStore.Close()
+}
+
+// flushLocked starts the insert of b, unless it has already started. s.mu must be held.
+func (s *MemoryStore) flushLocked(b *batch) {
+	if s.pending[b.sessionID] != b {
+		return
+	}
+	delete(s.pending, b.sessionID)
+	b.timer.Stop()
+
+	previous := s.inserting[b.sessionID]
+	inserted := make(chan struct{})
+	s.inserting[b.sessionID] = inserted
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if previous != nil {
+			<-previous
+		}
+		s.insert(b)
+		close(inserted)
+
+		s.mu.Lock()
+		if s.inserting[b.sessionID] == inserted {
+			delete(s.inserting, b.sessionID)
+		}
+		s.mu.Unlock()
+	}()
+}
+
+func (s *MemoryStore) insert(b *batch) {
+	messages := make([]models.Message, 0, b.messages)
+	for _, w := range b.writes {
+		messages = append(messages, w.messages...)
+	}
+
+	ctx := models.WithTenant(context.Background(), b.tenant)
+	ctx, cancel := context.WithTimeout(ctx, flushTimeout)
+	defer cancel()
+	err := s.MemoryStore.PutMemory(
+		ctx,
+		b.sessionID,
+		&models.Memory{Messages: messages},
+		b.skipNotify,
+	)
+	if err != nil {
+		err = fmt.Errorf("failed to insert buffered messages: %w", err)
+		if s.async {
+			log.Errorf(
+				"%d messages of session %s were lost: %v",
+				len(messages),
+				b.sessionID,
+				err,
+			)
+		}
+	}
+	for _, w := range b.writes {
+		if w.done != nil {
+			w.done <- err
+		}
+	}
+}
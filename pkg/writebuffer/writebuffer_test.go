@@ -0,0 +1,246 @@
+package writebuffer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/searchcache"
+)
+
+type put struct {
+	sessionID  string
+	contents   []string
+	skipNotify bool
+	tenant     string
+}
+
+// fakeMemoryStore records PutMemory calls. Only the methods called by the tests are
+// implemented.
+type fakeMemoryStore struct {
+	models.MemoryStore[any]
+	mu     sync.Mutex
+	puts   []put
+	err    error
+	closed bool
+}
+
+func (s *fakeMemoryStore) PutMemory(
+	ctx context.Context,
+	sessionID string,
+	memory *models.Memory,
+	skipNotify bool,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := put{
+		sessionID:  sessionID,
+		skipNotify: skipNotify,
+		tenant:     models.TenantFromContext(ctx),
+	}
+	for _, m := range memory.Messages {
+		p.contents = append(p.contents, m.Content)
+	}
+	s.puts = append(s.puts, p)
+	return s.err
+}
+
+// SearchMemory returns a result for each message put to the session.
+func (s *fakeMemoryStore) SearchMemory(
+	_ context.Context,
+	sessionID string,
+	_ *models.MemorySearchPayload,
+	_ int,
+) ([]models.MemorySearchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var results []models.MemorySearchResult
+	for _, p := range s.puts {
+		if p.sessionID != sessionID {
+			continue
+		}
+		for _, content := range p.contents {
+			results = append(results, models.MemorySearchResult{
+				Message: &models.Message{Content: content},
+			})
+		}
+	}
+	return results, nil
+}
+
+func (s *fakeMemoryStore) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *fakeMemoryStore) recorded() []put {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]put(nil), s.puts...)
+}
+
+func memory(contents ...string) *models.Memory {
+	m := &models.Memory{}
+	for _, c := range contents {
+		m.Messages = append(m.Messages, models.Message{Role: "user", Content: c})
+	}
+	return m
+}
+
+func TestMemoryStoreCoalescesWrites(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeMemoryStore{}
+	store := NewMemoryStore(fake, 50*time.Millisecond, 0, false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sessionID := fmt.Sprintf("s%d", i%2)
+			assert.NoError(t, store.PutMemory(ctx, sessionID, memory(fmt.Sprint(i)), false))
+		}(i)
+	}
+	wg.Wait()
+
+	puts := fake.recorded()
+	require.Len(t, puts, 2)
+	for _, p := range puts {
+		assert.Len(t, p.contents, 5)
+	}
+}
+
+func TestMemoryStoreOrdersWrites(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeMemoryStore{}
+	store := NewMemoryStore(fake, time.Millisecond, 2, false)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.PutMemory(ctx, "s1", memory(fmt.Sprint(i)), false))
+	}
+	// reaching max messages flushes without waiting for the interval
+	require.NoError(t, store.PutMemory(ctx, "s1", memory("3", "4"), false))
+	// writes differing in skipNotify aren't combined
+	require.NoError(t, store.PutMemory(ctx, "s1", memory("5"), true))
+
+	var contents []string
+	for _, p := range fake.recorded() {
+		contents = append(contents, p.contents...)
+	}
+	assert.Equal(t, []string{"0", "1", "2", "3", "4", "5"}, contents)
+	assert.True(t, fake.recorded()[len(fake.recorded())-1].skipNotify)
+}
+
+func TestMemoryStoreTenants(t *testing.T) {
+	fake := &fakeMemoryStore{}
+	store := NewMemoryStore(fake, time.Hour, 0, false)
+
+	acme := models.WithTenant(context.Background(), "acme")
+	done := make(chan error)
+	go func() { done <- store.PutMemory(acme, "s1", memory("a"), false) }()
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return store.pending["s1"] != nil
+	}, time.Second, time.Millisecond)
+
+	// writes of different tenants aren't combined, and each is inserted on behalf of its
+	// tenant
+	go func() { done <- store.PutMemory(context.Background(), "s1", memory("b"), false) }()
+	require.NoError(t, <-done)
+	store.Flush()
+	require.NoError(t, <-done)
+
+	puts := fake.recorded()
+	require.Len(t, puts, 2)
+	assert.Equal(t, "acme", puts[0].tenant)
+	assert.Equal(t, []string{"a"}, puts[0].contents)
+	assert.Empty(t, puts[1].tenant)
+}
+
+func TestMemoryStoreErrors(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeMemoryStore{err: errors.New("insert failed")}
+	store := NewMemoryStore(fake, 20*time.Millisecond, 0, false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.PutMemory(ctx, "s1", memory("hi"), false)
+			assert.ErrorIs(t, err, fake.err)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMemoryStoreAsync(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeMemoryStore{}
+	store := NewMemoryStore(fake, time.Hour, 0, true)
+
+	require.NoError(t, store.PutMemory(ctx, "s1", memory("a"), false))
+	require.NoError(t, store.PutMemory(ctx, "s1", memory("b"), false))
+	assert.Empty(t, fake.recorded())
+
+	// closing inserts the buffered messages
+	require.NoError(t, store.Close())
+	assert.True(t, fake.closed)
+	puts := fake.recorded()
+	require.Len(t, puts, 1)
+	assert.Equal(t, []string{"a", "b"}, puts[0].contents)
+
+	// writes after closing aren't buffered
+	require.NoError(t, store.PutMemory(ctx, "s1", memory("c"), false))
+	assert.Len(t, fake.recorded(), 2)
+}
+
+func TestMemoryStoreInvalidatesCachedSearches(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeMemoryStore{}
+	cached := searchcache.NewMemoryStore(fake, searchcache.New(time.Minute, 0))
+	store := NewMemoryStore(cached, time.Hour, 0, true)
+	query := &models.MemorySearchPayload{Text: "a"}
+
+	results, err := store.SearchMemory(ctx, "s1", query, 10)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// the cached results are invalidated once the buffered messages are inserted, rather
+	// than once they're buffered
+	require.NoError(t, store.PutMemory(ctx, "s1", memory("a"), false))
+	results, err = store.SearchMemory(ctx, "s1", query, 10)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	store.Flush()
+	results, err = store.SearchMemory(ctx, "s1", query, 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "a", results[0].Message.Content)
+}
+
+func TestWrap(t *testing.T) {
+	fake := &fakeMemoryStore{}
+	appState := &models.AppState{Config: &config.Config{}, MemoryStore: fake}
+	appState.Config.Memory.WriteBuffer = config.WriteBufferConfig{
+		Enabled:    true,
+		Durability: "eventually",
+	}
+	assert.ErrorContains(t, Wrap(appState), "invalid memory.write_buffer.durability")
+
+	appState.Config.Memory.WriteBuffer.Durability = DurabilityAsync
+	require.NoError(t, Wrap(appState))
+	store, ok := appState.MemoryStore.(*MemoryStore)
+	require.True(t, ok)
+	assert.True(t, store.async)
+	assert.Equal(t, DefaultFlushInterval, store.flushInterval)
+}
@@ -0,0 +1,39 @@
+package writehooks
+
+import (
+	"context"
+	"strings"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+const (
+	// DropEmptyMessages drops messages whose content is empty or whitespace.
+	DropEmptyMessages = "drop_empty_messages"
+	// NormalizeRoles trims and lowercases message roles, so that "User " and "user" are
+	// stored alike.
+	NormalizeRoles = "normalize_roles"
+)
+
+func init() {
+	Register(DropEmptyMessages, HookFunc(dropEmptyMessages))
+	Register(NormalizeRoles, HookFunc(normalizeRoles))
+}
+
+func dropEmptyMessages(_ context.Context, _ string, memory *models.Memory) error {
+	messages := memory.Messages[:0]
+	for _, m := range memory.Messages {
+		if strings.TrimSpace(m.Content) != "" {
+			messages = append(messages, m)
+		}
+	}
+	memory.Messages = messages
+	return nil
+}
+
+func normalizeRoles(_ context.Context, _ string, memory *models.Memory) error {
+	for i := range memory.Messages {
+		memory.Messages[i].Role = strings.ToLower(strings.TrimSpace(memory.Messages[i].Role))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package writehooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getzep/zep/pkg/llms"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/webhooks"
+)
+
+const (
+	DefaultHTTPTimeout = 5 * time.Second
+	// httpRetryMax is low, as the hook is called while the client waits for its write.
+	httpRetryMax = 1
+	// maxRejectionSize bounds the part of a rejection's body returned to the client.
+	maxRejectionSize = 1 << 10
+)
+
+// HTTPRequest is the payload POSTed to an HTTP hook.
+type HTTPRequest struct {
+	SessionID string         `json:"session_id"`
+	Memory    *models.Memory `json:"memory"`
+}
+
+// HTTPResponse is the optional payload of a 2xx response of an HTTP hook. If Memory is
+// set, it replaces the written memory.
+type HTTPResponse struct {
+	Memory *models.Memory `json:"memory"`
+}
+
+// HTTPHook POSTs the written memory to an external endpoint, signed like webhook events.
+// The endpoint accepts the memory with a 2xx response, whose body may replace it, and
+// rejects it with a 400 or 422 response, whose body is returned to the client.
+type HTTPHook struct {
+	url      string
+	secret   []byte
+	failOpen bool
+	client   *http.Client
+}
+
+// NewHTTPHook returns an HTTPHook calling url. If failOpen is true, writes are accepted
+// unchanged when the endpoint can't be reached or fails, rather than failing. A zero
+// timeout uses DefaultHTTPTimeout.
+func NewHTTPHook(url, secret string, timeout time.Duration, failOpen bool) *HTTPHook {
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	return &HTTPHook{
+		url:      url,
+		secret:   []byte(secret),
+		failOpen: failOpen,
+		client:   llms.NewRetryableHTTPClient(httpRetryMax, timeout),
+	}
+}
+
+// Apply calls the endpoint with memory, replacing memory with the memory it returns.
+func (h *HTTPHook) Apply(ctx context.Context, sessionID string, memory *models.Memory) error {
+	replacement, err := h.call(ctx, sessionID, memory)
+	if err != nil {
+		if h.failOpen && !errors.Is(err, models.ErrValidation) {
+			log.Warnf("memory write hook failed, accepting write: %s", err)
+			return nil
+		}
+		return err
+	}
+	if replacement != nil {
+		*memory = *replacement
+	}
+	return nil
+}
+
+func (h *HTTPHook) call(
+	ctx context.Context,
+	sessionID string,
+	memory *models.Memory,
+) (*models.Memory, error) {
+	body, err := json.Marshal(&HTTPRequest{SessionID: sessionID, Memory: memory})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal write hook request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create write hook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(h.secret) > 0 {
+		req.Header.Set(webhooks.SignatureHeader, webhooks.Sign(h.secret, body))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call write hook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusBadRequest ||
+		resp.StatusCode == http.StatusUnprocessableEntity:
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, maxRejectionSize))
+		return nil, models.NewValidationError(
+			"memory rejected by write hook: " + strings.TrimSpace(string(reason)),
+		)
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("write hook returned status %d", resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read write hook response: %w", err)
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, nil
+	}
+	var hookResp HTTPResponse
+	if err := json.Unmarshal(b, &hookResp); err != nil {
+		return nil, fmt.Errorf("failed to decode write hook response: %w", err)
+	}
+	return hookResp.Memory, nil
+}
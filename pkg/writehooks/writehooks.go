@@ -0,0 +1,144 @@
+// Package writehooks runs hooks over the memory written with PutMemory before it's stored.
+// Hooks may validate memory, rejecting it with a ValidationError, or transform it, such
+// as by dropping empty messages. Hooks are built in and registered by name, or are an
+// external HTTP endpoint.
+package writehooks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getzep/zep/internal"
+	"github.com/getzep/zep/pkg/models"
+)
+
+var log = internal.GetLogger()
+
+// Hook validates or transforms memory before it's stored. It may modify memory in place.
+// A ValidationError rejects the write, and is returned to the client. Other errors fail
+// the write.
+type Hook interface {
+	Apply(ctx context.Context, sessionID string, memory *models.Memory) error
+}
+
+// HookFunc adapts a function to a Hook.
+type HookFunc func(ctx context.Context, sessionID string, memory *models.Memory) error
+
+// Apply calls f.
+func (f HookFunc) Apply(ctx context.Context, sessionID string, memory *models.Memory) error {
+	return f(ctx, sessionID, memory)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Hook{}
+)
+
+// Register makes a hook available by name to memory.write_hooks.hooks. It's meant to be
+// called from the init function of a package built into Zep, and panics if the name is
+// already registered.
+func Register(name string, hook Hook) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("write hook %q is already registered", name))
+	}
+	registry[name] = hook
+}
+
+// Lookup returns the hook registered with name.
+func Lookup(name string) (Hook, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	hook, ok := registry[name]
+	return hook, ok
+}
+
+// names returns the names of the registered hooks, sorted.
+func names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var _ models.MemoryStore[any] = &MemoryStore{}
+
+// MemoryStore runs its hooks, in order, over the memory written by PutMemory, and writes
+// the result to the wrapped store unless a hook fails. Other calls are passed through.
+type MemoryStore struct {
+	models.MemoryStore[any]
+	hooks []Hook
+}
+
+// NewMemoryStore wraps memoryStore with a MemoryStore running hooks.
+func NewMemoryStore(memoryStore models.MemoryStore[any], hooks ...Hook) *MemoryStore {
+	return &MemoryStore{MemoryStore: memoryStore, hooks: hooks}
+}
+
+// PutMemory runs the hooks over a copy of memoryMessages, so that the caller's memory
+// isn't modified, and writes the result.
+func (s *MemoryStore) PutMemory(
+	ctx context.Context,
+	sessionID string,
+	memoryMessages *models.Memory,
+	skipNotify bool,
+) error {
+	memory := *memoryMessages
+	memory.Messages = make([]models.Message, len(memoryMessages.Messages))
+	copy(memory.Messages, memoryMessages.Messages)
+	for _, hook := range s.hooks {
+		if err := hook.Apply(ctx, sessionID, &memory); err != nil {
+			return err
+		}
+	}
+	return s.MemoryStore.PutMemory(ctx, sessionID, &memory, skipNotify)
+}
+
+// Wrap replaces the memory store of appState with a MemoryStore running the configured
+// hooks, if any are configured.
+func Wrap(appState *models.AppState) error {
+	cfg := appState.Config.Memory.WriteHooks
+	if appState.MemoryStore == nil {
+		return nil
+	}
+	var hooks []Hook
+	for _, name := range cfg.Hooks {
+		hook, ok := Lookup(name)
+		if !ok {
+			return fmt.Errorf(
+				"unknown memory.write_hooks.hooks %q: must be one of %s",
+				name,
+				strings.Join(names(), ", "),
+			)
+		}
+		hooks = append(hooks, hook)
+	}
+	if cfg.HTTP.URL != "" {
+		hooks = append(hooks, NewHTTPHook(
+			cfg.HTTP.URL,
+			cfg.HTTP.Secret,
+			time.Duration(cfg.HTTP.Timeout)*time.Millisecond,
+			cfg.HTTP.FailOpen,
+		))
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	log.Infof(
+		"memory write hooks enabled: hooks=%v http=%t",
+		cfg.Hooks,
+		cfg.HTTP.URL != "",
+	)
+	appState.MemoryStore = NewMemoryStore(appState.MemoryStore, hooks...)
+	return nil
+}
@@ -0,0 +1,106 @@
+package writehooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+	"github.com/getzep/zep/pkg/webhooks"
+)
+
+// fakeMemoryStore records the memory written with PutMemory. Only PutMemory is
+// implemented.
+type fakeMemoryStore struct {
+	models.MemoryStore[any]
+	puts []*models.Memory
+}
+
+func (s *fakeMemoryStore) PutMemory(
+	_ context.Context,
+	_ string,
+	memory *models.Memory,
+	_ bool,
+) error {
+	s.puts = append(s.puts, memory)
+	return nil
+}
+
+func TestBuiltinHooks(t *testing.T) {
+	fake := &fakeMemoryStore{}
+	appState := &models.AppState{Config: &config.Config{}, MemoryStore: fake}
+	appState.Config.Memory.WriteHooks.Hooks = []string{DropEmptyMessages, NormalizeRoles}
+	require.NoError(t, Wrap(appState))
+
+	memory := &models.Memory{Messages: []models.Message{
+		{Role: " User", Content: "hello"},
+		{Role: "assistant", Content: "  "},
+		{Role: "AI", Content: "hi"},
+	}}
+	require.NoError(t, appState.MemoryStore.PutMemory(context.Background(), "s1", memory, false))
+
+	require.Len(t, fake.puts, 1)
+	assert.Equal(t, []models.Message{
+		{Role: "user", Content: "hello"},
+		{Role: "ai", Content: "hi"},
+	}, fake.puts[0].Messages)
+	// the caller's memory isn't modified
+	assert.Len(t, memory.Messages, 3)
+	assert.Equal(t, " User", memory.Messages[0].Role)
+
+	appState.Config.Memory.WriteHooks.Hooks = []string{"missing"}
+	assert.ErrorContains(t, Wrap(appState), `unknown memory.write_hooks.hooks "missing"`)
+}
+
+func TestHTTPHook(t *testing.T) {
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhooks.SignatureHeader)
+		var req HTTPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch req.SessionID {
+		case "reject":
+			http.Error(w, "no secrets allowed", http.StatusUnprocessableEntity)
+		case "fail":
+			w.WriteHeader(http.StatusNotImplemented)
+		case "accept":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			req.Memory.Messages[0].Content = "[redacted]"
+			_ = json.NewEncoder(w).Encode(HTTPResponse{Memory: req.Memory})
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	fake := &fakeMemoryStore{}
+	store := NewMemoryStore(fake, NewHTTPHook(server.URL, "secret", 0, false))
+	memory := func() *models.Memory {
+		return &models.Memory{Messages: []models.Message{{Role: "user", Content: "password"}}}
+	}
+
+	require.NoError(t, store.PutMemory(ctx, "redact", memory(), false))
+	assert.NotEmpty(t, signature)
+	require.NoError(t, store.PutMemory(ctx, "accept", memory(), false))
+	require.Len(t, fake.puts, 2)
+	assert.Equal(t, "[redacted]", fake.puts[0].Messages[0].Content)
+	assert.Equal(t, "password", fake.puts[1].Messages[0].Content)
+
+	err := store.PutMemory(ctx, "reject", memory(), false)
+	assert.ErrorIs(t, err, models.ErrValidation)
+	assert.ErrorContains(t, err, "no secrets allowed")
+	assert.Error(t, store.PutMemory(ctx, "fail", memory(), false))
+	assert.Len(t, fake.puts, 2)
+
+	// failing open accepts writes when the hook fails, but not when it rejects them
+	store = NewMemoryStore(fake, NewHTTPHook(server.URL, "", 0, true))
+	assert.NoError(t, store.PutMemory(ctx, "fail", memory(), false))
+	assert.ErrorIs(t, store.PutMemory(ctx, "reject", memory(), false), models.ErrValidation)
+	assert.Len(t, fake.puts, 3)
+}